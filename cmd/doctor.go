@@ -0,0 +1,81 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"dingospeed/internal/doctor"
+	"dingospeed/pkg/config"
+	log "dingospeed/pkg/logger"
+
+	"github.com/bytedance/sonic"
+)
+
+// runDoctor dingospeed doctor [-config path] [-json]，检查远端连通性、TLS信任、磁盘性能、缓存布局版本、
+// 缓存文件完整性及时钟偏差并输出处理建议，有检查失败时退出码为1。可在服务运行时执行。
+func runDoctor(args []string) error {
+	var (
+		opts     doctor.Options
+		jsonOut  bool
+		ioSizeMB int64
+	)
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	fs.BoolVar(&jsonOut, "json", false, "以json格式输出")
+	fs.DurationVar(&opts.Timeout, "timeout", 10*time.Second, "每项网络检查的超时时间")
+	fs.Int64Var(&ioSizeMB, "io-size", 64, "磁盘写入测试的数据量，单位MB")
+	fs.DurationVar(&opts.MaxSkew, "max-skew", 30*time.Second, "允许的时钟偏差")
+	fs.IntVar(&opts.MaxFiles, "max-files", 0, "完整性检查最多扫描的文件数，0为不限制")
+	fs.BoolVar(&opts.SkipNetwork, "skip-network", false, "跳过远端连通性、TLS及时钟偏差检查")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts.IOSize = ioSizeMB << 20
+	if _, err := config.Scan(configPath, profile, overrides...); err != nil {
+		return err
+	}
+	log.InitLogger()
+	report := doctor.Run(context.Background(), config.SysConfig.Repos(), opts)
+	if jsonOut {
+		data, err := sonic.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		printDoctorReport(report)
+	}
+	if failed := report.Count(doctor.StatusFail); failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(report.Checks))
+	}
+	return nil
+}
+
+func printDoctorReport(report *doctor.Report) {
+	for _, c := range report.Checks {
+		fmt.Fprintf(os.Stdout, "[%-4s] %-9s %s (%s)\n", strings.ToUpper(c.Status), c.Name, c.Detail, c.Duration)
+		if c.Advice != "" {
+			fmt.Fprintf(os.Stdout, "       %-9s -> %s\n", "", c.Advice)
+		}
+	}
+	fmt.Fprintf(os.Stdout, "%d ok, %d warnings, %d failed\n",
+		report.Count(doctor.StatusOK), report.Count(doctor.StatusWarn), report.Count(doctor.StatusFail))
+}
@@ -18,41 +18,79 @@ import (
 	"flag"
 	"fmt"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"runtime"
 
+	"dingospeed/internal/migrate"
 	"dingospeed/internal/server"
 	"dingospeed/pkg/app"
+	"dingospeed/pkg/backup"
+	"dingospeed/pkg/common"
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/errreport"
 	log "dingospeed/pkg/logger"
+	"dingospeed/pkg/notify"
 )
 
 var (
 	configPath string
+	profile    string
+	overrides  config.Overrides
 	id, _      = os.Hostname() //nolint:errcheck
 	Name       = "dingospeed"
 	Version    string
 )
 
 func init() {
-	flag.StringVar(&configPath, "config", "./config/config.yaml", "配置文件路径")
+	flag.StringVar(&configPath, "config", envOr("DINGOSPEED_CONFIG", "./config/config.yaml"), "配置文件路径，可通过DINGOSPEED_CONFIG设置")
+	flag.StringVar(&profile, "profile", os.Getenv("DINGOSPEED_PROFILE"), "部署预设：edge-cache、offline-archive、cluster-node，配置文件中显式设置的项优先，可通过DINGOSPEED_PROFILE设置")
+	flag.Var(&overrides, "set", "覆盖配置项，格式为key=value，如server.online=false，可重复指定，优先于DINGOSPEED_*环境变量及配置文件")
 	flag.Parse()
 }
 
-func newApp(s *server.HTTPServer, schedulerServer *server.SchedulerServer) *app.App {
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func newApp(s *server.HTTPServer, schedulerServer *server.SchedulerServer, nodeServer *server.NodeServer) *app.App {
 	app := app.New(app.ID(id), app.Name(Name), app.Version(Version),
-		app.Server(s, schedulerServer))
+		app.Server(s, schedulerServer, nodeServer))
 	return app
 }
 
 func main() {
-	conf, err := config.Scan(configPath)
+	if flag.NArg() > 0 {
+		runCommand(flag.Arg(0), flag.Args()[1:])
+		return
+	}
+	conf, err := config.Scan(configPath, profile, overrides...)
 	if err != nil {
 		panic(err)
 	}
+	config.SystemInfo.Version = Version
 
 	log.InitLogger()
+	if err = errreport.Init(); err != nil {
+		panic(err)
+	}
+	if err = notify.Init(); err != nil {
+		panic(err)
+	}
+	if err = common.InitBackgroundLimiter(conf.Background.Windows, conf.Background.Bandwidth); err != nil {
+		panic(err)
+	}
+	if conf.EnableFairShare() {
+		common.InitServeLimiter(conf.TokenBucketLimit.FairShare.Bandwidth)
+	}
+	if err = migrate.CheckLayout(config.SysConfig.Repos()); err != nil {
+		panic(err)
+	}
+	if err = backup.CheckRestore(config.SysConfig.Repos()); err != nil {
+		panic(err)
+	}
 	myapp, f, err := wireApp(conf)
 	if err != nil {
 		panic(err)
@@ -63,7 +101,8 @@ func main() {
 		runtime.SetMutexProfileFraction(1)
 
 		go func() {
-			panic(http.ListenAndServe(fmt.Sprintf(":%d", config.SysConfig.Server.PProfPort), nil))
+			addr := fmt.Sprintf("%s:%d", config.SysConfig.Server.PProfHost, config.SysConfig.Server.PProfPort)
+			panic(http.ListenAndServe(addr, server.NewDebugHandler()))
 		}()
 	}
 
@@ -74,3 +113,26 @@ func main() {
 		panic(err)
 	}
 }
+
+// runCommand 执行子命令，如dingospeed migrate
+func runCommand(name string, args []string) {
+	var err error
+	switch name {
+	case "migrate":
+		err = runMigrate(args)
+	case "import-olah":
+		err = runImportOlah(args)
+	case "backup":
+		err = runBackup(args)
+	case "restore":
+		err = runRestore(args)
+	case "doctor":
+		err = runDoctor(args)
+	default:
+		err = fmt.Errorf("unknown command %s", name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,118 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"dingospeed/internal/migrate"
+	"dingospeed/pkg/config"
+	log "dingospeed/pkg/logger"
+)
+
+// runMigrate dingospeed migrate [-config path] [-dry-run]，将缓存目录升级到当前程序支持的布局版本，执行前需停止服务。
+// -dry-run只报告将移动及改写的文件和预估停机时间，不修改缓存目录。
+func runMigrate(args []string) error {
+	var dryRun bool
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	fs.BoolVar(&dryRun, "dry-run", false, "只报告将执行的操作及预估停机时间，不修改缓存目录")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := config.Scan(configPath, profile, overrides...); err != nil {
+		return err
+	}
+	log.InitLogger()
+	repos := config.SysConfig.Repos()
+	from, err := migrate.LayoutVersion(repos)
+	if err != nil {
+		return err
+	}
+	if from == migrate.CurrentLayoutVersion {
+		fmt.Fprintf(os.Stdout, "cache layout of %s is already version %d\n", repos, from)
+		if dryRun {
+			return nil
+		}
+		return migrate.WriteLayout(repos, from)
+	}
+	if dryRun {
+		report, err := migrate.DryRun(repos)
+		if err != nil {
+			return err
+		}
+		printMigrateReport(report)
+		return nil
+	}
+	var lastReport time.Time
+	err = migrate.Migrate(repos, func(step *migrate.StepPlan, done int) {
+		if total := step.Total(); done < total && time.Since(lastReport) < time.Second {
+			return
+		}
+		lastReport = time.Now()
+		fmt.Fprintf(os.Stdout, "layout %d -> %d: %d/%d files\n", step.From, step.To, done, step.Total())
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "cache layout of %s migrated from version %d to %d\n", repos, from, migrate.CurrentLayoutVersion)
+	return nil
+}
+
+func printMigrateReport(report *migrate.Report) {
+	fmt.Fprintf(os.Stdout, "cache layout of %s would be migrated from version %d to %d (dry run)\n", report.Repos, report.From, report.To)
+	for _, step := range report.Steps {
+		fmt.Fprintf(os.Stdout, "layout %d -> %d: %s\n", step.From, step.To, step.Desc)
+		fmt.Fprintf(os.Stdout, "  move %d files (%d bytes), relink %d files, skip %d files without paths-info\n",
+			step.Moved, step.Bytes, step.Linked, step.Skipped)
+		for _, sample := range step.Samples {
+			fmt.Fprintf(os.Stdout, "  %s\n", sample)
+		}
+		if more := step.Total() - len(step.Samples); more > 0 {
+			fmt.Fprintf(os.Stdout, "  ... and %d more\n", more)
+		}
+	}
+	fmt.Fprintf(os.Stdout, "estimated downtime: %s\n", report.EstimatedDowntime.Round(time.Second))
+}
+
+// runImportOlah dingospeed import-olah -src path，导入olah镜像的缓存目录，源目录不做修改。
+func runImportOlah(args []string) error {
+	var src string
+	fs := flag.NewFlagSet("import-olah", flag.ExitOnError)
+	fs.StringVar(&configPath, "config", configPath, "配置文件路径")
+	fs.StringVar(&src, "src", "", "olah的repos目录")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if src == "" {
+		return fmt.Errorf("-src is required")
+	}
+	if _, err := config.Scan(configPath, profile, overrides...); err != nil {
+		return err
+	}
+	log.InitLogger()
+	// 空缓存目录先标记布局版本，导入的数据即为当前布局
+	if err := migrate.CheckLayout(config.SysConfig.Repos()); err != nil {
+		return err
+	}
+	result, err := migrate.ImportOlah(src, config.SysConfig.Repos())
+	if result != nil {
+		fmt.Fprintf(os.Stdout, "imported %d files (%d blocks), %d api cache files, skipped %d files\n", result.Files, result.Blocks, result.ApiFile, result.Skipped)
+	}
+	return err
+}
@@ -17,10 +17,6 @@ import (
 	"dingospeed/pkg/config"
 )
 
-import (
-	_ "net/http/pprof"
-)
-
 // Injectors from wire.go:
 
 func wireApp(configConfig *config.Config) (*app.App, func(), error) {
@@ -30,23 +26,39 @@ func wireApp(configConfig *config.Config) (*app.App, func(), error) {
 	baseData := data.NewBaseData()
 	lockDao := dao.NewLockDao(baseData)
 	fileDao := dao.NewFileDao(downloaderDao, baseData, lockDao)
-	fileService := service.NewFileService(fileDao)
+	metaDao := dao.NewMetaDao(fileDao, lockDao, baseData)
+	prefetchService := service.NewPrefetchService(fileDao, metaDao)
+	fileService := service.NewFileService(fileDao, prefetchService)
 	sysService := service.NewSysService(schedulerDao)
 	localOperationService := service.NewLocalOperationService(schedulerDao)
 	fileHandler := handler.NewFileHandler(fileService, sysService, localOperationService)
-	metaDao := dao.NewMetaDao(fileDao, lockDao, baseData)
-	metaService := service.NewMetaService(fileDao, metaDao)
+	metaService := service.NewMetaService(fileDao, metaDao, prefetchService)
 	metaHandler := handler.NewMetaHandler(metaService)
 	sysHandler := handler.NewSysHandler(sysService)
 	cacheJobService := service.NewCacheJobService(fileDao, metaDao, downloaderDao, schedulerDao)
 	cacheJobHandler := handler.NewCacheJobHandler(cacheJobService)
 	modelscopeService := service.NewModelscopeService()
 	modelscopeHandler := handler.NewModelscopeHandler(modelscopeService)
-	httpRouter := router.NewHttpRouter(echo, fileHandler, metaHandler, sysHandler, cacheJobHandler, modelscopeHandler)
+	adminService := service.NewAdminService()
+	ociService := service.NewOciService(metaDao)
+	trashService := service.NewTrashService()
+	revisionGcService := service.NewRevisionGcService(metaDao)
+	syncService := service.NewSyncService(fileDao, metaDao)
+	curationService := service.NewCurationService(fileDao, metaDao)
+	batchService := service.NewBatchService(trashService, revisionGcService, prefetchService)
+	compactionService := service.NewCompactionService(fileDao)
+	bundleService := service.NewBundleService(fileDao, metaService, prefetchService)
+	adminHandler := handler.NewAdminHandler(adminService, ociService, trashService, revisionGcService, syncService, curationService, batchService, compactionService, bundleService)
+	uploadDao := dao.NewUploadDao(baseData)
+	uploadService := service.NewUploadService(uploadDao)
+	uploadHandler := handler.NewUploadHandler(uploadService)
+	httpRouter := router.NewHttpRouter(echo, fileHandler, metaHandler, sysHandler, cacheJobHandler, modelscopeHandler, adminHandler, uploadHandler)
 	httpServer := server.NewServer(configConfig, echo, httpRouter)
 	schedulerService := service.NewSchedulerService(schedulerDao)
 	schedulerServer := server.NewSchedulerServer(schedulerService, sysService, localOperationService)
-	appApp := newApp(httpServer, schedulerServer)
+	nodeService := service.NewNodeService()
+	nodeServer := server.NewNodeServer(nodeService)
+	appApp := newApp(httpServer, schedulerServer, nodeServer)
 	return appApp, func() {
 	}, nil
 }
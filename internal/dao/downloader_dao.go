@@ -48,7 +48,7 @@ func (d *DownloaderDao) FileDownload(chanErr chan error, startPos, endPos int64,
 		wg sync.WaitGroup
 	)
 	defer close(chanErr)
-	defer close(taskParam.ResponseChan)
+	defer taskParam.Stream.Close()
 	dingCacheManager := downloader.GetInstance()
 	dingFile, err := dingCacheManager.GetDingFile(taskParam.BlobsFile, taskParam.FileSize)
 	if err != nil {
@@ -60,11 +60,23 @@ func (d *DownloaderDao) FileDownload(chanErr chan error, startPos, endPos int64,
 		dingCacheManager.ReleasedDingFile(taskParam.BlobsFile)
 	}()
 	taskParam.DingFile = dingFile
+	taskParam.Transfer = downloader.RegisterTransfer(&downloader.Transfer{
+		DataType:   taskParam.DataType,
+		OrgRepo:    taskParam.OrgRepo,
+		FileName:   taskParam.FileName,
+		FileSize:   taskParam.FileSize,
+		StartPos:   startPos,
+		EndPos:     endPos,
+		Client:     util.Itoa(taskParam.Context.Value(consts.PromSource)),
+		FromClient: taskParam.FromClient,
+	}, taskParam.Cancel)
+	defer downloader.UnregisterTransfer(taskParam.Transfer)
 	tasks, err := d.constructTask(startPos, endPos, isInnerRequest, taskParam)
 	if err != nil {
 		chanErr <- err
 		return
 	}
+	chanErr <- nil // 任务构造完成，通知响应方开始读取数据流
 	wg.Add(1)
 	go func() {
 		defer func() {
@@ -74,11 +86,7 @@ func (d *DownloaderDao) FileDownload(chanErr chan error, startPos, endPos int64,
 			if taskParam.Context.Err() != nil {
 				break
 			}
-			task := tasks[i]
-			if i == 0 {
-				task.GetResponseChan() <- []byte{} // 先建立长连接
-			}
-			task.OutResult()
+			tasks[i].OutResult()
 		}
 	}()
 	if len(tasks) > 0 {
@@ -107,10 +115,11 @@ func (d *DownloaderDao) constructTask(startPos, endPos int64, isInnerRequest boo
 	// 分析下载类型是否全部存在，若文件不完整，返回当前已缓存的最大偏移量
 	fileComplete, curPos = analysisFilePosition(taskParam.DingFile, startPos, endPos)
 	if !fileComplete && !config.SysConfig.Online() { // 文件不完整，且当前节点为离线
-		return nil, myerr.NewAppendCode(http.StatusNotFound, "model file is not exist")
+		return nil, util.OfflineMissError(myerr.NewAppendCode(http.StatusNotFound, "model file is not exist"))
 	}
 	// isInnerRequest为true，即内部请求，是已经被调度过后，设置为内部域名的请求，这种请求将不会再次参与调度，直接做下载即可。
-	if !isInnerRequest && config.SysConfig.IsCluster() && !fileComplete {
+	// 通用代理的文件只向源站请求，不参与集群调度。
+	if !isInnerRequest && taskParam.Origin == "" && config.SysConfig.IsCluster() && !fileComplete {
 		if response, err := d.getRequestDomainScheduler(taskParam.DataType, taskParam.OrgRepo, taskParam.FileName, taskParam.Etag, curPos, endPos, taskParam.FileSize); err != nil {
 			zap.S().Errorf("getRequestDomainScheduler err.%v", err)
 			goto localTask
@@ -146,7 +155,9 @@ func (d *DownloaderDao) constructTask(startPos, endPos int64, isInnerRequest boo
 	}
 
 localTask:
-	taskParam.Domain = config.SysConfig.GetHFURLBase()
+	if taskParam.Origin == "" {
+		taskParam.Domain = config.SysConfig.GetHFURLBase()
+	}
 	tasks = getContiguousRanges(startPos, endPos, taskParam)
 	return tasks, nil
 }
@@ -320,7 +331,8 @@ func createCacheTask(taskNo int, start, end int64, taskParam *downloader.TaskPar
 	cache.TaskSize = taskParam.TaskSize
 	cache.FileName = taskParam.FileName
 	cache.OrgRepo = taskParam.OrgRepo
-	cache.ResponseChan = taskParam.ResponseChan
+	cache.Stream = taskParam.Stream
+	cache.Transfer = taskParam.Transfer
 	return cache
 }
 
@@ -331,13 +343,15 @@ func createRemoteTask(taskNo int, start, end int64, taskParam *downloader.TaskPa
 	remote.Authorization = taskParam.Authorization
 	remote.Domain = taskParam.Domain
 	remote.Uri = taskParam.Uri
+	remote.Origin = taskParam.Origin
 	remote.Queue = make(chan []byte, getQueueSize(remote.RangeStartPos, remote.RangeEndPos))
-	remote.ResponseChan = taskParam.ResponseChan
+	remote.Stream = taskParam.Stream
 	remote.TaskSize = taskParam.TaskSize
 	remote.FileName = taskParam.FileName
 	remote.OrgRepo = taskParam.OrgRepo
 	remote.DataType = taskParam.DataType
 	remote.Etag = taskParam.Etag
 	remote.Cancel = taskParam.Cancel
+	remote.Transfer = taskParam.Transfer
 	return remote
 }
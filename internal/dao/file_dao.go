@@ -15,11 +15,20 @@
 package dao
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"dingospeed/internal/data"
@@ -28,11 +37,14 @@ import (
 	"dingospeed/pkg/config"
 	"dingospeed/pkg/consts"
 	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/notify"
+	"dingospeed/pkg/prom"
 	"dingospeed/pkg/util"
 
 	"github.com/bytedance/sonic"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 type CommitHfSha struct {
@@ -47,14 +59,22 @@ type FileDao struct {
 	downloaderDao *DownloaderDao
 	baseData      *data.BaseData
 	lockDao       *LockDao
+	readAheadMu   sync.Mutex
+	scanGroup     singleflight.Group // 合并同一blob并发的下载扫描
+}
+
+// readAheadState 同一文件最近一次range请求的结束位置及已发起预读的结束位置
+type readAheadState struct {
+	nextPos  int64
+	aheadEnd int64
 }
 
 func NewFileDao(downloaderDao *DownloaderDao, baseData *data.BaseData, lockDao *LockDao) *FileDao {
 	return &FileDao{downloaderDao: downloaderDao, baseData: baseData, lockDao: lockDao}
 }
 
-func (f *FileDao) CheckCommitHf(repoType, orgRepo, commit, authorization string) (int, error) {
-	resp, err := f.RemoteRequestMeta(consts.RequestTypeHead, repoType, orgRepo, commit, authorization)
+func (f *FileDao) CheckCommitHf(ctx context.Context, repoType, orgRepo, commit, authorization string) (int, error) {
+	resp, err := f.RemoteRequestMeta(ctx, consts.RequestTypeHead, repoType, orgRepo, commit, authorization)
 	if err != nil {
 		zap.S().Errorf("head call meta %s/%s error.%v", orgRepo, commit, err)
 		return http.StatusInternalServerError, err
@@ -66,11 +86,28 @@ func (f *FileDao) CheckCommitHf(repoType, orgRepo, commit, authorization string)
 	return resp.StatusCode, myerr.New("request commit err")
 }
 
-func (f *FileDao) GetFileCommitSha(repoType, orgRepo, commit, authorization string, source string) (string, error) {
+// GetFileCommitSha 解析revision对应的commit sha，ctx取消时中止远端请求。
+func (f *FileDao) GetFileCommitSha(ctx context.Context, repoType, orgRepo, commit, authorization string, source string) (string, error) {
+	commit = util.NormalizeRevision(commit)
 	metaShaKey := GetMetaShaRepoKey(orgRepo, commit, authorization)
 	if v, ok := f.baseData.Cache.Get(metaShaKey); ok {
 		return v.(string), nil
 	}
+	negativeKey := GetNegativeRepoKey(repoType, orgRepo, commit, authorization)
+	if v, ok := f.baseData.Cache.Get(negativeKey); ok {
+		return "", v.(myerr.Error)
+	}
+	// 本地未命中时查询其他副本共享的结果
+	if v, ok := f.baseData.Shared.Get(metaShaKey); ok {
+		f.baseData.Cache.Set(metaShaKey, v, config.SysConfig.GetDefaultExpiration())
+		return v, nil
+	}
+	if v, ok := f.baseData.Shared.Get(negativeKey); ok {
+		if e, ok := decodeNegative(v); ok {
+			f.baseData.Cache.Set(negativeKey, e, config.SysConfig.GetNegativeExpiration())
+			return "", e
+		}
+	}
 	var (
 		commitSha string
 		err       error
@@ -78,84 +115,158 @@ func (f *FileDao) GetFileCommitSha(repoType, orgRepo, commit, authorization stri
 	if config.SysConfig.Online() {
 		goto remoteRequestMeta
 	}
-	commitSha, err = f.GetCommitHfOffline(repoType, orgRepo, commit)
+	commitSha, err = f.GetCommitHfOffline(repoType, orgRepo, commit, authorization)
 	if err != nil {
-		if source == "file" {
+		if source == "file" && !config.SysConfig.InMaintenance() {
 			// 若只是发起文件下载（先在线后离线），将不会校验meta文件是否存在，没有就创建，主要是看文件本身是否存在。
 			goto remoteRequestMeta
 		}
 		zap.S().Warnf("getFileCommitSha GetCommitHfOffline err.%v", err)
-		return "", myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s is not found", orgRepo))
+		return "", util.OfflineMissError(myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s is not found", orgRepo)))
 	}
-	f.baseData.Cache.Set(metaShaKey, commitSha, config.SysConfig.GetDefaultExpiration())
-	f.baseData.Cache.Set(GetMetaShaRepoKey(orgRepo, commitSha, authorization), commitSha, config.SysConfig.GetDefaultExpiration())
+	f.setCommitShaCache(metaShaKey, GetMetaShaRepoKey(orgRepo, commitSha, authorization), commitSha)
 	return commitSha, nil
 
 remoteRequestMeta:
-	code, sha, err := f.getCommitHfRemote(repoType, orgRepo, commit, authorization)
+	code, sha, errorCode, err := f.getCommitHfRemote(ctx, repoType, orgRepo, commit, authorization)
+	var redirect *util.RedirectError
+	if errors.As(err, &redirect) {
+		return "", err
+	}
 	if err != nil {
-		return "", myerr.NewAppendCode(code, fmt.Sprintf("request fail.%v", err))
+		e := myerr.NewAppendCode(code, fmt.Sprintf("request fail.%v", err))
+		if myerr.KindOf(err) == myerr.KindUpstreamTimeout {
+			e = e.WithKind(myerr.KindUpstreamTimeout)
+		}
+		return "", e
 	}
 	if code != http.StatusOK && code != http.StatusTemporaryRedirect {
-		zap.S().Errorf("getFileCommitSha %s code:%d", orgRepo, code)
+		zap.S().Errorf("getFileCommitSha %s code:%d, errorCode:%s", orgRepo, code, errorCode)
 		if code == http.StatusNotFound {
-			return "", myerr.NewAppendCode(code, "未找到该资源。")
+			if errorCode == "" {
+				errorCode = consts.HfErrorCodeRepoNotFound
+			}
+			e := myerr.NewErrorCode(code, errorCode, "未找到该资源。")
+			f.setNegativeCache(negativeKey, e)
+			return "", e
 		} else if code == http.StatusUnauthorized || code == http.StatusForbidden {
-			return "", myerr.NewAppendCode(code, "没有该资源的访问权限，请联系管理员。")
+			e := myerr.NewErrorCode(code, errorCode, "没有该资源的访问权限，请联系管理员。")
+			f.setNegativeCache(negativeKey, e)
+			return "", e
 		} else {
-			return "", myerr.NewAppendCode(code, fmt.Sprintf("请求资源失败.(%d)", code))
+			return "", myerr.NewErrorCode(code, errorCode, fmt.Sprintf("请求资源失败.(%d)", code))
 		}
 	} else {
 		commitSha = sha
 	}
-	f.baseData.Cache.Set(metaShaKey, commitSha, config.SysConfig.GetDefaultExpiration())
-	f.baseData.Cache.Set(GetMetaShaRepoKey(orgRepo, commitSha, authorization), commitSha, config.SysConfig.GetDefaultExpiration())
+	f.checkRevisionMoved(repoType, orgRepo, commit, authorization, commitSha)
+	f.setCommitShaCache(metaShaKey, GetMetaShaRepoKey(orgRepo, commitSha, authorization), commitSha)
 	return commitSha, nil
 }
 
+// checkRevisionMoved 跟踪的分支或标签解析到的commit与本地缓存的元数据不同时通知下游。
+func (f *FileDao) checkRevisionMoved(repoType, orgRepo, revision, authorization, commitSha string) {
+	if isCommitSha(revision) || !notify.Tracked(repoType, orgRepo, revision) {
+		return
+	}
+	if oldSha, err := f.GetCommitHfOffline(repoType, orgRepo, revision, authorization); err == nil {
+		notify.RevisionMoved(repoType, orgRepo, revision, oldSha, commitSha)
+	}
+}
+
+func (f *FileDao) setCommitShaCache(metaShaKey, commitShaKey, commitSha string) {
+	expiration := config.SysConfig.GetDefaultExpiration()
+	f.baseData.Cache.Set(metaShaKey, commitSha, expiration)
+	f.baseData.Cache.Set(commitShaKey, commitSha, expiration)
+	f.baseData.Shared.Set(metaShaKey, commitSha, expiration)
+	f.baseData.Shared.Set(commitShaKey, commitSha, expiration)
+}
+
+// 缓存仓库不存在或无权限的结果，避免短时间内重复请求远端。
+func (f *FileDao) setNegativeCache(negativeKey string, e myerr.Error) {
+	if expiration := config.SysConfig.GetNegativeExpiration(); expiration > 0 {
+		f.baseData.Cache.Set(negativeKey, e, expiration)
+		f.baseData.Shared.Set(negativeKey, encodeNegative(e), expiration)
+	}
+}
+
+// 负缓存在共享缓存中的格式为 状态码|错误码|错误信息
+func encodeNegative(e myerr.Error) string {
+	return fmt.Sprintf("%d|%s|%s", e.StatusCode(), e.ErrorCode(), e.Error())
+}
+
+func decodeNegative(v string) (myerr.Error, bool) {
+	parts := strings.SplitN(v, "|", 3)
+	if len(parts) != 3 {
+		return myerr.Error{}, false
+	}
+	code, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return myerr.Error{}, false
+	}
+	return myerr.NewErrorCode(code, parts[1], parts[2]), true
+}
+
 // 若为离线或在线请求失败，将进行本地仓库查找。
 
-func (f *FileDao) getCommitHfRemote(repoType, orgRepo, commit, authorization string) (int, string, error) {
-	resp, err := f.RemoteRequestMeta(consts.RequestTypeGet, repoType, orgRepo, commit, authorization)
+func (f *FileDao) getCommitHfRemote(ctx context.Context, repoType, orgRepo, commit, authorization string) (int, string, string, error) {
+	resp, err := f.RemoteRequestMeta(ctx, consts.RequestTypeGet, repoType, orgRepo, commit, authorization)
 	if err != nil {
 		zap.S().Errorf("get call meta %s/%s error.%v", orgRepo, commit, err)
-		return http.StatusInternalServerError, "", err
+		return http.StatusInternalServerError, "", "", err
+	}
+	// 策略为rewrite时远端的重定向不被跟随，交由客户端重新经由镜像请求
+	if util.IsRedirect(resp.StatusCode) {
+		return resp.StatusCode, "", "", util.NewRedirectError(resp.StatusCode, resp.GetKey("location"))
 	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusTemporaryRedirect {
-		return resp.StatusCode, "", nil
+		return resp.StatusCode, "", resp.GetKey(consts.HfHeaderErrorCode), nil
 	}
 	var sha CommitHfSha
 	if err = sonic.Unmarshal(resp.Body, &sha); err != nil {
 		zap.S().Errorf("unmarshal content:%s, error:%v", string(resp.Body), err)
-		return http.StatusInternalServerError, "", err
+		return http.StatusInternalServerError, "", "", err
+	}
+	if config.SysConfig.Policy.Enabled {
+		if card, err := util.ParseRepoCard(resp.Body); err == nil {
+			f.baseData.Cache.Set(GetRepoCardKey(repoType, orgRepo, sha.Sha), card, config.SysConfig.GetDefaultExpiration())
+		}
 	}
-	return resp.StatusCode, sha.Sha, nil
+	util.CacheTraceFrom(ctx).Revalidate(util.UpstreamFor(fmt.Sprintf("/api/%s/%s/revision/%s", repoType, orgRepo, util.EscapeRevision(commit))))
+	return resp.StatusCode, sha.Sha, "", nil
 }
 
-func (f *FileDao) RemoteRequestMeta(method, repoType, orgRepo, revision, authorization string) (*common.Response, error) {
+func (f *FileDao) RemoteRequestMeta(ctx context.Context, method, repoType, orgRepo, revision, authorization string) (*common.Response, error) {
 	var reqUri string
 	if revision == "" {
 		reqUri = fmt.Sprintf("/api/%s/%s", repoType, orgRepo)
 	} else {
-		reqUri = fmt.Sprintf("/api/%s/%s/revision/%s", repoType, orgRepo, revision)
+		reqUri = fmt.Sprintf("/api/%s/%s/revision/%s", repoType, orgRepo, util.EscapeRevision(revision))
 	}
 	headers := map[string]string{}
 	if authorization != "" {
 		headers["authorization"] = authorization
 	}
-	return util.RetryRequest(func() (*common.Response, error) {
+	return util.RetryRequestContext(ctx, func() (*common.Response, error) {
 		if method == consts.RequestTypeHead {
-			return util.Head(reqUri, headers)
+			return util.HeadContext(ctx, reqUri, headers)
 		} else if method == consts.RequestTypeGet {
-			return util.Get(reqUri, headers)
+			return util.GetContext(ctx, reqUri, headers)
 		} else {
 			return nil, fmt.Errorf("request method err")
 		}
 	})
 }
 
-func (f *FileDao) GetCommitHfOffline(repoType, orgRepo, commit string) (string, error) {
-	apiPath := fmt.Sprintf("%s/api/%s/%s/revision/%s/meta_get.json", config.SysConfig.Repos(), repoType, orgRepo, commit)
+func (f *FileDao) GetCommitHfOffline(repoType, orgRepo, commit, authorization string) (string, error) {
+	apiRoot := util.GetApiRoot(authorization)
+	apiPath := util.ApiMetaFile(apiRoot, repoType, orgRepo, commit, consts.RequestTypeGet)
+	if !util.CacheFileExists(apiPath) {
+		// 只缓存了commit目录时，通过已缓存的refs（包括PR）将分支、标签或refs/pr/N解析为commit
+		if targetCommit, ok := f.refsCommitOffline(apiRoot, repoType, orgRepo, commit); ok {
+			apiPath = util.ApiMetaFile(apiRoot, repoType, orgRepo, targetCommit, consts.RequestTypeGet)
+		}
+	}
 	if util.FileExists(apiPath) {
 		cacheContent, err := f.ReadCacheRequest(apiPath)
 		if err != nil {
@@ -171,6 +282,134 @@ func (f *FileDao) GetCommitHfOffline(repoType, orgRepo, commit string) (string,
 	return "", myerr.New(fmt.Sprintf("apiPath file not exist, %s", apiPath))
 }
 
+// CachedFileComplete 文件在该commit下的paths-info已缓存且blob已完整缓存，返回paths-info的缓存时间。
+func (f *FileDao) CachedFileComplete(repoType, orgRepo, commit, fileName, authorization string) (bool, time.Time) {
+	apiPathInfoPath := util.ApiPathsInfoFile(util.GetApiRoot(authorization), repoType, orgRepo, commit, fileName)
+	info, err := os.Stat(apiPathInfoPath)
+	if err != nil {
+		return false, time.Time{}
+	}
+	cacheContent, err := f.ReadCacheRequest(apiPathInfoPath)
+	if err != nil || cacheContent.Version != consts.VersionSnapshot {
+		return false, time.Time{}
+	}
+	pathsInfos := make([]common.PathsInfo, 0)
+	if err = sonic.Unmarshal(cacheContent.OriginContent, &pathsInfos); err != nil || len(pathsInfos) == 0 {
+		return false, time.Time{}
+	}
+	pathInfo := pathsInfos[0]
+	if pathInfo.Type == "directory" || pathInfo.Size > consts.MAX_HTTP_DOWNLOAD_SIZE {
+		return false, time.Time{}
+	}
+	etag := pathInfo.Oid
+	if pathInfo.Lfs.Oid != "" {
+		etag = pathInfo.Lfs.Oid
+	}
+	if pathInfo.Size > 0 {
+		org, repo := util.SplitOrgRepo(orgRepo)
+		if f.GetFileOffset(repoType, org, repo, etag, pathInfo.Size) < pathInfo.Size {
+			return false, time.Time{}
+		}
+	}
+	return true, info.ModTime()
+}
+
+// FallbackRevision 在本地缓存的其它版本中查找已完整缓存fileName的版本，返回其中最近缓存的commit，
+// 用于请求的版本未缓存且远端不可用时以相邻版本的文件响应（如main分支已移动但权重文件未变化）。
+func (f *FileDao) FallbackRevision(repoType, orgRepo, commit, fileName, authorization string) (string, bool) {
+	pathsInfoDir := filepath.Join(util.ApiRepoDir(util.GetApiRoot(authorization), repoType, orgRepo), "paths-info")
+	entries, err := os.ReadDir(pathsInfoDir)
+	if err != nil {
+		return "", false
+	}
+	var (
+		fallback string
+		newest   time.Time
+	)
+	for _, entry := range entries {
+		sibling := util.CacheName(entry.Name())
+		if !entry.IsDir() || sibling == commit {
+			continue
+		}
+		if ok, modTime := f.CachedFileComplete(repoType, orgRepo, sibling, fileName, authorization); ok && modTime.After(newest) {
+			fallback, newest = sibling, modTime
+		}
+	}
+	return fallback, fallback != ""
+}
+
+// RepoCard 返回仓库该版本的许可证及gated标记，优先取解析commit时记录的结果，其次读取已缓存的元数据，均不可用时返回nil。
+func (f *FileDao) RepoCard(repoType, orgRepo, commitSha, authorization string) *util.RepoCard {
+	cardKey := GetRepoCardKey(repoType, orgRepo, commitSha)
+	if v, ok := f.baseData.Cache.Get(cardKey); ok {
+		return v.(*util.RepoCard)
+	}
+	apiPath := util.ApiMetaFile(util.GetApiRoot(authorization), repoType, orgRepo, commitSha, consts.RequestTypeGet)
+	if !util.CacheFileExists(apiPath) {
+		return nil
+	}
+	cacheContent, err := f.ReadCacheRequest(apiPath)
+	if err != nil {
+		zap.S().Warnf("read meta %s err.%v", apiPath, err)
+		return nil
+	}
+	card, err := util.ParseRepoCard(cacheContent.OriginContent)
+	if err != nil {
+		zap.S().Warnf("parse repo card %s err.%v", apiPath, err)
+		return nil
+	}
+	f.baseData.Cache.Set(cardKey, card, config.SysConfig.GetDefaultExpiration())
+	return card
+}
+
+// CheckRepoPolicy 向客户端提供文件前按许可证及gated策略检查仓库。
+func (f *FileDao) CheckRepoPolicy(repoType, orgRepo, commitSha, authorization string) error {
+	if !config.SysConfig.Policy.Enabled {
+		return nil
+	}
+	return util.CheckRepoPolicy(repoType, orgRepo, f.RepoCard(repoType, orgRepo, commitSha, authorization))
+}
+
+// GitRefs 远端refs接口的响应，pullRequests仅在include_prs时返回。
+type GitRefs struct {
+	Branches     []GitRef `json:"branches"`
+	Tags         []GitRef `json:"tags"`
+	Converts     []GitRef `json:"converts"`
+	PullRequests []GitRef `json:"pullRequests"`
+}
+
+type GitRef struct {
+	Name         string `json:"name"`
+	Ref          string `json:"ref"`
+	TargetCommit string `json:"targetCommit"`
+}
+
+// refsCommitOffline 在已缓存的refs中查找revision对应的commit，revision可以是名称（main、v1.0）或完整的ref（refs/pr/5）。
+func (f *FileDao) refsCommitOffline(apiRoot, repoType, orgRepo, revision string) (string, bool) {
+	refsPath := util.ApiRefsFile(apiRoot, repoType, orgRepo)
+	if !util.CacheFileExists(refsPath) {
+		return "", false
+	}
+	cacheContent, err := f.ReadCacheRequest(refsPath)
+	if err != nil {
+		zap.S().Warnf("read refs %s err.%v", refsPath, err)
+		return "", false
+	}
+	var refs GitRefs
+	if err = sonic.Unmarshal(cacheContent.OriginContent, &refs); err != nil {
+		zap.S().Warnf("unmarshal refs %s err.%v", refsPath, err)
+		return "", false
+	}
+	for _, group := range [][]GitRef{refs.Branches, refs.Tags, refs.Converts, refs.PullRequests} {
+		for _, ref := range group {
+			if ref.TargetCommit != "" && (ref.Name == revision || ref.Ref == revision) {
+				return ref.TargetCommit, true
+			}
+		}
+	}
+	return "", false
+}
+
 func (f *FileDao) FileGetGenerator(c echo.Context, repoType, orgRepo, commit, fileName, method string) error {
 	var hfUri string
 	if repoType == "models" {
@@ -182,12 +421,8 @@ func (f *FileDao) FileGetGenerator(c echo.Context, repoType, orgRepo, commit, fi
 	// _file_realtime_stream
 	pathInfo, err := f.GetPathsInfo(hfUri, repoType, orgRepo, commit, authorization, fileName)
 	if err != nil {
-		if e, ok := err.(myerr.Error); ok {
-			zap.S().Warnf("GetPathsInfo code:%d, err:%v", e.StatusCode(), err)
-			return util.ErrorEntryUnknown(c, e.StatusCode(), e.Error())
-		}
-		zap.S().Errorf("GetPathsInfo err:%v", err)
-		return util.ErrorProxyError(c)
+		zap.S().Warnf("GetPathsInfo err:%v", err)
+		return util.ResponseHfError(c, err)
 	}
 	if pathInfo == nil {
 		zap.S().Errorf("pathsInfos is null. repo:%s, commit:%s, fileName:%s", orgRepo, commit, fileName)
@@ -197,14 +432,17 @@ func (f *FileDao) FileGetGenerator(c echo.Context, repoType, orgRepo, commit, fi
 		zap.S().Warnf("repo:%s, commit:%s, fileName:%s is directory", orgRepo, commit, fileName)
 		return util.ErrorEntryNotFound(c)
 	}
-	respHeaders, etag, startPos, endPos := constructRespHeader(c, pathInfo, commit, fileName)
-	blobsDir := fmt.Sprintf("%s/files/%s/%s/blobs", config.SysConfig.Repos(), repoType, orgRepo)
-	blobsFile := fmt.Sprintf("%s/%s", blobsDir, etag)
-	filesDir := fmt.Sprintf("%s/files/%s/%s/resolve/%s", config.SysConfig.Repos(), repoType, orgRepo, commit)
-	filesPath := fmt.Sprintf("%s/%s", filesDir, fileName)
+	downloader.RecordResolve(repoType, orgRepo, fileName)
+	respHeaders, etag, startPos, endPos := constructRespHeader(c, pathInfo, repoType, orgRepo, commit, fileName)
+	if err = f.checkMalwareScan(c, repoType, orgRepo, commit, fileName, etag, method, pathInfo.Size); err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	blobsFile := util.BlobsFile(repoType, orgRepo, etag)
+	filesPath := util.ResolveFile(repoType, orgRepo, commit, fileName)
 	if err = f.ConstructBlobsAndFileFile(blobsFile, filesPath); err != nil {
 		return util.ErrorProxyError(c)
 	}
+	f.traceFileCache(c, repoType, orgRepo, etag, hfUri, blobsFile, pathInfo.Size)
 	if method == consts.RequestTypeHead {
 		return util.ResponseHeaders(c, http.StatusOK, respHeaders)
 	} else if method == consts.RequestTypeGet {
@@ -219,13 +457,188 @@ func (f *FileDao) FileGetGenerator(c echo.Context, repoType, orgRepo, commit, fi
 			DataType:      repoType,
 			Etag:          etag,
 		}
-		return f.FileChunkGet(c, taskParam, startPos, endPos, respHeaders)
+		if config.SysConfig.Online() {
+			if err = util.CheckDiskSpace(blobsFile, pathInfo.Size); err != nil {
+				return util.ResponseHfError(c, err)
+			}
+		}
+		if c.Request().Header.Get("Range") != "" && c.Request().Header.Get(consts.RequestSourceInner) != "1" {
+			f.readAhead(taskParam, util.ReadAheadSize(c.RealIP()), startPos, endPos)
+		}
+		var token string
+		token, startPos = f.resumeDownload(c, etag, pathInfo.Size, startPos, endPos, respHeaders)
+		err = f.FileChunkGet(c, taskParam, startPos, endPos, respHeaders)
+		if token != "" {
+			f.saveResumePosition(token, etag, startPos+c.Response().Size, endPos)
+		}
+		return err
 	} else {
 		return util.ErrorMethodError(c)
 	}
 }
 
-func constructRespHeader(c echo.Context, pathInfo *common.PathsInfo, commit, fileName string) (map[string]string, string, int64, int64) {
+// traceFileCache 按blob是否已完整缓存记录X-Cache的取值。
+func (f *FileDao) traceFileCache(c echo.Context, repoType, orgRepo, etag, hfUri, blobsFile string, fileSize int64) {
+	trace := util.CacheTraceFrom(c.Request().Context())
+	if trace == nil {
+		return
+	}
+	org, repo := util.SplitOrgRepo(orgRepo)
+	if fileSize <= 0 || f.GetFileOffset(repoType, org, repo, etag, fileSize) >= fileSize {
+		var cachedAt time.Time
+		if info, err := os.Stat(blobsFile); err == nil {
+			cachedAt = info.ModTime()
+		}
+		trace.Hit(blobsFile, cachedAt)
+		return
+	}
+	trace.Miss(blobsFile, util.UpstreamFor(hfUri))
+}
+
+// resumeMargin 续传时从记录位置回退的字节数，记录的是写入连接的字节数，其中仍在发送缓冲区中的部分客户端未必收到。
+const resumeMargin = 8 << 20
+
+// resumeState 续传令牌对应的文件及已发送给客户端的位置
+type resumeState struct {
+	etag string
+	pos  int64
+}
+
+// resumeDownload 大文件不带Range的下载签发续传令牌，客户端在请求头中携带仍有效的令牌重连时，以206从记录的位置回退resumeMargin处继续，
+// 返回令牌及实际的起始位置。只接受显式的请求头，避免同一文件有意的重新下载被截断。
+func (f *FileDao) resumeDownload(c echo.Context, etag string, fileSize, startPos, endPos int64, respHeaders map[string]string) (string, int64) {
+	minSize := config.SysConfig.Download.ResumeToken.MinSize
+	req := c.Request()
+	if minSize <= 0 || fileSize < minSize || req.Header.Get("Range") != "" || req.Header.Get(consts.RequestSourceInner) == "1" {
+		return "", startPos
+	}
+	token := req.Header.Get(consts.HeaderResumeToken)
+	if v, ok := f.baseData.Cache.Get(GetResumeTokenKey(token)); token != "" && ok {
+		state := v.(*resumeState)
+		if pos := state.pos - resumeMargin; state.etag == etag && pos > startPos && pos < endPos {
+			zap.S().Infof("resume download of %s from %d by token", etag, pos)
+			startPos = pos
+			respHeaders[consts.HUGGINGFACE_HEADER_CONTENT_LENGTH] = util.Itoa(endPos - startPos)
+			respHeaders["content-range"] = fmt.Sprintf("bytes %d-%d/%d", startPos, endPos-1, fileSize)
+		}
+	} else {
+		token = util.UUID()
+	}
+	respHeaders[consts.HeaderResumeToken] = token
+	return token, startPos
+}
+
+// saveResumePosition 记录令牌已发送到的位置，传输完成后令牌失效。
+func (f *FileDao) saveResumePosition(token, etag string, pos, endPos int64) {
+	key := GetResumeTokenKey(token)
+	if pos >= endPos {
+		f.baseData.Cache.Delete(key)
+		return
+	}
+	f.baseData.Cache.Set(key, &resumeState{etag: etag, pos: pos}, config.SysConfig.GetResumeTokenExpiration())
+}
+
+// checkMalwareScan 开启恶意文件扫描时，匹配的文件完整缓存并扫描通过后才提供给客户端，未完整缓存时在后台下载并扫描，期间返回503。
+func (f *FileDao) checkMalwareScan(c echo.Context, repoType, orgRepo, commit, fileName, etag, method string, fileSize int64) error {
+	if !config.SysConfig.MalwareScan.Enabled || fileSize <= 0 || !util.MalwareScanMatch(fileName) {
+		return nil
+	}
+	if verdict, ok := util.LoadScanVerdict(etag); ok {
+		return scanVerdictError(verdict)
+	}
+	if method == consts.RequestTypeHead {
+		return nil
+	}
+	org, repo := util.SplitOrgRepo(orgRepo)
+	if f.GetFileOffset(repoType, org, repo, etag, fileSize) >= fileSize {
+		verdict, err := f.scanBlob(repoType, orgRepo, fileName, etag)
+		if err != nil {
+			return scanFailedError(fileName, err)
+		}
+		return scanVerdictError(verdict)
+	}
+	if !config.SysConfig.Online() {
+		return nil // 离线时由下载流程返回缓存未命中
+	}
+	authorization := c.Request().Header.Get("authorization")
+	go f.downloadAndScan(repoType, orgRepo, commit, fileName, etag, authorization)
+	c.Response().Header().Set("Retry-After", "30")
+	return myerr.NewKind(myerr.KindUnavailable, fmt.Sprintf("%s is being downloaded and scanned, retry later", fileName))
+}
+
+func (f *FileDao) downloadAndScan(repoType, orgRepo, commit, fileName, etag, authorization string) {
+	_, _, _ = f.scanGroup.Do("download/"+etag, func() (interface{}, error) {
+		if _, err := f.PrefetchFile(context.Background(), repoType, orgRepo, commit, fileName, authorization, 0); err != nil {
+			zap.S().Errorf("download %s/%s for malware scan err.%v", orgRepo, fileName, err)
+			return nil, err
+		}
+		return f.scanBlob(repoType, orgRepo, fileName, etag)
+	})
+}
+
+// scanBlob 扫描已完整缓存的blob并记录结果，感染的blob移入隔离目录。
+func (f *FileDao) scanBlob(repoType, orgRepo, fileName, etag string) (*util.ScanVerdict, error) {
+	v, err, _ := f.scanGroup.Do("scan/"+etag, func() (interface{}, error) {
+		if verdict, ok := util.LoadScanVerdict(etag); ok {
+			return verdict, nil
+		}
+		scanner := util.NewMalwareScanner()
+		if scanner == nil {
+			return nil, errors.New("neither clamd nor command is configured")
+		}
+		blobsFile := util.BlobsFile(repoType, orgRepo, etag)
+		reader, err := downloader.OpenCachedFile(blobsFile)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), config.SysConfig.GetMalwareScanTimeout())
+		defer cancel()
+		start := time.Now()
+		infected, signature, err := scanner.Scan(ctx, fileName, reader)
+		reader.Close()
+		if err != nil {
+			prom.MalwareScanCnt.WithLabelValues("error").Inc()
+			return nil, err
+		}
+		verdict := &util.ScanVerdict{Etag: etag, Datatype: repoType, OrgRepo: orgRepo, FileName: fileName, Infected: infected, Signature: signature}
+		if infected {
+			prom.MalwareScanCnt.WithLabelValues("infected").Inc()
+			dst, err := util.QuarantineBlob(repoType, orgRepo, etag)
+			if err != nil {
+				zap.S().Errorf("quarantine %s/%s err.%v", orgRepo, fileName, err)
+			}
+			zap.S().Warnf("%s/%s/%s is infected with %s, quarantined to %s", repoType, orgRepo, fileName, signature, dst)
+		} else {
+			prom.MalwareScanCnt.WithLabelValues("clean").Inc()
+			zap.S().Infof("%s/%s/%s is scanned clean in %s", repoType, orgRepo, fileName, time.Since(start).Round(time.Millisecond))
+		}
+		if err = util.SaveScanVerdict(verdict); err != nil {
+			zap.S().Errorf("save scan verdict of %s/%s err.%v", orgRepo, fileName, err)
+		}
+		return verdict, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*util.ScanVerdict), nil
+}
+
+func scanVerdictError(verdict *util.ScanVerdict) error {
+	if !verdict.Infected {
+		return nil
+	}
+	return myerr.NewAppendCode(http.StatusForbidden, fmt.Sprintf("%s is quarantined, malware detected: %s", verdict.FileName, verdict.Signature))
+}
+
+func scanFailedError(fileName string, err error) error {
+	zap.S().Errorf("malware scan %s err.%v", fileName, err)
+	if config.SysConfig.MalwareScan.OnError == "allow" {
+		return nil
+	}
+	return myerr.NewKind(myerr.KindUnavailable, fmt.Sprintf("malware scan of %s failed", fileName))
+}
+
+func constructRespHeader(c echo.Context, pathInfo *common.PathsInfo, repoType, orgRepo, commit, fileName string) (map[string]string, string, int64, int64) {
 	var startPos, endPos int64
 	if pathInfo.Size > 0 { // There exists a file of size 0
 		var headRange = c.Request().Header.Get("Range")
@@ -242,15 +655,14 @@ func constructRespHeader(c echo.Context, pathInfo *common.PathsInfo, commit, fil
 	if commit != "" {
 		respHeaders[strings.ToLower(consts.HUGGINGFACE_HEADER_X_REPO_COMMIT)] = commit
 	}
-	var etag string
-	if pathInfo.Lfs.Oid != "" {
-		etag = pathInfo.Lfs.Oid
-	} else {
-		etag = pathInfo.Oid
-	}
-	respHeaders[consts.HUGGINGFACE_HEADER_X_LINKED_ETAG] = etag
+	etag := PathsInfoEtag(pathInfo)
+	// 与huggingface.co保持一致，etag带双引号，客户端会去掉引号后做完整性校验。
+	respHeaders[consts.HUGGINGFACE_HEADER_ETAG] = fmt.Sprintf("\"%s\"", etag)
+	respHeaders[consts.HUGGINGFACE_HEADER_X_LINKED_ETAG] = fmt.Sprintf("\"%s\"", etag)
 	respHeaders[consts.HUGGINGFACE_HEADER_X_LINKED_SIZE] = util.Itoa(pathInfo.Size)
-	if pathInfo.Location != "" {
+	respHeaders[consts.HUGGINGFACE_HEADER_ACCEPT_RANGES] = "bytes"
+	respHeaders[consts.HUGGINGFACE_HEADER_CONTENT_DISPOSITION] = contentDisposition(fileName)
+	if serving, _ := c.Get(consts.CdnCacheServing).(bool); pathInfo.Location != "" && !serving {
 		// clientHost := c.Request().Host
 		// clientScheme := "http"
 		// if c.Request().TLS != nil {
@@ -258,13 +670,18 @@ func constructRespHeader(c echo.Context, pathInfo *common.PathsInfo, commit, fil
 		// }
 		// hfEndPoint := fmt.Sprintf("%s://%s", clientScheme, clientHost)
 		// loc := strings.ReplaceAll(pathInfo.Location, config.SysConfig.GetXetURLBase(), hfEndPoint)
-		respHeaders[consts.HUGGINGFACE_LOCATION] = pathInfo.Location
+		respHeaders[consts.HUGGINGFACE_LOCATION] = util.CdnCacheLocation(c, repoType, orgRepo, commit, fileName, pathInfo.Location)
 		respHeaders[consts.HUGGINGFACE_HEADER_X_XET_HASH] = pathInfo.XXetHash
 		respHeaders[consts.HUGGINGFACE_Link] = pathInfo.Link
 	}
 	return respHeaders, etag, startPos, endPos
 }
 
+func contentDisposition(fileName string) string {
+	name := path.Base(fileName)
+	return fmt.Sprintf("inline; filename*=UTF-8''%s; filename=\"%s\";", url.PathEscape(name), name)
+}
+
 func GetAnalysisFilePosition(dingFile *downloader.DingCache, startPos, endPos int64) int64 {
 	_, offset := analysisFilePosition(dingFile, startPos, endPos)
 	return offset
@@ -279,7 +696,7 @@ func (f *FileDao) ConstructBlobsAndFileFile(blobsFile, filesPath string) (err er
 		zap.S().Errorf("create %s dir err.%v", filesPath, err)
 		return err
 	}
-	if exist := util.FileExists(filesPath); exist {
+	if exist := util.CacheFileExists(filesPath); exist {
 		if b, localErr := util.IsSymlink(filesPath); localErr != nil {
 			zap.S().Errorf("IsSymlink %s err.%v", filesPath, localErr)
 			return err
@@ -318,7 +735,7 @@ func (f *FileDao) GetPathsInfo(hfUri, repoType, orgRepo, commit, authorization s
 	if pathFileName == "" {
 		return nil, fmt.Errorf("pathFileName is null, %s/%s", orgRepo, commit)
 	}
-	apiPathInfoPath := fmt.Sprintf("%s/api/%s/%s/paths-info/%s/%s/paths-info_post.json", config.SysConfig.Repos(), repoType, orgRepo, commit, pathFileName)
+	apiPathInfoPath := util.ApiPathsInfoFile(util.GetApiRoot(authorization), repoType, orgRepo, commit, pathFileName)
 	// 对每个用户检测是否有权限，在线、离线都检测，都需要携带token。
 	filePathInfoKey := GetFilePathInfoKey(repoType, orgRepo, authorization)
 	_, granted := f.baseData.Cache.Get(filePathInfoKey)
@@ -379,10 +796,113 @@ requestRemoteFileInfo:
 		if err = f.WriteCacheRequest(apiPathInfoPath, response.StatusCode, response.ExtractHeaders(response.Headers), b); err != nil {
 			return nil, fmt.Errorf("WriteCacheRequest err.%s,%v", apiPathInfoPath, err)
 		}
+		f.baseData.Cache.Delete(GetRepoFilesIndexKey(repoType, orgRepo, commit))
 	}
 	return pathInfo, nil
 }
 
+// ReadFileRange 读取文件[start,end]范围内的内容，优先读取本地缓存，缓存不完整且在线时通过range请求远端，返回内容及文件大小。
+func (f *FileDao) ReadFileRange(ctx context.Context, repoType, orgRepo, commit, fileName, authorization string, start, end int64) ([]byte, int64, error) {
+	commitSha, err := f.GetFileCommitSha(ctx, repoType, orgRepo, commit, authorization, "file")
+	if err != nil {
+		return nil, 0, err
+	}
+	filesPath := util.ResolveFile(repoType, orgRepo, commitSha, fileName)
+	if util.CacheFileExists(filesPath) {
+		if content, fileSize, ok := downloader.ReadCachedRange(filesPath, start, end); ok {
+			return content, fileSize, nil
+		}
+	}
+	if !config.SysConfig.Online() {
+		return nil, 0, util.OfflineMissError(myerr.NewErrorCode(http.StatusNotFound, consts.HfErrorCodeEntryNotFound, fmt.Sprintf("%s is not cached", fileName)))
+	}
+	var hfUri string
+	if repoType == "models" {
+		hfUri = fmt.Sprintf("/%s/resolve/%s/%s", orgRepo, commitSha, fileName)
+	} else {
+		hfUri = fmt.Sprintf("/%s/%s/resolve/%s/%s", repoType, orgRepo, commitSha, fileName)
+	}
+	headers := map[string]string{"range": fmt.Sprintf("bytes=%d-%d", start, end)}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+	resp, err := util.GetContext(ctx, hfUri, headers)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, 0, myerr.NewErrorCode(resp.StatusCode, resp.GetKey(consts.HfHeaderErrorCode), fmt.Sprintf("request %s err", fileName))
+	}
+	content, fileSize := resp.Body, int64(len(resp.Body))
+	if resp.StatusCode == http.StatusPartialContent {
+		// content-range: bytes 0-99/12345
+		contentRange := resp.GetKey("content-range")
+		if idx := strings.LastIndex(contentRange, "/"); idx >= 0 {
+			fileSize = util.Atoi64(contentRange[idx+1:])
+		}
+	} else if start < fileSize {
+		content = content[start:min(end+1, fileSize)]
+	} else {
+		content = nil
+	}
+	return content, fileSize, nil
+}
+
+// FileRangeReader 按块顺序读取文件，用于只需读取文件头的场景，避免下载整个文件。
+type FileRangeReader struct {
+	ctx           context.Context
+	fileDao       *FileDao
+	repoType      string
+	orgRepo       string
+	commit        string
+	fileName      string
+	authorization string
+	pos           int64
+	fileSize      int64
+	chunkSize     int64
+	buf           []byte
+}
+
+func (f *FileDao) NewFileRangeReader(ctx context.Context, repoType, orgRepo, commit, fileName, authorization string, chunkSize int64) *FileRangeReader {
+	return &FileRangeReader{
+		ctx:           ctx,
+		fileDao:       f,
+		repoType:      repoType,
+		orgRepo:       orgRepo,
+		commit:        commit,
+		fileName:      fileName,
+		authorization: authorization,
+		fileSize:      -1,
+		chunkSize:     chunkSize,
+	}
+}
+
+func (r *FileRangeReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.fileSize >= 0 && r.pos >= r.fileSize {
+			return 0, io.EOF
+		}
+		content, fileSize, err := r.fileDao.ReadFileRange(r.ctx, r.repoType, r.orgRepo, r.commit, r.fileName, r.authorization, r.pos, r.pos+r.chunkSize-1)
+		if err != nil {
+			return 0, err
+		}
+		r.fileSize = fileSize
+		if len(content) == 0 {
+			return 0, io.EOF
+		}
+		r.pos += int64(len(content))
+		r.buf = content
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// FileSize 返回文件大小，首次读取前为-1
+func (r *FileRangeReader) FileSize() int64 {
+	return r.fileSize
+}
+
 func (f *FileDao) requestFileResolve(fileResolveUri, authorization string) (*common.Response, error) {
 	headers := map[string]string{}
 	if authorization != "" {
@@ -404,7 +924,7 @@ func (f *FileDao) requestFileResolve(fileResolveUri, authorization string) (*com
 				return nil, myerr.NewAppendCode(response.StatusCode, fmt.Sprintf("response code %d, %v", response.StatusCode, err))
 			}
 		}
-		return nil, myerr.NewAppendCode(response.StatusCode, errorResp.Error)
+		return nil, myerr.NewErrorCode(response.StatusCode, response.GetKey(consts.HfHeaderErrorCode), errorResp.Error)
 	}
 	return response, nil
 }
@@ -434,41 +954,151 @@ func (f *FileDao) requestFilePathInfo(pathsInfoUri, authorization string, filePa
 				return nil, myerr.NewAppendCode(response.StatusCode, fmt.Sprintf("response code %d, %v", response.StatusCode, err))
 			}
 		}
-		return nil, myerr.NewAppendCode(response.StatusCode, errorResp.Error)
+		return nil, myerr.NewErrorCode(response.StatusCode, response.GetKey(consts.HfHeaderErrorCode), errorResp.Error)
+	} else if err = util.CheckPathsInfoBody(response.ExtractHeaders(response.Headers), response.Body); err != nil {
+		// 不是有效的paths-info时不交给调用方缓存
+		zap.S().Errorf("req %s err.%v", pathsInfoUri, err)
+		return nil, err
 	} else {
 		return response, nil
 	}
 }
 
 func (f *FileDao) FileChunkGet(c echo.Context, taskParam *downloader.TaskParam, startPos, endPos int64, respHeaders map[string]string) error {
-	responseChan := make(chan []byte, config.SysConfig.Download.RespChanSize)
 	source := util.Itoa(c.Get(consts.PromSource))
-	bgCtx := context.WithValue(c.Request().Context(), consts.PromSource, source)
+	reqCtx := c.Request().Context()
+	// 下载任务不直接继承请求的取消信号，客户端断开时由onDisconnect决定取消还是在后台继续完成。
+	bgCtx := context.WithValue(context.WithoutCancel(reqCtx), consts.PromSource, source)
 	ctx, cancel := context.WithCancel(bgCtx)
-	defer func() {
-		cancel()
-	}()
 	var isInnerRequest bool
 	if value := c.Request().Header.Get(consts.RequestSourceInner); value == "1" {
 		isInnerRequest = true
 	}
 	taskParam.Context = ctx
-	taskParam.ResponseChan = responseChan
+	taskParam.FromClient = true
+	stream := common.NewStreamPipe(ctx)
+	taskParam.Stream = stream
 	taskParam.Cancel = cancel
 	fileErrCh := make(chan error, 1)
 	fileName := fmt.Sprintf("%s/%s", taskParam.OrgRepo, taskParam.FileName)
+	var once sync.Once
+	onDisconnect := func() {
+		once.Do(func() {
+			sent := stream.Written()
+			if config.SysConfig.CompleteOnDisconnect(sent, endPos-startPos) {
+				stream.Detach()
+				zap.S().Infof("client disconnected, complete %s in background, sent:%d, range:%d-%d", fileName, sent, startPos, endPos)
+				return
+			}
+			cancel()
+		})
+	}
+	stop := context.AfterFunc(reqCtx, onDisconnect)
+	defer func() {
+		stop()
+		once.Do(cancel) // 未转入后台完成时结束下载任务
+	}()
 	go f.downloaderDao.FileDownload(fileErrCh, startPos, endPos, isInnerRequest, taskParam)
-	if err := util.ResponseStream(ctx, c, fileName, respHeaders, responseChan, fileErrCh); err != nil {
+	// 响应头带content-range时（如续传）以206响应
+	code := http.StatusOK
+	if respHeaders["content-range"] != "" {
+		code = http.StatusPartialContent
+	}
+	if err := util.ResponseStreamStatus(reqCtx, c, fileName, code, respHeaders, stream, fileErrCh); err != nil {
 		zap.S().Errorf("FileChunkGet stream err.%v", err)
+		onDisconnect()
 		return util.ErrorProxyTimeout(c)
 	}
 	return nil
 }
 
+// readAhead 顺序读取文件时（本次range紧接上一次range，或从头开始读），在后台预先拉取客户端读取位置之后size字节，
+// 使后续请求直接命中缓存而不必等待远端。
+func (f *FileDao) readAhead(taskParam *downloader.TaskParam, size, startPos, endPos int64) {
+	if size <= 0 || endPos >= taskParam.FileSize {
+		return
+	}
+	key := GetReadAheadKey(taskParam.BlobsFile)
+	f.readAheadMu.Lock()
+	state := &readAheadState{}
+	if v, ok := f.baseData.Cache.Get(key); ok {
+		state = v.(*readAheadState)
+	}
+	sequential := startPos == 0 || startPos == state.nextPos
+	state.nextPos = endPos
+	target := min(endPos+size, taskParam.FileSize)
+	aheadStart := max(endPos, state.aheadEnd)
+	if sequential && aheadStart < target {
+		state.aheadEnd = target
+	}
+	f.baseData.Cache.Set(key, state, consts.ReadAheadExpiration)
+	f.readAheadMu.Unlock()
+	if !sequential || aheadStart >= target {
+		return
+	}
+	go f.fetchAhead(taskParam, aheadStart, target)
+}
+
+// fetchAhead 将[startPos, endPos)按块对齐后下载到缓存，已缓存的前缀部分直接跳过。
+func (f *FileDao) fetchAhead(param *downloader.TaskParam, startPos, endPos int64) {
+	blockSize := config.SysConfig.Download.BlockSize
+	startPos = startPos / blockSize * blockSize
+	endPos = min((endPos+blockSize-1)/blockSize*blockSize, param.FileSize)
+	dingCacheManager := downloader.GetInstance()
+	dingFile, err := dingCacheManager.GetDingFile(param.BlobsFile, param.FileSize)
+	if err != nil {
+		zap.S().Errorf("read ahead GetDingFile err.%v", err)
+		return
+	}
+	complete, curPos := analysisFilePosition(dingFile, startPos, endPos)
+	dingCacheManager.ReleasedDingFile(param.BlobsFile)
+	if complete {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), consts.PromSource, "readahead"))
+	defer cancel()
+	if common.DownloadLimiter != nil {
+		if err = common.DownloadLimiter.Acquire(ctx, consts.PriorityLow); err != nil {
+			return
+		}
+		defer common.DownloadLimiter.Release()
+	}
+	// 预读没有读取方，数据只写入缓存
+	stream := common.NewStreamPipe(ctx)
+	stream.Detach()
+	taskParam := &downloader.TaskParam{
+		Context:       ctx,
+		Cancel:        cancel,
+		BlobsFile:     param.BlobsFile,
+		FileName:      param.FileName,
+		FileSize:      param.FileSize,
+		Stream:        stream,
+		OrgRepo:       param.OrgRepo,
+		Authorization: param.Authorization,
+		Uri:           param.Uri,
+		Origin:        param.Origin,
+		Domain:        param.Domain,
+		DataType:      param.DataType,
+		Etag:          param.Etag,
+	}
+	zap.S().Debugf("read ahead %s/%s, range:%d-%d", param.OrgRepo, param.FileName, curPos, endPos)
+	fileErrCh := make(chan error, 1)
+	f.downloaderDao.FileDownload(fileErrCh, curPos, endPos, false, taskParam)
+	if err = <-fileErrCh; err != nil {
+		zap.S().Warnf("read ahead %s/%s err.%v", param.OrgRepo, param.FileName, err)
+	}
+}
+
 func (f *FileDao) WriteCacheRequest(apiPath string, statusCode int, headers map[string]string, content []byte) error {
 	lock := f.lockDao.getMetaFileLock(apiPath)
 	lock.Lock()
 	defer lock.Unlock()
+	// 进程内读写锁之外再加文件锁，避免多个进程同时写入同一个元数据文件。
+	unlock, err := util.LockFile(apiPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	cacheContent := common.CacheContent{
 		Version:    consts.VersionSnapshot,
 		StatusCode: statusCode,
@@ -478,11 +1108,122 @@ func (f *FileDao) WriteCacheRequest(apiPath string, statusCode int, headers map[
 	return util.WriteDataToFile(apiPath, cacheContent)
 }
 
+// WriteCacheRequestStream 与WriteCacheRequest格式相同，响应体边读取边以hex编码写入，不在内存中缓冲。
+func (f *FileDao) WriteCacheRequestStream(apiPath string, statusCode int, headers map[string]string, body io.Reader) error {
+	prefix, err := sonic.Marshal(common.CacheContent{
+		Version:    consts.VersionSnapshot,
+		StatusCode: statusCode,
+		Headers:    headers,
+	})
+	if err != nil {
+		return err
+	}
+	// content为最后一个字段，去掉结尾的"}后接着写入编码后的内容
+	if !bytes.HasSuffix(prefix, []byte(`""}`)) {
+		return myerr.New(fmt.Sprintf("unexpected cache content format: %s", prefix))
+	}
+	prefix = prefix[:len(prefix)-2]
+	lock := f.lockDao.getMetaFileLock(apiPath)
+	lock.Lock()
+	defer lock.Unlock()
+	unlock, err := util.LockFile(apiPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return util.WriteStreamToFile(apiPath, func(w io.Writer) error {
+		if _, err := w.Write(prefix); err != nil {
+			return err
+		}
+		if _, err := io.Copy(hex.NewEncoder(w), body); err != nil {
+			return err
+		}
+		_, err := w.Write([]byte(`"}`))
+		return err
+	})
+}
+
+// LinkCacheRequest 以硬链接的方式将元数据缓存文件共享给其他版本目录，同一响应只写入一次，各版本内容不会出现不一致。
+// 文件系统不支持硬链接时退化为以流的方式复制。
+func (f *FileDao) LinkCacheRequest(srcPath, dstPath string) error {
+	srcLock := f.lockDao.getMetaFileLock(srcPath)
+	srcLock.RLock()
+	defer srcLock.RUnlock()
+	dstLock := f.lockDao.getMetaFileLock(dstPath)
+	dstLock.Lock()
+	defer dstLock.Unlock()
+	unlock, err := util.LockFile(dstPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = util.LinkFile(srcPath, dstPath); err == nil {
+		return nil
+	} else if os.IsNotExist(err) {
+		return err
+	}
+	zap.S().Debugf("link %s to %s err.%v, fallback to copy", srcPath, dstPath, err)
+	src, err := util.OpenCacheFile(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return util.WriteStreamToFile(dstPath, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+// RewriteCacheFile 按当前的压缩配置重写元数据缓存文件，links为与之硬链接的其他路径，重写后重新链接以继续共享，
+// 返回节省的字节数，由压缩改为不压缩时为负数。
+func (f *FileDao) RewriteCacheFile(apiPath string, links []string) (int64, error) {
+	saved, err := f.rewriteCacheFile(apiPath)
+	if err != nil {
+		return 0, err
+	}
+	for _, link := range links {
+		if err = f.LinkCacheRequest(apiPath, link); err != nil {
+			return saved, err
+		}
+	}
+	return saved, nil
+}
+
+func (f *FileDao) rewriteCacheFile(apiPath string) (int64, error) {
+	lock := f.lockDao.getMetaFileLock(apiPath)
+	lock.Lock()
+	defer lock.Unlock()
+	unlock, err := util.LockFile(apiPath)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	info, err := os.Stat(apiPath)
+	if err != nil {
+		return 0, err
+	}
+	data, err := util.ReadCacheFile(apiPath)
+	if err != nil {
+		return 0, err
+	}
+	if err = util.WriteStreamToFile(apiPath, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	newInfo, err := os.Stat(apiPath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size() - newInfo.Size(), nil
+}
+
 func (f *FileDao) ExistApiPathFile(apiPath string) bool {
 	lock := f.lockDao.getMetaFileLock(apiPath)
 	lock.RLock()
 	defer lock.RUnlock()
-	return util.FileExists(apiPath)
+	return util.CacheFileExists(apiPath)
 }
 
 func (f *FileDao) ReadCacheRequest(apiPath string) (*common.CacheContent, error) {
@@ -490,7 +1231,7 @@ func (f *FileDao) ReadCacheRequest(apiPath string) (*common.CacheContent, error)
 	lock.RLock()
 	defer lock.RUnlock()
 	cacheContent := common.CacheContent{}
-	bytes, err := util.ReadFileToBytes(apiPath)
+	bytes, err := util.ReadCacheFile(apiPath)
 	if err != nil {
 		return nil, myerr.Wrap("ReadFileToBytes err.", err)
 	}
@@ -507,9 +1248,8 @@ func (f *FileDao) ReadCacheRequest(apiPath string) (*common.CacheContent, error)
 
 func (f *FileDao) GetFileOffset(dataType string, org string, repo string, etag string, fileSize int64) int64 {
 	orgRepo := util.GetOrgRepo(org, repo)
-	blobsDir := fmt.Sprintf("%s/files/%s/%s/blobs", config.SysConfig.Repos(), dataType, orgRepo)
-	blobsFile := fmt.Sprintf("%s/%s", blobsDir, etag)
-	exists := util.FileExists(blobsFile)
+	blobsFile := util.BlobsFile(dataType, orgRepo, etag)
+	exists := util.CacheFileExists(blobsFile)
 	if !exists {
 		return 0
 	}
@@ -526,6 +1266,73 @@ func (f *FileDao) GetFileOffset(dataType string, org string, repo string, etag s
 	return curPos
 }
 
+// PrefetchFile 以后台传输方式将文件下载到缓存，已缓存完整或大于maxSize（大于0时生效）的文件直接跳过，返回本次下载的字节数。
+func (f *FileDao) PrefetchFile(ctx context.Context, repoType, orgRepo, commit, fileName, authorization string, maxSize int64) (int64, error) {
+	var hfUri string
+	if repoType == "models" {
+		hfUri = fmt.Sprintf("/%s/resolve/%s/%s", orgRepo, commit, fileName)
+	} else {
+		hfUri = fmt.Sprintf("/%s/%s/resolve/%s/%s", repoType, orgRepo, commit, fileName)
+	}
+	pathInfo, err := f.GetPathsInfo(hfUri, repoType, orgRepo, commit, authorization, fileName)
+	if err != nil {
+		return 0, err
+	}
+	if pathInfo == nil || pathInfo.Type == "directory" || pathInfo.Size == 0 {
+		return 0, nil
+	}
+	if maxSize > 0 && pathInfo.Size > maxSize {
+		return 0, nil
+	}
+	etag := pathInfo.Oid
+	if pathInfo.Lfs.Oid != "" {
+		etag = pathInfo.Lfs.Oid
+	}
+	org, repo := util.SplitOrgRepo(orgRepo)
+	offset := f.GetFileOffset(repoType, org, repo, etag, pathInfo.Size)
+	if offset >= pathInfo.Size {
+		return 0, nil
+	}
+	blobsFile := util.BlobsFile(repoType, orgRepo, etag)
+	filesPath := util.ResolveFile(repoType, orgRepo, commit, fileName)
+	if err = f.ConstructBlobsAndFileFile(blobsFile, filesPath); err != nil {
+		return 0, err
+	}
+	bgCtx, cancel := context.WithCancel(common.WithBackground(context.WithValue(ctx, consts.PromSource, "localhost")))
+	defer cancel()
+	if err = common.BackgroundLimiter.WaitWindow(bgCtx); err != nil {
+		return 0, err
+	}
+	if common.DownloadLimiter != nil {
+		if err = common.DownloadLimiter.Acquire(bgCtx, consts.PriorityLow); err != nil {
+			return 0, err
+		}
+		defer common.DownloadLimiter.Release()
+	}
+	// 预取没有读取方，数据只写入缓存
+	stream := common.NewStreamPipe(bgCtx)
+	stream.Detach()
+	taskParam := &downloader.TaskParam{
+		Context:       bgCtx,
+		Cancel:        cancel,
+		BlobsFile:     blobsFile,
+		FileName:      fileName,
+		FileSize:      pathInfo.Size,
+		Stream:        stream,
+		OrgRepo:       orgRepo,
+		Authorization: authorization,
+		Uri:           hfUri,
+		DataType:      repoType,
+		Etag:          etag,
+	}
+	fileErrCh := make(chan error, 1)
+	f.downloaderDao.FileDownload(fileErrCh, offset, pathInfo.Size, false, taskParam)
+	if err = <-fileErrCh; err != nil {
+		return 0, err
+	}
+	return pathInfo.Size - offset, bgCtx.Err()
+}
+
 func parseRangeParams(fileRange string, fileSize int64) (int64, int64) {
 	if strings.Contains(fileRange, "/") {
 		split := strings.SplitN(fileRange, "/", 2)
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dingospeed/internal/data"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+
+	"github.com/labstack/echo/v4"
+	"github.com/patrickmn/go-cache"
+)
+
+func TestResumeDownload(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Download.ResumeToken.MinSize = 1
+
+	const fileSize = 100 << 20
+	f := &FileDao{baseData: &data.BaseData{Cache: cache.New(time.Minute, time.Minute)}}
+	e := echo.New()
+	resume := func(header map[string]string, cookie string) (string, int64, map[string]string) {
+		req := httptest.NewRequest(http.MethodGet, "/org/repo/resolve/main/a.bin", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "dingospeed_resume", Value: cookie})
+		}
+		c := e.NewContext(req, httptest.NewRecorder())
+		respHeaders := map[string]string{}
+		token, startPos := f.resumeDownload(c, "etag", fileSize, 0, fileSize, respHeaders)
+		return token, startPos, respHeaders
+	}
+
+	token, startPos, respHeaders := resume(nil, "")
+	if token == "" || startPos != 0 || respHeaders[consts.HeaderResumeToken] != token || respHeaders["content-range"] != "" {
+		t.Fatalf("unexpected fresh download: token %q, start %d, headers %v", token, startPos, respHeaders)
+	}
+	f.saveResumePosition(token, "etag", 20<<20, fileSize)
+
+	// 续传从记录位置回退余量处开始，以content-range标识
+	_, startPos, respHeaders = resume(map[string]string{consts.HeaderResumeToken: token}, "")
+	if want := int64(20<<20 - resumeMargin); startPos != want {
+		t.Fatalf("expected resume from %d, got %d", want, startPos)
+	}
+	if want := fmt.Sprintf("bytes %d-%d/%d", startPos, fileSize-1, fileSize); respHeaders["content-range"] != want {
+		t.Fatalf("expected content-range %s, got %s", want, respHeaders["content-range"])
+	}
+
+	// 只携带Cookie视为新的下载
+	newToken, startPos, respHeaders := resume(nil, token)
+	if newToken == token || startPos != 0 || respHeaders["content-range"] != "" {
+		t.Fatalf("cookie must not resume: token %q, start %d", newToken, startPos)
+	}
+
+	// 记录的位置不足余量时从头开始
+	f.saveResumePosition(token, "etag", resumeMargin/2, fileSize)
+	if _, startPos, _ = resume(map[string]string{consts.HeaderResumeToken: token}, ""); startPos != 0 {
+		t.Fatalf("expected start from 0, got %d", startPos)
+	}
+
+	// 显式Range的请求不签发令牌
+	if token, _, _ = resume(map[string]string{"Range": "bytes=0-99"}, ""); token != "" {
+		t.Fatalf("range request must not get a token, got %q", token)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"dingospeed/internal/data"
+	"dingospeed/pkg/util"
 )
 
 type LockDao struct {
@@ -53,13 +54,57 @@ func (f *LockDao) getMetaDataReqLock(orgRepoKey string) *sync.RWMutex {
 }
 
 func GetMetaShaRepoKey(repo, commit, authorization string) string {
-	return fmt.Sprintf("meta/%s/%s/%s", repo, commit, authorization)
+	return fmt.Sprintf("meta/%s/%s/%s", repo, commit, util.TokenHash(authorization))
 }
 
-func GetMetaDataReqKey(repoType, orgRepo, commit string) string {
-	return fmt.Sprintf("metadatareq/%s/%s/%s", repoType, orgRepo, commit)
+func GetNegativeRepoKey(repoType, orgRepo, commit, authorization string) string {
+	return fmt.Sprintf("negative/%s/%s/%s/%s", repoType, orgRepo, commit, util.TokenHash(authorization))
+}
+
+func GetRefsKey(repoType, orgRepo, authorization string) string {
+	return fmt.Sprintf("refs/%s/%s/%s", repoType, orgRepo, util.TokenHash(authorization))
+}
+
+func GetWhoamiKey(authorization string) string {
+	return fmt.Sprintf("whoami/%s", util.TokenHash(authorization))
+}
+
+func GetMetaDataReqKey(repoType, orgRepo, commit, query string) string {
+	return fmt.Sprintf("metadatareq/%s/%s/%s?%s", repoType, orgRepo, commit, query)
+}
+
+func GetPathsInfoPrefetchKey(repoType, orgRepo, revision, authorization string) string {
+	return fmt.Sprintf("pathsInfoPrefetch/%s/%s/%s/%s", repoType, orgRepo, revision, util.TokenHash(authorization))
+}
+
+func GetRepoFilesIndexKey(repoType, orgRepo, commit string) string {
+	return fmt.Sprintf("filesIndex/%s/%s/%s", repoType, orgRepo, commit)
+}
+
+func GetReadAheadKey(blobsFile string) string {
+	return fmt.Sprintf("readAhead/%s", blobsFile)
+}
+
+func GetRepoTierKey(repoType, orgRepo string) string {
+	return fmt.Sprintf("repoTier/%s/%s", repoType, orgRepo)
+}
+
+func GetResumeTokenKey(token string) string {
+	return fmt.Sprintf("resumeToken/%s", token)
 }
 
 func GetFilePathInfoKey(repoType, orgRepo, authorization string) string {
 	return fmt.Sprintf("filePathInfo/%s/%s/%s", repoType, orgRepo, authorization)
 }
+
+func GetRepoCardKey(repoType, orgRepo, commit string) string {
+	return fmt.Sprintf("repoCard/%s/%s/%s", repoType, orgRepo, commit)
+}
+
+func GetPickleScanKey(etag string) string {
+	return fmt.Sprintf("pickleScan/%s", etag)
+}
+
+func GetFileDigestKey(etag string) string {
+	return fmt.Sprintf("fileDigest/%s", etag)
+}
@@ -15,25 +15,50 @@
 package dao
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"dingospeed/internal/data"
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
 	"dingospeed/pkg/common"
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
 	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/notify"
 	"dingospeed/pkg/util"
 
+	"github.com/bytedance/sonic"
 	"github.com/labstack/echo/v4"
+	"github.com/patrickmn/go-cache"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 type MetaDao struct {
-	fileDao  *FileDao
-	lockDao  *LockDao
-	baseData *data.BaseData
+	fileDao   *FileDao
+	lockDao   *LockDao
+	baseData  *data.BaseData
+	refsGroup singleflight.Group // 合并同一仓库并发的refs请求
 }
 
 func NewMetaDao(fileDao *FileDao, lockDao *LockDao, baseData *data.BaseData) *MetaDao {
@@ -44,21 +69,49 @@ func NewMetaDao(fileDao *FileDao, lockDao *LockDao, baseData *data.BaseData) *Me
 	}
 }
 
+// 在线时向远端校验token，成功结果按token哈希缓存；离线或远端不可用时返回缓存的身份信息。
 func (m *MetaDao) WhoamiV2Generator(c echo.Context) error {
-	newHeaders := make(map[string]string, 0)
-	for k := range c.Request().Header {
-		v := c.Request().Header.Get(k)
-		lowerKey := strings.ToLower(k)
-		if lowerKey == "host" {
-			continue
-		}
-		newHeaders[lowerKey] = v
+	authorization := c.Request().Header.Get("authorization")
+	if authorization == "" {
+		return myerr.NewAppendCode(http.StatusUnauthorized, "Invalid credentials in Authorization header")
 	}
-	resp, err := util.Get("/api/whoami-v2", newHeaders)
-	if err != nil {
+	whoamiKey := GetWhoamiKey(authorization)
+	if config.SysConfig.Online() {
+		newHeaders := make(map[string]string, 0)
+		for k := range c.Request().Header {
+			v := c.Request().Header.Get(k)
+			lowerKey := strings.ToLower(k)
+			if lowerKey == "host" {
+				continue
+			}
+			newHeaders[lowerKey] = v
+		}
+		resp, err := util.Get("/api/whoami-v2", newHeaders)
+		if err == nil {
+			switch resp.StatusCode {
+			case http.StatusOK:
+				m.baseData.Cache.Set(whoamiKey, resp, config.SysConfig.GetWhoamiExpiration())
+				return writeWhoamiResp(c, resp)
+			case http.StatusUnauthorized, http.StatusForbidden:
+				m.baseData.Cache.Delete(whoamiKey)
+				return writeWhoamiResp(c, resp)
+			}
+			// 限流、远端故障等不代表令牌失效，与离线时一样优先返回缓存的身份
+			if v, ok := m.baseData.Cache.Get(whoamiKey); ok {
+				zap.S().Warnf("whoami-v2 upstream status %d, serve cached identity", resp.StatusCode)
+				return writeWhoamiResp(c, v.(*common.Response))
+			}
+			return writeWhoamiResp(c, resp)
+		}
 		zap.S().Errorf("WhoamiV2Generator err.%v", err)
-		return err
 	}
+	if v, ok := m.baseData.Cache.Get(whoamiKey); ok {
+		return writeWhoamiResp(c, v.(*common.Response))
+	}
+	return myerr.NewAppendCode(http.StatusUnauthorized, "Invalid credentials in Authorization header")
+}
+
+func writeWhoamiResp(c echo.Context, resp *common.Response) error {
 	extractHeaders := resp.ExtractHeaders(resp.Headers)
 	for k, vv := range extractHeaders {
 		c.Response().Header().Add(k, vv)
@@ -70,27 +123,92 @@ func (m *MetaDao) WhoamiV2Generator(c echo.Context) error {
 	return nil
 }
 
+// ReposGenerator 列出已缓存的仓库及其缓存完整度，请求的Accept为application/json时返回json。
 func (m *MetaDao) ReposGenerator(c echo.Context) error {
 	reposPath := config.SysConfig.Repos()
-
-	datasets, _ := filepath.Glob(filepath.Join(reposPath, "api/datasets/*/*"))
-	datasetsRepos := util.ProcessPaths(datasets)
-
-	models, _ := filepath.Glob(filepath.Join(reposPath, "api/models/*/*"))
-	modelsRepos := util.ProcessPaths(models)
-
-	spaces, _ := filepath.Glob(filepath.Join(reposPath, "api/spaces/*/*"))
-	spacesRepos := util.ProcessPaths(spaces)
-
+	repos := make(map[string][]*query.RepoTier)
+	for _, repoType := range []string{"datasets", "models", "spaces"} {
+		paths, _ := filepath.Glob(filepath.Join(reposPath, "api", repoType, "*", "*"))
+		tiers := make([]*query.RepoTier, 0, len(paths))
+		for _, orgRepo := range util.ProcessPaths(paths) {
+			tiers = append(tiers, m.RepoTier(repoType, orgRepo))
+		}
+		repos[repoType] = tiers
+	}
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON) {
+		return c.JSON(http.StatusOK, repos)
+	}
 	return c.Render(http.StatusOK, "repos.html", map[string]interface{}{
-		"datasets_repos": datasetsRepos,
-		"models_repos":   modelsRepos,
-		"spaces_repos":   spacesRepos,
+		"datasets_repos": repos["datasets"],
+		"models_repos":   repos["models"],
+		"spaces_repos":   repos["spaces"],
 	})
 }
 
+// RepoTier 统计仓库默认版本的缓存完整度，只读取本地缓存，结果在内存中缓存一分钟。
+func (m *MetaDao) RepoTier(repoType, orgRepo string) *query.RepoTier {
+	key := GetRepoTierKey(repoType, orgRepo)
+	if v, ok := m.baseData.Cache.Get(key); ok {
+		return v.(*query.RepoTier)
+	}
+	tier := &query.RepoTier{OrgRepo: orgRepo, Tier: consts.RepoTierMeta}
+	defer m.baseData.Cache.Set(key, tier, consts.RepoTierExpiration)
+	revisions, err := m.LocalRevisions(repoType, orgRepo)
+	if err != nil || len(revisions) == 0 {
+		return tier
+	}
+	// 默认使用main分支，未缓存时使用最近缓存的版本
+	revision := revisions[0]
+	for _, r := range revisions {
+		if slices.Contains(r.Aliases, "main") {
+			revision = r
+			break
+		}
+	}
+	metaPath := util.ApiMetaFile(util.GetApiRoot(""), repoType, orgRepo, revision.Sha, consts.RequestTypeGet)
+	cacheContent, err := m.fileDao.ReadCacheRequest(metaPath)
+	if err != nil {
+		return tier
+	}
+	var sha CommitHfSha
+	if err = sonic.Unmarshal(cacheContent.OriginContent, &sha); err != nil {
+		return tier
+	}
+	tier.Sha, tier.TotalFiles, tier.TotalSize = sha.Sha, len(sha.Siblings), sha.UsedStorage
+	filesDir := util.ResolveDir(repoType, orgRepo, sha.Sha)
+	for _, sibling := range sha.Siblings {
+		filePath := filepath.Join(filesDir, util.CachePath(sibling.Rfilename))
+		if !util.CacheFileExists(filePath) {
+			continue
+		}
+		if fileSize, cachedSize, ok := downloader.CachedSize(filePath); ok {
+			tier.CachedSize += cachedSize
+			if cachedSize == fileSize {
+				tier.CachedFiles++
+			}
+		}
+	}
+	switch {
+	case tier.TotalFiles > 0 && tier.CachedFiles == tier.TotalFiles:
+		tier.Tier, tier.Percent = consts.RepoTierFull, 100
+		return tier
+	case tier.CachedFiles > 0 || tier.CachedSize > 0:
+		tier.Tier = consts.RepoTierPartial
+	default:
+		return tier
+	}
+	if tier.TotalSize > 0 {
+		tier.Percent = min(float64(tier.CachedSize)*100/float64(tier.TotalSize), 99.99)
+	} else {
+		tier.Percent = float64(tier.CachedFiles) * 100 / float64(tier.TotalFiles)
+	}
+	tier.Percent = math.Round(tier.Percent*100) / 100
+	return tier
+}
+
+// RepoRefs 请求远端refs，始终带上include_prs，缓存的结果同时包含PR。
 func (m *MetaDao) RepoRefs(repoType string, orgRepo string, authorization string) (*common.Response, error) {
-	refsUri := fmt.Sprintf("/api/%s/%s/refs", repoType, orgRepo)
+	refsUri := fmt.Sprintf("/api/%s/%s/refs?include_prs=1", repoType, orgRepo)
 	headers := map[string]string{}
 	if authorization != "" {
 		headers["authorization"] = authorization
@@ -101,96 +219,1488 @@ func (m *MetaDao) RepoRefs(repoType string, orgRepo string, authorization string
 	return resp, err
 }
 
+// CachedRefs 在线时的refs：有效期内直接返回本地缓存；过期后携带ETag向远端校验，未变化时只刷新有效期，
+// 内容变化时才重写缓存。同一仓库同一token的并发请求合并为一次远端请求，远端的非200响应原样返回且不缓存。
+func (m *MetaDao) CachedRefs(repoType, orgRepo, authorization string) (*common.CacheContent, error) {
+	refsPath := util.ApiRefsFile(util.GetApiRoot(authorization), repoType, orgRepo)
+	refsKey := GetRefsKey(repoType, orgRepo, authorization)
+	if _, ok := m.baseData.Cache.Get(refsKey); ok && util.CacheFileExists(refsPath) {
+		if cacheContent, err := m.fileDao.ReadCacheRequest(refsPath); err == nil {
+			return cacheContent, nil
+		}
+	}
+	v, err, _ := m.refsGroup.Do(refsKey, func() (interface{}, error) {
+		return m.revalidateCachedRefs(refsPath, refsKey, repoType, orgRepo, authorization)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*common.CacheContent), nil
+}
+
+func (m *MetaDao) revalidateCachedRefs(refsPath, refsKey, repoType, orgRepo, authorization string) (*common.CacheContent, error) {
+	var cached *common.CacheContent
+	if util.CacheFileExists(refsPath) {
+		if cacheContent, err := m.fileDao.ReadCacheRequest(refsPath); err == nil {
+			cached = cacheContent
+		}
+	}
+	refsUri := fmt.Sprintf("/api/%s/%s/refs?include_prs=1", repoType, orgRepo)
+	headers := map[string]string{}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+	if cached != nil && cached.Headers[consts.HUGGINGFACE_HEADER_ETAG] != "" {
+		headers["if-none-match"] = cached.Headers[consts.HUGGINGFACE_HEADER_ETAG]
+	}
+	resp, err := util.RetryRequest(func() (*common.Response, error) {
+		return util.Get(refsUri, headers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	expiration := config.SysConfig.GetRefsExpiration()
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		if expiration > 0 {
+			m.baseData.Cache.Set(refsKey, struct{}{}, expiration)
+		}
+		return cached, nil
+	}
+	extractHeaders := resp.ExtractHeaders(resp.Headers)
+	refs := &common.CacheContent{
+		StatusCode:    resp.StatusCode,
+		Headers:       extractHeaders,
+		OriginContent: resp.Body,
+	}
+	if resp.StatusCode != http.StatusOK {
+		return refs, nil
+	}
+	if err = util.CheckMetaBody(extractHeaders, resp.Body, "branches"); err != nil {
+		// 远端返回的不是refs时不覆盖已有缓存，继续使用旧的refs
+		zap.S().Warnf("refs of %s/%s is not cached.%v", repoType, orgRepo, err)
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	if cached == nil || !bytes.Equal(cached.OriginContent, resp.Body) ||
+		cached.Headers[consts.HUGGINGFACE_HEADER_ETAG] != extractHeaders[consts.HUGGINGFACE_HEADER_ETAG] {
+		if err = util.MakeDirs(refsPath); err != nil {
+			return nil, err
+		}
+		if err = m.fileDao.WriteCacheRequest(refsPath, resp.StatusCode, extractHeaders, resp.Body); err != nil {
+			return nil, err
+		}
+	}
+	if expiration > 0 {
+		m.baseData.Cache.Set(refsKey, struct{}{}, expiration)
+	}
+	return refs, nil
+}
+
+type collectionResp struct {
+	Items []struct {
+		Type string `json:"type"`
+		Id   string `json:"id"`
+	} `json:"items"`
+}
+
+type CollectionRepo struct {
+	RepoType string
+	OrgRepo  string
+}
+
+// CollectionRepos 通过collections接口获取collection中的模型和数据集，space、论文等其他条目忽略。
+func (m *MetaDao) CollectionRepos(slug, authorization string) ([]*CollectionRepo, error) {
+	if !config.SysConfig.Online() {
+		return nil, util.OfflineMissError(myerr.NewAppendCode(http.StatusServiceUnavailable, "collection is not available offline"))
+	}
+	headers := map[string]string{}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+	resp, err := util.Get(fmt.Sprintf("/api/collections/%s", slug), headers)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, myerr.NewAppendCode(resp.StatusCode, fmt.Sprintf("get collection %s: %s", slug, string(resp.Body)))
+	}
+	var collection collectionResp
+	if err = sonic.Unmarshal(resp.Body, &collection); err != nil {
+		return nil, err
+	}
+	repos := make([]*CollectionRepo, 0, len(collection.Items))
+	for _, item := range collection.Items {
+		switch item.Type {
+		case "model":
+			repos = append(repos, &CollectionRepo{RepoType: "models", OrgRepo: item.Id})
+		case "dataset":
+			repos = append(repos, &CollectionRepo{RepoType: "datasets", OrgRepo: item.Id})
+		}
+	}
+	return repos, nil
+}
+
+// DiscoverModels 通过models搜索接口按规则查找排名靠前的模型，返回模型id。
+func (m *MetaDao) DiscoverModels(rule config.CurationRule, authorization string) ([]string, error) {
+	if !config.SysConfig.Online() {
+		return nil, util.OfflineMissError(myerr.NewAppendCode(http.StatusServiceUnavailable, "model search is not available offline"))
+	}
+	params := url.Values{}
+	if rule.PipelineTag != "" {
+		params.Set("pipeline_tag", rule.PipelineTag)
+	}
+	if rule.Library != "" {
+		params.Set("library", rule.Library)
+	}
+	if rule.License != "" {
+		params.Set("filter", "license:"+rule.License)
+	}
+	params.Set("sort", rule.Sort)
+	params.Set("direction", "-1")
+	params.Set("limit", strconv.Itoa(rule.Limit))
+	headers := map[string]string{}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+	resp, err := util.Get(fmt.Sprintf("/api/models?%s", params.Encode()), headers)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, myerr.NewAppendCode(resp.StatusCode, fmt.Sprintf("search models: %s", string(resp.Body)))
+	}
+	var models []struct {
+		Id string `json:"id"`
+	}
+	if err = sonic.Unmarshal(resp.Body, &models); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(models))
+	for _, model := range models {
+		ids = append(ids, model.Id)
+	}
+	return ids, nil
+}
+
+// DatasetsServer 请求datasets-server接口并按查询参数缓存，在线且缓存未过期时直接返回缓存，
+// 离线或远端异常时返回已缓存的内容并标记为过期，query为util.DatasetsServerQuery的结果。
+func (m *MetaDao) DatasetsServer(ctx context.Context, endpoint, dataset, query, authorization string) (*common.CacheContent, error) {
+	cachePath := util.ApiDatasetsServerFile(util.GetApiRoot(authorization), dataset, endpoint, query)
+	cached, cacheErr := m.fileDao.ReadCacheRequest(cachePath)
+	var lastUpdated time.Time
+	if cacheErr == nil {
+		if info, err := os.Stat(cachePath); err == nil {
+			lastUpdated = info.ModTime()
+		}
+		if config.SysConfig.Online() && time.Since(lastUpdated) < config.SysConfig.GetDatasetsServerExpiration() {
+			return cached, nil
+		}
+	}
+	if !config.SysConfig.Online() {
+		if cacheErr != nil {
+			return nil, util.OfflineMissError(myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s of %s is not cached", endpoint, dataset)))
+		}
+		return staleCacheContent(cached, lastUpdated), nil
+	}
+	headers := map[string]string{}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+	resp, err := util.GetEndpointContext(ctx, config.SysConfig.DatasetsServer.Endpoint, fmt.Sprintf("/%s?%s", endpoint, query), headers)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		if cacheErr == nil {
+			zap.S().Warnf("datasets-server %s %s unavailable, serve stale cache", endpoint, query)
+			return staleCacheContent(cached, lastUpdated), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	extractHeaders := resp.ExtractHeaders(resp.Headers)
+	if resp.StatusCode == http.StatusOK {
+		if err = util.MakeDirs(cachePath); err != nil {
+			zap.S().Errorf("create %s dir err.%v", cachePath, err)
+		} else if err = m.fileDao.WriteCacheRequest(cachePath, resp.StatusCode, extractHeaders, resp.Body); err != nil {
+			zap.S().Errorf("write datasets-server cache %s err.%v", cachePath, err)
+		}
+	}
+	return &common.CacheContent{
+		StatusCode:    resp.StatusCode,
+		Headers:       extractHeaders,
+		OriginContent: resp.Body,
+	}, nil
+}
+
+func staleCacheContent(cached *common.CacheContent, lastUpdated time.Time) *common.CacheContent {
+	headers := make(map[string]string, len(cached.Headers)+2)
+	for k, v := range cached.Headers {
+		headers[k] = v
+	}
+	headers[consts.HeaderStale] = "true"
+	headers[consts.HeaderLastUpdated] = lastUpdated.UTC().Format(http.TimeFormat)
+	cached.Headers = headers
+	return cached
+}
+
 func (m *MetaDao) ForwardRefs(originalReq echo.Context) (*http.Response, error) {
 	return util.ForwardRequest(originalReq)
 }
 
+// ForwardCached 转发命中缓存规则的GET请求，与DatasetsServer相同：有效期内返回缓存，过期后请求远端并缓存200响应，
+// 离线或远端不可用时返回已缓存的内容。
+func (m *MetaDao) ForwardCached(c echo.Context, rule *config.ForwardCacheRule) (*common.CacheContent, error) {
+	req := c.Request()
+	cachePath := util.ApiForwardFile(util.GetApiRoot(req.Header.Get("authorization")), req.URL.Path, req.URL.RawQuery)
+	cached, cacheErr := m.fileDao.ReadCacheRequest(cachePath)
+	var lastUpdated time.Time
+	if cacheErr == nil {
+		if info, err := os.Stat(cachePath); err == nil {
+			lastUpdated = info.ModTime()
+		}
+		if config.SysConfig.Online() && time.Since(lastUpdated) < rule.GetExpiration() {
+			return cached, nil
+		}
+	}
+	if !config.SysConfig.Online() {
+		if cacheErr != nil {
+			return nil, util.OfflineMissError(myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s is not cached", req.URL.Path)))
+		}
+		return staleCacheContent(cached, lastUpdated), nil
+	}
+	// 缓存的内容需要对所有客户端可用，不透传客户端的压缩协商，由传输层透明解压
+	req.Header.Del("Accept-Encoding")
+	resp, err := util.ForwardRequest(c)
+	if err == nil && (resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests) && cacheErr == nil {
+		resp.Body.Close()
+		err = fmt.Errorf("upstream status %d", resp.StatusCode)
+	}
+	if err != nil {
+		if cacheErr == nil {
+			zap.S().Warnf("forward %s unavailable, serve stale cache.%v", req.URL.Path, err)
+			return staleCacheContent(cached, lastUpdated), nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := util.ReadLimited(resp.Body, config.SysConfig.Forward.MaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for k, v := range resp.Header {
+		if len(v) > 0 {
+			headers[strings.ToLower(k)] = v[0]
+		}
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err = util.MakeDirs(cachePath); err != nil {
+			zap.S().Errorf("create %s dir err.%v", cachePath, err)
+		} else if err = m.fileDao.WriteCacheRequest(cachePath, resp.StatusCode, headers, body); err != nil {
+			zap.S().Errorf("write forward cache %s err.%v", cachePath, err)
+		}
+	}
+	return &common.CacheContent{
+		StatusCode:    resp.StatusCode,
+		Headers:       headers,
+		OriginContent: body,
+	}, nil
+}
+
 func (m *MetaDao) GetMetadata(repoType, orgRepo, revision, method, authorization string) (*common.CacheContent, error) {
+	return m.getMetadata(context.Background(), repoType, orgRepo, revision, method, authorization, "", nil)
+}
+
+// GetQueryMetadata 带expand、blobs等查询参数的仓库信息，query为util.MetaQuery的结果，不同参数组合分别缓存。
+func (m *MetaDao) GetQueryMetadata(ctx context.Context, repoType, orgRepo, revision, method, authorization, query string) (*common.CacheContent, error) {
+	return m.getMetadata(ctx, repoType, orgRepo, revision, method, authorization, query, nil)
+}
+
+// StreamMetadata 与GetQueryMetadata相同，未缓存时远端响应在写入缓存的同时经sink回传客户端，此时返回的内容为nil。
+func (m *MetaDao) StreamMetadata(ctx context.Context, repoType, orgRepo, revision, authorization, query string, sink func(statusCode int, headers map[string]string) io.Writer) (*common.CacheContent, error) {
+	return m.getMetadata(ctx, repoType, orgRepo, revision, consts.RequestTypeGet, authorization, query, sink)
+}
+
+func (m *MetaDao) getMetadata(ctx context.Context, repoType, orgRepo, revision, method, authorization, query string, sink func(statusCode int, headers map[string]string) io.Writer) (*common.CacheContent, error) {
 	var (
 		cacheContent *common.CacheContent
 		err          error
 	)
-	orgRepoKey := GetMetaDataReqKey(repoType, orgRepo, revision)
+	revision = util.NormalizeRevision(revision)
+	orgRepoKey := GetMetaDataReqKey(repoType, orgRepo, revision, query)
 	lock := m.lockDao.getMetaDataReqLock(orgRepoKey)
 	lock.Lock()
 	defer lock.Unlock()
-	commitSha, err := m.fileDao.GetFileCommitSha(repoType, orgRepo, revision, authorization, "meta")
+	// 元数据在客户端断开后仍继续拉取并写入缓存，不绑定请求ctx的取消
+	commitSha, err := m.fileDao.GetFileCommitSha(context.WithoutCancel(ctx), repoType, orgRepo, revision, authorization, "meta")
 	if err != nil {
 		return nil, err
 	}
-	apiDir := fmt.Sprintf("%s/api/%s/%s/revision/%s", config.SysConfig.Repos(), repoType, orgRepo, commitSha)
-	apiMetaPath := fmt.Sprintf("%s/%s", apiDir, fmt.Sprintf("meta_%s.json", method))
-	if config.SysConfig.Online() {
-		if util.FileExists(apiMetaPath) {
-			if cacheContent, err = m.fileDao.ReadCacheRequest(apiMetaPath); err != nil {
-				zap.S().Errorf("ReadCacheRequest err.%v", err)
-				if cacheContent, err = m.requestAndSaveMeta(repoType, orgRepo, revision, commitSha, method, authorization); err != nil {
-					return nil, err
-				}
-			}
-		} else {
-			if cacheContent, err = m.requestAndSaveMeta(repoType, orgRepo, revision, commitSha, method, authorization); err != nil {
-				return nil, err
+	apiRoot := util.GetApiRoot(authorization)
+	trace := util.CacheTraceFrom(ctx)
+	if cacheContent, err = m.readCacheMeta(apiRoot, repoType, orgRepo, commitSha, method, query); err == nil {
+		if trace != nil {
+			cachePath := getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, consts.RequestTypeGet, query)
+			var cachedAt time.Time
+			if info, err := os.Stat(cachePath); err == nil {
+				cachedAt = info.ModTime()
 			}
+			trace.Hit(cachePath, cachedAt)
 		}
-	} else {
-		if cacheContent, err = m.fileDao.ReadCacheRequest(apiMetaPath); err != nil {
-			zap.S().Errorf("ReadCacheRequest err.%v", err)
+		return cacheContent, nil
+	}
+	if !config.SysConfig.Online() {
+		zap.S().Errorf("ReadCacheRequest err.%v", err)
+		return nil, util.OfflineMissError(err)
+	}
+	trace.Miss(getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, consts.RequestTypeGet, query),
+		util.UpstreamFor(fmt.Sprintf("/api/%s/%s/revision/%s", repoType, orgRepo, util.EscapeRevision(revision))))
+	// head和get统一通过get请求远端，一次请求同时缓存两种元数据。
+	if sink != nil {
+		return nil, m.requestAndSaveMeta(repoType, orgRepo, revision, commitSha, authorization, query, sink)
+	}
+	if method == consts.RequestTypeHead {
+		cacheContent = &common.CacheContent{}
+		err = m.requestAndSaveMeta(repoType, orgRepo, revision, commitSha, authorization, query, func(statusCode int, headers map[string]string) io.Writer {
+			cacheContent.StatusCode, cacheContent.Headers = statusCode, headers
+			return nil
+		})
+		if err != nil {
 			return nil, err
 		}
+		return cacheContent, nil
 	}
-	return cacheContent, nil
+	return m.requestAndSaveMetaContent(repoType, orgRepo, revision, commitSha, authorization, query)
 }
 
-func (m *MetaDao) requestAndSaveMeta(repoType, orgRepo, revision, commitSha, method, authorization string) (*common.CacheContent, error) {
-	resp, err := m.fileDao.RemoteRequestMeta(method, repoType, orgRepo, revision, authorization)
+// head请求优先读取meta_head.json，不存在时使用meta_get.json的响应头。
+func (m *MetaDao) readCacheMeta(apiRoot, repoType, orgRepo, commitSha, method, query string) (*common.CacheContent, error) {
+	apiMetaPath := getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, method, query)
+	if util.CacheFileExists(apiMetaPath) {
+		cacheContent, err := m.fileDao.ReadCacheRequest(apiMetaPath)
+		if err == nil {
+			return cacheContent, nil
+		}
+		zap.S().Errorf("ReadCacheRequest err.%v", err)
+		if method != consts.RequestTypeHead {
+			return nil, err
+		}
+	}
+	if method != consts.RequestTypeHead {
+		return nil, myerr.New(fmt.Sprintf("apiMetaPath file not exist, %s", apiMetaPath))
+	}
+	cacheContent, err := m.fileDao.ReadCacheRequest(getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, consts.RequestTypeGet, query))
 	if err != nil {
-		zap.S().Errorf("requestAndSaveMeta %s err.%v", method, err)
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusTemporaryRedirect {
-		return nil, myerr.NewAppendCode(resp.StatusCode, "request err")
+	cacheContent.OriginContent = nil
+	return cacheContent, nil
+}
+
+// requestAndSaveMeta 以流的方式将远端元数据写入commitSha目录，sink返回的写入器同时接收响应体（如回传客户端），不在内存中缓冲完整响应。
+// 其余版本目录的缓存以硬链接的方式共享写入的文件。
+func (m *MetaDao) requestAndSaveMeta(repoType, orgRepo, revision, commitSha, authorization, query string, sink func(statusCode int, headers map[string]string) io.Writer) error {
+	reqUri := fmt.Sprintf("/api/%s/%s/revision/%s", repoType, orgRepo, util.EscapeRevision(revision))
+	if query != "" {
+		reqUri = fmt.Sprintf("%s?%s", reqUri, query)
 	}
-	extractHeaders := resp.ExtractHeaders(resp.Headers)
-	mainVersion := "main"
-	if revision == mainVersion {
-		err = m.writeApiMetaFile(repoType, orgRepo, revision, method, resp.StatusCode, extractHeaders, resp.Body)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		apiDir := fmt.Sprintf("%s/api/%s/%s/revision/%s", config.SysConfig.Repos(), repoType, orgRepo, mainVersion)
-		apiMetaPath := fmt.Sprintf("%s/%s", apiDir, fmt.Sprintf("meta_%s.json", method))
-		if !util.FileExists(apiMetaPath) {
-			err = m.writeApiMetaFile(repoType, orgRepo, mainVersion, method, resp.StatusCode, extractHeaders, resp.Body) // create main dir
-			if err != nil {
-				return nil, err
+	headers := map[string]string{}
+	if authorization != "" {
+		headers["authorization"] = authorization
+	}
+	apiRoot := util.GetApiRoot(authorization)
+	apiMetaPath := getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, consts.RequestTypeGet, query)
+	if err := util.MakeDirs(apiMetaPath); err != nil {
+		zap.S().Errorf("create %s dir err.%v", apiMetaPath, err)
+		return err
+	}
+	var (
+		statusCode     int
+		extractHeaders map[string]string
+		started        bool
+	)
+	err := util.RetryStream(func() error {
+		return util.GetMetaStream(reqUri, headers, func(resp *http.Response) error {
+			if resp.StatusCode != http.StatusOK && !util.IsRedirect(resp.StatusCode) {
+				return myerr.NewAppendCode(resp.StatusCode, "request err")
 			}
-		}
+			respHeaders := make(map[string]interface{}, len(resp.Header))
+			for k, v := range resp.Header {
+				respHeaders[k] = v
+			}
+			statusCode, extractHeaders = resp.StatusCode, common.Response{}.ExtractHeaders(respHeaders)
+			if location, ok := extractHeaders["location"]; ok && util.IsRedirect(statusCode) {
+				extractHeaders["location"] = util.RewriteLocation(location)
+			}
+			body := io.Reader(resp.Body)
+			if statusCode == http.StatusOK {
+				// 写入缓存前校验元数据，HTML错误页等在转发给客户端之前即返回错误
+				br := bufio.NewReader(resp.Body)
+				if err := util.CheckMetaResponse(extractHeaders, br); err != nil {
+					return err
+				}
+				guard := util.GuardMetaStream(br, metaRequiredKeys(query)...)
+				defer guard.Close()
+				body = guard
+			}
+			if sink != nil {
+				started = true
+				if w := sink(statusCode, extractHeaders); w != nil {
+					body = io.TeeReader(resp.Body, w)
+				}
+			}
+			return m.fileDao.WriteCacheRequestStream(apiMetaPath, statusCode, extractHeaders, body)
+		})
+	}, func(err error) bool {
+		var e myerr.Error
+		return !started && !errors.As(err, &e)
+	})
+	if err != nil {
+		zap.S().Errorf("requestAndSaveMeta %s/%s/%s err.%v", repoType, orgRepo, revision, err)
+		return err
+	}
+	if err = m.fileDao.WriteCacheRequest(getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, consts.RequestTypeHead, query), statusCode, extractHeaders, nil); err != nil {
+		return err
+	}
+	mainVersion := "main"
+	if revision == mainVersion || !util.CacheFileExists(getApiMetaPath(apiRoot, repoType, orgRepo, mainVersion, consts.RequestTypeGet, query)) {
+		return m.linkApiMetaFiles(apiRoot, repoType, orgRepo, commitSha, mainVersion, query) // create main dir
+	}
+	return nil
+}
+
+// metaRequiredKeys 元数据须包含的字段，带expand等参数时远端只返回请求的字段，不要求sha。
+func metaRequiredKeys(query string) []string {
+	if query != "" {
+		return []string{"id"}
 	}
+	return []string{"id", "sha"}
+}
 
-	err = m.writeApiMetaFile(repoType, orgRepo, commitSha, method, resp.StatusCode, extractHeaders, resp.Body)
+// requestAndSaveMetaContent 请求并缓存远端元数据，返回完整内容，供需要解析元数据的调用方使用。
+func (m *MetaDao) requestAndSaveMetaContent(repoType, orgRepo, revision, commitSha, authorization, query string) (*common.CacheContent, error) {
+	cacheContent := &common.CacheContent{}
+	var body bytes.Buffer
+	err := m.requestAndSaveMeta(repoType, orgRepo, revision, commitSha, authorization, query, func(statusCode int, headers map[string]string) io.Writer {
+		body.Reset()
+		cacheContent.StatusCode, cacheContent.Headers = statusCode, headers
+		return &body
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &common.CacheContent{
-		StatusCode:    resp.StatusCode,
-		Headers:       extractHeaders,
-		OriginContent: resp.Body,
-	}, nil
+	cacheContent.OriginContent = body.Bytes()
+	return cacheContent, nil
+}
+
+// 将commitSha目录的元数据链接到其他版本目录，get与head缓存一并处理。
+func (m *MetaDao) linkApiMetaFiles(apiRoot, repoType, orgRepo, commitSha, revision, query string) error {
+	for _, method := range []string{consts.RequestTypeGet, consts.RequestTypeHead} {
+		dstPath := getApiMetaPath(apiRoot, repoType, orgRepo, revision, method, query)
+		if err := util.MakeDirs(dstPath); err != nil {
+			zap.S().Errorf("create %s dir err.%v", dstPath, err)
+			return err
+		}
+		if err := m.fileDao.LinkCacheRequest(getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, method, query), dstPath); err != nil {
+			zap.S().Errorf("link meta to %s err.%v", dstPath, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func getApiMetaPath(apiRoot, repoType, orgRepo, commitSha, method, query string) string {
+	return util.ApiMetaQueryFile(apiRoot, repoType, orgRepo, commitSha, method, query)
 }
 
-func (m *MetaDao) writeApiMetaFile(repoType, orgRepo, commitSha, method string, statusCode int, extractHeaders map[string]string, body []byte) error {
-	apiDir := fmt.Sprintf("%s/api/%s/%s/revision/%s", config.SysConfig.Repos(), repoType, orgRepo, commitSha)
-	apiMetaPath := fmt.Sprintf("%s/%s", apiDir, fmt.Sprintf("meta_%s.json", method))
-	err := util.MakeDirs(apiMetaPath)
+// LocalRevisions 根据api/.../revision目录汇总本地已缓存的版本，及解析到各版本的分支或标签。
+func (m *MetaDao) LocalRevisions(repoType, orgRepo string) ([]*query.LocalRevisionResp, error) {
+	revisionDir := filepath.Join(util.ApiRepoDir(util.GetApiRoot(""), repoType, orgRepo), "revision")
+	entries, err := os.ReadDir(revisionDir)
 	if err != nil {
-		zap.S().Errorf("create %s dir err.%v", apiMetaPath, err)
-		return err
+		if os.IsNotExist(err) {
+			return nil, myerr.NewErrorCode(http.StatusNotFound, consts.HfErrorCodeRepoNotFound, fmt.Sprintf("%s/%s is not cached", repoType, orgRepo))
+		}
+		return nil, err
 	}
-	if err = m.fileDao.WriteCacheRequest(apiMetaPath, statusCode, extractHeaders, body); err != nil {
-		zap.S().Errorf("writeCacheRequest err.%v", err)
-		return err
+	revisionMap := make(map[string]*query.LocalRevisionResp)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		revision := util.CacheName(entry.Name())
+		metaPath := filepath.Join(revisionDir, entry.Name(), fmt.Sprintf("meta_%s.json", consts.RequestTypeGet))
+		cacheContent, err := m.fileDao.ReadCacheRequest(metaPath)
+		if err != nil {
+			zap.S().Warnf("read %s err.%v", metaPath, err)
+			continue
+		}
+		var sha CommitHfSha
+		if err = sonic.Unmarshal(cacheContent.OriginContent, &sha); err != nil || sha.Sha == "" {
+			continue
+		}
+		localRevision, ok := revisionMap[sha.Sha]
+		if !ok {
+			localRevision = &query.LocalRevisionResp{Sha: sha.Sha, Aliases: make([]string, 0), Siblings: len(sha.Siblings)}
+			revisionMap[sha.Sha] = localRevision
+		}
+		if revision != sha.Sha {
+			localRevision.Aliases = append(localRevision.Aliases, revision)
+		}
+		if info, err := os.Stat(metaPath); err == nil && info.ModTime().Unix() > localRevision.LastModified {
+			localRevision.LastModified = info.ModTime().Unix()
+		}
 	}
-	return nil
+	revisions := make([]*query.LocalRevisionResp, 0, len(revisionMap))
+	for _, localRevision := range revisionMap {
+		filesDir := util.ResolveDir(repoType, orgRepo, localRevision.Sha)
+		_ = filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				localRevision.CachedFiles++
+			}
+			return nil
+		})
+		sort.Strings(localRevision.Aliases)
+		revisions = append(revisions, localRevision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].LastModified > revisions[j].LastModified
+	})
+	return revisions, nil
+}
+
+// OfflineRefs 离线时的refs视图：以最近缓存的refs为基础，合并本地已缓存的版本目录（分支、标签），
+// 版本目录比缓存的refs更新时以版本目录指向的commit为准，同时返回这些数据中最晚的更新时间（秒）。
+func (m *MetaDao) OfflineRefs(repoType, orgRepo, authorization string) (*GitRefs, int64, error) {
+	refs := &GitRefs{}
+	var refsUpdated int64
+	refsPath := util.ApiRefsFile(util.GetApiRoot(authorization), repoType, orgRepo)
+	if info, err := os.Stat(refsPath); err == nil {
+		cacheContent, err := m.fileDao.ReadCacheRequest(refsPath)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err = sonic.Unmarshal(cacheContent.OriginContent, refs); err != nil {
+			return nil, 0, err
+		}
+		refsUpdated = info.ModTime().Unix()
+	}
+	revisions, err := m.LocalRevisions(repoType, orgRepo)
+	if err != nil && refsUpdated == 0 {
+		return nil, 0, err
+	}
+	lastUpdated := refsUpdated
+	for _, revision := range revisions {
+		for _, alias := range revision.Aliases {
+			if !updateGitRef(refs, alias, revision.Sha, revision.LastModified > refsUpdated) {
+				if strings.HasPrefix(alias, "refs/pr/") {
+					refs.PullRequests = append(refs.PullRequests, GitRef{Name: alias, Ref: alias, TargetCommit: revision.Sha})
+				} else {
+					refs.Branches = append(refs.Branches, GitRef{Name: alias, Ref: "refs/heads/" + alias, TargetCommit: revision.Sha})
+				}
+			}
+		}
+		lastUpdated = max(lastUpdated, revision.LastModified)
+	}
+	for _, group := range []*[]GitRef{&refs.Branches, &refs.Tags, &refs.Converts, &refs.PullRequests} {
+		if *group == nil {
+			*group = make([]GitRef, 0)
+		}
+	}
+	return refs, lastUpdated, nil
+}
+
+// updateGitRef 查找名称为name的ref，newer为true时将其指向sha，未找到时返回false。
+func updateGitRef(refs *GitRefs, name, sha string, newer bool) bool {
+	for _, group := range [][]GitRef{refs.Branches, refs.Tags, refs.Converts, refs.PullRequests} {
+		for i := range group {
+			if group[i].Name == name || group[i].Ref == name {
+				if newer {
+					group[i].TargetCommit = sha
+				}
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RepoPathsInfos 返回仓库某版本全部文件的paths-info，优先读取本地缓存，缺失时在线批量请求远端，离线无法获取的文件Size为-1。
+func (m *MetaDao) RepoPathsInfos(repoType, orgRepo, revision, authorization string) (string, []*common.PathsInfo, error) {
+	commitSha, err := m.fileDao.GetFileCommitSha(context.Background(), repoType, orgRepo, revision, authorization, "meta")
+	if err != nil {
+		return "", nil, err
+	}
+	cacheContent, err := m.readCacheMeta(util.GetApiRoot(authorization), repoType, orgRepo, commitSha, consts.RequestTypeGet, "")
+	if err != nil {
+		if !config.SysConfig.Online() {
+			return "", nil, util.OfflineMissError(err)
+		}
+		if cacheContent, err = m.requestAndSaveMetaContent(repoType, orgRepo, commitSha, commitSha, authorization, ""); err != nil {
+			return "", nil, err
+		}
+	}
+	var sha CommitHfSha
+	if err = sonic.Unmarshal(cacheContent.OriginContent, &sha); err != nil {
+		return "", nil, err
+	}
+	files := make([]*common.PathsInfo, 0, len(sha.Siblings))
+	fileMap := make(map[string]int, len(sha.Siblings))
+	missing := make([]string, 0)
+	for _, sibling := range sha.Siblings {
+		pathsInfo, ok := m.cachedPathsInfo(authorization, repoType, orgRepo, commitSha, revision, sibling.Rfilename)
+		if !ok {
+			pathsInfo = &common.PathsInfo{Type: "file", Path: sibling.Rfilename, Size: -1}
+			missing = append(missing, sibling.Rfilename)
+		}
+		fileMap[sibling.Rfilename] = len(files)
+		files = append(files, pathsInfo)
+	}
+	if len(missing) > 0 && config.SysConfig.Online() {
+		pathsInfoUri := fmt.Sprintf("/api/%s/%s/paths-info/%s", repoType, orgRepo, commitSha)
+		for start := 0; start < len(missing); start += consts.PathsInfoBatchSize {
+			end := min(start+consts.PathsInfoBatchSize, len(missing))
+			resp, err := m.fileDao.requestFilePathInfo(pathsInfoUri, authorization, missing[start:end])
+			if err != nil {
+				return "", nil, err
+			}
+			pathsInfos := make([]*common.PathsInfo, 0)
+			if err = sonic.Unmarshal(resp.Body, &pathsInfos); err != nil {
+				return "", nil, err
+			}
+			for _, pathsInfo := range pathsInfos {
+				if i, ok := fileMap[pathsInfo.Path]; ok {
+					files[i] = pathsInfo
+				}
+			}
+		}
+	}
+	return commitSha, files, nil
+}
+
+// PrefetchPathsInfo 批量请求版本中尚未缓存paths-info的文件，逐个文件写入缓存，返回本次缓存的文件数。
+// 同一版本同一token在缓存有效期内只处理一次；超过直接下载上限的文件需要resolve信息，留给下载时请求。
+func (m *MetaDao) PrefetchPathsInfo(ctx context.Context, repoType, orgRepo, revision, authorization string) (int, error) {
+	prefetchKey := GetPathsInfoPrefetchKey(repoType, orgRepo, revision, authorization)
+	if _, ok := m.baseData.Cache.Get(prefetchKey); ok {
+		return 0, nil
+	}
+	commitSha, err := m.fileDao.GetFileCommitSha(ctx, repoType, orgRepo, revision, authorization, "meta")
+	if err != nil {
+		return 0, err
+	}
+	metadata, err := m.GetMetadata(repoType, orgRepo, commitSha, consts.RequestTypeGet, authorization)
+	if err != nil {
+		return 0, err
+	}
+	var sha CommitHfSha
+	if err = sonic.Unmarshal(metadata.OriginContent, &sha); err != nil {
+		return 0, err
+	}
+	apiRoot := util.GetApiRoot(authorization)
+	missing := make([]string, 0)
+	for _, sibling := range sha.Siblings {
+		if !util.CacheFileExists(util.ApiPathsInfoFile(apiRoot, repoType, orgRepo, commitSha, sibling.Rfilename)) {
+			missing = append(missing, sibling.Rfilename)
+		}
+	}
+	pathsInfoUri := fmt.Sprintf("/api/%s/%s/paths-info/%s", repoType, orgRepo, commitSha)
+	count := 0
+	for start := 0; start < len(missing); start += consts.PathsInfoBatchSize {
+		if ctx.Err() != nil {
+			return count, ctx.Err()
+		}
+		end := min(start+consts.PathsInfoBatchSize, len(missing))
+		resp, err := m.fileDao.requestFilePathInfo(pathsInfoUri, authorization, missing[start:end])
+		if err != nil {
+			return count, err
+		}
+		pathsInfos := make([]*common.PathsInfo, 0)
+		if err = sonic.Unmarshal(resp.Body, &pathsInfos); err != nil {
+			return count, err
+		}
+		headers := resp.ExtractHeaders(resp.Headers)
+		for _, pathsInfo := range pathsInfos {
+			if pathsInfo.Type != "file" || pathsInfo.Size > consts.MAX_HTTP_DOWNLOAD_SIZE {
+				continue
+			}
+			pathsInfoPath := util.ApiPathsInfoFile(apiRoot, repoType, orgRepo, commitSha, pathsInfo.Path)
+			content, err := sonic.Marshal([]*common.PathsInfo{pathsInfo})
+			if err != nil {
+				return count, err
+			}
+			if err = util.MakeDirs(pathsInfoPath); err != nil {
+				return count, err
+			}
+			if err = m.fileDao.WriteCacheRequest(pathsInfoPath, resp.StatusCode, headers, content); err != nil {
+				return count, err
+			}
+			count++
+		}
+		// 远端已确认该token可访问，之后的paths-info请求可直接读取缓存
+		m.baseData.Cache.Set(GetFilePathInfoKey(repoType, orgRepo, authorization), "", 24*time.Hour)
+	}
+	if count > 0 {
+		m.baseData.Cache.Delete(GetRepoFilesIndexKey(repoType, orgRepo, commitSha))
+	}
+	m.baseData.Cache.Set(prefetchKey, struct{}{}, config.SysConfig.GetDefaultExpiration())
+	return count, nil
+}
+
+// SyncManifest 生成供其他镜像实例同步的版本清单，包括版本元数据及各文件的paths-info和本地缓存状态。
+func (m *MetaDao) SyncManifest(repoType, orgRepo, revision, authorization string) (*query.SyncManifest, error) {
+	commitSha, pathsInfos, err := m.RepoPathsInfos(repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, err
+	}
+	cacheContent, err := m.readCacheMeta(util.GetApiRoot(authorization), repoType, orgRepo, commitSha, consts.RequestTypeGet, "")
+	if err != nil {
+		return nil, err
+	}
+	manifest := &query.SyncManifest{
+		Commit:      commitSha,
+		MetaHeaders: cacheContent.Headers,
+		Meta:        cacheContent.OriginContent,
+		Files:       make([]*query.SyncFile, 0, len(pathsInfos)),
+	}
+	for _, pathsInfo := range pathsInfos {
+		file := &query.SyncFile{PathsInfo: pathsInfo, Etag: PathsInfoEtag(pathsInfo)}
+		if pathsInfo.Size >= 0 && file.Etag != "" {
+			fileSize, cachedSize, ok := downloader.CachedSize(util.BlobsFile(repoType, orgRepo, file.Etag))
+			file.Complete = ok && fileSize == pathsInfo.Size && cachedSize == fileSize
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	return manifest, nil
+}
+
+// SaveSyncManifest 写入从其他镜像实例同步的版本元数据及paths-info，revision不是commit时一并建立别名。
+func (m *MetaDao) SaveSyncManifest(repoType, orgRepo, revision string, manifest *query.SyncManifest) error {
+	apiRoot := util.GetApiRoot("")
+	metaPath := getApiMetaPath(apiRoot, repoType, orgRepo, manifest.Commit, consts.RequestTypeGet, "")
+	if err := util.MakeDirs(metaPath); err != nil {
+		return err
+	}
+	if err := m.fileDao.WriteCacheRequest(metaPath, http.StatusOK, manifest.MetaHeaders, manifest.Meta); err != nil {
+		return err
+	}
+	headPath := getApiMetaPath(apiRoot, repoType, orgRepo, manifest.Commit, consts.RequestTypeHead, "")
+	if err := m.fileDao.WriteCacheRequest(headPath, http.StatusOK, manifest.MetaHeaders, nil); err != nil {
+		return err
+	}
+	for _, file := range manifest.Files {
+		if file.PathsInfo == nil || file.PathsInfo.Size < 0 {
+			continue
+		}
+		pathsInfoPath := util.ApiPathsInfoFile(apiRoot, repoType, orgRepo, manifest.Commit, file.PathsInfo.Path)
+		if err := util.MakeDirs(pathsInfoPath); err != nil {
+			return err
+		}
+		content, err := sonic.Marshal([]*common.PathsInfo{file.PathsInfo})
+		if err != nil {
+			return err
+		}
+		if err = m.fileDao.WriteCacheRequest(pathsInfoPath, http.StatusOK, map[string]string{}, content); err != nil {
+			return err
+		}
+	}
+	m.fileDao.baseData.Cache.Delete(GetRepoFilesIndexKey(repoType, orgRepo, manifest.Commit))
+	if revision != manifest.Commit {
+		if err := m.linkApiMetaFiles(apiRoot, repoType, orgRepo, manifest.Commit, revision, ""); err != nil {
+			return err
+		}
+	}
+	if revision != "main" && !util.CacheFileExists(getApiMetaPath(apiRoot, repoType, orgRepo, "main", consts.RequestTypeGet, "")) {
+		return m.linkApiMetaFiles(apiRoot, repoType, orgRepo, manifest.Commit, "main", "")
+	}
+	return nil
+}
+
+// PathsInfoEtag 文件在blobs目录下的名称，LFS文件为内容的sha256，其余文件为git对象哈希。
+func PathsInfoEtag(pathsInfo *common.PathsInfo) string {
+	if pathsInfo.Lfs.Oid != "" {
+		return pathsInfo.Lfs.Oid
+	}
+	return pathsInfo.Oid
+}
+
+// RepoSize 统计仓库某版本的文件总大小与本地已缓存大小。
+func (m *MetaDao) RepoSize(repoType, orgRepo, revision, authorization string) (*query.RepoSizeResp, error) {
+	commitSha, pathsInfos, err := m.RepoPathsInfos(repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, err
+	}
+	result := &query.RepoSizeResp{Sha: commitSha, Files: make([]*query.RepoFileSize, 0, len(pathsInfos))}
+	filesDir := util.ResolveDir(repoType, orgRepo, commitSha)
+	for _, pathsInfo := range pathsInfos {
+		file := &query.RepoFileSize{Path: pathsInfo.Path, Size: pathsInfo.Size}
+		filePath := filepath.Join(filesDir, util.CachePath(file.Path))
+		if util.CacheFileExists(filePath) {
+			if fileSize, cachedSize, ok := downloader.CachedSize(filePath); ok {
+				file.CachedSize = cachedSize
+				if file.Size < 0 {
+					file.Size = fileSize
+				}
+				if cachedSize == fileSize {
+					result.CachedFiles++
+				}
+			}
+		}
+		result.Files = append(result.Files, file)
+		result.TotalFiles++
+		result.CachedSize += file.CachedSize
+		if file.Size < 0 {
+			result.UnknownFiles++
+			continue
+		}
+		result.TotalSize += file.Size
+		result.RemainingSize += file.Size - file.CachedSize
+	}
+	return result, nil
+}
+
+// Provenance 返回已缓存文件的来源信息，只读取本地缓存，不访问远端。
+func (m *MetaDao) Provenance(repoType, orgRepo, revision, fileName, authorization string) (*query.ProvenanceResp, error) {
+	commitSha, err := m.fileDao.GetCommitHfOffline(repoType, orgRepo, revision, authorization)
+	if err != nil {
+		commitSha = revision
+	}
+	apiPathInfoPath := util.ApiPathsInfoFile(util.GetApiRoot(authorization), repoType, orgRepo, commitSha, fileName)
+	info, err := os.Stat(apiPathInfoPath)
+	if err != nil {
+		return nil, myerr.NewErrorCode(http.StatusNotFound, consts.HfErrorCodeEntryNotFound, fmt.Sprintf("%s/%s/%s is not cached", orgRepo, revision, fileName))
+	}
+	cacheContent, err := m.fileDao.ReadCacheRequest(apiPathInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	pathsInfos := make([]*common.PathsInfo, 0)
+	if err = sonic.Unmarshal(cacheContent.OriginContent, &pathsInfos); err != nil || len(pathsInfos) == 0 {
+		return nil, myerr.NewKind(myerr.KindCacheCorrupt, fmt.Sprintf("paths-info of %s/%s/%s is corrupt", orgRepo, revision, fileName))
+	}
+	pathInfo := pathsInfos[0]
+	result := &query.ProvenanceResp{
+		Sha:            commitSha,
+		Path:           fileName,
+		Size:           pathInfo.Size,
+		Etag:           pathInfo.Oid,
+		UpstreamStatus: cacheContent.StatusCode,
+		UpstreamEtag:   cacheContent.Headers["etag"],
+		InfoFetchedAt:  info.ModTime().Unix(),
+	}
+	if pathInfo.Lfs.Oid != "" {
+		result.Etag = pathInfo.Lfs.Oid
+	}
+	blobsFile := util.BlobsFile(repoType, orgRepo, result.Etag)
+	if util.CacheFileExists(blobsFile) {
+		if _, cachedSize, ok := downloader.CachedSize(blobsFile); ok {
+			result.CachedSize = cachedSize
+			result.Complete = cachedSize >= pathInfo.Size
+		}
+		if blobInfo, err := os.Stat(blobsFile); err == nil {
+			result.BlobModified = blobInfo.ModTime().Unix()
+		}
+	}
+	if stats, ok := downloader.GetFileStats(repoType, orgRepo, fileName); ok {
+		result.FetchedAt = stats.FetchedAt
+		result.Endpoint = stats.Endpoint
+		result.Requests = stats.Requests
+		result.Hits = stats.Hits
+		result.Resolves = stats.Resolves
+		result.LastAccess = stats.LastAccess
+	}
+	return result, nil
+}
+
+// PickleScan 扫描某版本已完整缓存的pickle格式文件，只解析操作码不反序列化，结果按blob缓存在内存中。
+// filePath不为空时只扫描该路径或该目录下的文件。
+func (m *MetaDao) PickleScan(repoType, orgRepo, revision, filePath, authorization string) (*query.PickleScanResp, error) {
+	commitSha, pathsInfos, err := m.RepoPathsInfos(repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, err
+	}
+	result := &query.PickleScanResp{Sha: commitSha, Files: make([]*query.PickleScanFile, 0)}
+	filesDir := util.ResolveDir(repoType, orgRepo, commitSha)
+	for _, pathsInfo := range pathsInfos {
+		if !util.IsPickleFile(pathsInfo.Path) {
+			continue
+		}
+		if filePath != "" && pathsInfo.Path != filePath && !strings.HasPrefix(pathsInfo.Path, strings.TrimSuffix(filePath, "/")+"/") {
+			continue
+		}
+		file := &query.PickleScanFile{Path: pathsInfo.Path, Size: pathsInfo.Size, Imports: make([]*util.PickleImport, 0)}
+		result.Files = append(result.Files, file)
+		etag := pathsInfo.Oid
+		if pathsInfo.Lfs.Oid != "" {
+			etag = pathsInfo.Lfs.Oid
+		}
+		imports, cached, err := m.pickleImports(filepath.Join(filesDir, util.CachePath(pathsInfo.Path)), etag)
+		file.Cached = cached
+		if err != nil {
+			file.Error = err.Error()
+			continue
+		}
+		for _, imp := range imports {
+			if imp.Safety == util.PickleDangerous {
+				file.Dangerous++
+			}
+		}
+		file.Imports = imports
+		result.Dangerous += file.Dangerous
+	}
+	return result, nil
+}
+
+func (m *MetaDao) pickleImports(filePath, etag string) ([]*util.PickleImport, bool, error) {
+	scanKey := GetPickleScanKey(etag)
+	if v, ok := m.baseData.Cache.Get(scanKey); ok {
+		return v.([]*util.PickleImport), true, nil
+	}
+	if !util.CacheFileExists(filePath) {
+		return nil, false, nil
+	}
+	fileSize, cachedSize, ok := downloader.CachedSize(filePath)
+	if !ok || cachedSize < fileSize {
+		return nil, false, nil
+	}
+	reader, err := downloader.OpenCachedFile(filePath)
+	if err != nil {
+		return nil, true, err
+	}
+	defer reader.Close()
+	imports, err := util.ScanPickleImports(reader, reader.Size())
+	if err != nil {
+		return nil, true, err
+	}
+	m.baseData.Cache.Set(scanKey, imports, config.SysConfig.GetDefaultExpiration())
+	return imports, true, nil
+}
+
+// Attestation 生成并签名版本清单，清单只包含已完整缓存的文件，sha256按本地缓存的内容计算，结果按blob缓存在内存中。
+func (m *MetaDao) Attestation(repoType, orgRepo, revision, authorization string) (*query.AttestationResp, error) {
+	key, err := util.AttestationKey()
+	if err != nil {
+		return nil, err
+	}
+	commitSha, pathsInfos, err := m.RepoPathsInfos(repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &query.AttestationManifest{
+		Version:     1,
+		RepoType:    repoType,
+		OrgRepo:     orgRepo,
+		Revision:    revision,
+		Commit:      commitSha,
+		GeneratedAt: time.Now().Unix(),
+		Files:       make([]*query.AttestationFile, 0, len(pathsInfos)),
+		Missing:     make([]string, 0),
+	}
+	filesDir := util.ResolveDir(repoType, orgRepo, commitSha)
+	for _, pathsInfo := range pathsInfos {
+		etag := pathsInfo.Oid
+		if pathsInfo.Lfs.Oid != "" {
+			etag = pathsInfo.Lfs.Oid
+		}
+		digest, ok := m.fileDigest(filepath.Join(filesDir, util.CachePath(pathsInfo.Path)), etag)
+		if !ok {
+			manifest.Missing = append(manifest.Missing, pathsInfo.Path)
+			continue
+		}
+		if pathsInfo.Lfs.Oid != "" && digest != pathsInfo.Lfs.Oid {
+			zap.S().Warnf("sha256 of cached %s/%s is %s, differs from upstream %s", orgRepo, pathsInfo.Path, digest, pathsInfo.Lfs.Oid)
+		}
+		file := &query.AttestationFile{Path: pathsInfo.Path, Size: pathsInfo.Size, Sha256: digest, Etag: etag}
+		if stats, ok := downloader.GetFileStats(repoType, orgRepo, pathsInfo.Path); ok {
+			file.FetchedAt = stats.FetchedAt
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+	payload, err := sonic.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &query.AttestationResp{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+		KeyId:     util.AttestationKeyId(key.Public().(ed25519.PublicKey)),
+		Algorithm: "ed25519",
+		Manifest:  manifest,
+	}, nil
+}
+
+// fileDigest 计算已完整缓存文件内容的sha256，未完整缓存时返回false。
+func (m *MetaDao) fileDigest(filePath, etag string) (string, bool) {
+	digestKey := GetFileDigestKey(etag)
+	if v, ok := m.baseData.Cache.Get(digestKey); ok {
+		return v.(string), true
+	}
+	if !util.CacheFileExists(filePath) {
+		return "", false
+	}
+	fileSize, cachedSize, ok := downloader.CachedSize(filePath)
+	if !ok || cachedSize < fileSize {
+		return "", false
+	}
+	reader, err := downloader.OpenCachedFile(filePath)
+	if err != nil {
+		return "", false
+	}
+	defer reader.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, reader); err != nil {
+		zap.S().Warnf("digest %s err.%v", filePath, err)
+		return "", false
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	// 内容由etag唯一确定，摘要不会过期
+	m.baseData.Cache.Set(digestKey, digest, cache.NoExpiration)
+	return digest, true
+}
+
+// 读取本地paths-info缓存，客户端可能以分支名或sha请求过该文件。
+func (m *MetaDao) cachedPathsInfo(authorization, repoType, orgRepo, commitSha, revision, fileName string) (*common.PathsInfo, bool) {
+	for _, commit := range []string{commitSha, revision} {
+		apiPathInfoPath := util.ApiPathsInfoFile(util.GetApiRoot(authorization), repoType, orgRepo, commit, fileName)
+		if !util.CacheFileExists(apiPathInfoPath) {
+			continue
+		}
+		cacheContent, err := m.fileDao.ReadCacheRequest(apiPathInfoPath)
+		if err != nil {
+			continue
+		}
+		pathsInfos := make([]*common.PathsInfo, 0)
+		if err = sonic.Unmarshal(cacheContent.OriginContent, &pathsInfos); err == nil && len(pathsInfos) > 0 {
+			return pathsInfos[0], true
+		}
+	}
+	return nil, false
+}
+
+// RepoFilesIndex 返回某版本下已缓存paths-info的全部文件及其上级目录，按路径排序。
+// 首次调用时遍历paths-info目录建立索引并缓存在内存中，避免大仓库每次浏览都读取全部paths-info文件。
+func (m *MetaDao) RepoFilesIndex(repoType, orgRepo, commit string) ([]*common.PathsInfo, error) {
+	indexKey := GetRepoFilesIndexKey(repoType, orgRepo, commit)
+	if v, ok := m.baseData.Cache.Get(indexKey); ok {
+		return v.([]*common.PathsInfo), nil
+	}
+	pathsInfoShaDir := util.ApiPathsInfoDir(util.GetApiRoot(""), repoType, orgRepo, commit)
+	if !util.FileExists(pathsInfoShaDir) {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, "file not exists")
+	}
+	entries := make([]*common.PathsInfo, 0)
+	dirs := make(map[string]bool)
+	err := filepath.WalkDir(pathsInfoShaDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "paths-info_post.json" {
+			return nil
+		}
+		fileDir := filepath.Dir(path)
+		fileName, err := filepath.Rel(pathsInfoShaDir, fileDir)
+		if err != nil {
+			return err
+		}
+		fileName = util.CacheName(fileName)
+		entry := &common.PathsInfo{Type: "file", Path: fileName}
+		if cacheContent, err := m.fileDao.ReadCacheRequest(path); err != nil {
+			zap.S().Warnf("read %s err.%v", path, err)
+		} else {
+			pathsInfos := make([]common.PathsInfo, 0)
+			if err = sonic.Unmarshal(cacheContent.OriginContent, &pathsInfos); err != nil {
+				zap.S().Warnf("pathsInfo Unmarshal %s err.%v", path, err)
+			}
+			for i := range pathsInfos {
+				if pathsInfos[i].Path == fileName {
+					entry = &pathsInfos[i]
+					break
+				}
+			}
+		}
+		entries = append(entries, entry)
+		for dir := filepath.Dir(fileDir); dir != pathsInfoShaDir && strings.HasPrefix(dir, pathsInfoShaDir); dir = filepath.Dir(dir) {
+			rel, _ := filepath.Rel(pathsInfoShaDir, dir)
+			dirs[util.CacheName(rel)] = true
+		}
+		// 文件本身以目录形式存放，无需继续遍历其下级
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	for dir := range dirs {
+		entries = append(entries, &common.PathsInfo{Type: "directory", Path: dir})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	m.baseData.Cache.Set(indexKey, entries, consts.RepoFilesIndexExpiration)
+	return entries, nil
+}
+
+// Revalidate 忽略缓存有效期，重新从远端获取refs，重新解析本地已缓存的分支及标签，刷新其元数据与paths-info缓存，返回发生的变化。
+// 以commit sha缓存的内容不会变化，不做刷新。
+func (m *MetaDao) Revalidate(repoType, orgRepo, authorization string) (*query.RevalidateResp, error) {
+	if !config.SysConfig.Online() {
+		return nil, myerr.NewAppendCode(http.StatusServiceUnavailable, "revalidate is not available in offline mode")
+	}
+	apiRoot := util.GetApiRoot(authorization)
+	apiDir := util.ApiRepoDir(apiRoot, repoType, orgRepo)
+	if !util.IsDir(apiDir) {
+		return nil, myerr.NewErrorCode(http.StatusNotFound, consts.HfErrorCodeRepoNotFound, fmt.Sprintf("%s/%s is not cached", repoType, orgRepo))
+	}
+	result := &query.RevalidateResp{
+		Refs:      make([]*query.RefChange, 0),
+		Revisions: make([]*query.RevisionChange, 0),
+		PathsInfo: make([]*query.PathsInfoChange, 0),
+	}
+	refChanges, err := m.revalidateRefs(apiRoot, repoType, orgRepo, authorization)
+	if err != nil {
+		return nil, err
+	}
+	result.Refs = append(result.Refs, refChanges...)
+
+	entries, err := os.ReadDir(filepath.Join(apiDir, "revision"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		revision := util.CacheName(entry.Name())
+		if !entry.IsDir() || isCommitSha(revision) {
+			continue
+		}
+		change, pathsInfoChanges, err := m.revalidateRevision(apiRoot, repoType, orgRepo, revision, authorization)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			result.Revisions = append(result.Revisions, change)
+		}
+		result.PathsInfo = append(result.PathsInfo, pathsInfoChanges...)
+	}
+	zap.S().Infof("revalidate %s/%s, %d refs, %d revisions, %d paths-info changed", repoType, orgRepo,
+		len(result.Refs), len(result.Revisions), len(result.PathsInfo))
+	return result, nil
+}
+
+func (m *MetaDao) revalidateRefs(apiRoot, repoType, orgRepo, authorization string) ([]*query.RefChange, error) {
+	resp, err := m.RepoRefs(repoType, orgRepo, authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, myerr.NewErrorCode(resp.StatusCode, resp.GetKey(consts.HfHeaderErrorCode), fmt.Sprintf("request refs of %s/%s err", repoType, orgRepo))
+	}
+	if err = util.CheckMetaBody(resp.ExtractHeaders(resp.Headers), resp.Body, "branches"); err != nil {
+		return nil, err
+	}
+	newRefs := &GitRefs{}
+	if err = sonic.Unmarshal(resp.Body, newRefs); err != nil {
+		return nil, err
+	}
+	oldRefs := &GitRefs{}
+	refsPath := util.ApiRefsFile(apiRoot, repoType, orgRepo)
+	if util.CacheFileExists(refsPath) {
+		if cacheContent, err := m.fileDao.ReadCacheRequest(refsPath); err == nil {
+			_ = sonic.Unmarshal(cacheContent.OriginContent, oldRefs)
+		}
+	}
+	if err = util.MakeDirs(refsPath); err != nil {
+		return nil, err
+	}
+	if err = m.fileDao.WriteCacheRequest(refsPath, resp.StatusCode, resp.ExtractHeaders(resp.Headers), resp.Body); err != nil {
+		return nil, err
+	}
+	return diffGitRefs(oldRefs, newRefs), nil
+}
+
+func diffGitRefs(oldRefs, newRefs *GitRefs) []*query.RefChange {
+	targets := func(refs *GitRefs) map[string]string {
+		m := make(map[string]string)
+		for _, group := range [][]GitRef{refs.Branches, refs.Tags, refs.Converts, refs.PullRequests} {
+			for _, ref := range group {
+				m[ref.Ref] = ref.TargetCommit
+			}
+		}
+		return m
+	}
+	oldTargets, newTargets := targets(oldRefs), targets(newRefs)
+	changes := make([]*query.RefChange, 0)
+	for ref, newTarget := range newTargets {
+		if oldTargets[ref] != newTarget {
+			changes = append(changes, &query.RefChange{Ref: ref, Old: oldTargets[ref], New: newTarget})
+		}
+	}
+	for ref, oldTarget := range oldTargets {
+		if _, ok := newTargets[ref]; !ok {
+			changes = append(changes, &query.RefChange{Ref: ref, Old: oldTarget})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Ref < changes[j].Ref
+	})
+	return changes
+}
+
+// revalidateRevision 重新解析分支或标签指向的commit并刷新缓存，未变化时返回的change为nil。
+func (m *MetaDao) revalidateRevision(apiRoot, repoType, orgRepo, revision, authorization string) (*query.RevisionChange, []*query.PathsInfoChange, error) {
+	lock := m.lockDao.getMetaDataReqLock(GetMetaDataReqKey(repoType, orgRepo, revision, ""))
+	lock.Lock()
+	defer lock.Unlock()
+
+	var oldSha string
+	if cacheContent, err := m.readCacheMeta(apiRoot, repoType, orgRepo, revision, consts.RequestTypeGet, ""); err == nil {
+		var sha CommitHfSha
+		if err = sonic.Unmarshal(cacheContent.OriginContent, &sha); err == nil {
+			oldSha = sha.Sha
+		}
+	}
+	code, newSha, _, err := m.fileDao.getCommitHfRemote(context.Background(), repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, nil, myerr.NewAppendCode(code, fmt.Sprintf("resolve %s err.%v", revision, err))
+	}
+	if code != http.StatusOK && code != http.StatusTemporaryRedirect {
+		if code == http.StatusNotFound {
+			return &query.RevisionChange{Revision: revision, OldSha: oldSha}, nil, nil
+		}
+		return nil, nil, myerr.NewAppendCode(code, fmt.Sprintf("resolve %s err", revision))
+	}
+
+	// 其他token解析的结果同样过期，按前缀清除本地缓存
+	for _, prefix := range []string{fmt.Sprintf("meta/%s/%s/", orgRepo, revision), fmt.Sprintf("negative/%s/%s/%s/", repoType, orgRepo, revision)} {
+		for key := range m.baseData.Cache.Items() {
+			if strings.HasPrefix(key, prefix) {
+				m.baseData.Cache.Delete(key)
+			}
+		}
+	}
+	m.baseData.Shared.Delete(GetNegativeRepoKey(repoType, orgRepo, revision, authorization))
+	notify.RevisionMoved(repoType, orgRepo, revision, oldSha, newSha)
+	m.fileDao.setCommitShaCache(GetMetaShaRepoKey(orgRepo, revision, authorization), GetMetaShaRepoKey(orgRepo, newSha, authorization), newSha)
+
+	if err = m.requestAndSaveMeta(repoType, orgRepo, revision, newSha, authorization, "", nil); err != nil {
+		return nil, nil, err
+	}
+	if revision != "main" {
+		if err = m.linkApiMetaFiles(apiRoot, repoType, orgRepo, newSha, revision, ""); err != nil {
+			return nil, nil, err
+		}
+	}
+	// 带查询参数的元数据在下次请求时重新拉取
+	variants, _ := filepath.Glob(filepath.Join(filepath.Dir(util.ApiMetaFile(apiRoot, repoType, orgRepo, revision, consts.RequestTypeGet)), "meta_*_*.json"))
+	for _, variant := range variants {
+		if err = os.Remove(variant); err != nil {
+			zap.S().Warnf("remove %s err.%v", variant, err)
+		}
+	}
+
+	pathsInfoChanges, err := m.revalidatePathsInfo(apiRoot, repoType, orgRepo, revision, newSha, authorization)
+	if err != nil {
+		return nil, nil, err
+	}
+	if oldSha == newSha {
+		return nil, pathsInfoChanges, nil
+	}
+	return &query.RevisionChange{Revision: revision, OldSha: oldSha, NewSha: newSha}, pathsInfoChanges, nil
+}
+
+// revalidatePathsInfo 按新的commit批量请求以分支名缓存的paths-info，更新有变化的文件，删除新版本中已不存在的文件。
+func (m *MetaDao) revalidatePathsInfo(apiRoot, repoType, orgRepo, revision, commitSha, authorization string) ([]*query.PathsInfoChange, error) {
+	changes := make([]*query.PathsInfoChange, 0)
+	pathsInfoDir := util.ApiPathsInfoDir(apiRoot, repoType, orgRepo, revision)
+	oldInfos := make(map[string]*common.PathsInfo)
+	err := filepath.WalkDir(pathsInfoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || d.Name() != "paths-info_post.json" {
+			return nil
+		}
+		rel, err := filepath.Rel(pathsInfoDir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		pathsInfo := &common.PathsInfo{Path: util.CacheName(rel)}
+		if cacheContent, err := m.fileDao.ReadCacheRequest(path); err == nil {
+			pathsInfos := make([]*common.PathsInfo, 0)
+			if err = sonic.Unmarshal(cacheContent.OriginContent, &pathsInfos); err == nil && len(pathsInfos) > 0 {
+				pathsInfo = pathsInfos[0]
+			}
+		}
+		oldInfos[pathsInfo.Path] = pathsInfo
+		return nil
+	})
+	if err != nil || len(oldInfos) == 0 {
+		return changes, err
+	}
+
+	names := make([]string, 0, len(oldInfos))
+	for name := range oldInfos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	newInfos := make(map[string]*common.PathsInfo, len(names))
+	pathsInfoUri := fmt.Sprintf("/api/%s/%s/paths-info/%s", repoType, orgRepo, commitSha)
+	for start := 0; start < len(names); start += consts.PathsInfoBatchSize {
+		end := min(start+consts.PathsInfoBatchSize, len(names))
+		resp, err := m.fileDao.requestFilePathInfo(pathsInfoUri, authorization, names[start:end])
+		if err != nil {
+			return nil, err
+		}
+		pathsInfos := make([]*common.PathsInfo, 0)
+		if err = sonic.Unmarshal(resp.Body, &pathsInfos); err != nil {
+			return nil, err
+		}
+		for _, pathsInfo := range pathsInfos {
+			newInfos[pathsInfo.Path] = pathsInfo
+		}
+	}
+
+	for _, name := range names {
+		oldInfo, newInfo := oldInfos[name], newInfos[name]
+		apiPathInfoPath := util.ApiPathsInfoFile(apiRoot, repoType, orgRepo, revision, name)
+		if newInfo == nil {
+			if err = os.Remove(apiPathInfoPath); err != nil {
+				zap.S().Warnf("remove %s err.%v", apiPathInfoPath, err)
+			}
+			changes = append(changes, &query.PathsInfoChange{Revision: revision, Path: name, OldOid: oldInfo.Oid, OldSize: oldInfo.Size})
+			continue
+		}
+		if newInfo.Oid == oldInfo.Oid && newInfo.Size == oldInfo.Size {
+			continue
+		}
+		b, err := sonic.Marshal([]*common.PathsInfo{newInfo})
+		if err != nil {
+			return nil, err
+		}
+		if err = m.fileDao.WriteCacheRequest(apiPathInfoPath, http.StatusOK, map[string]string{"content-type": echo.MIMEApplicationJSON}, b); err != nil {
+			return nil, err
+		}
+		changes = append(changes, &query.PathsInfoChange{Revision: revision, Path: name, OldOid: oldInfo.Oid, NewOid: newInfo.Oid, OldSize: oldInfo.Size, NewSize: newInfo.Size})
+	}
+	m.baseData.Cache.Delete(GetRepoFilesIndexKey(repoType, orgRepo, revision))
+	return changes, nil
+}
+
+// RepoDiff 对比本地缓存与远端的某版本，返回刷新后新增、删除及内容变化的文件，不修改本地缓存。
+// 本地未缓存paths-info的文件无法比较内容，blob未完整缓存时视为变化。
+func (m *MetaDao) RepoDiff(ctx context.Context, repoType, orgRepo, revision, authorization string) (*query.RepoDiffResp, error) {
+	if !config.SysConfig.Online() {
+		return nil, myerr.NewAppendCode(http.StatusServiceUnavailable, "diff is not available in offline mode")
+	}
+	apiRoot := util.GetApiRoot(authorization)
+	result := &query.RepoDiffResp{Revision: revision, Files: make([]*query.RepoDiffFile, 0)}
+	localFiles := make(map[string]*common.PathsInfo)
+	if cacheContent, err := m.readCacheMeta(apiRoot, repoType, orgRepo, revision, consts.RequestTypeGet, ""); err == nil {
+		var sha CommitHfSha
+		if err = sonic.Unmarshal(cacheContent.OriginContent, &sha); err == nil {
+			result.LocalSha = sha.Sha
+			for _, sibling := range sha.Siblings {
+				localFiles[sibling.Rfilename], _ = m.cachedPathsInfo(authorization, repoType, orgRepo, sha.Sha, revision, sibling.Rfilename)
+			}
+		}
+	}
+	resp, err := m.fileDao.RemoteRequestMeta(ctx, consts.RequestTypeGet, repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, myerr.NewErrorCode(resp.StatusCode, resp.GetKey(consts.HfHeaderErrorCode), fmt.Sprintf("get %s/%s/%s meta err", repoType, orgRepo, revision))
+	}
+	var remote CommitHfSha
+	if err = sonic.Unmarshal(resp.Body, &remote); err != nil {
+		return nil, err
+	}
+	result.RemoteSha = remote.Sha
+	names := make([]string, 0, len(remote.Siblings))
+	for _, sibling := range remote.Siblings {
+		names = append(names, sibling.Rfilename)
+	}
+	remoteFiles := make(map[string]*common.PathsInfo, len(names))
+	pathsInfoUri := fmt.Sprintf("/api/%s/%s/paths-info/%s", repoType, orgRepo, remote.Sha)
+	for start := 0; start < len(names); start += consts.PathsInfoBatchSize {
+		end := min(start+consts.PathsInfoBatchSize, len(names))
+		resp, err := m.fileDao.requestFilePathInfo(pathsInfoUri, authorization, names[start:end])
+		if err != nil {
+			return nil, err
+		}
+		pathsInfos := make([]*common.PathsInfo, 0)
+		if err = sonic.Unmarshal(resp.Body, &pathsInfos); err != nil {
+			return nil, err
+		}
+		for _, pathsInfo := range pathsInfos {
+			remoteFiles[pathsInfo.Path] = pathsInfo
+		}
+	}
+
+	for _, name := range names {
+		newInfo := remoteFiles[name]
+		if newInfo == nil || newInfo.Type == "directory" {
+			continue
+		}
+		file := &query.RepoDiffFile{Path: name, NewOid: newInfo.Oid, NewSize: newInfo.Size, OldSize: -1}
+		if etag := PathsInfoEtag(newInfo); etag != "" {
+			fileSize, cachedSize, ok := downloader.CachedSize(util.BlobsFile(repoType, orgRepo, etag))
+			file.Cached = ok && fileSize == newInfo.Size && cachedSize == fileSize
+		}
+		oldInfo, exists := localFiles[name]
+		switch {
+		case !exists:
+			file.Status = query.DiffStatusAdded
+		case oldInfo != nil:
+			if oldInfo.Oid == newInfo.Oid && oldInfo.Size == newInfo.Size {
+				continue
+			}
+			file.Status, file.OldOid, file.OldSize = query.DiffStatusChanged, oldInfo.Oid, oldInfo.Size
+		case !file.Cached:
+			file.Status = query.DiffStatusChanged
+		default:
+			continue
+		}
+		result.Files = append(result.Files, file)
+		if !file.Cached {
+			result.DownloadSize += file.NewSize
+		}
+	}
+	for name, oldInfo := range localFiles {
+		if _, ok := remoteFiles[name]; ok {
+			continue
+		}
+		file := &query.RepoDiffFile{Path: name, Status: query.DiffStatusRemoved, OldSize: -1}
+		if oldInfo != nil {
+			file.OldOid, file.OldSize = oldInfo.Oid, oldInfo.Size
+		}
+		result.Files = append(result.Files, file)
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path < result.Files[j].Path
+	})
+	for _, file := range result.Files {
+		switch file.Status {
+		case query.DiffStatusAdded:
+			result.Added++
+		case query.DiffStatusRemoved:
+			result.Removed++
+		case query.DiffStatusChanged:
+			result.Changed++
+		}
+	}
+	return result, nil
+}
+
+func isCommitSha(revision string) bool {
+	if len(revision) != 40 {
+		return false
+	}
+	for _, r := range revision {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,162 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package dao
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ProxyFileGenerator 通用代理：按块缓存allowHosts中源站的文件，复用blob缓存及range下载逻辑。
+func (f *FileDao) ProxyFileGenerator(c echo.Context, rawURL, method string) error {
+	u, err := util.ParseProxyURL(rawURL)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	obj, err := f.proxyObject(c, u)
+	if err != nil {
+		zap.S().Warnf("proxy %s err:%v", u.Host, err)
+		return util.ResponseHfError(c, err)
+	}
+	var startPos, endPos int64
+	respHeaders := map[string]string{}
+	if obj.Size > 0 {
+		headRange := c.Request().Header.Get("Range")
+		if headRange != "" {
+			startPos, endPos = parseRangeParams(headRange, obj.Size)
+			if startPos >= obj.Size || startPos > endPos {
+				respHeaders["content-range"] = fmt.Sprintf("bytes */%d", obj.Size)
+				return util.ResponseHeaders(c, http.StatusRequestedRangeNotSatisfiable, respHeaders)
+			}
+			endPos = min(endPos, obj.Size-1)
+			respHeaders["content-range"] = fmt.Sprintf("bytes %d-%d/%d", startPos, endPos, obj.Size)
+		} else {
+			endPos = obj.Size - 1
+		}
+		endPos = endPos + 1
+	}
+	fileName := path.Base(u.Path)
+	if fileName == "/" || fileName == "." {
+		fileName = u.Hostname()
+	}
+	respHeaders[consts.HUGGINGFACE_HEADER_CONTENT_LENGTH] = util.Itoa(endPos - startPos)
+	respHeaders[consts.HUGGINGFACE_HEADER_ACCEPT_RANGES] = "bytes"
+	respHeaders[consts.HUGGINGFACE_HEADER_CONTENT_DISPOSITION] = contentDisposition(fileName)
+	if obj.Etag != "" {
+		respHeaders[consts.HUGGINGFACE_HEADER_ETAG] = obj.Etag
+	}
+	if obj.ContentType != "" {
+		respHeaders["content-type"] = obj.ContentType
+	}
+	if obj.LastModified != "" {
+		respHeaders["last-modified"] = obj.LastModified
+	}
+	blobsFile := util.ProxyBlobFile(u, obj.Blob)
+	if method == consts.RequestTypeHead {
+		return util.ResponseHeaders(c, http.StatusOK, respHeaders)
+	} else if method == consts.RequestTypeGet {
+		if err = util.MakeDirs(blobsFile); err != nil {
+			zap.S().Errorf("create %s dir err.%v", blobsFile, err)
+			return util.ErrorProxyError(c)
+		}
+		taskParam := &downloader.TaskParam{
+			BlobsFile: blobsFile,
+			FileName:  fileName,
+			FileSize:  obj.Size,
+			OrgRepo:   u.Host,
+			Domain:    fmt.Sprintf("%s://%s", u.Scheme, u.Host),
+			Uri:       u.RequestURI(),
+			Origin:    u.String(),
+			DataType:  consts.RepoTypeProxy,
+			Etag:      obj.Blob,
+		}
+		if config.SysConfig.Online() {
+			if err = util.CheckDiskSpace(blobsFile, obj.Size); err != nil {
+				return util.ResponseHfError(c, err)
+			}
+		}
+		if c.Request().Header.Get("Range") != "" {
+			f.readAhead(taskParam, util.ReadAheadSize(c.RealIP()), startPos, endPos)
+		}
+		return f.FileChunkGet(c, taskParam, startPos, endPos, respHeaders)
+	} else {
+		return util.ErrorMethodError(c)
+	}
+}
+
+// proxyObject 返回源站文件的信息，在genericProxy.expiration内或离线时使用已缓存的信息，否则重新探测源站。
+// 源站文件变化后使用新的缓存文件并删除旧的缓存。
+func (f *FileDao) proxyObject(c echo.Context, u *url.URL) (*util.ProxyObject, error) {
+	metaFile := util.ProxyMetaFile(u)
+	var cached *util.ProxyObject
+	if b, err := util.ReadFileToBytes(metaFile); err == nil {
+		cached = &util.ProxyObject{}
+		if err = sonic.Unmarshal(b, cached); err != nil {
+			zap.S().Warnf("unmarshal %s err.%v", metaFile, err)
+			cached = nil
+		}
+	}
+	if cached != nil {
+		fresh := time.Since(time.Unix(cached.CheckedAt, 0)) < config.SysConfig.GetGenericProxyExpiration()
+		if fresh || !config.SysConfig.Online() {
+			return cached, nil
+		}
+	} else if !config.SysConfig.Online() {
+		return nil, util.OfflineMissError(myerr.NewAppendCode(http.StatusNotFound, "proxy file is not cached"))
+	}
+	key := util.ProxyCacheKey(u)
+	obj, err := util.ProbeOrigin(c.Request().Context(), u.String())
+	if err != nil {
+		if cached != nil {
+			zap.S().Warnf("probe %s err, use cached meta.%v", u.Host, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	obj.Url = util.ProxyDisplayURL(u)
+	obj.CheckedAt = time.Now().Unix()
+	if cached != nil && cached.SameVersion(obj) {
+		obj.Blob = cached.Blob
+	} else {
+		obj.Blob = obj.BlobName(key)
+		if cached != nil && cached.Blob != obj.Blob {
+			zap.S().Infof("origin file %s changed, drop cached blob %s", obj.Url, cached.Blob)
+			if err = os.Remove(util.ProxyBlobFile(u, cached.Blob)); err != nil && !os.IsNotExist(err) {
+				zap.S().Warnf("remove old proxy blob err.%v", err)
+			}
+		}
+	}
+	if err = util.MakeDirs(metaFile); err != nil {
+		return nil, err
+	}
+	if err = util.WriteDataToFile(metaFile, obj); err != nil {
+		zap.S().Warnf("write %s err.%v", metaFile, err)
+	}
+	return obj, nil
+}
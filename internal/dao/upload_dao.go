@@ -0,0 +1,205 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package dao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dingospeed/internal/data"
+	"dingospeed/internal/downloader"
+	"dingospeed/pkg/config"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type UploadDao struct {
+	baseData *data.BaseData
+}
+
+func NewUploadDao(baseData *data.BaseData) *UploadDao {
+	return &UploadDao{
+		baseData: baseData,
+	}
+}
+
+// LfsUploadTarget LFS批量接口返回的原始上传地址，客户端通过镜像地址上传时据此转发。
+type LfsUploadTarget struct {
+	Url       string
+	RepoType  string
+	OrgRepo   string
+	Oid       string
+	Size      int64
+	Action    string
+	Multipart bool
+}
+
+func GetLfsProxyKey(id string) string {
+	return fmt.Sprintf("lfsproxy/%s", id)
+}
+
+// Forward 转发commit、preupload等上传相关请求，并原样回写响应。
+func (u *UploadDao) Forward(c echo.Context) error {
+	resp, err := util.ForwardUpload(c, c.Request().URL.RequestURI(), c.Request().Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return writeForwardResp(c, resp, nil)
+}
+
+func writeForwardResp(c echo.Context, resp *http.Response, body []byte) error {
+	response := c.Response()
+	for k, v := range resp.Header {
+		if body != nil && strings.EqualFold(k, "content-length") {
+			continue
+		}
+		response.Header()[k] = v
+	}
+	response.WriteHeader(resp.StatusCode)
+	var err error
+	if body != nil {
+		_, err = response.Write(body)
+	} else {
+		_, err = io.Copy(response, resp.Body)
+	}
+	if err != nil {
+		zap.S().Errorf("响应内容回传失败.%v", err)
+	}
+	return nil
+}
+
+// LfsBatch 转发LFS批量接口，将响应中的上传地址改写为镜像地址，使受限网络内的客户端也能完成上传。
+func (u *UploadDao) LfsBatch(c echo.Context, repoType, orgRepo string) error {
+	resp, err := util.ForwardUpload(c, c.Request().URL.RequestURI(), c.Request().Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return writeForwardResp(c, resp, nil)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var batch map[string]interface{}
+	if err = sonic.Unmarshal(body, &batch); err != nil {
+		zap.S().Warnf("unmarshal lfs batch resp err.%v", err)
+		return writeForwardResp(c, resp, body)
+	}
+	objects, _ := batch["objects"].([]interface{})
+	for _, o := range objects {
+		object, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		actions, ok := object["actions"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		oid, _ := object["oid"].(string)
+		size, _ := object["size"].(float64)
+		for actionName, a := range actions {
+			action, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			target := &LfsUploadTarget{RepoType: repoType, OrgRepo: orgRepo, Oid: oid, Size: int64(size), Action: actionName}
+			// 分片上传时，各分片地址位于header中
+			if header, ok := action["header"].(map[string]interface{}); ok {
+				for k, v := range header {
+					if partUrl, ok := v.(string); ok && strings.HasPrefix(partUrl, "http") {
+						target.Multipart = true
+						header[k] = u.proxyHref(c, &LfsUploadTarget{Url: partUrl, RepoType: repoType, OrgRepo: orgRepo, Oid: oid, Action: actionName, Multipart: true})
+					}
+				}
+			}
+			if href, ok := action["href"].(string); ok {
+				target.Url = href
+				action["href"] = u.proxyHref(c, target)
+			}
+		}
+	}
+	newBody, err := sonic.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	return writeForwardResp(c, resp, newBody)
+}
+
+// proxyHref 登记上传目标，返回客户端访问本服务时的中转地址。
+func (u *UploadDao) proxyHref(c echo.Context, target *LfsUploadTarget) string {
+	id := util.UUID()
+	u.baseData.Cache.Set(GetLfsProxyKey(id), target, config.SysConfig.GetUploadProxyTTL())
+	return fmt.Sprintf("%s%s/lfs-proxy/%s", util.RequestBaseURL(c), util.PathPrefix(c), id)
+}
+
+// LfsProxy 将客户端的上传请求转发到原始地址，开启cacheBlobs时同时将文件缓存到本地。
+func (u *UploadDao) LfsProxy(c echo.Context, id string) error {
+	v, ok := u.baseData.Cache.Get(GetLfsProxyKey(id))
+	if !ok {
+		return myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("upload url %s is expired or not exist", id))
+	}
+	target := v.(*LfsUploadTarget)
+	var (
+		body    io.Reader = c.Request().Body
+		tmpFile *os.File
+		hash    = sha256.New()
+		err     error
+	)
+	blobsFile := util.BlobsFile(target.RepoType, target.OrgRepo, target.Oid)
+	if config.SysConfig.Upload.CacheBlobs && target.Action == "upload" && !target.Multipart &&
+		c.Request().Method == http.MethodPut && target.Oid != "" && target.Size > 0 {
+		// 同一oid可能被并发上传，临时文件名各不相同
+		if err = util.MakeDirs(blobsFile); err == nil {
+			tmpFile, err = os.CreateTemp(filepath.Dir(blobsFile), filepath.Base(blobsFile)+".upload-*")
+		}
+		if err != nil {
+			zap.S().Warnf("create upload tmp file err.%v", err)
+		} else {
+			defer func() {
+				tmpFile.Close()
+				os.Remove(tmpFile.Name())
+			}()
+			body = io.TeeReader(body, io.MultiWriter(tmpFile, hash))
+		}
+	}
+	resp, err := util.ForwardUpload(c, target.Url, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if tmpFile != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		// 预签名地址不校验内容，只有内容与声明的oid及大小一致时才写入共享缓存
+		info, statErr := tmpFile.Stat()
+		if sum := hex.EncodeToString(hash.Sum(nil)); statErr != nil || sum != target.Oid || info.Size() != target.Size {
+			zap.S().Warnf("uploaded content of %s does not match its oid or size, not cached", target.Oid)
+		} else if err = downloader.ImportBlob(tmpFile.Name(), blobsFile, target.Size); err != nil {
+			zap.S().Warnf("cache uploaded blob %s err.%v", target.Oid, err)
+		}
+	}
+	return writeForwardResp(c, resp, nil)
+}
@@ -15,7 +15,12 @@
 package data
 
 import (
+	"time"
+
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/lock"
+	"dingospeed/pkg/redis"
 
 	"github.com/google/wire"
 	"github.com/patrickmn/go-cache"
@@ -26,15 +31,42 @@ var BaseDataProvider = wire.NewSet(NewBaseData)
 // 缓存预读取的文件块，默认每个文件16个块
 
 type BaseData struct {
-	Cache *cache.Cache
+	Cache  *cache.Cache
+	Shared *SharedCache // 多副本共享的缓存，未启用Redis时为nil
 }
 
 func NewBaseData() *BaseData {
 	gCache := cache.New(config.SysConfig.GetDefaultExpiration(), config.SysConfig.GetCleanupInterval())
 	initGlobal(gCache)
-	return &BaseData{
+	baseData := &BaseData{
 		Cache: gCache,
 	}
+	var client *redis.Client
+	if redisConf := config.SysConfig.Redis; redisConf.Enabled {
+		client = redis.NewClient(redisConf.Addr, redisConf.Password, redisConf.DB, time.Duration(redisConf.Timeout)*time.Millisecond, redisConf.PoolSize)
+		baseData.Shared = NewSharedCache(client, redisConf.KeyPrefix)
+	}
+	lock.SetDefault(newLockManager(client))
+	return baseData
+}
+
+// newLockManager 按配置创建全局锁管理器，锁名为被保护文件的绝对路径。
+func newLockManager(client *redis.Client) lock.Manager {
+	conf := config.SysConfig
+	switch conf.GetLockBackend() {
+	case consts.LockBackendLease:
+		return lock.NewLeaseManager(conf.GetLeaseTTL(), conf.GetLeasePath)
+	case consts.LockBackendRedis:
+		return lock.NewRedisManager(client, conf.GetLeaseTTL(), func(name string) string {
+			return conf.Redis.KeyPrefix + "lock:" + conf.GetLockKey(name)
+		})
+	case consts.LockBackendEtcd:
+		return lock.NewEtcdManager(conf.Lock.Etcd.Endpoints, conf.GetLockEtcdTimeout(), conf.GetLeaseTTL(), func(name string) string {
+			return conf.GetLockEtcdKeyPrefix() + conf.GetLockKey(name)
+		})
+	default:
+		return lock.NewFlockManager()
+	}
 }
 
 func initGlobal(gCache *cache.Cache) {
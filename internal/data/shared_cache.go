@@ -0,0 +1,68 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dingospeed/pkg/redis"
+
+	"go.uber.org/zap"
+)
+
+// SharedCache 基于Redis的共享缓存，作为本地缓存之后的第二级，Redis不可用时按未命中处理。
+// 方法均可在nil上调用，未启用时为空操作。
+type SharedCache struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewSharedCache(client *redis.Client, prefix string) *SharedCache {
+	return &SharedCache{client: client, prefix: prefix}
+}
+
+func (s *SharedCache) Get(key string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	v, err := s.client.Get(context.Background(), s.prefix+key)
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			zap.S().Warnf("shared cache get %s err.%v", key, err)
+		}
+		return "", false
+	}
+	return v, true
+}
+
+func (s *SharedCache) Set(key, value string, ttl time.Duration) {
+	if s == nil {
+		return
+	}
+	if err := s.client.Set(context.Background(), s.prefix+key, value, ttl); err != nil {
+		zap.S().Warnf("shared cache set %s err.%v", key, err)
+	}
+}
+
+func (s *SharedCache) Delete(key string) {
+	if s == nil {
+		return
+	}
+	if err := s.client.Del(context.Background(), s.prefix+key); err != nil {
+		zap.S().Warnf("shared cache delete %s err.%v", key, err)
+	}
+}
@@ -0,0 +1,406 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/migrate"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+)
+
+// 启动自检：检查远端连通性、TLS信任、磁盘性能、缓存布局版本、缓存文件完整性及时钟偏差，
+// 每项给出结果及处理建议，用于排查部署问题。
+
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+type Check struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail"`
+	Advice   string        `json:"advice,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+type Report struct {
+	Checks []*Check `json:"checks"`
+}
+
+// Count 返回指定状态的检查项数量。
+func (r *Report) Count(status string) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+type Options struct {
+	Timeout     time.Duration // 每项网络检查的超时时间
+	IOSize      int64         // 磁盘顺序写入测试的数据量
+	MaxSkew     time.Duration // 允许的时钟偏差，超过时告警，超过10倍时失败
+	MaxFiles    int           // 完整性检查最多扫描的文件数，0为不限制
+	SkipNetwork bool          // 跳过远端连通性、TLS及时钟偏差检查
+}
+
+func (o *Options) setDefaults() {
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.IOSize <= 0 {
+		o.IOSize = 64 << 20
+	}
+	if o.MaxSkew <= 0 {
+		o.MaxSkew = 30 * time.Second
+	}
+}
+
+// Run 依次执行所有检查。
+func Run(ctx context.Context, repos string, opts Options) *Report {
+	opts.setDefaults()
+	report := &Report{}
+	add := func(name string, f func() *Check) {
+		start := time.Now()
+		c := f()
+		c.Name = name
+		c.Duration = time.Since(start).Round(time.Millisecond)
+		report.Checks = append(report.Checks, c)
+	}
+	var upstreamDate time.Time
+	if opts.SkipNetwork || !config.SysConfig.Online() {
+		skipped := &Check{Status: StatusWarn, Detail: "skipped, server.online is false"}
+		if opts.SkipNetwork {
+			skipped.Detail = "skipped by -skip-network"
+		}
+		for _, name := range []string{"upstream", "tls", "clock"} {
+			c := *skipped
+			add(name, func() *Check { return &c })
+		}
+	} else {
+		add("upstream", func() *Check {
+			c, date := checkUpstream(ctx, opts.Timeout)
+			upstreamDate = date
+			return c
+		})
+		add("tls", func() *Check { return checkTLS(ctx, config.SysConfig.GetHFURLBase(), opts.Timeout) })
+		add("clock", func() *Check { return checkClock(upstreamDate, time.Now(), opts.MaxSkew) })
+	}
+	add("disk", func() *Check { return checkDisk(repos, opts.IOSize) })
+	add("layout", func() *Check { return checkLayout(repos) })
+	add("integrity", func() *Check { return checkIntegrity(repos, opts.MaxFiles) })
+	return report
+}
+
+// checkUpstream 向远端发起HEAD请求，同时返回响应的Date头用于检查时钟偏差。
+func checkUpstream(ctx context.Context, timeout time.Duration) (*Check, time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	base := config.SysConfig.GetHFURLBase()
+	start := time.Now()
+	resp, err := util.HeadContext(ctx, "/", nil)
+	if err != nil {
+		return &Check{Status: StatusFail, Detail: fmt.Sprintf("%s is unreachable: %v", base, err),
+			Advice: "check DNS, firewall and proxy settings (server.hfNetLoc, dynamicProxy, HTTPS_PROXY); set server.online=false to serve the cache only"}, time.Time{}
+	}
+	detail := fmt.Sprintf("%s responded %d in %s", base, resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	date, _ := http.ParseTime(resp.GetKey("date"))
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "upstream is reachable but unhealthy, retry later or switch server.hfNetLoc to another mirror"}, date
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "upstream rejected the request, check the configured upstream token"}, date
+	}
+	return &Check{Status: StatusOK, Detail: detail}, date
+}
+
+// checkTLS 按server.upstreamTls直接与远端握手并校验证书链，经由代理访问远端时连接失败只告警。
+func checkTLS(ctx context.Context, base string, timeout time.Duration) *Check {
+	u, err := url.Parse(base)
+	if err != nil {
+		return &Check{Status: StatusFail, Detail: fmt.Sprintf("invalid upstream url %s: %v", base, err)}
+	}
+	if u.Scheme != "https" {
+		return &Check{Status: StatusOK, Detail: fmt.Sprintf("%s does not use tls", base)}
+	}
+	upstreamTls, _ := config.SysConfig.GetUpstreamTLS(base)
+	tlsConfig, err := util.NewUpstreamTLSConfig(upstreamTls)
+	if err != nil {
+		return &Check{Status: StatusFail, Detail: fmt.Sprintf("invalid server.upstreamTls: %v", err), Advice: "fix caFile, certFile and keyFile in server.upstreamTls"}
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.ServerName = u.Hostname()
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: tlsConfig}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return tlsError(addr, err)
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	if tlsConfig.InsecureSkipVerify {
+		return &Check{Status: StatusWarn, Detail: "certificate verification is disabled", Advice: "remove server.upstreamTls.insecureSkipVerify outside of test environments"}
+	}
+	leaf := state.PeerCertificates[0]
+	detail := fmt.Sprintf("%s presented %q issued by %q, expires %s", addr, leaf.Subject.CommonName, leaf.Issuer.CommonName, leaf.NotAfter.Format(time.DateOnly))
+	if time.Until(leaf.NotAfter) < 14*24*time.Hour {
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "upstream certificate expires within 14 days"}
+	}
+	return &Check{Status: StatusOK, Detail: detail}
+}
+
+func tlsError(addr string, err error) *Check {
+	detail := fmt.Sprintf("tls handshake with %s failed: %v", addr, err)
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalid          x509.CertificateInvalidError
+		hostname         x509.HostnameError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return &Check{Status: StatusFail, Detail: detail,
+			Advice: "the certificate is signed by an unknown CA, usually a TLS-intercepting proxy; add its CA to server.upstreamTls.caFile"}
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return &Check{Status: StatusFail, Detail: detail, Advice: "the certificate is expired or not yet valid, check the system clock"}
+	case errors.As(err, &hostname):
+		return &Check{Status: StatusFail, Detail: detail, Advice: "the certificate does not match the host, check server.hfNetLoc and DNS"}
+	case errors.As(err, &invalid):
+		return &Check{Status: StatusFail, Detail: detail, Advice: "the certificate chain is invalid, check server.upstreamTls"}
+	}
+	// 只能经由代理访问远端时直连失败属于正常情况，连通性以upstream检查为准
+	return &Check{Status: StatusWarn, Detail: detail, Advice: "direct connection failed; ignore if upstream is only reachable through a proxy"}
+}
+
+// checkClock 比较远端响应的Date头与本机时间，偏差过大会导致签名地址、令牌及证书校验失败。
+func checkClock(upstream, now time.Time, maxSkew time.Duration) *Check {
+	if upstream.IsZero() {
+		return &Check{Status: StatusWarn, Detail: "upstream did not return a Date header"}
+	}
+	// Date头精确到秒
+	skew := now.Sub(upstream).Round(time.Second)
+	detail := fmt.Sprintf("local clock differs from upstream by %s", skew)
+	if skew < 0 {
+		skew = -skew
+	}
+	switch {
+	case skew > 10*maxSkew:
+		return &Check{Status: StatusFail, Detail: detail, Advice: "synchronize the clock with NTP (chronyd or systemd-timesyncd)"}
+	case skew > maxSkew:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "synchronize the clock with NTP (chronyd or systemd-timesyncd)"}
+	}
+	return &Check{Status: StatusOK, Detail: detail}
+}
+
+// checkDisk 检查缓存目录的可用空间，并写入ioSize字节测试顺序读写速度及fsync延迟。
+func checkDisk(repos string, ioSize int64) *Check {
+	advice := fmt.Sprintf("make sure %s exists and is writable by this user", repos)
+	if err := os.MkdirAll(repos, 0755); err != nil {
+		return &Check{Status: StatusFail, Detail: err.Error(), Advice: advice}
+	}
+	free, err := util.DiskFree(repos)
+	if err != nil {
+		return &Check{Status: StatusFail, Detail: err.Error(), Advice: advice}
+	}
+	bench, err := benchDisk(repos, ioSize)
+	if err != nil {
+		return &Check{Status: StatusFail, Detail: fmt.Sprintf("io test in %s failed: %v", repos, err), Advice: advice}
+	}
+	detail := fmt.Sprintf("free %s, write %s/s, read %s/s, fsync %s", util.ConvertBytesToHumanReadable(free),
+		util.ConvertBytesToHumanReadable(bench.writeSpeed), util.ConvertBytesToHumanReadable(bench.readSpeed), bench.fsync)
+	reserve := max(config.SysConfig.Download.DiskReserve, 0)
+	switch {
+	case free <= reserve:
+		return &Check{Status: StatusFail, Detail: detail, Advice: "free space is below download.diskReserve, enable disk cleaning or add storage"}
+	case free < reserve+10<<30:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "less than 10GiB available for new files"}
+	case bench.writeSpeed < 50<<20:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "sequential write is slower than 50MiB/s, downloads will be limited by the disk"}
+	case bench.fsync > 50*time.Millisecond:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "fsync is slow, check for network storage or an overloaded disk"}
+	}
+	return &Check{Status: StatusOK, Detail: detail}
+}
+
+type diskBench struct {
+	writeSpeed int64
+	readSpeed  int64
+	fsync      time.Duration // 4KiB写入后fsync的平均耗时
+}
+
+func benchDisk(dir string, size int64) (*diskBench, error) {
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	bench := &diskBench{}
+	chunk := make([]byte, 1<<20)
+	for i := range chunk {
+		chunk[i] = byte(i)
+	}
+	start := time.Now()
+	for written := int64(0); written < size; written += int64(len(chunk)) {
+		if _, err = f.Write(chunk[:min(int64(len(chunk)), size-written)]); err != nil {
+			return nil, err
+		}
+	}
+	if err = f.Sync(); err != nil {
+		return nil, err
+	}
+	bench.writeSpeed = speed(size, time.Since(start))
+	if _, err = f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	start = time.Now()
+	var read int64
+	for {
+		n, err := f.Read(chunk)
+		read += int64(n)
+		if err != nil {
+			break
+		}
+	}
+	bench.readSpeed = speed(read, time.Since(start))
+	const syncs = 16
+	start = time.Now()
+	for i := 0; i < syncs; i++ {
+		if _, err = f.WriteAt(chunk[:4096], int64(i)*4096); err != nil {
+			return nil, err
+		}
+		if err = f.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	bench.fsync = (time.Since(start) / syncs).Round(time.Microsecond)
+	return bench, nil
+}
+
+func speed(n int64, d time.Duration) int64 {
+	if d <= 0 {
+		d = time.Microsecond
+	}
+	return int64(float64(n) / d.Seconds())
+}
+
+func checkLayout(repos string) *Check {
+	version, err := migrate.LayoutVersion(repos)
+	if err != nil {
+		return &Check{Status: StatusFail, Detail: err.Error(), Advice: "the layout file is unreadable, restore it from backup or remove it to re-detect the layout"}
+	}
+	detail := fmt.Sprintf("cache layout version %d, supported version %d", version, migrate.CurrentLayoutVersion)
+	switch {
+	case version > migrate.CurrentLayoutVersion:
+		return &Check{Status: StatusFail, Detail: detail, Advice: "the cache was written by a newer dingospeed, upgrade this binary"}
+	case version < migrate.CurrentLayoutVersion:
+		return &Check{Status: StatusWarn, Detail: detail, Advice: "stop the service and run `dingospeed migrate`"}
+	}
+	return &Check{Status: StatusOK, Detail: detail}
+}
+
+// checkIntegrity 扫描{repos}/files，检查resolve目录下指向不存在blob的软链接及头部无法解析的blob。
+func checkIntegrity(repos string, maxFiles int) *Check {
+	var (
+		scanned, incomplete int
+		dangling, corrupt   []string
+	)
+	root := filepath.Join(repos, "files")
+	errLimit := errors.New("limit reached")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == root {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if maxFiles > 0 && scanned >= maxFiles {
+			return errLimit
+		}
+		rel, _ := filepath.Rel(root, path)
+		if d.Type()&fs.ModeSymlink != 0 {
+			scanned++
+			if _, err := os.Stat(path); err != nil {
+				dangling = append(dangling, rel)
+			}
+			return nil
+		}
+		if !strings.Contains(filepath.ToSlash(rel), "/blobs/") {
+			return nil
+		}
+		scanned++
+		if info, err := d.Info(); err != nil || info.Size() == 0 {
+			return nil // 刚创建尚未写入的blob
+		}
+		complete, _, err := downloader.BlobComplete(path)
+		if err != nil {
+			corrupt = append(corrupt, rel)
+		} else if !complete {
+			incomplete++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return &Check{Status: StatusFail, Detail: fmt.Sprintf("scan %s failed: %v", root, err)}
+	}
+	detail := fmt.Sprintf("scanned %d files, %d partially cached", scanned, incomplete)
+	if errors.Is(err, errLimit) {
+		detail += fmt.Sprintf(" (stopped at -max-files %d)", maxFiles)
+	}
+	if len(dangling) == 0 && len(corrupt) == 0 {
+		return &Check{Status: StatusOK, Detail: detail}
+	}
+	detail += fmt.Sprintf(", %d dangling links%s, %d corrupt blobs%s", len(dangling), samples(dangling), len(corrupt), samples(corrupt))
+	return &Check{Status: StatusFail, Detail: detail,
+		Advice: fmt.Sprintf("delete the listed files under %s, they are fetched again on the next request", root)}
+}
+
+func samples(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	if len(paths) > 3 {
+		return fmt.Sprintf(" (%s, ...)", strings.Join(paths[:3], ", "))
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(paths, ", "))
+}
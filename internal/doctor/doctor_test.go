@@ -0,0 +1,84 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dingospeed/internal/migrate"
+)
+
+func TestCheckClock(t *testing.T) {
+	now := time.Now()
+	if c := checkClock(now.Add(-5*time.Second), now, 30*time.Second); c.Status != StatusOK {
+		t.Fatalf("expected ok, got %+v", c)
+	}
+	if c := checkClock(now.Add(time.Minute), now, 30*time.Second); c.Status != StatusWarn {
+		t.Fatalf("expected warn, got %+v", c)
+	}
+	if c := checkClock(now.Add(-time.Hour), now, 30*time.Second); c.Status != StatusFail {
+		t.Fatalf("expected fail, got %+v", c)
+	}
+	if c := checkClock(time.Time{}, now, 30*time.Second); c.Status != StatusWarn {
+		t.Fatalf("expected warn without date, got %+v", c)
+	}
+}
+
+func TestCheckLayout(t *testing.T) {
+	repos := t.TempDir()
+	if c := checkLayout(repos); c.Status != StatusOK {
+		t.Fatalf("expected ok for empty repos, got %+v", c)
+	}
+	if err := migrate.WriteLayout(repos, migrate.CurrentLayoutVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkLayout(repos); c.Status != StatusFail {
+		t.Fatalf("expected fail for newer layout, got %+v", c)
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	repos := t.TempDir()
+	if c := checkIntegrity(repos, 0); c.Status != StatusOK {
+		t.Fatalf("expected ok for empty repos, got %+v", c)
+	}
+	repoDir := filepath.Join(repos, "files", "models", "org", "repo")
+	blobs := filepath.Join(repoDir, "blobs")
+	resolve := filepath.Join(repoDir, "resolve", "main")
+	for _, dir := range []string{blobs, resolve} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(blobs, "empty"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(blobs, "empty"), filepath.Join(resolve, "ok.bin")); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkIntegrity(repos, 0); c.Status != StatusOK {
+		t.Fatalf("expected ok, got %+v", c)
+	}
+	if err := os.WriteFile(filepath.Join(blobs, "broken"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(blobs, "missing"), filepath.Join(resolve, "missing.bin")); err != nil {
+		t.Fatal(err)
+	}
+	c := checkIntegrity(repos, 0)
+	if c.Status != StatusFail {
+		t.Fatalf("expected fail, got %+v", c)
+	}
+	t.Log(c.Detail)
+}
+
+func TestBenchDisk(t *testing.T) {
+	bench, err := benchDisk(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bench.writeSpeed <= 0 || bench.readSpeed <= 0 {
+		t.Fatalf("unexpected bench %+v", bench)
+	}
+}
@@ -0,0 +1,91 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"io"
+	"os"
+
+	"dingospeed/pkg/util"
+)
+
+// ImportBlob 将普通文件按块写入缓存文件，用于缓存通过镜像上传的文件。
+func ImportBlob(srcPath, blobsFile string, fileSize int64) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return ImportBlobReader(src, blobsFile, fileSize)
+}
+
+// ImportBlobReader 将src的内容按块写入缓存文件，src的长度必须为fileSize。
+func ImportBlobReader(src io.Reader, blobsFile string, fileSize int64) error {
+	if err := util.MakeDirs(blobsFile); err != nil {
+		return err
+	}
+	dingFile, err := GetInstance().GetDingFile(blobsFile, fileSize)
+	if err != nil {
+		return err
+	}
+	defer GetInstance().ReleasedDingFile(blobsFile)
+	blockSize := dingFile.GetBlockSize()
+	buf := make([]byte, blockSize)
+	for blockIndex := int64(0); blockIndex*blockSize < fileSize; blockIndex++ {
+		n, err := io.ReadFull(src, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if err = dingFile.WriteBlock(blockIndex, dingFile.padBlock(buf[:n])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportRanges 从其他格式的缓存导入数据，readRange返回[start,end)范围的数据，范围内数据不完整时返回false，对应的块跳过。
+// 已存在的块不会重复写入，返回本次写入的块数。
+func ImportRanges(blobsFile string, fileSize int64, readRange func(start, end int64) ([]byte, bool, error)) (int64, error) {
+	if err := util.MakeDirs(blobsFile); err != nil {
+		return 0, err
+	}
+	dingFile, err := GetInstance().GetDingFile(blobsFile, fileSize)
+	if err != nil {
+		return 0, err
+	}
+	defer GetInstance().ReleasedDingFile(blobsFile)
+	blockSize := dingFile.GetBlockSize()
+	var imported int64
+	for blockIndex := int64(0); blockIndex*blockSize < fileSize; blockIndex++ {
+		if ok, err := dingFile.HasBlock(blockIndex); err != nil {
+			return imported, err
+		} else if ok {
+			continue
+		}
+		start := blockIndex * blockSize
+		data, ok, err := readRange(start, min(start+blockSize, fileSize))
+		if err != nil {
+			return imported, err
+		}
+		if !ok {
+			continue
+		}
+		if err = dingFile.WriteBlock(blockIndex, dingFile.padBlock(data)); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
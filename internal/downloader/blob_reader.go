@@ -0,0 +1,149 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// ReadCachedRange 读取缓存文件[start,end]范围内的数据，end超出文件大小时截断，范围内有未缓存的块时返回false。
+func ReadCachedRange(path string, start, end int64) ([]byte, int64, bool) {
+	blobsFile, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, 0, false
+	}
+	dingFile, err := GetInstance().GetDingFile(blobsFile, 0)
+	if err != nil {
+		return nil, 0, false
+	}
+	defer GetInstance().ReleasedDingFile(blobsFile)
+	fileSize := dingFile.GetFileSize()
+	if fileSize == 0 || start >= fileSize {
+		return nil, fileSize, fileSize > 0
+	}
+	if end >= fileSize {
+		end = fileSize - 1
+	}
+	blockSize := dingFile.GetBlockSize()
+	data := make([]byte, 0, end-start+1)
+	for pos := start; pos <= end; {
+		blockIndex, blockStartPos, blockEndPos := GetBlockInfo(pos, blockSize, fileSize)
+		block, err := dingFile.ReadBlock(blockIndex)
+		if err != nil || block == nil {
+			return nil, fileSize, false
+		}
+		stop := min(blockEndPos, end+1)
+		data = append(data, block[pos-blockStartPos:stop-blockStartPos]...)
+		pos = stop
+	}
+	return data, fileSize, true
+}
+
+// CachedSize 返回缓存文件的大小及已下载完成的字节数。
+func CachedSize(path string) (int64, int64, bool) {
+	blobsFile, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	dingFile, err := GetInstance().GetDingFile(blobsFile, 0)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer GetInstance().ReleasedDingFile(blobsFile)
+	fileSize, blockSize := dingFile.GetFileSize(), dingFile.GetBlockSize()
+	var cachedSize int64
+	for blockIndex := int64(0); blockIndex*blockSize < fileSize; blockIndex++ {
+		if ok, err := dingFile.HasBlock(blockIndex); err == nil && ok {
+			cachedSize += min(blockSize, fileSize-blockIndex*blockSize)
+		}
+	}
+	return fileSize, cachedSize, true
+}
+
+// CachedFileReader 按块顺序读取已完整缓存的文件，遇到未缓存的块时返回错误。
+type CachedFileReader struct {
+	blobsFile  string
+	dingFile   *DingCache
+	pos        int64
+	blockIndex int64
+	block      []byte
+}
+
+// OpenCachedFile 打开缓存文件用于顺序读取，调用方需Close以释放文件句柄。
+func OpenCachedFile(path string) (*CachedFileReader, error) {
+	blobsFile, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	dingFile, err := GetInstance().GetDingFile(blobsFile, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &CachedFileReader{blobsFile: blobsFile, dingFile: dingFile, blockIndex: -1}, nil
+}
+
+func (r *CachedFileReader) Size() int64 {
+	return r.dingFile.GetFileSize()
+}
+
+func (r *CachedFileReader) Read(p []byte) (int, error) {
+	fileSize := r.dingFile.GetFileSize()
+	if r.pos >= fileSize {
+		return 0, io.EOF
+	}
+	blockIndex, blockStartPos, blockEndPos := GetBlockInfo(r.pos, r.dingFile.GetBlockSize(), fileSize)
+	if blockIndex != r.blockIndex {
+		block, err := r.dingFile.ReadBlock(blockIndex)
+		if err != nil {
+			return 0, err
+		}
+		if block == nil {
+			return 0, fmt.Errorf("block %d of %s is not cached", blockIndex, r.blobsFile)
+		}
+		r.block, r.blockIndex = block, blockIndex
+	}
+	n := copy(p, r.block[r.pos-blockStartPos:blockEndPos-blockStartPos])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// ReadAt 随机读取，供zip等需要随机访问的格式使用。
+func (r *CachedFileReader) ReadAt(p []byte, off int64) (int, error) {
+	fileSize, blockSize := r.dingFile.GetFileSize(), r.dingFile.GetBlockSize()
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= fileSize {
+			return n, io.EOF
+		}
+		blockIndex, blockStartPos, blockEndPos := GetBlockInfo(pos, blockSize, fileSize)
+		block, err := r.dingFile.ReadBlock(blockIndex)
+		if err != nil {
+			return n, err
+		}
+		if block == nil {
+			return n, fmt.Errorf("block %d of %s is not cached", blockIndex, r.blobsFile)
+		}
+		n += copy(p[n:], block[pos-blockStartPos:blockEndPos-blockStartPos])
+	}
+	return n, nil
+}
+
+func (r *CachedFileReader) Close() error {
+	GetInstance().ReleasedDingFile(r.blobsFile)
+	return nil
+}
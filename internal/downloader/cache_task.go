@@ -17,6 +17,8 @@ package downloader
 import (
 	"context"
 
+	"dingospeed/pkg/common"
+
 	"go.uber.org/zap"
 )
 
@@ -28,14 +30,17 @@ type TaskParam struct {
 	BlobsFile     string
 	FileName      string
 	FileSize      int64
-	ResponseChan  chan []byte
+	Stream        *common.StreamPipe
 	OrgRepo       string
 	Authorization string
 	Domain        string
 	Uri           string
+	Origin        string // 通用代理的源站地址，非空时直接向源站请求
 	DataType      string
 	Etag          string
 	Cancel        context.CancelFunc
+	Transfer      *Transfer
+	FromClient    bool // 由客户端请求发起的下载，预读、预取、预热等后台传输为false
 }
 
 type DownloadTask struct {
@@ -44,11 +49,12 @@ type DownloadTask struct {
 	RangeEndPos   int64
 	TaskSize      int
 	FileName      string
-	DingFile      *DingCache      `json:"-"`
-	ResponseChan  chan []byte     `json:"-"`
-	Context       context.Context `json:"-"`
+	DingFile      *DingCache         `json:"-"`
+	Stream        *common.StreamPipe `json:"-"`
+	Context       context.Context    `json:"-"`
 	OrgRepo       string
 	Preheat       bool
+	Transfer      *Transfer `json:"-"`
 }
 
 func (d *DownloadTask) GetTaskNo() int {
@@ -89,6 +95,9 @@ func (c *CacheFileTask) OutResult() {
 			zap.S().Errorf("for cache ctx err :%s, %v", c.FileName, c.Context.Err())
 			return
 		}
+		if c.Stream.Detached() {
+			return // 客户端已断开，已缓存的数据无需再读取
+		}
 		_, blockStartPos, blockEndPos := GetBlockInfo(curPos, c.DingFile.GetBlockSize(), c.DingFile.GetFileSize())
 		hasBlockBool, err := c.DingFile.HasBlock(curBlock)
 		if err != nil {
@@ -116,11 +125,11 @@ func (c *CacheFileTask) OutResult() {
 			ePos = rawLen
 		}
 		chunk := rawBlock[sPos:ePos]
-		select {
-		case c.ResponseChan <- chunk:
-		case <-c.Context.Done():
+		if _, err = c.Stream.Write(chunk); err != nil {
+			zap.S().Warnf("cache out stream closed:%s, %v", c.FileName, err)
 			return
 		}
+		c.Transfer.AddSent(int64(len(chunk)))
 		curPos += int64(len(chunk))
 	}
 	if curPos != c.RangeEndPos {
@@ -128,7 +137,3 @@ func (c *CacheFileTask) OutResult() {
 	}
 	zap.S().Infof("cache out:%s/%s, taskNo:%d, size:%d, startPos:%d, endPos:%d", c.OrgRepo, c.FileName, c.TaskNo, c.TaskSize, c.RangeStartPos, c.RangeEndPos)
 }
-
-func (c *CacheFileTask) GetResponseChan() chan []byte {
-	return c.ResponseChan
-}
@@ -16,13 +16,17 @@ package downloader
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	cache "dingospeed/internal/data"
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/lock"
 	"dingospeed/pkg/util"
 
 	"go.uber.org/zap"
@@ -30,12 +34,16 @@ import (
 
 const (
 	CURRENT_OLAH_CACHE_VERSION = 8
+	// 版本号的该位表示数据块使用AES-GCM加密存储
+	ENCRYPTED_VERSION_FLAG uint64 = 1 << 32
 	// DEFAULT_BLOCK_MASK_MAX     = 30
 	DEFAULT_BLOCK_MASK_MAX uint64 = 1024 * 1024
 
 	cost = 1
 )
 
+var errPreallocateUnsupported = errors.New("preallocate is not supported")
+
 // DingCache 结构体表示 Olah 缓存文件
 type DingCache struct {
 	path       string
@@ -43,6 +51,13 @@ type DingCache struct {
 	isOpen     bool
 	headerLock sync.RWMutex
 	fileLock   sync.RWMutex
+
+	aead        cipher.AEAD  // 数据块加密存储时使用
+	lease       lock.Manager // 共享缓存卷时的写入租约，锁名为缓存文件路径
+	writable    atomic.Bool
+	leaseTime   atomic.Int64 // 上次获取或续期租约的时间
+	refreshTime atomic.Int64 // 非租约持有者上次从磁盘刷新头部的时间
+	dirty       atomic.Bool  // 是否有尚未刷盘的写入
 }
 
 func NewDingCache(path string, blockSize int64) (*DingCache, error) {
@@ -52,6 +67,7 @@ func NewDingCache(path string, blockSize int64) (*DingCache, error) {
 		isOpen:     false,
 		headerLock: sync.RWMutex{},
 	}
+	dingCache.initLease(path)
 	if err := dingCache.Open(path, blockSize); err != nil {
 		return nil, err
 	}
@@ -73,7 +89,12 @@ func (c *DingCache) Open(path string, blockSize int64) error {
 		defer f.Close()
 		c.header = &DingCacheHeader{}
 		if err = c.header.Read(f); err != nil {
-			c.header = NewDingCacheHeader(CURRENT_OLAH_CACHE_VERSION, uint64(blockSize), 0)
+			c.header = newCacheHeader(blockSize)
+			if !c.Writable() {
+				// 其他副本正在写入该文件，头部可能尚未写完，不覆盖。
+				c.isOpen = true
+				return c.initCipher()
+			}
 			if err = c.header.Write(f); err != nil {
 				return err
 			}
@@ -85,12 +106,16 @@ func (c *DingCache) Open(path string, blockSize int64) error {
 		if err != nil {
 			return err
 		}
+		util.AddCacheIndex(path)
 		defer f.Close()
-		c.header = NewDingCacheHeader(CURRENT_OLAH_CACHE_VERSION, uint64(blockSize), 0)
+		c.header = newCacheHeader(blockSize)
 		if err = c.header.Write(f); err != nil {
 			return err
 		}
 	}
+	if err := c.initCipher(); err != nil {
+		return err
+	}
 	c.isOpen = true
 	return nil
 }
@@ -102,6 +127,12 @@ func (c *DingCache) Close() error {
 	}
 	c.fileLock.Lock()
 	defer c.fileLock.Unlock()
+	if config.SysConfig != nil && config.SysConfig.GetSyncPolicy() != consts.SyncPolicyNever {
+		if err := c.Sync(); err != nil {
+			zap.S().Errorf("sync %s err.%v", c.path, err)
+		}
+	}
+	c.releaseLease()
 	// 20250619 fix when the file is read, the update date is modified
 	// if err := c.flushHeader(); err != nil {
 	// 	return err
@@ -126,6 +157,19 @@ func (c *DingCache) flushHeader() error {
 	return c.header.Write(f)
 }
 
+// Sync 将已写入的数据块和头部刷到磁盘。
+func (c *DingCache) Sync() error {
+	if !c.dirty.Swap(false) {
+		return nil
+	}
+	f, err := os.OpenFile(c.path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
 func (c *DingCache) GetPath() string {
 	return c.path
 }
@@ -165,6 +209,7 @@ func (c *DingCache) setHeaderBlock(blockIndex int64) error {
 }
 
 func (c *DingCache) HasBlock(blockIndex int64) (bool, error) {
+	c.refreshHeader()
 	c.headerLock.RLock()
 	defer c.headerLock.RUnlock()
 	return c.header.BlockMask.Test(uint64(blockIndex))
@@ -202,23 +247,18 @@ func (c *DingCache) ReadBlock(blockIndex int64) ([]byte, error) {
 	if !hasBlock {
 		return nil, nil
 	}
-	offset := c.getHeaderSize() + blockIndex*c.GetBlockSize()
 	f, err := os.OpenFile(c.path, os.O_RDONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	if _, err := f.Seek(offset, 0); err != nil {
-		return nil, err
-	}
-	rawBlock := make([]byte, c.GetBlockSize()) // 读取当前块（blockIndex）的数据
-	if _, err := f.Read(rawBlock); err != nil {
+	block, err := c.readBlockAt(f, blockIndex) // 读取当前块（blockIndex）的数据
+	if err != nil {
 		return nil, err
 	}
 	if config.SysConfig.EnableReadBlockCache() {
 		c.readBlockAndCache(f, blockIndex)
 	}
-	block := c.padBlock(rawBlock)
 	return block, nil
 }
 
@@ -240,12 +280,11 @@ func (c *DingCache) readBlockAndCache(f *os.File, blockIndex int64) {
 		}
 		if hasNextBlock {
 			key := c.getBlockKey(newOffsetBlock)
-			prefetchRawBlock := make([]byte, c.GetBlockSize())
-			if _, err := f.Read(prefetchRawBlock); err != nil {
+			blockByte, err := c.readBlockAt(f, newOffsetBlock)
+			if err != nil {
 				zap.S().Errorf("read err. newOffsetBlock:%d, %v", newOffsetBlock, err)
 				break
 			}
-			blockByte := c.padBlock(prefetchRawBlock)
 			cache.FileBlockCache.Set(key, blockByte)
 			// 删除上一个缓存周期的内容，释放内存
 			oldOffsetBlock := newOffsetBlock - config.SysConfig.GetPrefetchBlocks() - 1
@@ -278,7 +317,11 @@ func (c *DingCache) WriteBlock(blockIndex int64, blockBytes []byte) error {
 	if int64(len(blockBytes)) != c.GetBlockSize() {
 		return errors.New("block size does not match the cache's block size")
 	}
-	offset := c.getHeaderSize() + blockIndex*c.GetBlockSize()
+	if !c.Writable() {
+		zap.S().Debugf("skip write block %d of %s, lease is held by other replica", blockIndex, c.path)
+		return nil
+	}
+	offset := c.blockOffset(blockIndex)
 	f, err := os.OpenFile(c.path, os.O_RDWR, 0644)
 	if err != nil {
 		return err
@@ -288,14 +331,13 @@ func (c *DingCache) WriteBlock(blockIndex int64, blockBytes []byte) error {
 		return err
 	}
 	if (blockIndex+1)*c.GetBlockSize() > c.GetFileSize() {
-		realBlockBytes := blockBytes[:c.GetFileSize()-blockIndex*c.GetBlockSize()]
-		if _, err = f.Write(realBlockBytes); err != nil {
-			return err
-		}
-	} else {
-		if _, err = f.Write(blockBytes); err != nil {
-			return err
-		}
+		blockBytes = blockBytes[:c.GetFileSize()-blockIndex*c.GetBlockSize()]
+	}
+	if c.aead != nil {
+		blockBytes = c.aead.Seal(nil, blockNonce(blockIndex), blockBytes, nil)
+	}
+	if _, err = f.Write(blockBytes); err != nil {
+		return err
 	}
 	c.fileLock.Lock()
 	defer c.fileLock.Unlock()
@@ -305,6 +347,7 @@ func (c *DingCache) WriteBlock(blockIndex int64, blockBytes []byte) error {
 	if err = c.flushHeader(); err != nil {
 		return err
 	}
+	c.dirty.Store(true)
 	// key := c.getBlockKey(blockIndex)  不需要删除，本来就没有
 	// cache.FileBlockCache.Del(key)
 	return nil
@@ -316,6 +359,10 @@ func (c *DingCache) Resize(fileSize int64) error {
 	}
 	bs := c.GetBlockSize()
 	newBlockNum := (fileSize + bs - 1) / bs
+	if !c.Writable() {
+		// 由持有租约的副本扩展文件，当前副本只更新内存中的头部。
+		return c.resizeHeader(newBlockNum, fileSize)
+	}
 	c.fileLock.Lock()
 	defer c.fileLock.Unlock()
 	if err := c.resizeFileSize(fileSize); err != nil {
@@ -345,6 +392,15 @@ func (c *DingCache) resizeFileSize(fileSize int64) error {
 	}
 	defer f.Close()
 	newBinSize := c.getHeaderSize() + fileSize
+	if c.aead != nil {
+		newBinSize += (fileSize + c.GetBlockSize() - 1) / c.GetBlockSize() * int64(c.aead.Overhead())
+	}
+	if config.SysConfig != nil && config.SysConfig.Cache.Preallocate {
+		done, err := c.preallocate(f, newBinSize)
+		if done || err != nil {
+			return err
+		}
+	}
 	if _, err = f.Seek(newBinSize-1, 0); err != nil {
 		return err
 	}
@@ -357,6 +413,30 @@ func (c *DingCache) resizeFileSize(fileSize int64) error {
 	return nil
 }
 
+// preallocate 预分配扩展部分的磁盘空间，空间不足时恢复原大小并返回错误，文件系统不支持时返回false，由调用方扩展为稀疏文件。
+func (c *DingCache) preallocate(f *os.File, newBinSize int64) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	curSize := fi.Size()
+	if newBinSize <= curSize {
+		return false, nil
+	}
+	err = preallocate(f, curSize, newBinSize-curSize)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, errPreallocateUnsupported) {
+		zap.S().Debugf("preallocate is not supported for %s, fallback to sparse file", c.path)
+		return false, nil
+	}
+	if terr := f.Truncate(curSize); terr != nil {
+		zap.S().Warnf("truncate %s after preallocate failure err.%v", c.path, terr)
+	}
+	return false, fmt.Errorf("preallocate %d bytes for %s: %w", newBinSize-curSize, c.path, err)
+}
+
 func (c *DingCache) resizeHeader(blockNum, fileSize int64) error {
 	c.headerLock.RLock()
 	defer c.headerLock.RUnlock()
@@ -0,0 +1,97 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"dingospeed/pkg/config"
+)
+
+// 启用加密时新建的缓存文件在版本号中标记加密位。
+func newCacheHeader(blockSize int64) *DingCacheHeader {
+	version := uint64(CURRENT_OLAH_CACHE_VERSION)
+	if config.SysConfig != nil && config.SysConfig.EnableEncryption() {
+		version |= ENCRYPTED_VERSION_FLAG
+	}
+	return NewDingCacheHeader(version, uint64(blockSize), 0)
+}
+
+// 加密文件的每个数据块使用AES-GCM单独加密，密钥由主密钥和文件名（etag）派生，nonce为块编号；
+// 同一blob的内容不会变化，重写同一块时明文相同，不会因nonce重复泄露信息。
+func (c *DingCache) initCipher() error {
+	if !c.header.Encrypted() || c.aead != nil {
+		return nil
+	}
+	if config.SysConfig == nil {
+		return errors.New("encrypted cache file requires encryption key")
+	}
+	masterKey, err := config.SysConfig.GetEncryptionKey()
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(filepath.Base(c.path)))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return err
+	}
+	c.aead, err = cipher.NewGCM(block)
+	return err
+}
+
+func blockNonce(blockIndex int64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], uint64(blockIndex))
+	return nonce
+}
+
+// 加密存储时每个块后附带GCM校验标签。
+func (c *DingCache) blockOffset(blockIndex int64) int64 {
+	stride := c.GetBlockSize()
+	if c.aead != nil {
+		stride += int64(c.aead.Overhead())
+	}
+	return c.getHeaderSize() + blockIndex*stride
+}
+
+func (c *DingCache) readBlockAt(f *os.File, blockIndex int64) ([]byte, error) {
+	blockSize := c.GetBlockSize()
+	if c.aead == nil {
+		rawBlock := make([]byte, blockSize)
+		if n, err := f.ReadAt(rawBlock, c.blockOffset(blockIndex)); err != nil && (err != io.EOF || n == 0) {
+			return nil, err
+		}
+		return rawBlock, nil
+	}
+	realLen := min(blockSize, c.GetFileSize()-blockIndex*blockSize)
+	sealed := make([]byte, realLen+int64(c.aead.Overhead()))
+	if _, err := f.ReadAt(sealed, c.blockOffset(blockIndex)); err != nil {
+		return nil, err
+	}
+	rawBlock, err := c.aead.Open(sealed[:0], blockNonce(blockIndex), sealed, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.padBlock(rawBlock), nil
+}
@@ -77,15 +77,21 @@ func (h *DingCacheHeader) ValidHeader() error {
 	if h.FileSize > h.BlockMaskSize*h.BlockSize {
 		return fmt.Errorf("the size of file %d is out of the max capability of container (%d * %d)", h.FileSize, h.BlockMaskSize, h.BlockSize)
 	}
-	if h.Version < CURRENT_OLAH_CACHE_VERSION {
+	version := h.Version &^ ENCRYPTED_VERSION_FLAG
+	if version < CURRENT_OLAH_CACHE_VERSION {
 		return fmt.Errorf("the Olah Cache file is created by older version Olah. Please remove cache files and retry")
 	}
-	if h.Version > CURRENT_OLAH_CACHE_VERSION {
+	if version > CURRENT_OLAH_CACHE_VERSION {
 		return fmt.Errorf("the Olah Cache file is created by newer version Olah. Please remove cache files and retry")
 	}
 	return nil
 }
 
+// Encrypted 数据块是否加密存储
+func (h *DingCacheHeader) Encrypted() bool {
+	return h.Version&ENCRYPTED_VERSION_FLAG != 0
+}
+
 // Write 将头部信息写入文件流
 func (h *DingCacheHeader) Write(f *os.File) error {
 	if _, err := f.Write(h.MagicNumber[:]); err != nil {
@@ -108,3 +114,22 @@ func (h *DingCacheHeader) Write(f *os.File) error {
 	}
 	return nil
 }
+
+// BlobComplete 读取缓存文件的头部，判断所有块是否均已写入，同时返回文件大小。
+func BlobComplete(path string) (bool, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, 0, err
+	}
+	defer f.Close()
+	header := &DingCacheHeader{}
+	if err = header.Read(f); err != nil {
+		return false, 0, err
+	}
+	for i := uint64(0); i < header.BlockNumber; i++ {
+		if ok, err := header.BlockMask.Test(i); err != nil || !ok {
+			return false, int64(header.FileSize), err
+		}
+	}
+	return true, int64(header.FileSize), nil
+}
@@ -0,0 +1,111 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/lock"
+
+	"go.uber.org/zap"
+)
+
+// 共享缓存卷时，同一缓存文件只允许持有写入租约的副本写入数据块和头部，其他副本只读，并周期性从磁盘刷新头部。
+func (c *DingCache) initLease(path string) {
+	if config.SysConfig == nil || !config.SysConfig.EnableSharedVolume() {
+		return
+	}
+	c.lease = lock.Default()
+	c.acquireLease()
+}
+
+func (c *DingCache) acquireLease() bool {
+	ok, err := c.lease.TryLock(context.Background(), c.path)
+	if err != nil {
+		zap.S().Warnf("acquire lease %s err.%v", c.path, err)
+	}
+	c.writable.Store(ok)
+	c.leaseTime.Store(time.Now().UnixNano())
+	return ok
+}
+
+// Writable 当前副本是否可以写入该缓存文件，持有租约时按周期续期，未持有时周期性尝试接管。
+func (c *DingCache) Writable() bool {
+	if c.lease == nil {
+		return true
+	}
+	if time.Since(time.Unix(0, c.leaseTime.Load())) < config.SysConfig.GetLeaseTTL()/3 {
+		return c.writable.Load()
+	}
+	if c.writable.Load() {
+		ok, err := c.lease.Renew(context.Background(), c.path)
+		if err != nil {
+			zap.S().Warnf("renew lease %s err.%v", c.path, err)
+		}
+		c.writable.Store(ok)
+		c.leaseTime.Store(time.Now().UnixNano())
+	} else if c.acquireLease() {
+		// 接管前其他副本可能已写入数据块，重新加载头部。
+		c.reloadHeader()
+	}
+	return c.writable.Load()
+}
+
+func (c *DingCache) releaseLease() {
+	if c.lease == nil || !c.writable.Load() {
+		return
+	}
+	if err := c.lease.Unlock(context.Background(), c.path); err != nil {
+		zap.S().Warnf("release lease %s err.%v", c.path, err)
+	}
+	c.writable.Store(false)
+}
+
+// 非租约持有者每秒最多从磁盘刷新一次头部，以感知其他副本写入的数据块。
+func (c *DingCache) refreshHeader() {
+	if c.lease == nil || c.writable.Load() {
+		return
+	}
+	now, last := time.Now().UnixNano(), c.refreshTime.Load()
+	if now-last < int64(time.Second) || !c.refreshTime.CompareAndSwap(last, now) {
+		return
+	}
+	c.reloadHeader()
+}
+
+func (c *DingCache) reloadHeader() {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	header := &DingCacheHeader{}
+	if err = header.Read(f); err != nil {
+		return
+	}
+	c.headerLock.Lock()
+	defer c.headerLock.Unlock()
+	// 持有者尚未完成Resize时，保留内存中的文件大小。
+	if c.header != nil && header.FileSize < c.header.FileSize {
+		return
+	}
+	c.header = header
+	if err = c.initCipher(); err != nil {
+		zap.S().Warnf("init cipher %s err.%v", c.path, err)
+	}
+}
@@ -3,9 +3,11 @@ package downloader
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"dingospeed/pkg/common"
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
 
 	"go.uber.org/zap"
 )
@@ -21,6 +23,9 @@ func GetInstance() *DingCacheManager {
 			dingCacheMap: common.NewSafeMap[string, *DingCache](),
 			dingCacheRef: common.NewSafeMap[string, *atomic.Int64](),
 		}
+		if config.SysConfig != nil && config.SysConfig.GetSyncPolicy() == consts.SyncPolicyPeriodic {
+			go instance.cycleSync()
+		}
 	})
 	return instance
 }
@@ -85,3 +90,23 @@ func (f *DingCacheManager) ReleasedDingFile(savePath string) {
 		f.dingCacheRef.Set(savePath, refCount)
 	}
 }
+
+// 周期性刷盘已打开的缓存文件，避免每次写入都同步IO。
+func (f *DingCacheManager) cycleSync() {
+	ticker := time.NewTicker(config.SysConfig.GetSyncInterval())
+	defer ticker.Stop()
+	for range ticker.C {
+		f.mu.RLock()
+		dingFiles := f.dingCacheMap.Values()
+		f.mu.RUnlock()
+		for _, dingFile := range dingFiles {
+			dingFile.fileLock.RLock()
+			if dingFile.isOpen {
+				if err := dingFile.Sync(); err != nil {
+					zap.S().Errorf("sync %s err.%v", dingFile.path, err)
+				}
+			}
+			dingFile.fileLock.RUnlock()
+		}
+	}
+}
@@ -15,9 +15,15 @@
 package downloader
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"dingospeed/pkg/config"
+
 	"go.uber.org/zap"
 )
 
@@ -52,3 +58,67 @@ func TestFileWrite3(t *testing.T) {
 	s := make([]byte, (size+7)/8)
 	fmt.Println(len(s))
 }
+
+func TestEncryptedBlock(t *testing.T) {
+	origin := config.SysConfig
+	defer func() { config.SysConfig = origin }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Cache.Encryption = config.Encryption{Enabled: true, Key: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))}
+
+	path := filepath.Join(t.TempDir(), "etag")
+	blockSize := int64(16)
+	dingFile, err := NewDingCache(path, blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dingFile.Resize(20); err != nil {
+		t.Fatal(err)
+	}
+	if err = dingFile.WriteBlock(0, []byte("0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	if err = dingFile.WriteBlock(1, dingFile.padBlock([]byte("ghij"))); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("0123456789")) {
+		t.Fatalf("block is stored in plaintext")
+	}
+
+	reopened, err := NewDingCache(path, blockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block0, err := reopened.ReadBlock(0)
+	if err != nil || string(block0) != "0123456789abcdef" {
+		t.Fatalf("block0=%q err=%v", block0, err)
+	}
+	block1, err := reopened.ReadBlock(1)
+	if err != nil || string(block1[:4]) != "ghij" || len(block1) != int(blockSize) {
+		t.Fatalf("block1=%q err=%v", block1, err)
+	}
+}
+
+func TestResizePreallocate(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Cache.Preallocate = true
+
+	savePath := filepath.Join(t.TempDir(), "blob")
+	dingFile, err := NewDingCache(savePath, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileSize := int64(5<<20 + 100)
+	if err = dingFile.Resize(fileSize); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := dingFile.getHeaderSize() + fileSize; fi.Size() != want {
+		t.Errorf("file size = %d, want %d", fi.Size(), want)
+	}
+}
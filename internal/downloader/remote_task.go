@@ -39,6 +39,7 @@ type RemoteFileTask struct {
 	Authorization string
 	Domain        string
 	Uri           string
+	Origin        string
 	DataType      string
 	Etag          string
 	Queue         chan []byte `json:"-"`
@@ -90,6 +91,11 @@ func (r *RemoteFileTask) DoTask() {
 					if !ok {
 						return
 					}
+					if err := common.BackgroundLimiter.WaitN(r.Context, int64(len(chunk))); err != nil {
+						zap.S().Warnf("background limit wait err:%s/%s, task %d, %v", r.OrgRepo, r.FileName, r.TaskNo, err)
+						data.ReportFileProcess(r.Context, r.constructFileProcessParam(lastReportPos, lastBlockEndPos, consts.StatusDownloadBreak))
+						return
+					}
 					select {
 					case r.Queue <- chunk:
 					case <-r.Context.Done():
@@ -99,6 +105,7 @@ func (r *RemoteFileTask) DoTask() {
 					}
 					chunkLen := int64(len(chunk))
 					curPos += chunkLen
+					r.Transfer.AddFetched(chunkLen)
 					if config.SysConfig.EnableMetric() {
 						// 原子性地更新总下载字节数
 						source := util.Itoa(r.Context.Value(consts.PromSource))
@@ -209,12 +216,11 @@ func (r *RemoteFileTask) OutResult() {
 				zap.S().Debugf("end remote outResult close. taskNo:%d, %s/%s", r.TaskNo, r.OrgRepo, r.FileName)
 				return
 			}
-			select {
-			case r.ResponseChan <- chunk:
-			case <-r.Context.Done():
-				zap.S().Debugf("end remote outResult Context.Done() %s/%s", r.OrgRepo, r.FileName)
+			if _, err := r.Stream.Write(chunk); err != nil {
+				zap.S().Debugf("end remote outResult stream closed %s/%s, %v", r.OrgRepo, r.FileName, err)
 				return
 			}
+			r.Transfer.AddSent(int64(len(chunk)))
 		case <-r.Context.Done():
 			zap.S().Debugf("end remote outResult fileName:%s/%s,err:%v", r.OrgRepo, r.FileName, r.Context.Err())
 			return
@@ -222,13 +228,9 @@ func (r *RemoteFileTask) OutResult() {
 	}
 }
 
-func (r *RemoteFileTask) GetResponseChan() chan []byte {
-	return r.ResponseChan
-}
-
 func (r *RemoteFileTask) getFileRangeFromRemote(startPos, endPos int64, contentChan chan<- []byte) error {
 	var (
-		rawData         []byte
+		rawData         = util.NewSpillBuffer() // 有编码的数据先收集，内存预算不足时转存到临时文件
 		chunkByteLen    = 0
 		attempts        = 2
 		contentEncoding = ""
@@ -240,13 +242,17 @@ func (r *RemoteFileTask) getFileRangeFromRemote(startPos, endPos int64, contentC
 		headers["authorization"] = r.Authorization
 	}
 	headers["range"] = fmt.Sprintf("bytes=%d-%d", startPos, endPos-1)
+	defer rawData.Close()
 	for i := 0; i < attempts; {
-		if _, err = util.RetryRequest(func() (*common.Response, error) {
-			err = util.GetStream(r.Domain, r.Uri, headers, func(resp *http.Response) error {
+		if _, err = util.RetryRequestContext(r.Context, func() (*common.Response, error) {
+			err = r.getStream(headers, func(resp *http.Response) error {
 				contentEncoding = resp.Header.Get("content-encoding")
 				code := resp.StatusCode
 				if code != http.StatusOK && code != http.StatusPartialContent {
-					if code == http.StatusNotFound {
+					if code == http.StatusTooManyRequests {
+						// 远端限流，不再重试，由客户端按Retry-After稍后重试
+						return myerr.NewAppendCode(code, fmt.Sprintf("upstream is rate limited. %s", r.OrgRepo))
+					} else if code == http.StatusNotFound {
 						zap.S().Errorf("The resource was not found. %s", r.OrgRepo)
 					} else if code == http.StatusUnauthorized || code == http.StatusForbidden {
 						zap.S().Errorf("Do not have access to this resource. %s", r.OrgRepo)
@@ -264,7 +270,9 @@ func (r *RemoteFileTask) getFileRangeFromRemote(startPos, endPos int64, contentC
 						n, err = resp.Body.Read(chunk)
 						if n > 0 {
 							if contentEncoding != "" { // 数据有编码，先收集，后面解码
-								rawData = append(rawData, chunk[:n]...)
+								if _, werr := rawData.Write(chunk[:n]); werr != nil {
+									return myerr.New(fmt.Sprintf("buffer encoded data err.%v", werr))
+								}
 							} else {
 								select {
 								case contentChan <- chunk[:n]:
@@ -293,8 +301,11 @@ func (r *RemoteFileTask) getFileRangeFromRemote(startPos, endPos int64, contentC
 			if errors.As(err, &t) {
 				break
 			}
+			if r.Context.Err() != nil {
+				break // 客户端已断开，不再切换其他域名重试
+			}
 			// 若从内部其他节点获取数据出现异常，则切换到官网获取。
-			if config.SysConfig.IsCluster() && util.IsInnerDomain(r.Domain) {
+			if r.Origin == "" && config.SysConfig.IsCluster() && util.IsInnerDomain(r.Domain) {
 				officialDomain := config.SysConfig.GetHFURLBase()
 				zap.S().Infof("request fail %s/%s req from %s to %s", r.OrgRepo, r.FileName, r.Domain, officialDomain)
 				r.Domain = officialDomain
@@ -313,18 +324,55 @@ func (r *RemoteFileTask) getFileRangeFromRemote(startPos, endPos int64, contentC
 		return fmt.Errorf("GetStream err.%v", err)
 	}
 	if contentEncoding != "" {
-		// 这里需要实现解压缩逻辑
-		finalData, err := util.DecompressData(rawData, contentEncoding)
-		if err != nil {
+		if chunkByteLen, err = r.sendDecompressed(rawData, contentEncoding, contentChan); err != nil {
 			zap.S().Errorf("DecompressData err.%v", err)
 			return err
 		}
-		contentChan <- finalData      // 返回解码后的数据流
-		chunkByteLen = len(finalData) // 将解码后的长度复制为原始的chunkByteLen
 	}
 	expectedLength := endPos - startPos
 	if expectedLength != int64(chunkByteLen) {
 		return fmt.Errorf("file:%s/%s, taskNo:%d,The block is incomplete. Expected-%d. Accepted-%d", r.OrgRepo, r.FileName, r.TaskNo, expectedLength, chunkByteLen)
 	}
+	RecordFetch(r.DataType, r.OrgRepo, r.FileName, r.Domain)
 	return nil
 }
+
+// sendDecompressed 以流的方式解码收集的数据并按块发送，返回解码后的长度。
+func (r *RemoteFileTask) sendDecompressed(rawData *util.SpillBuffer, contentEncoding string, contentChan chan<- []byte) (int, error) {
+	reader, err := rawData.Reader()
+	if err != nil {
+		return 0, err
+	}
+	dr, err := util.DecompressReader(reader, contentEncoding)
+	if err != nil {
+		return 0, err
+	}
+	defer dr.Close()
+	total := 0
+	for {
+		chunk := make([]byte, config.SysConfig.Download.RespChunkSize)
+		n, err := io.ReadFull(dr, chunk)
+		if n > 0 {
+			select {
+			case contentChan <- chunk[:n]:
+			case <-r.Context.Done():
+				return total, fmt.Errorf("decompress ctx done")
+			}
+			total += n
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// getStream 通用代理的任务直接请求源站，其余任务请求远端或集群内的节点。
+func (r *RemoteFileTask) getStream(headers map[string]string, f func(r *http.Response) error) error {
+	if r.Origin != "" {
+		return util.GetOriginStream(r.Context, r.Origin, headers, f)
+	}
+	return util.GetStream(r.Context, r.Domain, r.Uri, headers, f)
+}
@@ -0,0 +1,309 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 按仓库、文件统计的传输数据，定期持久化到repos/stats目录。
+var (
+	repoStatsMap  = make(map[string]*RepoStats)
+	repoStatsLock sync.Mutex
+)
+
+type FileStats struct {
+	Requests     int64  `json:"requests"`
+	Hits         int64  `json:"hits"`                // 完全由缓存响应的请求数
+	BytesServed  int64  `json:"bytesServed"`         // 发送给客户端的字节数
+	BytesFetched int64  `json:"bytesFetched"`        // 从远端拉取的字节数
+	DurationMs   int64  `json:"durationMs"`          // 累计传输耗时
+	Resolves     int64  `json:"resolves"`            // resolve请求次数，包括HEAD
+	LastAccess   int64  `json:"lastAccess"`          // 最近一次resolve的时间戳，单位秒
+	FetchedAt    int64  `json:"fetchedAt,omitempty"` // 最近一次从远端拉取数据的时间戳，单位秒
+	Endpoint     string `json:"endpoint,omitempty"`  // 最近一次拉取数据的远端地址
+}
+
+type RepoStats struct {
+	DataType string                `json:"datatype"`
+	OrgRepo  string                `json:"orgRepo"`
+	Total    FileStats             `json:"total"`
+	Files    map[string]*FileStats `json:"files,omitempty"`
+}
+
+type RepoStatsInfo struct {
+	*RepoStats
+	HitRatio   float64 `json:"hitRatio"`   // 字节命中率
+	Throughput int64   `json:"throughput"` // 平均吞吐，单位B/s
+}
+
+func (f *FileStats) add(t *Transfer, durationMs int64) {
+	sent, fetched := t.sent.Load(), t.fetched.Load()
+	f.Requests++
+	if fetched == 0 {
+		f.Hits++
+	}
+	f.BytesServed += sent
+	f.BytesFetched += fetched
+	f.DurationMs += durationMs
+}
+
+func repoStatsKey(dataType, orgRepo string) string {
+	return dataType + "/" + orgRepo
+}
+
+func recordTransfer(t *Transfer) {
+	durationMs := time.Since(t.StartTime).Milliseconds()
+	if !t.FromClient {
+		return // 预读、预取、预热、扫描等后台传输不计入仓库统计及预热程度
+	}
+	recordWarm(t.fetched.Load() == 0)
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	stats, fileStats := getFileStats(t.DataType, t.OrgRepo, t.FileName)
+	stats.Total.add(t, durationMs)
+	fileStats.add(t, durationMs)
+}
+
+// RecordResolve 记录一次文件resolve请求，用于统计文件及仓库的热度。
+func RecordResolve(dataType, orgRepo, fileName string) {
+	now := time.Now().Unix()
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	stats, fileStats := getFileStats(dataType, orgRepo, fileName)
+	stats.Total.Resolves++
+	stats.Total.LastAccess = now
+	fileStats.Resolves++
+	fileStats.LastAccess = now
+}
+
+// RecordFetch 记录文件最近一次从远端拉取数据的时间及远端地址，用于追溯缓存文件的来源。
+func RecordFetch(dataType, orgRepo, fileName, endpoint string) {
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	_, fileStats := getFileStats(dataType, orgRepo, fileName)
+	fileStats.FetchedAt = time.Now().Unix()
+	fileStats.Endpoint = endpoint
+}
+
+// GetFileStats 返回单个文件的统计。
+func GetFileStats(dataType, orgRepo, fileName string) (FileStats, bool) {
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	stats, ok := repoStatsMap[repoStatsKey(dataType, orgRepo)]
+	if !ok {
+		return FileStats{}, false
+	}
+	fileStats, ok := stats.Files[fileName]
+	if !ok {
+		return FileStats{}, false
+	}
+	return *fileStats, true
+}
+
+// 调用方需持有repoStatsLock
+func getFileStats(dataType, orgRepo, fileName string) (*RepoStats, *FileStats) {
+	key := repoStatsKey(dataType, orgRepo)
+	stats, ok := repoStatsMap[key]
+	if !ok {
+		stats = &RepoStats{DataType: dataType, OrgRepo: orgRepo, Files: make(map[string]*FileStats)}
+		repoStatsMap[key] = stats
+	}
+	if stats.Files == nil {
+		stats.Files = make(map[string]*FileStats)
+	}
+	fileStats, ok := stats.Files[fileName]
+	if !ok {
+		fileStats = &FileStats{}
+		stats.Files[fileName] = fileStats
+	}
+	return stats, fileStats
+}
+
+func newRepoStatsInfo(stats *RepoStats) *RepoStatsInfo {
+	info := &RepoStatsInfo{RepoStats: stats}
+	if served := stats.Total.BytesServed; served > 0 {
+		hit := served - stats.Total.BytesFetched
+		if hit < 0 {
+			hit = 0
+		}
+		info.HitRatio = float64(hit) / float64(served)
+	}
+	if stats.Total.DurationMs > 0 {
+		info.Throughput = stats.Total.BytesServed * 1000 / stats.Total.DurationMs
+	}
+	return info
+}
+
+// ListRepoStats 按发送字节数倒序返回各仓库的统计，不包含文件明细。
+func ListRepoStats() []*RepoStatsInfo {
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	infos := make([]*RepoStatsInfo, 0, len(repoStatsMap))
+	for _, stats := range repoStatsMap {
+		infos = append(infos, newRepoStatsInfo(&RepoStats{DataType: stats.DataType, OrgRepo: stats.OrgRepo, Total: stats.Total}))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Total.BytesServed > infos[j].Total.BytesServed
+	})
+	return infos
+}
+
+func GetRepoStats(dataType, orgRepo string) (*RepoStatsInfo, bool) {
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	stats, ok := repoStatsMap[repoStatsKey(dataType, orgRepo)]
+	if !ok {
+		return nil, false
+	}
+	files := make(map[string]*FileStats, len(stats.Files))
+	for fileName, fileStats := range stats.Files {
+		fs := *fileStats
+		files[fileName] = &fs
+	}
+	return newRepoStatsInfo(&RepoStats{DataType: stats.DataType, OrgRepo: stats.OrgRepo, Total: stats.Total, Files: files}), true
+}
+
+// RepoLastAccess 返回仓库最近一次resolve的时间戳，单位秒，没有记录时返回0。
+func RepoLastAccess(dataType, orgRepo string) int64 {
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	if stats, ok := repoStatsMap[repoStatsKey(dataType, orgRepo)]; ok {
+		return stats.Total.LastAccess
+	}
+	return 0
+}
+
+// PopularItem 热度排行中的一项，FileName为空表示仓库。
+type PopularItem struct {
+	DataType    string `json:"datatype"`
+	OrgRepo     string `json:"orgRepo"`
+	FileName    string `json:"fileName,omitempty"`
+	Resolves    int64  `json:"resolves"`
+	Requests    int64  `json:"requests"`
+	BytesServed int64  `json:"bytesServed"`
+	LastAccess  int64  `json:"lastAccess"`
+}
+
+func newPopularItem(stats *RepoStats, fileName string, fileStats *FileStats) *PopularItem {
+	return &PopularItem{
+		DataType:    stats.DataType,
+		OrgRepo:     stats.OrgRepo,
+		FileName:    fileName,
+		Resolves:    fileStats.Resolves,
+		Requests:    fileStats.Requests,
+		BytesServed: fileStats.BytesServed,
+		LastAccess:  fileStats.LastAccess,
+	}
+}
+
+// topN 按resolve次数倒序，次数相同时最近访问的在前，n小于等于0时返回全部。
+func topN(items []*PopularItem, n int) []*PopularItem {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Resolves != items[j].Resolves {
+			return items[i].Resolves > items[j].Resolves
+		}
+		return items[i].LastAccess > items[j].LastAccess
+	})
+	if n > 0 && len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+// TopFiles 返回resolve次数最多的n个文件，供统计接口及复制、预取等策略使用。
+func TopFiles(n int) []*PopularItem {
+	repoStatsLock.Lock()
+	items := make([]*PopularItem, 0)
+	for _, stats := range repoStatsMap {
+		for fileName, fileStats := range stats.Files {
+			if fileStats.Resolves > 0 {
+				items = append(items, newPopularItem(stats, fileName, fileStats))
+			}
+		}
+	}
+	repoStatsLock.Unlock()
+	return topN(items, n)
+}
+
+// TopRepos 返回resolve次数最多的n个仓库。
+func TopRepos(n int) []*PopularItem {
+	repoStatsLock.Lock()
+	items := make([]*PopularItem, 0, len(repoStatsMap))
+	for _, stats := range repoStatsMap {
+		if stats.Total.Resolves > 0 {
+			items = append(items, newPopularItem(stats, "", &stats.Total))
+		}
+	}
+	repoStatsLock.Unlock()
+	return topN(items, n)
+}
+
+func repoStatsPath() string {
+	return filepath.Join(config.SysConfig.Repos(), "stats", "repo_stats.json")
+}
+
+func LoadRepoStats() {
+	statsPath := repoStatsPath()
+	if !util.FileExists(statsPath) {
+		return
+	}
+	data, err := util.ReadFileToBytes(statsPath)
+	if err != nil {
+		zap.S().Errorf("read %s err.%v", statsPath, err)
+		return
+	}
+	statsMap := make(map[string]*RepoStats)
+	if err = sonic.Unmarshal(data, &statsMap); err != nil {
+		zap.S().Errorf("unmarshal %s err.%v", statsPath, err)
+		return
+	}
+	repoStatsLock.Lock()
+	defer repoStatsLock.Unlock()
+	repoStatsMap = statsMap
+}
+
+func FlushRepoStats() {
+	statsPath := repoStatsPath()
+	if err := util.MakeDirs(statsPath); err != nil {
+		zap.S().Errorf("create %s dir err.%v", statsPath, err)
+		return
+	}
+	repoStatsLock.Lock()
+	data, err := sonic.Marshal(repoStatsMap)
+	repoStatsLock.Unlock()
+	if err != nil {
+		zap.S().Errorf("marshal repo stats err.%v", err)
+		return
+	}
+	tmpPath := statsPath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		zap.S().Errorf("write %s err.%v", tmpPath, err)
+		return
+	}
+	if err = os.Rename(tmpPath, statsPath); err != nil {
+		zap.S().Errorf("rename %s err.%v", tmpPath, err)
+	}
+}
@@ -0,0 +1,99 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestTopFiles(t *testing.T) {
+	repoStatsLock.Lock()
+	old := repoStatsMap
+	repoStatsMap = make(map[string]*RepoStats)
+	repoStatsLock.Unlock()
+	defer func() {
+		repoStatsLock.Lock()
+		repoStatsMap = old
+		repoStatsLock.Unlock()
+	}()
+
+	for i := 0; i < 3; i++ {
+		RecordResolve("models", "org/a", "model.safetensors")
+	}
+	RecordResolve("models", "org/a", "config.json")
+	for i := 0; i < 2; i++ {
+		RecordResolve("datasets", "org/b", "data.parquet")
+	}
+
+	files := TopFiles(2)
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[0].FileName != "model.safetensors" || files[0].Resolves != 3 {
+		t.Errorf("unexpected first file %+v", files[0])
+	}
+	if files[1].FileName != "data.parquet" || files[1].Resolves != 2 {
+		t.Errorf("unexpected second file %+v", files[1])
+	}
+
+	repos := TopRepos(0)
+	if len(repos) != 2 || repos[0].OrgRepo != "org/a" || repos[0].Resolves != 4 {
+		t.Errorf("unexpected repos %+v", repos)
+	}
+}
+
+func TestRecordFetch(t *testing.T) {
+	repoStatsLock.Lock()
+	old := repoStatsMap
+	repoStatsMap = make(map[string]*RepoStats)
+	repoStatsLock.Unlock()
+	defer func() {
+		repoStatsLock.Lock()
+		repoStatsMap = old
+		repoStatsLock.Unlock()
+	}()
+
+	if _, ok := GetFileStats("models", "org/a", "model.safetensors"); ok {
+		t.Fatal("expected no stats before any access")
+	}
+	RecordResolve("models", "org/a", "model.safetensors")
+	RecordFetch("models", "org/a", "model.safetensors", "https://huggingface.co")
+	stats, ok := GetFileStats("models", "org/a", "model.safetensors")
+	if !ok {
+		t.Fatal("expected stats after fetch")
+	}
+	if stats.Endpoint != "https://huggingface.co" || stats.FetchedAt == 0 || stats.Resolves != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRecordTransferClientOnly(t *testing.T) {
+	origin := config.SysConfig
+	defer func() { config.SysConfig = origin }()
+	config.SysConfig = &config.Config{}
+	repoStatsLock.Lock()
+	old := repoStatsMap
+	repoStatsMap = make(map[string]*RepoStats)
+	repoStatsLock.Unlock()
+	defer func() {
+		repoStatsLock.Lock()
+		repoStatsMap = old
+		repoStatsLock.Unlock()
+	}()
+
+	background := &Transfer{DataType: "models", OrgRepo: "org/a", FileName: "model.safetensors", StartTime: time.Now()}
+	background.AddFetched(100)
+	recordTransfer(background)
+	if _, ok := GetFileStats("models", "org/a", "model.safetensors"); ok {
+		t.Fatal("background transfer should not be recorded")
+	}
+
+	client := &Transfer{DataType: "models", OrgRepo: "org/a", FileName: "model.safetensors", StartTime: time.Now(), FromClient: true}
+	client.AddSent(100)
+	recordTransfer(client)
+	stats, ok := GetFileStats("models", "org/a", "model.safetensors")
+	if !ok || stats.Requests != 1 || stats.Hits != 1 || stats.BytesServed != 100 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,141 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/events"
+)
+
+// 当前正在进行的文件传输，包括客户端下载和预热任务。
+var (
+	transferMap = common.NewSafeMap[int64, *Transfer]()
+	transferSeq atomic.Int64
+)
+
+type Transfer struct {
+	Id         int64        `json:"id"`
+	DataType   string       `json:"datatype"`
+	OrgRepo    string       `json:"orgRepo"`
+	FileName   string       `json:"fileName"`
+	FileSize   int64        `json:"fileSize"`
+	StartPos   int64        `json:"startPos"`
+	EndPos     int64        `json:"endPos"`
+	Client     string       `json:"client"`
+	StartTime  time.Time    `json:"startTime"`
+	FromClient bool         `json:"fromClient"` // 由客户端请求发起，只有这类传输计入仓库统计及预热程度
+	sent       atomic.Int64 // 已发送给客户端的字节数
+	fetched    atomic.Int64 // 已从远端拉取的字节数
+	cancel     context.CancelFunc
+}
+
+type TransferInfo struct {
+	*Transfer
+	Sent     int64   `json:"sent"`
+	Fetched  int64   `json:"fetched"`
+	Progress float64 `json:"progress"`
+	Speed    int64   `json:"speed"` // 平均速度，单位B/s
+	Eta      int64   `json:"eta"`   // 预计剩余时间，单位秒，-1表示未知
+}
+
+func RegisterTransfer(t *Transfer, cancel context.CancelFunc) *Transfer {
+	t.Id = transferSeq.Add(1)
+	t.StartTime = time.Now()
+	t.cancel = cancel
+	transferMap.Set(t.Id, t)
+	return t
+}
+
+func UnregisterTransfer(t *Transfer) {
+	if t == nil {
+		return
+	}
+	transferMap.Delete(t.Id)
+	recordTransfer(t)
+	if t.fetched.Load() > 0 {
+		events.Publish(events.CacheFill, t.Info())
+	}
+}
+
+func (t *Transfer) AddSent(n int64) {
+	if t == nil {
+		return
+	}
+	t.sent.Add(n)
+}
+
+func (t *Transfer) AddFetched(n int64) {
+	if t == nil {
+		return
+	}
+	t.fetched.Add(n)
+}
+
+func (t *Transfer) Info() *TransferInfo {
+	info := &TransferInfo{
+		Transfer: t,
+		Sent:     t.sent.Load(),
+		Fetched:  t.fetched.Load(),
+		Eta:      -1,
+	}
+	if total := t.EndPos - t.StartPos; total > 0 {
+		info.Progress = float64(info.Sent) / float64(total) * 100
+	}
+	if elapsed := time.Since(t.StartTime).Seconds(); elapsed > 0 {
+		info.Speed = int64(float64(info.Sent) / elapsed)
+	}
+	if remaining := t.EndPos - t.StartPos - info.Sent; info.Speed > 0 && remaining >= 0 {
+		info.Eta = remaining / info.Speed
+	}
+	return info
+}
+
+func ListTransfers() []*TransferInfo {
+	transfers := transferMap.Values()
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].Id < transfers[j].Id
+	})
+	infos := make([]*TransferInfo, 0, len(transfers))
+	for _, t := range transfers {
+		infos = append(infos, t.Info())
+	}
+	return infos
+}
+
+func ListRepoTransfers(dataType, orgRepo string) []*TransferInfo {
+	infos := make([]*TransferInfo, 0)
+	for _, info := range ListTransfers() {
+		if info.DataType == dataType && info.OrgRepo == orgRepo {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func CancelTransfer(id int64) bool {
+	t, ok := transferMap.Get(id)
+	if !ok {
+		return false
+	}
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/prom"
+)
+
+// 按窗口统计文件请求是否完全由缓存响应，得到节点的预热程度（warm ratio），供指标及就绪检查使用。
+
+const warmBuckets = 10 // 窗口划分的桶数，按桶滚动淘汰过期的统计
+
+type warmBucket struct {
+	slot  int64
+	total int
+	hits  int
+}
+
+type warmWindow struct {
+	mu      sync.Mutex
+	buckets [warmBuckets]warmBucket
+}
+
+var warmStats = &warmWindow{}
+
+func (w *warmWindow) slot(now time.Time, window int) int64 {
+	return now.Unix() / max(int64(window)/warmBuckets, 1)
+}
+
+func (w *warmWindow) record(now time.Time, window int, hit bool) {
+	slot := w.slot(now, window)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := &w.buckets[slot%warmBuckets]
+	if b.slot != slot {
+		*b = warmBucket{slot: slot}
+	}
+	b.total++
+	if hit {
+		b.hits++
+	}
+}
+
+// stats 汇总窗口内未过期的桶，没有请求时比例为1
+func (w *warmWindow) stats(now time.Time, window int) (float64, int) {
+	slot := w.slot(now, window)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var total, hits int
+	for _, b := range w.buckets {
+		if slot-b.slot < warmBuckets {
+			total += b.total
+			hits += b.hits
+		}
+	}
+	if total == 0 {
+		return 1, 0
+	}
+	return float64(hits) / float64(total), total
+}
+
+func recordWarm(hit bool) {
+	now, window := time.Now(), config.SysConfig.GetReadinessWindow()
+	warmStats.record(now, window, hit)
+	ratio, _ := warmStats.stats(now, window)
+	prom.CacheWarmRatio.Set(ratio)
+}
+
+// WarmRatio 返回窗口内文件请求完全由缓存响应的比例及请求数。
+func WarmRatio() (float64, int) {
+	ratio, total := warmStats.stats(time.Now(), config.SysConfig.GetReadinessWindow())
+	prom.CacheWarmRatio.Set(ratio)
+	return ratio, total
+}
@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestWarmWindow(t *testing.T) {
+	w := &warmWindow{}
+	now := time.Unix(1700000000, 0)
+	if ratio, total := w.stats(now, 100); ratio != 1 || total != 0 {
+		t.Errorf("empty window = %v/%d, want 1/0", ratio, total)
+	}
+	for i := 0; i < 4; i++ {
+		w.record(now, 100, i != 0)
+	}
+	if ratio, total := w.stats(now, 100); ratio != 0.75 || total != 4 {
+		t.Errorf("stats = %v/%d, want 0.75/4", ratio, total)
+	}
+	// 超出窗口的统计被淘汰
+	later := now.Add(101 * time.Second)
+	w.record(later, 100, false)
+	if ratio, total := w.stats(later, 100); ratio != 0 || total != 1 {
+		t.Errorf("stats after window = %v/%d, want 0/1", ratio, total)
+	}
+}
+
+func TestWarmRatioClientOnly(t *testing.T) {
+	origin, oldWarm := config.SysConfig, warmStats
+	repoStatsLock.Lock()
+	oldStats := repoStatsMap
+	repoStatsMap = make(map[string]*RepoStats)
+	repoStatsLock.Unlock()
+	defer func() {
+		config.SysConfig, warmStats = origin, oldWarm
+		repoStatsLock.Lock()
+		repoStatsMap = oldStats
+		repoStatsLock.Unlock()
+	}()
+	config.SysConfig = &config.Config{}
+	warmStats = &warmWindow{}
+
+	// 预读等后台传输从远端拉取数据，不影响预热程度
+	background := &Transfer{DataType: "models", OrgRepo: "org/a", FileName: "model.safetensors", StartTime: time.Now()}
+	background.AddFetched(100)
+	recordTransfer(background)
+	if ratio, total := WarmRatio(); ratio != 1 || total != 0 {
+		t.Errorf("after background transfer = %v/%d, want 1/0", ratio, total)
+	}
+	client := &Transfer{DataType: "models", OrgRepo: "org/a", FileName: "model.safetensors", StartTime: time.Now(), FromClient: true}
+	client.AddFetched(100)
+	recordTransfer(client)
+	if ratio, total := WarmRatio(); ratio != 0 || total != 1 {
+		t.Errorf("after client transfer = %v/%d, want 0/1", ratio, total)
+	}
+}
@@ -0,0 +1,106 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package downloader
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+// WarmupPageCache 按热度顺序读取已缓存的文件，使其进入页缓存。同一文件的多个版本指向同一blob时只读取一次，
+// 读取总量达到maxBytes（大于0时）后停止。
+func WarmupPageCache(ctx context.Context, topN int, maxBytes int64) {
+	start := time.Now()
+	var (
+		files int
+		total int64
+	)
+	seen := make(map[string]struct{})
+	for _, item := range TopFiles(topN) {
+		for _, blob := range cachedBlobs(item.DataType, item.OrgRepo, item.FileName) {
+			if _, ok := seen[blob]; ok {
+				continue
+			}
+			seen[blob] = struct{}{}
+			if ctx.Err() != nil {
+				return
+			}
+			limit := int64(-1)
+			if maxBytes > 0 {
+				if limit = maxBytes - total; limit <= 0 {
+					zap.S().Infof("warmup reach max bytes %d, %d files, cost %s", maxBytes, files, time.Since(start))
+					return
+				}
+			}
+			n, err := readThrough(blob, limit)
+			if err != nil {
+				zap.S().Warnf("warmup read %s err.%v", blob, err)
+				continue
+			}
+			files++
+			total += n
+		}
+	}
+	zap.S().Infof("warmup complete, %d files, %d bytes, cost %s", files, total, time.Since(start))
+}
+
+// cachedBlobs 返回文件各已缓存版本对应的blob路径。
+func cachedBlobs(dataType, orgRepo, fileName string) []string {
+	entries, err := os.ReadDir(filepath.Join(util.RepoFilesDir(dataType, orgRepo), "resolve"))
+	if err != nil {
+		return nil
+	}
+	blobs := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		resolveFile := filepath.Join(util.RepoFilesDir(dataType, orgRepo), "resolve", entry.Name(), util.CachePath(fileName))
+		if blob, err := filepath.EvalSymlinks(resolveFile); err == nil {
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs
+}
+
+// readThrough 顺序读取文件并丢弃内容，limit小于0时读取全部。
+func readThrough(path string, limit int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	return io.Copy(io.Discard, r)
+}
+
+// StartWarmup 按配置在后台预热页缓存。
+func StartWarmup() {
+	if !config.SysConfig.Warmup.Enabled {
+		return
+	}
+	go WarmupPageCache(context.Background(), config.SysConfig.GetWarmupTopN(), config.SysConfig.Warmup.MaxBytes)
+}
@@ -0,0 +1,515 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dingospeed/internal/model/query"
+	"dingospeed/internal/service"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	adminService    *service.AdminService
+	ociService      *service.OciService
+	trashService    *service.TrashService
+	gcService       *service.RevisionGcService
+	syncService     *service.SyncService
+	curationService *service.CurationService
+	batchService    *service.BatchService
+	compactService  *service.CompactionService
+	bundleService   *service.BundleService
+}
+
+func NewAdminHandler(adminService *service.AdminService, ociService *service.OciService, trashService *service.TrashService, gcService *service.RevisionGcService, syncService *service.SyncService, curationService *service.CurationService, batchService *service.BatchService, compactService *service.CompactionService, bundleService *service.BundleService) *AdminHandler {
+	return &AdminHandler{
+		adminService:    adminService,
+		ociService:      ociService,
+		trashService:    trashService,
+		gcService:       gcService,
+		syncService:     syncService,
+		curationService: curationService,
+		batchService:    batchService,
+		compactService:  compactService,
+		bundleService:   bundleService,
+	}
+}
+
+func (handler *AdminHandler) ListDownloadsHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.adminService.ListDownloads())
+}
+
+func (handler *AdminHandler) CancelDownloadHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	if err = handler.adminService.CancelDownload(id); err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, nil)
+}
+
+func (handler *AdminHandler) ListRepoStatsHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.adminService.ListRepoStats())
+}
+
+func (handler *AdminHandler) GetRepoStatsHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	stats, err := handler.adminService.GetRepoStats(repoType, orgRepo)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, stats)
+}
+
+// UpstreamBudgetHandler 当前预算窗口内远端请求数及下载流量的使用情况
+func (handler *AdminHandler) UpstreamBudgetHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.adminService.UpstreamBudget())
+}
+
+func (handler *AdminHandler) TopStatsHandler(c echo.Context) error {
+	n := consts.DefaultTopStatsNum
+	if v := c.QueryParam("n"); v != "" {
+		num, err := strconv.Atoi(v)
+		if err != nil || num <= 0 {
+			return util.ErrorRequestParam(c)
+		}
+		n = num
+	}
+	items, err := handler.adminService.TopStats(c.QueryParam("by"), n)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, items)
+}
+
+// OciExportHandler 将已缓存的仓库版本打包为OCI制品，异步执行，返回任务信息。
+func (handler *AdminHandler) OciExportHandler(c echo.Context) error {
+	req := new(query.OciExportReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	if _, ok := consts.RepoTypesMapping[req.Datatype]; !ok || req.Repo == "" {
+		return util.ErrorRequestParam(c)
+	}
+	for name, value := range map[string]string{"org": req.Org, "repo": req.Repo, "revision": req.Revision} {
+		if err := util.ValidatePathParam(name, value); err != nil {
+			return util.ErrorRequestParam(c)
+		}
+	}
+	job, err := handler.ociService.Export(req, c.Request().Header.Get("authorization"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, job)
+}
+
+func (handler *AdminHandler) OciExportJobHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	job, err := handler.ociService.ExportJob(id)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, job)
+}
+
+func (handler *AdminHandler) GetLogLevelHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.adminService.GetLogLevels())
+}
+
+func (handler *AdminHandler) GetMaintenanceHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.adminService.GetMaintenance())
+}
+
+// SetMaintenanceHandler 开启或关闭维护模式，维护期间只提供已缓存的内容，缓存未命中及管理类修改请求返回503。
+func (handler *AdminHandler) SetMaintenanceHandler(c echo.Context) error {
+	req := new(query.MaintenanceReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	return util.ResponseData(c, handler.adminService.SetMaintenance(req))
+}
+
+func (handler *AdminHandler) GetOnlineHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.adminService.GetOnlineStatus())
+}
+
+// SetOnlineHandler 手动设置在线状态，auto表示按配置及远端探测结果自动切换。
+func (handler *AdminHandler) SetOnlineHandler(c echo.Context) error {
+	req := new(query.OnlineModeReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	status, err := handler.adminService.SetOnlineMode(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, status)
+}
+
+// StartCaptureHandler 为仓库开启抓包，有效期内发往远端的请求及响应记录到repos/captures下，认证信息脱敏。
+func (handler *AdminHandler) StartCaptureHandler(c echo.Context) error {
+	req := new(query.CaptureReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	s, err := handler.adminService.StartCapture(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, s)
+}
+
+func (handler *AdminHandler) ListCapturesHandler(c echo.Context) error {
+	return util.ResponseData(c, util.ListCaptures())
+}
+
+func (handler *AdminHandler) StopCaptureHandler(c echo.Context) error {
+	s, err := handler.adminService.StopCapture(c.Param("id"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, s)
+}
+
+func (handler *AdminHandler) ListApprovalsHandler(c echo.Context) error {
+	return util.ResponseData(c, util.ListRepoApprovals())
+}
+
+// ApproveRepoHandler 审批gated仓库，开启policy.gatedApproval时审批通过后才向客户端提供文件。
+func (handler *AdminHandler) ApproveRepoHandler(c echo.Context) error {
+	req := new(query.PolicyApprovalReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	approval, err := handler.adminService.ApproveRepo(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, approval)
+}
+
+func (handler *AdminHandler) RevokeRepoHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	if err := handler.adminService.RevokeRepo(repoType, util.GetOrgRepo(c.Param("org"), c.Param("repo"))); err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, nil)
+}
+
+// ListRepoAliasesHandler 返回检测到的仓库改名，旧名称的请求按新名称处理。
+func (handler *AdminHandler) ListRepoAliasesHandler(c echo.Context) error {
+	return util.ResponseData(c, util.ListRepoAliases())
+}
+
+func (handler *AdminHandler) AddRepoAliasHandler(c echo.Context) error {
+	req := new(query.RepoAliasReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	alias, err := handler.adminService.AddRepoAlias(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, alias)
+}
+
+func (handler *AdminHandler) RemoveRepoAliasHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	if err := handler.adminService.RemoveRepoAlias(repoType, util.GetOrgRepo(c.Param("org"), c.Param("repo"))); err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, nil)
+}
+
+// SetLogLevelHandler 运行时修改全局或单个模块的日志级别，无需重启服务。
+func (handler *AdminHandler) SetLogLevelHandler(c echo.Context) error {
+	req := new(query.LogLevelReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	levels, err := handler.adminService.SetLogLevel(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, levels)
+}
+
+// PurgeRepoHandler 删除仓库的缓存，缓存先移入回收站，保留期内可恢复。
+func (handler *AdminHandler) PurgeRepoHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorRequestParam(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	entry, err := handler.trashService.PurgeRepo(repoType, orgRepo)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, entry)
+}
+
+// BatchHandler 按glob批量删除、固定或预取仓库，异步执行，返回任务信息。
+func (handler *AdminHandler) BatchHandler(c echo.Context) error {
+	req := new(query.BatchReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	job, err := handler.batchService.Submit(req, c.Request().Header.Get("authorization"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, job)
+}
+
+// IdleReportHandler 返回闲置超过days天（默认30天）的仓库及其大小。
+func (handler *AdminHandler) IdleReportHandler(c echo.Context) error {
+	days := consts.DefaultIdleDays
+	if v := c.QueryParam("days"); v != "" {
+		num, err := strconv.Atoi(v)
+		if err != nil || num < 0 {
+			return util.ErrorRequestParam(c)
+		}
+		days = num
+	}
+	return util.ResponseData(c, handler.batchService.IdleReport(days))
+}
+
+// PurgeIdleHandler 将闲置的仓库移入回收站，作为批量任务执行，进度通过/admin/batch/:id查询。
+func (handler *AdminHandler) PurgeIdleHandler(c echo.Context) error {
+	req := new(query.IdlePurgeReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	job, err := handler.batchService.PurgeIdle(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, job)
+}
+
+// CompactHandler 在后台执行一次缓存整理，dryRun时只统计可回收的空间，进度通过CompactStatusHandler查看。
+func (handler *AdminHandler) CompactHandler(c echo.Context) error {
+	req := new(query.CompactReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	status, err := handler.compactService.Start(req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, status)
+}
+
+func (handler *AdminHandler) CompactStatusHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.compactService.Status())
+}
+
+func (handler *AdminHandler) ListBundlesHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.bundleService.List())
+}
+
+// CreateBundleHandler 创建发布包，各仓库的版本在创建时解析为commit，prefetch为true时在后台预取。
+func (handler *AdminHandler) CreateBundleHandler(c echo.Context) error {
+	req := new(query.BundleReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	status, err := handler.bundleService.Create(c.Request().Context(), req, c.Request().Header.Get("authorization"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, status)
+}
+
+func (handler *AdminHandler) BundleHandler(c echo.Context) error {
+	status, err := handler.bundleService.Status(c.Param("name"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, status)
+}
+
+func (handler *AdminHandler) DeleteBundleHandler(c echo.Context) error {
+	if err := handler.bundleService.Delete(c.Param("name")); err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, nil)
+}
+
+func (handler *AdminHandler) PrefetchBundleHandler(c echo.Context) error {
+	status, err := handler.bundleService.Prefetch(c.Param("name"), c.Request().Header.Get("authorization"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, status)
+}
+
+// BundleArchiveHandler 将发布包导出为一个tar归档，compress=true时使用zstd压缩，发布包不可修改，相同的发布包总是生成相同的归档。
+func (handler *AdminHandler) BundleArchiveHandler(c echo.Context) error {
+	compress, _ := strconv.ParseBool(c.QueryParam("compress"))
+	files, bundle, err := handler.bundleService.Archive(c.Param("name"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	ext, contentType := ".tar", "application/x-tar"
+	if compress {
+		ext, contentType = ".tar.zst", "application/zstd"
+	}
+	etag := fmt.Sprintf(`"%s-%d%s"`, bundle.Name, bundle.CreatedAt, ext)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s%s", bundle.Name, ext))
+	header.Set("ETag", etag)
+	c.Response().WriteHeader(http.StatusOK)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	if err = util.WriteArchive(c.Response(), bundle.Name, files, compress); err != nil {
+		// 响应已开始发送，无法再返回错误码，客户端读取到不完整的tar即可感知
+		zap.S().Errorf("archive bundle %s err.%v", bundle.Name, err)
+	}
+	return nil
+}
+
+func (handler *AdminHandler) BatchJobHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	job, err := handler.batchService.Job(id)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, job)
+}
+
+func (handler *AdminHandler) ListTrashHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.trashService.ListTrash())
+}
+
+func (handler *AdminHandler) RestoreTrashHandler(c echo.Context) error {
+	entry, err := handler.trashService.RestoreTrash(c.Param("id"))
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, entry)
+}
+
+func (handler *AdminHandler) DeleteTrashHandler(c echo.Context) error {
+	if err := handler.trashService.DeleteTrash(c.Param("id")); err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, nil)
+}
+
+// SyncHandler 从其他镜像实例同步仓库的某个版本，只传输本地缺少的文件。
+func (handler *AdminHandler) SyncHandler(c echo.Context) error {
+	req := new(query.SyncReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	resp, err := handler.syncService.Sync(c.Request().Context(), req)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, resp)
+}
+
+// RevisionGcHandler 立即执行一次版本回收，dryRun=true时只返回可回收的内容。
+func (handler *AdminHandler) RevisionGcHandler(c echo.Context) error {
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dryRun"))
+	return util.ResponseData(c, handler.gcService.Run(dryRun))
+}
+
+func (handler *AdminHandler) CurationStatusHandler(c echo.Context) error {
+	return util.ResponseData(c, handler.curationService.Status())
+}
+
+// RunCurationHandler 在后台立即执行一次模型精选同步，通过CurationStatusHandler查看进度。
+func (handler *AdminHandler) RunCurationHandler(c echo.Context) error {
+	if !handler.curationService.Trigger() {
+		return util.ResponseError(c, myerr.NewAppendCode(http.StatusConflict, "curation is running"))
+	}
+	return util.ResponseData(c, handler.curationService.Status())
+}
+
+// EventsHandler 以server-sent events推送缓存写入与清理、任务进度及远端状态变化，
+// types参数按逗号分隔的类型前缀过滤，如types=cache,upstream，客户端断开时结束。
+func (handler *AdminHandler) EventsHandler(c echo.Context) error {
+	var types []string
+	if v := c.QueryParam("types"); v != "" {
+		types = strings.Split(v, ",")
+	}
+	ch, cancel := events.Subscribe(types)
+	defer func() {
+		if dropped := cancel(); dropped > 0 {
+			zap.S().Warnf("event subscriber %s dropped %d events", c.RealIP(), dropped)
+		}
+	}()
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+	keepAlive := time.NewTicker(consts.EventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case event := <-ch:
+			data, err := sonic.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err = fmt.Fprintf(resp, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data); err != nil {
+				return nil
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(resp, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		}
+		resp.Flush()
+	}
+}
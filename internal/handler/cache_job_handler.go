@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"dingospeed/internal/model/query"
 	"dingospeed/internal/service"
@@ -29,6 +30,16 @@ func (handler *CacheJobHandler) CreateCacheJobHandler(c echo.Context) error {
 			"error": "无效的 JSON 数据",
 		})
 	}
+	if createCacheJobReq.Collection != "" {
+		if err := util.ValidatePathParam("collection", createCacheJobReq.Collection); err != nil {
+			return util.ErrorRequestParam(c)
+		}
+		jobs, err := handler.cacheJobService.CreateCollectionCacheJobs(c, createCacheJobReq)
+		if err != nil {
+			return util.ResponseError(c, err)
+		}
+		return util.ResponseData(c, util.Body{Msg: "success", Data: jobs})
+	}
 	if _, ok := consts.RepoTypesMapping[createCacheJobReq.Datatype]; !ok {
 		zap.S().Errorf("MetaProxyCommon repoType:%s is not exist RepoTypesMapping", createCacheJobReq.Datatype)
 		return util.ErrorPageNotFound(c)
@@ -82,3 +93,23 @@ func (handler *CacheJobHandler) RealtimeCacheJobHandler(c echo.Context) error {
 	resp := handler.cacheJobService.RealtimeCacheJob(realtimeReq)
 	return util.ResponseData(c, resp)
 }
+
+func (handler *CacheJobHandler) JobProgressHandler(c echo.Context) error {
+	jobId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	progress, err := handler.cacheJobService.JobProgress(jobId)
+	if err != nil {
+		return util.ResponseError(c, err)
+	}
+	return util.ResponseData(c, progress)
+}
+
+func (handler *CacheJobHandler) RepoProgressHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	return util.ResponseData(c, handler.cacheJobService.RepoProgress(repoType, c.Param("org"), c.Param("repo")))
+}
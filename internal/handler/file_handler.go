@@ -16,6 +16,7 @@ package handler
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
 
@@ -98,6 +99,35 @@ func (handler *FileHandler) GetFileHandler3(c echo.Context) error {
 	return handler.fileGetCommon(c, repoType, orgRepo, commit, filePath)
 }
 
+// ProxyHandler 通用代理：/proxy?url=...，按块缓存genericProxy.allowHosts中源站的文件，支持Range请求。
+func (handler *FileHandler) ProxyHandler(c echo.Context) error {
+	if !config.SysConfig.GenericProxy.Enabled {
+		return util.ErrorEntryNotFound(c)
+	}
+	method := consts.RequestTypeGet
+	if c.Request().Method == http.MethodHead {
+		method = consts.RequestTypeHead
+	}
+	return handler.fileService.ProxyFile(c, c.QueryParam("url"), method)
+}
+
+// CdnFileHandler 经由本镜像访问原本重定向到CDN的文件，按仓库、版本及文件路径缓存，不受签名地址变化影响。
+func (handler *FileHandler) CdnFileHandler(c echo.Context) error {
+	repoType, orgRepo, commit, filePath, err := paramProcess(c, 1)
+	if err != nil {
+		zap.S().Error("解码出错:%v", err)
+		return util.ErrorRequestParam(c)
+	}
+	if !config.SysConfig.CdnCache.Enabled {
+		return util.ErrorEntryNotFound(c)
+	}
+	c.Set(consts.CdnCacheServing, true)
+	if c.Request().Method == http.MethodHead {
+		return handler.fileService.FileHeadCommon(c, repoType, orgRepo, commit, filePath)
+	}
+	return handler.fileGetCommon(c, repoType, orgRepo, commit, filePath)
+}
+
 func paramProcess(c echo.Context, processMode int) (string, string, string, string, error) {
 	var (
 		repoType string
@@ -192,3 +222,64 @@ func (handler *FileHandler) FileProcessSync(c echo.Context) error {
 	}
 	return util.ResponseData(c, nil)
 }
+
+func (handler *FileHandler) GgufHandler(c echo.Context) error {
+	repoType, orgRepo, revision, filePath, err := inspectParam(c)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	info, err := handler.fileService.GgufInfo(c, repoType, orgRepo, revision, filePath)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+func (handler *FileHandler) SafetensorsHandler(c echo.Context) error {
+	repoType, orgRepo, revision, filePath, err := inspectParam(c)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	info, err := handler.fileService.SafetensorsInfo(c, repoType, orgRepo, revision, filePath)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+// PreviewHandler 返回文件开头的内容，size参数单位为KB。
+func (handler *FileHandler) PreviewHandler(c echo.Context) error {
+	repoType, orgRepo, revision, filePath, err := inspectParam(c)
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	size := int64(consts.DefaultPreviewSize)
+	if sizeStr := c.QueryParam("size"); sizeStr != "" {
+		kb, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil || kb <= 0 {
+			return util.ErrorRequestParam(c)
+		}
+		size = min(kb*1024, consts.MaxPreviewSize)
+	}
+	content, contentType, fileSize, err := handler.fileService.Preview(c, repoType, orgRepo, revision, filePath, size)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	c.Response().Header().Set("X-File-Size", strconv.FormatInt(fileSize, 10))
+	c.Response().Header().Set("X-Preview-Truncated", strconv.FormatBool(int64(len(content)) < fileSize))
+	// 预览的内容来自任意远端仓库，禁止浏览器嗅探类型及执行脚本
+	c.Response().Header().Set(echo.HeaderXContentTypeOptions, "nosniff")
+	c.Response().Header().Set(echo.HeaderContentSecurityPolicy, "sandbox")
+	return c.Blob(http.StatusOK, contentType, content)
+}
+
+func inspectParam(c echo.Context) (string, string, string, string, error) {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return "", "", "", "", fmt.Errorf("repoType:%s is invalid", repoType)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	c.Set(consts.PromOrgRepo, orgRepo)
+	filePath, err := url.QueryUnescape(c.Param("filePath"))
+	return repoType, orgRepo, c.Param("revision"), filePath, err
+}
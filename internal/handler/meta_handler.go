@@ -15,13 +15,22 @@
 package handler
 
 import (
-	"context"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 
+	"dingospeed/internal/model/query"
 	"dingospeed/internal/service"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
 	"dingospeed/pkg/consts"
-	myerr "dingospeed/pkg/error"
 	"dingospeed/pkg/util"
 
 	"github.com/labstack/echo/v4"
@@ -47,29 +56,48 @@ func (handler *MetaHandler) GetMetadataHandler(c echo.Context) error {
 	orgRepo := util.GetOrgRepo(org, repo)
 	c.Set(consts.PromOrgRepo, orgRepo)
 	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		if revision == "" {
+			// /api/collections/...等非仓库接口原样转发
+			return handler.metaService.ForwardToNewSite(c)
+		}
 		zap.S().Errorf("repoType:%s is not exist RepoTypesMapping", repoType)
 		return util.ErrorPageNotFound(c)
 	}
+	if revision == "" {
+		// 未指定版本的仓库信息按main分支缓存
+		revision = "main"
+	}
 	if org == "" && repo == "" {
 		zap.S().Errorf("org and repo is null")
 		return util.ErrorRepoNotFound(c)
 	}
 	authorization := c.Request().Header.Get("authorization")
-	cacheContent, err := handler.metaService.GetMetadata(repoType, orgRepo, revision, method, authorization)
+	query := util.MetaQuery(c.QueryParams())
+	var (
+		cacheContent *common.CacheContent
+		err          error
+	)
+	if method == consts.RequestTypeHead {
+		cacheContent, err = handler.metaService.GetMetadata(c.Request().Context(), repoType, orgRepo, revision, method, authorization, query)
+	} else {
+		cacheContent, err = handler.metaService.StreamMetadata(c, repoType, orgRepo, revision, authorization, query)
+	}
 	if err != nil {
-		if e, ok := err.(myerr.Error); ok {
-			return util.ErrorEntryUnknown(c, e.StatusCode(), e.Error())
+		if c.Response().Committed {
+			// 已开始回传远端响应，无法再返回错误信息
+			zap.S().Warnf("stream metadata %s/%s/%s err.%v", repoType, orgRepo, revision, err)
+			return nil
 		}
-		return util.ErrorProxyError(c)
+		return util.ResponseHfError(c, err)
+	}
+	if method == consts.RequestTypeGet {
+		handler.metaService.PrefetchPathsInfo(c, repoType, orgRepo, revision)
 	}
 	if cacheContent != nil {
 		if method == consts.RequestTypeHead {
 			return util.ResponseHeaders(c, http.StatusOK, cacheContent.Headers)
 		}
-		var bodyStreamChan = make(chan []byte, consts.RespChanSize)
-		bodyStreamChan <- cacheContent.OriginContent
-		close(bodyStreamChan)
-		err = util.ResponseStream(context.Background(), c, orgRepo, cacheContent.Headers, bodyStreamChan, nil)
+		err = util.ResponseStream(c.Request().Context(), c, orgRepo, cacheContent.Headers, bytes.NewReader(cacheContent.OriginContent), nil)
 		if err != nil {
 			return err
 		}
@@ -92,6 +120,11 @@ func (handler *MetaHandler) RepoRefsHandler(c echo.Context) error {
 	return handler.metaService.RepoRefs(c, repoType, org, repo)
 }
 
+// DatasetsServerHandler 路由为/{endpoint}，与datasets-server的接口路径一致。
+func (handler *MetaHandler) DatasetsServerHandler(c echo.Context) error {
+	return handler.metaService.DatasetsServer(c, strings.TrimPrefix(c.Path(), "/"))
+}
+
 func (handler *MetaHandler) ForwardToNewSiteHandler(c echo.Context) error {
 	return handler.metaService.ForwardToNewSite(c)
 }
@@ -112,9 +145,427 @@ func (handler *MetaHandler) RepositoryFilesHandler(c echo.Context) error {
 		zap.S().Errorf("MetaProxyCommon org and repo is null")
 		return util.ErrorRepoNotFound(c)
 	}
-	files, err := handler.metaService.RepositoryFiles(repoType, orgRepo, commit, filePath)
+	req := new(query.RepoFilesReq)
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, req); err != nil || req.Limit < 0 || req.MinSize < 0 || req.MaxSize < 0 {
+		return util.ErrorRequestParam(c)
+	}
+	files, nextCursor, err := handler.metaService.RepositoryFiles(c, repoType, orgRepo, commit, filePath, req)
 	if err != nil {
 		return util.ResponseError(c, err)
 	}
+	if nextCursor != "" {
+		// 与HF的tree接口一致，通过Link头返回下一页地址
+		nextUrl := *c.Request().URL
+		q := nextUrl.Query()
+		q.Set("cursor", nextCursor)
+		nextUrl.RawQuery = q.Encode()
+		c.Response().Header().Set("Link", fmt.Sprintf("<%s%s%s>; rel=\"next\"", util.RequestBaseURL(c), util.PathPrefix(c), nextUrl.RequestURI()))
+	}
 	return util.ResponseData(c, files)
 }
+
+func (handler *MetaHandler) LocalRevisionsHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	revisions, err := handler.metaService.LocalRevisions(repoType, orgRepo)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, revisions)
+}
+
+// RevalidateHandler 忽略缓存有效期，立即从远端刷新仓库的refs、分支元数据及paths-info，返回发生的变化。
+func (handler *MetaHandler) RevalidateHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	result, err := handler.metaService.Revalidate(c, repoType, orgRepo)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, result)
+}
+
+// RepoDiffHandler 对比本地缓存与远端的某版本，返回刷新将新增、删除及变化的文件，便于在刷新前评估需要下载的内容。
+func (handler *MetaHandler) RepoDiffHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	result, err := handler.metaService.RepoDiff(c, repoType, orgRepo, c.Param("revision"))
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, result)
+}
+
+// ProvenanceHandler 返回已缓存文件的获取时间、来源地址、etag、远端状态码及被访问次数，用于审计镜像文件的来源。
+func (handler *MetaHandler) ProvenanceHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	filePath := c.Param("*")
+	if filePath == "" {
+		return util.ErrorEntryNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	result, err := handler.metaService.Provenance(c, repoType, orgRepo, c.Param("revision"), filePath)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, result)
+}
+
+// PickleScanHandler 返回仓库某版本已缓存的pickle格式文件（.bin、.pt等）引用的导入及其安全性，path参数可限定文件或目录。
+func (handler *MetaHandler) PickleScanHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	result, err := handler.metaService.PickleScan(c, repoType, orgRepo, c.Param("revision"))
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, result)
+}
+
+// AttestationHandler 返回仓库某版本的签名清单，包含已缓存文件的sha256、etag及获取时间，下游以公钥校验payload。
+func (handler *MetaHandler) AttestationHandler(c echo.Context) error {
+	if !config.SysConfig.Attestation.Enabled {
+		return util.ErrorPageNotFound(c)
+	}
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	c.Set(consts.PromOrgRepo, orgRepo)
+	result, err := handler.metaService.Attestation(c, repoType, orgRepo, c.Param("revision"))
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, result)
+}
+
+func (handler *MetaHandler) AttestationKeyHandler(c echo.Context) error {
+	if !config.SysConfig.Attestation.Enabled {
+		return util.ErrorPageNotFound(c)
+	}
+	result, err := handler.metaService.AttestationKey()
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, result)
+}
+
+// RepoSizeHandler 返回仓库某版本的总大小及本地已缓存大小，便于评估完整下载还需拉取的数据量。
+func (handler *MetaHandler) RepoSizeHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	c.Set(consts.PromOrgRepo, orgRepo)
+	size, err := handler.metaService.RepoSize(c, repoType, orgRepo, c.Param("revision"))
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, size)
+}
+
+// MetalinkHandler 返回仓库某版本的metalink或aria2输入文件，供外部工具多连接下载整个仓库。
+func (handler *MetaHandler) MetalinkHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	format := c.QueryParam("format")
+	if format != "" && format != "metalink" && format != "aria2" {
+		return util.ErrorRequestParam(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	c.Set(consts.PromOrgRepo, orgRepo)
+	content, commitSha, err := handler.metaService.Metalink(c, repoType, orgRepo, c.Param("revision"), format)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	fileName := fmt.Sprintf("%s-%s", c.Param("repo"), commitSha)
+	if format == "aria2" {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s.aria2.txt", fileName))
+		return c.Blob(http.StatusOK, "text/plain; charset=utf-8", content)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s.meta4", fileName))
+	return c.Blob(http.StatusOK, "application/metalink4+xml", content)
+}
+
+// ArchiveHandler 以tar流式返回仓库某版本的全部文件，文件名以.tar.zst结尾时使用zstd压缩，
+// 文件按路径排序且使用固定的属性，相同版本总是得到相同的归档，最后一项为各文件的SHA256SUMS。
+func (handler *MetaHandler) ArchiveHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	archive := c.Param("archive")
+	revision, compress := strings.CutSuffix(archive, ".tar.zst")
+	if !compress {
+		var ok bool
+		if revision, ok = strings.CutSuffix(archive, ".tar"); !ok {
+			return util.ErrorPageNotFound(c)
+		}
+	}
+	if revision == "" {
+		return util.ErrorRequestParam(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	c.Set(consts.PromOrgRepo, orgRepo)
+	files, commitSha, err := handler.metaService.Archive(c, repoType, orgRepo, revision)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	prefix := fmt.Sprintf("%s-%s", c.Param("repo"), commitSha)
+	ext, contentType := ".tar", "application/x-tar"
+	if compress {
+		ext, contentType = ".tar.zst", "application/zstd"
+	}
+	etag := fmt.Sprintf(`"%s%s"`, commitSha, ext)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s%s", prefix, ext))
+	header.Set(consts.HUGGINGFACE_HEADER_X_REPO_COMMIT, commitSha)
+	header.Set("ETag", etag)
+	c.Response().WriteHeader(http.StatusOK)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	if err = util.WriteArchive(c.Response(), prefix, files, compress); err != nil {
+		// 响应已开始发送，无法再返回错误码，客户端读取到不完整的tar即可感知
+		zap.S().Errorf("archive %s/%s@%s err.%v", repoType, orgRepo, commitSha, err)
+	}
+	return nil
+}
+
+// CreateShareHandler 为已完整缓存的文件或快照生成签名的只读分享链接。
+func (handler *MetaHandler) CreateShareHandler(c echo.Context) error {
+	req := new(query.ShareReq)
+	if err := c.Bind(req); err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	if _, ok := consts.RepoTypesMapping[req.RepoType]; !ok || req.Repo == "" {
+		return util.ErrorRequestParam(c)
+	}
+	for name, value := range map[string]string{"org": req.Org, "repo": req.Repo, "revision": req.Revision, "filePath": req.FilePath} {
+		if err := util.ValidatePathParam(name, value); err != nil {
+			return util.ErrorHf(c, http.StatusBadRequest, "", err.Error())
+		}
+	}
+	resp, err := handler.metaService.CreateShare(c, req)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, resp)
+}
+
+// SharedFileHandler 校验分享链接的签名及有效期，返回已缓存的文件，无需认证。
+func (handler *MetaHandler) SharedFileHandler(c echo.Context) error {
+	repoType, orgRepo, commit, filePath, err := shareFileParams(c)
+	if err != nil {
+		return util.ErrorPageNotFound(c)
+	}
+	if !verifyShareLink(c, util.ShareScope(repoType, orgRepo, commit, filePath)) {
+		return util.ErrorHf(c, http.StatusForbidden, "", "share link is invalid or expired")
+	}
+	c.Set(consts.PromOrgRepo, orgRepo)
+	reader, fileSize, err := handler.metaService.SharedFile(repoType, orgRepo, commit, filePath)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	defer reader.Close()
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "application/octet-stream")
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(fileSize, 10))
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", path.Base(filePath)))
+	header.Set(consts.HUGGINGFACE_HEADER_X_REPO_COMMIT, commit)
+	c.Response().WriteHeader(http.StatusOK)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	if _, err = io.Copy(c.Response(), reader); err != nil {
+		zap.S().Warnf("send shared file %s/%s@%s/%s err.%v", repoType, orgRepo, commit, filePath, err)
+	}
+	return nil
+}
+
+// shareFileParams 解析分享文件链接的参数，文件路径在链接中转义为一段，无组织的仓库为/share/{repoType}/{repo}/resolve/...
+func shareFileParams(c echo.Context) (string, string, string, string, error) {
+	repoType, commit := c.Param("repoType"), c.Param("commit")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return "", "", "", "", fmt.Errorf("repoType:%s is invalid", repoType)
+	}
+	filePath, err := url.PathUnescape(c.Param("filePath"))
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return repoType, util.GetOrgRepo(c.Param("org"), c.Param("repo")), commit, filePath, nil
+}
+
+// ArchiveDirHandler 以tar流式返回仓库某版本一个目录（如数据集的某个split）下的文件，路径为{dir}.tar.gz、{dir}.tar.zst或{dir}.tar，
+// 文件须已完整缓存，归档内保留文件在仓库中的路径。
+func (handler *MetaHandler) ArchiveDirHandler(c echo.Context) error {
+	repoType, org := c.Param("repoType"), c.Param("org")
+	if repoType == "" {
+		// /{org}/{repo}/archive/...省略了models类型，/{repoType}/{repo}/archive/...为无组织的仓库
+		if _, ok := consts.RepoTypesMapping[c.Param("orgOrRepoType")]; ok {
+			repoType = c.Param("orgOrRepoType")
+		} else {
+			repoType, org = "models", c.Param("orgOrRepoType")
+		}
+	}
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	target, err := url.PathUnescape(c.Param("*"))
+	if err != nil {
+		return util.ErrorRequestParam(c)
+	}
+	var (
+		dir         string
+		ext         string
+		contentType string
+	)
+	for _, format := range [][2]string{{".tar.gz", "application/gzip"}, {".tar.zst", "application/zstd"}, {".tar", "application/x-tar"}} {
+		if d, ok := strings.CutSuffix(target, format[0]); ok {
+			dir, ext, contentType = strings.Trim(d, "/"), format[0], format[1]
+			break
+		}
+	}
+	if ext == "" {
+		return util.ErrorPageNotFound(c)
+	}
+	if dir == "" || strings.Contains(dir, "..") {
+		return util.ErrorRequestParam(c)
+	}
+	repo := c.Param("repo")
+	orgRepo := util.GetOrgRepo(org, repo)
+	c.Set(consts.PromOrgRepo, orgRepo)
+	files, commitSha, err := handler.metaService.ArchiveDir(c, repoType, orgRepo, c.Param("revision"), dir)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	prefix := fmt.Sprintf("%s-%s", repo, commitSha)
+	etag := fmt.Sprintf(`"%s/%s%s"`, commitSha, dir, ext)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s-%s%s", prefix, strings.ReplaceAll(dir, "/", "-"), ext))
+	header.Set(consts.HUGGINGFACE_HEADER_X_REPO_COMMIT, commitSha)
+	header.Set("ETag", etag)
+	c.Response().WriteHeader(http.StatusOK)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	var w io.Writer = c.Response()
+	var gw *gzip.Writer
+	if ext == ".tar.gz" {
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+	if err = util.WriteArchive(w, prefix, files, ext == ".tar.zst"); err == nil && gw != nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		zap.S().Errorf("archive %s/%s@%s/%s err.%v", repoType, orgRepo, commitSha, dir, err)
+	}
+	return nil
+}
+
+// SharedArchiveHandler 校验分享链接的签名及有效期，以tar返回快照的全部文件，无需认证。
+func (handler *MetaHandler) SharedArchiveHandler(c echo.Context) error {
+	repoType, archive := c.Param("repoType"), c.Param("archive")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	commit, compress := strings.CutSuffix(archive, ".tar.zst")
+	if !compress {
+		var ok bool
+		if commit, ok = strings.CutSuffix(archive, ".tar"); !ok {
+			return util.ErrorPageNotFound(c)
+		}
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	if !verifyShareLink(c, util.ShareScope(repoType, orgRepo, commit, "")) {
+		return util.ErrorHf(c, http.StatusForbidden, "", "share link is invalid or expired")
+	}
+	c.Set(consts.PromOrgRepo, orgRepo)
+	files, err := handler.metaService.SharedArchive(repoType, orgRepo, commit)
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	prefix := fmt.Sprintf("%s-%s", c.Param("repo"), commit)
+	ext, contentType := ".tar", "application/x-tar"
+	if compress {
+		ext, contentType = ".tar.zst", "application/zstd"
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%s%s", prefix, ext))
+	header.Set(consts.HUGGINGFACE_HEADER_X_REPO_COMMIT, commit)
+	c.Response().WriteHeader(http.StatusOK)
+	if c.Request().Method == http.MethodHead {
+		return nil
+	}
+	if err = util.WriteArchive(c.Response(), prefix, files, compress); err != nil {
+		zap.S().Errorf("shared archive %s/%s@%s err.%v", repoType, orgRepo, commit, err)
+	}
+	return nil
+}
+
+func verifyShareLink(c echo.Context, scope string) bool {
+	expires, err := strconv.ParseInt(c.QueryParam("expires"), 10, 64)
+	if err != nil {
+		return false
+	}
+	return util.VerifyShare(config.SysConfig.Share.Secret, scope, expires, c.QueryParam("sig"), time.Now())
+}
+
+// SyncManifestHandler 返回仓库某版本的同步清单，供其他镜像实例据此只传输缺少的文件。
+func (handler *MetaHandler) SyncManifestHandler(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	orgRepo := util.GetOrgRepo(c.Param("org"), c.Param("repo"))
+	manifest, err := handler.metaService.SyncManifest(c, repoType, orgRepo, c.Param("revision"))
+	if err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return util.ResponseData(c, manifest)
+}
+
+func (handler *MetaHandler) ModelCardHandler1(c echo.Context) error {
+	org := c.Param("org")
+	if org == "api" {
+		return handler.metaService.ForwardToNewSite(c)
+	}
+	return handler.metaService.ModelCard(c, "models", org, c.Param("repo"))
+}
+
+func (handler *MetaHandler) ModelCardHandler2(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return handler.metaService.ForwardToNewSite(c)
+	}
+	return handler.metaService.ModelCard(c, repoType, c.Param("org"), c.Param("repo"))
+}
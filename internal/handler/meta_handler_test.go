@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestShareFileParams(t *testing.T) {
+	e := echo.New()
+	var got [4]string
+	fileHandler := func(c echo.Context) error {
+		repoType, orgRepo, commit, filePath, err := shareFileParams(c)
+		if err != nil {
+			return c.NoContent(http.StatusNotFound)
+		}
+		got = [4]string{repoType, orgRepo, commit, filePath}
+		return c.NoContent(http.StatusOK)
+	}
+	archiveHandler := func(c echo.Context) error {
+		return c.NoContent(http.StatusAccepted)
+	}
+	// 与router中分享链接的路由一致
+	e.GET("/share/:repoType/:org/:repo/:commit/:filePath", fileHandler)
+	e.GET("/share/:repoType/:org/:repo/:archive", archiveHandler)
+	e.GET("/share/:repoType/:repo/resolve/:commit/:filePath", fileHandler)
+	e.GET("/share/:repoType/:repo/:archive", archiveHandler)
+
+	cases := []struct {
+		org, repo, filePath string
+		want                [4]string
+	}{
+		{"org", "repo", "subdir/x.bin", [4]string{"models", "org/repo", "abc", "subdir/x.bin"}},
+		{"org", "repo", "a b+c#d.txt", [4]string{"models", "org/repo", "abc", "a b+c#d.txt"}},
+		{"", "gpt2", "config.json", [4]string{"models", "gpt2", "abc", "config.json"}},
+		{"", "gpt2", "onnx/model.onnx", [4]string{"models", "gpt2", "abc", "onnx/model.onnx"}},
+	}
+	for _, tc := range cases {
+		got = [4]string{}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, util.SharePath("models", tc.org, tc.repo, "abc", tc.filePath), nil))
+		if rec.Code != http.StatusOK || got != tc.want {
+			t.Errorf("share link of %s/%s %s: code %d, params %v, want %v", tc.org, tc.repo, tc.filePath, rec.Code, got, tc.want)
+		}
+	}
+	for _, org := range []string{"org", ""} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, util.SharePath("models", org, "repo", "abc", ""), nil))
+		if rec.Code != http.StatusAccepted {
+			t.Errorf("archive link with org %q: code %d", org, rec.Code)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"net/http"
+
 	"dingospeed/internal/model"
 	"dingospeed/internal/service"
 	"dingospeed/pkg/app"
@@ -38,3 +40,12 @@ func (s *SysHandler) Info(c echo.Context) error {
 	info.DynamicProxy = string(marshal)
 	return util.ResponseData(c, info)
 }
+
+// Readyz 就绪检查，未预热时返回503，供负载均衡及自动扩缩容使用
+func (s *SysHandler) Readyz(c echo.Context) error {
+	r := s.sysService.Readiness()
+	if !r.Ready {
+		return c.JSON(http.StatusServiceUnavailable, r)
+	}
+	return util.ResponseData(c, r)
+}
@@ -0,0 +1,76 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package handler
+
+import (
+	"strings"
+
+	"dingospeed/internal/service"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+type UploadHandler struct {
+	uploadService *service.UploadService
+}
+
+func NewUploadHandler(uploadService *service.UploadService) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+	}
+}
+
+func (handler *UploadHandler) CommitHandler(c echo.Context) error {
+	if _, ok := consts.RepoTypesMapping[c.Param("repoType")]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	return handler.uploadService.Forward(c)
+}
+
+// RepoManageHandler 仓库创建、删除、移动等管理请求直接转发远端，鉴权信息原样透传。
+func (handler *UploadHandler) RepoManageHandler(c echo.Context) error {
+	return handler.uploadService.Forward(c)
+}
+
+func (handler *UploadHandler) RepoSettingsHandler(c echo.Context) error {
+	if _, ok := consts.RepoTypesMapping[c.Param("repoType")]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	return handler.uploadService.Forward(c)
+}
+
+func (handler *UploadHandler) LfsBatchHandler(c echo.Context) error {
+	return handler.lfsBatch(c, "models")
+}
+
+func (handler *UploadHandler) LfsBatchHandler2(c echo.Context) error {
+	repoType := c.Param("repoType")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok {
+		return util.ErrorPageNotFound(c)
+	}
+	return handler.lfsBatch(c, repoType)
+}
+
+func (handler *UploadHandler) lfsBatch(c echo.Context, repoType string) error {
+	repo := strings.TrimSuffix(c.Param("repo"), ".git")
+	orgRepo := util.GetOrgRepo(c.Param("org"), repo)
+	return handler.uploadService.LfsBatch(c, repoType, orgRepo)
+}
+
+func (handler *UploadHandler) LfsProxyHandler(c echo.Context) error {
+	return handler.uploadService.LfsProxy(c, c.Param("id"))
+}
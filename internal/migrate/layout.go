@@ -0,0 +1,110 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 缓存目录布局版本，每次存储结构变更时加1并在migrations中增加对应的升级步骤。
+// 0: 文件直接存放在resolve目录下
+// 1: 文件内容存放在blobs/{etag}，resolve目录下为指向blobs的软链接
+const CurrentLayoutVersion = 1
+
+type Layout struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func layoutPath(repos string) string {
+	return filepath.Join(repos, consts.LayoutFile)
+}
+
+// ReadLayout 读取缓存目录的布局版本，未标记时返回false。
+func ReadLayout(repos string) (*Layout, bool, error) {
+	data, err := os.ReadFile(layoutPath(repos))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	layout := &Layout{}
+	if err = sonic.Unmarshal(data, layout); err != nil {
+		return nil, false, fmt.Errorf("parse %s err.%v", layoutPath(repos), err)
+	}
+	return layout, true, nil
+}
+
+func WriteLayout(repos string, version int) error {
+	data, err := sonic.Marshal(&Layout{Version: version, UpdatedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(repos, 0755); err != nil {
+		return err
+	}
+	tmpPath := layoutPath(repos) + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, layoutPath(repos))
+}
+
+// LayoutVersion 返回缓存目录当前的布局版本，未标记且已有缓存数据时视为版本0，空目录视为当前版本。
+func LayoutVersion(repos string) (int, error) {
+	layout, ok, err := ReadLayout(repos)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return layout.Version, nil
+	}
+	for _, dir := range []string{"files", "api"} {
+		if util.FileExists(filepath.Join(repos, dir)) {
+			return 0, nil
+		}
+	}
+	return CurrentLayoutVersion, nil
+}
+
+// CheckLayout 启动时校验布局版本，新建的缓存目录直接标记为当前版本，高于当前版本时拒绝启动以免旧程序破坏数据。
+func CheckLayout(repos string) error {
+	version, err := LayoutVersion(repos)
+	if err != nil {
+		return err
+	}
+	if version > CurrentLayoutVersion {
+		return fmt.Errorf("cache layout version %d of %s is newer than supported version %d", version, repos, CurrentLayoutVersion)
+	}
+	if version < CurrentLayoutVersion {
+		// 旧布局的数据在访问时仍可兼容读取，建议停止服务后执行migrate完成升级
+		zap.S().Warnf("cache layout version of %s is %d, run `dingospeed migrate` to upgrade to %d", repos, version, CurrentLayoutVersion)
+		return nil
+	}
+	if _, ok, err := ReadLayout(repos); err != nil || ok {
+		return err
+	}
+	return WriteLayout(repos, CurrentLayoutVersion)
+}
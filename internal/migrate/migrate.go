@@ -0,0 +1,284 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package migrate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// migration 将缓存目录从From版本升级到From+1版本，需可重复执行，中断后再次运行能继续完成。
+// Plan只读取缓存目录，统计Run将执行的操作，用于dry-run及迁移时的进度。
+type migration struct {
+	From int
+	Desc string
+	Plan func(repos string) (*StepPlan, error)
+	Run  func(repos string, progress func(done int)) error
+}
+
+var migrations = []migration{
+	{From: 0, Desc: "move files under resolve into blobs and replace them with symlinks", Plan: planResolveToBlobs, Run: migrateResolveToBlobs},
+}
+
+// StepPlan 一个升级步骤预计执行的操作。
+type StepPlan struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Desc    string   `json:"desc"`
+	Moved   int      `json:"moved"`   // 移动到blobs并替换为软链接的文件数
+	Linked  int      `json:"linked"`  // blobs中已存在，只需替换为软链接的文件数
+	Skipped int      `json:"skipped"` // 缺少paths-info缓存而保留原样的文件数
+	Bytes   int64    `json:"bytes"`   // 移动的数据量
+	Samples []string `json:"samples"` // 部分将被改写的文件
+}
+
+func (p *StepPlan) Total() int {
+	return p.Moved + p.Linked
+}
+
+// Report dry-run的结果，EstimatedDowntime按扫描耗时（迁移时需重复）加上每个文件的移动及链接耗时估算，
+// 文件在同一文件系统内移动，与数据量无关。
+type Report struct {
+	Repos             string        `json:"repos"`
+	From              int           `json:"from"`
+	To                int           `json:"to"`
+	Steps             []*StepPlan   `json:"steps"`
+	EstimatedDowntime time.Duration `json:"estimatedDowntime"`
+}
+
+// Progress 迁移过程中报告步骤的进度，done为已处理的文件数。
+type Progress func(step *StepPlan, done int)
+
+const (
+	maxPlanSamples = 10
+	perFileCost    = time.Millisecond // 移动文件并创建软链接的预估耗时
+)
+
+// pendingMigrations 返回从version升级到当前版本需执行的步骤。
+func pendingMigrations(repos string) (int, []migration, error) {
+	version, err := LayoutVersion(repos)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version > CurrentLayoutVersion {
+		return 0, nil, fmt.Errorf("cache layout version %d is newer than supported version %d", version, CurrentLayoutVersion)
+	}
+	pending := make([]migration, 0)
+	for _, m := range migrations {
+		if m.From >= version {
+			pending = append(pending, m)
+		}
+	}
+	return version, pending, nil
+}
+
+// DryRun 统计升级将移动及改写的文件和预估停机时间，不修改缓存目录。
+func DryRun(repos string) (*Report, error) {
+	version, pending, err := pendingMigrations(repos)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Repos: repos, From: version, To: CurrentLayoutVersion}
+	start := time.Now()
+	for _, m := range pending {
+		plan, err := m.Plan(repos)
+		if err != nil {
+			return nil, fmt.Errorf("plan layout %d -> %d err.%v", m.From, m.From+1, err)
+		}
+		plan.From, plan.To, plan.Desc = m.From, m.From+1, m.Desc
+		report.Steps = append(report.Steps, plan)
+		report.EstimatedDowntime += time.Duration(plan.Total()) * perFileCost
+	}
+	report.EstimatedDowntime += time.Since(start)
+	return report, nil
+}
+
+// Migrate 依次执行升级步骤，每完成一步即更新布局版本，执行期间需停止服务。progress非空时报告各步骤的进度。
+func Migrate(repos string, progress Progress) error {
+	version, pending, err := pendingMigrations(repos)
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		zap.S().Infof("migrate %s layout %d -> %d: %s", repos, m.From, m.From+1, m.Desc)
+		report := func(int) {}
+		if progress != nil {
+			plan, err := m.Plan(repos)
+			if err != nil {
+				return fmt.Errorf("plan layout %d -> %d err.%v", m.From, m.From+1, err)
+			}
+			plan.From, plan.To, plan.Desc = m.From, m.From+1, m.Desc
+			report = func(done int) { progress(plan, done) }
+		}
+		if err = m.Run(repos, report); err != nil {
+			return fmt.Errorf("migrate layout %d -> %d err.%v", m.From, m.From+1, err)
+		}
+		if err = WriteLayout(repos, m.From+1); err != nil {
+			return err
+		}
+		version = m.From + 1
+	}
+	if version != CurrentLayoutVersion {
+		return fmt.Errorf("no migration from layout version %d", version)
+	}
+	return WriteLayout(repos, CurrentLayoutVersion)
+}
+
+// resolveFile resolve目录下待转换为软链接的文件
+type resolveFile struct {
+	path      string
+	blobsFile string
+	size      int64
+	exists    bool // blobs中已存在，只需删除后创建软链接
+}
+
+// scanResolveFiles 早期版本将文件直接存放在files/{type}/{org}/{repo}/resolve/{commit}/{path}，
+// 按paths-info缓存中的etag确定对应的blobs文件，没有paths-info缓存的文件计入skipped。
+func scanResolveFiles(repos string, fn func(f *resolveFile) error) (int, error) {
+	filesRoot := filepath.Join(repos, "files")
+	if !util.FileExists(filesRoot) {
+		return 0, nil
+	}
+	var skipped int
+	err := filepath.WalkDir(filesRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(filesRoot, path)
+		if err != nil {
+			return err
+		}
+		repoType, orgRepo, commit, fileName, ok := splitResolvePath(filepath.ToSlash(rel))
+		if !ok {
+			return nil
+		}
+		pathsInfoFile := filepath.Join(repos, "api", repoType, orgRepo, "paths-info", commit, fileName, "paths-info_post.json")
+		etag, err := readEtag(pathsInfoFile, fileName)
+		if err != nil {
+			zap.S().Warnf("skip %s, read etag err.%v", path, err)
+			skipped++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		blobsFile := filepath.Join(filesRoot, repoType, orgRepo, "blobs", etag)
+		return fn(&resolveFile{path: path, blobsFile: blobsFile, size: info.Size(), exists: util.FileExists(blobsFile)})
+	})
+	return skipped, err
+}
+
+func planResolveToBlobs(repos string) (*StepPlan, error) {
+	plan := &StepPlan{Samples: make([]string, 0)}
+	skipped, err := scanResolveFiles(repos, func(f *resolveFile) error {
+		if f.exists {
+			plan.Linked++
+		} else {
+			plan.Moved++
+			plan.Bytes += f.size
+		}
+		if len(plan.Samples) < maxPlanSamples {
+			plan.Samples = append(plan.Samples, f.path)
+		}
+		return nil
+	})
+	plan.Skipped = skipped
+	return plan, err
+}
+
+// migrateResolveToBlobs 将resolve目录下的文件移动到blobs目录，原位置改为软链接，没有paths-info缓存的文件保留原样，访问时再转换。
+func migrateResolveToBlobs(repos string, progress func(done int)) error {
+	var moved int
+	skipped, err := scanResolveFiles(repos, func(f *resolveFile) error {
+		if f.exists {
+			if err := os.Remove(f.path); err != nil {
+				return err
+			}
+		} else {
+			if err := os.MkdirAll(filepath.Dir(f.blobsFile), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(f.path, f.blobsFile); err != nil {
+				return err
+			}
+		}
+		if err := util.CreateSymlinkIfNotExists(f.blobsFile, f.path); err != nil {
+			return err
+		}
+		moved++
+		progress(moved)
+		return nil
+	})
+	zap.S().Infof("migrate resolve to blobs, moved %d files, skipped %d files", moved, skipped)
+	return err
+}
+
+// splitResolvePath 解析{type}/{org}/{repo}/resolve/{commit}/{path}形式的相对路径，org可以为空。
+func splitResolvePath(rel string) (repoType, orgRepo, commit, fileName string, ok bool) {
+	parts := strings.Split(rel, "/")
+	for i := 2; i < len(parts)-2 && i <= 3; i++ {
+		if parts[i] == "resolve" {
+			return parts[0], strings.Join(parts[1:i], "/"), parts[i+1], strings.Join(parts[i+2:], "/"), true
+		}
+	}
+	return "", "", "", "", false
+}
+
+func readEtag(pathsInfoFile, fileName string) (string, error) {
+	data, err := util.ReadCacheFile(pathsInfoFile)
+	if err != nil {
+		return "", err
+	}
+	cacheContent := common.CacheContent{}
+	if err = sonic.Unmarshal(data, &cacheContent); err != nil {
+		return "", err
+	}
+	content, err := hex.DecodeString(cacheContent.Content)
+	if err != nil {
+		return "", err
+	}
+	pathsInfos := make([]common.PathsInfo, 0)
+	if err = sonic.Unmarshal(content, &pathsInfos); err != nil {
+		return "", err
+	}
+	for _, pathsInfo := range pathsInfos {
+		if pathsInfo.Path != fileName {
+			continue
+		}
+		// 与下载时的blobs命名保持一致，LFS文件使用sha256
+		if pathsInfo.Lfs.Oid != "" {
+			return pathsInfo.Lfs.Oid, nil
+		}
+		if pathsInfo.Oid != "" {
+			return pathsInfo.Oid, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not found in paths-info", fileName)
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dingospeed/pkg/common"
+
+	"github.com/bytedance/sonic"
+)
+
+func TestSplitResolvePath(t *testing.T) {
+	cases := []struct {
+		rel                                 string
+		repoType, orgRepo, commit, fileName string
+		ok                                  bool
+	}{
+		{"models/org/repo/resolve/abc/sub/a.bin", "models", "org/repo", "abc", "sub/a.bin", true},
+		{"models/gpt2/resolve/abc/config.json", "models", "gpt2", "abc", "config.json", true},
+		{"models/org/repo/blobs/etag", "", "", "", "", false},
+		{"models/org/repo/resolve/abc", "", "", "", "", false},
+	}
+	for _, c := range cases {
+		repoType, orgRepo, commit, fileName, ok := splitResolvePath(c.rel)
+		if ok != c.ok || repoType != c.repoType || orgRepo != c.orgRepo || commit != c.commit || fileName != c.fileName {
+			t.Errorf("splitResolvePath(%s) = %s %s %s %s %v", c.rel, repoType, orgRepo, commit, fileName, ok)
+		}
+	}
+}
+
+func TestMigrateResolveToBlobs(t *testing.T) {
+	repos := t.TempDir()
+	filesPath := filepath.Join(repos, "files/models/org/repo/resolve/abc/model.bin")
+	if err := os.MkdirAll(filepath.Dir(filesPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filesPath, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pathsInfo, _ := sonic.Marshal([]common.PathsInfo{{Type: "file", Path: "model.bin", Lfs: common.Lfs{Oid: "sha256"}}})
+	cacheContent, _ := sonic.Marshal(common.CacheContent{Version: 1, StatusCode: 200, Content: hex.EncodeToString(pathsInfo)})
+	pathsInfoFile := filepath.Join(repos, "api/models/org/repo/paths-info/abc/model.bin/paths-info_post.json")
+	if err := os.MkdirAll(filepath.Dir(pathsInfoFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pathsInfoFile, cacheContent, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if version, _ := LayoutVersion(repos); version != 0 {
+		t.Fatalf("expected layout version 0, got %d", version)
+	}
+	report, err := DryRun(repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Steps) != 1 || report.Steps[0].Moved != 1 || report.Steps[0].Bytes != 4 {
+		t.Fatalf("unexpected dry-run report %+v", report.Steps)
+	}
+	if info, err := os.Lstat(filesPath); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("dry-run must not modify %s", filesPath)
+	}
+	var done int
+	if err := Migrate(repos, func(step *StepPlan, n int) { done = n }); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(filesPath); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected %s to be a symlink", filesPath)
+	}
+	if data, err := os.ReadFile(filesPath); err != nil || string(data) != "data" {
+		t.Fatalf("unexpected content %q, %v", data, err)
+	}
+	if done != 1 {
+		t.Fatalf("expected progress of 1 file, got %d", done)
+	}
+	if version, _ := LayoutVersion(repos); version != CurrentLayoutVersion {
+		t.Fatalf("expected layout version %d, got %d", CurrentLayoutVersion, version)
+	}
+}
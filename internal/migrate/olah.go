@@ -0,0 +1,256 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package migrate
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// olah缓存文件头：4字节magic "OLAH"，随后为小端的version、blockSize、fileSize、blockMaskSize，之后是块位图。
+// 每个块按blockSize对齐存放，因此文件内容的第p个字节位于headerSize+p处。
+const (
+	olahMagic          = "OLAH"
+	olahHeaderFixSize  = 36
+	olahMaxBlockMaskSz = 1 << 24
+)
+
+type olahHeader struct {
+	version    uint64
+	blockSize  int64
+	fileSize   int64
+	headerSize int64
+	blockMask  []byte
+}
+
+func readOlahHeader(r io.Reader) (*olahHeader, error) {
+	fix := make([]byte, olahHeaderFixSize)
+	if _, err := io.ReadFull(r, fix); err != nil {
+		return nil, err
+	}
+	if string(fix[:4]) != olahMagic {
+		return nil, fmt.Errorf("not an olah cache file")
+	}
+	h := &olahHeader{
+		version:   binary.LittleEndian.Uint64(fix[4:12]),
+		blockSize: int64(binary.LittleEndian.Uint64(fix[12:20])),
+		fileSize:  int64(binary.LittleEndian.Uint64(fix[20:28])),
+	}
+	maskSize := binary.LittleEndian.Uint64(fix[28:36])
+	if h.blockSize <= 0 || h.fileSize < 0 || maskSize > olahMaxBlockMaskSz {
+		return nil, fmt.Errorf("invalid olah cache header")
+	}
+	h.blockMask = make([]byte, (maskSize+7)/8)
+	if _, err := io.ReadFull(r, h.blockMask); err != nil {
+		return nil, err
+	}
+	h.headerSize = olahHeaderFixSize + int64(len(h.blockMask))
+	return h, nil
+}
+
+func (h *olahHeader) hasBlock(blockIndex int64) bool {
+	byteIndex := blockIndex / 8
+	if byteIndex >= int64(len(h.blockMask)) {
+		return false
+	}
+	return h.blockMask[byteIndex]&(1<<(blockIndex%8)) != 0
+}
+
+// hasRange [start,end)范围涉及的块是否均已缓存
+func (h *olahHeader) hasRange(start, end int64) bool {
+	for blockIndex := start / h.blockSize; blockIndex*h.blockSize < end; blockIndex++ {
+		if !h.hasBlock(blockIndex) {
+			return false
+		}
+	}
+	return true
+}
+
+type OlahImportResult struct {
+	Files   int   `json:"files"`   // 导入的文件数
+	Blocks  int64 `json:"blocks"`  // 写入的块数
+	Skipped int   `json:"skipped"` // 无法确定etag或格式不符而跳过的文件数
+	ApiFile int   `json:"apiFile"` // 复制的api缓存文件数
+}
+
+// ImportOlah 导入olah镜像的缓存目录，api缓存与本程序格式相同直接复制，files下的olah缓存文件按块转换为blobs文件，
+// 并在resolve目录下建立软链接。源目录只读取，不做修改，已存在的数据不会被覆盖。
+func ImportOlah(src, repos string) (*OlahImportResult, error) {
+	result := &OlahImportResult{}
+	if !util.FileExists(src) {
+		return nil, fmt.Errorf("%s is not exist", src)
+	}
+	if err := copyOlahApi(src, repos, result); err != nil {
+		return result, err
+	}
+	filesRoot := filepath.Join(src, "files")
+	if !util.FileExists(filesRoot) {
+		return result, nil
+	}
+	err := filepath.WalkDir(filesRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(filesRoot, path)
+		if err != nil {
+			return err
+		}
+		repoType, orgRepo, commit, fileName, ok := splitResolvePath(filepath.ToSlash(rel))
+		if !ok {
+			return nil
+		}
+		blocks, err := importOlahFile(src, repos, path, repoType, orgRepo, commit, fileName)
+		if err != nil {
+			zap.S().Warnf("skip olah file %s.%v", path, err)
+			result.Skipped++
+			return nil
+		}
+		result.Files++
+		result.Blocks += blocks
+		return nil
+	})
+	return result, err
+}
+
+func importOlahFile(src, repos, path, repoType, orgRepo, commit, fileName string) (int64, error) {
+	etag, err := olahEtag(src, repoType, orgRepo, commit, fileName)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	header, err := readOlahHeader(f)
+	if err != nil {
+		return 0, err
+	}
+	blobsFile := filepath.Join(repos, "files", repoType, orgRepo, "blobs", etag)
+	blocks, err := downloader.ImportRanges(blobsFile, header.fileSize, func(start, end int64) ([]byte, bool, error) {
+		if !header.hasRange(start, end) {
+			return nil, false, nil
+		}
+		data := make([]byte, end-start)
+		if _, err := f.ReadAt(data, header.headerSize+start); err != nil {
+			return nil, false, err
+		}
+		return data, true, nil
+	})
+	if err != nil {
+		return blocks, err
+	}
+	filesPath := filepath.Join(repos, "files", repoType, orgRepo, "resolve", commit, fileName)
+	if err = util.MakeDirs(filesPath); err != nil {
+		return blocks, err
+	}
+	return blocks, util.CreateSymlinkIfNotExists(blobsFile, filesPath)
+}
+
+// olahEtag 优先读取olah保存的HEAD响应头，其次读取paths-info缓存
+func olahEtag(src, repoType, orgRepo, commit, fileName string) (string, error) {
+	for _, dir := range []string{"resolve_head", "resolve"} {
+		headPath := filepath.Join(src, "heads", repoType, orgRepo, dir, commit, fileName)
+		if util.IsDir(headPath) {
+			headPath = filepath.Join(headPath, "meta_head.json")
+		}
+		data, err := util.ReadCacheFile(headPath)
+		if err != nil {
+			continue
+		}
+		cacheContent := common.CacheContent{}
+		if err = sonic.Unmarshal(data, &cacheContent); err != nil {
+			continue
+		}
+		for _, key := range []string{"x-linked-etag", "etag"} {
+			for k, v := range cacheContent.Headers {
+				if strings.EqualFold(k, key) && v != "" {
+					return strings.Trim(strings.TrimPrefix(v, "W/"), "\""), nil
+				}
+			}
+		}
+	}
+	pathsInfoFile := filepath.Join(src, "api", repoType, orgRepo, "paths-info", commit, fileName, "paths-info_post.json")
+	return readEtag(pathsInfoFile, fileName)
+}
+
+// copyOlahApi 复制api缓存（meta、paths-info等），本地已存在的文件保留不动
+func copyOlahApi(src, repos string, result *OlahImportResult) error {
+	apiRoot := filepath.Join(src, "api")
+	if !util.FileExists(apiRoot) {
+		return nil
+	}
+	return filepath.WalkDir(apiRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(repos, rel)
+		if _, err = os.Lstat(dst); err == nil {
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err = copyFile(path, dst); err != nil {
+			return err
+		}
+		result.ApiFile++
+		return nil
+	})
+}
+
+func copyFile(src, dst string) error {
+	if err := util.MakeDirs(dst); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
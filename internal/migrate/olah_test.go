@@ -0,0 +1,29 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestReadOlahHeader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(olahMagic)
+	for _, v := range []uint64{8, 4, 10, 16} { // version, blockSize, fileSize, blockMaskSize
+		_ = binary.Write(buf, binary.LittleEndian, v)
+	}
+	buf.Write([]byte{0b101, 0}) // 第0、2块已缓存
+	header, err := readOlahHeader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.blockSize != 4 || header.fileSize != 10 || header.headerSize != olahHeaderFixSize+2 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	if !header.hasRange(0, 4) || header.hasRange(0, 8) || !header.hasRange(8, 10) {
+		t.Errorf("unexpected block mask %08b", header.blockMask[0])
+	}
+	if _, err = readOlahHeader(bytes.NewReader([]byte("NOTOLAH..."))); err == nil {
+		t.Errorf("expected error for invalid magic")
+	}
+}
@@ -1,5 +1,13 @@
 package query
 
+import (
+	"time"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/util"
+)
+
 type CreateCacheJobReq struct {
 	Type         int32  `json:"type"`
 	InstanceId   string `json:"instanceId"`
@@ -7,6 +15,16 @@ type CreateCacheJobReq struct {
 	Org          string `json:"org"`
 	Repo         string `json:"repo"`
 	RepositoryId int64  `json:"repositoryId"`
+	Collection   string `json:"collection"` // 预热collection中的所有模型和数据集，如 org/name-64f9a55bb3115b4f513ec026
+}
+
+// CollectionCacheJob collection中单个仓库的预热任务，创建失败时err不为空
+type CollectionCacheJob struct {
+	Datatype string `json:"datatype"`
+	Org      string `json:"org"`
+	Repo     string `json:"repo"`
+	JobId    int64  `json:"jobId"`
+	Err      string `json:"err,omitempty"`
 }
 
 type ResumeCacheJobReq struct {
@@ -34,3 +52,496 @@ type RealtimeResp struct {
 	StockSpeed   string  `json:"stockSpeed"`
 	StockProcess float32 `json:"stockProcess"`
 }
+
+type JobProgressResp struct {
+	CacheJobId   int64           `json:"cacheJobId"`
+	Datatype     string          `json:"datatype"`
+	Org          string          `json:"org"`
+	Repo         string          `json:"repo"`
+	Commit       string          `json:"commit"`
+	StockSpeed   string          `json:"stockSpeed"`
+	StockProcess float32         `json:"stockProcess"`
+	Eta          int64           `json:"eta"` // 预计剩余时间，单位秒，-1表示未知
+	Err          string          `json:"err,omitempty"`
+	Files        []*FileProgress `json:"files"`
+}
+
+type FileProgress struct {
+	FileName   string `json:"fileName"`
+	Size       int64  `json:"size"`
+	Downloaded int64  `json:"downloaded"`
+	Status     string `json:"status"`
+	Err        string `json:"err,omitempty"`
+}
+
+type RepoProgressResp struct {
+	Jobs      []*JobProgressResp         `json:"jobs"`
+	Downloads []*downloader.TransferInfo `json:"downloads"`
+}
+
+// RepoTier 仓库默认版本（main，未缓存时为最近缓存的版本）的缓存完整度，Percent按已缓存字节数占仓库大小的比例计算，
+// 仓库大小未知时按已完整缓存的文件数计算。
+type RepoTier struct {
+	OrgRepo     string  `json:"orgRepo"`
+	Tier        string  `json:"tier"`
+	Sha         string  `json:"sha"`
+	TotalFiles  int     `json:"totalFiles"`
+	CachedFiles int     `json:"cachedFiles"`
+	CachedSize  int64   `json:"cachedSize"`
+	TotalSize   int64   `json:"totalSize"`
+	Percent     float64 `json:"percent"`
+}
+
+type LocalRevisionResp struct {
+	Sha          string   `json:"sha"`
+	Aliases      []string `json:"aliases"` // 解析到该sha的分支或标签
+	Siblings     int      `json:"siblings"`
+	CachedFiles  int      `json:"cachedFiles"`
+	LastModified int64    `json:"lastModified"`
+}
+
+// ProvenanceResp 缓存文件的来源信息，由paths-info缓存、blob文件及访问统计拼出。
+type ProvenanceResp struct {
+	Sha            string `json:"sha"`
+	Path           string `json:"path"`
+	Size           int64  `json:"size"`
+	Etag           string `json:"etag"`           // blob的etag，LFS文件为sha256
+	UpstreamStatus int    `json:"upstreamStatus"` // 获取paths-info时远端的状态码
+	UpstreamEtag   string `json:"upstreamEtag,omitempty"`
+	InfoFetchedAt  int64  `json:"infoFetchedAt"` // paths-info的缓存时间戳，单位秒
+	CachedSize     int64  `json:"cachedSize"`
+	Complete       bool   `json:"complete"`
+	BlobModified   int64  `json:"blobModified,omitempty"` // blob文件最近写入的时间戳，单位秒
+	FetchedAt      int64  `json:"fetchedAt,omitempty"`    // 最近一次从远端拉取数据的时间戳，单位秒
+	Endpoint       string `json:"endpoint,omitempty"`     // 最近一次拉取数据的远端地址
+	Requests       int64  `json:"requests"`               // 下载请求数
+	Hits           int64  `json:"hits"`                   // 完全由缓存响应的请求数
+	Resolves       int64  `json:"resolves"`
+	LastAccess     int64  `json:"lastAccess"`
+}
+
+// PickleScanResp 仓库某版本已缓存的pickle格式文件引用的导入，dangerous为可执行代码的导入数。
+type PickleScanResp struct {
+	Sha       string            `json:"sha"`
+	Dangerous int               `json:"dangerous"`
+	Files     []*PickleScanFile `json:"files"`
+}
+
+type PickleScanFile struct {
+	Path      string               `json:"path"`
+	Size      int64                `json:"size"`
+	Cached    bool                 `json:"cached"` // 未完整缓存的文件不扫描
+	Error     string               `json:"error,omitempty"`
+	Dangerous int                  `json:"dangerous"`
+	Imports   []*util.PickleImport `json:"imports"`
+}
+
+// AttestationManifest 签名的版本清单，只包含已完整缓存的文件，sha256由本地缓存的内容计算。
+type AttestationManifest struct {
+	Version     int                `json:"version"`
+	RepoType    string             `json:"repoType"`
+	OrgRepo     string             `json:"orgRepo"`
+	Revision    string             `json:"revision"`
+	Commit      string             `json:"commit"`
+	GeneratedAt int64              `json:"generatedAt"`
+	Files       []*AttestationFile `json:"files"`
+	Missing     []string           `json:"missing"` // 未完整缓存、未列入清单的文件
+}
+
+type AttestationFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Sha256    string `json:"sha256"`
+	Etag      string `json:"etag"`                // 远端的etag，LFS文件为sha256，其余为git blob id
+	FetchedAt int64  `json:"fetchedAt,omitempty"` // 最近一次从远端拉取的时间戳，单位秒
+}
+
+// AttestationResp payload为清单序列化后的原始字节（base64），签名针对payload原文。
+type AttestationResp struct {
+	Payload   string               `json:"payload"`
+	Signature string               `json:"signature"`
+	KeyId     string               `json:"keyId"`
+	Algorithm string               `json:"algorithm"`
+	Manifest  *AttestationManifest `json:"manifest"`
+}
+
+type AttestationKeyResp struct {
+	KeyId     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"` // PKIX PEM
+}
+
+type RepoSizeResp struct {
+	Sha           string          `json:"sha"`
+	TotalFiles    int             `json:"totalFiles"`
+	TotalSize     int64           `json:"totalSize"`
+	CachedFiles   int             `json:"cachedFiles"` // 已完整缓存的文件数
+	CachedSize    int64           `json:"cachedSize"`
+	RemainingSize int64           `json:"remainingSize"` // 完整下载该版本还需从远端拉取的字节数
+	UnknownFiles  int             `json:"unknownFiles"`  // 离线且无paths-info缓存，无法获取大小的文件数
+	Files         []*RepoFileSize `json:"files"`
+}
+
+type RepoFileSize struct {
+	Path       string `json:"path"`
+	Size       int64  `json:"size"` // -1表示未知
+	CachedSize int64  `json:"cachedSize"`
+}
+
+// OciExportReq reference与layoutDir二选一，分别推送到镜像仓库或导出为repos/oci下的OCI布局目录
+type OciExportReq struct {
+	Datatype  string `json:"datatype"`
+	Org       string `json:"org"`
+	Repo      string `json:"repo"`
+	Revision  string `json:"revision"`
+	Reference string `json:"reference"` // host/name:tag，未指定tag时使用commit sha
+	LayoutDir string `json:"layoutDir"`
+	Tag       string `json:"tag"` // 导出布局目录时的tag，默认为commit sha
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	PlainHttp bool   `json:"plainHttp"` // 镜像仓库使用http协议
+}
+
+type OciExportJob struct {
+	Id         int64     `json:"id"`
+	Datatype   string    `json:"datatype"`
+	OrgRepo    string    `json:"orgRepo"`
+	Commit     string    `json:"commit"`
+	Target     string    `json:"target"`
+	Status     string    `json:"status"`
+	Digest     string    `json:"digest,omitempty"` // 清单摘要
+	TotalFiles int       `json:"totalFiles"`
+	DoneFiles  int       `json:"doneFiles"`
+	Err        string    `json:"err,omitempty"`
+	StartTime  time.Time `json:"startTime"`
+	EndTime    time.Time `json:"endTime,omitempty"`
+}
+
+// RevalidateResp 强制刷新仓库元数据的结果，只列出发生变化的项
+type RevalidateResp struct {
+	Refs      []*RefChange       `json:"refs"`
+	Revisions []*RevisionChange  `json:"revisions"`
+	PathsInfo []*PathsInfoChange `json:"pathsInfo"`
+	Queued    bool               `json:"queued,omitempty"` // 远端请求预算已用尽，刷新已排队到下一个预算窗口
+}
+
+// RefChange old为空表示新增，new为空表示远端已删除
+type RefChange struct {
+	Ref string `json:"ref"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// RevisionChange 本地已缓存的分支或标签，newSha为空表示远端已不存在，本地缓存保持不变
+type RevisionChange struct {
+	Revision string `json:"revision"`
+	OldSha   string `json:"oldSha"`
+	NewSha   string `json:"newSha"`
+}
+
+// PathsInfoChange 以分支名缓存的paths-info，newOid为空表示文件在新版本中已删除
+type PathsInfoChange struct {
+	Revision string `json:"revision"`
+	Path     string `json:"path"`
+	OldOid   string `json:"oldOid"`
+	NewOid   string `json:"newOid"`
+	OldSize  int64  `json:"oldSize"`
+	NewSize  int64  `json:"newSize"`
+}
+
+const (
+	DiffStatusAdded   = "added"
+	DiffStatusRemoved = "removed"
+	DiffStatusChanged = "changed"
+)
+
+// RepoDiffResp 本地缓存与远端版本的差异，downloadSize为刷新后需要下载的大小（新blob未缓存的文件）
+type RepoDiffResp struct {
+	Revision     string          `json:"revision"`
+	LocalSha     string          `json:"localSha"`
+	RemoteSha    string          `json:"remoteSha"`
+	Added        int             `json:"added"`
+	Removed      int             `json:"removed"`
+	Changed      int             `json:"changed"`
+	DownloadSize int64           `json:"downloadSize"`
+	Files        []*RepoDiffFile `json:"files"`
+}
+
+// RepoDiffFile size为-1表示本地未缓存该文件的paths-info，cached表示远端版本的blob已完整缓存
+type RepoDiffFile struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	OldOid  string `json:"oldOid"`
+	NewOid  string `json:"newOid"`
+	OldSize int64  `json:"oldSize"`
+	NewSize int64  `json:"newSize"`
+	Cached  bool   `json:"cached"`
+}
+
+// TrashEntry 回收站中的仓库缓存，expireAt之后被永久删除
+type TrashEntry struct {
+	Id        string    `json:"id"`
+	Datatype  string    `json:"datatype"`
+	OrgRepo   string    `json:"orgRepo"`
+	Size      int64     `json:"size"`
+	DeletedAt time.Time `json:"deletedAt"`
+	ExpireAt  time.Time `json:"expireAt"`
+}
+
+type RevisionGcResp struct {
+	DryRun bool              `json:"dryRun"`
+	Repos  []*RevisionGcRepo `json:"repos"`
+	Blobs  int               `json:"blobs"`
+	Size   int64             `json:"size"`
+}
+
+// RevisionGcRepo 单个仓库的回收结果，revisions为被回收的版本
+type RevisionGcRepo struct {
+	Datatype  string   `json:"datatype"`
+	OrgRepo   string   `json:"orgRepo"`
+	Revisions []string `json:"revisions"`
+	Blobs     int      `json:"blobs"`
+	Size      int64    `json:"size"`
+}
+
+// BatchReq 批量管理操作，patterns为{repoType}/{org}/{repo}格式的glob，如models/org/*，带通配符时只匹配已缓存的仓库
+type BatchReq struct {
+	Action    string   `json:"action"` // purge、pin、unpin、prefetch
+	Patterns  []string `json:"patterns"`
+	Revision  string   `json:"revision"`  // pin、unpin、prefetch的版本，默认main
+	OlderThan int      `json:"olderThan"` // purge时只删除最后更新早于该天数的版本，0表示将整个仓库移入回收站
+	DryRun    bool     `json:"dryRun"`    // 只列出匹配的仓库及将被删除的版本，不做修改
+}
+
+type BatchJob struct {
+	Id        int64        `json:"id"`
+	Action    string       `json:"action"`
+	Patterns  []string     `json:"patterns"`
+	DryRun    bool         `json:"dryRun"`
+	Status    string       `json:"status"`
+	Total     int          `json:"total"`
+	Done      int          `json:"done"`
+	Failed    int          `json:"failed"`
+	Items     []*BatchItem `json:"items"`
+	StartTime time.Time    `json:"startTime"`
+	EndTime   time.Time    `json:"endTime,omitempty"`
+}
+
+// BatchItem 单个仓库的执行结果，revisions为删除、固定或预取的版本
+type BatchItem struct {
+	Datatype  string   `json:"datatype"`
+	OrgRepo   string   `json:"orgRepo"`
+	Status    string   `json:"status"`
+	Revisions []string `json:"revisions,omitempty"`
+	Size      int64    `json:"size,omitempty"` // 删除或下载的字节数
+	Err       string   `json:"err,omitempty"`
+}
+
+// IdleReport 已缓存仓库的闲置报告，repos为闲置超过days天的仓库，按闲置天数倒序；heatmap按闲置天数分段统计全部已缓存仓库
+type IdleReport struct {
+	Days      int           `json:"days"`
+	Repos     []*IdleRepo   `json:"repos"`
+	TotalSize int64         `json:"totalSize"` // repos的总大小
+	Heatmap   []*IdleBucket `json:"heatmap"`
+}
+
+// IdleRepo 仓库最近访问时间取最近一次resolve，没有记录时取缓存目录的修改时间
+type IdleRepo struct {
+	Datatype   string `json:"datatype"`
+	OrgRepo    string `json:"orgRepo"`
+	Size       int64  `json:"size"`
+	LastAccess int64  `json:"lastAccess"`
+	IdleDays   int    `json:"idleDays"`
+}
+
+// IdleBucket 闲置天数在[minDays, maxDays)内的仓库数及大小，maxDays为0表示不设上限
+type IdleBucket struct {
+	MinDays int   `json:"minDays"`
+	MaxDays int   `json:"maxDays"`
+	Repos   int   `json:"repos"`
+	Size    int64 `json:"size"`
+}
+
+// IdlePurgeReq 将闲置超过days天的仓库移入回收站，repos不为空时只删除其中仍闲置的仓库（格式为{repoType}/{org}/{repo}），
+// 用于按先前查看的报告删除，避免删除期间被重新访问的仓库
+type IdlePurgeReq struct {
+	Days   int      `json:"days"`
+	Repos  []string `json:"repos"`
+	DryRun bool     `json:"dryRun"`
+}
+
+// CompactReq 缓存整理，bandwidth为比较及重写文件的读取速度上限，单位MB/s，0表示不限制
+type CompactReq struct {
+	DryRun    bool `json:"dryRun"` // 只统计可合并的blob、可删除的空目录及需重写的元数据文件，不做修改
+	Bandwidth int  `json:"bandwidth"`
+}
+
+// CompactResp 缓存整理的状态，running为true时为本轮已完成的部分
+type CompactResp struct {
+	Running        bool      `json:"running"`
+	DryRun         bool      `json:"dryRun"`
+	Phase          string    `json:"phase"`
+	StartTime      time.Time `json:"startTime"`
+	FinishTime     time.Time `json:"finishTime"`
+	MergedBlobs    int       `json:"mergedBlobs"`    // 与其他仓库中相同内容的blob合并为硬链接的数量
+	RemovedDirs    int       `json:"removedDirs"`    // 删除的空版本目录数
+	RewrittenFiles int       `json:"rewrittenFiles"` // 按当前压缩配置重写的元数据文件数
+	Reclaimed      int64     `json:"reclaimed"`      // 回收的空间，单位字节，dryRun时为预计值（不含元数据重写）
+	Errors         []string  `json:"errors"`
+}
+
+// CurationResp 模型精选同步的状态，running为true时repos为本轮已处理的模型
+type CurationResp struct {
+	Running    bool            `json:"running"`
+	StartTime  time.Time       `json:"startTime"`
+	FinishTime time.Time       `json:"finishTime"`
+	Repos      []*CurationRepo `json:"repos"`
+	Size       int64           `json:"size"`
+}
+
+type CurationRepo struct {
+	OrgRepo string `json:"orgRepo"`
+	Commit  string `json:"commit"`
+	Files   int    `json:"files"`
+	Size    int64  `json:"size"`
+	Skipped bool   `json:"skipped"`
+	Error   string `json:"error,omitempty"`
+}
+
+// OnlineStatusResp configured为配置的在线状态，online为综合手动设置、远端探测及维护模式后的实际状态
+type OnlineStatusResp struct {
+	Configured   bool                       `json:"configured"`
+	Mode         string                     `json:"mode"`
+	UpstreamDown bool                       `json:"upstreamDown"`
+	Maintenance  bool                       `json:"maintenance"`
+	Online       bool                       `json:"online"`
+	Probe        *util.UpstreamProbeStatus  `json:"probe,omitempty"`
+	Budget       *util.UpstreamBudgetStatus `json:"budget,omitempty"`
+}
+
+// SyncReq 从其他镜像实例同步仓库的某个版本，source为对端镜像的地址
+type SyncReq struct {
+	Source   string `json:"source"`
+	RepoType string `json:"repoType"`
+	Org      string `json:"org"`
+	Repo     string `json:"repo"`
+	Revision string `json:"revision"`
+}
+
+// ShareReq filePath为空时分享整个快照的归档，expiration为有效期，单位秒，为0时使用默认值
+type ShareReq struct {
+	RepoType   string `json:"repoType"`
+	Org        string `json:"org"`
+	Repo       string `json:"repo"`
+	Revision   string `json:"revision"`
+	FilePath   string `json:"filePath"`
+	Expiration int64  `json:"expiration"`
+}
+
+type ShareResp struct {
+	Url      string    `json:"url"`
+	Commit   string    `json:"commit"`
+	ExpireAt time.Time `json:"expireAt"`
+}
+
+// SyncManifest 对端镜像返回的版本清单，meta为版本元数据的原始内容
+type SyncManifest struct {
+	Commit      string            `json:"commit"`
+	MetaHeaders map[string]string `json:"metaHeaders"`
+	Meta        []byte            `json:"meta"`
+	Files       []*SyncFile       `json:"files"`
+}
+
+// SyncFile complete表示对端已完整缓存该文件
+type SyncFile struct {
+	PathsInfo *common.PathsInfo `json:"pathsInfo"`
+	Etag      string            `json:"etag"`
+	Complete  bool              `json:"complete"`
+}
+
+// SyncResp skipped为本地已有、无需传输的文件，unavailable为对端未缓存、未能同步的文件
+type SyncResp struct {
+	Commit           string   `json:"commit"`
+	Files            int      `json:"files"`
+	Skipped          int      `json:"skipped"`
+	Transferred      int      `json:"transferred"`
+	TransferredBytes int64    `json:"transferredBytes"`
+	Unavailable      []string `json:"unavailable"`
+}
+
+// LogLevelReq module为空时修改全局日志级别，level为空时删除该模块的单独设置
+type LogLevelReq struct {
+	Module string `json:"module"`
+	Level  string `json:"level"`
+}
+
+// MaintenanceReq message为维护原因，会附加在返回给客户端的提示中
+type MaintenanceReq struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+// CaptureReq 为仓库开启抓包，duration为有效期，单位秒，maxBodySize为每个响应体保存的字节数上限，0表示只记录sha256
+type CaptureReq struct {
+	RepoType    string `json:"repoType"`
+	Org         string `json:"org"`
+	Repo        string `json:"repo"`
+	Duration    int    `json:"duration"`
+	MaxBodySize *int64 `json:"maxBodySize"`
+}
+
+// PolicyApprovalReq 审批gated仓库
+type PolicyApprovalReq struct {
+	RepoType string `json:"repoType"`
+	Org      string `json:"org"`
+	Repo     string `json:"repo"`
+	Comment  string `json:"comment"`
+}
+
+// OnlineModeReq 手动设置在线状态，mode为auto、online或offline
+type OnlineModeReq struct {
+	Mode string `json:"mode"`
+}
+
+// RepoFilesReq 仓库文件列表的查询参数，sort为name、size或mtime，加"-"前缀表示倒序
+type RepoFilesReq struct {
+	Recursive bool   `query:"recursive"`
+	Cursor    string `query:"cursor"`
+	Limit     int    `query:"limit"`
+	Glob      string `query:"glob"`
+	Sort      string `query:"sort"`
+	MinSize   int64  `query:"minSize"`
+	MaxSize   int64  `query:"maxSize"`
+}
+
+// BundleReq 创建发布包，repos为{repoType}/{org}/{repo}@{revision}格式，省略revision时为main，创建时解析为commit
+type BundleReq struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Repos       []string `json:"repos"`
+	Prefetch    bool     `json:"prefetch"` // 创建后在后台预取包内的全部版本
+}
+
+// BundleStatus 发布包及包内各版本的缓存情况，cached为已完整缓存的版本数
+type BundleStatus struct {
+	*util.ReleaseBundle
+	Prefetching bool                `json:"prefetching"`
+	Cached      int                 `json:"cached"`
+	Size        int64               `json:"size"`
+	Items       []*BundleRepoStatus `json:"items"`
+}
+
+type BundleRepoStatus struct {
+	*util.BundleRepo
+	Cached bool   `json:"cached"`
+	Files  int    `json:"files"`
+	Size   int64  `json:"size"`
+	Err    string `json:"err,omitempty"` // 未完整缓存的原因或最近一次预取的错误
+}
+
+// RepoAliasReq 手动添加仓库别名，from、to为{org}/{repo}，from的请求按to处理
+type RepoAliasReq struct {
+	RepoType string `json:"repoType"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
@@ -16,3 +16,11 @@ func (s *SystemInfo) SetMemoryUsed(collectTime int64, usedPercent float64) {
 	s.CollectTime = collectTime
 	s.MemoryUsedPercent = usedPercent
 }
+
+// Readiness 就绪检查结果，WarmRatio为窗口内文件请求完全由缓存响应的比例
+type Readiness struct {
+	Ready        bool    `json:"ready"`
+	WarmRatio    float64 `json:"warmRatio"`
+	Requests     int     `json:"requests"`
+	MinWarmRatio float64 `json:"minWarmRatio"`
+}
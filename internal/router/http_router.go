@@ -17,6 +17,8 @@ package router
 import (
 	"dingospeed/internal/handler"
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/middleware"
 
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -29,10 +31,13 @@ type HttpRouter struct {
 	sysHandler        *handler.SysHandler
 	cacheJobHandler   *handler.CacheJobHandler
 	modelscopeHandler *handler.ModelscopeHandler
+	adminHandler      *handler.AdminHandler
+	uploadHandler     *handler.UploadHandler
 }
 
 func NewHttpRouter(echo *echo.Echo, fileHandler *handler.FileHandler, metaHandler *handler.MetaHandler,
-	sysHandler *handler.SysHandler, cacheJobHandler *handler.CacheJobHandler, modelscopeHandler *handler.ModelscopeHandler) *HttpRouter {
+	sysHandler *handler.SysHandler, cacheJobHandler *handler.CacheJobHandler, modelscopeHandler *handler.ModelscopeHandler,
+	adminHandler *handler.AdminHandler, uploadHandler *handler.UploadHandler) *HttpRouter {
 	r := &HttpRouter{
 		echo:              echo,
 		fileHandler:       fileHandler,
@@ -40,6 +45,8 @@ func NewHttpRouter(echo *echo.Echo, fileHandler *handler.FileHandler, metaHandle
 		sysHandler:        sysHandler,
 		cacheJobHandler:   cacheJobHandler,
 		modelscopeHandler: modelscopeHandler,
+		adminHandler:      adminHandler,
+		uploadHandler:     uploadHandler,
 	}
 	r.initRouter()
 	return r
@@ -48,12 +55,16 @@ func NewHttpRouter(echo *echo.Echo, fileHandler *handler.FileHandler, metaHandle
 func (r *HttpRouter) initRouter() {
 	// 系统信息
 	r.echo.GET("/info", r.sysHandler.Info)
+	r.echo.GET("/readyz", r.sysHandler.Readyz)
 	if config.SysConfig.EnableMetric() {
 		r.echo.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 	}
 	// 内部使用
 	r.routerForScheduler()
 	r.routerForCacheJob()
+	r.routerForAdmin()
+	r.routerForUpload()
+	r.routerForDatasetsServer()
 
 	r.routerForSpeed()
 	r.routerForModelscope()
@@ -71,11 +82,44 @@ func (r *HttpRouter) routerForSpeed() { // alayanew
 	// 模型&数据集元数据
 	r.echo.HEAD("/api/:repoType/:org/:repo/revision/:revision", r.metaHandler.GetMetadataHandler)
 	r.echo.GET("/api/:repoType/:org/:repo/revision/:revision", r.metaHandler.GetMetadataHandler)
+	r.echo.HEAD("/api/:repoType/:org/:repo", r.metaHandler.GetMetadataHandler)
+	r.echo.GET("/api/:repoType/:org/:repo", r.metaHandler.GetMetadataHandler)
 
-	// refs
-	// r.echo.GET("/api/:repoType/:org/:repo/refs", r.metaHandler.RepoRefsHandler)  修复转发响应码，走统一转发。
+	// 文件头解析
+	r.echo.GET("/api/:repoType/:org/:repo/gguf/:revision/:filePath", r.fileHandler.GgufHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/safetensors/:revision/:filePath", r.fileHandler.SafetensorsHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/preview/:revision/:filePath", r.fileHandler.PreviewHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/size/:revision", r.metaHandler.RepoSizeHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/metalink/:revision", r.metaHandler.MetalinkHandler)
+	r.echo.HEAD("/api/:repoType/:org/:repo/archive/:archive", r.metaHandler.ArchiveHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/manifest/:revision", r.metaHandler.SyncManifestHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/attestation/:revision", r.metaHandler.AttestationHandler)
+	r.echo.GET("/api/attestation/public-key", r.metaHandler.AttestationKeyHandler)
+	r.echo.GET("/api/:repoType/:org/:repo/archive/:archive", r.metaHandler.ArchiveHandler)
+	r.echo.HEAD("/:repoType/:org/:repo/archive/:revision/*", r.metaHandler.ArchiveDirHandler)
+	r.echo.GET("/:repoType/:org/:repo/archive/:revision/*", r.metaHandler.ArchiveDirHandler)
+	r.echo.HEAD("/:orgOrRepoType/:repo/archive/:revision/*", r.metaHandler.ArchiveDirHandler)
+	r.echo.GET("/:orgOrRepoType/:repo/archive/:revision/*", r.metaHandler.ArchiveDirHandler)
+	r.echo.HEAD("/cdn/:repoType/:org/:repo/:commit/:filePath", r.fileHandler.CdnFileHandler)
+	r.echo.GET("/cdn/:repoType/:org/:repo/:commit/:filePath", r.fileHandler.CdnFileHandler)
+	r.echo.HEAD("/proxy", r.fileHandler.ProxyHandler)
+	r.echo.GET("/proxy", r.fileHandler.ProxyHandler)
+	r.echo.HEAD("/share/:repoType/:org/:repo/:commit/:filePath", r.metaHandler.SharedFileHandler)
+	r.echo.GET("/share/:repoType/:org/:repo/:commit/:filePath", r.metaHandler.SharedFileHandler)
+	r.echo.HEAD("/share/:repoType/:org/:repo/:archive", r.metaHandler.SharedArchiveHandler)
+	r.echo.GET("/share/:repoType/:org/:repo/:archive", r.metaHandler.SharedArchiveHandler)
+	r.echo.HEAD("/share/:repoType/:repo/resolve/:commit/:filePath", r.metaHandler.SharedFileHandler)
+	r.echo.GET("/share/:repoType/:repo/resolve/:commit/:filePath", r.metaHandler.SharedFileHandler)
+	r.echo.HEAD("/share/:repoType/:repo/:archive", r.metaHandler.SharedArchiveHandler)
+	r.echo.GET("/share/:repoType/:repo/:archive", r.metaHandler.SharedArchiveHandler)
+
+	// refs，远端的错误响应码原样返回
+	r.echo.GET("/api/:repoType/:org/:repo/refs", r.metaHandler.RepoRefsHandler)
 	r.echo.GET("/api/whoami-v2", r.metaHandler.WhoamiV2Handler)
 	r.echo.GET("/repos", r.metaHandler.ReposHandler)
+	// 模型卡片
+	r.echo.GET("/:org/:repo", r.metaHandler.ModelCardHandler1)
+	r.echo.GET("/:repoType/:org/:repo", r.metaHandler.ModelCardHandler2)
 	r.echo.Any("/*", r.metaHandler.ForwardToNewSiteHandler)
 }
 
@@ -95,6 +139,87 @@ func (r *HttpRouter) routerForCacheJob() { // alayanew
 	r.echo.POST("/api/cacheJob/realtime", r.cacheJobHandler.RealtimeCacheJobHandler)
 }
 
+func (r *HttpRouter) routerForAdmin() {
+	r.echo.Use(middleware.AdminAuthMiddleware)
+	r.echo.GET("/admin/downloads", r.adminHandler.ListDownloadsHandler)
+	r.echo.POST("/admin/downloads/:id/cancel", r.adminHandler.CancelDownloadHandler)
+	r.echo.GET("/admin/jobs/:id", r.cacheJobHandler.JobProgressHandler)
+	r.echo.GET("/admin/jobs/repos/:repoType/:org/:repo", r.cacheJobHandler.RepoProgressHandler)
+	r.echo.GET("/admin/repos/:repoType/:org/:repo/revisions", r.metaHandler.LocalRevisionsHandler)
+	r.echo.DELETE("/admin/repos/:repoType/:org/:repo", r.adminHandler.PurgeRepoHandler)
+	r.echo.POST("/admin/revalidate/:repoType/:org/:repo", r.metaHandler.RevalidateHandler)
+	r.echo.GET("/admin/repos/:repoType/:org/:repo/diff/:revision", r.metaHandler.RepoDiffHandler)
+	r.echo.GET("/admin/repos/:repoType/:org/:repo/provenance/:revision/*", r.metaHandler.ProvenanceHandler)
+	r.echo.GET("/admin/repos/:repoType/:org/:repo/pickle-scan/:revision", r.metaHandler.PickleScanHandler)
+	r.echo.POST("/admin/share", r.metaHandler.CreateShareHandler)
+	r.echo.GET("/admin/trash", r.adminHandler.ListTrashHandler)
+	r.echo.POST("/admin/trash/:id/restore", r.adminHandler.RestoreTrashHandler)
+	r.echo.DELETE("/admin/trash/:id", r.adminHandler.DeleteTrashHandler)
+	r.echo.POST("/admin/gc/revisions", r.adminHandler.RevisionGcHandler)
+	r.echo.POST("/admin/batch", r.adminHandler.BatchHandler)
+	r.echo.GET("/admin/batch/:id", r.adminHandler.BatchJobHandler)
+	r.echo.GET("/admin/idle", r.adminHandler.IdleReportHandler)
+	r.echo.POST("/admin/idle/purge", r.adminHandler.PurgeIdleHandler)
+	r.echo.GET("/admin/bundles", r.adminHandler.ListBundlesHandler)
+	r.echo.POST("/admin/bundles", r.adminHandler.CreateBundleHandler)
+	r.echo.GET("/admin/bundles/:name", r.adminHandler.BundleHandler)
+	r.echo.DELETE("/admin/bundles/:name", r.adminHandler.DeleteBundleHandler)
+	r.echo.POST("/admin/bundles/:name/prefetch", r.adminHandler.PrefetchBundleHandler)
+	r.echo.HEAD("/admin/bundles/:name/archive", r.adminHandler.BundleArchiveHandler)
+	r.echo.GET("/admin/bundles/:name/archive", r.adminHandler.BundleArchiveHandler)
+	r.echo.GET("/admin/compact", r.adminHandler.CompactStatusHandler)
+	r.echo.POST("/admin/compact", r.adminHandler.CompactHandler)
+	r.echo.GET("/admin/curation", r.adminHandler.CurationStatusHandler)
+	r.echo.POST("/admin/curation/run", r.adminHandler.RunCurationHandler)
+	r.echo.GET("/admin/stats/repos", r.adminHandler.ListRepoStatsHandler)
+	r.echo.GET("/admin/stats/repos/:repoType/:org/:repo", r.adminHandler.GetRepoStatsHandler)
+	r.echo.GET("/admin/stats/top", r.adminHandler.TopStatsHandler)
+	r.echo.GET("/admin/stats/upstream-budget", r.adminHandler.UpstreamBudgetHandler)
+	r.echo.GET("/admin/events", r.adminHandler.EventsHandler)
+	r.echo.POST("/admin/oci/export", r.adminHandler.OciExportHandler)
+	r.echo.GET("/admin/oci/export/:id", r.adminHandler.OciExportJobHandler)
+	r.echo.GET("/admin/loglevel", r.adminHandler.GetLogLevelHandler)
+	r.echo.PUT("/admin/loglevel", r.adminHandler.SetLogLevelHandler)
+	r.echo.GET("/admin/capture", r.adminHandler.ListCapturesHandler)
+	r.echo.POST("/admin/capture", r.adminHandler.StartCaptureHandler)
+	r.echo.DELETE("/admin/capture/:id", r.adminHandler.StopCaptureHandler)
+	r.echo.GET("/admin/policy/approvals", r.adminHandler.ListApprovalsHandler)
+	r.echo.POST("/admin/policy/approvals", r.adminHandler.ApproveRepoHandler)
+	r.echo.DELETE("/admin/policy/approvals/:repoType/:org/:repo", r.adminHandler.RevokeRepoHandler)
+	r.echo.GET("/admin/aliases", r.adminHandler.ListRepoAliasesHandler)
+	r.echo.POST("/admin/aliases", r.adminHandler.AddRepoAliasHandler)
+	r.echo.DELETE("/admin/aliases/:repoType/:org/:repo", r.adminHandler.RemoveRepoAliasHandler)
+	r.echo.DELETE("/admin/aliases/:repoType/:repo", r.adminHandler.RemoveRepoAliasHandler)
+	r.echo.GET("/admin/maintenance", r.adminHandler.GetMaintenanceHandler)
+	r.echo.PUT("/admin/maintenance", r.adminHandler.SetMaintenanceHandler)
+	r.echo.POST("/admin/sync", r.adminHandler.SyncHandler)
+	r.echo.GET("/admin/online", r.adminHandler.GetOnlineHandler)
+	r.echo.PUT("/admin/online", r.adminHandler.SetOnlineHandler)
+}
+
+func (r *HttpRouter) routerForUpload() {
+	r.echo.POST("/api/:repoType/:org/:repo/commit/:revision", r.uploadHandler.CommitHandler)
+	r.echo.POST("/api/:repoType/:org/:repo/preupload/:revision", r.uploadHandler.CommitHandler)
+	r.echo.POST("/:org/:repo/info/lfs/objects/batch", r.uploadHandler.LfsBatchHandler)
+	r.echo.POST("/:repoType/:org/:repo/info/lfs/objects/batch", r.uploadHandler.LfsBatchHandler2)
+	r.echo.Any("/lfs-proxy/:id", r.uploadHandler.LfsProxyHandler)
+
+	// 仓库管理
+	r.echo.POST("/api/repos/create", r.uploadHandler.RepoManageHandler)
+	r.echo.DELETE("/api/repos/delete", r.uploadHandler.RepoManageHandler)
+	r.echo.POST("/api/repos/move", r.uploadHandler.RepoManageHandler)
+	r.echo.PUT("/api/:repoType/:org/:repo/settings", r.uploadHandler.RepoSettingsHandler)
+	r.echo.POST("/api/:repoType/:org/:repo/branch/:branch", r.uploadHandler.RepoSettingsHandler)
+	r.echo.DELETE("/api/:repoType/:org/:repo/branch/:branch", r.uploadHandler.RepoSettingsHandler)
+}
+
+// datasets-server的数据集预览接口，客户端将datasets-server地址指向镜像即可使用
+func (r *HttpRouter) routerForDatasetsServer() {
+	for _, endpoint := range consts.DatasetsServerEndpoints {
+		r.echo.GET("/"+endpoint, r.metaHandler.DatasetsServerHandler)
+	}
+}
+
 func (r *HttpRouter) routerForModelscope() { // modelscope
 	r.echo.GET("/api/v1/:repoType/:org/:repo", r.modelscopeHandler.ModelInfoHandler)
 	r.echo.GET("/api/v1/:repoType/:org/:repo/revisions", r.modelscopeHandler.RevisionsHandler)
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/internal/handler"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/middleware"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestWildcardRouteParams(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+
+	e := echo.New()
+	e.Use(middleware.ParamValidateMiddleware)
+	// 参数校验通过后直接返回，不进入实际的handler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.String(http.StatusOK, c.Path())
+		}
+	})
+	NewHttpRouter(e, &handler.FileHandler{}, &handler.MetaHandler{}, &handler.SysHandler{}, &handler.CacheJobHandler{},
+		&handler.ModelscopeHandler{}, &handler.AdminHandler{}, &handler.UploadHandler{})
+
+	cases := []struct {
+		path  string
+		route string
+		code  int
+	}{
+		{"/datasets/org/repo/archive/main/data/train.tar.gz", "/:repoType/:org/:repo/archive/:revision/*", http.StatusOK},
+		{"/admin/repos/models/org/repo/provenance/main/sub/dir/model.safetensors", "/admin/repos/:repoType/:org/:repo/provenance/:revision/*", http.StatusOK},
+		{"/some/unknown/nested/path/to/forward", "/*", http.StatusOK},
+		{"/datasets/org/repo/archive/main/data/../../../etc.tar", "", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("GET %s = %d, want %d: %s", tc.path, rec.Code, tc.code, rec.Body.String())
+			continue
+		}
+		if tc.route != "" && rec.Body.String() != tc.route {
+			t.Errorf("GET %s matched %s, want %s", tc.path, rec.Body.String(), tc.route)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package server
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"dingospeed/pkg/middleware"
+
+	"go.uber.org/zap"
+)
+
+const dumpDir = "log/dump" // 转储文件目录，与日志目录同级
+
+// NewDebugHandler 调试端口的路由，包括pprof、expvar及goroutine/heap转储，配置adminToken时需携带Bearer令牌，未配置时只允许本机访问。
+func NewDebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/dump", dumpHandler)
+	return adminAuth(mux)
+}
+
+func adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !middleware.AdminAuthorized(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dumpHandler 将goroutine或heap快照写入log/dump目录，供事后分析，POST /debug/dump?type=goroutine|heap
+func dumpHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dumpType := r.URL.Query().Get("type")
+	debug := 0
+	switch dumpType {
+	case "goroutine":
+		debug = 2 // 输出完整调用栈文本
+	case "heap":
+		runtime.GC()
+	default:
+		http.Error(w, "type must be goroutine or heap", http.StatusBadRequest)
+		return
+	}
+	if err := os.MkdirAll(dumpDir, 0755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	path := filepath.Join(dumpDir, fmt.Sprintf("%s-%s.pprof", dumpType, time.Now().Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	if err = rpprof.Lookup(dumpType).WriteTo(f, debug); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	zap.S().Infof("%s dump is written to %s", dumpType, path)
+	_, _ = fmt.Fprintln(w, path)
+}
@@ -9,23 +9,28 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
 
+	"dingospeed/internal/downloader"
 	"dingospeed/internal/router"
 	"dingospeed/pkg/config"
 	"dingospeed/pkg/middleware"
+	"dingospeed/pkg/util"
 
 	"github.com/labstack/echo/v4"
 )
 
 type HTTPServer struct {
 	*http.Server
-	lis     net.Listener
-	err     error
-	network string
-	address string
-	http    *router.HttpRouter
+	downloads *http.Server // 独立的下载监听，未开启时为nil
+	lis       net.Listener
+	err       error
+	network   string
+	address   string
+	http      *router.HttpRouter
+	ready     chan struct{}
 }
 
 //go:embed "templates/*.html"
@@ -44,13 +49,28 @@ func NewServer(config *config.Config, echo *echo.Echo, httpr *router.HttpRouter)
 		network: "tcp",
 		address: fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
 		http:    httpr,
+		ready:   make(chan struct{}),
 	}
+	limits := config.Server.Limits
 	s.Server = &http.Server{
-		Handler:        echo,
+		Handler:           echo,
+		ReadHeaderTimeout: time.Duration(limits.ReadHeaderTimeout) * time.Second,
+		// 读写超时由LimitMiddleware按路由设置，此处不限制，避免截断长时间的下载
 		ReadTimeout:    0,
-		WriteTimeout:   0, // 对用户侧的响应设置永不超时
+		WriteTimeout:   0,
+		IdleTimeout:    time.Duration(limits.IdleTimeout) * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
+	if config.EnableDownloadsListener() {
+		d := config.Server.Downloads
+		s.downloads = &http.Server{
+			Addr:              fmt.Sprintf("%s:%d", d.Host, d.Port),
+			Handler:           echo,
+			ReadHeaderTimeout: time.Duration(d.ReadHeaderTimeout) * time.Second,
+			IdleTimeout:       time.Duration(d.IdleTimeout) * time.Second,
+			MaxHeaderBytes:    1 << 20,
+		}
+	}
 	return s
 }
 
@@ -61,26 +81,81 @@ func (s *HTTPServer) Start(ctx context.Context) error {
 		return err
 	}
 	s.lis = lis
+	var downloadsLis net.Listener
+	if s.downloads != nil {
+		if downloadsLis, err = net.Listen(s.network, s.downloads.Addr); err != nil {
+			s.err = err
+			_ = lis.Close()
+			return err
+		}
+	}
+	close(s.ready)
 	s.BaseContext = func(net.Listener) context.Context {
 		return ctx
 	}
+	errCh := make(chan error, 1)
+	if downloadsLis != nil {
+		s.downloads.BaseContext = s.BaseContext
+		zap.S().Infof("[HTTP] downloads listening on: %s", downloadsLis.Addr().String())
+		go func() {
+			if err := s.downloads.Serve(downloadsLis); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+				_ = s.Close()
+			}
+		}()
+	}
 	zap.S().Infof("[HTTP] server listening on: %s", s.lis.Addr().String())
 	if err := s.Serve(s.lis); !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
-	return nil
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+// Ready 监听端口建立后关闭
+func (s *HTTPServer) Ready() <-chan struct{} {
+	return s.ready
 }
 
 func (s *HTTPServer) Stop(ctx context.Context) error {
 	zap.S().Infof("[HTTP] server shutdown.")
-	return s.Shutdown(ctx)
+	if s.downloads != nil {
+		if err := s.downloads.Shutdown(ctx); err != nil {
+			zap.S().Warnf("downloads listener shutdown err:%v", err)
+		}
+	}
+	err := s.Shutdown(ctx)
+	// 等进行中的传输结束后保存仓库统计，避免丢失最近一次定时保存之后的计数
+	downloader.FlushRepoStats()
+	return err
 }
 
 func NewEngine() *echo.Echo {
 	r := echo.New()
+	// Scan时已校验过可信代理配置
+	trustedProxies, _ := config.SysConfig.GetTrustedProxies()
+	r.IPExtractor = util.ClientIPExtractor(trustedProxies)
 	middleware.InitMiddlewareConfig()
-	r.Use(middleware.QueueLimitMiddleware)
+	r.Pre(middleware.BasePathMiddleware)
+	r.Pre(middleware.RepoAliasMiddleware)
+	r.Use(middleware.AccessLogMiddleware)
+	r.Use(middleware.RecoverMiddleware)
+	r.Use(middleware.NetworkAclMiddleware())
+	r.Use(middleware.DownloadsListenerMiddleware)
+	r.Use(middleware.ChaosMiddleware)
+	r.Use(middleware.ShadowMiddleware)
 	r.Use(middleware.CORSMiddleware())
+	r.Use(middleware.CacheStatusMiddleware)
+	r.Use(middleware.ResponseHeaderMiddleware)
+	r.Use(middleware.ApiSurfaceMiddleware)
+	r.Use(middleware.LimitMiddleware)
+	r.Use(middleware.QueueLimitMiddleware)
+	r.Use(middleware.ParamValidateMiddleware)
+	r.Use(middleware.MaintenanceMiddleware)
 
 	t := &Template{
 		templates: template.Must(template.ParseFS(templatesFS, "templates/*.html")),
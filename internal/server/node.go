@@ -0,0 +1,93 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+
+	"dingospeed/internal/service"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/proto/node"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// NodeServer 节点间gRPC服务，仅在cluster模式且配置了nodePort时启动。
+type NodeServer struct {
+	server      *grpc.Server
+	nodeService *service.NodeService
+}
+
+func NewNodeServer(nodeService *service.NodeService) *NodeServer {
+	return &NodeServer{
+		nodeService: nodeService,
+	}
+}
+
+func (s *NodeServer) Start(ctx context.Context) error {
+	if !config.SysConfig.IsCluster() || config.SysConfig.Server.NodePort == 0 {
+		return nil
+	}
+	// 加载配置时已校验ssl，节点间只以双向TLS提供服务
+	ssl := config.SysConfig.Server.Ssl
+	creds, err := serverCredential(ssl.CrtFile, ssl.KeyFile, ssl.CaFile)
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.SysConfig.Server.Host, config.SysConfig.Server.NodePort))
+	if err != nil {
+		return err
+	}
+	s.server = grpc.NewServer(grpc.Creds(creds))
+	node.RegisterNodeServer(s.server, s.nodeService)
+	zap.S().Infof("[GRPC] node server listening on: %s", lis.Addr().String())
+	return s.server.Serve(lis)
+}
+
+// serverCredential 双向TLS，要求对端节点出示由同一CA签发的证书
+func serverCredential(crtFile, keyFile, caFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(crtFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	certPool := x509.NewCertPool()
+	if ok := certPool.AppendCertsFromPEM(ca); !ok {
+		return nil, fmt.Errorf("failed to append ca certs")
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certPool,
+	}), nil
+}
+
+func (s *NodeServer) Stop(ctx context.Context) error {
+	if s.server != nil {
+		zap.S().Infof("[GRPC] node server shutdown.")
+		s.server.GracefulStop()
+	}
+	return nil
+}
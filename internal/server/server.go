@@ -2,4 +2,4 @@ package server
 
 import "github.com/google/wire"
 
-var ServerProvider = wire.NewSet(NewServer, NewEngine, NewSchedulerServer)
+var ServerProvider = wire.NewSet(NewServer, NewEngine, NewSchedulerServer, NewNodeServer)
@@ -0,0 +1,244 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	log "dingospeed/pkg/logger"
+	"dingospeed/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+var statsOnce sync.Once
+
+type AdminService struct {
+}
+
+func NewAdminService() *AdminService {
+	adminSvc := &AdminService{}
+	statsOnce.Do(func() {
+		downloader.LoadRepoStats()
+		downloader.StartWarmup()
+		go adminSvc.cycleFlushRepoStats()
+	})
+	return adminSvc
+}
+
+func (a *AdminService) cycleFlushRepoStats() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		downloader.FlushRepoStats()
+	}
+}
+
+func (a *AdminService) ListDownloads() []*downloader.TransferInfo {
+	return downloader.ListTransfers()
+}
+
+func (a *AdminService) CancelDownload(id int64) error {
+	if !downloader.CancelTransfer(id) {
+		return myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("download %d is not exist", id))
+	}
+	zap.S().Infof("download %d is cancelled by admin", id)
+	return nil
+}
+
+func (a *AdminService) ListRepoStats() []*downloader.RepoStatsInfo {
+	return downloader.ListRepoStats()
+}
+
+func (a *AdminService) GetRepoStats(repoType, orgRepo string) (*downloader.RepoStatsInfo, error) {
+	stats, ok := downloader.GetRepoStats(repoType, orgRepo)
+	if !ok {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("stats of %s/%s is not exist", repoType, orgRepo))
+	}
+	return stats, nil
+}
+
+// TopStats 按resolve次数返回最热门的文件或仓库，by为file或repo。
+func (a *AdminService) TopStats(by string, n int) ([]*downloader.PopularItem, error) {
+	switch by {
+	case "", "file":
+		return downloader.TopFiles(n), nil
+	case "repo":
+		return downloader.TopRepos(n), nil
+	default:
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid by: %s", by))
+	}
+}
+
+func (a *AdminService) GetMaintenance() *config.Maintenance {
+	return config.SysConfig.GetMaintenance()
+}
+
+func (a *AdminService) SetMaintenance(req *query.MaintenanceReq) *config.Maintenance {
+	before := config.SysConfig.GetMaintenance()
+	m := config.SysConfig.SetMaintenance(req.Enabled, req.Message)
+	if before.Enabled != m.Enabled {
+		if m.Enabled {
+			zap.S().Warnf("maintenance mode is enabled by admin, message: %s", m.Message)
+		} else {
+			zap.S().Warnf("maintenance mode is disabled by admin, lasted %s", time.Since(before.Since).Round(time.Second))
+		}
+	}
+	return m
+}
+
+func (a *AdminService) UpstreamBudget() *util.UpstreamBudgetStatus {
+	return util.GetUpstreamBudgetStatus()
+}
+
+func (a *AdminService) GetOnlineStatus() *query.OnlineStatusResp {
+	resp := &query.OnlineStatusResp{
+		Configured:   config.SysConfig.Server.Online,
+		Mode:         config.SysConfig.GetOnlineMode(),
+		UpstreamDown: config.SysConfig.UpstreamDown(),
+		Maintenance:  config.SysConfig.InMaintenance(),
+		Online:       config.SysConfig.Online(),
+	}
+	if config.SysConfig.UpstreamProbe.Enabled {
+		resp.Probe = util.GetUpstreamProbeStatus()
+	}
+	if config.SysConfig.UpstreamBudget.Enabled {
+		resp.Budget = util.GetUpstreamBudgetStatus()
+	}
+	return resp
+}
+
+// SetOnlineMode 手动固定在线或离线状态，设置为auto时恢复按配置及远端探测结果自动切换。
+func (a *AdminService) SetOnlineMode(req *query.OnlineModeReq) (*query.OnlineStatusResp, error) {
+	switch req.Mode {
+	case consts.OnlineModeAuto, consts.OnlineModeOnline, consts.OnlineModeOffline:
+	default:
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid mode: %s", req.Mode))
+	}
+	if before := config.SysConfig.GetOnlineMode(); before != req.Mode {
+		zap.S().Warnf("online mode is changed by admin from %s to %s", before, req.Mode)
+	}
+	config.SysConfig.SetOnlineMode(req.Mode)
+	return a.GetOnlineStatus(), nil
+}
+
+// StartCapture 抓包有效期默认10分钟，最长24小时，每个响应体默认保存前10MB。
+func (a *AdminService) StartCapture(req *query.CaptureReq) (*util.CaptureSession, error) {
+	if _, ok := consts.RepoTypesMapping[req.RepoType]; !ok || req.Org == "" || req.Repo == "" {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "repoType, org and repo are required")
+	}
+	duration := time.Duration(req.Duration) * time.Second
+	if req.Duration <= 0 {
+		duration = 10 * time.Minute
+	}
+	if duration > 24*time.Hour {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "duration must not exceed 86400 seconds")
+	}
+	maxBodySize := int64(10 << 20)
+	if req.MaxBodySize != nil {
+		maxBodySize = max(*req.MaxBodySize, 0)
+	}
+	return util.StartCapture(req.RepoType, fmt.Sprintf("%s/%s", req.Org, req.Repo), duration, maxBodySize)
+}
+
+func (a *AdminService) StopCapture(id string) (*util.CaptureSession, error) {
+	s, ok := util.StopCapture(id)
+	if !ok {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("capture %s not found", id))
+	}
+	return s, nil
+}
+
+func (a *AdminService) ApproveRepo(req *query.PolicyApprovalReq) (*util.RepoApproval, error) {
+	if _, ok := consts.RepoTypesMapping[req.RepoType]; !ok || req.Org == "" || req.Repo == "" {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "repoType, org and repo are required")
+	}
+	orgRepo := util.GetOrgRepo(req.Org, req.Repo)
+	approval, err := util.ApproveRepo(req.RepoType, orgRepo, req.Comment)
+	if err != nil {
+		return nil, err
+	}
+	zap.S().Infof("gated repo %s/%s is approved by admin", req.RepoType, orgRepo)
+	return approval, nil
+}
+
+func (a *AdminService) RevokeRepo(repoType, orgRepo string) error {
+	ok, err := util.RevokeRepo(repoType, orgRepo)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s/%s is not approved", repoType, orgRepo))
+	}
+	zap.S().Infof("approval of gated repo %s/%s is revoked by admin", repoType, orgRepo)
+	return nil
+}
+
+// AddRepoAlias 手动添加仓库别名，通常用于远端改名时未返回重定向的情况。
+func (a *AdminService) AddRepoAlias(req *query.RepoAliasReq) (*util.RepoAlias, error) {
+	if _, ok := consts.RepoTypesMapping[req.RepoType]; !ok {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid repoType: %s", req.RepoType))
+	}
+	for _, orgRepo := range []string{req.From, req.To} {
+		segments := strings.Split(orgRepo, "/")
+		if len(segments) > 2 {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid repo: %s", orgRepo))
+		}
+		for _, segment := range segments {
+			if err := util.ValidatePathParam("repo", segment); err != nil || segment == "" {
+				return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid repo: %s", orgRepo))
+			}
+		}
+	}
+	alias, err := util.AddRepoAlias(req.RepoType, req.From, req.To, 0)
+	if err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	zap.S().Infof("repo alias %s/%s -> %s is added by admin", req.RepoType, req.From, req.To)
+	return alias, nil
+}
+
+func (a *AdminService) RemoveRepoAlias(repoType, from string) error {
+	ok, err := util.RemoveRepoAlias(repoType, from)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s/%s has no alias", repoType, from))
+	}
+	zap.S().Infof("repo alias %s/%s is removed by admin", repoType, from)
+	return nil
+}
+
+func (a *AdminService) GetLogLevels() map[string]string {
+	return log.Levels()
+}
+
+func (a *AdminService) SetLogLevel(req *query.LogLevelReq) (map[string]string, error) {
+	if err := log.SetLevel(req.Module, req.Level); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	zap.S().Infof("log level of %s is set to %s by admin", req.Module, req.Level)
+	return log.Levels(), nil
+}
@@ -0,0 +1,360 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+// BatchService 按glob匹配仓库，批量删除、固定或预取，作为后台任务逐个仓库执行，进度通过任务id查询。
+type BatchService struct {
+	trashService    *TrashService
+	gcService       *RevisionGcService
+	prefetchService *PrefetchService
+	jobs            *common.SafeMap[int64, *query.BatchJob]
+	jobSeq          atomic.Int64
+	jobMu           sync.Mutex // 保护任务状态的更新与读取
+}
+
+func NewBatchService(trashService *TrashService, gcService *RevisionGcService, prefetchService *PrefetchService) *BatchService {
+	return &BatchService{
+		trashService:    trashService,
+		gcService:       gcService,
+		prefetchService: prefetchService,
+		jobs:            common.NewSafeMap[int64, *query.BatchJob](),
+	}
+}
+
+// Submit 校验参数并匹配仓库后异步执行，返回任务信息。
+func (b *BatchService) Submit(req *query.BatchReq, authorization string) (*query.BatchJob, error) {
+	switch req.Action {
+	case consts.BatchActionPurge, consts.BatchActionPin, consts.BatchActionUnpin, consts.BatchActionPrefetch:
+	default:
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid action: %s", req.Action))
+	}
+	if len(req.Patterns) == 0 {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "patterns is required")
+	}
+	if req.OlderThan < 0 || (req.OlderThan > 0 && req.Action != consts.BatchActionPurge) {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "olderThan is only valid for purge")
+	}
+	if req.Revision == "" {
+		req.Revision = "main"
+	}
+	if err := util.ValidatePathParam("revision", req.Revision); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	repos, err := matchBatchRepos(req.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	job := &query.BatchJob{
+		Action:    req.Action,
+		Patterns:  req.Patterns,
+		DryRun:    req.DryRun,
+		Status:    consts.BatchStatusRunning,
+		Total:     len(repos),
+		Items:     make([]*query.BatchItem, 0, len(repos)),
+		StartTime: time.Now(),
+	}
+	for _, repo := range repos {
+		repoType, orgRepo, _ := strings.Cut(repo, "/")
+		job.Items = append(job.Items, &query.BatchItem{Datatype: repoType, OrgRepo: orgRepo, Status: consts.BatchStatusPending})
+	}
+	job.Id = b.jobSeq.Add(1)
+	b.jobs.Set(job.Id, job)
+	jobCopy := b.copyJob(job)
+	go b.run(job, req, authorization)
+	return jobCopy, nil
+}
+
+func (b *BatchService) Job(id int64) (*query.BatchJob, error) {
+	job, ok := b.jobs.Get(id)
+	if !ok {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("batch job %d is not exist", id))
+	}
+	b.jobMu.Lock()
+	defer b.jobMu.Unlock()
+	return b.copyJob(job), nil
+}
+
+// 调用方需持有jobMu，或任务尚未开始执行
+func (b *BatchService) copyJob(job *query.BatchJob) *query.BatchJob {
+	jobCopy := *job
+	jobCopy.Items = make([]*query.BatchItem, 0, len(job.Items))
+	for _, item := range job.Items {
+		itemCopy := *item
+		jobCopy.Items = append(jobCopy.Items, &itemCopy)
+	}
+	return &jobCopy
+}
+
+func (b *BatchService) updateJob(f func()) {
+	b.jobMu.Lock()
+	defer b.jobMu.Unlock()
+	f()
+}
+
+func (b *BatchService) run(job *query.BatchJob, req *query.BatchReq, authorization string) {
+	zap.S().Infof("batch %s %v start, repos %d, dryRun %t", req.Action, req.Patterns, job.Total, req.DryRun)
+	for _, item := range job.Items {
+		b.updateJob(func() { item.Status = consts.BatchStatusRunning })
+		result := &query.BatchItem{Datatype: item.Datatype, OrgRepo: item.OrgRepo, Status: consts.BatchStatusSuccess}
+		if err := b.runItem(req, result, authorization); err != nil {
+			zap.S().Warnf("batch %s %s/%s err.%v", req.Action, item.Datatype, item.OrgRepo, err)
+			result.Status, result.Err = consts.BatchStatusFailed, err.Error()
+		}
+		b.updateJob(func() {
+			*item = *result
+			job.Done++
+			if result.Status == consts.BatchStatusFailed {
+				job.Failed++
+			}
+		})
+		events.Publish(events.JobProgress, map[string]interface{}{"job": "batch", "id": job.Id, "action": req.Action, "datatype": result.Datatype,
+			"orgRepo": result.OrgRepo, "status": result.Status, "err": result.Err, "done": job.Done, "total": job.Total})
+	}
+	b.updateJob(func() {
+		job.EndTime = time.Now()
+		job.Status = consts.BatchStatusSuccess
+		if job.Failed > 0 {
+			job.Status = consts.BatchStatusFailed
+		}
+	})
+	zap.S().Infof("batch %s %v done, repos %d, failed %d", req.Action, req.Patterns, job.Total, job.Failed)
+}
+
+func (b *BatchService) runItem(req *query.BatchReq, item *query.BatchItem, authorization string) error {
+	repoType, orgRepo := item.Datatype, item.OrgRepo
+	switch req.Action {
+	case consts.BatchActionPurge:
+		if req.OlderThan > 0 {
+			before := time.Now().AddDate(0, 0, -req.OlderThan)
+			result := b.gcService.PurgeRevisions(repoType, orgRepo, before, req.DryRun)
+			if result == nil {
+				item.Status = consts.BatchStatusSkipped
+				return nil
+			}
+			item.Revisions, item.Size = result.Revisions, result.Size
+			return nil
+		}
+		if req.DryRun {
+			size, err := util.GetFolderSize(util.RepoFilesDir(repoType, orgRepo))
+			if err == nil {
+				item.Size = size
+			}
+			return nil
+		}
+		entry, err := b.trashService.PurgeRepo(repoType, orgRepo)
+		if err != nil {
+			return err
+		}
+		item.Size = entry.Size
+	case consts.BatchActionPin, consts.BatchActionUnpin:
+		item.Revisions = []string{req.Revision}
+		if req.DryRun {
+			return nil
+		}
+		if req.Action == consts.BatchActionPin {
+			_, err := util.PinRevision(repoType, orgRepo, req.Revision)
+			return err
+		}
+		if ok, err := util.UnpinRevision(repoType, orgRepo, req.Revision); err != nil {
+			return err
+		} else if !ok {
+			item.Status = consts.BatchStatusSkipped
+		}
+	case consts.BatchActionPrefetch:
+		if req.DryRun {
+			item.Revisions = []string{req.Revision}
+			return nil
+		}
+		if !config.SysConfig.Online() {
+			return myerr.NewAppendCode(http.StatusServiceUnavailable, "prefetch is not available in offline mode")
+		}
+		commit, size, err := b.prefetchService.PrefetchRevision(context.Background(), repoType, orgRepo, req.Revision, authorization)
+		item.Size = size
+		if commit != "" {
+			item.Revisions = []string{commit}
+		}
+		return err
+	}
+	return nil
+}
+
+// matchBatchRepos 返回匹配的仓库，格式为{repoType}/{orgRepo}，不含通配符的模式直接作为仓库，无论是否已缓存。
+func matchBatchRepos(patterns []string) ([]string, error) {
+	var cached []string
+	matched := make(map[string]struct{})
+	for _, pattern := range patterns {
+		repoType, orgRepo, _ := strings.Cut(pattern, "/")
+		if _, ok := consts.RepoTypesMapping[repoType]; !ok || orgRepo == "" {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid pattern: %s, expect {repoType}/{org}/{repo}", pattern))
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid pattern: %s", pattern))
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			segments := strings.Split(orgRepo, "/")
+			if len(segments) > 2 {
+				return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid pattern: %s", pattern))
+			}
+			for _, segment := range segments {
+				if err := util.ValidatePathParam("repo", segment); err != nil || segment == "" {
+					return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid pattern: %s", pattern))
+				}
+			}
+			matched[pattern] = struct{}{}
+			continue
+		}
+		if cached == nil {
+			cached = cachedRepos()
+		}
+		for _, repo := range cached {
+			if ok, _ := path.Match(pattern, repo); ok {
+				matched[repo] = struct{}{}
+			}
+		}
+	}
+	repos := make([]string, 0, len(matched))
+	for repo := range matched {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// IdleReport 统计已缓存仓库的闲置天数及大小，返回闲置超过days天的仓库及按闲置天数分段的分布。
+func (b *BatchService) IdleReport(days int) *query.IdleReport {
+	report := &query.IdleReport{Days: days, Repos: make([]*query.IdleRepo, 0)}
+	bounds := append([]int{0}, consts.IdleHeatmapDays...)
+	for i, minDays := range bounds {
+		bucket := &query.IdleBucket{MinDays: minDays}
+		if i+1 < len(bounds) {
+			bucket.MaxDays = bounds[i+1]
+		}
+		report.Heatmap = append(report.Heatmap, bucket)
+	}
+	for _, idle := range idleRepos() {
+		for i := len(report.Heatmap) - 1; i >= 0; i-- {
+			if bucket := report.Heatmap[i]; idle.IdleDays >= bucket.MinDays {
+				bucket.Repos++
+				bucket.Size += idle.Size
+				break
+			}
+		}
+		if idle.IdleDays >= days {
+			report.Repos = append(report.Repos, idle)
+			report.TotalSize += idle.Size
+		}
+	}
+	sort.Slice(report.Repos, func(i, j int) bool {
+		return report.Repos[i].LastAccess < report.Repos[j].LastAccess
+	})
+	return report
+}
+
+// PurgeIdle 将闲置超过days天的仓库作为批量删除任务移入回收站，闲置状态在提交时重新判断。
+func (b *BatchService) PurgeIdle(req *query.IdlePurgeReq) (*query.BatchJob, error) {
+	if req.Days <= 0 {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "days must be greater than 0")
+	}
+	var selected map[string]struct{}
+	if len(req.Repos) > 0 {
+		selected = make(map[string]struct{}, len(req.Repos))
+		for _, repo := range req.Repos {
+			selected[repo] = struct{}{}
+		}
+	}
+	patterns := make([]string, 0)
+	for _, idle := range b.IdleReport(req.Days).Repos {
+		repo := idle.Datatype + "/" + idle.OrgRepo
+		if _, ok := selected[repo]; selected != nil && !ok {
+			continue
+		}
+		patterns = append(patterns, repo)
+	}
+	if len(patterns) == 0 {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("no repo is idle for %d days", req.Days))
+	}
+	return b.Submit(&query.BatchReq{Action: consts.BatchActionPurge, Patterns: patterns, DryRun: req.DryRun}, "")
+}
+
+func idleRepos() []*query.IdleRepo {
+	now := time.Now()
+	repos := make([]*query.IdleRepo, 0)
+	for _, repo := range cachedRepos() {
+		repoType, orgRepo, _ := strings.Cut(repo, "/")
+		filesDir := util.RepoFilesDir(repoType, orgRepo)
+		lastAccess := downloader.RepoLastAccess(repoType, orgRepo)
+		if lastAccess == 0 {
+			info, err := os.Stat(filesDir)
+			if err != nil {
+				continue
+			}
+			lastAccess = info.ModTime().Unix()
+		}
+		size, err := util.GetFolderSize(filesDir)
+		if err != nil {
+			zap.S().Warnf("get folder size of %s err.%v", filesDir, err)
+		}
+		repos = append(repos, &query.IdleRepo{
+			Datatype:   repoType,
+			OrgRepo:    orgRepo,
+			Size:       size,
+			LastAccess: lastAccess,
+			IdleDays:   int(now.Sub(time.Unix(lastAccess, 0)).Hours() / 24),
+		})
+	}
+	return repos
+}
+
+// cachedRepos 返回已缓存文件的仓库，格式为{repoType}/{orgRepo}
+func cachedRepos() []string {
+	filesRoot := filepath.Join(config.SysConfig.Repos(), "files")
+	repos := make([]string, 0)
+	for _, pattern := range []string{"*/*/resolve", "*/*/*/resolve"} {
+		resolveDirs, _ := filepath.Glob(filepath.Join(filesRoot, pattern))
+		for _, resolveDir := range resolveDirs {
+			rel, err := filepath.Rel(filesRoot, filepath.Dir(resolveDir))
+			if err != nil {
+				continue
+			}
+			repos = append(repos, filepath.ToSlash(util.CacheName(rel)))
+		}
+	}
+	return repos
+}
@@ -0,0 +1,268 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/internal/dao"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 发布包归档中记录包内容的文件
+const bundleManifestFile = "bundle.json"
+
+var bundleNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// BundleService 管理发布包，发布包内的版本按commit固定，可整体预取及导出为一个归档。
+type BundleService struct {
+	fileDao         *dao.FileDao
+	metaService     *MetaService
+	prefetchService *PrefetchService
+	mu              sync.Mutex
+	prefetching     map[string]bool
+	prefetchErrs    *common.SafeMap[string, string] // {bundle}/{repoType}/{orgRepo}最近一次预取的错误
+}
+
+func NewBundleService(fileDao *dao.FileDao, metaService *MetaService, prefetchService *PrefetchService) *BundleService {
+	return &BundleService{
+		fileDao:         fileDao,
+		metaService:     metaService,
+		prefetchService: prefetchService,
+		prefetching:     make(map[string]bool),
+		prefetchErrs:    common.NewSafeMap[string, string](),
+	}
+}
+
+// Create 解析各仓库的版本并保存发布包，同名的发布包已存在时返回冲突。
+func (b *BundleService) Create(ctx context.Context, req *query.BundleReq, authorization string) (*query.BundleStatus, error) {
+	if !bundleNameRegexp.MatchString(req.Name) {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid bundle name: %s", req.Name))
+	}
+	if len(req.Repos) == 0 {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "repos is required")
+	}
+	if _, ok := util.GetReleaseBundle(req.Name); ok {
+		return nil, myerr.NewAppendCode(http.StatusConflict, fmt.Sprintf("bundle %s already exists", req.Name))
+	}
+	repos := make([]*util.BundleRepo, 0, len(req.Repos))
+	seen := make(map[string]struct{}, len(req.Repos))
+	for _, item := range req.Repos {
+		repo, err := parseBundleRepo(item)
+		if err != nil {
+			return nil, err
+		}
+		key := repo.Datatype + "/" + repo.OrgRepo
+		if _, ok := seen[key]; ok {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("duplicate repo: %s", key))
+		}
+		seen[key] = struct{}{}
+		if repo.Commit, err = b.fileDao.GetFileCommitSha(ctx, repo.Datatype, repo.OrgRepo, repo.Revision, authorization, "meta"); err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	bundle := &util.ReleaseBundle{Name: req.Name, Description: req.Description, Repos: repos, CreatedAt: time.Now().Unix()}
+	ok, err := util.AddReleaseBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, myerr.NewAppendCode(http.StatusConflict, fmt.Sprintf("bundle %s already exists", req.Name))
+	}
+	zap.S().Infof("bundle %s created, repos %d", bundle.Name, len(bundle.Repos))
+	if req.Prefetch {
+		if _, err = b.Prefetch(bundle.Name, authorization); err != nil {
+			return nil, err
+		}
+	}
+	return b.Status(bundle.Name)
+}
+
+// parseBundleRepo 解析{repoType}/{org}/{repo}@{revision}，省略revision时为main。
+func parseBundleRepo(item string) (*util.BundleRepo, error) {
+	repo, revision, _ := strings.Cut(item, "@")
+	if revision == "" {
+		revision = "main"
+	}
+	repoType, orgRepo, _ := strings.Cut(repo, "/")
+	segments := strings.Split(orgRepo, "/")
+	if _, ok := consts.RepoTypesMapping[repoType]; !ok || len(segments) > 2 || orgRepo == "" {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid repo: %s, expect {repoType}/{org}/{repo}@{revision}", item))
+	}
+	for _, segment := range segments {
+		if err := util.ValidatePathParam("repo", segment); err != nil || segment == "" {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid repo: %s", item))
+		}
+	}
+	if err := util.ValidatePathParam("revision", revision); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	return &util.BundleRepo{Datatype: repoType, OrgRepo: orgRepo, Revision: revision}, nil
+}
+
+func (b *BundleService) List() []*util.ReleaseBundle {
+	return util.ListReleaseBundles()
+}
+
+func (b *BundleService) bundle(name string) (*util.ReleaseBundle, error) {
+	bundle, ok := util.GetReleaseBundle(name)
+	if !ok {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("bundle %s is not exist", name))
+	}
+	return bundle, nil
+}
+
+// Status 返回发布包内各版本是否已完整缓存，只检查公共缓存。
+func (b *BundleService) Status(name string) (*query.BundleStatus, error) {
+	bundle, err := b.bundle(name)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	prefetching := b.prefetching[name]
+	b.mu.Unlock()
+	status := &query.BundleStatus{ReleaseBundle: bundle, Prefetching: prefetching, Items: make([]*query.BundleRepoStatus, 0, len(bundle.Repos))}
+	for _, repo := range bundle.Repos {
+		item := &query.BundleRepoStatus{BundleRepo: repo}
+		files, _, err := b.metaService.archiveFiles(repo.Datatype, repo.OrgRepo, repo.Commit, "", "")
+		if err != nil {
+			item.Err = err.Error()
+		} else {
+			item.Cached, item.Files = true, len(files)
+			for _, f := range files {
+				item.Size += f.Size
+			}
+			status.Cached++
+			status.Size += item.Size
+		}
+		if prefetchErr, ok := b.prefetchErrs.Get(bundlePrefetchKey(name, repo)); ok && !item.Cached {
+			item.Err = prefetchErr
+		}
+		status.Items = append(status.Items, item)
+	}
+	return status, nil
+}
+
+// Delete 删除发布包，包内的版本不再因此被固定，已缓存的文件不删除，由版本回收处理。
+func (b *BundleService) Delete(name string) error {
+	ok, err := util.DeleteReleaseBundle(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("bundle %s is not exist", name))
+	}
+	zap.S().Infof("bundle %s deleted", name)
+	return nil
+}
+
+func bundlePrefetchKey(name string, repo *util.BundleRepo) string {
+	return fmt.Sprintf("%s/%s/%s", name, repo.Datatype, repo.OrgRepo)
+}
+
+// Prefetch 在后台逐个预取发布包内的版本，同一发布包正在预取时返回冲突。
+func (b *BundleService) Prefetch(name, authorization string) (*query.BundleStatus, error) {
+	bundle, err := b.bundle(name)
+	if err != nil {
+		return nil, err
+	}
+	if !config.SysConfig.Online() {
+		return nil, myerr.NewAppendCode(http.StatusServiceUnavailable, "prefetch is not available in offline mode")
+	}
+	b.mu.Lock()
+	if b.prefetching[name] {
+		b.mu.Unlock()
+		return nil, myerr.NewAppendCode(http.StatusConflict, fmt.Sprintf("bundle %s is prefetching", name))
+	}
+	b.prefetching[name] = true
+	b.mu.Unlock()
+	go b.prefetch(bundle, authorization)
+	return b.Status(name)
+}
+
+func (b *BundleService) prefetch(bundle *util.ReleaseBundle, authorization string) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.prefetching, bundle.Name)
+		b.mu.Unlock()
+	}()
+	zap.S().Infof("bundle %s prefetch start, repos %d", bundle.Name, len(bundle.Repos))
+	failed := 0
+	for i, repo := range bundle.Repos {
+		key := bundlePrefetchKey(bundle.Name, repo)
+		_, size, err := b.prefetchService.PrefetchRevision(context.Background(), repo.Datatype, repo.OrgRepo, repo.Commit, authorization)
+		status := consts.BatchStatusSuccess
+		if err != nil {
+			zap.S().Warnf("bundle %s prefetch %s/%s@%s err.%v", bundle.Name, repo.Datatype, repo.OrgRepo, repo.Commit, err)
+			b.prefetchErrs.Set(key, err.Error())
+			status = consts.BatchStatusFailed
+			failed++
+		} else {
+			b.prefetchErrs.Delete(key)
+		}
+		events.Publish(events.JobProgress, map[string]interface{}{"job": "bundle", "name": bundle.Name, "datatype": repo.Datatype,
+			"orgRepo": repo.OrgRepo, "commit": repo.Commit, "status": status, "size": size, "done": i + 1, "total": len(bundle.Repos)})
+	}
+	zap.S().Infof("bundle %s prefetch done, repos %d, failed %d", bundle.Name, len(bundle.Repos), failed)
+}
+
+// Archive 返回发布包的归档清单，文件位于{repoType}/{org}/{repo}目录下，并附带记录包内容的bundle.json，所有版本都必须已完整缓存。
+func (b *BundleService) Archive(name string) ([]*util.ArchiveFile, *util.ReleaseBundle, error) {
+	bundle, err := b.bundle(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, err := sonic.Marshal(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	archiveFiles := []*util.ArchiveFile{{
+		Name: bundleManifestFile,
+		Size: int64(len(manifest)),
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(manifest)), nil
+		},
+	}}
+	for _, repo := range bundle.Repos {
+		files, _, err := b.metaService.archiveFiles(repo.Datatype, repo.OrgRepo, repo.Commit, "", "")
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range files {
+			f.Name = path.Join(repo.Datatype, repo.OrgRepo, f.Name)
+			archiveFiles = append(archiveFiles, f)
+		}
+	}
+	return archiveFiles, bundle, nil
+}
@@ -3,14 +3,18 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/http"
 
 	"dingospeed/internal/dao"
+	"dingospeed/internal/downloader"
 	"dingospeed/internal/model/query"
 	task2 "dingospeed/internal/service/task"
 	"dingospeed/pkg/app"
 	"dingospeed/pkg/common"
 	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
 	"dingospeed/pkg/proto/manager"
+	"dingospeed/pkg/util"
 
 	"github.com/bytedance/sonic"
 	"github.com/labstack/echo/v4"
@@ -100,6 +104,31 @@ func (p *CacheJobService) CreateCacheJob(c echo.Context, jobReq *query.CreateCac
 	return int64(cacheTask.TaskNo), nil
 }
 
+// CreateCollectionCacheJobs 解析collection中的模型和数据集，为每个仓库创建预热任务，单个仓库创建失败不影响其他仓库。
+func (p *CacheJobService) CreateCollectionCacheJobs(c echo.Context, jobReq *query.CreateCacheJobReq) ([]*query.CollectionCacheJob, error) {
+	if jobReq.Type != consts.CacheTypePreheat {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "collection only supports preheat jobs")
+	}
+	repos, err := p.metaDao.CollectionRepos(jobReq.Collection, c.Request().Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*query.CollectionCacheJob, 0, len(repos))
+	for _, repo := range repos {
+		org, name := util.SplitOrgRepo(repo.OrgRepo)
+		job := &query.CollectionCacheJob{Datatype: repo.RepoType, Org: org, Repo: name}
+		req := *jobReq
+		req.Datatype, req.Org, req.Repo, req.Collection = job.Datatype, job.Org, job.Repo, ""
+		if job.JobId, err = p.CreateCacheJob(c, &req); err != nil {
+			zap.S().Warnf("create preheat job for %s/%s in collection %s err.%v", job.Datatype, repo.OrgRepo, jobReq.Collection, err)
+			job.Err = err.Error()
+		}
+		jobs = append(jobs, job)
+	}
+	zap.S().Infof("collection %s resolved to %d repos", jobReq.Collection, len(repos))
+	return jobs, nil
+}
+
 func (p *CacheJobService) StopCacheJob(jobStatusReq *query.JobStatusReq) error {
 	if task, ok := p.cachePool.GetTask(int(jobStatusReq.Id)); ok {
 		if t, ok := task.(*task2.PreheatCacheTask); ok {
@@ -175,3 +204,25 @@ func (p *CacheJobService) RealtimeCacheJob(realtimeReq *query.RealtimeReq) []*qu
 	}
 	return ret
 }
+
+func (p *CacheJobService) JobProgress(jobId int64) (*query.JobProgressResp, error) {
+	if task, ok := p.cachePool.GetTask(int(jobId)); ok {
+		if pTask, ok := task.(*task2.PreheatCacheTask); ok {
+			return pTask.Progress(), nil
+		}
+	}
+	return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("job %d is not running", jobId))
+}
+
+// RepoProgress 返回仓库正在执行的预热任务及正在回源的下载。
+func (p *CacheJobService) RepoProgress(datatype, org, repo string) *query.RepoProgressResp {
+	resp := &query.RepoProgressResp{Jobs: make([]*query.JobProgressResp, 0)}
+	for _, task := range p.cachePool.Tasks() {
+		if pTask, ok := task.(*task2.PreheatCacheTask); ok && pTask.Job.Datatype == datatype &&
+			pTask.Job.Org == org && pTask.Job.Repo == repo {
+			resp.Jobs = append(resp.Jobs, pTask.Progress())
+		}
+	}
+	resp.Downloads = downloader.ListRepoTransfers(datatype, util.GetOrgRepo(org, repo))
+	return resp
+}
@@ -0,0 +1,304 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/internal/dao"
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+// 最近有写入的文件及目录可能正在下载或创建，不参与本轮整理
+const compactGracePeriod = time.Hour
+
+// CompactionService 由管理员触发的缓存整理：将不同仓库中内容相同的blob合并为硬链接，删除空的版本目录，
+// 按当前压缩配置重写元数据文件，在线执行，读取文件的速度可限制。
+type CompactionService struct {
+	fileDao *dao.FileDao
+	mu      sync.Mutex
+	status  *query.CompactResp
+}
+
+func NewCompactionService(fileDao *dao.FileDao) *CompactionService {
+	return &CompactionService{
+		fileDao: fileDao,
+		status:  &query.CompactResp{Errors: make([]string, 0)},
+	}
+}
+
+// Start 在后台执行一次整理，已在执行时返回冲突。
+func (s *CompactionService) Start(req *query.CompactReq) (*query.CompactResp, error) {
+	if req.Bandwidth < 0 {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "bandwidth must not be negative")
+	}
+	limiter, err := common.NewWindowLimiter(nil, int64(req.Bandwidth)<<20)
+	if err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	s.mu.Lock()
+	if s.status.Running {
+		s.mu.Unlock()
+		return nil, myerr.NewAppendCode(http.StatusConflict, "compaction is running")
+	}
+	s.status = &query.CompactResp{Running: true, DryRun: req.DryRun, StartTime: time.Now(), Errors: make([]string, 0)}
+	s.mu.Unlock()
+	go s.run(req.DryRun, limiter)
+	return s.Status(), nil
+}
+
+// Status 返回当前或最近一次整理的状态。
+func (s *CompactionService) Status() *query.CompactResp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := *s.status
+	status.Errors = append(make([]string, 0, len(s.status.Errors)), s.status.Errors...)
+	return &status
+}
+
+func (s *CompactionService) update(f func(status *query.CompactResp)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.status)
+}
+
+func (s *CompactionService) addError(err error) {
+	zap.S().Warnf("compaction err.%v", err)
+	s.update(func(status *query.CompactResp) {
+		status.Errors = append(status.Errors, err.Error())
+	})
+}
+
+func (s *CompactionService) setPhase(phase string) {
+	s.update(func(status *query.CompactResp) {
+		status.Phase = phase
+	})
+	status := s.Status()
+	events.Publish(events.JobProgress, map[string]interface{}{"job": "compaction", "phase": phase, "dryRun": status.DryRun,
+		"mergedBlobs": status.MergedBlobs, "removedDirs": status.RemovedDirs, "rewrittenFiles": status.RewrittenFiles, "reclaimed": status.Reclaimed})
+}
+
+func (s *CompactionService) run(dryRun bool, limiter *common.WindowLimiter) {
+	ctx := common.WithBackground(context.Background())
+	wait := func(n int64) error {
+		return limiter.WaitN(ctx, n)
+	}
+	before := time.Now().Add(-compactGracePeriod)
+	zap.S().Infof("compaction start, dryRun %t", dryRun)
+
+	s.setPhase(consts.CompactPhaseBlobs)
+	s.mergeBlobs(before, dryRun, wait)
+	s.setPhase(consts.CompactPhaseDirs)
+	s.removeEmptyDirs(before, dryRun)
+	s.setPhase(consts.CompactPhaseMeta)
+	s.rewriteMeta(before, dryRun, wait)
+
+	if !dryRun && config.SysConfig.Cache.Index.Enabled {
+		// 删除及重写的文件不再由旧索引记录
+		if err := util.BuildCacheIndex(config.SysConfig.Repos()); err != nil {
+			s.addError(err)
+		}
+	}
+	s.update(func(status *query.CompactResp) {
+		status.Running = false
+		status.FinishTime = time.Now()
+	})
+	s.setPhase(consts.CompactPhaseDone)
+	status := s.Status()
+	zap.S().Infof("compaction done, dryRun %t, merged blobs %d, removed dirs %d, rewritten files %d, reclaimed %s",
+		dryRun, status.MergedBlobs, status.RemovedDirs, status.RewrittenFiles, util.ConvertBytesToHumanReadable(status.Reclaimed))
+}
+
+type compactBlob struct {
+	path string
+	info os.FileInfo
+	key  string
+}
+
+// mergeBlobs 按文件名（etag）及大小分组，同组内已完整缓存且内容相同的blob硬链接到同一个文件。
+func (s *CompactionService) mergeBlobs(before time.Time, dryRun bool, wait func(n int64) error) {
+	filesRoot := filepath.Join(config.SysConfig.Repos(), "files")
+	groups := make(map[string][]*compactBlob)
+	for _, pattern := range []string{"*/*/blobs/*", "*/*/*/blobs/*"} {
+		blobs, _ := filepath.Glob(filepath.Join(filesRoot, pattern))
+		for _, blob := range blobs {
+			if strings.HasPrefix(filepath.Base(blob), ".") || strings.HasSuffix(blob, ".tmp") {
+				continue
+			}
+			info, err := os.Lstat(blob)
+			if err != nil || !info.Mode().IsRegular() || info.ModTime().After(before) {
+				continue
+			}
+			key, ok := util.FileKey(info)
+			if !ok {
+				continue
+			}
+			group := fmt.Sprintf("%s/%d", filepath.Base(blob), info.Size())
+			groups[group] = append(groups[group], &compactBlob{path: blob, info: info, key: key})
+		}
+	}
+	for _, blobs := range groups {
+		if len(blobs) < 2 {
+			continue
+		}
+		var keeper *compactBlob
+		merged := map[string]struct{}{}
+		for _, blob := range blobs {
+			if complete, _, err := downloader.BlobComplete(blob.path); err != nil || !complete {
+				continue
+			}
+			if keeper == nil {
+				keeper, merged[blob.key] = blob, struct{}{}
+				continue
+			}
+			if _, ok := merged[blob.key]; ok {
+				continue
+			}
+			same, err := util.SameContent(keeper.path, blob.path, wait)
+			if err != nil {
+				s.addError(fmt.Errorf("compare %s err.%v", blob.path, err))
+				continue
+			}
+			if !same {
+				continue
+			}
+			if !dryRun {
+				if err = s.linkBlob(keeper.path, blob.path); err != nil {
+					s.addError(fmt.Errorf("link %s err.%v", blob.path, err))
+					continue
+				}
+			}
+			merged[blob.key] = struct{}{}
+			s.update(func(status *query.CompactResp) {
+				status.MergedBlobs++
+				status.Reclaimed += blob.info.Size()
+			})
+		}
+	}
+}
+
+func (s *CompactionService) linkBlob(src, dst string) error {
+	unlock, err := util.LockFile(dst)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return util.LinkFile(src, dst)
+}
+
+// removeEmptyDirs 删除resolve、revision及paths-info下空的版本目录，通常是版本回收或清理后留下的。
+func (s *CompactionService) removeEmptyDirs(before time.Time, dryRun bool) {
+	roots := make([]string, 0)
+	filesRoot := filepath.Join(config.SysConfig.Repos(), "files")
+	for _, pattern := range []string{"*/*/resolve", "*/*/*/resolve"} {
+		resolveDirs, _ := filepath.Glob(filepath.Join(filesRoot, pattern))
+		roots = append(roots, resolveDirs...)
+	}
+	apiRoot := util.GetApiRoot("")
+	_ = filepath.WalkDir(apiRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if name := d.Name(); name == "revision" || name == "paths-info" {
+			roots = append(roots, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	for _, root := range roots {
+		n, err := util.RemoveEmptyDirs(root, before, dryRun)
+		if err != nil {
+			s.addError(fmt.Errorf("remove empty dirs of %s err.%v", root, err))
+		}
+		s.update(func(status *query.CompactResp) {
+			status.RemovedDirs += n
+		})
+	}
+}
+
+// rewriteMeta 重写压缩方式与当前配置不一致的元数据文件，例如开启compressMeta之前缓存的文件，硬链接共享的文件只重写一次。
+func (s *CompactionService) rewriteMeta(before time.Time, dryRun bool, wait func(n int64) error) {
+	compress := config.SysConfig.Cache.CompressMeta
+	links := make(map[string][]string)
+	keys := make([]string, 0)
+	_ = filepath.WalkDir(util.GetApiRoot(""), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(before) {
+			return nil
+		}
+		head := make([]byte, 4)
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		n, _ := f.Read(head)
+		f.Close()
+		if util.IsZstd(head[:n]) == compress {
+			return nil
+		}
+		key, ok := util.FileKey(info)
+		if !ok {
+			key = path
+		}
+		if _, ok = links[key]; !ok {
+			keys = append(keys, key)
+		}
+		links[key] = append(links[key], path)
+		return nil
+	})
+	for _, key := range keys {
+		paths := links[key]
+		if dryRun {
+			s.update(func(status *query.CompactResp) {
+				status.RewrittenFiles++
+			})
+			continue
+		}
+		if info, err := os.Stat(paths[0]); err == nil {
+			if err = wait(info.Size()); err != nil {
+				return
+			}
+		}
+		saved, err := s.fileDao.RewriteCacheFile(paths[0], paths[1:])
+		if err != nil {
+			s.addError(fmt.Errorf("rewrite %s err.%v", paths[0], err))
+			continue
+		}
+		s.update(func(status *query.CompactResp) {
+			status.RewrittenFiles++
+			status.Reclaimed += saved
+		})
+	}
+}
@@ -0,0 +1,167 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"dingospeed/internal/dao"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/lock"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+var curationOnce sync.Once
+
+// CurationService 按配置的规则定期发现远端排名靠前的模型，并将其main分支同步到缓存。
+type CurationService struct {
+	fileDao *dao.FileDao
+	metaDao *dao.MetaDao
+	mu      sync.Mutex
+	status  *query.CurationResp
+}
+
+func NewCurationService(fileDao *dao.FileDao, metaDao *dao.MetaDao) *CurationService {
+	curationSvc := &CurationService{
+		fileDao: fileDao,
+		metaDao: metaDao,
+		status:  &query.CurationResp{Repos: make([]*query.CurationRepo, 0)},
+	}
+	curationOnce.Do(func() {
+		if config.SysConfig.Curation.Enabled {
+			go curationSvc.cycleCuration()
+		}
+	})
+	return curationSvc
+}
+
+func (s *CurationService) cycleCuration() {
+	ticker := time.NewTicker(config.SysConfig.GetCurationInterval())
+	defer ticker.Stop()
+	for {
+		// 共享缓存卷时只由leader副本同步
+		if lock.IsLeader() {
+			s.Run(context.Background())
+		}
+		<-ticker.C
+	}
+}
+
+// Trigger 在后台立即执行一次同步，已在执行时返回false。
+func (s *CurationService) Trigger() bool {
+	s.mu.Lock()
+	running := s.status.Running
+	s.mu.Unlock()
+	if running {
+		return false
+	}
+	go s.Run(context.Background())
+	return true
+}
+
+// Status 返回当前或最近一次同步的状态。
+func (s *CurationService) Status() *query.CurationResp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := *s.status
+	status.Repos = append(make([]*query.CurationRepo, 0, len(s.status.Repos)), s.status.Repos...)
+	return &status
+}
+
+// Run 依次按规则发现模型并同步，多个规则命中同一模型时只同步一次。
+func (s *CurationService) Run(ctx context.Context) {
+	s.mu.Lock()
+	if s.status.Running {
+		s.mu.Unlock()
+		return
+	}
+	s.status = &query.CurationResp{Running: true, StartTime: time.Now(), Repos: make([]*query.CurationRepo, 0)}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.status.Running = false
+		s.status.FinishTime = time.Now()
+		s.mu.Unlock()
+	}()
+	synced := make(map[string]struct{})
+	for _, rule := range config.SysConfig.Curation.Rules {
+		orgRepos, err := s.metaDao.DiscoverModels(rule, "")
+		if err != nil {
+			zap.S().Warnf("curation discover models %+v err.%v", rule, err)
+			continue
+		}
+		for _, orgRepo := range orgRepos {
+			if ctx.Err() != nil {
+				return
+			}
+			if _, ok := synced[orgRepo]; ok {
+				continue
+			}
+			synced[orgRepo] = struct{}{}
+			result := s.syncModel(ctx, orgRepo)
+			s.mu.Lock()
+			s.status.Repos = append(s.status.Repos, result)
+			s.status.Size += result.Size
+			s.mu.Unlock()
+		}
+	}
+	zap.S().Infof("curation finished, models %d, downloaded %s", len(synced), util.ConvertBytesToHumanReadable(s.Status().Size))
+}
+
+func (s *CurationService) syncModel(ctx context.Context, orgRepo string) *query.CurationRepo {
+	curation := config.SysConfig.Curation
+	result := &query.CurationRepo{OrgRepo: orgRepo}
+	metadata, err := s.metaDao.GetMetadata("models", orgRepo, "main", consts.RequestTypeGet, "")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	var sha dao.CommitHfSha
+	if err = sonic.Unmarshal(metadata.OriginContent, &sha); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Commit = sha.Sha
+	if curation.MaxTotalSize > 0 && sha.UsedStorage > curation.MaxTotalSize {
+		result.Skipped = true
+		return result
+	}
+	for _, sibling := range sha.Siblings {
+		if ctx.Err() != nil {
+			return result
+		}
+		fileName := sibling.Rfilename
+		if !prefetchMatch(curation.Include, curation.Exclude, fileName) {
+			continue
+		}
+		n, err := s.fileDao.PrefetchFile(ctx, "models", orgRepo, sha.Sha, fileName, "", curation.MaxFileSize)
+		if err != nil {
+			zap.S().Warnf("curation %s/%s/%s err.%v", orgRepo, sha.Sha, fileName, err)
+			result.Error = err.Error()
+			continue
+		}
+		result.Files++
+		result.Size += n
+	}
+	zap.S().Infof("curation %s/%s done, downloaded %d bytes", orgRepo, sha.Sha, result.Size)
+	return result
+}
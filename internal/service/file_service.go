@@ -15,7 +15,13 @@
 package service
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
 	"dingospeed/internal/dao"
+	"dingospeed/pkg/config"
 	"dingospeed/pkg/consts"
 	myerr "dingospeed/pkg/error"
 	"dingospeed/pkg/util"
@@ -25,41 +31,123 @@ import (
 )
 
 type FileService struct {
-	fileDao *dao.FileDao
+	fileDao         *dao.FileDao
+	prefetchService *PrefetchService
 }
 
-func NewFileService(fileDao *dao.FileDao) *FileService {
+func NewFileService(fileDao *dao.FileDao, prefetchService *PrefetchService) *FileService {
 	return &FileService{
-		fileDao: fileDao,
+		fileDao:         fileDao,
+		prefetchService: prefetchService,
 	}
 }
 
 func (f *FileService) FileHeadCommon(c echo.Context, repoType, orgRepo, commit, filePath string) error {
-	zap.S().Infof("exec file head:%s/%s/%s/%s, remoteAdd:%s", repoType, orgRepo, commit, filePath, c.Request().RemoteAddr)
+	zap.S().Infof("exec file head:%s/%s/%s/%s, remoteAdd:%s", repoType, orgRepo, commit, filePath, c.RealIP())
 	authorization := c.Request().Header.Get("authorization")
-	commitSha, err := f.fileDao.GetFileCommitSha(repoType, orgRepo, commit, authorization, "file")
-	if err != nil {
-		if e, ok := err.(myerr.Error); ok {
-			return util.ErrorEntryUnknown(c, e.StatusCode(), e.Error())
-		}
-		return util.ErrorProxyError(c)
+	commitSha, err := f.fileDao.GetFileCommitSha(c.Request().Context(), repoType, orgRepo, commit, authorization, "file")
+	if commitSha, err = f.fallbackRevision(c, repoType, orgRepo, commit, filePath, commitSha, err); err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	if err = f.fileDao.CheckRepoPolicy(repoType, orgRepo, commitSha, authorization); err != nil {
+		return util.ResponseHfError(c, err)
 	}
 	return f.fileDao.FileGetGenerator(c, repoType, orgRepo, commitSha, filePath, consts.RequestTypeHead)
 }
 
 func (f *FileService) FileGetCommon(c echo.Context, repoType, orgRepo, commit, filePath string) error {
-	zap.S().Infof("exec file get:%s/%s/%s/%s, remoteAdd:%s", repoType, orgRepo, commit, filePath, c.Request().RemoteAddr)
+	zap.S().Infof("exec file get:%s/%s/%s/%s, remoteAdd:%s", repoType, orgRepo, commit, filePath, c.RealIP())
+	authorization := c.Request().Header.Get("authorization")
+	commitSha, err := f.fileDao.GetFileCommitSha(c.Request().Context(), repoType, orgRepo, commit, authorization, "file")
+	if commitSha, err = f.fallbackRevision(c, repoType, orgRepo, commit, filePath, commitSha, err); err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	if err = f.fileDao.CheckRepoPolicy(repoType, orgRepo, commitSha, authorization); err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	f.prefetchService.Trigger(c, repoType, orgRepo, commitSha, filePath)
+	return f.fileDao.FileGetGenerator(c, repoType, orgRepo, commitSha, filePath, consts.RequestTypeGet)
+}
+
+// fallbackRevision 开启offline.fallbackRevision时，若请求的版本无法解析（远端不可用）或离线时该版本未缓存此文件，
+// 改用最近缓存了该文件的其它版本响应，并通过响应头标明实际的版本。
+func (f *FileService) fallbackRevision(c echo.Context, repoType, orgRepo, revision, filePath, commitSha string, err error) (string, error) {
+	if !config.SysConfig.Offline.FallbackRevision {
+		return commitSha, err
+	}
 	authorization := c.Request().Header.Get("authorization")
-	commitSha, err := f.fileDao.GetFileCommitSha(repoType, orgRepo, commit, authorization, "file")
 	if err != nil {
-		if e, ok := err.(myerr.Error); ok {
-			return util.ErrorEntryUnknown(c, e.StatusCode(), e.Error())
+		switch myerr.KindOf(err) {
+		case myerr.KindOfflineMiss, myerr.KindUpstreamTimeout, myerr.KindUpstreamError, myerr.KindUnavailable:
+		default:
+			return commitSha, err
 		}
-		return util.ErrorProxyError(c)
+	} else if config.SysConfig.Online() {
+		return commitSha, nil
+	} else if ok, _ := f.fileDao.CachedFileComplete(repoType, orgRepo, commitSha, filePath, authorization); ok {
+		return commitSha, nil
 	}
-	return f.fileDao.FileGetGenerator(c, repoType, orgRepo, commitSha, filePath, consts.RequestTypeGet)
+	fallback, ok := f.fileDao.FallbackRevision(repoType, orgRepo, commitSha, filePath, authorization)
+	if !ok {
+		return commitSha, err
+	}
+	zap.S().Warnf("revision %s of %s/%s/%s is not available, fallback to cached revision %s", revision, repoType, orgRepo, filePath, fallback)
+	header := c.Response().Header()
+	header.Set(consts.HeaderFallbackRevision, fallback)
+	header.Set(consts.HeaderStale, "true")
+	header.Set("Warning", fmt.Sprintf(`299 dingospeed "revision %s is not cached, served from revision %s"`, revision, fallback))
+	return fallback, nil
+}
+
+// ProxyFile 通用代理，method为HEAD或GET。
+func (f *FileService) ProxyFile(c echo.Context, rawURL, method string) error {
+	return f.fileDao.ProxyFileGenerator(c, rawURL, method)
 }
 
 func (f *FileService) GetFileOffset(dataType string, org string, repo string, etag string, fileSize int64) int64 {
 	return f.fileDao.GetFileOffset(dataType, org, repo, etag, fileSize)
 }
+
+// GgufInfo 通过range请求只读取gguf文件头，返回架构、参数量及量化信息。
+func (f *FileService) GgufInfo(c echo.Context, repoType, orgRepo, revision, filePath string) (*util.GGUFInfo, error) {
+	authorization := c.Request().Header.Get("authorization")
+	reader := f.fileDao.NewFileRangeReader(c.Request().Context(), repoType, orgRepo, revision, filePath, authorization, consts.HeaderReadChunkSize)
+	info, err := util.ParseGGUF(io.LimitReader(reader, consts.MaxGgufHeaderSize))
+	if err != nil {
+		if _, ok := err.(myerr.Error); ok {
+			return nil, err
+		}
+		zap.S().Warnf("parse gguf %s/%s/%s err.%v", orgRepo, revision, filePath, err)
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("parse gguf err: %v", err))
+	}
+	return info, nil
+}
+
+// Preview 读取文件开头size字节用于预览，返回内容、内容类型及文件总大小。
+func (f *FileService) Preview(c echo.Context, repoType, orgRepo, revision, filePath string, size int64) ([]byte, string, int64, error) {
+	authorization := c.Request().Header.Get("authorization")
+	content, fileSize, err := f.fileDao.ReadFileRange(c.Request().Context(), repoType, orgRepo, revision, filePath, authorization, 0, size-1)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	contentType := util.PreviewContentType(filePath, content)
+	if int64(len(content)) < fileSize && strings.Contains(contentType, "charset=utf-8") {
+		content = util.TrimIncompleteRune(content)
+	}
+	return content, contentType, fileSize, nil
+}
+
+// SafetensorsInfo 通过range请求只读取safetensors文件头，返回各张量的名称、类型和形状。
+func (f *FileService) SafetensorsInfo(c echo.Context, repoType, orgRepo, revision, filePath string) (*util.SafetensorsInfo, error) {
+	authorization := c.Request().Header.Get("authorization")
+	reader := f.fileDao.NewFileRangeReader(c.Request().Context(), repoType, orgRepo, revision, filePath, authorization, consts.HeaderReadChunkSize)
+	info, err := util.ParseSafetensors(reader)
+	if err != nil {
+		if _, ok := err.(myerr.Error); ok {
+			return nil, err
+		}
+		zap.S().Warnf("parse safetensors %s/%s/%s err.%v", orgRepo, revision, filePath, err)
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("parse safetensors err: %v", err))
+	}
+	return info, nil
+}
@@ -15,16 +15,30 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
+	"html/template"
 	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"dingospeed/internal/dao"
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
 	"dingospeed/pkg/common"
 	"dingospeed/pkg/config"
 	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
 	"dingospeed/pkg/util"
 
 	"github.com/bytedance/sonic"
@@ -34,25 +48,42 @@ import (
 )
 
 type MetaService struct {
-	fileDao *dao.FileDao
-	metaDao *dao.MetaDao
+	fileDao         *dao.FileDao
+	metaDao         *dao.MetaDao
+	prefetchService *PrefetchService
 }
 
-func NewMetaService(fileDao *dao.FileDao, metaDao *dao.MetaDao) *MetaService {
+func NewMetaService(fileDao *dao.FileDao, metaDao *dao.MetaDao, prefetchService *PrefetchService) *MetaService {
 	return &MetaService{
-		fileDao: fileDao,
-		metaDao: metaDao,
+		fileDao:         fileDao,
+		metaDao:         metaDao,
+		prefetchService: prefetchService,
 	}
 }
 
-func (m *MetaService) GetMetadata(repoType, orgRepo, revision, method, authorization string) (*common.CacheContent, error) {
-	zap.S().Debugf("GetMetadata:%s/%s/%s/%s", repoType, orgRepo, revision, method)
-	return m.metaDao.GetMetadata(repoType, orgRepo, revision, method, authorization)
+func (m *MetaService) GetMetadata(ctx context.Context, repoType, orgRepo, revision, method, authorization, query string) (*common.CacheContent, error) {
+	zap.S().Debugf("GetMetadata:%s/%s/%s/%s?%s", repoType, orgRepo, revision, method, query)
+	return m.metaDao.GetQueryMetadata(ctx, repoType, orgRepo, revision, method, authorization, query)
+}
+
+// PrefetchPathsInfo 返回仓库信息后按配置在后台缓存该版本各文件的paths-info。
+func (m *MetaService) PrefetchPathsInfo(c echo.Context, repoType, orgRepo, revision string) {
+	m.prefetchService.TriggerPathsInfo(c, repoType, orgRepo, revision)
+}
+
+// StreamMetadata get请求元数据，未缓存时边缓存边回传客户端，已回传时返回的内容为nil。
+func (m *MetaService) StreamMetadata(c echo.Context, repoType, orgRepo, revision, authorization, query string) (*common.CacheContent, error) {
+	zap.S().Debugf("StreamMetadata:%s/%s/%s?%s", repoType, orgRepo, revision, query)
+	return m.metaDao.StreamMetadata(c.Request().Context(), repoType, orgRepo, revision, authorization, query, func(statusCode int, headers map[string]string) io.Writer {
+		return util.ResponseStreamWriter(c, orgRepo, headers)
+	})
 }
 
 func (m *MetaService) WhoamiV2(c echo.Context) error {
-	err := m.metaDao.WhoamiV2Generator(c)
-	return err
+	if err := m.metaDao.WhoamiV2Generator(c); err != nil {
+		return util.ResponseHfError(c, err)
+	}
+	return nil
 }
 
 func (m *MetaService) Repos(c echo.Context) error {
@@ -72,44 +103,130 @@ func (m *MetaService) RepoRefs(c echo.Context, repoType, org, repo string) error
 		return util.ErrorRepoNotFound(c)
 	}
 	authorization := c.Request().Header.Get("authorization")
-	localRefsDir := fmt.Sprintf("%s/api/%s/%s/refs", config.SysConfig.Repos(), repoType, orgRepo)
-	localRefsPath := fmt.Sprintf("%s/%s", localRefsDir, fmt.Sprintf("refs_get.json"))
-	err := util.MakeDirs(localRefsPath)
-	if err != nil {
-		zap.S().Errorf("create %s dir err.%v", localRefsPath, err)
-		return util.ErrorProxyError(c)
-	}
 	var cacheContent *common.CacheContent
-	if !config.SysConfig.Online() && util.FileExists(localRefsPath) {
-		cacheContent, err = m.fileDao.ReadCacheRequest(localRefsPath)
+	if !config.SysConfig.Online() {
+		refs, lastUpdated, err := m.metaDao.OfflineRefs(repoType, orgRepo, authorization)
+		if err != nil {
+			zap.S().Warnf("offline refs %s/%s err.%v", repoType, orgRepo, err)
+			return util.ResponseHfError(c, util.OfflineMissError(err))
+		}
+		content, err := sonic.Marshal(refs)
 		if err != nil {
-			zap.S().Errorf("ReadCacheRequest %s dir err.%v", localRefsPath, err)
 			return util.ErrorProxyError(c)
 		}
+		cacheContent = &common.CacheContent{
+			Headers: map[string]string{
+				"content-type":           echo.MIMEApplicationJSON,
+				consts.HeaderStale:       "true",
+				consts.HeaderLastUpdated: time.Unix(lastUpdated, 0).UTC().Format(http.TimeFormat),
+			},
+			OriginContent: content,
+		}
 	} else {
-		resp, err := m.metaDao.RepoRefs(repoType, orgRepo, authorization)
+		refs, err := m.metaDao.CachedRefs(repoType, orgRepo, authorization)
 		if err != nil {
 			zap.S().Errorf("get repo refs err.%v", err)
 			return util.ErrorProxyError(c)
 		}
-		extractHeaders := resp.ExtractHeaders(resp.Headers)
-		if err = m.fileDao.WriteCacheRequest(localRefsPath, resp.StatusCode, extractHeaders, resp.Body); err != nil {
-			zap.S().Errorf("writeCacheRequest err.%v", err)
+		if refs.StatusCode != http.StatusOK {
+			// 远端的错误响应原样返回，不写入缓存
+			for k, v := range refs.Headers {
+				c.Response().Header().Set(k, v)
+			}
+			return c.Blob(refs.StatusCode, echo.MIMEApplicationJSON, refs.OriginContent)
+		}
+		cacheContent = refs
+	}
+	content := cacheContent.OriginContent
+	if includePrs, _ := strconv.ParseBool(c.QueryParam("include_prs")); !includePrs {
+		// 缓存中始终包含PR，客户端未要求时去掉
+		var err error
+		if content, err = dropPullRequests(content); err != nil {
+			zap.S().Errorf("drop pull requests from refs err.%v", err)
 			return util.ErrorProxyError(c)
 		}
-		cacheContent = &common.CacheContent{
-			Headers:       extractHeaders,
-			OriginContent: resp.Body,
+	}
+	headers := make(map[string]string, len(cacheContent.Headers))
+	for k, v := range cacheContent.Headers {
+		if k != "content-length" && k != "content-encoding" {
+			headers[k] = v
+		}
+	}
+	return util.ResponseStream(c.Request().Context(), c, orgRepo, headers, bytes.NewReader(content), nil)
+}
+
+// DatasetsServer 代理datasets-server的数据集预览接口，endpoint为splits、rows、parquet等，远端的错误响应原样返回。
+func (m *MetaService) DatasetsServer(c echo.Context, endpoint string) error {
+	dataset := c.QueryParam("dataset")
+	org, repo, found := strings.Cut(dataset, "/")
+	if !found {
+		org, repo = "", org
+	}
+	if repo == "" || util.ValidatePathParam("org", org) != nil || util.ValidatePathParam("repo", repo) != nil {
+		return util.ErrorRequestParam(c)
+	}
+	authorization := c.Request().Header.Get("authorization")
+	query := util.DatasetsServerQuery(c.QueryParams())
+	cacheContent, err := m.metaDao.DatasetsServer(c.Request().Context(), endpoint, dataset, query, authorization)
+	if err != nil {
+		zap.S().Warnf("datasets-server %s %s err.%v", endpoint, query, err)
+		return util.ResponseHfError(c, err)
+	}
+	for k, v := range cacheContent.Headers {
+		if k != "content-length" && k != "content-encoding" && k != "transfer-encoding" {
+			c.Response().Header().Set(k, v)
 		}
 	}
-	var bodyStreamChan = make(chan []byte, consts.RespChanSize)
-	bodyStreamChan <- cacheContent.OriginContent
-	close(bodyStreamChan)
-	return util.ResponseStream(context.Background(), c, orgRepo, cacheContent.Headers, bodyStreamChan, nil)
+	statusCode := cacheContent.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	return c.Blob(statusCode, echo.MIMEApplicationJSON, cacheContent.OriginContent)
 }
 
+func dropPullRequests(content []byte) ([]byte, error) {
+	var refs map[string]interface{}
+	if err := sonic.Unmarshal(content, &refs); err != nil {
+		return nil, err
+	}
+	if _, ok := refs["pullRequests"]; !ok {
+		return content, nil
+	}
+	delete(refs, "pullRequests")
+	return sonic.Marshal(refs)
+}
+
+// ForwardToNewSite 原样转发未适配的远端接口，命中forward.cacheRules的GET请求经缓存返回。
 func (m *MetaService) ForwardToNewSite(c echo.Context) error {
-	zap.S().Infof("ForwardToNewSite url:%s", c.Request().URL.Path)
+	reqPath := c.Request().URL.Path
+	zap.S().Infof("ForwardToNewSite url:%s", reqPath)
+	if !config.SysConfig.ApiFeatureEnabled(consts.ApiFeatureForward) {
+		return util.ErrorPageNotFound(c)
+	}
+	if rule := config.SysConfig.GetForwardCacheRule(reqPath); rule != nil && c.Request().Method == http.MethodGet {
+		cacheContent, err := m.metaDao.ForwardCached(c, rule)
+		if err != nil {
+			zap.S().Warnf("forward cached %s err.%v", reqPath, err)
+			return util.ResponseHfError(c, err)
+		}
+		for k, v := range cacheContent.Headers {
+			if k == "link" {
+				v = rewriteForwardLink(reqPath, v)
+			}
+			if k != "content-length" && k != "content-encoding" && k != "transfer-encoding" {
+				c.Response().Header().Set(k, v)
+			}
+		}
+		contentType := cacheContent.Headers["content-type"]
+		if contentType == "" {
+			contentType = echo.MIMEOctetStream
+		}
+		statusCode := cacheContent.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+		return c.Blob(statusCode, contentType, cacheContent.OriginContent)
+	}
 	resp, err := m.metaDao.ForwardRefs(c)
 	if err != nil {
 		zap.S().Errorf("forward request refs err.%v", err)
@@ -117,116 +234,243 @@ func (m *MetaService) ForwardToNewSite(c echo.Context) error {
 	}
 	defer resp.Body.Close()
 
-	// 获取当前请求路径
-	var flag bool
-	reqPath := c.Request().URL.Path
-	if strings.Contains(reqPath, "/tree/") && strings.Contains(reqPath, "/api/") {
-		flag = true
+	maxBodySize := config.SysConfig.Forward.MaxBodySize
+	if maxBodySize > 0 && resp.ContentLength > maxBodySize {
+		zap.S().Warnf("forward %s response size %d exceeds %d", reqPath, resp.ContentLength, maxBodySize)
+		return util.ErrorProxyError(c)
 	}
 	response := c.Response()
 	for k, v := range resp.Header {
-		if flag && k == "Link" {
-			originalLink := strings.Join(v, ", ")
-			newLink := strings.ReplaceAll(
-				originalLink,
-				"https://huggingface.co",
-				config.SysConfig.Scheduler.LinkDomain,
-			)
-			response.Header()[k] = []string{newLink}
+		if k == "Link" {
+			response.Header()[k] = []string{rewriteForwardLink(reqPath, strings.Join(v, ", "))}
 		} else {
 			response.Header()[k] = v
 		}
 	}
 	response.WriteHeader(resp.StatusCode)
-	_, err = io.Copy(response, resp.Body)
+	var body io.Reader = resp.Body
+	if maxBodySize > 0 {
+		body = io.LimitReader(resp.Body, maxBodySize)
+	}
+	_, err = io.Copy(response, body)
 	if err != nil {
 		return util.ErrorProxyError(c)
 	}
+	if maxBodySize > 0 {
+		if n, _ := resp.Body.Read(make([]byte, 1)); n > 0 {
+			// 响应头已发出，中断连接让客户端感知内容不完整
+			zap.S().Warnf("forward %s response exceeds %d bytes, abort", reqPath, maxBodySize)
+			panic(http.ErrAbortHandler)
+		}
+	}
 	return nil
 }
 
-func (m *MetaService) RepositoryFiles(repoType, orgRepo, commit, filePath string) ([]*FileDescribe, error) {
-	pathsInfoShaDir := fmt.Sprintf("%s/api/%s/%s/paths-info/%s", config.SysConfig.Repos(), repoType, orgRepo, commit)
-	if filePath != "" {
-		pathsInfoShaDir += fmt.Sprintf("/%s", filePath)
+// rewriteForwardLink tree接口的分页Link指向远端域名，改写为配置的链接域名。
+func rewriteForwardLink(reqPath, link string) string {
+	if !strings.Contains(reqPath, "/tree/") || !strings.Contains(reqPath, "/api/") {
+		return link
 	}
-	downloadLinkRoot := fmt.Sprintf("%s/%s/%s/resolve/%s", config.SysConfig.Scheduler.PublicDomain, repoType, orgRepo, commit)
-	if b := util.FileExists(pathsInfoShaDir); !b {
-		log.Warnf("pathsInfoShaDir is not exitst.%s", pathsInfoShaDir)
-		return nil, fmt.Errorf("file not exists")
+	return strings.ReplaceAll(link, "https://huggingface.co", config.SysConfig.Scheduler.LinkDomain)
+}
+
+// RepositoryFiles 列出某版本下已缓存的文件，recursive为true时列出filePath下的全部层级，
+// 可按glob及大小过滤、按名称/大小/修改时间排序，limit大于0或携带cursor时分页返回，第二个返回值为下一页的cursor，为空表示已到末尾。
+func (m *MetaService) RepositoryFiles(c echo.Context, repoType, orgRepo, commit, filePath string, req *query.RepoFilesReq) ([]*FileDescribe, string, error) {
+	order, err := parseFileOrder(req.Sort, !req.Recursive)
+	if err != nil {
+		return nil, "", err
 	}
-	if files, err := util.ReadDir(pathsInfoShaDir); err != nil {
-		log.Warnf("ReadDir %s , %s error.%v", orgRepo, pathsInfoShaDir, err)
-		return nil, err
-	} else {
-		fileDescribes := make([]*FileDescribe, 0)
-		filePathName := ""
-		for _, item := range files {
-			fileDescribe, err := m.analysisFile(pathsInfoShaDir, filePath, item)
-			if err != nil {
-				zap.S().Errorf("analysisFile err.%v", err)
+	index, err := m.metaDao.RepoFilesIndex(repoType, orgRepo, commit)
+	if err != nil {
+		log.Warnf("RepoFilesIndex %s/%s err.%v", orgRepo, commit, err)
+		return nil, "", err
+	}
+	downloadLink := util.FileLinkFunc(c, repoType, orgRepo, commit)
+	previewLinkRoot := fmt.Sprintf("%s%s/api/%s/%s/preview/%s", util.RequestBaseURL(c), util.PathPrefix(c), repoType, orgRepo, commit)
+	prefix := ""
+	if filePath != "" {
+		prefix = strings.TrimSuffix(filePath, "/") + "/"
+	}
+	// 设置了过滤条件时只返回文件
+	filtering := req.Glob != "" || req.MinSize > 0 || req.MaxSize > 0
+	matched := false
+	fileDescribes := make([]*FileDescribe, 0)
+	for _, entry := range index {
+		if !strings.HasPrefix(entry.Path, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(entry.Path, prefix)
+		if !req.Recursive && strings.Contains(name, "/") {
+			continue
+		}
+		matched = true
+		isDir := entry.Type == "directory"
+		if filtering {
+			if isDir || (req.MinSize > 0 && entry.Size < req.MinSize) || (req.MaxSize > 0 && entry.Size > req.MaxSize) {
 				continue
 			}
-			if !fileDescribe.IsDir {
-				if filePath != "" {
-					filePathName = fmt.Sprintf("%s/%s", filePath, item)
-				} else {
-					filePathName = item
-				}
-				fileDescribe.Link = fmt.Sprintf("%s/%s", downloadLinkRoot, filePathName)
+			if ok, err := matchFileGlob(req.Glob, name); err != nil {
+				return nil, "", myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid glob: %v", err))
+			} else if !ok {
+				continue
+			}
+		}
+		fileDescribe := &FileDescribe{Name: name, Size: entry.Size, IsDir: isDir, path: entry.Path}
+		if !fileDescribe.IsDir {
+			fileDescribe.Oid = entry.Oid
+			fileDescribe.Lfs = entry.Lfs.Oid != ""
+			fileDescribe.Sha256 = entry.Lfs.Oid
+			if entry.LastCommit != nil {
+				fileDescribe.LastModified = entry.LastCommit.Date
 			}
-			fileDescribes = append(fileDescribes, fileDescribe)
+			fileDescribe.Link = downloadLink(entry.Path)
+			if util.IsPreviewable(entry.Path) {
+				fileDescribe.PreviewLink = fmt.Sprintf("%s/%s", previewLinkRoot, url.QueryEscape(entry.Path))
+			}
+		}
+		fileDescribes = append(fileDescribes, fileDescribe)
+	}
+	if !matched && prefix != "" {
+		return nil, "", myerr.NewAppendCode(http.StatusNotFound, "file not exists")
+	}
+	filesDir := util.ResolveDir(repoType, orgRepo, commit)
+	if order.key == "mtime" {
+		// 按修改时间排序需要先取得全部文件的本地状态
+		for _, fileDescribe := range fileDescribes {
+			fillCacheState(filesDir, fileDescribe)
+		}
+	}
+	sort.Slice(fileDescribes, func(i, j int) bool {
+		return order.less(fileDescribes[i], fileDescribes[j])
+	})
+	page, nextCursor, err := pageFiles(fileDescribes, req.Cursor, req.Limit, order)
+	if err != nil {
+		return nil, "", err
+	}
+	if order.key != "mtime" {
+		// 仅对当前页检查本地缓存状态，避免大仓库逐个打开缓存文件
+		for _, fileDescribe := range page {
+			fillCacheState(filesDir, fileDescribe)
 		}
-		sortNodes(fileDescribes)
-		return fileDescribes, nil
 	}
+	return page, nextCursor, nil
 }
 
-func sortNodes(nodes []*FileDescribe) {
-	sort.Slice(nodes, func(i, j int) bool {
-		// 目录排在文件前面
-		if nodes[i].IsDir && !nodes[j].IsDir {
-			return true
+func fillCacheState(filesDir string, fileDescribe *FileDescribe) {
+	if fileDescribe.IsDir {
+		return
+	}
+	filesPath := filepath.Join(filesDir, util.CachePath(fileDescribe.path))
+	info, err := os.Stat(filesPath)
+	if err != nil {
+		return
+	}
+	if fileDescribe.LastModified == "" {
+		fileDescribe.LastModified = info.ModTime().UTC().Format(time.RFC3339)
+	}
+	if fileSize, cachedSize, ok := downloader.CachedSize(filesPath); ok {
+		fileDescribe.CachedSize = cachedSize
+		fileDescribe.Cached = fileSize > 0 && cachedSize == fileSize
+	}
+}
+
+// matchFileGlob 不含"/"的模式只匹配文件名，如*.safetensors可匹配任意层级下的分片
+func matchFileGlob(pattern, name string) (bool, error) {
+	if pattern == "" {
+		return true, nil
+	}
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	return path.Match(pattern, name)
+}
+
+// fileOrder 列表的排序规则，key为name、size或mtime，非递归列出时目录在前，名称作为最后的排序依据以保证顺序唯一。
+type fileOrder struct {
+	key       string
+	desc      bool
+	dirsFirst bool
+}
+
+// parseFileOrder 解析sort参数，如size或-size，"-"前缀表示倒序
+func parseFileOrder(sortParam string, dirsFirst bool) (fileOrder, error) {
+	order := fileOrder{key: "name", dirsFirst: dirsFirst}
+	if sortParam == "" {
+		return order, nil
+	}
+	order.desc = strings.HasPrefix(sortParam, "-")
+	order.key = strings.TrimPrefix(sortParam, "-")
+	switch order.key {
+	case "name", "size", "mtime":
+		return order, nil
+	default:
+		return order, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid sort: %s", sortParam))
+	}
+}
+
+func (o fileOrder) less(a, b *FileDescribe) bool {
+	if o.dirsFirst && a.IsDir != b.IsDir {
+		return a.IsDir
+	}
+	switch o.key {
+	case "size":
+		if a.Size != b.Size {
+			return (a.Size < b.Size) != o.desc
 		}
-		if !nodes[i].IsDir && nodes[j].IsDir {
-			return false
+	case "mtime":
+		if a.LastModified != b.LastModified {
+			return (a.LastModified < b.LastModified) != o.desc
 		}
-		// 同是目录或同是文件，按名称正序排列
-		return nodes[i].Name < nodes[j].Name
-	})
+	}
+	if o.key == "name" && o.desc {
+		return a.Name > b.Name
+	}
+	return a.Name < b.Name
+}
+
+// fileCursor 上一页最后一项的排序字段，文件增删后仍能从正确位置继续。
+type fileCursor struct {
+	IsDir        bool   `json:"d"`
+	Name         string `json:"n"`
+	Size         int64  `json:"s"`
+	LastModified string `json:"m"`
 }
 
-func (m *MetaService) analysisFile(pathInfoShaDir, filePath, fileName string) (*FileDescribe, error) {
-	pathInfoPath := fmt.Sprintf("%s/%s/paths-info_post.json", pathInfoShaDir, fileName)
-	fileDescribe := &FileDescribe{
-		Name: fileName,
+// pageFiles 按cursor截取一页，files需已按order排序。
+func pageFiles(files []*FileDescribe, cursor string, limit int, order fileOrder) ([]*FileDescribe, string, error) {
+	if cursor == "" && limit <= 0 {
+		return files, "", nil
 	}
-	if exist := util.FileExists(pathInfoPath); exist {
-		fileDescribe.IsDir = false
-		cacheContent, err := m.fileDao.ReadCacheRequest(pathInfoPath)
-		if err != nil {
-			log.Errorf(fmt.Sprintf("read file:%s err", pathInfoPath))
-			return nil, err
+	if limit <= 0 || limit > consts.RepoFilesMaxLimit {
+		limit = consts.RepoFilesMaxLimit
+	}
+	start := 0
+	if cursor != "" {
+		var last fileCursor
+		b, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			err = sonic.Unmarshal(b, &last)
 		}
-		remoteRespPathsInfos := make([]common.PathsInfo, 0)
-		err = sonic.Unmarshal(cacheContent.OriginContent, &remoteRespPathsInfos)
 		if err != nil {
-			log.Errorf("remoteRespPathsInfos Unmarshal err.%v", err)
-			return nil, err
+			return nil, "", myerr.NewAppendCode(http.StatusBadRequest, "invalid cursor")
 		}
-		if filePath != "" {
-			fileName = fmt.Sprintf("%s/%s", filePath, fileName)
-		}
-		for _, item := range remoteRespPathsInfos {
-			if item.Path == fileName {
-				fileDescribe.Size = item.Size
-				break
-			}
-		}
-	} else {
-		fileDescribe.IsDir = true
+		lastFile := &FileDescribe{IsDir: last.IsDir, Name: last.Name, Size: last.Size, LastModified: last.LastModified}
+		start = sort.Search(len(files), func(i int) bool {
+			return order.less(lastFile, files[i])
+		})
+	}
+	end := min(start+limit, len(files))
+	page := files[start:end]
+	if end >= len(files) {
+		return page, "", nil
 	}
-	return fileDescribe, nil
+	last := page[len(page)-1]
+	b, err := sonic.Marshal(&fileCursor{IsDir: last.IsDir, Name: last.Name, Size: last.Size, LastModified: last.LastModified})
+	if err != nil {
+		return nil, "", err
+	}
+	return page, base64.RawURLEncoding.EncodeToString(b), nil
 }
 
 type FileDescribe struct {
@@ -234,4 +478,249 @@ type FileDescribe struct {
 	Size  int64  `json:"size"`
 	IsDir bool   `json:"isDir"`
 	Link  string `json:"link"`
+	// 文本文件的预览地址
+	PreviewLink string `json:"previewLink,omitempty"`
+	Oid         string `json:"oid,omitempty"`    // git blob的oid
+	Lfs         bool   `json:"lfs"`              // 是否为LFS文件
+	Sha256      string `json:"sha256,omitempty"` // LFS文件内容的sha256
+	// 远端返回了最近一次提交时为提交时间，否则为本地缓存文件的修改时间
+	LastModified string `json:"lastModified,omitempty"`
+	Cached       bool   `json:"cached"`     // 文件是否已完整缓存到本地
+	CachedSize   int64  `json:"cachedSize"` // 已缓存的字节数
+	path         string
+}
+
+func (m *MetaService) LocalRevisions(repoType, orgRepo string) ([]*query.LocalRevisionResp, error) {
+	return m.metaDao.LocalRevisions(repoType, orgRepo)
+}
+
+// Revalidate 远端请求预算用尽时刷新排队到下一个预算窗口执行。
+func (m *MetaService) Revalidate(c echo.Context, repoType, orgRepo string) (*query.RevalidateResp, error) {
+	authorization := c.Request().Header.Get("authorization")
+	key := fmt.Sprintf("revalidate/%s/%s", repoType, orgRepo)
+	if authorization != "" {
+		key = fmt.Sprintf("%s@%s", key, util.TokenHash(authorization))
+	}
+	queued := util.QueueUpstreamRefresh(key, func() {
+		if _, err := m.metaDao.Revalidate(repoType, orgRepo, authorization); err != nil {
+			zap.S().Warnf("queued revalidate %s/%s err.%v", repoType, orgRepo, err)
+		}
+	})
+	if queued {
+		return &query.RevalidateResp{
+			Refs:      make([]*query.RefChange, 0),
+			Revisions: make([]*query.RevisionChange, 0),
+			PathsInfo: make([]*query.PathsInfoChange, 0),
+			Queued:    true,
+		}, nil
+	}
+	return m.metaDao.Revalidate(repoType, orgRepo, authorization)
+}
+
+func (m *MetaService) RepoDiff(c echo.Context, repoType, orgRepo, revision string) (*query.RepoDiffResp, error) {
+	return m.metaDao.RepoDiff(c.Request().Context(), repoType, orgRepo, revision, c.Request().Header.Get("authorization"))
+}
+
+func (m *MetaService) RepoSize(c echo.Context, repoType, orgRepo, revision string) (*query.RepoSizeResp, error) {
+	return m.metaDao.RepoSize(repoType, orgRepo, revision, c.Request().Header.Get("authorization"))
+}
+
+func (m *MetaService) Provenance(c echo.Context, repoType, orgRepo, revision, fileName string) (*query.ProvenanceResp, error) {
+	return m.metaDao.Provenance(repoType, orgRepo, revision, fileName, c.Request().Header.Get("authorization"))
+}
+
+func (m *MetaService) PickleScan(c echo.Context, repoType, orgRepo, revision string) (*query.PickleScanResp, error) {
+	return m.metaDao.PickleScan(repoType, orgRepo, revision, c.QueryParam("path"), c.Request().Header.Get("authorization"))
+}
+
+func (m *MetaService) Attestation(c echo.Context, repoType, orgRepo, revision string) (*query.AttestationResp, error) {
+	return m.metaDao.Attestation(repoType, orgRepo, revision, c.Request().Header.Get("authorization"))
+}
+
+func (m *MetaService) AttestationKey() (*query.AttestationKeyResp, error) {
+	key, err := util.AttestationKey()
+	if err != nil {
+		return nil, err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	publicKey, err := util.AttestationPublicKeyPEM(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &query.AttestationKeyResp{KeyId: util.AttestationKeyId(pub), Algorithm: "ed25519", PublicKey: publicKey}, nil
+}
+
+// Metalink 生成仓库某版本全部文件的下载清单，format为metalink或aria2，下载地址指向本镜像。
+func (m *MetaService) Metalink(c echo.Context, repoType, orgRepo, revision, format string) ([]byte, string, error) {
+	commitSha, pathsInfos, err := m.metaDao.RepoPathsInfos(repoType, orgRepo, revision, c.Request().Header.Get("authorization"))
+	if err != nil {
+		return nil, "", err
+	}
+	downloadLink := util.FileLinkFunc(c, repoType, orgRepo, commitSha)
+	files := make([]*util.MetalinkFile, 0, len(pathsInfos))
+	for _, pathsInfo := range pathsInfos {
+		files = append(files, &util.MetalinkFile{
+			Name:   pathsInfo.Path,
+			Size:   pathsInfo.Size,
+			Sha256: pathsInfo.Lfs.Oid, // 非LFS文件的oid为git对象哈希，不能用于校验文件内容
+			Url:    downloadLink(pathsInfo.Path),
+		})
+	}
+	if format == "aria2" {
+		return util.BuildAria2Input(files), commitSha, nil
+	}
+	content, err := util.BuildMetalink(files)
+	return content, commitSha, err
+}
+
+// Archive 返回仓库某版本全部文件的归档清单，所有文件都必须已完整缓存。
+func (m *MetaService) Archive(c echo.Context, repoType, orgRepo, revision string) ([]*util.ArchiveFile, string, error) {
+	return m.archiveFiles(repoType, orgRepo, revision, c.Request().Header.Get("authorization"), "")
+}
+
+// ArchiveDir 返回仓库某版本dir目录下的全部文件，目录下没有文件时返回404。
+func (m *MetaService) ArchiveDir(c echo.Context, repoType, orgRepo, revision, dir string) ([]*util.ArchiveFile, string, error) {
+	files, commitSha, err := m.archiveFiles(repoType, orgRepo, revision, c.Request().Header.Get("authorization"), dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) == 0 {
+		return nil, "", myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("%s is not found in %s@%s", dir, orgRepo, revision))
+	}
+	return files, commitSha, nil
+}
+
+// archiveFiles dir不为空时只返回该目录下的文件，目录外的文件不要求已缓存。
+func (m *MetaService) archiveFiles(repoType, orgRepo, revision, authorization, dir string) ([]*util.ArchiveFile, string, error) {
+	commitSha, pathsInfos, err := m.metaDao.RepoPathsInfos(repoType, orgRepo, revision, authorization)
+	if err != nil {
+		return nil, "", err
+	}
+	filesDir := util.ResolveDir(repoType, orgRepo, commitSha)
+	files := make([]*util.ArchiveFile, 0, len(pathsInfos))
+	for _, pathsInfo := range pathsInfos {
+		if dir != "" && !strings.HasPrefix(pathsInfo.Path, dir+"/") {
+			continue
+		}
+		filePath, fileSize, err := fullyCachedFile(filesDir, pathsInfo)
+		if err != nil {
+			return nil, "", err
+		}
+		files = append(files, &util.ArchiveFile{
+			Name:   pathsInfo.Path,
+			Size:   fileSize,
+			Sha256: pathsInfo.Lfs.Oid, // 仅LFS文件的oid为内容的sha256
+			Open: func() (io.ReadCloser, error) {
+				return downloader.OpenCachedFile(filePath)
+			},
+		})
+	}
+	return files, commitSha, nil
+}
+
+// CreateShare 为已完整缓存的文件或快照生成签名的分享链接，revision解析为commit，链接指向的内容不随分支变化。
+func (m *MetaService) CreateShare(c echo.Context, req *query.ShareReq) (*query.ShareResp, error) {
+	secret := config.SysConfig.Share.Secret
+	if secret == "" {
+		return nil, myerr.NewAppendCode(http.StatusServiceUnavailable, "share is not enabled")
+	}
+	expiration := config.SysConfig.GetShareExpiration()
+	if req.Expiration > 0 {
+		expiration = time.Duration(req.Expiration) * time.Second
+	}
+	if expiration > config.SysConfig.GetShareMaxExpiration() {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("expiration exceeds %s", config.SysConfig.GetShareMaxExpiration()))
+	}
+	orgRepo := util.GetOrgRepo(req.Org, req.Repo)
+	authorization := c.Request().Header.Get("authorization")
+	revision := req.Revision
+	if revision == "" {
+		revision = "main"
+	}
+	var commitSha string
+	if req.FilePath == "" {
+		_, sha, err := m.archiveFiles(req.RepoType, orgRepo, revision, authorization, "")
+		if err != nil {
+			return nil, err
+		}
+		commitSha = sha
+	} else {
+		sha, err := m.fileDao.GetFileCommitSha(c.Request().Context(), req.RepoType, orgRepo, revision, authorization, "meta")
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err = fullyCachedFile(util.ResolveDir(req.RepoType, orgRepo, sha), &common.PathsInfo{Path: req.FilePath, Size: -1}); err != nil {
+			return nil, err
+		}
+		commitSha = sha
+	}
+	if err := m.fileDao.CheckRepoPolicy(req.RepoType, orgRepo, commitSha, authorization); err != nil {
+		return nil, err
+	}
+	expireAt := time.Now().Add(expiration)
+	sig := util.SignShare(secret, util.ShareScope(req.RepoType, orgRepo, commitSha, req.FilePath), expireAt.Unix())
+	sharePath := util.SharePath(req.RepoType, req.Org, req.Repo, commitSha, req.FilePath)
+	return &query.ShareResp{
+		Url:      fmt.Sprintf("%s%s%s?expires=%d&sig=%s", util.RequestBaseURL(c), util.PathPrefix(c), sharePath, expireAt.Unix(), sig),
+		Commit:   commitSha,
+		ExpireAt: expireAt,
+	}, nil
+}
+
+// SharedFile 打开分享的文件，返回其内容及大小，文件必须已完整缓存。
+func (m *MetaService) SharedFile(repoType, orgRepo, commit, fileName string) (io.ReadCloser, int64, error) {
+	if err := m.fileDao.CheckRepoPolicy(repoType, orgRepo, commit, ""); err != nil {
+		return nil, 0, err
+	}
+	filePath, fileSize, err := fullyCachedFile(util.ResolveDir(repoType, orgRepo, commit), &common.PathsInfo{Path: fileName, Size: -1})
+	if err != nil {
+		return nil, 0, err
+	}
+	reader, err := downloader.OpenCachedFile(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reader, fileSize, nil
+}
+
+// SharedArchive 返回分享的快照的归档清单，分享链接无需认证，只读取公共缓存。
+func (m *MetaService) SharedArchive(repoType, orgRepo, commit string) ([]*util.ArchiveFile, error) {
+	if err := m.fileDao.CheckRepoPolicy(repoType, orgRepo, commit, ""); err != nil {
+		return nil, err
+	}
+	files, _, err := m.archiveFiles(repoType, orgRepo, commit, "", "")
+	return files, err
+}
+
+func (m *MetaService) SyncManifest(c echo.Context, repoType, orgRepo, revision string) (*query.SyncManifest, error) {
+	return m.metaDao.SyncManifest(repoType, orgRepo, revision, c.Request().Header.Get("authorization"))
+}
+
+// ModelCard 渲染已缓存仓库的README，仓库未缓存或README不存在时转发远端。
+func (m *MetaService) ModelCard(c echo.Context, repoType, org, repo string) error {
+	orgRepo := util.GetOrgRepo(org, repo)
+	apiDir := util.ApiRepoDir(util.GetApiRoot(""), repoType, orgRepo)
+	if !strings.Contains(c.Request().Header.Get("Accept"), "text/html") || !util.FileExists(apiDir) {
+		return m.ForwardToNewSite(c)
+	}
+	authorization := c.Request().Header.Get("authorization")
+	content, _, err := m.fileDao.ReadFileRange(c.Request().Context(), repoType, orgRepo, "main", "README.md", authorization, 0, consts.MaxModelCardSize-1)
+	if err != nil {
+		zap.S().Warnf("read %s/%s README.md err.%v", repoType, orgRepo, err)
+		return m.ForwardToNewSite(c)
+	}
+	metadata, body := util.SplitFrontMatter(string(content))
+	metaItems := make([]map[string]string, 0, len(metadata))
+	for k, v := range metadata {
+		metaItems = append(metaItems, map[string]string{"key": k, "value": fmt.Sprintf("%v", v)})
+	}
+	sort.Slice(metaItems, func(i, j int) bool {
+		return metaItems[i]["key"] < metaItems[j]["key"]
+	})
+	return c.Render(http.StatusOK, "model_card.html", map[string]interface{}{
+		"repo_type": repoType,
+		"org_repo":  orgRepo,
+		"metadata":  metaItems,
+		"content":   template.HTML(util.RenderMarkdown(body)),
+	})
 }
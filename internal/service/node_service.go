@@ -0,0 +1,119 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dingospeed/internal/downloader"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/proto/node"
+	"dingospeed/pkg/util"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const blobChunkSize = 1 << 20 // 单条消息携带的数据量，需小于gRPC默认4MB的消息上限
+
+// NodeService 节点间gRPC服务的实现，供其他dingospeed节点查询和复制本地缓存。
+type NodeService struct {
+	node.UnimplementedNodeServer
+}
+
+func NewNodeService() *NodeService {
+	return &NodeService{}
+}
+
+func (s *NodeService) LookupBlob(ctx context.Context, req *node.LookupBlobRequest) (*node.LookupBlobResponse, error) {
+	blobsFile, err := nodeBlobsFile(req.DataType, req.Org, req.Repo, req.Etag)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !util.FileExists(blobsFile) {
+		return &node.LookupBlobResponse{}, nil
+	}
+	fileSize, cachedSize, ok := downloader.CachedSize(blobsFile)
+	if !ok {
+		return &node.LookupBlobResponse{}, nil
+	}
+	return &node.LookupBlobResponse{
+		Exists:     true,
+		FileSize:   fileSize,
+		CachedSize: cachedSize,
+		Complete:   fileSize > 0 && cachedSize == fileSize,
+	}, nil
+}
+
+func (s *NodeService) ReadBlob(req *node.ReadBlobRequest, stream grpc.ServerStreamingServer[node.BlobChunk]) error {
+	blobsFile, err := nodeBlobsFile(req.DataType, req.Org, req.Repo, req.Etag)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !util.FileExists(blobsFile) {
+		return status.Errorf(codes.NotFound, "blob %s is not cached", req.Etag)
+	}
+	fileSize, _, ok := downloader.CachedSize(blobsFile)
+	if !ok {
+		return status.Errorf(codes.NotFound, "blob %s is not cached", req.Etag)
+	}
+	endPos := req.EndPos
+	if endPos <= 0 || endPos > fileSize {
+		endPos = fileSize
+	}
+	if req.StartPos < 0 || req.StartPos > endPos {
+		return status.Errorf(codes.OutOfRange, "invalid range %d-%d of %d", req.StartPos, req.EndPos, fileSize)
+	}
+	for pos := req.StartPos; pos < endPos; {
+		if err = stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		end := min(pos+blobChunkSize, endPos)
+		data, _, ok := downloader.ReadCachedRange(blobsFile, pos, end-1)
+		if !ok {
+			// 对端据此回退到远端下载剩余部分
+			return status.Errorf(codes.NotFound, "blob %s is not cached at offset %d", req.Etag, pos)
+		}
+		if err = stream.Send(&node.BlobChunk{Offset: pos, Data: data}); err != nil {
+			return err
+		}
+		pos = end
+	}
+	return nil
+}
+
+func (s *NodeService) Heartbeat(ctx context.Context, req *node.NodeHeartbeatRequest) (*node.NodeHeartbeatResponse, error) {
+	return &node.NodeHeartbeatResponse{
+		InstanceId: config.SysConfig.Scheduler.Discovery.InstanceId,
+		Online:     config.SysConfig.Online(),
+		Version:    config.SystemInfo.Version,
+		Timestamp:  time.Now().Unix(),
+	}, nil
+}
+
+func nodeBlobsFile(dataType, org, repo, etag string) (string, error) {
+	if dataType == "" || repo == "" || etag == "" {
+		return "", fmt.Errorf("dataType, repo and etag are required")
+	}
+	for name, value := range map[string]string{"repoType": dataType, "org": org, "repo": repo, "etag": etag} {
+		if err := util.ValidatePathParam(name, value); err != nil {
+			return "", err
+		}
+	}
+	return util.BlobsFile(dataType, util.GetOrgRepo(org, repo), etag), nil
+}
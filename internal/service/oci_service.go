@@ -0,0 +1,190 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/internal/dao"
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/oci"
+	"dingospeed/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+// OciService 将已完整缓存的仓库版本打包为OCI制品（与oras兼容），推送到镜像仓库或导出为OCI布局目录。
+type OciService struct {
+	metaDao *dao.MetaDao
+	jobs    *common.SafeMap[int64, *query.OciExportJob]
+	jobSeq  atomic.Int64
+	jobMu   sync.Mutex // 保护任务状态的更新与读取
+}
+
+func NewOciService(metaDao *dao.MetaDao) *OciService {
+	return &OciService{
+		metaDao: metaDao,
+		jobs:    common.NewSafeMap[int64, *query.OciExportJob](),
+	}
+}
+
+// Export 校验参数及缓存完整性后异步执行导出，返回任务信息。
+func (o *OciService) Export(req *query.OciExportReq, authorization string) (*query.OciExportJob, error) {
+	if (req.Reference == "") == (req.LayoutDir == "") {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "one of reference and layoutDir is required")
+	}
+	orgRepo := util.GetOrgRepo(req.Org, req.Repo)
+	if req.Revision == "" {
+		req.Revision = "main"
+	}
+	commitSha, pathsInfos, err := o.metaDao.RepoPathsInfos(req.Datatype, orgRepo, req.Revision, authorization)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := cachedLayers(req.Datatype, orgRepo, commitSha, pathsInfos)
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{
+		oci.AnnotationSource:   fmt.Sprintf("%s/%s/%s", config.SysConfig.GetHFURLBase(), req.Datatype, orgRepo),
+		oci.AnnotationRevision: commitSha,
+		oci.AnnotationCreated:  time.Now().UTC().Format(time.RFC3339),
+	}
+	job := &query.OciExportJob{
+		Datatype:   req.Datatype,
+		OrgRepo:    orgRepo,
+		Commit:     commitSha,
+		Status:     consts.OciExportRunning,
+		TotalFiles: len(layers),
+		StartTime:  time.Now(),
+	}
+	var run func() (string, error)
+	if req.Reference != "" {
+		host, repository, tag, err := oci.ParseReference(req.Reference)
+		if err != nil {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+		}
+		if tag == "" {
+			tag = commitSha
+		}
+		job.Target = fmt.Sprintf("%s/%s:%s", host, repository, tag)
+		registry := &oci.Registry{Host: host, Repository: repository, Username: req.Username, Password: req.Password, PlainHTTP: req.PlainHttp}
+		run = func() (string, error) {
+			return registry.Push(context.Background(), tag, layers, annotations, func(*oci.Layer) {
+				o.updateJob(func() { job.DoneFiles++ })
+			})
+		}
+	} else {
+		if err = util.ValidatePathParam("filePath", req.LayoutDir); err != nil {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+		}
+		tag := req.Tag
+		if tag == "" {
+			tag = commitSha
+		}
+		layoutDir := filepath.Join(config.SysConfig.Repos(), consts.OciExportDir, req.LayoutDir)
+		job.Target = fmt.Sprintf("%s:%s", layoutDir, tag)
+		run = func() (string, error) {
+			return oci.WriteLayout(layoutDir, tag, layers, annotations)
+		}
+	}
+	job.Id = o.jobSeq.Add(1)
+	o.jobs.Set(job.Id, job)
+	jobCopy := *job
+	go func() {
+		zap.S().Infof("oci export %s/%s@%s to %s start", job.Datatype, job.OrgRepo, job.Commit, job.Target)
+		digest, err := run()
+		if err != nil {
+			zap.S().Errorf("oci export %s/%s@%s to %s err.%v", job.Datatype, job.OrgRepo, job.Commit, job.Target, err)
+		} else {
+			zap.S().Infof("oci export %s/%s@%s to %s done, digest:%s", job.Datatype, job.OrgRepo, job.Commit, job.Target, digest)
+		}
+		o.updateJob(func() {
+			job.EndTime = time.Now()
+			if err != nil {
+				job.Err = err.Error()
+				job.Status = consts.OciExportFailed
+				return
+			}
+			job.Digest = digest
+			job.DoneFiles = job.TotalFiles
+			job.Status = consts.OciExportSuccess
+		})
+	}()
+	return &jobCopy, nil
+}
+
+func (o *OciService) ExportJob(id int64) (*query.OciExportJob, error) {
+	job, ok := o.jobs.Get(id)
+	if !ok {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("oci export job %d is not exist", id))
+	}
+	o.jobMu.Lock()
+	defer o.jobMu.Unlock()
+	jobCopy := *job
+	return &jobCopy, nil
+}
+
+func (o *OciService) updateJob(f func()) {
+	o.jobMu.Lock()
+	defer o.jobMu.Unlock()
+	f()
+}
+
+// 每个文件作为一层，所有文件都必须已完整缓存；LFS文件的oid即内容的sha256，其余文件在打包时计算。
+func cachedLayers(repoType, orgRepo, commitSha string, pathsInfos []*common.PathsInfo) ([]*oci.Layer, error) {
+	filesDir := util.ResolveDir(repoType, orgRepo, commitSha)
+	layers := make([]*oci.Layer, 0, len(pathsInfos))
+	for _, pathsInfo := range pathsInfos {
+		filePath, fileSize, err := fullyCachedFile(filesDir, pathsInfo)
+		if err != nil {
+			return nil, err
+		}
+		layer := &oci.Layer{
+			Name: pathsInfo.Path,
+			Size: fileSize,
+			Open: func() (io.ReadCloser, error) {
+				return downloader.OpenCachedFile(filePath)
+			},
+		}
+		if pathsInfo.Lfs.Oid != "" {
+			layer.Digest = "sha256:" + pathsInfo.Lfs.Oid
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// fullyCachedFile 返回已完整缓存的文件路径及大小，未缓存或缓存不完整时返回409。
+func fullyCachedFile(filesDir string, pathsInfo *common.PathsInfo) (string, int64, error) {
+	filePath := filepath.Join(filesDir, util.CachePath(pathsInfo.Path))
+	fileSize, cachedSize, ok := downloader.CachedSize(filePath)
+	if !ok || fileSize != cachedSize || (pathsInfo.Size >= 0 && pathsInfo.Size != fileSize) {
+		return "", 0, myerr.NewErrorCode(http.StatusConflict, consts.HfErrorCodeEntryNotFound, fmt.Sprintf("%s is not fully cached", pathsInfo.Path))
+	}
+	return filePath, fileSize, nil
+}
@@ -0,0 +1,203 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+
+	"dingospeed/internal/dao"
+	"dingospeed/pkg/app"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// PrefetchService 客户端下载未缓存版本的config.json等文件时，通常随后会下载整个快照，提前在后台预取其余文件。
+type PrefetchService struct {
+	fileDao *dao.FileDao
+	metaDao *dao.MetaDao
+	mu      sync.Mutex
+	running map[string]struct{}
+}
+
+func NewPrefetchService(fileDao *dao.FileDao, metaDao *dao.MetaDao) *PrefetchService {
+	return &PrefetchService{
+		fileDao: fileDao,
+		metaDao: metaDao,
+		running: make(map[string]struct{}),
+	}
+}
+
+// Trigger 需在下载文件之前调用，版本已有缓存文件或该版本正在预取时不重复触发。
+func (p *PrefetchService) Trigger(c echo.Context, repoType, orgRepo, commit, fileName string) {
+	prefetch := config.SysConfig.Prefetch
+	if !prefetch.Enabled || !isPrefetchTrigger(prefetch.Triggers, fileName) {
+		return
+	}
+	if util.IsDir(util.ResolveDir(repoType, orgRepo, commit)) {
+		return
+	}
+	key := fmt.Sprintf("%s/%s/%s", repoType, orgRepo, commit)
+	p.mu.Lock()
+	if _, ok := p.running[key]; ok {
+		p.mu.Unlock()
+		return
+	}
+	p.running[key] = struct{}{}
+	p.mu.Unlock()
+	ctx := context.Background()
+	if appInfo, ok := app.FromContext(c.Request().Context()); ok {
+		ctx = appInfo.Ctx()
+	}
+	authorization := c.Request().Header.Get("authorization")
+	go func() {
+		defer func() {
+			p.mu.Lock()
+			delete(p.running, key)
+			p.mu.Unlock()
+		}()
+		p.prefetch(ctx, repoType, orgRepo, commit, fileName, authorization)
+	}()
+}
+
+// TriggerPathsInfo 在线返回仓库信息后调用，在后台缓存该版本全部文件的paths-info，同一版本正在处理时不重复触发。
+func (p *PrefetchService) TriggerPathsInfo(c echo.Context, repoType, orgRepo, revision string) {
+	if !config.SysConfig.Prefetch.PathsInfo || !config.SysConfig.Online() {
+		return
+	}
+	authorization := c.Request().Header.Get("authorization")
+	key := fmt.Sprintf("pathsInfo/%s/%s/%s/%s", repoType, orgRepo, revision, util.TokenHash(authorization))
+	p.mu.Lock()
+	if _, ok := p.running[key]; ok {
+		p.mu.Unlock()
+		return
+	}
+	p.running[key] = struct{}{}
+	p.mu.Unlock()
+	ctx := context.Background()
+	if appInfo, ok := app.FromContext(c.Request().Context()); ok {
+		ctx = appInfo.Ctx()
+	}
+	go func() {
+		defer func() {
+			p.mu.Lock()
+			delete(p.running, key)
+			p.mu.Unlock()
+		}()
+		n, err := p.metaDao.PrefetchPathsInfo(ctx, repoType, orgRepo, revision, authorization)
+		if err != nil {
+			zap.S().Warnf("prefetch paths-info %s/%s/%s err.%v", repoType, orgRepo, revision, err)
+			return
+		}
+		if n > 0 {
+			zap.S().Infof("prefetch paths-info %s/%s/%s done, %d files", repoType, orgRepo, revision, n)
+		}
+	}()
+}
+
+func (p *PrefetchService) prefetch(ctx context.Context, repoType, orgRepo, commit, triggerFile, authorization string) {
+	prefetch := config.SysConfig.Prefetch
+	metadata, err := p.metaDao.GetMetadata(repoType, orgRepo, commit, consts.RequestTypeGet, authorization)
+	if err != nil {
+		zap.S().Warnf("prefetch %s/%s/%s get metadata err.%v", repoType, orgRepo, commit, err)
+		return
+	}
+	var sha dao.CommitHfSha
+	if err = sonic.Unmarshal(metadata.OriginContent, &sha); err != nil {
+		zap.S().Warnf("prefetch %s/%s/%s unmarshal metadata err.%v", repoType, orgRepo, commit, err)
+		return
+	}
+	if prefetch.MaxTotalSize > 0 && sha.UsedStorage > prefetch.MaxTotalSize {
+		zap.S().Infof("skip prefetch %s/%s/%s, used storage %d exceeds %d", repoType, orgRepo, commit, sha.UsedStorage, prefetch.MaxTotalSize)
+		return
+	}
+	zap.S().Infof("start prefetch %s/%s/%s triggered by %s, %d files", repoType, orgRepo, commit, triggerFile, len(sha.Siblings))
+	var total int64
+	for _, sibling := range sha.Siblings {
+		if ctx.Err() != nil {
+			return
+		}
+		fileName := sibling.Rfilename
+		if fileName == triggerFile || !prefetchMatch(prefetch.Include, prefetch.Exclude, fileName) {
+			continue
+		}
+		n, err := p.fileDao.PrefetchFile(ctx, repoType, orgRepo, commit, fileName, authorization, prefetch.MaxFileSize)
+		if err != nil {
+			zap.S().Warnf("prefetch %s/%s/%s/%s err.%v", repoType, orgRepo, commit, fileName, err)
+			continue
+		}
+		total += n
+	}
+	zap.S().Infof("prefetch %s/%s/%s done, downloaded %d bytes", repoType, orgRepo, commit, total)
+}
+
+// PrefetchRevision 下载仓库某版本的全部文件，已缓存的文件跳过，返回版本的commit及下载的字节数。
+func (p *PrefetchService) PrefetchRevision(ctx context.Context, repoType, orgRepo, revision, authorization string) (string, int64, error) {
+	metadata, err := p.metaDao.GetMetadata(repoType, orgRepo, revision, consts.RequestTypeGet, authorization)
+	if err != nil {
+		return "", 0, err
+	}
+	var sha dao.CommitHfSha
+	if err = sonic.Unmarshal(metadata.OriginContent, &sha); err != nil {
+		return "", 0, err
+	}
+	var total int64
+	for _, sibling := range sha.Siblings {
+		if err = ctx.Err(); err != nil {
+			return sha.Sha, total, err
+		}
+		n, err := p.fileDao.PrefetchFile(ctx, repoType, orgRepo, sha.Sha, sibling.Rfilename, authorization, 0)
+		if err != nil {
+			return sha.Sha, total, fmt.Errorf("prefetch %s err.%v", sibling.Rfilename, err)
+		}
+		total += n
+	}
+	return sha.Sha, total, nil
+}
+
+func isPrefetchTrigger(triggers []string, fileName string) bool {
+	name := path.Base(fileName)
+	for _, trigger := range triggers {
+		if trigger == name {
+			return true
+		}
+	}
+	return false
+}
+
+// prefetchMatch include为空时匹配所有文件，exclude优先。
+func prefetchMatch(include, exclude []string, fileName string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := matchFileGlob(pattern, fileName); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if ok, _ := matchFileGlob(pattern, fileName); ok {
+			return true
+		}
+	}
+	return false
+}
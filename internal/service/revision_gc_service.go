@@ -0,0 +1,240 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/internal/dao"
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/lock"
+	"dingospeed/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+// 最近有写入的blob可能正在下载，不参与本轮回收
+const revisionGcGracePeriod = time.Hour
+
+var revisionGcOnce sync.Once
+
+// RevisionGcService 每个仓库保留最新的N个版本，删除仅被更早版本引用的blob及这些版本的目录，
+// 分支、标签当前指向的版本及固定的版本不会被回收。
+type RevisionGcService struct {
+	metaDao *dao.MetaDao
+	mu      sync.Mutex
+}
+
+type gcRevision struct {
+	sha          string
+	aliases      []string
+	lastModified int64
+}
+
+func NewRevisionGcService(metaDao *dao.MetaDao) *RevisionGcService {
+	gcSvc := &RevisionGcService{metaDao: metaDao}
+	revisionGcOnce.Do(func() {
+		if config.SysConfig.RevisionGc.Enabled {
+			go gcSvc.cycleRevisionGc()
+		}
+	})
+	return gcSvc
+}
+
+func (r *RevisionGcService) cycleRevisionGc() {
+	ticker := time.NewTicker(config.SysConfig.GetRevisionGcInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// 共享缓存卷时只由leader副本回收
+			if lock.IsLeader() {
+				r.Run(false)
+			}
+		}
+	}
+}
+
+// Run 对所有已缓存的仓库执行回收，dryRun为true时只统计可回收的内容，不做删除。
+func (r *RevisionGcService) Run(dryRun bool) *query.RevisionGcResp {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resp := &query.RevisionGcResp{DryRun: dryRun, Repos: make([]*query.RevisionGcRepo, 0)}
+	filesRoot := filepath.Join(config.SysConfig.Repos(), "files")
+	for _, pattern := range []string{"*/*/resolve", "*/*/*/resolve"} {
+		resolveDirs, _ := filepath.Glob(filepath.Join(filesRoot, pattern))
+		for _, resolveDir := range resolveDirs {
+			rel, err := filepath.Rel(filesRoot, filepath.Dir(resolveDir))
+			if err != nil {
+				continue
+			}
+			repoType, orgRepo, _ := strings.Cut(util.CacheName(rel), "/")
+			result := r.gcRepo(repoType, orgRepo, dryRun)
+			if result == nil {
+				continue
+			}
+			resp.Repos = append(resp.Repos, result)
+			resp.Blobs += result.Blobs
+			resp.Size += result.Size
+		}
+	}
+	zap.S().Infof("revision gc finished, dryRun %t, repos %d, blobs %d, size %s", dryRun, len(resp.Repos), resp.Blobs, util.ConvertBytesToHumanReadable(resp.Size))
+	return resp
+}
+
+// gcRepo 回收单个仓库，没有可回收的版本时返回nil。
+func (r *RevisionGcService) gcRepo(repoType, orgRepo string, dryRun bool) *query.RevisionGcRepo {
+	revisions := r.localRevisions(repoType, orgRepo)
+	keep := config.SysConfig.GetRevisionGcKeep()
+	if len(revisions) <= keep {
+		return nil
+	}
+	expired := make([]*gcRevision, 0)
+	for _, revision := range revisions[keep:] {
+		if len(revision.aliases) == 0 && !util.IsRevisionPinned(repoType, orgRepo, append(revision.aliases, revision.sha)...) {
+			expired = append(expired, revision)
+		}
+	}
+	return r.removeRevisions(repoType, orgRepo, expired, dryRun)
+}
+
+// PurgeRevisions 删除仓库中最后更新早于before的版本，不受保留个数限制，固定的版本除外，没有可删除的版本时返回nil。
+func (r *RevisionGcService) PurgeRevisions(repoType, orgRepo string, before time.Time, dryRun bool) *query.RevisionGcRepo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expired := make([]*gcRevision, 0)
+	for _, revision := range r.localRevisions(repoType, orgRepo) {
+		if revision.lastModified < before.Unix() && !util.IsRevisionPinned(repoType, orgRepo, append(revision.aliases, revision.sha)...) {
+			expired = append(expired, revision)
+		}
+	}
+	return r.removeRevisions(repoType, orgRepo, expired, dryRun)
+}
+
+// removeRevisions 删除版本目录及仅被这些版本引用的blob，最近有写入blob的版本跳过。
+func (r *RevisionGcService) removeRevisions(repoType, orgRepo string, expired []*gcRevision, dryRun bool) *query.RevisionGcRepo {
+	if len(expired) == 0 {
+		return nil
+	}
+
+	// 按引用计数预估，回收版本的引用全部去掉后引用数为0的blob才会被删除
+	refs := util.BlobRefCounts(repoType, orgRepo)
+	result := &query.RevisionGcRepo{Datatype: repoType, OrgRepo: orgRepo, Revisions: make([]string, 0)}
+	for _, revision := range expired {
+		links := util.ResolveLinks(util.ResolveDir(repoType, orgRepo, revision.sha))
+		remaining := maps.Clone(refs)
+		for _, blob := range links {
+			remaining[blob]--
+		}
+		blobs := make(map[string]int64)
+		recent := false
+		for _, blob := range links {
+			if remaining[blob] > 0 || blobs[blob] > 0 {
+				continue
+			}
+			info, err := os.Stat(blob)
+			if err != nil {
+				continue
+			}
+			if time.Since(info.ModTime()) < revisionGcGracePeriod {
+				recent = true
+				break
+			}
+			blobs[blob] = info.Size()
+		}
+		if recent {
+			zap.S().Infof("revision %s of %s/%s has recently written blobs, skip gc", revision.sha, repoType, orgRepo)
+			continue
+		}
+		refs = remaining
+		for _, size := range blobs {
+			result.Size += size
+			result.Blobs++
+		}
+		result.Revisions = append(result.Revisions, revision.sha)
+		if dryRun {
+			continue
+		}
+		for link := range links {
+			blob, count, err := util.RemoveBlobRef(link)
+			if err != nil {
+				zap.S().Warnf("remove %s err.%v", link, err)
+				continue
+			}
+			if blob != "" && count == 0 {
+				if err = os.Remove(blob); err != nil && !os.IsNotExist(err) {
+					zap.S().Warnf("remove blob %s err.%v", blob, err)
+				}
+			}
+		}
+		apiDir := util.ApiRepoDir(util.GetApiRoot(""), repoType, orgRepo)
+		for _, dir := range []string{
+			util.ResolveDir(repoType, orgRepo, revision.sha),
+			filepath.Join(apiDir, "revision", util.CachePath(revision.sha)),
+			util.ApiPathsInfoDir(util.GetApiRoot(""), repoType, orgRepo, revision.sha),
+		} {
+			if err := os.RemoveAll(dir); err != nil {
+				zap.S().Warnf("remove %s err.%v", dir, err)
+			}
+		}
+	}
+	if len(result.Revisions) == 0 {
+		return nil
+	}
+	zap.S().Infof("revision gc %s/%s, revisions %v, blobs %d, size %s, dryRun %t", repoType, orgRepo, result.Revisions, result.Blobs, util.ConvertBytesToHumanReadable(result.Size), dryRun)
+	if !dryRun {
+		events.Publish(events.CacheEvict, map[string]interface{}{"reason": "revisionGc", "datatype": repoType, "orgRepo": orgRepo, "revisions": result.Revisions, "size": result.Size})
+	}
+	return result
+}
+
+// localRevisions 返回仓库已缓存文件的版本，按最后更新时间倒序，更新时间取元数据缓存的时间，没有元数据时取目录的时间。
+func (r *RevisionGcService) localRevisions(repoType, orgRepo string) []*gcRevision {
+	entries, err := os.ReadDir(filepath.Join(util.RepoFilesDir(repoType, orgRepo), "resolve"))
+	if err != nil {
+		return nil
+	}
+	metaRevisions := make(map[string]*query.LocalRevisionResp)
+	if localRevisions, err := r.metaDao.LocalRevisions(repoType, orgRepo); err == nil {
+		for _, localRevision := range localRevisions {
+			metaRevisions[localRevision.Sha] = localRevision
+		}
+	}
+	revisions := make([]*gcRevision, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		revision := &gcRevision{sha: util.CacheName(entry.Name())}
+		if localRevision, ok := metaRevisions[revision.sha]; ok {
+			revision.aliases, revision.lastModified = localRevision.Aliases, localRevision.LastModified
+		} else if info, err := entry.Info(); err == nil {
+			revision.lastModified = info.ModTime().Unix()
+		}
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].lastModified > revisions[j].lastModified
+	})
+	return revisions
+}
@@ -16,4 +16,4 @@ package service
 
 import "github.com/google/wire"
 
-var ServiceProvider = wire.NewSet(NewFileService, NewMetaService, NewSysService, NewSchedulerService, NewCacheJobService, NewLocalOperationService, NewModelscopeService)
+var ServiceProvider = wire.NewSet(NewFileService, NewMetaService, NewSysService, NewSchedulerService, NewCacheJobService, NewLocalOperationService, NewModelscopeService, NewAdminService, NewUploadService, NewOciService, NewNodeService, NewPrefetchService, NewTrashService, NewRevisionGcService, NewSyncService, NewCurationService, NewBatchService, NewCompactionService, NewBundleService)
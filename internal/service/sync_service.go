@@ -0,0 +1,149 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"dingospeed/internal/dao"
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model/query"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// SyncService 从其他镜像实例同步仓库的版本，先交换版本清单，只传输本地缺少的blob，
+// 新版本与已缓存版本大部分文件相同时无需重复传输。
+type SyncService struct {
+	fileDao *dao.FileDao
+	metaDao *dao.MetaDao
+}
+
+func NewSyncService(fileDao *dao.FileDao, metaDao *dao.MetaDao) *SyncService {
+	return &SyncService{fileDao: fileDao, metaDao: metaDao}
+}
+
+func (s *SyncService) Sync(ctx context.Context, req *query.SyncReq) (*query.SyncResp, error) {
+	if req.Source == "" || req.RepoType == "" || req.Repo == "" || req.Revision == "" {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "source, repoType, repo and revision are required")
+	}
+	for name, value := range map[string]string{"repoType": req.RepoType, "org": req.Org, "repo": req.Repo, "revision": req.Revision} {
+		if err := util.ValidatePathParam(name, value); err != nil {
+			return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+		}
+	}
+	orgRepo := util.GetOrgRepo(req.Org, req.Repo)
+	manifest, err := s.fetchManifest(ctx, req.Source, req.RepoType, orgRepo, req.Revision)
+	if err != nil {
+		return nil, err
+	}
+	resp := &query.SyncResp{Commit: manifest.Commit, Files: len(manifest.Files), Unavailable: make([]string, 0)}
+	for _, file := range manifest.Files {
+		if file.PathsInfo == nil || file.PathsInfo.Size < 0 || file.Etag == "" {
+			resp.Unavailable = append(resp.Unavailable, syncFileName(file))
+			continue
+		}
+		if err = util.ValidatePathParam("filePath", file.PathsInfo.Path); err != nil {
+			return nil, myerr.NewAppendCode(http.StatusBadGateway, fmt.Sprintf("invalid file path from source: %v", err))
+		}
+		blobsFile := util.BlobsFile(req.RepoType, orgRepo, file.Etag)
+		if fileSize, cachedSize, ok := downloader.CachedSize(blobsFile); ok && fileSize == file.PathsInfo.Size && cachedSize == fileSize {
+			resp.Skipped++
+		} else if !file.Complete {
+			resp.Unavailable = append(resp.Unavailable, file.PathsInfo.Path)
+			continue
+		} else {
+			if err = s.transferBlob(ctx, req.Source, req.RepoType, orgRepo, manifest.Commit, file, blobsFile); err != nil {
+				return nil, err
+			}
+			resp.Transferred++
+			resp.TransferredBytes += file.PathsInfo.Size
+		}
+		filesPath := util.ResolveFile(req.RepoType, orgRepo, manifest.Commit, file.PathsInfo.Path)
+		if err = s.fileDao.ConstructBlobsAndFileFile(blobsFile, filesPath); err != nil {
+			return nil, err
+		}
+	}
+	// 文件就绪后再写入元数据，同步中断时本地不会出现指向不完整文件的版本
+	if err = s.metaDao.SaveSyncManifest(req.RepoType, orgRepo, req.Revision, manifest); err != nil {
+		return nil, err
+	}
+	zap.S().Infof("sync %s/%s@%s from %s done, files:%d, skipped:%d, transferred:%d(%s), unavailable:%d", req.RepoType, orgRepo, manifest.Commit,
+		req.Source, resp.Files, resp.Skipped, resp.Transferred, util.ConvertBytesToHumanReadable(resp.TransferredBytes), len(resp.Unavailable))
+	return resp, nil
+}
+
+func (s *SyncService) fetchManifest(ctx context.Context, source, repoType, orgRepo, revision string) (*query.SyncManifest, error) {
+	uri := fmt.Sprintf("/api/%s/%s/manifest/%s", repoType, orgRepo, util.EscapeRevision(revision))
+	manifest := &query.SyncManifest{}
+	err := util.GetPeerStream(ctx, source, uri, map[string]string{}, func(r *http.Response) error {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if r.StatusCode != http.StatusOK {
+			return myerr.NewAppendCode(r.StatusCode, fmt.Sprintf("fetch manifest from %s: %s", source, strings.TrimSpace(string(body))))
+		}
+		return sonic.Unmarshal(body, manifest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Commit == "" {
+		return nil, myerr.NewAppendCode(http.StatusBadGateway, fmt.Sprintf("invalid manifest from %s", source))
+	}
+	return manifest, nil
+}
+
+// transferBlob 从对端下载文件写入blob，LFS文件校验内容的sha256，校验失败时删除写入的blob。
+func (s *SyncService) transferBlob(ctx context.Context, source, repoType, orgRepo, commit string, file *query.SyncFile, blobsFile string) error {
+	uri := fmt.Sprintf("/%s/%s/resolve/%s/%s", repoType, orgRepo, commit, file.PathsInfo.Path)
+	if repoType == "models" {
+		uri = fmt.Sprintf("/%s/resolve/%s/%s", orgRepo, commit, file.PathsInfo.Path)
+	}
+	return util.GetPeerStream(ctx, source, uri, map[string]string{}, func(r *http.Response) error {
+		if r.StatusCode != http.StatusOK {
+			return myerr.NewAppendCode(http.StatusBadGateway, fmt.Sprintf("download %s from %s: status %d", file.PathsInfo.Path, source, r.StatusCode))
+		}
+		hash := sha256.New()
+		if err := downloader.ImportBlobReader(io.TeeReader(r.Body, hash), blobsFile, file.PathsInfo.Size); err != nil {
+			return err
+		}
+		if sum := hex.EncodeToString(hash.Sum(nil)); file.PathsInfo.Lfs.Oid != "" && sum != file.PathsInfo.Lfs.Oid {
+			if err := os.Remove(blobsFile); err != nil {
+				zap.S().Warnf("remove %s err.%v", blobsFile, err)
+			}
+			return myerr.NewAppendCode(http.StatusBadGateway, fmt.Sprintf("sha256 of %s mismatch, expected %s, got %s", file.PathsInfo.Path, file.PathsInfo.Lfs.Oid, sum))
+		}
+		return nil
+	})
+}
+
+func syncFileName(file *query.SyncFile) string {
+	if file.PathsInfo == nil {
+		return ""
+	}
+	return file.PathsInfo.Path
+}
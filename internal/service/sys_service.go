@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,7 +13,12 @@ import (
 	"time"
 
 	"dingospeed/internal/dao"
+	"dingospeed/internal/downloader"
+	"dingospeed/internal/model"
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/lock"
 	"dingospeed/pkg/proto/manager"
 	"dingospeed/pkg/util"
 
@@ -33,6 +39,9 @@ func NewSysService(schedulerDao *dao.SchedulerDao) *SysService {
 	}
 	once.Do(
 		func() {
+			if config.SysConfig.EnableSharedVolume() {
+				lock.StartLeaderElection(context.Background(), lock.Default(), filepath.Join(config.SysConfig.Repos(), consts.LeaderLockName), config.SysConfig.GetLeaseTTL())
+			}
 			if config.SysConfig.EnableReadBlockCache() {
 				go sysSvc.MemoryUsed()
 			}
@@ -43,6 +52,15 @@ func NewSysService(schedulerDao *dao.SchedulerDao) *SysService {
 			if config.SysConfig.DynamicProxy.HttpProxyConnTest {
 				go sysSvc.cycleTestProxyConnectivity()
 			}
+			if config.SysConfig.EnableCacheIndex() {
+				go sysSvc.cycleBuildCacheIndex()
+			}
+			if config.SysConfig.UpstreamProbe.Enabled && config.SysConfig.Server.Online {
+				go sysSvc.cycleProbeUpstream()
+			}
+			if config.SysConfig.UpstreamBudget.Enabled {
+				go sysSvc.cycleCheckUpstreamBudget()
+			}
 		})
 	return sysSvc
 }
@@ -72,13 +90,25 @@ func (s *SysService) cycleCheckDiskUsage() {
 	for {
 		select {
 		case <-ticker.C:
-			s.checkDiskUsage()
+			s.checkDiskUsageWithLease(0)
+		case needed := <-util.DiskSpaceRequests():
+			zap.S().Infof("disk space is insufficient for download, try to free %s", util.ConvertBytesToHumanReadable(needed))
+			s.checkDiskUsageWithLease(needed)
 		}
 	}
 }
 
-// 检查磁盘使用情况
-func (s *SysService) checkDiskUsage() {
+// 共享缓存卷时，只有leader副本执行磁盘清理。
+func (s *SysService) checkDiskUsageWithLease(needed int64) {
+	if !lock.IsLeader() {
+		zap.S().Debugf("skip disk clean, not leader")
+		return
+	}
+	s.checkDiskUsage(needed)
+}
+
+// 检查磁盘使用情况，needed大于0时表示下载因磁盘空间不足被拒绝，需额外释放的字节数
+func (s *SysService) checkDiskUsage(needed int64) {
 	if !config.SysConfig.Online() {
 		return
 	}
@@ -87,6 +117,12 @@ func (s *SysService) checkDiskUsage() {
 	}
 
 	baseRepoPath := config.SysConfig.Repos()
+	filesPath := filepath.Join(baseRepoPath, "files")
+	// 命名空间的配额独立检查，与全局是否超限无关
+	for i := range config.SysConfig.DiskClean.Namespaces {
+		s.checkNamespaceUsage(&config.SysConfig.DiskClean.Namespaces[i], baseRepoPath, filesPath)
+	}
+
 	currentSize, err := util.GetFolderSize(baseRepoPath)
 	if err != nil {
 		zap.S().Errorf("Error getting folder size: %v", err)
@@ -94,6 +130,9 @@ func (s *SysService) checkDiskUsage() {
 	}
 
 	limitSize := config.SysConfig.DiskClean.CacheSizeLimit
+	if needed > 0 {
+		limitSize = min(limitSize, currentSize-needed)
+	}
 	limitSizeH := util.ConvertBytesToHumanReadable(limitSize)
 	currentSizeH := util.ConvertBytesToHumanReadable(currentSize)
 
@@ -104,60 +143,125 @@ func (s *SysService) checkDiskUsage() {
 	zap.S().Infof("Cache size exceeded! Limit: %s, Current: %s.\n", limitSizeH, currentSizeH)
 	zap.S().Infof("Cleaning...")
 
-	filesPath := filepath.Join(config.SysConfig.Repos(), "files")
-	var allFiles []util.FileWithPath
-	switch config.SysConfig.CacheCleanStrategy() {
-	case "LRU":
-		allFiles, err = util.SortFilesByAccessTime(filesPath)
-		if err != nil {
-			zap.S().Errorf("Error sorting files by access time in %s: %v\n", filesPath, err)
-			return
-		}
-	case "FIFO":
-		allFiles, err = util.SortFilesByModifyTime(filesPath)
-		if err != nil {
-			zap.S().Errorf("Error sorting files by modify time in %s: %v\n", filesPath, err)
-			return
-		}
-	case "LARGE_FIRST":
-		allFiles, err = util.SortFilesBySize(filesPath)
-		if err != nil {
-			zap.S().Errorf("Error sorting files by size in %s: %v\n", filesPath, err)
-			return
+	allFiles, err := sortCacheFiles(filesPath, config.SysConfig.CacheCleanStrategy())
+	if err != nil {
+		zap.S().Errorf("Error sorting files in %s: %v\n", filesPath, err)
+		return
+	}
+	// 全局清理不删除命名空间内的文件
+	files := make([]util.FileWithPath, 0, len(allFiles))
+	for _, file := range allFiles {
+		if cacheNamespace(filesPath, file.Path) == nil {
+			files = append(files, file)
 		}
-	default:
-		zap.S().Errorf("Unknown cache clean strategy: %s\n", config.SysConfig.CacheCleanStrategy())
+	}
+	s.removeCacheFiles(baseRepoPath, files, currentSize, limitSize)
+
+	currentSize, err = util.GetFolderSize(config.SysConfig.Repos())
+	if err != nil {
+		zap.S().Errorf("Error getting folder size after cleaning: %v\n", err)
 		return
 	}
+	currentSizeH = util.ConvertBytesToHumanReadable(currentSize)
+	zap.S().Infof("Cleaning finished. Limit: %s, Current: %s.\n", limitSizeH, currentSizeH)
+}
 
-	instanceID := config.SysConfig.Scheduler.Discovery.InstanceId
+// 按命名空间的配额和清理策略清理其中的文件
+func (s *SysService) checkNamespaceUsage(ns *config.CacheNamespace, baseRepoPath, filesPath string) {
+	if ns.CacheSizeLimit <= 0 {
+		return
+	}
+	allFiles, err := sortCacheFiles(filesPath, ns.GetCleanStrategy())
+	if err != nil {
+		zap.S().Errorf("Error sorting files of namespace %s: %v", ns.Name, err)
+		return
+	}
+	var (
+		files       []util.FileWithPath
+		currentSize int64
+	)
 	for _, file := range allFiles {
+		if cacheNamespace(filesPath, file.Path) == ns {
+			files = append(files, file)
+			currentSize += file.Info.Size()
+		}
+	}
+	if currentSize < ns.CacheSizeLimit {
+		return
+	}
+	limitSizeH := util.ConvertBytesToHumanReadable(ns.CacheSizeLimit)
+	zap.S().Infof("Namespace %s cache size exceeded! Limit: %s, Current: %s.", ns.Name, limitSizeH, util.ConvertBytesToHumanReadable(currentSize))
+	currentSize = s.removeCacheFiles(baseRepoPath, files, currentSize, ns.CacheSizeLimit)
+	zap.S().Infof("Namespace %s cleaning finished. Limit: %s, Current: %s.", ns.Name, limitSizeH, util.ConvertBytesToHumanReadable(currentSize))
+}
+
+// 按顺序删除文件直到低于限制，返回删除后的大小。
+// 仍被版本引用的blob不直接删除，版本的软链接被清理后引用数为0时随之删除。
+func (s *SysService) removeCacheFiles(baseRepoPath string, files []util.FileWithPath, currentSize, limitSize int64) int64 {
+	for _, file := range files {
 		if currentSize < limitSize {
 			break
 		}
-		filePath := file.Path
-		fileSize := file.Info.Size()
-
-		if s.Client != nil {
-			s.deleteRecordByFilePath(baseRepoPath, filePath, instanceID)
+		filePath, fileSize := file.Path, file.Info.Size()
+		if file.Info.Mode()&os.ModeSymlink != 0 {
+			s.recordCacheFileRemoved(baseRepoPath, filePath)
+			blob, remaining, err := util.RemoveBlobRef(filePath)
+			if err != nil {
+				zap.S().Errorf("Error removing file %s: %v\n", filePath, err)
+				continue
+			}
+			currentSize -= fileSize
+			if blob == "" || remaining > 0 {
+				continue
+			}
+			filePath, fileSize = blob, util.GetFileSize(blob)
+		} else if util.IsBlobFile(filePath) && util.BlobRefCount(filePath) > 0 {
+			continue
 		}
-
-		err := os.Remove(filePath)
-		if err != nil {
-			zap.S().Errorf("Error removing file %s: %v\n", filePath, err)
+		s.recordCacheFileRemoved(baseRepoPath, filePath)
+		if err := os.Remove(filePath); err != nil {
+			if !os.IsNotExist(err) {
+				zap.S().Errorf("Error removing file %s: %v\n", filePath, err)
+			}
 			continue
 		}
 		currentSize -= fileSize
 		zap.S().Infof("Remove file: %s. File Size: %s\n", filePath, util.ConvertBytesToHumanReadable(fileSize))
+		events.Publish(events.CacheEvict, map[string]interface{}{"reason": "diskClean", "path": filePath, "size": fileSize})
 	}
+	return currentSize
+}
 
-	currentSize, err = util.GetFolderSize(config.SysConfig.Repos())
+func (s *SysService) recordCacheFileRemoved(baseRepoPath, filePath string) {
+	if s.Client != nil {
+		s.deleteRecordByFilePath(baseRepoPath, filePath, config.SysConfig.Scheduler.Discovery.InstanceId)
+	}
+}
+
+func sortCacheFiles(filesPath, strategy string) ([]util.FileWithPath, error) {
+	switch strategy {
+	case "LRU":
+		return util.SortFilesByAccessTime(filesPath)
+	case "FIFO":
+		return util.SortFilesByModifyTime(filesPath)
+	case "LARGE_FIRST":
+		return util.SortFilesBySize(filesPath)
+	default:
+		return nil, fmt.Errorf("unknown cache clean strategy: %s", strategy)
+	}
+}
+
+// 文件路径形如files/{repoType}/{org}/{repo}/...，返回其所属的命名空间
+func cacheNamespace(filesPath, filePath string) *config.CacheNamespace {
+	relPath, err := filepath.Rel(filesPath, filePath)
 	if err != nil {
-		zap.S().Errorf("Error getting folder size after cleaning: %v\n", err)
-		return
+		return nil
 	}
-	currentSizeH = util.ConvertBytesToHumanReadable(currentSize)
-	zap.S().Infof("Cleaning finished. Limit: %s, Current: %s.\n", limitSizeH, currentSizeH)
+	parts := strings.Split(util.CacheName(relPath), "/")
+	if len(parts) < 3 {
+		return nil
+	}
+	return config.SysConfig.GetCacheNamespace(parts[0], parts[1])
 }
 
 func (s *SysService) deleteRecordByFilePath(baseRepoPath, filePath, instanceID string) {
@@ -211,6 +315,39 @@ func (s *SysService) cycleTestProxyConnectivity() {
 	}
 }
 
+// cycleProbeUpstream 每个节点独立探测远端，根据探测结果切换本节点的在线状态。
+func (s *SysService) cycleProbeUpstream() {
+	ticker := time.NewTicker(time.Duration(config.SysConfig.UpstreamProbe.Interval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		util.ProbeUpstream()
+	}
+}
+
+// cycleCheckUpstreamBudget 定期检查远端请求预算窗口，窗口重置后恢复在线并执行排队的刷新。
+func (s *SysService) cycleCheckUpstreamBudget() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		util.CheckUpstreamBudget()
+	}
+}
+
+// cycleBuildCacheIndex 启动时建立缓存文件索引，之后定期重建以淘汰已删除的文件。
+func (s *SysService) cycleBuildCacheIndex() {
+	build := func() {
+		if err := util.BuildCacheIndex(config.SysConfig.Repos()); err != nil {
+			zap.S().Errorf("build cache index err.%v", err)
+		}
+	}
+	build()
+	ticker := time.NewTicker(time.Duration(config.SysConfig.Cache.Index.RebuildInterval) * time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		build()
+	}
+}
+
 var (
 	successMsg          = "，当前代理已恢复连通性"
 	failMsg             = "，当前代理无法连接，请检查网络或代理设置"
@@ -300,3 +437,14 @@ func handleContinuousFailure(statusCode int) {
 		hasSentFailureMsg = true // 标记已发送，避免重复发送
 	}
 }
+
+// Readiness 启用minWarmRatio且窗口内请求数足够时，按缓存命中比例判断是否就绪。
+func (s *SysService) Readiness() *model.Readiness {
+	conf := config.SysConfig
+	ratio, total := downloader.WarmRatio()
+	r := &model.Readiness{Ready: true, WarmRatio: ratio, Requests: total, MinWarmRatio: conf.Readiness.MinWarmRatio}
+	if conf.Readiness.MinWarmRatio > 0 && total >= conf.GetReadinessMinRequests() && ratio < conf.Readiness.MinWarmRatio {
+		r.Ready = false
+	}
+	return r
+}
@@ -3,6 +3,7 @@ package task
 import (
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"sync"
 	"sync/atomic"
@@ -11,8 +12,10 @@ import (
 	"dingospeed/internal/dao"
 	"dingospeed/internal/downloader"
 	"dingospeed/internal/model/query"
-	"dingospeed/pkg/config"
+	"dingospeed/pkg/common"
 	"dingospeed/pkg/consts"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/util"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -43,8 +46,12 @@ type PreheatCacheTask struct {
 	DownloaderDao *dao.DownloaderDao
 	UsedStorage   uint64
 	stockLen      atomic.Uint64
+	speedBps      atomic.Uint64
 	StockSpeed    string
 	StockProcess  float32
+	progressLock  sync.RWMutex
+	files         []*query.FileProgress
+	err           string
 }
 
 func (p *PreheatCacheTask) DoTask() {
@@ -54,6 +61,7 @@ func (p *PreheatCacheTask) DoTask() {
 	go p.realTimeSpeed(ctx)
 	err := p.preheatProcess(orgRepo)
 	if err != nil {
+		p.setErr(err)
 		p.SchedulerDao.ExecUpdateCacheJobStatus(p.TaskNo, p.RunningStatus, p.Job.InstanceId, p.Job.Org, p.Job.Repo, err.Error(), p.StockProcess)
 		return
 	}
@@ -63,10 +71,12 @@ func (p *PreheatCacheTask) DoTask() {
 
 func (p *PreheatCacheTask) preheatProcess(orgRepo string) error {
 	limit := make(chan struct{}, 8)
-	for _, rFile := range p.Sha.Siblings {
+	p.initFiles()
+	for i, rFile := range p.Sha.Siblings {
 		if p.Ctx.Err() != nil {
 			return p.Ctx.Err()
 		}
+		fp := p.files[i]
 		fileName := rFile.Rfilename
 		var hfUri string
 		if p.Job.Datatype == "models" {
@@ -78,10 +88,13 @@ func (p *PreheatCacheTask) preheatProcess(orgRepo string) error {
 			p.Authorization, fileName) // 获取模型元数据
 		if err != nil {
 			zap.S().Errorf("RemoteRequestPathsInfo err,%v", err)
+			p.setFileStatus(fp, consts.FileStatusFailed, err)
 			return err
 		}
 		if pathInfo == nil {
-			return fmt.Errorf("RemoteRequestPathsInfo err, pathInfo is null, %s/%s", orgRepo, fileName)
+			err = fmt.Errorf("RemoteRequestPathsInfo err, pathInfo is null, %s/%s", orgRepo, fileName)
+			p.setFileStatus(fp, consts.FileStatusFailed, err)
+			return err
 		}
 		var etag string
 		if pathInfo.Lfs.Oid != "" {
@@ -89,33 +102,99 @@ func (p *PreheatCacheTask) preheatProcess(orgRepo string) error {
 		} else {
 			etag = pathInfo.Oid
 		}
+		p.progressLock.Lock()
+		fp.Size = pathInfo.Size
+		p.progressLock.Unlock()
 		if pathInfo.Size == 0 {
+			p.setFileStatus(fp, consts.FileStatusComplete, nil)
 			continue
 		}
 		offset := p.FileDao.GetFileOffset(p.Job.Datatype, p.Job.Org, p.Job.Repo, etag, pathInfo.Size)
 		if offset > 0 {
 			p.stockLen.Add(uint64(offset))
+			p.addFileDownloaded(fp, offset)
 		}
 		if offset < pathInfo.Size {
 			limit <- struct{}{}
-			if err = p.startPreheat(hfUri, orgRepo, fileName, p.Sha.Sha, etag, p.Authorization, pathInfo.Size, offset); err != nil {
+			p.setFileStatus(fp, consts.FileStatusDownloading, nil)
+			if err = p.startPreheat(hfUri, orgRepo, fileName, p.Sha.Sha, etag, p.Authorization, pathInfo.Size, offset, fp); err != nil {
 				zap.S().Errorf("startPreheat err, %s/%s %v", orgRepo, fileName, err)
+				p.setFileStatus(fp, consts.FileStatusFailed, err)
 				return err
 			}
 			<-limit
 		}
+		p.setFileStatus(fp, consts.FileStatusComplete, nil)
 	}
 	return nil
 }
 
-func (p *PreheatCacheTask) startPreheat(hfUri, orgRepo, fileName, commit, etag, authorization string, fileSize, offset int64) error {
+func (p *PreheatCacheTask) initFiles() {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	p.files = make([]*query.FileProgress, 0, len(p.Sha.Siblings))
+	for _, rFile := range p.Sha.Siblings {
+		p.files = append(p.files, &query.FileProgress{FileName: rFile.Rfilename, Status: consts.FileStatusPending})
+	}
+}
+
+func (p *PreheatCacheTask) setFileStatus(fp *query.FileProgress, status string, err error) {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	fp.Status = status
+	if err != nil {
+		fp.Err = err.Error()
+	}
+	events.Publish(events.JobProgress, map[string]interface{}{"job": "cache", "id": p.TaskNo, "datatype": p.Job.Datatype,
+		"orgRepo": util.GetOrgRepo(p.Job.Org, p.Job.Repo), "fileName": fp.FileName, "status": status, "err": fp.Err})
+}
+
+func (p *PreheatCacheTask) addFileDownloaded(fp *query.FileProgress, n int64) {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	fp.Downloaded += n
+}
+
+func (p *PreheatCacheTask) setErr(err error) {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	p.err = err.Error()
+}
+
+// Progress 返回任务及各文件的下载进度，预计剩余时间根据近期的下载速度估算。
+func (p *PreheatCacheTask) Progress() *query.JobProgressResp {
+	resp := &query.JobProgressResp{
+		CacheJobId:   int64(p.TaskNo),
+		Datatype:     p.Job.Datatype,
+		Org:          p.Job.Org,
+		Repo:         p.Job.Repo,
+		Commit:       p.Sha.Sha,
+		StockSpeed:   p.StockSpeed,
+		StockProcess: p.StockProcess,
+		Eta:          -1,
+	}
+	current := p.stockLen.Load()
+	if speed := p.speedBps.Load(); speed > 0 && p.UsedStorage > current {
+		resp.Eta = int64((p.UsedStorage - current) / speed)
+	} else if p.UsedStorage > 0 && current >= p.UsedStorage {
+		resp.Eta = 0
+	}
+	p.progressLock.RLock()
+	defer p.progressLock.RUnlock()
+	resp.Err = p.err
+	resp.Files = make([]*query.FileProgress, 0, len(p.files))
+	for _, fp := range p.files {
+		f := *fp
+		resp.Files = append(resp.Files, &f)
+	}
+	return resp
+}
+
+func (p *PreheatCacheTask) startPreheat(hfUri, orgRepo, fileName, commit, etag, authorization string, fileSize, offset int64, fp *query.FileProgress) error {
 	var wg sync.WaitGroup
-	bgCtx := context.WithValue(p.Ctx, consts.PromSource, "localhost")
-	responseChan := make(chan []byte, config.SysConfig.Download.RespChanSize)
-	blobsDir := fmt.Sprintf("%s/files/%s/%s/blobs", config.SysConfig.Repos(), p.Job.Datatype, orgRepo)
-	blobsFile := fmt.Sprintf("%s/%s", blobsDir, etag)
-	filesDir := fmt.Sprintf("%s/files/%s/%s/resolve/%s", config.SysConfig.Repos(), p.Job.Datatype, orgRepo, commit)
-	filesPath := fmt.Sprintf("%s/%s", filesDir, fileName)
+	bgCtx := common.WithBackground(context.WithValue(p.Ctx, consts.PromSource, "localhost"))
+	blobsFile := util.BlobsFile(p.Job.Datatype, orgRepo, etag)
+	filesPath := util.ResolveFile(p.Job.Datatype, orgRepo, commit, fileName)
 	if err := p.FileDao.ConstructBlobsAndFileFile(blobsFile, filesPath); err != nil {
 		zap.S().Errorf("ConstructBlobsAndFileFile err.%v", err)
 		return err
@@ -131,13 +210,25 @@ func (p *PreheatCacheTask) startPreheat(hfUri, orgRepo, fileName, commit, etag,
 		DataType:      p.Job.Datatype,
 		Etag:          etag,
 	}
+	// 窗口外不占用下载名额，等到允许后台传输时再排队
+	if err := common.BackgroundLimiter.WaitWindow(bgCtx); err != nil {
+		return err
+	}
+	// 预热属于批量任务，以最低优先级参与下载排队
+	if common.DownloadLimiter != nil {
+		if err := common.DownloadLimiter.Acquire(bgCtx, consts.PriorityLow); err != nil {
+			return err
+		}
+		defer common.DownloadLimiter.Release()
+	}
 	taskParam.Context = bgCtx
-	taskParam.ResponseChan = responseChan
 	taskParam.Cancel = p.CancelFunc
 	wg.Add(2)
 	eg, ctx := errgroup.WithContext(bgCtx)
+	stream := common.NewStreamPipe(ctx)
+	taskParam.Stream = stream
 	eg.Go(func() error {
-		return p.result(ctx, responseChan)
+		return p.result(stream, fp)
 	})
 	eg.Go(func() error {
 		fileErrCh := make(chan error, 1)
@@ -150,16 +241,19 @@ func (p *PreheatCacheTask) startPreheat(hfUri, orgRepo, fileName, commit, etag,
 	return eg.Wait()
 }
 
-func (p *PreheatCacheTask) result(ctx context.Context, responseChan chan []byte) error {
+func (p *PreheatCacheTask) result(stream io.Reader, fp *query.FileProgress) error {
+	buf := make([]byte, 32*1024)
 	for {
-		select {
-		case b, ok := <-responseChan:
-			if !ok {
-				return nil
-			}
-			p.stockLen.Add(uint64(len(b)))
-		case <-ctx.Done():
-			return ctx.Err()
+		n, err := stream.Read(buf)
+		if n > 0 {
+			p.stockLen.Add(uint64(n))
+			p.addFileDownloaded(fp, int64(n))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 	}
 }
@@ -174,6 +268,8 @@ func (p *PreheatCacheTask) realTimeSpeed(ctx context.Context) {
 			currentBytes := p.stockLen.Load()
 			delta := currentBytes - lastBytes
 			p.StockSpeed = formatSpeed(delta, 1*time.Second) // 采样间隔 1 秒
+			// 平滑后的速度用于估算剩余时间
+			p.speedBps.Store((p.speedBps.Load()*4 + delta) / 5)
 			// 计算下载进度（百分比）
 			process := float64(currentBytes) / float64(p.UsedStorage) * 100
 			if process >= 100 {
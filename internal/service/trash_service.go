@@ -0,0 +1,239 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"dingospeed/internal/model/query"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/lock"
+	"dingospeed/pkg/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+var trashOnce sync.Once
+
+// TrashService 管理员删除的仓库缓存先移入回收站，保留期内可恢复，过期后由后台任务永久删除。
+// 回收站条目的目录结构为{repos}/trash/{id}/{entry.json,files,api}。
+type TrashService struct {
+	mu sync.Mutex
+}
+
+func NewTrashService() *TrashService {
+	trashSvc := &TrashService{}
+	trashOnce.Do(func() {
+		go trashSvc.cycleCleanTrash()
+	})
+	return trashSvc
+}
+
+func (t *TrashService) cycleCleanTrash() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// 共享缓存卷时只由leader副本清理
+			if lock.IsLeader() {
+				t.cleanExpired()
+			}
+		}
+	}
+}
+
+func trashRoot() string {
+	return filepath.Join(config.SysConfig.Repos(), consts.TrashDir)
+}
+
+// PurgeRepo 将仓库的文件及元数据缓存移入回收站。
+func (t *TrashService) PurgeRepo(repoType, orgRepo string) (*query.TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	filesDir := util.RepoFilesDir(repoType, orgRepo)
+	apiDir := util.ApiRepoDir(util.GetApiRoot(""), repoType, orgRepo)
+	if !util.IsDir(filesDir) && !util.IsDir(apiDir) {
+		return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("cache of %s/%s is not exist", repoType, orgRepo))
+	}
+
+	now := time.Now()
+	entry := &query.TrashEntry{
+		Id:        strconv.FormatInt(now.UnixNano(), 10),
+		Datatype:  repoType,
+		OrgRepo:   orgRepo,
+		DeletedAt: now,
+		ExpireAt:  now.Add(config.SysConfig.GetTrashRetention()),
+	}
+	if util.IsDir(filesDir) {
+		size, err := util.GetFolderSize(filesDir)
+		if err != nil {
+			zap.S().Warnf("get folder size of %s err.%v", filesDir, err)
+		}
+		entry.Size = size
+	}
+
+	entryDir := filepath.Join(trashRoot(), entry.Id)
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	if err := util.WriteDataToFile(filepath.Join(entryDir, consts.TrashEntryFile), entry); err != nil {
+		os.RemoveAll(entryDir)
+		return nil, myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	if err := moveDirs(map[string]string{filesDir: filepath.Join(entryDir, "files"), apiDir: filepath.Join(entryDir, "api")}); err != nil {
+		os.RemoveAll(entryDir)
+		return nil, myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	util.ResetBlobRefs(repoType, orgRepo)
+	zap.S().Infof("%s/%s is moved to trash %s by admin, size %s", repoType, orgRepo, entry.Id, util.ConvertBytesToHumanReadable(entry.Size))
+	return entry, nil
+}
+
+// RestoreTrash 将回收站条目移回缓存目录，仓库在删除后已被重新缓存时返回冲突。
+func (t *TrashService) RestoreTrash(id string) (*query.TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, err := readTrashEntry(id)
+	if err != nil {
+		return nil, err
+	}
+	filesDir := util.RepoFilesDir(entry.Datatype, entry.OrgRepo)
+	apiDir := util.ApiRepoDir(util.GetApiRoot(""), entry.Datatype, entry.OrgRepo)
+	if util.IsDir(filesDir) || util.IsDir(apiDir) {
+		return nil, myerr.NewAppendCode(http.StatusConflict, fmt.Sprintf("%s/%s is cached again after deletion", entry.Datatype, entry.OrgRepo))
+	}
+	entryDir := filepath.Join(trashRoot(), id)
+	if err = moveDirs(map[string]string{filepath.Join(entryDir, "files"): filesDir, filepath.Join(entryDir, "api"): apiDir}); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	util.ResetBlobRefs(entry.Datatype, entry.OrgRepo)
+	util.AddCacheIndexDir(filesDir)
+	util.AddCacheIndexDir(apiDir)
+	if err = os.RemoveAll(entryDir); err != nil {
+		zap.S().Warnf("remove trash %s err.%v", id, err)
+	}
+	zap.S().Infof("%s/%s is restored from trash %s by admin", entry.Datatype, entry.OrgRepo, id)
+	return entry, nil
+}
+
+func (t *TrashService) DeleteTrash(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := readTrashEntry(id); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(trashRoot(), id)); err != nil {
+		return myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	zap.S().Infof("trash %s is deleted by admin", id)
+	return nil
+}
+
+func (t *TrashService) ListTrash() []*query.TrashEntry {
+	entries := make([]*query.TrashEntry, 0)
+	names, err := os.ReadDir(trashRoot())
+	if err != nil {
+		return entries
+	}
+	for _, name := range names {
+		if !name.IsDir() {
+			continue
+		}
+		entry, err := readTrashEntry(name.Name())
+		if err != nil {
+			zap.S().Warnf("read trash %s err.%v", name.Name(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].DeletedAt.After(entries[j].DeletedAt)
+	})
+	return entries
+}
+
+func (t *TrashService) cleanExpired() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range t.ListTrash() {
+		if now.Before(entry.ExpireAt) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(trashRoot(), entry.Id)); err != nil {
+			zap.S().Errorf("remove expired trash %s err.%v", entry.Id, err)
+			continue
+		}
+		zap.S().Infof("expired trash %s of %s/%s is removed", entry.Id, entry.Datatype, entry.OrgRepo)
+	}
+}
+
+func readTrashEntry(id string) (*query.TrashEntry, error) {
+	if err := util.ValidatePathParam("id", id); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	data, err := util.ReadCacheFile(filepath.Join(trashRoot(), id, consts.TrashEntryFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, myerr.NewAppendCode(http.StatusNotFound, fmt.Sprintf("trash %s is not exist", id))
+		}
+		return nil, myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	entry := new(query.TrashEntry)
+	if err = sonic.Unmarshal(data, entry); err != nil {
+		return nil, myerr.NewAppendCode(http.StatusInternalServerError, err.Error())
+	}
+	return entry, nil
+}
+
+// moveDirs 依次移动存在的目录，失败时将已移动的目录移回原处。
+func moveDirs(moves map[string]string) error {
+	moved := make(map[string]string)
+	for src, dst := range moves {
+		if !util.IsDir(src) {
+			continue
+		}
+		err := os.MkdirAll(filepath.Dir(dst), 0755)
+		if err == nil {
+			err = os.Rename(src, dst)
+		}
+		if err != nil {
+			for movedSrc, movedDst := range moved {
+				if rollbackErr := os.Rename(movedDst, movedSrc); rollbackErr != nil {
+					zap.S().Errorf("move %s back to %s err.%v", movedDst, movedSrc, rollbackErr)
+				}
+			}
+			return err
+		}
+		moved[src] = dst
+	}
+	return nil
+}
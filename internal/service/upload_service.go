@@ -0,0 +1,58 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package service
+
+import (
+	"dingospeed/internal/dao"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type UploadService struct {
+	uploadDao *dao.UploadDao
+}
+
+func NewUploadService(uploadDao *dao.UploadDao) *UploadService {
+	return &UploadService{
+		uploadDao: uploadDao,
+	}
+}
+
+func (u *UploadService) Forward(c echo.Context) error {
+	zap.S().Infof("forward upload request:%s", c.Request().URL.Path)
+	if err := u.uploadDao.Forward(c); err != nil {
+		zap.S().Errorf("forward upload request err.%v", err)
+		return util.ErrorProxyError(c)
+	}
+	return nil
+}
+
+func (u *UploadService) LfsBatch(c echo.Context, repoType, orgRepo string) error {
+	if err := u.uploadDao.LfsBatch(c, repoType, orgRepo); err != nil {
+		zap.S().Errorf("lfs batch %s/%s err.%v", repoType, orgRepo, err)
+		return util.ErrorProxyError(c)
+	}
+	return nil
+}
+
+func (u *UploadService) LfsProxy(c echo.Context, id string) error {
+	if err := u.uploadDao.LfsProxy(c, id); err != nil {
+		zap.S().Errorf("lfs proxy %s err.%v", id, err)
+		return util.ResponseHfError(c, err)
+	}
+	return nil
+}
@@ -10,6 +10,8 @@ import (
 	"syscall"
 	"time"
 
+	"dingospeed/pkg/server"
+
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -85,15 +87,32 @@ func (a *App) Run() error {
 		})
 	}
 	wg.Wait()
+	eg.Go(func() error {
+		a.notifyReady(ctx)
+		return nil
+	})
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, a.opts.sigs...)
 	eg.Go(func() error {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-c:
-			return a.Stop()
+		var watchdog <-chan time.Time
+		if interval := watchdogInterval(); interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			watchdog = ticker.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-c:
+				_ = sdNotify("STOPPING=1")
+				return a.Stop()
+			case <-watchdog:
+				if err := sdNotify("WATCHDOG=1"); err != nil {
+					zap.S().Warnf("systemd watchdog notify err.%v", err)
+				}
+			}
 		}
 	})
 
@@ -102,3 +121,19 @@ func (a *App) Run() error {
 	}
 	return nil
 }
+
+// notifyReady 所有服务监听就绪后通知systemd，缓存索引等在构造服务时已加载完成。
+func (a *App) notifyReady(ctx context.Context) {
+	for _, srv := range a.opts.servers {
+		if r, ok := srv.(server.Readier); ok {
+			select {
+			case <-r.Ready():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	if err := sdNotify("READY=1"); err != nil {
+		zap.S().Warnf("systemd ready notify err.%v", err)
+	}
+}
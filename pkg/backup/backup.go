@@ -0,0 +1,284 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/pkg/consts"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// 备份目标的目录结构：
+//   data/{path}                  与repos下相对路径一致的文件内容
+//   manifests/{time}.json        每次备份的快照清单，记录当时所有文件的大小、修改时间及软链接
+//   manifests/latest.json        最近一次成功备份的清单，下次备份据此只复制变化的文件
+// 清单在所有文件复制完成后才写入，中断的备份不会影响上一次的快照。
+
+const (
+	dataDir        = "data"
+	manifestsDir   = "manifests"
+	latestManifest = "latest.json"
+)
+
+// 需要备份的repos下的条目，租约、回收站、临时文件等不备份
+var backupRoots = []string{"files", "api", consts.LayoutFile}
+
+type Manifest struct {
+	Time  time.Time `json:"time"`
+	Files []*File   `json:"files"`
+}
+
+type File struct {
+	Path    string `json:"path"` // 相对repos的路径，以"/"分隔
+	Size    int64  `json:"size,omitempty"`
+	ModTime int64  `json:"modTime,omitempty"` // 纳秒时间戳
+	Link    string `json:"link,omitempty"`    // 软链接的目标
+	Sha256  string `json:"sha256,omitempty"`  // 文件内容的摘要，恢复时据此校验
+}
+
+type Result struct {
+	Files       int   `json:"files"`
+	Copied      int64 `json:"copied"`
+	CopiedBytes int64 `json:"copiedBytes"`
+	Links       int   `json:"links"`
+}
+
+// Target 备份目标，文件以相对路径写入，软链接只记录在清单中，目标支持时一并创建。
+type Target interface {
+	Put(ctx context.Context, name string, r io.ReaderAt, size int64) error
+	Get(ctx context.Context, name string) (io.ReadCloser, error) // 文件不存在时返回os.ErrNotExist
+	Symlink(ctx context.Context, name, link string) error
+}
+
+// NewTarget 解析备份目标，支持本地目录及s3://bucket/prefix。
+func NewTarget(target string) (Target, error) {
+	if strings.HasPrefix(target, "s3://") {
+		return NewS3TargetFromEnv(target)
+	}
+	if target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	return &DirTarget{Dir: target}, nil
+}
+
+// Run 增量备份repos，与上次备份的清单相比大小或修改时间有变化的文件才复制。
+func Run(ctx context.Context, repos string, target Target, concurrency int) (*Result, error) {
+	prev, err := readLatest(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	prevFiles := make(map[string]*File)
+	if prev != nil {
+		for _, f := range prev.Files {
+			prevFiles[f.Path] = f
+		}
+	}
+
+	manifest := &Manifest{Time: time.Now()}
+	var toCopy []*File
+	for _, root := range backupRoots {
+		err = filepath.WalkDir(filepath.Join(repos, root), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(repos, p)
+			if err != nil {
+				return err
+			}
+			f := &File{Path: filepath.ToSlash(rel)}
+			if d.Type()&fs.ModeSymlink != 0 {
+				if f.Link, err = os.Readlink(p); err != nil {
+					return err
+				}
+				manifest.Files = append(manifest.Files, f)
+				return nil
+			}
+			if !d.Type().IsRegular() || strings.HasSuffix(d.Name(), ".tmp") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			f.Size, f.ModTime = info.Size(), info.ModTime().UnixNano()
+			manifest.Files = append(manifest.Files, f)
+			if old, ok := prevFiles[f.Path]; !ok || old.Size != f.Size || old.ModTime != f.ModTime || old.Link != "" || old.Sha256 == "" {
+				toCopy = append(toCopy, f)
+			} else {
+				f.Sha256 = old.Sha256
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{Files: len(manifest.Files)}
+	var copied, copiedBytes atomic.Int64
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+	for _, f := range toCopy {
+		g.Go(func() error {
+			if err := putFile(gctx, target, repos, f); err != nil {
+				return fmt.Errorf("backup %s err.%v", f.Path, err)
+			}
+			copied.Add(1)
+			copiedBytes.Add(f.Size)
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+	result.Copied, result.CopiedBytes = copied.Load(), copiedBytes.Load()
+
+	for _, f := range manifest.Files {
+		if f.Link == "" {
+			continue
+		}
+		result.Links++
+		if old, ok := prevFiles[f.Path]; ok && old.Link == f.Link {
+			continue
+		}
+		if err = target.Symlink(ctx, path.Join(dataDir, f.Path), f.Link); err != nil {
+			return nil, fmt.Errorf("backup symlink %s err.%v", f.Path, err)
+		}
+	}
+
+	data, err := sonic.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	name := path.Join(manifestsDir, manifest.Time.UTC().Format("20060102T150405Z")+".json")
+	for _, n := range []string{name, path.Join(manifestsDir, latestManifest)} {
+		if err = target.Put(ctx, n, strings.NewReader(string(data)), int64(len(data))); err != nil {
+			return nil, fmt.Errorf("write manifest %s err.%v", n, err)
+		}
+	}
+	zap.S().Infof("backup finished, %d files, copied %d files (%d bytes)", result.Files, result.Copied, result.CopiedBytes)
+	return result, nil
+}
+
+func readLatest(ctx context.Context, target Target) (*Manifest, error) {
+	manifest, err := ReadManifest(ctx, target, "")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return manifest, err
+}
+
+// ReadManifest 读取指定的快照清单，name为空时读取最近一次备份的清单。
+func ReadManifest(ctx context.Context, target Target, name string) (*Manifest, error) {
+	if name == "" {
+		name = latestManifest
+	}
+	r, err := target.Get(ctx, path.Join(manifestsDir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("read manifest %s err.%v", name, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest %s err.%v", name, err)
+	}
+	manifest := &Manifest{}
+	if err = sonic.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest %s err.%v", name, err)
+	}
+	return manifest, nil
+}
+
+// putFile 先计算摘要再上传，摘要与上传的内容均限定在遍历时记录的大小内。
+func putFile(ctx context.Context, target Target, repos string, f *File) error {
+	file, err := os.Open(filepath.Join(repos, filepath.FromSlash(f.Path)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, io.NewSectionReader(file, 0, f.Size)); err != nil {
+		return err
+	}
+	f.Sha256 = hex.EncodeToString(h.Sum(nil))
+	return target.Put(ctx, path.Join(dataDir, f.Path), file, f.Size)
+}
+
+// DirTarget 备份到本地目录，通常为挂载的备份盘或NFS。
+type DirTarget struct {
+	Dir string
+}
+
+func (t *DirTarget) Put(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	dst := filepath.Join(t.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, io.NewSectionReader(r, 0, size))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (t *DirTarget) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(t.Dir, filepath.FromSlash(name)))
+}
+
+func (t *DirTarget) Symlink(ctx context.Context, name, link string) error {
+	dst := filepath.Join(t.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(link, dst)
+}
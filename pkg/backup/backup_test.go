@@ -0,0 +1,129 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunIncremental(t *testing.T) {
+	repos, dir := t.TempDir(), t.TempDir()
+	blob := filepath.Join(repos, "files", "models", "a", "b", "blobs", "etag1")
+	meta := filepath.Join(repos, "api", "models", "a", "b", "revision", "main", "meta_get.json")
+	link := filepath.Join(repos, "files", "models", "a", "b", "resolve", "sha", "model.bin")
+	for name, content := range map[string]string{blob: "weights", meta: "{}", filepath.Join(repos, "locks", "leader.lease"): "x"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../blobs/etag1", link); err != nil {
+		t.Fatal(err)
+	}
+
+	target := &DirTarget{Dir: dir}
+	result, err := Run(context.Background(), repos, target, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 3 || result.Copied != 2 || result.Links != 1 {
+		t.Fatalf("first backup = %+v", result)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "data", "files", "models", "a", "b", "resolve", "sha", "model.bin")); err != nil || string(data) != "weights" {
+		t.Fatalf("symlink in backup = %q, %v", data, err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "data", "locks")); !os.IsNotExist(err) {
+		t.Fatalf("locks should not be backed up, %v", err)
+	}
+
+	// 只有修改过的文件被再次复制
+	later := time.Now().Add(time.Minute)
+	if err = os.WriteFile(meta, []byte(`{"sha":"1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(meta, later, later); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Run(context.Background(), repos, target, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 3 || result.Copied != 1 || result.CopiedBytes != int64(len(`{"sha":"1"}`)) {
+		t.Fatalf("second backup = %+v", result)
+	}
+	manifests, err := os.ReadDir(filepath.Join(dir, "manifests"))
+	if err != nil || len(manifests) < 2 {
+		t.Fatalf("manifests = %v, %v", manifests, err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	repos, dir := t.TempDir(), t.TempDir()
+	blob := filepath.Join(repos, "files", "models", "a", "b", "blobs", "etag1")
+	link := filepath.Join(repos, "files", "models", "a", "b", "resolve", "sha", "model.bin")
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blob, []byte("weights"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../blobs/etag1", link); err != nil {
+		t.Fatal(err)
+	}
+	target := &DirTarget{Dir: dir}
+	if _, err := Run(context.Background(), repos, target, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := t.TempDir()
+	result, err := Restore(context.Background(), restored, target, "", 2, false)
+	if err != nil || result.Restored != 1 || result.Links != 1 {
+		t.Fatalf("Restore = %+v, %v", result, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(restored, "files", "models", "a", "b", "resolve", "sha", "model.bin")); err != nil || string(data) != "weights" {
+		t.Fatalf("restored file = %q, %v", data, err)
+	}
+	if err = CheckRestore(restored); err != nil {
+		t.Fatal(err)
+	}
+
+	// 备份中的文件损坏时校验失败，服务拒绝启动，允许降级后可以启动
+	if err = os.WriteFile(filepath.Join(dir, "data", "files", "models", "a", "b", "blobs", "etag1"), []byte("weightz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restored = t.TempDir()
+	if result, err = Restore(context.Background(), restored, target, "", 2, false); err == nil || len(result.Failed) != 1 {
+		t.Fatalf("Restore corrupted = %+v, %v", result, err)
+	}
+	if _, err = os.Stat(filepath.Join(restored, "files", "models", "a", "b", "blobs", "etag1")); !os.IsNotExist(err) {
+		t.Fatalf("corrupted file should not be restored, %v", err)
+	}
+	if err = CheckRestore(restored); err == nil {
+		t.Fatal("CheckRestore should fail after verification failure")
+	}
+	if _, err = Restore(context.Background(), restored, target, "", 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckRestore(restored); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUriEncode(t *testing.T) {
+	if got := uriEncode("data/files/a b+c~.json", false); got != "data/files/a%20b%2Bc~.json" {
+		t.Errorf("uriEncode = %s", got)
+	}
+	if got := canonicalQuery(map[string]string{"uploadId": "a/b", "partNumber": "1"}); got != "partNumber=1&uploadId=a%2Fb" {
+		t.Errorf("canonicalQuery = %s", got)
+	}
+}
@@ -0,0 +1,236 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/consts"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	RestoreStatusRestoring = "restoring" // 恢复中或被中断
+	RestoreStatusFailed    = "failed"    // 有文件校验失败
+	RestoreStatusDegraded  = "degraded"  // 有文件校验失败，但允许服务启动，缺失的文件从远端重新拉取
+)
+
+type RestoreState struct {
+	Status    string    `json:"status"`
+	Snapshot  time.Time `json:"snapshot"` // 所用快照的备份时间
+	Failed    []string  `json:"failed,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type RestoreResult struct {
+	Files    int      `json:"files"`
+	Restored int      `json:"restored"`
+	Skipped  int      `json:"skipped"` // 本地已存在且校验通过
+	Links    int      `json:"links"`
+	Failed   []string `json:"failed"`
+}
+
+// Restore 按快照清单重建缓存目录，逐个校验文件摘要，本地已存在且校验通过的文件跳过，可重复执行。
+// 校验失败的文件不会写入，allowDegraded为false时服务拒绝启动，直到再次恢复全部通过。
+func Restore(ctx context.Context, repos string, source Target, manifestName string, concurrency int, allowDegraded bool) (*RestoreResult, error) {
+	manifest, err := ReadManifest(ctx, source, manifestName)
+	if err != nil {
+		return nil, err
+	}
+	state := &RestoreState{Status: RestoreStatusRestoring, Snapshot: manifest.Time}
+	if err = writeRestoreState(repos, state); err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Files: len(manifest.Files)}
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+	for _, f := range manifest.Files {
+		if f.Link != "" {
+			continue
+		}
+		g.Go(func() error {
+			skipped, err := restoreFile(gctx, source, repos, f)
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				zap.S().Errorf("restore %s err.%v", f.Path, err)
+				result.Failed = append(result.Failed, f.Path)
+			case skipped:
+				result.Skipped++
+			default:
+				result.Restored++
+			}
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	for _, f := range manifest.Files {
+		if f.Link == "" {
+			continue
+		}
+		dst := filepath.Join(repos, filepath.FromSlash(f.Path))
+		if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, err
+		}
+		if err = os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err = os.Symlink(f.Link, dst); err != nil {
+			return nil, err
+		}
+		result.Links++
+	}
+
+	if len(result.Failed) == 0 {
+		return result, os.Remove(restoreStatePath(repos))
+	}
+	state.Failed = result.Failed
+	state.Status = RestoreStatusFailed
+	if allowDegraded {
+		state.Status = RestoreStatusDegraded
+	}
+	if err = writeRestoreState(repos, state); err != nil {
+		return nil, err
+	}
+	if !allowDegraded {
+		return result, fmt.Errorf("%d files failed verification, run restore again or allow degraded mode", len(result.Failed))
+	}
+	return result, nil
+}
+
+// restoreFile 先写临时文件并校验大小及摘要，通过后再改名，本地文件已一致时返回skipped。
+func restoreFile(ctx context.Context, source Target, repos string, f *File) (bool, error) {
+	dst := filepath.Join(repos, filepath.FromSlash(f.Path))
+	if ok, err := verifyFile(dst, f); err == nil && ok {
+		return true, nil
+	}
+	r, err := source.Get(ctx, path.Join(dataDir, f.Path))
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return false, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = checkDigest(f, n, hex.EncodeToString(h.Sum(nil)))
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return false, err
+	}
+	if f.ModTime != 0 {
+		modTime := time.Unix(0, f.ModTime)
+		os.Chtimes(dst, modTime, modTime)
+	}
+	return false, nil
+}
+
+func verifyFile(name string, f *File) (bool, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return false, err
+	}
+	return checkDigest(f, n, hex.EncodeToString(h.Sum(nil))) == nil, nil
+}
+
+// checkDigest 旧版本的清单中没有摘要，只校验大小。
+func checkDigest(f *File, size int64, digest string) error {
+	if size != f.Size {
+		return fmt.Errorf("size mismatch, expected %d, got %d", f.Size, size)
+	}
+	if f.Sha256 != "" && digest != f.Sha256 {
+		return fmt.Errorf("sha256 mismatch, expected %s, got %s", f.Sha256, digest)
+	}
+	return nil
+}
+
+func restoreStatePath(repos string) string {
+	return filepath.Join(repos, consts.RestoreStateFile)
+}
+
+func writeRestoreState(repos string, state *RestoreState) error {
+	state.UpdatedAt = time.Now()
+	data, err := sonic.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(repos, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(restoreStatePath(repos), data, 0644)
+}
+
+// CheckRestore 服务启动前检查恢复状态，恢复未完成或校验失败时返回错误，降级模式下打印告警后继续启动。
+func CheckRestore(repos string) error {
+	data, err := os.ReadFile(restoreStatePath(repos))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	state := &RestoreState{}
+	if err = sonic.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("parse %s err.%v", restoreStatePath(repos), err)
+	}
+	if state.Status != RestoreStatusDegraded {
+		return fmt.Errorf("restore of %s is %s, run dingospeed restore again before serving", repos, state.Status)
+	}
+	zap.S().Warnf("cache of %s is restored in degraded mode, %d files failed verification and will be fetched from upstream", repos, len(state.Failed))
+	for _, name := range state.Failed {
+		zap.S().Warnf("restore failed: %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	unsignedPayload    = "UNSIGNED-PAYLOAD"
+	multipartThreshold = 512 << 20 // 超过该大小的文件分片上传，单次PUT最大为5GB
+	minPartSize        = 128 << 20
+	maxParts           = 10000
+)
+
+// S3Target 兼容S3协议的对象存储（AWS S3、MinIO等），使用path-style地址及SigV4签名。
+type S3Target struct {
+	Endpoint     string // 如https://s3.us-east-1.amazonaws.com
+	Region       string
+	Bucket       string
+	Prefix       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Client       *http.Client
+}
+
+// NewS3TargetFromEnv 解析s3://bucket/prefix，凭证及区域读取AWS_ACCESS_KEY_ID、AWS_SECRET_ACCESS_KEY、
+// AWS_SESSION_TOKEN、AWS_REGION，AWS_ENDPOINT_URL用于指定MinIO等非AWS的服务地址。
+func NewS3TargetFromEnv(target string) (*S3Target, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(target, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid target %q, expected s3://bucket/prefix", target)
+	}
+	t := &S3Target{
+		Endpoint:     strings.TrimSuffix(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		Region:       os.Getenv("AWS_REGION"),
+		Bucket:       bucket,
+		Prefix:       strings.Trim(prefix, "/"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		Client:       http.DefaultClient,
+	}
+	if t.AccessKey == "" || t.SecretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for s3 target")
+	}
+	if t.Region == "" {
+		t.Region = "us-east-1"
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", t.Region)
+	}
+	return t, nil
+}
+
+func (t *S3Target) Put(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	if size <= multipartThreshold {
+		_, err := t.do(ctx, http.MethodPut, name, nil, io.NewSectionReader(r, 0, size), size, http.StatusOK)
+		return err
+	}
+	return t.putMultipart(ctx, name, r, size)
+}
+
+func (t *S3Target) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := t.request(ctx, http.MethodGet, name, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, os.ErrNotExist
+	default:
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("GET %s: %s %s", name, resp.Status, data)
+	}
+}
+
+// Symlink 对象存储不支持软链接，仅记录在清单中。
+func (t *S3Target) Symlink(ctx context.Context, name, link string) error {
+	return nil
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (t *S3Target) putMultipart(ctx context.Context, name string, r io.ReaderAt, size int64) error {
+	partSize := max(int64(minPartSize), (size+maxParts-1)/maxParts)
+	resp, err := t.do(ctx, http.MethodPost, name, map[string]string{"uploads": ""}, nil, 0, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var initResult initiateMultipartUploadResult
+	if err = xml.Unmarshal(resp.body, &initResult); err != nil || initResult.UploadId == "" {
+		return fmt.Errorf("initiate multipart upload of %s failed: %v", name, err)
+	}
+	uploadId := initResult.UploadId
+
+	complete := completeMultipartUpload{}
+	for offset, partNumber := int64(0), 1; offset < size; offset, partNumber = offset+partSize, partNumber+1 {
+		n := min(partSize, size-offset)
+		query := map[string]string{"partNumber": strconv.Itoa(partNumber), "uploadId": uploadId}
+		resp, err = t.do(ctx, http.MethodPut, name, query, io.NewSectionReader(r, offset, n), n, http.StatusOK)
+		if err != nil {
+			t.do(context.Background(), http.MethodDelete, name, map[string]string{"uploadId": uploadId}, nil, 0, http.StatusNoContent)
+			return err
+		}
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: partNumber, ETag: resp.header.Get("ETag")})
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	resp, err = t.do(ctx, http.MethodPost, name, map[string]string{"uploadId": uploadId}, bytes.NewReader(body), int64(len(body)), http.StatusOK)
+	if err != nil {
+		return err
+	}
+	// 合并失败时仍可能返回200，错误信息在响应体中
+	if bytes.Contains(resp.body, []byte("<Error>")) {
+		return fmt.Errorf("complete multipart upload of %s failed: %s", name, resp.body)
+	}
+	return nil
+}
+
+type s3Response struct {
+	StatusCode int
+	header     http.Header
+	body       []byte
+}
+
+func (t *S3Target) do(ctx context.Context, method, name string, query map[string]string, body io.Reader, size int64, expected ...int) (*s3Response, error) {
+	resp, err := t.request(ctx, method, name, query, body, size)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return &s3Response{StatusCode: resp.StatusCode, header: resp.Header, body: data}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: %s %s", method, name, resp.Status, data)
+}
+
+func (t *S3Target) request(ctx context.Context, method, name string, query map[string]string, body io.Reader, size int64) (*http.Response, error) {
+	key := strings.TrimPrefix(t.Prefix+"/"+name, "/")
+	uri := "/" + uriEncode(t.Bucket, false) + "/" + uriEncode(key, false)
+	rawQuery := canonicalQuery(query)
+	u := t.Endpoint + uri
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	if body != nil && size == 0 {
+		body = http.NoBody // 长度为0时避免使用chunked编码
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	t.sign(req, uri, rawQuery, time.Now())
+	return t.Client.Do(req)
+}
+
+// sign 按AWS SigV4对请求签名，内容使用UNSIGNED-PAYLOAD，不计算摘要以便流式上传大文件。
+func (t *S3Target) sign(req *http.Request, uri, rawQuery string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", unsignedPayload)
+	if t.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", t.SessionToken)
+	}
+
+	names := []string{"host"}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := req.URL.Host
+		if name != "host" {
+			value = strings.TrimSpace(req.Header.Get(name))
+		}
+		canonicalHeaders.WriteString(name + ":" + value + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, uri, rawQuery, canonicalHeaders.String(), signedHeaders, unsignedPayload}, "\n")
+	scope := date + "/" + t.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSha256([]byte(canonicalRequest))
+	key := hmacSha256([]byte("AWS4"+t.SecretKey), date)
+	for _, s := range []string{t.Region, "s3", "aws4_request"} {
+		key = hmacSha256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.AccessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(query[k], true))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode 按SigV4的规则编码，除字母数字及-_.~外均转义，encodeSlash为false时保留路径中的"/"。
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z') || ('0' <= ch && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (ch == '/' && !encodeSlash) {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,212 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/consts"
+
+	"go.uber.org/zap"
+)
+
+// BackgroundLimiter 后台传输（预热、副本同步、定时刷新）的时间窗口与带宽限制，未配置时为nil，不做限制。
+var BackgroundLimiter *WindowLimiter
+
+// 允许积攒的突发流量时长
+const backgroundBurst = time.Second
+
+func InitBackgroundLimiter(windows []string, bandwidthMbps int) error {
+	if len(windows) == 0 && bandwidthMbps <= 0 {
+		return nil
+	}
+	l, err := NewWindowLimiter(windows, int64(bandwidthMbps)*1000*1000/8)
+	if err != nil {
+		return err
+	}
+	BackgroundLimiter = l
+	return nil
+}
+
+// WithBackground 将ctx标记为后台传输。
+func WithBackground(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consts.KeyBackground, true)
+}
+
+func IsBackground(ctx context.Context) bool {
+	v, _ := ctx.Value(consts.KeyBackground).(bool)
+	return v
+}
+
+// TimeWindow 每天的时间段，以零点起的分钟数表示，End小于Start时表示跨越零点。
+type TimeWindow struct {
+	Start int
+	End   int
+}
+
+// ParseTimeWindow 解析形如 01:00-06:00 的时间窗口。
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q, expected HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(startStr)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %v", s, err)
+	}
+	end, err := parseClock(endStr)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %v", s, err)
+	}
+	if start == end {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q, start equals end", s)
+	}
+	return TimeWindow{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	hourStr, minuteStr, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid clock %q", s)
+	}
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid hour %q", hourStr)
+	}
+	minute, err := strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid minute %q", minuteStr)
+	}
+	return hour*60 + minute, nil
+}
+
+func (w TimeWindow) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+// untilOpen 距离时间窗口开始的时长，已在窗口内时返回0。
+func (w TimeWindow) untilOpen(t time.Time) time.Duration {
+	if w.Contains(t) {
+		return 0
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	open := midnight.Add(time.Duration(w.Start) * time.Minute)
+	if !open.After(t) {
+		open = open.AddDate(0, 0, 1)
+	}
+	return open.Sub(t)
+}
+
+// WindowLimiter 限制传输只在时间窗口内进行，并按字节数控制速率。
+type WindowLimiter struct {
+	windows []TimeWindow
+	rate    int64 // 每秒字节数，0表示不限制
+	mu      sync.Mutex
+	next    time.Time // 已分配出去的流量在不超速的情况下最早完成的时间
+}
+
+func NewWindowLimiter(windows []string, rate int64) (*WindowLimiter, error) {
+	l := &WindowLimiter{rate: rate}
+	for _, s := range windows {
+		w, err := ParseTimeWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		l.windows = append(l.windows, w)
+	}
+	return l, nil
+}
+
+// untilOpen 距离最近一个时间窗口开始的时长，未配置窗口时返回0。
+func (l *WindowLimiter) untilOpen(t time.Time) time.Duration {
+	if len(l.windows) == 0 {
+		return 0
+	}
+	var wait time.Duration = -1
+	for _, w := range l.windows {
+		d := w.untilOpen(t)
+		if wait < 0 || d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// WaitWindow 阻塞到处于时间窗口内，ctx结束时返回错误。
+func (l *WindowLimiter) WaitWindow(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	logged := false
+	for {
+		wait := l.untilOpen(time.Now())
+		if wait == 0 {
+			return ctx.Err()
+		}
+		if !logged {
+			zap.S().Infof("background transfer paused until time window opens, wait %s", wait.Round(time.Second))
+			logged = true
+		}
+		// 分段等待，避免系统时间调整后长时间错过窗口
+		if err := sleepContext(ctx, min(wait, time.Minute)); err != nil {
+			return err
+		}
+	}
+}
+
+// WaitN 传输n字节前调用，仅对后台传输的ctx生效：窗口外阻塞到窗口开始，超过带宽上限时等待。
+func (l *WindowLimiter) WaitN(ctx context.Context, n int64) error {
+	if l == nil || !IsBackground(ctx) {
+		return nil
+	}
+	if err := l.WaitWindow(ctx); err != nil {
+		return err
+	}
+	if l.rate <= 0 || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / float64(l.rate) * float64(time.Second)))
+	wait := l.next.Sub(now) - backgroundBurst
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, wait)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
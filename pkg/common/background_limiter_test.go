@@ -0,0 +1,83 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	w, err := ParseTimeWindow("01:00-06:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Start != 60 || w.End != 390 {
+		t.Fatalf("unexpected window %+v", w)
+	}
+	for _, s := range []string{"", "01:00", "25:00-06:00", "01:60-06:00", "01:00-01:00", "a:b-c:d"} {
+		if _, err = ParseTimeWindow(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2025, 1, 1, h, m, 0, 0, time.Local)
+	}
+	day, _ := ParseTimeWindow("01:00-06:00")
+	night, _ := ParseTimeWindow("22:00-02:00")
+	cases := []struct {
+		w    TimeWindow
+		t    time.Time
+		want bool
+	}{
+		{day, at(0, 59), false},
+		{day, at(1, 0), true},
+		{day, at(5, 59), true},
+		{day, at(6, 0), false},
+		{night, at(23, 0), true},
+		{night, at(1, 30), true},
+		{night, at(2, 0), false},
+		{night, at(12, 0), false},
+	}
+	for _, c := range cases {
+		if got := c.w.Contains(c.t); got != c.want {
+			t.Errorf("%+v contains %s = %v, want %v", c.w, c.t.Format("15:04"), got, c.want)
+		}
+	}
+	if d := day.untilOpen(at(7, 0)); d != 18*time.Hour {
+		t.Fatalf("untilOpen = %s", d)
+	}
+	if d := day.untilOpen(at(0, 30)); d != 30*time.Minute {
+		t.Fatalf("untilOpen = %s", d)
+	}
+}
+
+func TestWindowLimiterWaitN(t *testing.T) {
+	var nilLimiter *WindowLimiter
+	if err := nilLimiter.WaitN(WithBackground(context.Background()), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewWindowLimiter(nil, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 非后台传输不受限制
+	start := time.Now()
+	if err = l.WaitN(context.Background(), 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("foreground transfer should not be limited")
+	}
+	ctx, cancel := context.WithTimeout(WithBackground(context.Background()), 50*time.Millisecond)
+	defer cancel()
+	if err = l.WaitN(ctx, 1000); err != nil {
+		t.Fatal(err)
+	}
+	// 超出突发额度后需要等待，ctx超时返回错误
+	if err = l.WaitN(ctx, 10000); err == nil {
+		t.Fatal("expected wait to be interrupted by ctx")
+	}
+}
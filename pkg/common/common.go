@@ -80,14 +80,21 @@ func (r Response) ExtractHeaders(headers map[string]interface{}) map[string]stri
 }
 
 type PathsInfo struct {
-	Type     string `json:"type"`
-	Oid      string `json:"oid"`
-	Size     int64  `json:"size"`
-	Lfs      Lfs    `json:"lfs"`
-	Path     string `json:"path"`
-	XXetHash string `json:"-"`
-	Location string `json:"-"`
-	Link     string `json:"-"`
+	Type       string      `json:"type"`
+	Oid        string      `json:"oid"`
+	Size       int64       `json:"size"`
+	Lfs        Lfs         `json:"lfs"`
+	Path       string      `json:"path"`
+	LastCommit *LastCommit `json:"lastCommit,omitempty"` // 远端按expand返回的最近一次提交，未返回时为空
+	XXetHash   string      `json:"-"`
+	Location   string      `json:"-"`
+	Link       string      `json:"-"`
+}
+
+type LastCommit struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Date  string `json:"date"`
 }
 
 type Lfs struct {
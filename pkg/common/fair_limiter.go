@@ -0,0 +1,117 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ServeLimiter 客户端下载带宽的加权公平分配，未启用时为nil，不做限制。
+var ServeLimiter *FairLimiter
+
+const (
+	fairBurst        = 200 * time.Millisecond // 允许积攒的突发流量时长
+	fairActiveWindow = 2 * time.Second        // 在该时长内有传输的客户端参与带宽分配
+	fairFlowExpire   = time.Minute            // 超过该时长没有传输的客户端从表中删除
+)
+
+func InitServeLimiter(bandwidthMbps int) {
+	if bandwidthMbps <= 0 {
+		return
+	}
+	ServeLimiter = NewFairLimiter(int64(bandwidthMbps) * 1000 * 1000 / 8)
+}
+
+// FairLimiter 按客户端（token或IP）分配总带宽，每个活跃客户端的速率为总带宽乘以其权重占所有活跃客户端权重之和的比例，
+// 同一客户端的所有连接共享该速率，并发连接再多也不会挤占其他客户端。
+type FairLimiter struct {
+	rate  int64 // 每秒字节数
+	mu    sync.Mutex
+	flows map[string]*fairFlow
+	swept time.Time
+}
+
+type fairFlow struct {
+	weight     int
+	next       time.Time // 已分配出去的流量在不超速的情况下最早完成的时间
+	lastActive time.Time
+}
+
+func NewFairLimiter(rate int64) *FairLimiter {
+	return &FairLimiter{rate: rate, flows: make(map[string]*fairFlow)}
+}
+
+// WaitN 向客户端key发送n字节前调用，超过其分得的带宽时等待。
+func (l *FairLimiter) WaitN(ctx context.Context, key string, weight int, n int64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	wait := l.reserve(time.Now(), key, weight, n)
+	if wait <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, wait)
+}
+
+// reserve 为key预留n字节的发送额度，返回需要等待的时长。
+func (l *FairLimiter) reserve(now time.Time, key string, weight int, n int64) time.Duration {
+	weight = max(weight, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > fairFlowExpire {
+		for k, f := range l.flows {
+			if now.Sub(f.lastActive) > fairFlowExpire {
+				delete(l.flows, k)
+			}
+		}
+		l.swept = now
+	}
+	flow, ok := l.flows[key]
+	if !ok {
+		flow = &fairFlow{}
+		l.flows[key] = flow
+	}
+	flow.weight, flow.lastActive = weight, now
+	totalWeight := 0
+	for _, f := range l.flows {
+		if now.Sub(f.lastActive) <= fairActiveWindow {
+			totalWeight += f.weight
+		}
+	}
+	rate := float64(l.rate) * float64(weight) / float64(totalWeight)
+	if flow.next.Before(now) {
+		flow.next = now
+	}
+	flow.next = flow.next.Add(time.Duration(float64(n) / rate * float64(time.Second)))
+	return flow.next.Sub(now) - fairBurst
+}
+
+// ActiveFlows 返回当前参与带宽分配的客户端数。
+func (l *FairLimiter) ActiveFlows() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now, count := time.Now(), 0
+	for _, f := range l.flows {
+		if now.Sub(f.lastActive) <= fairActiveWindow {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFairLimiterShare(t *testing.T) {
+	l := NewFairLimiter(1000) // 1000B/s
+	now := time.Now()
+	// 只有一个客户端时独占全部带宽
+	if wait := l.reserve(now, "a", 1, 1000); wait != time.Second-fairBurst {
+		t.Fatalf("single flow wait = %s", wait)
+	}
+	// b权重为3，与a同时活跃时分得750B/s
+	if wait := l.reserve(now, "b", 3, 750); wait != time.Second-fairBurst {
+		t.Fatalf("weighted flow wait = %s", wait)
+	}
+	// a此时只能分得250B/s，在已预留的1秒之后再发送250字节需再等1秒
+	if wait := l.reserve(now, "a", 1, 250); wait != 2*time.Second-fairBurst {
+		t.Fatalf("shared flow wait = %s", wait)
+	}
+}
+
+func TestFairLimiterInactiveFlow(t *testing.T) {
+	l := NewFairLimiter(1000)
+	now := time.Now()
+	l.reserve(now, "a", 1, 1)
+	// a超过活跃窗口未发送，不再参与分配
+	later := now.Add(fairActiveWindow + time.Second)
+	if wait := l.reserve(later, "b", 1, 1000); wait != time.Second-fairBurst {
+		t.Fatalf("wait = %s, inactive flow should not take a share", wait)
+	}
+	if l.reserve(later.Add(fairFlowExpire+time.Second), "c", 1, 1); len(l.flows) != 1 {
+		t.Fatalf("expired flows should be removed, got %d", len(l.flows))
+	}
+}
@@ -0,0 +1,131 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package common
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// 超过该时长没有请求的客户端从请求速率表中删除
+const rateBucketExpire = time.Minute
+
+// IPAcl deny优先于allow，allow为空时允许所有未被deny的地址。
+type IPAcl struct {
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	classes []*NetworkClass
+}
+
+func NewIPAcl(allow, deny []*net.IPNet) *IPAcl {
+	return &IPAcl{allow: allow, deny: deny}
+}
+
+// AddClass 按添加顺序匹配，bandwidthMbps、rate为0时不限制。
+func (a *IPAcl) AddClass(name string, nets []*net.IPNet, bandwidthMbps, rate, burst int) {
+	class := &NetworkClass{Name: name, nets: nets, rate: float64(rate), burst: float64(burst), buckets: make(map[string]*rateBucket)}
+	if burst <= 0 {
+		class.burst = float64(rate)
+	}
+	if bandwidthMbps > 0 {
+		class.bandwidth = NewFairLimiter(int64(bandwidthMbps) * 1000 * 1000 / 8)
+	}
+	a.classes = append(a.classes, class)
+}
+
+// Check 返回ip是否允许访问，及其所属的限速等级，不属于任何等级时为nil。
+func (a *IPAcl) Check(ip string) (bool, *NetworkClass) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return len(a.allow) == 0 && len(a.deny) == 0, nil
+	}
+	if containsIP(a.deny, parsed) {
+		return false, nil
+	}
+	if len(a.allow) > 0 && !containsIP(a.allow, parsed) {
+		return false, nil
+	}
+	for _, class := range a.classes {
+		if containsIP(class.nets, parsed) {
+			return true, class
+		}
+	}
+	return true, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkClass 网段的限速等级，请求速率按客户端IP的令牌桶限制，下载带宽由网段内的客户端IP公平分配。
+type NetworkClass struct {
+	Name      string
+	nets      []*net.IPNet
+	bandwidth *FairLimiter
+	rate      float64 // 每秒请求数
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*rateBucket
+	swept     time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Allow 消耗ip的一个请求令牌，超过速率时返回false。
+func (c *NetworkClass) Allow(ip string, now time.Time) bool {
+	if c == nil || c.rate <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.swept) > rateBucketExpire {
+		for k, b := range c.buckets {
+			if now.Sub(b.last) > rateBucketExpire {
+				delete(c.buckets, k)
+			}
+		}
+		c.swept = now
+	}
+	b, ok := c.buckets[ip]
+	if !ok {
+		b = &rateBucket{tokens: c.burst, last: now}
+		c.buckets[ip] = b
+	}
+	b.tokens = min(c.burst, b.tokens+now.Sub(b.last).Seconds()*c.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// WaitN 向ip发送n字节前调用，超过其分得的带宽时等待。
+func (c *NetworkClass) WaitN(ctx context.Context, ip string, n int64) error {
+	if c == nil || c.bandwidth == nil {
+		return nil
+	}
+	return c.bandwidth.WaitN(ctx, ip, 1, n)
+}
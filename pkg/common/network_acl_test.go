@@ -0,0 +1,75 @@
+package common
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func mustParseCIDRs(t *testing.T, cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func TestIPAclCheck(t *testing.T) {
+	acl := NewIPAcl(mustParseCIDRs(t, "10.0.0.0/8", "192.168.1.0/24"), mustParseCIDRs(t, "10.0.9.0/24"))
+	acl.AddClass("gpu", mustParseCIDRs(t, "10.1.0.0/16"), 0, 0, 0)
+	cases := []struct {
+		ip      string
+		allowed bool
+		class   string
+	}{
+		{"10.1.2.3", true, "gpu"},
+		{"10.2.0.1", true, ""},
+		{"192.168.1.7", true, ""},
+		{"10.0.9.5", false, ""},
+		{"172.16.0.1", false, ""},
+		{"not-an-ip", false, ""},
+	}
+	for _, tc := range cases {
+		allowed, class := acl.Check(tc.ip)
+		if allowed != tc.allowed {
+			t.Errorf("Check(%s) allowed = %v, want %v", tc.ip, allowed, tc.allowed)
+		}
+		name := ""
+		if class != nil {
+			name = class.Name
+		}
+		if name != tc.class {
+			t.Errorf("Check(%s) class = %q, want %q", tc.ip, name, tc.class)
+		}
+	}
+}
+
+func TestNetworkClassAllow(t *testing.T) {
+	acl := NewIPAcl(nil, nil)
+	acl.AddClass("office", mustParseCIDRs(t, "192.168.0.0/16"), 0, 2, 3)
+	_, class := acl.Check("192.168.0.1")
+	now := time.Unix(1000, 0)
+	for i := 0; i < 3; i++ {
+		if !class.Allow("192.168.0.1", now) {
+			t.Fatalf("request %d within burst rejected", i)
+		}
+	}
+	if class.Allow("192.168.0.1", now) {
+		t.Fatal("request over burst allowed")
+	}
+	// 其他客户端不受影响
+	if !class.Allow("192.168.0.2", now) {
+		t.Fatal("other client rejected")
+	}
+	// 按速率恢复令牌
+	if !class.Allow("192.168.0.1", now.Add(500*time.Millisecond)) {
+		t.Fatal("request after refill rejected")
+	}
+	if class.Allow("192.168.0.1", now.Add(500*time.Millisecond)) {
+		t.Fatal("refill exceeded rate")
+	}
+}
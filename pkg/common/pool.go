@@ -33,7 +33,6 @@ type Task interface {
 type DownloadTask interface {
 	Task
 	OutResult()
-	GetResponseChan() chan []byte
 	SetTaskSize(taskSize int)
 }
 
@@ -89,6 +88,10 @@ func (p *Pool) GetTask(taskNo int) (Task, bool) {
 	return p.taskMap.Get(taskNo)
 }
 
+func (p *Pool) Tasks() []Task {
+	return p.taskMap.Values()
+}
+
 // Submit 提交任务
 func (p *Pool) Submit(ctx context.Context, task Task) error {
 	p.mu.Lock()
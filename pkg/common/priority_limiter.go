@@ -0,0 +1,134 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package common
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// DownloadLimiter 文件下载的准入控制，启用优先级后由中间件和缓存任务共享。
+var DownloadLimiter *PriorityLimiter
+
+func InitDownloadLimiter(capacity int) {
+	DownloadLimiter = NewPriorityLimiter(capacity)
+}
+
+type waiter struct {
+	priority int
+	seq      int64
+	ready    chan struct{}
+	index    int
+}
+
+type waiterHeap []*waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+
+// 优先级高的先出队，同优先级按到达顺序
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waiterHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waiterHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*h = old[:n-1]
+	return w
+}
+
+// PriorityLimiter 按优先级分配有限的并发名额，名额释放时交给等待队列中优先级最高的请求。
+type PriorityLimiter struct {
+	mu       sync.Mutex
+	capacity int
+	running  int
+	seq      int64
+	waiters  waiterHeap
+}
+
+func NewPriorityLimiter(capacity int) *PriorityLimiter {
+	return &PriorityLimiter{capacity: capacity}
+}
+
+func (p *PriorityLimiter) Acquire(ctx context.Context, priority int) error {
+	p.mu.Lock()
+	if p.running < p.capacity && len(p.waiters) == 0 {
+		p.running++
+		p.mu.Unlock()
+		return nil
+	}
+	p.seq++
+	w := &waiter{priority: priority, seq: p.seq, ready: make(chan struct{})}
+	heap.Push(&p.waiters, w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		select {
+		case <-w.ready:
+			// 超时的同时已获得名额，直接归还
+			p.release()
+		default:
+			heap.Remove(&p.waiters, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+func (p *PriorityLimiter) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.release()
+}
+
+func (p *PriorityLimiter) release() {
+	if len(p.waiters) > 0 {
+		w := heap.Pop(&p.waiters).(*waiter)
+		close(w.ready)
+		return
+	}
+	if p.running > 0 {
+		p.running--
+	}
+}
+
+func (p *PriorityLimiter) Waiting() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.waiters)
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPriorityLimiterOrder(t *testing.T) {
+	limiter := NewPriorityLimiter(1)
+	if err := limiter.Acquire(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan int, 2)
+	for _, priority := range []int{0, 2} {
+		go func(priority int) {
+			if err := limiter.Acquire(context.Background(), priority); err == nil {
+				order <- priority
+			}
+		}(priority)
+		time.Sleep(10 * time.Millisecond)
+	}
+	limiter.Release()
+	if first := <-order; first != 2 {
+		t.Fatalf("expect high priority first, got %d", first)
+	}
+	limiter.Release()
+	<-order
+}
+
+func TestPriorityLimiterTimeout(t *testing.T) {
+	limiter := NewPriorityLimiter(1)
+	_ = limiter.Acquire(context.Background(), 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(ctx, 1); err == nil {
+		t.Fatal("expect timeout")
+	}
+	if limiter.Waiting() != 0 {
+		t.Fatalf("waiter not removed")
+	}
+}
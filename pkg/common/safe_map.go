@@ -59,6 +59,16 @@ func (sm *SafeMap[K, V]) DeleteAll() {
 	sm.m = make(map[K]V)
 }
 
+func (sm *SafeMap[K, V]) Values() []V {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	values := make([]V, 0, len(sm.m))
+	for _, v := range sm.m {
+		values = append(values, v)
+	}
+	return values
+}
+
 func (sm *SafeMap[K, V]) Len() int {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
@@ -0,0 +1,89 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+var errStreamDetached = errors.New("stream detached")
+
+// StreamPipe 下载任务与客户端响应之间的数据管道。
+// 基于io.Pipe实现，写入方在读取方取走数据前阻塞，因此下载输出速度受客户端读取速度约束，不会在内存中堆积数据；
+// ctx取消后两端立即返回错误。
+type StreamPipe struct {
+	pr       *io.PipeReader
+	pw       *io.PipeWriter
+	stop     func() bool
+	written  atomic.Int64
+	detached atomic.Bool
+}
+
+func NewStreamPipe(ctx context.Context) *StreamPipe {
+	pr, pw := io.Pipe()
+	p := &StreamPipe{pr: pr, pw: pw}
+	p.stop = context.AfterFunc(ctx, func() {
+		err := context.Cause(ctx)
+		_ = pw.CloseWithError(err)
+		_ = pr.CloseWithError(err)
+	})
+	return p
+}
+
+// Write 阻塞直到数据被读取方全部取走，读取方关闭或ctx取消时返回错误。
+func (p *StreamPipe) Write(b []byte) (int, error) {
+	if p.detached.Load() {
+		return len(b), nil
+	}
+	n, err := p.pw.Write(b)
+	p.written.Add(int64(n))
+	if err != nil && p.detached.Load() {
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Written 返回已被读取方取走的字节数。
+func (p *StreamPipe) Written() int64 {
+	return p.written.Load()
+}
+
+// Detach 读取方不再消费数据时调用，之后的写入直接丢弃并返回成功，写入方可继续运行完成缓存。
+func (p *StreamPipe) Detach() {
+	p.detached.Store(true)
+	_ = p.pr.CloseWithError(errStreamDetached)
+}
+
+func (p *StreamPipe) Detached() bool {
+	return p.detached.Load()
+}
+
+func (p *StreamPipe) Read(b []byte) (int, error) {
+	return p.pr.Read(b)
+}
+
+// Close 由写入方调用，读取方读完剩余数据后得到io.EOF。
+func (p *StreamPipe) Close() error {
+	p.stop()
+	return p.pw.Close()
+}
+
+// CloseRead 由读取方调用，之后写入方的Write立即返回io.ErrClosedPipe。
+func (p *StreamPipe) CloseRead() error {
+	return p.pr.Close()
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStreamPipeReadWrite(t *testing.T) {
+	p := NewStreamPipe(context.Background())
+	go func() {
+		_, _ = p.Write([]byte("hello "))
+		_, _ = p.Write([]byte("world"))
+		_ = p.Close()
+	}()
+	b, err := io.ReadAll(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello world" {
+		t.Fatalf("got %q", b)
+	}
+}
+
+func TestStreamPipeBlocksUntilRead(t *testing.T) {
+	p := NewStreamPipe(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_, _ = p.Write([]byte("data"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("write returned before read")
+	case <-time.After(50 * time.Millisecond):
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(p, buf); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestStreamPipeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewStreamPipe(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := p.Write([]byte("data"))
+		errCh <- err
+	}()
+	cancel()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("expected write error after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write not released after cancel")
+	}
+	if _, err := p.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected read error after cancel")
+	}
+}
+
+func TestStreamPipeCloseRead(t *testing.T) {
+	p := NewStreamPipe(context.Background())
+	_ = p.CloseRead()
+	if _, err := p.Write([]byte("data")); err != io.ErrClosedPipe {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestStreamPipeDetach(t *testing.T) {
+	p := NewStreamPipe(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := p.Write([]byte("blocked"))
+		errCh <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	p.Detach()
+	if err := <-errCh; err != nil {
+		t.Fatalf("blocked write should succeed after detach, got %v", err)
+	}
+	if n, err := p.Write([]byte("more")); err != nil || n != 4 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if !p.Detached() {
+		t.Fatal("expected detached")
+	}
+}
@@ -15,11 +15,21 @@
 package config
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"dingospeed/internal/model"
@@ -35,6 +45,12 @@ import (
 var SysConfig *Config
 var SystemInfo *model.SystemInfo
 
+var (
+	encryptionKeyOnce sync.Once
+	encryptionKey     []byte
+	encryptionKeyErr  error
+)
+
 type Config struct {
 	Id               int32
 	Server           ServerConfig     `json:"server" yaml:"server"`
@@ -46,8 +62,45 @@ type Config struct {
 	DiskClean        DiskClean        `json:"diskClean" yaml:"diskClean"`
 	DynamicProxy     DynamicProxy     `json:"dynamicProxy" yaml:"dynamicProxy"`
 	Scheduler        Scheduler        `json:"scheduler" yaml:"scheduler"`
+	Dns              Dns              `json:"dns" yaml:"dns"`
+	Upload           Upload           `json:"upload" yaml:"upload"`
+	SharedVolume     SharedVolume     `json:"sharedVolume" yaml:"sharedVolume"`
+	Outbound         Outbound         `json:"outbound" yaml:"outbound"`
+	ErrorReport      ErrorReport      `json:"errorReport" yaml:"errorReport"`
+	Redis            Redis            `json:"redis" yaml:"redis"`
+	Background       Background       `json:"background" yaml:"background"`
+	Prefetch         Prefetch         `json:"prefetch" yaml:"prefetch"`
+	Warmup           Warmup           `json:"warmup" yaml:"warmup"`
+	Trash            Trash            `json:"trash" yaml:"trash"`
+	RevisionGc       RevisionGc       `json:"revisionGc" yaml:"revisionGc"`
+	Curation         Curation         `json:"curation" yaml:"curation"`
+	UpstreamAlert    UpstreamAlert    `json:"upstreamAlert" yaml:"upstreamAlert"`
+	UpstreamProbe    UpstreamProbe    `json:"upstreamProbe" yaml:"upstreamProbe"`
+	UpstreamThrottle UpstreamThrottle `json:"upstreamThrottle" yaml:"upstreamThrottle"`
+	Redirect         Redirect         `json:"redirect" yaml:"redirect"`
+	NetworkAcl       NetworkAcl       `json:"networkAcl" yaml:"networkAcl"`
+	Share            Share            `json:"share" yaml:"share"`
+	DatasetsServer   DatasetsServer   `json:"datasetsServer" yaml:"datasetsServer"`
+	Forward          Forward          `json:"forward" yaml:"forward"`
+	ApiSurface       ApiSurface       `json:"apiSurface" yaml:"apiSurface"`
+	Shadow           Shadow           `json:"shadow" yaml:"shadow"`
+	Readiness        Readiness        `json:"readiness" yaml:"readiness"`
+	Offline          Offline          `json:"offline" yaml:"offline"`
+	Policy           Policy           `json:"policy" yaml:"policy"`
+	MalwareScan      MalwareScan      `json:"malwareScan" yaml:"malwareScan"`
+	Attestation      Attestation      `json:"attestation" yaml:"attestation"`
+	Lock             Lock             `json:"lock" yaml:"lock"`
+	UpstreamBudget   UpstreamBudget   `json:"upstreamBudget" yaml:"upstreamBudget"`
+	CdnCache         CdnCache         `json:"cdnCache" yaml:"cdnCache"`
+	RevisionNotify   RevisionNotify   `json:"revisionNotify" yaml:"revisionNotify"`
+	Chaos            Chaos            `json:"chaos" yaml:"chaos"`
+	GenericProxy     GenericProxy     `json:"genericProxy" yaml:"genericProxy"`
 	mu               sync.RWMutex
-	Modelscope       Modelscope `yaml:"modelscope"`
+	upstreamDown     atomic.Bool // 默认远端被判定为不可用，自动切换为离线服务
+	budgetExhausted  atomic.Bool // 远端请求预算已用尽，窗口重置前只提供缓存内容
+	maintenance      atomic.Pointer[Maintenance]
+	onlineMode       atomic.Value // 管理员手动设置的在线状态，为空或auto时自动切换
+	Modelscope       Modelscope   `yaml:"modelscope"`
 }
 
 type ServerConfig struct {
@@ -56,6 +109,9 @@ type ServerConfig struct {
 	Port       int    `json:"port" yaml:"port"`
 	PProf      bool   `json:"pprof" yaml:"pprof"`
 	PProfPort  int    `json:"pprofPort" yaml:"pprofPort"`
+	PProfHost  string `json:"pprofHost" yaml:"pprofHost"` // 调试端口监听地址，默认127.0.0.1
+	AdminToken string `json:"-" yaml:"adminToken"`        // 调试端口及/admin接口的Bearer令牌，为空时只允许本机访问
+	NodePort   int    `json:"nodePort" yaml:"nodePort"`   // 节点间gRPC端口，仅cluster模式生效，需配置ssl（双向TLS），0表示不启用
 	Metrics    bool   `json:"metrics" yaml:"metrics"`
 	Online     bool   `json:"online" yaml:"online"`
 	Repos      string `json:"repos" yaml:"repos"`
@@ -64,6 +120,174 @@ type ServerConfig struct {
 	XetNetLoc  string `json:"xetNetLoc" yaml:"xetNetLoc"`
 	HfScheme   string `json:"hfScheme" yaml:"hfScheme" validate:"oneof=https http"`
 	Ssl        SSL    `json:"ssl" yaml:"ssl"`
+	// 可信代理的IP或网段，仅来自这些地址的请求才采信X-Forwarded-For/X-Real-IP
+	TrustedProxies []string `json:"trustedProxies" yaml:"trustedProxies"`
+	Cors           Cors     `json:"cors" yaml:"cors"`
+	Limits         Limits   `json:"limits" yaml:"limits"`
+	// 独立的下载监听端口，承载resolve等大流量下载，便于对下载与元数据接口分别设置负载均衡、超时及网络QoS
+	Downloads Downloads `json:"downloads" yaml:"downloads"`
+	// 响应头规则，按顺序作用于缓存及代理的响应
+	ResponseHeaders []HeaderRule `json:"responseHeaders" yaml:"responseHeaders" validate:"dive"`
+	// 按仓库类型或组织路由到不同的远端，按顺序匹配第一条规则，均不匹配时使用hfNetLoc
+	Upstreams []UpstreamRule `json:"upstreams" yaml:"upstreams" validate:"dive"`
+	// 访问远端的TLS配置，作用于hfNetLoc等未单独配置tls的远端
+	UpstreamTls UpstreamTLS `json:"upstreamTls" yaml:"upstreamTls"`
+}
+
+type UpstreamRule struct {
+	RepoType string `json:"repoType" yaml:"repoType"` // 为空表示匹配所有类型
+	Org      string `json:"org" yaml:"org"`           // 为空表示匹配所有组织，以*结尾表示前缀匹配
+	Endpoint string `json:"endpoint" yaml:"endpoint" validate:"required,url"`
+	UseProxy bool   `json:"useProxy" yaml:"useProxy"` // 是否经由代理访问该远端
+	// 该远端单独的TLS配置，为空时使用server.upstreamTls
+	Tls *UpstreamTLS `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+// 访问远端的TLS配置，用于TLS拦截的企业代理（自定义CA）、要求客户端证书的内部hub等场景。
+type UpstreamTLS struct {
+	CaFile             string `json:"caFile" yaml:"caFile"`                                                    // 额外信任的CA证书（PEM），与系统CA一起使用
+	CertFile           string `json:"certFile" yaml:"certFile" validate:"required_with=KeyFile"`               // 客户端证书
+	KeyFile            string `json:"keyFile" yaml:"keyFile" validate:"required_with=CertFile"`                // 客户端证书私钥
+	MinVersion         string `json:"minVersion" yaml:"minVersion" validate:"omitempty,oneof=1.0 1.1 1.2 1.3"` // 最低TLS版本，为空时使用Go的默认值
+	InsecureSkipVerify bool   `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`                            // 不校验远端证书，仅用于测试
+}
+
+func (t *UpstreamTLS) IsZero() bool {
+	return t.CaFile == "" && t.CertFile == "" && t.MinVersion == "" && !t.InsecureSkipVerify
+}
+
+func (r *UpstreamRule) Match(repoType, org string) bool {
+	if r.RepoType != "" && r.RepoType != repoType {
+		return false
+	}
+	if r.Org == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(r.Org, "*"); ok {
+		return strings.HasPrefix(org, prefix)
+	}
+	return r.Org == org
+}
+
+// 下载监听端口仅提供resolve、cdn、archive、share等下载路由，其余请求按未找到处理。
+// exclusive为true时主端口不再提供下载，下载请求重定向到下载端口。
+type Downloads struct {
+	Host              string `json:"host" yaml:"host"`                           // 为空时与server.host相同
+	Port              int    `json:"port" yaml:"port"`                           // 0表示不启用，下载与接口共用server.port
+	Exclusive         bool   `json:"exclusive" yaml:"exclusive"`                 // 主端口的下载请求重定向到下载端口
+	Endpoint          string `json:"endpoint" yaml:"endpoint"`                   // 重定向使用的下载地址，如https://dl.example.com，为空时使用请求的主机名及下载端口
+	ReadHeaderTimeout int    `json:"readHeaderTimeout" yaml:"readHeaderTimeout"` // 为0时沿用limits.readHeaderTimeout
+	IdleTimeout       int    `json:"idleTimeout" yaml:"idleTimeout"`             // 为0时沿用limits.idleTimeout
+}
+
+// 跨域配置，AllowOrigins支持*及*.example.com形式的通配；AllowHeaders为*时回显预检请求的头部
+type Cors struct {
+	AllowOrigins     []string `json:"allowOrigins" yaml:"allowOrigins"`
+	AllowMethods     []string `json:"allowMethods" yaml:"allowMethods"`
+	AllowHeaders     []string `json:"allowHeaders" yaml:"allowHeaders"`
+	ExposeHeaders    []string `json:"exposeHeaders" yaml:"exposeHeaders"`
+	AllowCredentials bool     `json:"allowCredentials" yaml:"allowCredentials"`
+	MaxAge           int      `json:"maxAge" yaml:"maxAge"` // 预检结果缓存时间，单位秒
+}
+
+// 客户端侧的超时与请求体大小限制，单位秒及字节，超时为0表示不限制。
+// 下载响应可能持续很久，writeTimeout默认不限制，仅对routes中配置的路由单独设置。
+type Limits struct {
+	ReadHeaderTimeout int          `json:"readHeaderTimeout" yaml:"readHeaderTimeout"` // 读取请求头的超时，防止慢速攻击
+	ReadTimeout       int          `json:"readTimeout" yaml:"readTimeout"`             // 读取整个请求(含请求体)的超时
+	WriteTimeout      int          `json:"writeTimeout" yaml:"writeTimeout"`
+	IdleTimeout       int          `json:"idleTimeout" yaml:"idleTimeout"`       // keep-alive连接的空闲超时
+	MaxBodySize       int64        `json:"maxBodySize" yaml:"maxBodySize"`       // 请求体大小上限，小于0表示不限制
+	Routes            []RouteLimit `json:"routes" yaml:"routes" validate:"dive"` // 按路由单独设置，按顺序匹配第一条
+}
+
+// RouteLimit 路由级别的限制，path使用path.Match语法，method为空表示匹配所有方法，未设置的项沿用全局值。
+type RouteLimit struct {
+	Method       string `json:"method" yaml:"method"`
+	Path         string `json:"path" yaml:"path" validate:"required"`
+	ReadTimeout  int    `json:"readTimeout" yaml:"readTimeout"`
+	WriteTimeout int    `json:"writeTimeout" yaml:"writeTimeout"`
+	MaxBodySize  int64  `json:"maxBodySize" yaml:"maxBodySize"`
+}
+
+func (r *RouteLimit) Match(method, urlPath string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	ok, _ := path.Match(r.Path, urlPath)
+	return ok
+}
+
+// HeaderRule 响应头规则，remove删除该头部，set覆盖，add追加一个值，rewrite将已有值中的pattern子串替换为value。
+// path使用path.Match语法，为空表示匹配所有请求。
+type HeaderRule struct {
+	Path    string `json:"path" yaml:"path"`
+	Action  string `json:"action" yaml:"action" validate:"oneof=remove set add rewrite"`
+	Name    string `json:"name" yaml:"name" validate:"required"`
+	Value   string `json:"value" yaml:"value"`
+	Pattern string `json:"pattern" yaml:"pattern" validate:"required_if=Action rewrite"`
+}
+
+func (r *HeaderRule) Match(urlPath string) bool {
+	if r.Path == "" {
+		return true
+	}
+	ok, _ := path.Match(r.Path, urlPath)
+	return ok
+}
+
+// GetResponseHeaderRules 返回作用于该请求路径的响应头规则。
+func (c *Config) GetResponseHeaderRules(urlPath string) []HeaderRule {
+	var rules []HeaderRule
+	for _, rule := range c.Server.ResponseHeaders {
+		if rule.Match(urlPath) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// 上传类路由的默认限制，请求体为提交内容或完整文件，不受全局maxBodySize约束；在配置的routes之后匹配
+var defaultRouteLimits = []RouteLimit{
+	{Method: http.MethodPost, Path: "/api/*/*/*/commit/*", MaxBodySize: 100 << 20},
+	{Method: http.MethodPost, Path: "/api/*/*/commit/*", MaxBodySize: 100 << 20},
+	{Path: "/lfs-proxy/*", MaxBodySize: -1},
+}
+
+// GetRouteLimit 返回请求实际生效的限制。
+func (c *Config) GetRouteLimit(method, urlPath string) RouteLimit {
+	limit := RouteLimit{
+		ReadTimeout:  c.Server.Limits.ReadTimeout,
+		WriteTimeout: c.Server.Limits.WriteTimeout,
+		MaxBodySize:  c.Server.Limits.MaxBodySize,
+	}
+	for _, r := range slices.Concat(c.Server.Limits.Routes, defaultRouteLimits) {
+		if !r.Match(method, urlPath) {
+			continue
+		}
+		limit.Method, limit.Path = r.Method, r.Path
+		if r.ReadTimeout != 0 {
+			limit.ReadTimeout = r.ReadTimeout
+		}
+		if r.WriteTimeout != 0 {
+			limit.WriteTimeout = r.WriteTimeout
+		}
+		if r.MaxBodySize != 0 {
+			limit.MaxBodySize = r.MaxBodySize
+		}
+		break
+	}
+	return limit
+}
+
+// MarshalYAML 打印配置时隐藏管理令牌。
+func (s ServerConfig) MarshalYAML() (interface{}, error) {
+	type plain ServerConfig
+	p := plain(s)
+	if p.AdminToken != "" {
+		p.AdminToken = "******"
+	}
+	return p, nil
 }
 
 type SSL struct {
@@ -72,23 +296,618 @@ type SSL struct {
 	CaFile  string `json:"caFile" yaml:"caFile" `
 }
 
+// 远端域名解析配置
+type Dns struct {
+	Nameservers []string          `json:"nameservers" yaml:"nameservers"`
+	Hosts       map[string]string `json:"hosts" yaml:"hosts"`       // 域名到IP的固定映射
+	CacheTTL    int               `json:"cacheTTL" yaml:"cacheTTL"` // 解析结果缓存时间，单位秒
+}
+
 type Download struct {
-	RetryChannelNum         int   `json:"retryChannelNum" yaml:"retryChannelNum"`
-	GoroutineMaxNumPerFile  int   `json:"goroutineMaxNumPerFile" yaml:"goroutineMaxNumPerFile" validate:"min=1,max=8"`
-	BlockSize               int64 `json:"blockSize" yaml:"blockSize" validate:"min=1048576,max=134217728"`
-	ReqTimeout              int64 `json:"reqTimeout" yaml:"reqTimeout"`
-	RespChunkSize           int64 `json:"respChunkSize" yaml:"respChunkSize" validate:"min=1024,max=8388608"`
-	RespChanSize            int64 `json:"respChanSize" yaml:"respChanSize"`
-	RemoteFileRangeSize     int64 `json:"remoteFileRangeSize" yaml:"remoteFileRangeSize" validate:"min=0,max=1073741824"`
-	RemoteFileRangeWaitTime int64 `json:"remoteFileRangeWaitTime" yaml:"remoteFileRangeWaitTime" validate:"min=1,max=10"`
-	RemoteFileBufferSize    int64 `json:"remoteFileBufferSize" yaml:"remoteFileBufferSize" validate:"min=0,max=134217728"`
+	RetryChannelNum         int         `json:"retryChannelNum" yaml:"retryChannelNum"`
+	GoroutineMaxNumPerFile  int         `json:"goroutineMaxNumPerFile" yaml:"goroutineMaxNumPerFile" validate:"min=1,max=8"`
+	BlockSize               int64       `json:"blockSize" yaml:"blockSize" validate:"min=1048576,max=134217728"`
+	ReqTimeout              int64       `json:"reqTimeout" yaml:"reqTimeout"`
+	RespChunkSize           int64       `json:"respChunkSize" yaml:"respChunkSize" validate:"min=1024,max=8388608"`
+	RemoteFileRangeSize     int64       `json:"remoteFileRangeSize" yaml:"remoteFileRangeSize" validate:"min=0,max=1073741824"`
+	RemoteFileRangeWaitTime int64       `json:"remoteFileRangeWaitTime" yaml:"remoteFileRangeWaitTime" validate:"min=1,max=10"`
+	RemoteFileBufferSize    int64       `json:"remoteFileBufferSize" yaml:"remoteFileBufferSize" validate:"min=0,max=134217728"`
+	ReadAheadSize           int64       `json:"readAheadSize" yaml:"readAheadSize" validate:"min=0"` // 顺序读取时预读的字节数，0表示不预读
+	BufferBudget            int64       `json:"bufferBudget" yaml:"bufferBudget"`                    // 缓冲远端响应体的内存总量上限，超过后转存到临时文件，单位字节，小于0不限制
+	SpillDir                string      `json:"spillDir" yaml:"spillDir"`                            // 转存临时文件的目录，为空时使用{repos}/.spill
+	DiskReserve             int64       `json:"diskReserve" yaml:"diskReserve"`                      // 冷下载前磁盘需保留的剩余空间，单位字节，小于0不检查
+	Timeout                 Timeout     `json:"timeout" yaml:"timeout"`
+	ConnPool                ConnPool    `json:"connPool" yaml:"connPool"`
+	ResumeToken             ResumeToken `json:"resumeToken" yaml:"resumeToken"`
+	Adaptive                Adaptive    `json:"adaptive" yaml:"adaptive"`
+}
+
+// 按连接实测的客户端吞吐量调整每次写给客户端的数据量及预读大小，慢速客户端使用小块，局域网内的快速客户端使用大块。
+type Adaptive struct {
+	Enabled          bool  `json:"enabled" yaml:"enabled"`
+	MinChunkSize     int64 `json:"minChunkSize" yaml:"minChunkSize"`         // 每次写入的最小字节数，默认16KB
+	MaxChunkSize     int64 `json:"maxChunkSize" yaml:"maxChunkSize"`         // 每次写入的最大字节数，默认1MB
+	ReadAheadSeconds int   `json:"readAheadSeconds" yaml:"readAheadSeconds"` // 预读客户端按实测速度在该时长内读取的数据量，以readAheadSize为上限，默认10秒
+}
+
+// 大文件未携带Range的下载在响应中签发续传令牌，客户端断线重连时在请求头中携带令牌且未指定Range，
+// 以206从服务端记录的已发送位置继续传输，记录的位置为写入连接的字节数，续传时回退一段余量。
+type ResumeToken struct {
+	MinSize    int64 `json:"minSize" yaml:"minSize"`       // 签发令牌的最小文件大小，单位字节，0表示不签发
+	Expiration int   `json:"expiration" yaml:"expiration"` // 令牌有效期，单位分钟，默认30
+}
+
+// 远端http客户端连接池配置，时间单位秒，0表示使用默认值。
+type ConnPool struct {
+	MaxIdleConns        int `json:"maxIdleConns" yaml:"maxIdleConns"`
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost" yaml:"maxIdleConnsPerHost"`
+	IdleConnTimeout     int `json:"idleConnTimeout" yaml:"idleConnTimeout"`
+	KeepAlive           int `json:"keepAlive" yaml:"keepAlive"`
+	TLSHandshakeTimeout int `json:"tlsHandshakeTimeout" yaml:"tlsHandshakeTimeout"`
+}
+
+// 按请求类型区分的远端超时配置，单位秒，未配置时使用reqTimeout。
+type Timeout struct {
+	Meta      ClassTimeout `json:"meta" yaml:"meta"`
+	PathsInfo ClassTimeout `json:"pathsInfo" yaml:"pathsInfo"`
+	Blob      ClassTimeout `json:"blob" yaml:"blob"`
+	Upload    ClassTimeout `json:"upload" yaml:"upload"`
+}
+
+type ClassTimeout struct {
+	Connect int `json:"connect" yaml:"connect"` // 建立连接的超时时间
+	Read    int `json:"read" yaml:"read"`       // meta和pathsInfo为整个请求的超时时间，blob为等待响应头的超时时间
 }
 
 type Cache struct {
-	DefaultExpiration int       `json:"defaultExpiration" yaml:"defaultExpiration" `
-	CleanupInterval   int       `json:"cleanupInterval" yaml:"cleanupInterval"`
-	ReadBlock         ReadBlock `json:"readBlock" yaml:"readBlock"`
-	MountModelDir     string    `json:"mountModelDir" yaml:"mountModelDir"`
+	DefaultExpiration    int                  `json:"defaultExpiration" yaml:"defaultExpiration" `
+	CleanupInterval      int                  `json:"cleanupInterval" yaml:"cleanupInterval"`
+	NegativeExpiration   int                  `json:"negativeExpiration" yaml:"negativeExpiration"` // 404/403结果的缓存时间，单位秒，小于0不缓存
+	ReadBlock            ReadBlock            `json:"readBlock" yaml:"readBlock"`
+	MountModelDir        string               `json:"mountModelDir" yaml:"mountModelDir"`
+	PrivateCache         bool                 `json:"privateCache" yaml:"privateCache"`         // 按token隔离元数据缓存
+	WhoamiExpiration     int                  `json:"whoamiExpiration" yaml:"whoamiExpiration"` // token身份信息的缓存时间，单位分钟
+	RefsExpiration       int                  `json:"refsExpiration" yaml:"refsExpiration"`     // refs缓存的有效期，单位秒，过期后按ETag向远端校验，小于0每次都校验
+	SyncPolicy           string               `json:"syncPolicy" yaml:"syncPolicy" validate:"omitempty,oneof=never on-close periodic"`
+	SyncInterval         int                  `json:"syncInterval" yaml:"syncInterval"` // periodic策略的刷盘周期，单位秒
+	CompressMeta         bool                 `json:"compressMeta" yaml:"compressMeta"` // 元数据缓存文件使用zstd压缩存储
+	Preallocate          bool                 `json:"preallocate" yaml:"preallocate"`   // 首次写入文件前按文件大小预分配磁盘空间
+	Encryption           Encryption           `json:"encryption" yaml:"encryption"`
+	CompleteOnDisconnect CompleteOnDisconnect `json:"completeOnDisconnect" yaml:"completeOnDisconnect"`
+	Index                CacheIndex           `json:"index" yaml:"index"`
+}
+
+// 缓存文件索引，以布隆过滤器记录已缓存的文件，缓存未命中时无需访问文件系统，适用于网络文件系统上的大容量缓存。
+// 共享缓存卷时其他节点写入的文件无法记录到本节点的索引，此时不启用。
+type CacheIndex struct {
+	Enabled           bool    `json:"enabled" yaml:"enabled"`
+	ExpectedItems     int     `json:"expectedItems" yaml:"expectedItems"`         // 预期的缓存文件数量，用于计算过滤器大小
+	FalsePositiveRate float64 `json:"falsePositiveRate" yaml:"falsePositiveRate"` // 误判率，误判时仍会访问文件系统
+	RebuildInterval   int     `json:"rebuildInterval" yaml:"rebuildInterval"`     // 重建周期，单位小时，用于淘汰已删除的文件
+}
+
+func (c *Config) EnableCacheIndex() bool {
+	return c.Cache.Index.Enabled && !c.EnableSharedVolume()
+}
+
+// 客户端中途断开时，若冷下载已发送的比例达到阈值，则在后台继续拉取剩余数据写入缓存。
+type CompleteOnDisconnect struct {
+	Enabled   bool `json:"enabled" yaml:"enabled"`
+	Threshold int  `json:"threshold" yaml:"threshold" validate:"min=0,max=100"` // 已发送数据占请求范围的百分比
+}
+
+// 缓存文件的静态加密配置，密钥为base64编码的32字节AES-256密钥，按key、keyFile、keyCommand的顺序取第一个已配置的来源。
+type Encryption struct {
+	Enabled    bool   `json:"enabled" yaml:"enabled"` // 新写入的缓存文件是否加密，已加密的文件始终可读
+	Key        string `json:"-" yaml:"key"`
+	KeyFile    string `json:"keyFile" yaml:"keyFile"`
+	KeyCommand string `json:"keyCommand" yaml:"keyCommand"` // 执行命令从标准输出获取密钥，用于对接KMS
+}
+
+// MarshalYAML 打印配置时隐藏密钥。
+func (e Encryption) MarshalYAML() (interface{}, error) {
+	type plain Encryption
+	p := plain(e)
+	if p.Key != "" {
+		p.Key = "******"
+	}
+	return p, nil
+}
+
+// 发往远端请求的头部策略
+type Outbound struct {
+	UserAgent    string            `json:"userAgent" yaml:"userAgent"`       // 为空时保留原UA，支持{version}占位符
+	Headers      map[string]string `json:"headers" yaml:"headers"`           // 附加的请求头，如出口网关令牌
+	StripHeaders []string          `json:"stripHeaders" yaml:"stripHeaders"` // 发往远端前移除的请求头
+	DefaultToken string            `json:"-" yaml:"defaultToken"`            // 客户端未携带token时，读取类请求使用的默认token
+	// 镜像签发的key到远端token的映射，客户端只持有key，远端token只保存在镜像上
+	TokenMappings []TokenMapping `json:"-" yaml:"tokenMappings" validate:"dive"`
+}
+
+// TokenMapping 按团队配置，keys为签发给该团队客户端的key，发往远端时替换为token。
+type TokenMapping struct {
+	Name  string   `yaml:"name" validate:"required"`
+	Keys  []string `yaml:"keys" validate:"required"`
+	Token string   `yaml:"token" validate:"required"`
+}
+
+// GetMappedToken 返回key映射的远端token及所属团队，未配置映射时返回false。
+func (c *Config) GetMappedToken(key string) (string, string, bool) {
+	if key == "" {
+		return "", "", false
+	}
+	for _, mapping := range c.Outbound.TokenMappings {
+		for _, k := range mapping.Keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return mapping.Token, mapping.Name, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// MarshalYAML 打印配置时隐藏附加请求头的值、默认token及token映射，其中可能包含令牌。
+func (o Outbound) MarshalYAML() (interface{}, error) {
+	type plain Outbound
+	p := plain(o)
+	if len(o.Headers) > 0 {
+		p.Headers = make(map[string]string, len(o.Headers))
+		for k := range o.Headers {
+			p.Headers[k] = "******"
+		}
+	}
+	if p.DefaultToken != "" {
+		p.DefaultToken = "******"
+	}
+	if len(o.TokenMappings) > 0 {
+		p.TokenMappings = make([]TokenMapping, 0, len(o.TokenMappings))
+		for _, mapping := range o.TokenMappings {
+			p.TokenMappings = append(p.TokenMappings, TokenMapping{Name: mapping.Name, Keys: []string{"******"}, Token: "******"})
+		}
+	}
+	return p, nil
+}
+
+// Redis配置，启用后commit sha缓存、负缓存等热点元数据在多个副本间共享。
+type Redis struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	Addr      string `json:"addr" yaml:"addr"`
+	Password  string `json:"-" yaml:"password"`
+	DB        int    `json:"db" yaml:"db"`
+	KeyPrefix string `json:"keyPrefix" yaml:"keyPrefix"`
+	Timeout   int    `json:"timeout" yaml:"timeout"`   // 单个命令的超时时间，单位毫秒
+	PoolSize  int    `json:"poolSize" yaml:"poolSize"` // 空闲连接池大小
+}
+
+// 后台传输配置，预热、副本同步、定时刷新等后台任务仅在时间窗口内进行，并限制占用的总带宽。
+type Background struct {
+	Windows   []string `json:"windows" yaml:"windows"`     // 允许后台传输的时间窗口，如 01:00-06:00，可跨越零点，为空表示不限时间
+	Bandwidth int      `json:"bandwidth" yaml:"bandwidth"` // 后台传输的总带宽上限，单位Mbps，0表示不限制
+}
+
+// 自动预取配置，客户端下载未缓存版本的config.json等文件时，在后台预取该版本的其余文件。
+// 启动后按热度读取最常访问的已缓存文件，预热操作系统的页缓存，避免重启后的首批请求都读冷盘。
+type Warmup struct {
+	Enabled  bool  `json:"enabled" yaml:"enabled"`
+	TopN     int   `json:"topN" yaml:"topN"`         // 预热resolve次数最多的前N个文件，默认100
+	MaxBytes int64 `json:"maxBytes" yaml:"maxBytes"` // 预热读取的总字节数上限，单位字节，0表示不限制
+}
+
+func (c *Config) GetWarmupTopN() int {
+	if c.Warmup.TopN <= 0 {
+		return 100
+	}
+	return c.Warmup.TopN
+}
+
+type Prefetch struct {
+	Enabled      bool     `json:"enabled" yaml:"enabled"`
+	Triggers     []string `json:"triggers" yaml:"triggers"`         // 触发预取的文件名，按文件名匹配，不含目录
+	Include      []string `json:"include" yaml:"include"`           // 需要预取的文件glob，为空表示全部文件
+	Exclude      []string `json:"exclude" yaml:"exclude"`           // 不预取的文件glob
+	MaxFileSize  int64    `json:"maxFileSize" yaml:"maxFileSize"`   // 大于该值的文件不预取，单位字节，0表示不限制
+	MaxTotalSize int64    `json:"maxTotalSize" yaml:"maxTotalSize"` // 仓库总大小超过该值时不预取，单位字节，0表示不限制
+	PathsInfo    bool     `json:"pathsInfo" yaml:"pathsInfo"`       // 在线请求仓库信息时，在后台批量缓存该版本全部文件的paths-info，与enabled无关
+}
+
+// 远端失败告警，按远端地址统计窗口内的失败率（网络错误及5xx），超过阈值时通过errorReport的webhook/Sentry告警。
+type UpstreamAlert struct {
+	Enabled       bool    `json:"enabled" yaml:"enabled"`
+	Window        int     `json:"window" yaml:"window"`                              // 统计窗口，单位秒
+	MinRequests   int     `json:"minRequests" yaml:"minRequests"`                    // 窗口内请求数少于该值时不判定
+	ErrorRate     float64 `json:"errorRate" yaml:"errorRate" validate:"min=0,max=1"` // 失败率阈值
+	AutoOffline   bool    `json:"autoOffline" yaml:"autoOffline"`                    // 默认远端不可用时自动切换为离线服务
+	ProbeInterval int     `json:"probeInterval" yaml:"probeInterval"`                // 自动离线后探测远端是否恢复的间隔，单位秒
+}
+
+// 就绪检查，按窗口内文件请求的缓存命中比例（warm ratio）判断节点是否已预热，避免新节点先于已预热的节点接收流量。
+type Readiness struct {
+	MinWarmRatio float64 `json:"minWarmRatio" yaml:"minWarmRatio" validate:"min=0,max=1"` // 命中比例低于该值时/readyz返回503，0表示不检查
+	Window       int     `json:"window" yaml:"window"`                                    // 统计窗口，单位秒
+	MinRequests  int     `json:"minRequests" yaml:"minRequests"`                          // 窗口内请求数少于该值时视为就绪
+}
+
+func (c *Config) GetReadinessWindow() int {
+	if c.Readiness.Window <= 0 {
+		return 300
+	}
+	return c.Readiness.Window
+}
+
+func (c *Config) GetReadinessMinRequests() int {
+	if c.Readiness.MinRequests <= 0 {
+		return 20
+	}
+	return c.Readiness.MinRequests
+}
+
+// 离线服务的降级策略。
+type Offline struct {
+	FallbackRevision bool `json:"fallbackRevision" yaml:"fallbackRevision"` // 请求的版本未缓存且远端不可用时，以最近缓存了该文件的其它版本响应
+}
+
+// 仓库访问策略，按模型卡片的许可证及gated标记在向客户端提供文件前检查，许可证名称不区分大小写，支持path.Match语法。
+type Policy struct {
+	Enabled       bool     `json:"enabled" yaml:"enabled"`
+	DenyLicenses  []string `json:"denyLicenses" yaml:"denyLicenses"`   // 禁止的许可证，优先于allowLicenses
+	AllowLicenses []string `json:"allowLicenses" yaml:"allowLicenses"` // 允许的许可证，为空表示不限制
+	DenyUnknown   bool     `json:"denyUnknown" yaml:"denyUnknown"`     // 未声明许可证或元数据不可用的仓库是否禁止
+	GatedApproval bool     `json:"gatedApproval" yaml:"gatedApproval"` // gated仓库需管理员通过/admin/policy/approvals审批后才提供文件
+}
+
+// 恶意文件扫描，匹配的文件（默认为pickle格式的权重）完整下载并扫描通过后才提供给客户端，感染的文件移入隔离目录。
+// clamd与command二选一，clamd优先。
+type MalwareScan struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Include []string `json:"include" yaml:"include"`                                        // 需要扫描的文件名glob，按文件名匹配，不含目录
+	Clamd   string   `json:"clamd" yaml:"clamd"`                                            // clamd地址，如unix:///var/run/clamav/clamd.ctl、tcp://127.0.0.1:3310
+	Command string   `json:"command" yaml:"command"`                                        // 外部扫描命令，经sh -c执行，文件内容写入标准输入，命令中的{file}替换为临时文件路径；退出码0为正常，1为感染
+	Timeout int      `json:"timeout" yaml:"timeout"`                                        // 单个文件的扫描超时，单位秒
+	OnError string   `json:"onError" yaml:"onError" validate:"omitempty,oneof=allow block"` // 扫描出错时allow放行或block拒绝
+}
+
+func (c *Config) GetMalwareScanInclude() []string {
+	if len(c.MalwareScan.Include) == 0 {
+		return []string{"*.bin", "*.pt", "*.pth", "*.pkl", "*.pickle", "*.ckpt"}
+	}
+	return c.MalwareScan.Include
+}
+
+func (c *Config) GetMalwareScanTimeout() time.Duration {
+	if c.MalwareScan.Timeout <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(c.MalwareScan.Timeout) * time.Second
+}
+
+// 缓存清单签名，按版本生成包含已缓存文件sha256、etag及获取时间的清单并以ed25519签名，下游可离线校验镜像提供的内容。
+type Attestation struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	KeyFile string `json:"keyFile" yaml:"keyFile"` // ed25519私钥（PKCS8 PEM），为空时使用repos/attestation/ed25519.pem，不存在时自动生成
+}
+
+// 锁管理配置，缓存元数据写入、共享缓存卷的写入租约及后台任务的leader选举使用同一锁后端。
+type Lock struct {
+	Backend string   `json:"backend" yaml:"backend" validate:"omitempty,oneof=flock lease redis etcd"` // 为空时单机使用flock，共享缓存卷使用lease
+	Etcd    LockEtcd `json:"etcd" yaml:"etcd"`
+}
+
+type LockEtcd struct {
+	Endpoints []string `json:"endpoints" yaml:"endpoints"` // etcd v3 HTTP网关地址，如http://127.0.0.1:2379
+	KeyPrefix string   `json:"keyPrefix" yaml:"keyPrefix"`
+	Timeout   int      `json:"timeout" yaml:"timeout"` // 单个请求超时时间，单位毫秒
+}
+
+// 版本回收，每个仓库保留最新的keep个版本，删除仅被更早版本引用的blob。
+type RevisionGc struct {
+	Enabled  bool     `json:"enabled" yaml:"enabled"`
+	Keep     int      `json:"keep" yaml:"keep"`         // 每个仓库保留的版本数
+	Interval int      `json:"interval" yaml:"interval"` // 执行间隔，单位小时
+	Pins     []string `json:"pins" yaml:"pins"`         // 不参与回收的版本，格式为{repoType}/{org}/{repo}@{revision}，revision为commit或分支、标签
+}
+
+// 模型精选同步，按规则定期发现远端排名靠前的模型，并将其main分支同步到缓存。
+type Curation struct {
+	Enabled      bool           `json:"enabled" yaml:"enabled"`
+	Interval     int            `json:"interval" yaml:"interval"`         // 执行间隔，单位小时
+	Include      []string       `json:"include" yaml:"include"`           // 需要同步的文件glob，为空表示全部文件
+	Exclude      []string       `json:"exclude" yaml:"exclude"`           // 不同步的文件glob
+	MaxFileSize  int64          `json:"maxFileSize" yaml:"maxFileSize"`   // 大于该值的文件不同步，单位字节，0表示不限制
+	MaxTotalSize int64          `json:"maxTotalSize" yaml:"maxTotalSize"` // 模型总大小超过该值时不同步，单位字节，0表示不限制
+	Rules        []CurationRule `json:"rules" yaml:"rules"`
+}
+
+// 精选规则，过滤条件为空表示不过滤。
+type CurationRule struct {
+	PipelineTag string `json:"pipelineTag" yaml:"pipelineTag"` // 如text-generation
+	Library     string `json:"library" yaml:"library"`         // 如transformers
+	License     string `json:"license" yaml:"license"`         // 如apache-2.0
+	Sort        string `json:"sort" yaml:"sort"`               // 排序字段，默认downloads，可选likes、trendingScore等
+	Limit       int    `json:"limit" yaml:"limit"`             // 同步排名前N的模型
+}
+
+// 远端限流处理，远端返回429时按Retry-After暂停向该远端发起请求，暂停期间的请求排队等待，等待过久则直接返回429。
+type UpstreamThrottle struct {
+	MaxWait      int `json:"maxWait" yaml:"maxWait"`           // 请求最长排队时间，单位秒，小于0时不排队
+	DefaultPause int `json:"defaultPause" yaml:"defaultPause"` // 远端未返回Retry-After时的暂停时长，单位秒
+	MaxPause     int `json:"maxPause" yaml:"maxPause"`         // 暂停时长上限，单位秒
+}
+
+// 远端请求预算，时间窗口内请求数或下载流量达到上限后只提供已缓存的内容，强制刷新请求排队到下一个窗口执行。
+type UpstreamBudget struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	Window      string `json:"window" yaml:"window" validate:"omitempty,oneof=hour day"` // 预算窗口，hour或day，默认day
+	MaxRequests int64  `json:"maxRequests" yaml:"maxRequests"`                           // 窗口内最多请求数，0表示不限制
+	MaxMB       int64  `json:"maxMB" yaml:"maxMB"`                                       // 窗口内最多下载流量，单位MB，0表示不限制
+}
+
+// 客户端网络访问控制，按客户端IP（经trustedProxies解析）匹配，集群部署时需将其他节点的地址加入allow。
+type NetworkAcl struct {
+	Enabled bool           `json:"enabled" yaml:"enabled"`
+	Allow   []string       `json:"allow" yaml:"allow"`                     // 允许访问的IP或网段，为空表示不限制
+	Deny    []string       `json:"deny" yaml:"deny"`                       // 拒绝访问的IP或网段，优先于allow
+	Classes []NetworkClass `json:"classes" yaml:"classes" validate:"dive"` // 按网段设置的限速等级，按顺序匹配第一条
+}
+
+// NetworkClass 网段的限速等级，bandwidth为网段内所有客户端共享的下载带宽，由各客户端IP公平分配。
+type NetworkClass struct {
+	Name      string   `json:"name" yaml:"name" validate:"required"`
+	Cidrs     []string `json:"cidrs" yaml:"cidrs" validate:"required"`
+	Bandwidth int      `json:"bandwidth" yaml:"bandwidth"` // 下载带宽，单位Mbps，0表示不限制
+	Rate      int      `json:"rate" yaml:"rate"`           // 每个客户端IP每秒的请求数，0表示不限制
+	Burst     int      `json:"burst" yaml:"burst"`         // 允许的突发请求数，默认与rate相同
+}
+
+// 只读分享链接，签名的链接在有效期内无需认证即可下载已缓存的文件或快照归档。
+type Share struct {
+	Secret        string `json:"-" yaml:"secret"`                    // 签名密钥，为空时不启用，多副本需配置相同的值
+	Expiration    int    `json:"expiration" yaml:"expiration"`       // 未指定有效期时的默认值，单位小时
+	MaxExpiration int    `json:"maxExpiration" yaml:"maxExpiration"` // 有效期上限，单位小时
+}
+
+// MarshalYAML 打印配置时隐藏签名密钥。
+func (s Share) MarshalYAML() (interface{}, error) {
+	type plain Share
+	p := plain(s)
+	if p.Secret != "" {
+		p.Secret = "******"
+	}
+	return p, nil
+}
+
+func (c *Config) GetShareExpiration() time.Duration {
+	if c.Share.Expiration <= 0 {
+		c.Share.Expiration = 24
+	}
+	return time.Duration(c.Share.Expiration) * time.Hour
+}
+
+func (c *Config) GetShareMaxExpiration() time.Duration {
+	if c.Share.MaxExpiration <= 0 {
+		c.Share.MaxExpiration = 168
+	}
+	return time.Duration(c.Share.MaxExpiration) * time.Hour
+}
+
+// 远端元数据请求返回302/307时的处理策略，follow在服务端跟随重定向并缓存最终内容，
+// rewrite不跟随，将Location中的远端地址改写为经由本镜像访问后返回给客户端。
+type Redirect struct {
+	Policy string         `json:"policy" yaml:"policy" validate:"omitempty,oneof=follow rewrite"` // 默认follow
+	Rules  []RedirectRule `json:"rules" yaml:"rules" validate:"dive"`                             // 按请求路径单独设置，按顺序匹配第一条
+}
+
+// RedirectRule path为发往远端的请求路径，使用path.Match语法。
+type RedirectRule struct {
+	Path   string `json:"path" yaml:"path" validate:"required"`
+	Policy string `json:"policy" yaml:"policy" validate:"oneof=follow rewrite"`
+}
+
+// 远端返回指向CDN（如cdn-lfs）的带签名地址时，改写为本镜像按仓库、版本及文件路径访问的地址，
+// 由镜像跟随签名地址拉取并按文件缓存，避免签名地址变化导致缓存失效。
+type CdnCache struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Hosts   []string `json:"hosts" yaml:"hosts"` // 允许的CDN域名，使用path.Match语法，为空时使用huggingface的CDN域名
+}
+
+// 跟踪的仓库分支或标签指向新的commit时，通知下游系统（如CI/CD重新构建内置模型的镜像）。
+type RevisionNotify struct {
+	Enabled   bool         `json:"enabled" yaml:"enabled"`
+	Repos     []string     `json:"repos" yaml:"repos"`         // 跟踪的仓库，{repoType}/{org}/{repo}格式的glob，为空表示全部仓库
+	Revisions []string     `json:"revisions" yaml:"revisions"` // 跟踪的分支或标签，为空时为main
+	Sinks     []NotifySink `json:"sinks" yaml:"sinks" validate:"dive"`
+}
+
+// NotifySink 通知的目标，type为webhook时POST JSON；kafka经由Kafka REST Proxy写入topic；nats发布到subject。
+type NotifySink struct {
+	Type    string            `json:"type" yaml:"type" validate:"oneof=webhook kafka nats"`
+	Url     string            `json:"url" yaml:"url" validate:"required"` // webhook地址、REST Proxy地址或nats://host:4222
+	Topic   string            `json:"topic" yaml:"topic"`                 // kafka的topic或nats的subject
+	Secret  string            `json:"-" yaml:"secret"`                    // webhook的HMAC-SHA256签名密钥，为空时不签名
+	Headers map[string]string `json:"headers" yaml:"headers"`             // webhook及kafka请求附加的头部
+	Timeout int               `json:"timeout" yaml:"timeout"`             // 单次发送超时，单位秒，默认10秒
+}
+
+func (c *Config) GetNotifyRevisions() []string {
+	if len(c.RevisionNotify.Revisions) == 0 {
+		return []string{"main"}
+	}
+	return c.RevisionNotify.Revisions
+}
+
+func (s *NotifySink) GetTimeout() time.Duration {
+	if s.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(s.Timeout) * time.Second
+}
+
+// datasets-server接口（数据集预览）代理，响应按查询参数缓存，远端不可用或离线时返回已缓存的内容。
+type DatasetsServer struct {
+	Endpoint   string `json:"endpoint" yaml:"endpoint"`
+	Expiration int    `json:"expiration" yaml:"expiration"` // 在线时缓存的有效期，单位分钟，过期后重新请求远端
+}
+
+// 未适配的远端接口原样转发时的策略，cacheRules匹配的GET请求按路径及查询参数缓存，离线时返回已缓存的内容。
+type Forward struct {
+	Timeout        int                `json:"timeout" yaml:"timeout"`         // 单次转发的超时时间，单位秒，0表示不限制
+	MaxBodySize    int64              `json:"maxBodySize" yaml:"maxBodySize"` // 转发响应体的大小上限，单位字节，超过时中止，0表示不限制
+	Retry          bool               `json:"retry" yaml:"retry"`             // GET/HEAD请求连接失败或远端返回5xx时按retry配置重试
+	CacheRules     []ForwardCacheRule `json:"cacheRules" yaml:"cacheRules" validate:"dive"`
+	RequestHeaders []HeaderRule       `json:"requestHeaders" yaml:"requestHeaders" validate:"dive"` // 转发前作用于请求头的规则
+}
+
+// ForwardCacheRule path使用path.Match语法。
+type ForwardCacheRule struct {
+	Path       string `json:"path" yaml:"path" validate:"required"`
+	Expiration int    `json:"expiration" yaml:"expiration"` // 在线时缓存的有效期，单位秒，默认300
+}
+
+func (r *ForwardCacheRule) GetExpiration() time.Duration {
+	if r.Expiration <= 0 {
+		return 300 * time.Second
+	}
+	return time.Duration(r.Expiration) * time.Second
+}
+
+// GetForwardCacheRule 返回该请求路径匹配的第一条缓存规则，不匹配时为nil。
+func (c *Config) GetForwardCacheRule(urlPath string) *ForwardCacheRule {
+	for i := range c.Forward.CacheRules {
+		if ok, _ := path.Match(c.Forward.CacheRules[i].Path, urlPath); ok {
+			return &c.Forward.CacheRules[i]
+		}
+	}
+	return nil
+}
+
+// GetForwardRequestHeaderRules 返回作用于该转发路径的请求头规则。
+func (c *Config) GetForwardRequestHeaderRules(urlPath string) []HeaderRule {
+	var rules []HeaderRule
+	for _, rule := range c.Forward.RequestHeaders {
+		if rule.Match(urlPath) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// 按功能关闭代理的HF接口，只暴露部署所需的最小接口，关闭的接口返回404。
+type ApiSurface struct {
+	Disabled []string `json:"disabled" yaml:"disabled" validate:"dive,oneof=search commits forward upload datasetsServer"`
+}
+
+func (c *Config) ApiFeatureEnabled(feature string) bool {
+	return !slices.Contains(c.ApiSurface.Disabled, feature)
+}
+
+// 请求影子转发，按比例将GET/HEAD请求复制到另一个实例（如新版本），比较状态码及响应头，用于升级前验证。
+type Shadow struct {
+	Enabled        bool     `json:"enabled" yaml:"enabled"`
+	Target         string   `json:"target" yaml:"target" validate:"required_if=Enabled true,omitempty,url"` // 影子实例地址，如http://10.0.0.2:8090
+	Percent        float64  `json:"percent" yaml:"percent" validate:"min=0,max=100"`                        // 复制的请求比例，0-100
+	Timeout        int      `json:"timeout" yaml:"timeout"`                                                 // 等待影子实例响应头的超时，单位秒
+	MaxConcurrent  int      `json:"maxConcurrent" yaml:"maxConcurrent"`                                     // 同时进行的影子请求上限，超过时丢弃
+	CompareHeaders []string `json:"compareHeaders" yaml:"compareHeaders"`                                   // 需要比较的响应头
+}
+
+func (c *Config) GetShadowTimeout() time.Duration {
+	if c.Shadow.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.Shadow.Timeout) * time.Second
+}
+
+func (c *Config) GetShadowMaxConcurrent() int {
+	if c.Shadow.MaxConcurrent <= 0 {
+		return 16
+	}
+	return c.Shadow.MaxConcurrent
+}
+
+func (c *Config) GetShadowCompareHeaders() []string {
+	if len(c.Shadow.CompareHeaders) == 0 {
+		return []string{"Content-Type", "Content-Length", "ETag", "X-Repo-Commit", "X-Linked-Etag", "X-Linked-Size", "Location"}
+	}
+	return c.Shadow.CompareHeaders
+}
+
+// 通用缓存代理，/proxy?url=...按块缓存allowHosts中的非HF文件（如S3、http上的数据集），与模型文件共用缓存及range逻辑。
+type GenericProxy struct {
+	Enabled     bool     `json:"enabled" yaml:"enabled"`
+	AllowHosts  []string `json:"allowHosts" yaml:"allowHosts"`   // 允许代理的主机，使用path.Match语法，如*.s3.amazonaws.com，重定向的目标同样需在其中
+	IgnoreQuery bool     `json:"ignoreQuery" yaml:"ignoreQuery"` // 缓存键忽略查询参数，适用于每次签名不同的预签名URL
+	Expiration  int      `json:"expiration" yaml:"expiration"`   // 源站文件信息的缓存时长，单位分钟，过期后向源站确认文件是否变化
+}
+
+func (c *Config) GetGenericProxyExpiration() time.Duration {
+	if c.GenericProxy.Expiration <= 0 {
+		return 60 * time.Minute
+	}
+	return time.Duration(c.GenericProxy.Expiration) * time.Minute
+}
+
+// 故障注入，仅用于测试环境：对客户端请求注入延迟、错误及截断的响应，对远端请求注入延迟及失败，用于验证客户端重试及本服务的降级逻辑。
+type Chaos struct {
+	Enabled              bool     `json:"enabled" yaml:"enabled"`
+	Paths                []string `json:"paths" yaml:"paths"`                                                        // 注入故障的请求路径前缀，为空时为/admin/以外的全部请求
+	Latency              int      `json:"latency" yaml:"latency"`                                                    // 客户端请求的固定延迟，单位毫秒
+	LatencyJitter        int      `json:"latencyJitter" yaml:"latencyJitter"`                                        // 在固定延迟上随机增加的延迟上限，单位毫秒
+	ErrorPercent         float64  `json:"errorPercent" yaml:"errorPercent" validate:"min=0,max=100"`                 // 直接返回错误的客户端请求比例，0-100
+	ErrorStatus          int      `json:"errorStatus" yaml:"errorStatus"`                                            // 返回的状态码，默认503
+	TruncatePercent      float64  `json:"truncatePercent" yaml:"truncatePercent" validate:"min=0,max=100"`           // 截断响应体的客户端请求比例，0-100
+	TruncateBytes        int64    `json:"truncateBytes" yaml:"truncateBytes"`                                        // 截断前写入的字节数，0表示Content-Length的一半
+	UpstreamLatency      int      `json:"upstreamLatency" yaml:"upstreamLatency"`                                    // 远端请求的固定延迟，单位毫秒
+	UpstreamErrorPercent float64  `json:"upstreamErrorPercent" yaml:"upstreamErrorPercent" validate:"min=0,max=100"` // 失败的远端请求比例，0-100
+	UpstreamErrorStatus  int      `json:"upstreamErrorStatus" yaml:"upstreamErrorStatus"`                            // 远端失败时返回的状态码，0表示连接错误
+}
+
+func (c *Config) GetChaosErrorStatus() int {
+	if c.Chaos.ErrorStatus <= 0 {
+		return http.StatusServiceUnavailable
+	}
+	return c.Chaos.ErrorStatus
+}
+
+// 远端可用性探测，连续失败达到阈值时切换为离线服务，连续成功达到阈值后切回在线，避免网络抖动导致频繁切换。
+type UpstreamProbe struct {
+	Enabled          bool `json:"enabled" yaml:"enabled"`
+	Interval         int  `json:"interval" yaml:"interval"`                 // 探测间隔，单位秒
+	Timeout          int  `json:"timeout" yaml:"timeout"`                   // 单次探测超时，单位秒
+	FailThreshold    int  `json:"failThreshold" yaml:"failThreshold"`       // 连续失败次数达到该值时切换为离线
+	RecoverThreshold int  `json:"recoverThreshold" yaml:"recoverThreshold"` // 连续成功次数达到该值时切回在线
+}
+
+// 回收站配置，管理员删除的仓库缓存先移入回收站，保留期内可恢复。
+type Trash struct {
+	Retention int `json:"retention" yaml:"retention"` // 保留时间，单位小时
+}
+
+// MarshalYAML 打印配置时隐藏密码。
+func (r Redis) MarshalYAML() (interface{}, error) {
+	type plain Redis
+	p := plain(r)
+	if p.Password != "" {
+		p.Password = "******"
+	}
+	return p, nil
+}
+
+// 错误上报配置，panic及短时间内重复出现的错误日志上报到Sentry或通用webhook。
+type ErrorReport struct {
+	Enabled         bool   `json:"enabled" yaml:"enabled"`
+	SentryDsn       string `json:"-" yaml:"sentryDsn"`
+	Webhook         string `json:"webhook" yaml:"webhook" validate:"omitempty,url"` // 以JSON格式POST错误事件
+	RepeatThreshold int    `json:"repeatThreshold" yaml:"repeatThreshold"`          // 同一位置的错误在窗口内达到该次数时上报
+	RepeatWindow    int    `json:"repeatWindow" yaml:"repeatWindow"`                // 重复错误统计窗口，单位秒
+}
+
+// MarshalYAML 打印配置时隐藏DSN中的密钥。
+func (e ErrorReport) MarshalYAML() (interface{}, error) {
+	type plain ErrorReport
+	p := plain(e)
+	if p.SentryDsn != "" {
+		p.SentryDsn = "******"
+	}
+	return p, nil
 }
 
 type ReadBlock struct {
@@ -100,102 +919,509 @@ type ReadBlock struct {
 	PrefetchBlockTTL            int64   `json:"prefetchBlockTTL" yaml:"prefetchBlockTTL" validate:"min=1,max=120"` // 读取块数据，预先缓存的块数据数量
 }
 
-type Scheduler struct {
-	OriginMode   string    `json:"-" yaml:"-"` // 记录原始状态
-	Mode         string    `json:"mode" yaml:"mode"`
-	Addr         string    `json:"addr" yaml:"addr"`
-	Strategy     Strategy  `json:"strategy" yaml:"strategy"`
-	Discovery    Discovery `json:"discovery" yaml:"discovery"`
-	PublicDomain string    `json:"publicDomain" yaml:"publicDomain"`
-	LinkDomain   string    `json:"linkDomain" yaml:"linkDomain"`
+type Scheduler struct {
+	OriginMode   string    `json:"-" yaml:"-"` // 记录原始状态
+	Mode         string    `json:"mode" yaml:"mode"`
+	Addr         string    `json:"addr" yaml:"addr"`
+	Strategy     Strategy  `json:"strategy" yaml:"strategy"`
+	Discovery    Discovery `json:"discovery" yaml:"discovery"`
+	PublicDomain string    `json:"publicDomain" yaml:"publicDomain"`
+	LinkDomain   string    `json:"linkDomain" yaml:"linkDomain"`
+	// 按仓库类型（models/datasets/spaces）配置的文件下载链接域名，如CDN地址
+	CdnDomains map[string]string `json:"cdnDomains" yaml:"cdnDomains"`
+	// 生成链接时优先使用反向代理传递的X-Forwarded-Host/X-Forwarded-Proto/X-Forwarded-Prefix
+	ForwardedHost bool `json:"forwardedHost" yaml:"forwardedHost"`
+	// 镜像的路径前缀，如/hf，带前缀的请求在路由前去掉前缀，生成的链接及相对Location补上前缀
+	PathPrefix string `json:"pathPrefix" yaml:"pathPrefix"`
+	// 文件下载链接模板，为空时为{base}{prefix}/{repoType}/{orgRepo}/resolve/{revision}/{path}，占位符见util.FileLinkFunc
+	LinkTemplate string `json:"linkTemplate" yaml:"linkTemplate"`
+}
+
+type Strategy struct {
+	MinimumFileSize     int64 `json:"minimumFileSize" yaml:"minimumFileSize"`
+	SyncProcessInterval int64 `json:"syncProcessInterval" yaml:"syncProcessInterval"`
+}
+
+type Discovery struct {
+	InstanceId      string `json:"instanceId" yaml:"instanceId"`
+	Host            string `json:"host" yaml:"host"`
+	Port            int    `json:"port" yaml:"port"`
+	HeartbeatPeriod int    `json:"heartbeatPeriod" yaml:"heartbeatPeriod"`
+}
+
+type Retry struct {
+	Delay    int  `json:"delay" yaml:"delay" validate:"min=0,max=60"`
+	Attempts uint `json:"attempts" yaml:"attempts" validate:"min=1,max=5"`
+}
+
+type LogConfig struct {
+	MaxSize      int               `json:"maxSize" yaml:"maxSize"`
+	MaxBackups   int               `json:"maxBackups" yaml:"maxBackups"`
+	MaxAge       int               `json:"maxAge" yaml:"maxAge"`
+	Level        string            `json:"level" yaml:"level"`               // 全局日志级别，为空时按server.mode决定
+	ModuleLevels map[string]string `json:"moduleLevels" yaml:"moduleLevels"` // 按模块（源码目录名）单独设置日志级别
+	Encoder      string            `json:"encoder" yaml:"encoder" validate:"omitempty,oneof=json console"`
+	Outputs      []string          `json:"outputs" yaml:"outputs" validate:"dive,oneof=file stdout stderr syslog"`
+	ErrorFile    string            `json:"errorFile" yaml:"errorFile"` // error及以上级别日志额外写入的文件，为空不写
+	Syslog       SyslogConfig      `json:"syslog" yaml:"syslog"`
+	AccessLog    AccessLogConfig   `json:"accessLog" yaml:"accessLog"`
+}
+
+// 系统日志输出配置，network和address为空时写入本机syslog。
+type SyslogConfig struct {
+	Network string `json:"network" yaml:"network" validate:"omitempty,oneof=udp tcp unix unixgram"`
+	Address string `json:"address" yaml:"address"`
+	Tag     string `json:"tag" yaml:"tag"`
+}
+
+// 访问日志配置，每个请求记录一行，写入单独的文件。
+type AccessLogConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	File    string `json:"file" yaml:"file"`
+}
+
+type TokenBucketLimit struct {
+	Capacity        int       `json:"capacity" yaml:"capacity"`
+	Rate            int       `json:"rate" yaml:"rate"`
+	HandlerCapacity int       `json:"handlerCapacity" yaml:"handlerCapacity"`
+	Priority        Priority  `json:"priority" yaml:"priority"`
+	FairShare       FairShare `json:"fairShare" yaml:"fairShare"`
+}
+
+// 客户端下载带宽的加权公平分配，按token区分客户端，未携带token时按IP区分，同一客户端的所有连接共享其分得的带宽。
+type FairShare struct {
+	Enabled       bool           `json:"enabled" yaml:"enabled"`
+	Bandwidth     int            `json:"bandwidth" yaml:"bandwidth"`         // 客户端下载的总带宽，单位Mbps
+	DefaultWeight int            `json:"defaultWeight" yaml:"defaultWeight"` // 未单独配置的客户端的权重
+	Weights       map[string]int `json:"-" yaml:"weights"`                   // token或客户端IP对应的权重
+}
+
+func (c *Config) EnableFairShare() bool {
+	return c.TokenBucketLimit.FairShare.Enabled && c.TokenBucketLimit.FairShare.Bandwidth > 0
+}
+
+// GetFairShareWeight 依次按token、客户端IP查找权重。
+func (c *Config) GetFairShareWeight(token, ip string) int {
+	weights := c.TokenBucketLimit.FairShare.Weights
+	if w, ok := weights[token]; ok && token != "" {
+		return w
+	}
+	if w, ok := weights[ip]; ok {
+		return w
+	}
+	return max(c.TokenBucketLimit.FairShare.DefaultWeight, 1)
+}
+
+type Priority struct {
+	Enabled     bool              `json:"enabled" yaml:"enabled"`
+	Header      string            `json:"header" yaml:"header"`
+	WaitTimeout int               `json:"waitTimeout" yaml:"waitTimeout"` // 等待下载名额的时间，单位秒
+	Tokens      map[string]string `json:"-" yaml:"tokens"`                // token对应的优先级：high,normal,low
+}
+
+type DiskClean struct {
+	Enabled            bool             `json:"enabled" yaml:"enabled"`
+	CacheSizeLimit     int64            `json:"cacheSizeLimit" yaml:"cacheSizeLimit"`
+	CacheCleanStrategy string           `json:"cacheCleanStrategy" yaml:"cacheCleanStrategy"`
+	CollectTimePeriod  int              `json:"collectTimePeriod" yaml:"collectTimePeriod" validate:"min=1,max=600"` // 周期采集内存使用量，单位秒
+	InstanceID         string           `json:"instanceID" yaml:"instanceID"`
+	Namespaces         []CacheNamespace `json:"namespaces" yaml:"namespaces" validate:"dive"`
+}
+
+// 缓存命名空间，按组织划分缓存，各命名空间独立计算配额并按各自的策略清理，
+// 全局清理只删除不属于任何命名空间的文件，避免某个团队的大量缓存挤掉其他团队的文件。
+type CacheNamespace struct {
+	Name               string   `json:"name" yaml:"name" validate:"required"`
+	Orgs               []string `json:"orgs" yaml:"orgs" validate:"min=1"` // 以*结尾表示前缀匹配
+	RepoTypes          []string `json:"repoTypes" yaml:"repoTypes"`        // 为空表示所有仓库类型
+	CacheSizeLimit     int64    `json:"cacheSizeLimit" yaml:"cacheSizeLimit"`
+	CacheCleanStrategy string   `json:"cacheCleanStrategy" yaml:"cacheCleanStrategy"` // 为空时使用diskClean.cacheCleanStrategy
+}
+
+func (n *CacheNamespace) Match(repoType, org string) bool {
+	if len(n.RepoTypes) > 0 && !slices.Contains(n.RepoTypes, repoType) {
+		return false
+	}
+	for _, pattern := range n.Orgs {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(org, prefix) {
+				return true
+			}
+		} else if pattern == org {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *CacheNamespace) GetCleanStrategy() string {
+	if n.CacheCleanStrategy != "" {
+		return n.CacheCleanStrategy
+	}
+	return SysConfig.CacheCleanStrategy()
+}
+
+type DynamicProxy struct {
+	Enabled            bool   `json:"enabled" yaml:"enabled"`
+	HttpProxy          string `json:"httpProxy" yaml:"httpProxy"`
+	HttpProxyConnTest  bool   `json:"httpProxyConnTest" yaml:"httpProxyConnTest"`
+	HttpProxyName      string `json:"httpProxyName" yaml:"httpProxyName"`
+	TimePeriod         int    `json:"timePeriod" yaml:"timePeriod"`
+	MaxContinuousFails int    `json:"maxContinuousFails" yaml:"maxContinuousFails"`
+	Webhook            string `json:"webhook " yaml:"webhook"`
+}
+
+// 上传代理配置
+type Upload struct {
+	CacheBlobs bool `json:"cacheBlobs" yaml:"cacheBlobs"` // 上传成功后将LFS文件缓存到本地
+	ProxyTTL   int  `json:"proxyTTL" yaml:"proxyTTL"`     // 改写后的上传地址有效期，单位分钟
+}
+
+// 多个副本共享同一缓存卷时的协调配置
+type SharedVolume struct {
+	Enabled  bool `json:"enabled" yaml:"enabled"`
+	LeaseTTL int  `json:"leaseTTL" yaml:"leaseTTL"` // 写入及清理租约的有效期，单位秒
+}
+
+type Modelscope struct {
+	OfficialBaseURL string `yaml:"officialBaseURL"`
+	ChunkSize       int64  `yaml:"chunkSize"`
+	MaxRetry        int    `yaml:"maxRetry"`
+	RetryDelay      int    `yaml:"retryDelay"`
+}
+
+func (c *Config) GetHFURLBase() string {
+	return fmt.Sprintf("%s://%s", c.GetHfScheme(), c.GetHfNetLoc())
+}
+
+func (c *Config) GetBpHFURLBase() string {
+	return fmt.Sprintf("%s://%s", c.GetHfScheme(), c.GetBpHfNetLoc())
+}
+
+func (c *Config) GetXetURLBase() string {
+	return fmt.Sprintf("%s://%s", c.GetHfScheme(), c.GetXetNetLoc())
+}
+
+func (c *Config) GetXetNetLoc() string {
+	if c.Server.XetNetLoc == "" {
+		c.Server.XetNetLoc = "cas-bridge.xethub.hf.co"
+	}
+	return c.Server.XetNetLoc
+}
+
+func (c *Config) GetAdaptiveChunkRange() (int, int) {
+	minSize, maxSize := c.Download.Adaptive.MinChunkSize, c.Download.Adaptive.MaxChunkSize
+	if minSize <= 0 {
+		minSize = 16 * 1024
+	}
+	if maxSize <= 0 {
+		maxSize = 1024 * 1024
+	}
+	return int(minSize), int(max(minSize, maxSize))
+}
+
+func (c *Config) GetAdaptiveReadAheadSeconds() int64 {
+	if c.Download.Adaptive.ReadAheadSeconds <= 0 {
+		return 10
+	}
+	return int64(c.Download.Adaptive.ReadAheadSeconds)
+}
+
+func (c *Config) GetResumeTokenExpiration() time.Duration {
+	if c.Download.ResumeToken.Expiration <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(c.Download.ResumeToken.Expiration) * time.Minute
+}
+
+// CompleteOnDisconnect 判断客户端断开时是否在后台继续完成下载，sent为已发送字节数，total为请求范围大小。
+func (c *Config) CompleteOnDisconnect(sent, total int64) bool {
+	policy := c.Cache.CompleteOnDisconnect
+	if !policy.Enabled || total <= 0 {
+		return false
+	}
+	return sent*100 >= total*int64(policy.Threshold)
+}
+
+func (c *Config) GetMinimumFileSize() int64 {
+	return c.Scheduler.Strategy.MinimumFileSize
+}
+
+func (c *Config) GetSyncProcessInterval() int64 {
+	if c.Scheduler.Strategy.SyncProcessInterval < 1 {
+		c.Scheduler.Strategy.SyncProcessInterval = 1
+	}
+	return c.Scheduler.Strategy.SyncProcessInterval
+}
+
+// Online 维护模式下始终离线，手动设置优先于配置及远端探测的结果。
+func (c *Config) Online() bool {
+	if c.InMaintenance() {
+		return false
+	}
+	switch c.GetOnlineMode() {
+	case consts.OnlineModeOnline:
+		return true
+	case consts.OnlineModeOffline:
+		return false
+	}
+	return c.Server.Online && !c.upstreamDown.Load() && !c.budgetExhausted.Load()
+}
+
+func (c *Config) SetOnlineMode(mode string) {
+	c.onlineMode.Store(mode)
+}
+
+func (c *Config) GetOnlineMode() string {
+	if mode, ok := c.onlineMode.Load().(string); ok && mode != "" {
+		return mode
+	}
+	return consts.OnlineModeAuto
+}
+
+// Maintenance 维护模式下只提供已缓存的内容，不请求远端，拒绝管理类修改操作。
+type Maintenance struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+}
+
+func (c *Config) SetMaintenance(enabled bool, message string) *Maintenance {
+	m := &Maintenance{Enabled: enabled}
+	if enabled {
+		m.Message = message
+		m.Since = time.Now()
+	}
+	c.maintenance.Store(m)
+	return m
+}
+
+func (c *Config) GetMaintenance() *Maintenance {
+	if m := c.maintenance.Load(); m != nil {
+		return m
+	}
+	return &Maintenance{}
+}
+
+func (c *Config) InMaintenance() bool {
+	m := c.maintenance.Load()
+	return m != nil && m.Enabled
+}
+
+// SetUpstreamDown 默认远端不可用时切换为离线服务，恢复后切回，配置为离线时不受影响。
+func (c *Config) SetUpstreamDown(down bool) {
+	c.upstreamDown.Store(down)
+}
+
+func (c *Config) UpstreamDown() bool {
+	return c.upstreamDown.Load()
+}
+
+// SetBudgetExhausted 远端请求预算用尽时切换为只提供缓存内容，窗口重置后切回。
+func (c *Config) SetBudgetExhausted(exhausted bool) {
+	c.budgetExhausted.Store(exhausted)
+}
+
+func (c *Config) BudgetExhausted() bool {
+	return c.budgetExhausted.Load()
+}
+
+func (c *Config) GetUpstreamBudgetWindow() string {
+	if c.UpstreamBudget.Window == "" {
+		c.UpstreamBudget.Window = "day"
+	}
+	return c.UpstreamBudget.Window
 }
 
-type Strategy struct {
-	MinimumFileSize     int64 `json:"minimumFileSize" yaml:"minimumFileSize"`
-	SyncProcessInterval int64 `json:"syncProcessInterval" yaml:"syncProcessInterval"`
+func (c *Config) GetDatasetsServerExpiration() time.Duration {
+	return time.Duration(c.DatasetsServer.Expiration) * time.Minute
 }
 
-type Discovery struct {
-	InstanceId      string `json:"instanceId" yaml:"instanceId"`
-	Host            string `json:"host" yaml:"host"`
-	Port            int    `json:"port" yaml:"port"`
-	HeartbeatPeriod int    `json:"heartbeatPeriod" yaml:"heartbeatPeriod"`
+// GetTrustedProxies 解析可信代理配置。
+func (c *Config) GetTrustedProxies() ([]*net.IPNet, error) {
+	return ParseIPNets(c.Server.TrustedProxies)
 }
 
-type Retry struct {
-	Delay    int  `json:"delay" yaml:"delay" validate:"min=0,max=60"`
-	Attempts uint `json:"attempts" yaml:"attempts" validate:"min=1,max=5"`
+// ParseIPNets 解析IP或网段列表，单个IP按/32或/128处理。
+func ParseIPNets(items []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %q", item)
+			}
+			if ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %v", item, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
 }
 
-type LogConfig struct {
-	MaxSize    int `json:"maxSize" yaml:"maxSize"`
-	MaxBackups int `json:"maxBackups" yaml:"maxBackups"`
-	MaxAge     int `json:"maxAge" yaml:"maxAge"`
+// checkNetworkAcl 校验访问控制中的IP及网段。
+func (c *Config) checkNetworkAcl() error {
+	lists := [][]string{c.NetworkAcl.Allow, c.NetworkAcl.Deny}
+	for _, class := range c.NetworkAcl.Classes {
+		lists = append(lists, class.Cidrs)
+	}
+	for _, list := range lists {
+		if _, err := ParseIPNets(list); err != nil {
+			return fmt.Errorf("networkAcl: %v", err)
+		}
+	}
+	return nil
 }
 
-type TokenBucketLimit struct {
-	Capacity        int `json:"capacity" yaml:"capacity"`
-	Rate            int `json:"rate" yaml:"rate"`
-	HandlerCapacity int `json:"handlerCapacity" yaml:"handlerCapacity"`
+// MatchUpstream 返回仓库对应的远端路由规则，无匹配时返回nil。
+func (c *Config) MatchUpstream(repoType, org string) *UpstreamRule {
+	for i := range c.Server.Upstreams {
+		if c.Server.Upstreams[i].Match(repoType, org) {
+			return &c.Server.Upstreams[i]
+		}
+	}
+	return nil
 }
 
-type DiskClean struct {
-	Enabled            bool   `json:"enabled" yaml:"enabled"`
-	CacheSizeLimit     int64  `json:"cacheSizeLimit" yaml:"cacheSizeLimit"`
-	CacheCleanStrategy string `json:"cacheCleanStrategy" yaml:"cacheCleanStrategy"`
-	CollectTimePeriod  int    `json:"collectTimePeriod" yaml:"collectTimePeriod" validate:"min=1,max=600"` // 周期采集内存使用量，单位秒
-	InstanceID         string `json:"instanceID" yaml:"instanceID"`
+// GetUpstreamTLS 返回访问远端地址使用的TLS配置，按scheme及host匹配单独配置了tls的路由规则，
+// 第二个返回值为配置的标识，供复用客户端时区分。
+func (c *Config) GetUpstreamTLS(endpoint string) (*UpstreamTLS, string) {
+	host := upstreamHost(endpoint)
+	for i := range c.Server.Upstreams {
+		rule := &c.Server.Upstreams[i]
+		if rule.Tls != nil && host != "" && upstreamHost(rule.Endpoint) == host {
+			return rule.Tls, host
+		}
+	}
+	return &c.Server.UpstreamTls, ""
 }
 
-type DynamicProxy struct {
-	Enabled            bool   `json:"enabled" yaml:"enabled"`
-	HttpProxy          string `json:"httpProxy" yaml:"httpProxy"`
-	HttpProxyConnTest  bool   `json:"httpProxyConnTest" yaml:"httpProxyConnTest"`
-	HttpProxyName      string `json:"httpProxyName" yaml:"httpProxyName"`
-	TimePeriod         int    `json:"timePeriod" yaml:"timePeriod"`
-	MaxContinuousFails int    `json:"maxContinuousFails" yaml:"maxContinuousFails"`
-	Webhook            string `json:"webhook " yaml:"webhook"`
+// checkUpstreamTls 启动时检查TLS配置引用的文件，避免运行中首次访问远端时才报错。
+func (c *Config) checkUpstreamTls() error {
+	configs := []*UpstreamTLS{&c.Server.UpstreamTls}
+	for i := range c.Server.Upstreams {
+		if c.Server.Upstreams[i].Tls != nil {
+			configs = append(configs, c.Server.Upstreams[i].Tls)
+		}
+	}
+	for _, t := range configs {
+		for _, file := range []string{t.CaFile, t.CertFile, t.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				return fmt.Errorf("upstream tls file: %w", err)
+			}
+		}
+	}
+	return nil
 }
 
-type Modelscope struct {
-	OfficialBaseURL string `yaml:"officialBaseURL"`
-	ChunkSize       int64  `yaml:"chunkSize"`
-	MaxRetry        int    `yaml:"maxRetry"`
-	RetryDelay      int    `yaml:"retryDelay"`
+// checkLock 检查锁后端依赖的配置
+func (c *Config) checkLock() error {
+	switch c.GetLockBackend() {
+	case consts.LockBackendRedis:
+		if !c.Redis.Enabled {
+			return myerr.New("lock backend redis requires redis.enabled")
+		}
+	case consts.LockBackendEtcd:
+		if len(c.Lock.Etcd.Endpoints) == 0 {
+			return myerr.New("lock backend etcd requires lock.etcd.endpoints")
+		}
+	}
+	return nil
 }
 
-func (c *Config) GetHFURLBase() string {
-	return fmt.Sprintf("%s://%s", c.GetHfScheme(), c.GetHfNetLoc())
+// checkNode 节点间接口无其他鉴权，可读取任意缓存文件，启用时必须配置双向TLS
+func (c *Config) checkNode() error {
+	if !c.IsCluster() || c.Server.NodePort == 0 {
+		return nil
+	}
+	if ssl := c.Server.Ssl; ssl.CrtFile == "" || ssl.KeyFile == "" || ssl.CaFile == "" {
+		return myerr.New("server.nodePort requires server.ssl crtFile, keyFile and caFile for mutual TLS")
+	}
+	return nil
 }
 
-func (c *Config) GetBpHFURLBase() string {
-	return fmt.Sprintf("%s://%s", c.GetHfScheme(), c.GetBpHfNetLoc())
+func (c *Config) checkDownloads() error {
+	d := c.Server.Downloads
+	if d.Port == 0 {
+		return nil
+	}
+	if d.Port == c.Server.Port || d.Port == c.Server.PProfPort {
+		return fmt.Errorf("server.downloads.port %d conflicts with server port", d.Port)
+	}
+	if d.Endpoint != "" {
+		if u, err := url.Parse(d.Endpoint); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid server.downloads.endpoint: %s", d.Endpoint)
+		}
+	}
+	return nil
 }
 
-func (c *Config) GetXetURLBase() string {
-	return fmt.Sprintf("%s://%s", c.GetHfScheme(), c.GetXetNetLoc())
+func (c *Config) EnableDownloadsListener() bool {
+	return c.Server.Downloads.Port > 0
 }
 
-func (c *Config) GetXetNetLoc() string {
-	if c.Server.XetNetLoc == "" {
-		c.Server.XetNetLoc = "cas-bridge.xethub.hf.co"
+func upstreamHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
 	}
-	return c.Server.XetNetLoc
+	return u.Scheme + "://" + u.Host
 }
 
-func (c *Config) GetMinimumFileSize() int64 {
-	return c.Scheduler.Strategy.MinimumFileSize
+// IsUpstreamEndpoint 判断地址是否属于配置的远端。
+// GetRedirectPolicy 返回发往远端的请求路径对应的重定向策略。
+func (c *Config) GetRedirectPolicy(uri string) string {
+	for _, rule := range c.Redirect.Rules {
+		if ok, _ := path.Match(rule.Path, uri); ok {
+			return rule.Policy
+		}
+	}
+	if c.Redirect.Policy == "" {
+		return consts.RedirectFollow
+	}
+	return c.Redirect.Policy
 }
 
-func (c *Config) GetSyncProcessInterval() int64 {
-	if c.Scheduler.Strategy.SyncProcessInterval < 1 {
-		c.Scheduler.Strategy.SyncProcessInterval = 1
+// GetCdnCacheHosts 返回允许经由本镜像缓存的CDN域名。
+func (c *Config) GetCdnCacheHosts() []string {
+	if len(c.CdnCache.Hosts) == 0 {
+		return []string{"cdn-lfs*.hf.co", "cdn-lfs*.huggingface.co", "cas-bridge*.xethub.hf.co"}
 	}
-	return c.Scheduler.Strategy.SyncProcessInterval
+	return c.CdnCache.Hosts
 }
 
-func (c *Config) Online() bool {
-	return c.Server.Online
+func (c *Config) IsUpstreamEndpoint(url string) bool {
+	for _, rule := range c.Server.Upstreams {
+		if strings.HasPrefix(url, strings.TrimSuffix(rule.Endpoint, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) GetBufferBudget() int64 {
+	if c.Download.BufferBudget == 0 {
+		return 256 * 1024 * 1024
+	}
+	return c.Download.BufferBudget
+}
+
+func (c *Config) GetSpillDir() string {
+	if c.Download.SpillDir != "" {
+		return c.Download.SpillDir
+	}
+	return filepath.Join(c.Repos(), ".spill")
 }
 
 func (c *Config) Repos() string {
@@ -230,6 +1456,171 @@ func (c *Config) GetReqTimeOut() time.Duration {
 	return time.Duration(c.Download.ReqTimeout) * time.Second
 }
 
+func (c *Config) getClassTimeout(class string) ClassTimeout {
+	switch class {
+	case consts.RequestClassPathsInfo:
+		return c.Download.Timeout.PathsInfo
+	case consts.RequestClassBlob, consts.RequestClassProxy:
+		return c.Download.Timeout.Blob
+	case consts.RequestClassUpload:
+		return c.Download.Timeout.Upload
+	default:
+		return c.Download.Timeout.Meta
+	}
+}
+
+func (c *Config) GetConnectTimeout(class string) time.Duration {
+	if t := c.getClassTimeout(class); t.Connect > 0 {
+		return time.Duration(t.Connect) * time.Second
+	}
+	return 30 * time.Second
+}
+
+func (c *Config) GetReadTimeout(class string) time.Duration {
+	if t := c.getClassTimeout(class); t.Read > 0 {
+		return time.Duration(t.Read) * time.Second
+	}
+	return c.GetReqTimeOut()
+}
+
+func (c *Config) GetMaxIdleConns() int {
+	if c.Download.ConnPool.MaxIdleConns <= 0 {
+		c.Download.ConnPool.MaxIdleConns = 512
+	}
+	return c.Download.ConnPool.MaxIdleConns
+}
+
+func (c *Config) GetMaxIdleConnsPerHost() int {
+	if c.Download.ConnPool.MaxIdleConnsPerHost <= 0 {
+		c.Download.ConnPool.MaxIdleConnsPerHost = 128
+	}
+	return c.Download.ConnPool.MaxIdleConnsPerHost
+}
+
+func (c *Config) GetIdleConnTimeout() time.Duration {
+	if c.Download.ConnPool.IdleConnTimeout <= 0 {
+		c.Download.ConnPool.IdleConnTimeout = 90
+	}
+	return time.Duration(c.Download.ConnPool.IdleConnTimeout) * time.Second
+}
+
+func (c *Config) GetKeepAlive() time.Duration {
+	if c.Download.ConnPool.KeepAlive <= 0 {
+		c.Download.ConnPool.KeepAlive = 30
+	}
+	return time.Duration(c.Download.ConnPool.KeepAlive) * time.Second
+}
+
+func (c *Config) GetTLSHandshakeTimeout() time.Duration {
+	if c.Download.ConnPool.TLSHandshakeTimeout <= 0 {
+		c.Download.ConnPool.TLSHandshakeTimeout = 10
+	}
+	return time.Duration(c.Download.ConnPool.TLSHandshakeTimeout) * time.Second
+}
+
+func (c *Config) GetUploadProxyTTL() time.Duration {
+	if c.Upload.ProxyTTL <= 0 {
+		c.Upload.ProxyTTL = 1440
+	}
+	return time.Duration(c.Upload.ProxyTTL) * time.Minute
+}
+
+func (c *Config) EnableSharedVolume() bool {
+	return c.SharedVolume.Enabled
+}
+
+func (c *Config) GetLeaseTTL() time.Duration {
+	if c.SharedVolume.LeaseTTL <= 0 {
+		c.SharedVolume.LeaseTTL = 30
+	}
+	return time.Duration(c.SharedVolume.LeaseTTL) * time.Second
+}
+
+func (c *Config) GetTrashRetention() time.Duration {
+	if c.Trash.Retention <= 0 {
+		c.Trash.Retention = 72
+	}
+	return time.Duration(c.Trash.Retention) * time.Hour
+}
+
+func (c *Config) GetRevisionGcInterval() time.Duration {
+	if c.RevisionGc.Interval <= 0 {
+		c.RevisionGc.Interval = 24
+	}
+	return time.Duration(c.RevisionGc.Interval) * time.Hour
+}
+
+func (c *Config) GetCurationInterval() time.Duration {
+	if c.Curation.Interval <= 0 {
+		c.Curation.Interval = 24
+	}
+	return time.Duration(c.Curation.Interval) * time.Hour
+}
+
+func (c *Config) GetRevisionGcKeep() int {
+	if c.RevisionGc.Keep <= 0 {
+		c.RevisionGc.Keep = 3
+	}
+	return c.RevisionGc.Keep
+}
+
+// IsRevisionPinned 判断仓库的版本是否被固定，revisions为同一版本的commit及指向它的分支、标签。
+func (c *Config) IsRevisionPinned(repoType, orgRepo string, revisions ...string) bool {
+	for _, revision := range revisions {
+		if slices.Contains(c.RevisionGc.Pins, fmt.Sprintf("%s/%s@%s", repoType, orgRepo, revision)) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLeasePath 返回缓存文件对应的租约文件路径，租约统一存放在locks目录，不参与磁盘清理。
+func (c *Config) GetLeasePath(path string) string {
+	return filepath.Join(c.Repos(), consts.LeaseDir, filepath.FromSlash(c.GetLockKey(path))+".lease")
+}
+
+// GetLockBackend 返回锁后端，未配置时单机使用flock，共享缓存卷使用lease。
+func (c *Config) GetLockBackend() string {
+	if c.Lock.Backend != "" {
+		return c.Lock.Backend
+	}
+	if c.EnableSharedVolume() {
+		return consts.LockBackendLease
+	}
+	return consts.LockBackendFlock
+}
+
+// GetLockKey 返回path在Redis、etcd等锁后端中使用的名称，即相对于缓存目录的路径。
+func (c *Config) GetLockKey(path string) string {
+	rel, err := filepath.Rel(c.Repos(), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (c *Config) GetLockEtcdKeyPrefix() string {
+	if c.Lock.Etcd.KeyPrefix == "" {
+		c.Lock.Etcd.KeyPrefix = "/dingospeed/locks/"
+	}
+	return c.Lock.Etcd.KeyPrefix
+}
+
+func (c *Config) GetLockEtcdTimeout() time.Duration {
+	if c.Lock.Etcd.Timeout <= 0 {
+		c.Lock.Etcd.Timeout = 3000
+	}
+	return time.Duration(c.Lock.Etcd.Timeout) * time.Millisecond
+}
+
+func (c *Config) EnableDns() bool {
+	return len(c.Dns.Nameservers) > 0 || len(c.Dns.Hosts) > 0 || c.Dns.CacheTTL > 0
+}
+
+func (c *Config) GetDnsCacheTTL() time.Duration {
+	return time.Duration(c.Dns.CacheTTL) * time.Second
+}
+
 func (c *Config) GetCollectTimePeriod() time.Duration {
 	if c.Cache.ReadBlock.CollectTimePeriod == 0 {
 		c.Cache.ReadBlock.CollectTimePeriod = 5
@@ -252,6 +1643,87 @@ func (c *Config) GetDefaultExpiration() time.Duration {
 	return time.Duration(c.Cache.DefaultExpiration) * time.Minute
 }
 
+func (c *Config) GetNegativeExpiration() time.Duration {
+	if c.Cache.NegativeExpiration == 0 {
+		c.Cache.NegativeExpiration = 30
+	}
+	return time.Duration(c.Cache.NegativeExpiration) * time.Second
+}
+
+func (c *Config) GetWhoamiExpiration() time.Duration {
+	if c.Cache.WhoamiExpiration <= 0 {
+		c.Cache.WhoamiExpiration = 1440
+	}
+	return time.Duration(c.Cache.WhoamiExpiration) * time.Minute
+}
+
+func (c *Config) GetRefsExpiration() time.Duration {
+	if c.Cache.RefsExpiration == 0 {
+		c.Cache.RefsExpiration = 60
+	}
+	if c.Cache.RefsExpiration < 0 {
+		return 0
+	}
+	return time.Duration(c.Cache.RefsExpiration) * time.Second
+}
+
+func (c *Config) GetSyncPolicy() string {
+	if c.Cache.SyncPolicy == "" {
+		c.Cache.SyncPolicy = consts.SyncPolicyOnClose
+	}
+	return c.Cache.SyncPolicy
+}
+
+func (c *Config) GetSyncInterval() time.Duration {
+	if c.Cache.SyncInterval <= 0 {
+		c.Cache.SyncInterval = 5
+	}
+	return time.Duration(c.Cache.SyncInterval) * time.Second
+}
+
+func (c *Config) EnableEncryption() bool {
+	return c.Cache.Encryption.Enabled
+}
+
+// GetEncryptionKey 加载缓存加密的主密钥，只在首次调用时加载。
+func (c *Config) GetEncryptionKey() ([]byte, error) {
+	encryptionKeyOnce.Do(func() {
+		encryptionKey, encryptionKeyErr = c.loadEncryptionKey()
+	})
+	return encryptionKey, encryptionKeyErr
+}
+
+func (c *Config) loadEncryptionKey() ([]byte, error) {
+	e := c.Cache.Encryption
+	var encoded string
+	switch {
+	case e.Key != "":
+		encoded = e.Key
+	case e.KeyFile != "":
+		b, err := os.ReadFile(e.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key file err.%v", err)
+		}
+		encoded = string(b)
+	case e.KeyCommand != "":
+		out, err := exec.Command("sh", "-c", e.KeyCommand).Output()
+		if err != nil {
+			return nil, fmt.Errorf("exec encryption key command err.%v", err)
+		}
+		encoded = string(out)
+	default:
+		return nil, errors.New("encryption key is not configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode encryption key err.%v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
 func (c *Config) GetCleanupInterval() time.Duration {
 	if c.Cache.CleanupInterval == 0 {
 		c.Cache.CleanupInterval = 60
@@ -289,6 +1761,16 @@ func (c *Config) CacheCleanStrategy() string {
 	return c.DiskClean.CacheCleanStrategy
 }
 
+// GetCacheNamespace 返回仓库所属的缓存命名空间，按配置顺序取第一个匹配项，不属于任何命名空间时返回nil。
+func (c *Config) GetCacheNamespace(repoType, org string) *CacheNamespace {
+	for i := range c.DiskClean.Namespaces {
+		if c.DiskClean.Namespaces[i].Match(repoType, org) {
+			return &c.DiskClean.Namespaces[i]
+		}
+	}
+	return nil
+}
+
 func (c *Config) GetInstanceID() string {
 	return c.DiskClean.InstanceID
 }
@@ -313,6 +1795,24 @@ func (c *Config) GetWebhook() string {
 	return c.DynamicProxy.Webhook
 }
 
+func (c *Config) EnablePriority() bool {
+	return c.TokenBucketLimit.Priority.Enabled
+}
+
+func (c *Config) GetPriorityHeader() string {
+	if c.TokenBucketLimit.Priority.Header == "" {
+		c.TokenBucketLimit.Priority.Header = "X-Dingospeed-Priority"
+	}
+	return c.TokenBucketLimit.Priority.Header
+}
+
+func (c *Config) GetPriorityWaitTimeout() time.Duration {
+	if c.TokenBucketLimit.Priority.WaitTimeout == 0 {
+		c.TokenBucketLimit.Priority.WaitTimeout = 30
+	}
+	return time.Duration(c.TokenBucketLimit.Priority.WaitTimeout) * time.Second
+}
+
 func (c *Config) IsCluster() bool {
 	return c.GetSchedulerModel() == consts.SchedulerModeCluster
 }
@@ -351,6 +1851,102 @@ func (c *Config) SetDefaults() {
 	if c.Server.PProfPort == 0 {
 		c.Server.PProfPort = 6060
 	}
+	if c.Server.PProfHost == "" {
+		c.Server.PProfHost = "127.0.0.1"
+	}
+	if c.Server.Limits.ReadHeaderTimeout <= 0 {
+		c.Server.Limits.ReadHeaderTimeout = 10
+	}
+	if c.Server.Limits.IdleTimeout <= 0 {
+		c.Server.Limits.IdleTimeout = 120
+	}
+	if c.Server.Limits.MaxBodySize == 0 {
+		c.Server.Limits.MaxBodySize = 10 << 20
+	}
+	if c.Server.Downloads.Host == "" {
+		c.Server.Downloads.Host = c.Server.Host
+	}
+	if c.Server.Downloads.ReadHeaderTimeout <= 0 {
+		c.Server.Downloads.ReadHeaderTimeout = c.Server.Limits.ReadHeaderTimeout
+	}
+	if c.Server.Downloads.IdleTimeout <= 0 {
+		c.Server.Downloads.IdleTimeout = c.Server.Limits.IdleTimeout
+	}
+	c.Server.Downloads.Endpoint = strings.TrimSuffix(c.Server.Downloads.Endpoint, "/")
+	for i := range c.Server.Upstreams {
+		c.Server.Upstreams[i].Endpoint = strings.TrimSuffix(c.Server.Upstreams[i].Endpoint, "/")
+	}
+	if len(c.Server.Cors.AllowOrigins) == 0 {
+		c.Server.Cors.AllowOrigins = []string{"*"}
+	}
+	if len(c.Server.Cors.AllowMethods) == 0 {
+		c.Server.Cors.AllowMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+	if len(c.Server.Cors.AllowHeaders) == 0 {
+		c.Server.Cors.AllowHeaders = []string{"*"}
+	}
+	if len(c.Server.Cors.ExposeHeaders) == 0 {
+		c.Server.Cors.ExposeHeaders = []string{"*"}
+	}
+	if c.UpstreamAlert.Window <= 0 {
+		c.UpstreamAlert.Window = 60
+	}
+	if c.UpstreamAlert.MinRequests <= 0 {
+		c.UpstreamAlert.MinRequests = 20
+	}
+	if c.UpstreamAlert.ErrorRate == 0 {
+		c.UpstreamAlert.ErrorRate = 0.5
+	}
+	if c.UpstreamAlert.ProbeInterval <= 0 {
+		c.UpstreamAlert.ProbeInterval = 30
+	}
+	if c.Cache.Index.ExpectedItems <= 0 {
+		c.Cache.Index.ExpectedItems = 10000000
+	}
+	if c.Cache.Index.FalsePositiveRate <= 0 || c.Cache.Index.FalsePositiveRate >= 1 {
+		c.Cache.Index.FalsePositiveRate = 0.01
+	}
+	if c.Cache.Index.RebuildInterval <= 0 {
+		c.Cache.Index.RebuildInterval = 24
+	}
+	if c.UpstreamProbe.Interval <= 0 {
+		c.UpstreamProbe.Interval = 10
+	}
+	if c.UpstreamProbe.Timeout <= 0 {
+		c.UpstreamProbe.Timeout = 5
+	}
+	if c.UpstreamProbe.FailThreshold <= 0 {
+		c.UpstreamProbe.FailThreshold = 3
+	}
+	if c.UpstreamProbe.RecoverThreshold <= 0 {
+		c.UpstreamProbe.RecoverThreshold = 2
+	}
+	if c.DatasetsServer.Endpoint == "" {
+		c.DatasetsServer.Endpoint = "https://datasets-server.huggingface.co"
+	}
+	if c.DatasetsServer.Expiration <= 0 {
+		c.DatasetsServer.Expiration = 60
+	}
+	if c.UpstreamThrottle.MaxWait == 0 {
+		c.UpstreamThrottle.MaxWait = 10
+	}
+	if c.UpstreamThrottle.DefaultPause <= 0 {
+		c.UpstreamThrottle.DefaultPause = 30
+	}
+	if c.UpstreamThrottle.MaxPause <= 0 {
+		c.UpstreamThrottle.MaxPause = 600
+	}
+	for i := range c.Curation.Rules {
+		if c.Curation.Rules[i].Sort == "" {
+			c.Curation.Rules[i].Sort = "downloads"
+		}
+		if c.Curation.Rules[i].Limit <= 0 {
+			c.Curation.Rules[i].Limit = 10
+		}
+	}
+	if len(c.Prefetch.Triggers) == 0 {
+		c.Prefetch.Triggers = []string{"config.json", "tokenizer.json"}
+	}
 	if c.Download.GoroutineMaxNumPerFile == 0 {
 		c.Download.GoroutineMaxNumPerFile = 8
 	}
@@ -360,13 +1956,34 @@ func (c *Config) SetDefaults() {
 	if c.Download.RespChunkSize == 0 {
 		c.Download.RespChunkSize = 2048
 	}
+	if c.Redis.KeyPrefix == "" {
+		c.Redis.KeyPrefix = "dingospeed:"
+	}
+	if c.Redis.Timeout <= 0 {
+		c.Redis.Timeout = 200
+	}
+	if c.ErrorReport.RepeatThreshold <= 0 {
+		c.ErrorReport.RepeatThreshold = 10
+	}
+	if c.ErrorReport.RepeatWindow <= 0 {
+		c.ErrorReport.RepeatWindow = 60
+	}
+	if c.Log.Encoder == "" {
+		c.Log.Encoder = "json"
+	}
+	if len(c.Log.Outputs) == 0 {
+		c.Log.Outputs = []string{"file", "stdout"}
+	}
+	if c.Log.Syslog.Tag == "" {
+		c.Log.Syslog.Tag = "dingospeed"
+	}
+	if c.Log.AccessLog.File == "" {
+		c.Log.AccessLog.File = "log/access.log"
+	}
 	if c.Download.RemoteFileRangeWaitTime == 0 {
 		c.Download.RemoteFileRangeWaitTime = 1
 	}
 
-	if c.Download.RespChanSize == 0 {
-		c.Download.RespChanSize = 30
-	}
 	if c.DiskClean.CollectTimePeriod == 0 {
 		c.DiskClean.CollectTimePeriod = 1
 	}
@@ -376,17 +1993,27 @@ func (c *Config) SetDefaults() {
 	}
 }
 
-func Scan(path string) (*Config, error) {
+// Scan 读取配置文件，profile不为空时先加载对应的内置预设，再由配置文件覆盖，
+// 最后应用DINGOSPEED_*环境变量及--set参数，优先级见override.go。
+func Scan(path, profile string, sets ...string) (*Config, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
 	var c Config
+	if profile != "" {
+		if err = applyProfile(&c, profile); err != nil {
+			return nil, err
+		}
+	}
 	err = yaml.Unmarshal(b, &c)
 	if err != nil {
 		return nil, err
 	}
+	if err = applyOverrides(&c, os.Environ(), sets); err != nil {
+		return nil, err
+	}
 	c.SetDefaults()
 
 	if c.Download.RemoteFileRangeSize%c.Download.BlockSize != 0 {
@@ -401,6 +2028,32 @@ func Scan(path string) (*Config, error) {
 		}
 		return nil, err
 	}
+	if _, err = c.GetTrustedProxies(); err != nil {
+		return nil, err
+	}
+	if err = c.checkNetworkAcl(); err != nil {
+		return nil, err
+	}
+	if err = c.checkUpstreamTls(); err != nil {
+		return nil, err
+	}
+	if err = c.checkLock(); err != nil {
+		return nil, err
+	}
+	if err = c.checkDownloads(); err != nil {
+		return nil, err
+	}
+	if err = c.checkNode(); err != nil {
+		return nil, err
+	}
+	if t := c.Scheduler.LinkTemplate; t != "" && !strings.Contains(t, "{path}") {
+		return nil, fmt.Errorf("scheduler.linkTemplate must contain {path}: %s", t)
+	}
+	if c.EnableEncryption() {
+		if _, err = c.GetEncryptionKey(); err != nil {
+			return nil, err
+		}
+	}
 	SysConfig = &c // 设置全局配置变量
 
 	marshal, err := yaml.Marshal(c)
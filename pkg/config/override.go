@@ -0,0 +1,120 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 配置项的覆盖优先级：内置默认值 < --profile预设 < 配置文件 < DINGOSPEED_*环境变量 < --set参数。
+// 配置项以yaml路径表示，如server.online；环境变量为路径的大写形式，层级及驼峰处的单词以下划线分隔，
+// 如DINGOSPEED_SERVER_ONLINE、DINGOSPEED_SERVER_HF_NET_LOC。
+// 值按yaml解析，列表及对象可写为[a, b]、{k: v}。
+const EnvPrefix = "DINGOSPEED_"
+
+// Overrides 收集可重复的--set key=value参数。
+type Overrides []string
+
+func (o *Overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *Overrides) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("invalid override %q, expected key=value", v)
+	}
+	*o = append(*o, v)
+	return nil
+}
+
+// applyOverrides 先应用环境变量，再应用--set参数。未知的环境变量忽略，未知的--set配置项返回错误。
+func applyOverrides(c *Config, environ []string, sets []string) error {
+	fields := make(map[string]reflect.Value)
+	collectFields(reflect.ValueOf(c).Elem(), "", fields)
+
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		field, ok := fields[normalizeKey(strings.TrimPrefix(name, EnvPrefix))]
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("env %s: %w", name, err)
+		}
+	}
+	for _, kv := range sets {
+		key, value, _ := strings.Cut(kv, "=")
+		field, ok := fields[normalizeKey(key)]
+		if !ok {
+			return fmt.Errorf("unknown config key %q", key)
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("--set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// collectFields 按yaml路径收集所有可设置的配置项，嵌套的结构体同时作为整体及逐个字段登记。
+func collectFields(v reflect.Value, prefix string, fields map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		fields[normalizeKey(key)] = v.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			collectFields(v.Field(i), key, fields)
+		}
+	}
+}
+
+// normalizeKey 忽略大小写及分隔符，使server.hfNetLoc与SERVER_HF_NET_LOC对应同一配置项。
+func normalizeKey(key string) string {
+	key = strings.NewReplacer(".", "", "_", "", "-", "").Replace(key)
+	return strings.ToLower(key)
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Kind() == reflect.String {
+		field.SetString(value)
+		return nil
+	}
+	ptr := reflect.New(field.Type())
+	if field.Kind() == reflect.Struct {
+		ptr.Elem().Set(field) // 对象按字段合并，未指定的字段保持原值
+	}
+	if err := yaml.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		return err
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
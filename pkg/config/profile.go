@@ -0,0 +1,51 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package config
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 内置的部署预设，通过--profile选择，作为配置文件的默认值，配置文件中显式设置的项优先。
+//
+//go:embed profiles/*.yaml
+var profilesFS embed.FS
+
+func ProfileNames() []string {
+	entries, err := profilesFS.ReadDir("profiles")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func applyProfile(c *Config, profile string) error {
+	b, err := profilesFS.ReadFile("profiles/" + profile + ".yaml")
+	if err != nil {
+		return fmt.Errorf("unknown profile %q, available: %s", profile, strings.Join(ProfileNames(), ", "))
+	}
+	return yaml.Unmarshal(b, c)
+}
@@ -36,15 +36,66 @@ func (a RepoType) Value() string {
 }
 
 const (
-	HUGGINGFACE_HEADER_CONTENT_LENGTH = "content-length"
-	HUGGINGFACE_HEADER_ETAG           = "etag"
-	HUGGINGFACE_HEADER_X_REPO_COMMIT  = "X-Repo-Commit"
-	HUGGINGFACE_HEADER_X_LINKED_ETAG  = "X-Linked-Etag"
-	HUGGINGFACE_HEADER_X_LINKED_SIZE  = "X-Linked-Size"
-	HUGGINGFACE_HEADER_X_XET_HASH     = "X-Xet-Hash"
-	HUGGINGFACE_LOCATION              = "Location"
-	HUGGINGFACE_Link                  = "Link"
+	HUGGINGFACE_HEADER_CONTENT_LENGTH      = "content-length"
+	HUGGINGFACE_HEADER_ETAG                = "etag"
+	HUGGINGFACE_HEADER_ACCEPT_RANGES       = "accept-ranges"
+	HUGGINGFACE_HEADER_CONTENT_DISPOSITION = "content-disposition"
+	HUGGINGFACE_HEADER_X_REPO_COMMIT       = "X-Repo-Commit"
+	HUGGINGFACE_HEADER_X_LINKED_ETAG       = "X-Linked-Etag"
+	HUGGINGFACE_HEADER_X_LINKED_SIZE       = "X-Linked-Size"
+	HUGGINGFACE_HEADER_X_XET_HASH          = "X-Xet-Hash"
+	HUGGINGFACE_LOCATION                   = "Location"
+	HUGGINGFACE_Link                       = "Link"
 )
+
+// huggingface返回的X-Error-Code
+const (
+	HfErrorCodeRepoNotFound     = "RepoNotFound"
+	HfErrorCodeRevisionNotFound = "RevisionNotFound"
+	HfErrorCodeEntryNotFound    = "EntryNotFound"
+	HfErrorCodeGatedRepo        = "GatedRepo"
+	HfErrorCodeBadRequest       = "BadRequest"
+	HfHeaderErrorCode           = "x-error-code"
+	HfHeaderErrorMessage        = "x-error-message"
+)
+
+// 离线时由本地缓存拼出的响应，标记内容可能已过期及最后更新时间
+const (
+	HeaderStale       = "X-Dingospeed-Stale"
+	HeaderLastUpdated = "X-Dingospeed-Last-Updated"
+	// 请求的版本未缓存时实际响应的版本
+	HeaderFallbackRevision = "X-Dingospeed-Fallback-Revision"
+)
+
+// 错误响应的分类，见myerr.Kind
+const HeaderErrorKind = "X-Dingospeed-Error-Kind"
+
+// 影子转发的请求携带此头部，影子实例不再继续转发
+const HeaderShadow = "X-Dingospeed-Shadow"
+
+// 通用缓存代理的文件在下载统计中使用的类型，仓库为源站主机
+const RepoTypeProxy = "proxy"
+
+// 请求的仓库已改名时，响应携带改名后的{repoType}/{org}/{repo}
+const HeaderRepoAlias = "X-Dingospeed-Repo-Alias"
+
+// 注入故障的响应携带的头，值为注入的故障类型
+const HeaderChaos = "X-Dingospeed-Chaos"
+
+// 缓存命中情况，请求带debug=1时附加缓存路径、缓存时长（秒）及使用的远端
+const (
+	HeaderCache     = "X-Cache"
+	HeaderCachePath = "X-Dingospeed-Cache-Path"
+	HeaderCacheAge  = "X-Dingospeed-Cache-Age"
+	HeaderUpstream  = "X-Dingospeed-Upstream"
+)
+
+// 大文件下载的续传令牌，在响应头中下发，客户端重连时通过请求头显式携带
+const HeaderResumeToken = "X-Dingospeed-Resume-Token"
+
+// 代理的datasets-server接口，/info与镜像自身的接口冲突，不做代理
+var DatasetsServerEndpoints = []string{"splits", "rows", "parquet", "first-rows", "size", "is-valid"}
+
 const MAX_HTTP_DOWNLOAD_SIZE = 50 * 1000 * 1000 * 1000 // 50 GB
 
 const (
@@ -55,6 +106,7 @@ const (
 const RespChanSize = 100
 const PromSource = "source"
 const PromOrgRepo = "orgRepo"
+const CdnCacheServing = "cdnCacheServing" // 请求经由/cdn地址访问，响应中不再返回Location
 
 const (
 	VersionOrigin           = 0
@@ -63,6 +115,28 @@ const (
 	SchedulerModeCluster    = "cluster"
 )
 
+// 在线状态的手动设置，auto表示按配置及远端探测结果自动切换
+const (
+	OnlineModeAuto    = "auto"
+	OnlineModeOnline  = "online"
+	OnlineModeOffline = "offline"
+)
+
+// 远端元数据请求返回重定向时的处理策略
+const (
+	RedirectFollow  = "follow"
+	RedirectRewrite = "rewrite"
+)
+
+// 可按配置关闭的代理接口
+const (
+	ApiFeatureSearch         = "search"
+	ApiFeatureCommits        = "commits"
+	ApiFeatureForward        = "forward"
+	ApiFeatureUpload         = "upload"
+	ApiFeatureDatasetsServer = "datasetsServer"
+)
+
 var RpcRequestTimeout = time.Duration(300) * time.Second
 
 const (
@@ -99,13 +173,149 @@ const (
 
 	KeyProcessId        = "processId"
 	KeyMasterInstanceId = "masterInstanceId"
+	KeyBackground       = "background"   // 标记后台传输，受时间窗口与带宽限制
+	KeyNetworkClass     = "networkClass" // 客户端IP所属的限速等级
+)
+
+// 预热任务中单个文件的状态
+const (
+	FileStatusPending     = "pending"
+	FileStatusDownloading = "downloading"
+	FileStatusComplete    = "complete"
+	FileStatusFailed      = "failed"
 )
 
 const (
 	TaskMoreErrMsg = "当前缓存任务较多导致启动失败，请稍后再启动。"
 )
 
+const (
+	PriorityLow    = 0
+	PriorityNormal = 1
+	PriorityHigh   = 2
+)
+
+var PriorityMapping = map[string]int{
+	"low":    PriorityLow,
+	"normal": PriorityNormal,
+	"high":   PriorityHigh,
+}
+
+const PrivateCacheDir = "private"
+
+// 缓存写入的刷盘策略
+const (
+	SyncPolicyNever    = "never"    // 不主动刷盘，由操作系统决定
+	SyncPolicyOnClose  = "on-close" // 缓存文件关闭时刷盘
+	SyncPolicyPeriodic = "periodic" // 周期性刷盘已打开的缓存文件，关闭时刷盘剩余数据
+)
+
+const (
+	LeaseDir       = "locks"  // 共享缓存卷时租约文件的存放目录
+	LeaderLockName = "leader" // 后台任务（磁盘清理等）的leader锁，lease后端对应locks/leader.lease
+)
+
+// 锁后端
+const (
+	LockBackendFlock = "flock" // 单机flock
+	LockBackendLease = "lease" // 共享缓存卷上的租约文件
+	LockBackendRedis = "redis"
+	LockBackendEtcd  = "etcd"
+)
+
+const MaxModelCardSize = 1024 * 1024 // 模型卡片最多读取1MB
+
+const PathsInfoBatchSize = 200 // 批量请求paths-info时每次携带的文件数
+
+const (
+	RepoFilesIndexExpiration = 5 * time.Minute // 仓库文件索引在内存中的有效期，写入新的paths-info时立即失效
+	RepoFilesMaxLimit        = 10000           // 文件列表分页时单页最多返回的条目数
+)
+
+// 仓库的缓存完整度，full为全部文件已完整缓存，partial为部分缓存，meta为只缓存了元数据
+const (
+	RepoTierFull       = "full"
+	RepoTierPartial    = "partial"
+	RepoTierMeta       = "meta"
+	RepoTierExpiration = time.Minute // 缓存完整度在内存中的有效期，避免每次浏览仓库列表都遍历缓存文件
+)
+
+const EventKeepAlive = 15 * time.Second // 事件流无事件时发送注释行的间隔，避免被代理判定为空闲连接断开
+
+const ReadAheadExpiration = 10 * time.Minute // 顺序读取状态的有效期，超时后重新判断是否为顺序读取
+
+const (
+	TrashDir       = "trash"      // 管理员删除的仓库缓存暂存目录，保留期内可恢复
+	TrashEntryFile = "entry.json" // 回收站条目的描述文件
+)
+
+const DefaultTopStatsNum = 20 // 热度排行默认返回的条目数
+
+const DefaultIdleDays = 30 // 闲置仓库报告默认的闲置天数
+
+// 缓存整理的阶段
+const (
+	CompactPhaseBlobs = "blobs" // 合并相同内容的blob
+	CompactPhaseDirs  = "dirs"  // 删除空的版本目录
+	CompactPhaseMeta  = "meta"  // 重写元数据文件
+	CompactPhaseDone  = "done"
+)
+
+// IdleHeatmapDays 闲置仓库报告按闲置天数分段的边界
+var IdleHeatmapDays = []int{1, 7, 30, 90, 180}
+
+const OciExportDir = "oci" // 导出OCI布局目录的根目录，位于repos下
+
+const CaptureDir = "captures" // 抓包记录的根目录，位于repos下，每次抓包一个子目录
+
+// OCI导出任务状态
+const (
+	OciExportRunning = "running"
+	OciExportSuccess = "success"
+	OciExportFailed  = "failed"
+)
+
+// 批量管理操作
+const (
+	BatchActionPurge    = "purge"
+	BatchActionPin      = "pin"
+	BatchActionUnpin    = "unpin"
+	BatchActionPrefetch = "prefetch"
+)
+
+// 批量管理任务及其中每个仓库的状态
+const (
+	BatchStatusPending = "pending"
+	BatchStatusRunning = "running"
+	BatchStatusSuccess = "success"
+	BatchStatusFailed  = "failed"
+	BatchStatusSkipped = "skipped" // 没有需要处理的版本
+)
+
+const (
+	DefaultPreviewSize = 64 * 1024   // 文件预览默认返回的字节数
+	MaxPreviewSize     = 1024 * 1024 // 文件预览最多返回的字节数
+)
+
+const (
+	HeaderReadChunkSize = 1024 * 1024       // 读取文件头时每次range请求的大小
+	MaxGgufHeaderSize   = 256 * 1024 * 1024 // gguf文件头（含词表）最多读取256MB
+)
+
+// 远端请求类型，用于区分超时配置
+const (
+	RequestClassMeta      = "meta"
+	RequestClassPathsInfo = "pathsInfo"
+	RequestClassBlob      = "blob"
+	RequestClassUpload    = "upload"
+	RequestClassProxy     = "proxy" // 通用缓存代理向源站的请求，超时同blob
+)
+
 const (
 	ModelCacheRoot   = "modelscope/models"
 	DatasetCacheRoot = "modelscope/datasets"
 )
+
+const LayoutFile = "layout.json" // 记录缓存目录布局版本的文件，位于repos下
+
+const RestoreStateFile = "restore.json" // 从备份恢复的状态，未完成或校验失败时服务拒绝启动
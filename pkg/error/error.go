@@ -16,8 +16,10 @@ package myerr
 
 type Error struct {
 	statusCode int
+	errorCode  string
 	msg        string
 	err        error
+	kind       Kind
 }
 
 func (e Error) Error() string {
@@ -27,6 +29,12 @@ func (e Error) Error() string {
 func (e Error) StatusCode() int {
 	return e.statusCode
 }
+
+// ErrorCode 与huggingface的X-Error-Code保持一致，如RepoNotFound
+func (e Error) ErrorCode() string {
+	return e.errorCode
+}
+
 func (e Error) Cause(err error) {
 	e.err = err
 }
@@ -43,6 +51,10 @@ func NewAppendCode(code int, msg string) Error {
 	return Error{msg: msg, statusCode: code}
 }
 
+func NewErrorCode(code int, errorCode, msg string) Error {
+	return Error{msg: msg, statusCode: code, errorCode: errorCode}
+}
+
 func Wrap(msg string, err error) Error {
 	return Error{msg: msg, err: err}
 }
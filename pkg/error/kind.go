@@ -0,0 +1,133 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package myerr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Kind 错误分类，用于按类型统计错误及客户端诊断，未显式指定时按状态码及错误编码推断。
+type Kind string
+
+const (
+	KindBadRequest          Kind = "BadRequest"
+	KindUnauthorized        Kind = "Unauthorized"
+	KindGated               Kind = "Gated"
+	KindNotFound            Kind = "NotFound"
+	KindQuotaExceeded       Kind = "QuotaExceeded" // 客户端超出本镜像的限流或配额
+	KindUpstreamRateLimited Kind = "UpstreamRateLimited"
+	KindUpstreamTimeout     Kind = "UpstreamTimeout"
+	KindUpstreamError       Kind = "UpstreamError"
+	KindOfflineMiss         Kind = "OfflineMiss" // 离线时缓存未命中
+	KindCacheCorrupt        Kind = "CacheCorrupt"
+	KindUnavailable         Kind = "Unavailable"         // 维护模式等暂不可用
+	KindInsufficientStorage Kind = "InsufficientStorage" // 磁盘剩余空间不足以缓存文件
+	KindInternal            Kind = "Internal"
+)
+
+// StatusCode 该类错误对应的HTTP状态码。
+func (k Kind) StatusCode() int {
+	switch k {
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindGated:
+		return http.StatusForbidden
+	case KindNotFound, KindOfflineMiss:
+		return http.StatusNotFound
+	case KindQuotaExceeded, KindUpstreamRateLimited:
+		return http.StatusTooManyRequests
+	case KindUpstreamTimeout:
+		return http.StatusGatewayTimeout
+	case KindUpstreamError:
+		return http.StatusBadGateway
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindInsufficientStorage:
+		return http.StatusInsufficientStorage
+	}
+	return http.StatusInternalServerError
+}
+
+// InferKind 根据状态码及huggingface的错误编码推断错误分类。
+func InferKind(statusCode int, errorCode string) Kind {
+	if errorCode == "GatedRepo" {
+		return KindGated
+	}
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return KindBadRequest
+	case statusCode == http.StatusUnauthorized:
+		return KindUnauthorized
+	case statusCode == http.StatusForbidden:
+		return KindGated
+	case statusCode == http.StatusNotFound:
+		return KindNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return KindUpstreamRateLimited
+	case statusCode == http.StatusGatewayTimeout:
+		return KindUpstreamTimeout
+	case statusCode == http.StatusServiceUnavailable:
+		return KindUnavailable
+	case statusCode == http.StatusBadGateway:
+		return KindUpstreamError
+	case statusCode == http.StatusInsufficientStorage:
+		return KindInsufficientStorage
+	case statusCode > 0 && statusCode < http.StatusBadRequest:
+		return ""
+	}
+	return KindInternal
+}
+
+// NewKind 按分类创建错误，状态码取该分类的默认值。
+func NewKind(kind Kind, msg string) Error {
+	return Error{msg: msg, statusCode: kind.StatusCode(), kind: kind}
+}
+
+// WithKind 返回指定了分类的副本，未设置状态码时使用该分类的默认值。
+func (e Error) WithKind(kind Kind) Error {
+	e.kind = kind
+	if e.statusCode == 0 {
+		e.statusCode = kind.StatusCode()
+	}
+	return e
+}
+
+func (e Error) Kind() Kind {
+	if e.kind != "" {
+		return e.kind
+	}
+	return InferKind(e.statusCode, e.errorCode)
+}
+
+// KindOf 返回任意错误的分类，超时类错误视为远端超时，未知错误视为内部错误，err为nil时返回空。
+func KindOf(err error) Kind {
+	if err == nil {
+		return ""
+	}
+	var e Error
+	if errors.As(err, &e) {
+		return e.Kind()
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return KindUpstreamTimeout
+	}
+	return KindInternal
+}
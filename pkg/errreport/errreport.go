@@ -0,0 +1,212 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package errreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const eventQueueSize = 100
+
+// Event 上报的错误事件，Tags中携带仓库、请求路径、代码位置等上下文。
+type Event struct {
+	EventId  string            `json:"eventId"`
+	Time     time.Time         `json:"time"`
+	Level    string            `json:"level"`
+	Message  string            `json:"message"`
+	Instance string            `json:"instance"`
+	Version  string            `json:"version"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Count    int               `json:"count,omitempty"` // 重复错误在统计窗口内出现的次数
+	Stack    string            `json:"stack,omitempty"`
+}
+
+var (
+	events   chan *Event
+	sentry   *sentryTarget
+	webhook  string
+	instance string
+	client   = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Init 按配置启动上报协程，未启用时Report为空操作。
+func Init() error {
+	conf := config.SysConfig.ErrorReport
+	if !conf.Enabled {
+		return nil
+	}
+	if conf.SentryDsn != "" {
+		target, err := parseDsn(conf.SentryDsn)
+		if err != nil {
+			return err
+		}
+		sentry = target
+	}
+	webhook = conf.Webhook
+	instance = config.SysConfig.Scheduler.Discovery.InstanceId
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+	events = make(chan *Event, eventQueueSize)
+	go worker()
+	return nil
+}
+
+// Report 异步上报错误事件，队列满时丢弃，不阻塞调用方。
+func Report(e *Event) {
+	if events == nil {
+		return
+	}
+	if e.EventId == "" {
+		e.EventId = newEventId()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Level == "" {
+		e.Level = "error"
+	}
+	e.Instance = instance
+	e.Version = config.SystemInfo.Version
+	select {
+	case events <- e:
+	default:
+	}
+}
+
+// ReportPanic 上报请求处理过程中的panic。
+func ReportPanic(r interface{}, stack []byte, tags map[string]string) {
+	Report(&Event{
+		Level:   "fatal",
+		Message: fmt.Sprintf("panic: %v", r),
+		Tags:    tags,
+		Stack:   string(stack),
+	})
+}
+
+func worker() {
+	for e := range events {
+		if webhook != "" {
+			if err := postWebhook(e); err != nil {
+				zap.S().Warnf("report error to webhook err.%v", err)
+			}
+		}
+		if sentry != nil {
+			if err := sentry.send(e); err != nil {
+				zap.S().Warnf("report error to sentry err.%v", err)
+			}
+		}
+	}
+}
+
+func postWebhook(e *Event) error {
+	body, err := sonic.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook response code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newEventId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// repeatCore 统计error及以上级别的日志，同一代码位置在窗口内达到阈值时上报一次。
+type repeatCore struct {
+	threshold int
+	window    time.Duration
+	mu        sync.Mutex
+	counters  map[string]*repeatCounter
+}
+
+type repeatCounter struct {
+	start time.Time
+	count int
+}
+
+// NewRepeatCore 返回用于挂载到日志上的重复错误统计core。
+func NewRepeatCore() zapcore.Core {
+	conf := config.SysConfig.ErrorReport
+	return &repeatCore{
+		threshold: conf.RepeatThreshold,
+		window:    time.Duration(conf.RepeatWindow) * time.Second,
+		counters:  make(map[string]*repeatCounter),
+	}
+}
+
+func (c *repeatCore) Enabled(l zapcore.Level) bool {
+	return l >= zapcore.ErrorLevel
+}
+
+func (c *repeatCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *repeatCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *repeatCore) Write(ent zapcore.Entry, _ []zapcore.Field) error {
+	key := ent.Caller.TrimmedPath()
+	c.mu.Lock()
+	counter, ok := c.counters[key]
+	if !ok || ent.Time.Sub(counter.start) > c.window {
+		counter = &repeatCounter{start: ent.Time}
+		c.counters[key] = counter
+	}
+	counter.count++
+	count := counter.count
+	c.mu.Unlock()
+	if count == c.threshold || ent.Level > zapcore.ErrorLevel {
+		Report(&Event{
+			Time:    ent.Time,
+			Level:   ent.Level.String(),
+			Message: ent.Message,
+			Tags:    map[string]string{"caller": key},
+			Count:   count,
+		})
+	}
+	return nil
+}
+
+func (c *repeatCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,24 @@
+package errreport
+
+import "testing"
+
+func TestParseDsn(t *testing.T) {
+	cases := map[string]string{
+		"https://key@sentry.example.com/12":        "https://sentry.example.com/api/12/store/",
+		"http://key@sentry.example.com/sub/path/3": "http://sentry.example.com/sub/path/api/3/store/",
+	}
+	for dsn, want := range cases {
+		target, err := parseDsn(dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", dsn, err)
+		}
+		if target.storeUrl != want || target.publicKey != "key" {
+			t.Fatalf("%s: got %s %s", dsn, target.storeUrl, target.publicKey)
+		}
+	}
+	for _, dsn := range []string{"https://sentry.example.com/1", "https://key@sentry.example.com/"} {
+		if _, err := parseDsn(dsn); err == nil {
+			t.Fatalf("%s: expected error", dsn)
+		}
+	}
+}
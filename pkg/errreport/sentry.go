@@ -0,0 +1,107 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package errreport
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// sentryTarget 通过Sentry的store接口上报事件，无需引入SDK。
+type sentryTarget struct {
+	storeUrl  string
+	publicKey string
+}
+
+// parseDsn 解析形如 https://<key>@host/<project> 的DSN。
+func parseDsn(dsn string) (*sentryTarget, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %v", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing public key")
+	}
+	path := strings.Trim(u.Path, "/")
+	idx := strings.LastIndex(path, "/")
+	prefix, project := "", path
+	if idx >= 0 {
+		prefix, project = "/"+path[:idx], path[idx+1:]
+	}
+	if project == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing project id")
+	}
+	return &sentryTarget{
+		storeUrl:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		publicKey: u.User.Username(),
+	}, nil
+}
+
+func (s *sentryTarget) send(e *Event) error {
+	extra := map[string]interface{}{}
+	if e.Count > 0 {
+		extra["count"] = e.Count
+	}
+	if e.Stack != "" {
+		extra["stack"] = e.Stack
+	}
+	payload := map[string]interface{}{
+		"event_id":    e.EventId,
+		"timestamp":   e.Time.UTC().Format("2006-01-02T15:04:05"),
+		"level":       sentryLevel(e.Level),
+		"logger":      "dingospeed",
+		"platform":    "go",
+		"message":     e.Message,
+		"server_name": e.Instance,
+		"release":     e.Version,
+		"tags":        e.Tags,
+		"extra":       extra,
+	}
+	body, err := sonic.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.storeUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=dingospeed/%s, sentry_key=%s", e.Version, s.publicKey))
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sentry response code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sentryLevel(level string) string {
+	switch level {
+	case "dpanic", "panic", "fatal":
+		return "fatal"
+	case "warn":
+		return "warning"
+	default:
+		return level
+	}
+}
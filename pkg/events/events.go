@@ -0,0 +1,99 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package events
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 事件类型，订阅时可按前缀过滤，如cache匹配cache.fill及cache.evict。
+const (
+	CacheFill     = "cache.fill"     // 文件从远端拉取并写入缓存
+	CacheEvict    = "cache.evict"    // 磁盘清理或版本回收删除缓存
+	JobProgress   = "job.progress"   // 预热、批量任务中文件或仓库的状态变化
+	UpstreamState = "upstream.state" // 远端可用状态变化
+	RevisionMove  = "revision.move"  // 跟踪的分支或标签指向新的commit
+)
+
+// 每个订阅者缓冲的事件数，订阅者读取过慢时丢弃新事件，不阻塞发布方。
+const subscriberBuffer = 256
+
+type Event struct {
+	Id   int64       `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+type subscriber struct {
+	ch      chan *Event
+	types   []string
+	dropped atomic.Int64
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers = make(map[*subscriber]struct{})
+	eventSeq    atomic.Int64
+)
+
+// Publish 向所有订阅者广播事件，没有订阅者时直接返回。
+func Publish(eventType string, data interface{}) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(subscribers) == 0 {
+		return
+	}
+	event := &Event{Id: eventSeq.Add(1), Type: eventType, Time: time.Now(), Data: data}
+	for sub := range subscribers {
+		if !sub.match(eventType) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe 订阅类型前缀为types之一的事件，types为空时订阅全部事件，返回的函数用于取消订阅及查询丢弃的事件数。
+func Subscribe(types []string) (<-chan *Event, func() int64) {
+	sub := &subscriber{ch: make(chan *Event, subscriberBuffer), types: types}
+	mu.Lock()
+	subscribers[sub] = struct{}{}
+	mu.Unlock()
+	return sub.ch, func() int64 {
+		mu.Lock()
+		delete(subscribers, sub)
+		mu.Unlock()
+		return sub.dropped.Load()
+	}
+}
+
+func (s *subscriber) match(eventType string) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+	for _, t := range s.types {
+		if eventType == t || strings.HasPrefix(eventType, t+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package events
+
+import "testing"
+
+func TestPublishSubscribe(t *testing.T) {
+	all, cancelAll := Subscribe(nil)
+	cache, cancelCache := Subscribe([]string{"cache"})
+
+	Publish(CacheFill, map[string]string{"orgRepo": "org/repo"})
+	Publish(UpstreamState, map[string]bool{"down": true})
+
+	if e := <-all; e.Type != CacheFill {
+		t.Fatalf("expected %s, got %s", CacheFill, e.Type)
+	}
+	if e := <-all; e.Type != UpstreamState {
+		t.Fatalf("expected %s, got %s", UpstreamState, e.Type)
+	}
+	if e := <-cache; e.Type != CacheFill {
+		t.Fatalf("expected %s, got %s", CacheFill, e.Type)
+	}
+	select {
+	case e := <-cache:
+		t.Fatalf("unexpected event %s for cache subscriber", e.Type)
+	default:
+	}
+
+	cancelCache()
+	for i := 0; i < subscriberBuffer+10; i++ {
+		Publish(JobProgress, i)
+	}
+	if dropped := cancelAll(); dropped != 10 {
+		t.Fatalf("expected 10 dropped events, got %d", dropped)
+	}
+}
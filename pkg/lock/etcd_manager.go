@@ -0,0 +1,184 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/consts"
+)
+
+// EtcdManager 基于etcd v3 HTTP网关的锁：锁键绑定租约，键不存在时通过事务创建，持有者通过续约租约续期。
+type EtcdManager struct {
+	endpoints []string
+	client    *http.Client
+	ttl       time.Duration
+	keyFunc   func(name string) string // 锁名到etcd键的映射
+	mu        sync.Mutex
+	leases    map[string]int64 // 当前进程持有的锁及其租约ID
+}
+
+func NewEtcdManager(endpoints []string, timeout, ttl time.Duration, keyFunc func(name string) string) *EtcdManager {
+	return &EtcdManager{
+		endpoints: endpoints,
+		client:    &http.Client{Timeout: timeout},
+		ttl:       ttl,
+		keyFunc:   keyFunc,
+		leases:    make(map[string]int64),
+	}
+}
+
+func (m *EtcdManager) Backend() string {
+	return consts.LockBackendEtcd
+}
+
+func (m *EtcdManager) TTL() time.Duration {
+	return m.ttl
+}
+
+type etcdLease struct {
+	ID  int64 `json:"ID,string"`
+	TTL int64 `json:"TTL,string"`
+}
+
+type etcdKeepAliveResp struct {
+	Result *etcdLease `json:"result"`
+}
+
+type etcdTxnResp struct {
+	Succeeded bool `json:"succeeded"`
+}
+
+func (m *EtcdManager) TryLock(ctx context.Context, name string) (bool, error) {
+	if ok, err := m.Renew(ctx, name); ok || err != nil {
+		return ok, err
+	}
+	ttl := int64(m.ttl.Seconds())
+	if ttl <= 0 {
+		ttl = 1
+	}
+	lease := &etcdLease{}
+	if err := m.post(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": ttl}, lease); err != nil {
+		return false, err
+	}
+	key := base64.StdEncoding.EncodeToString([]byte(m.keyFunc(name)))
+	txn := map[string]interface{}{
+		"compare": []interface{}{map[string]interface{}{"key": key, "target": "CREATE", "create_revision": "0"}},
+		"success": []interface{}{map[string]interface{}{"request_put": map[string]interface{}{
+			"key":   key,
+			"value": base64.StdEncoding.EncodeToString([]byte(leaseOwner)),
+			"lease": fmt.Sprint(lease.ID),
+		}}},
+	}
+	resp := &etcdTxnResp{}
+	if err := m.post(ctx, "/v3/kv/txn", txn, resp); err != nil || !resp.Succeeded {
+		m.revoke(ctx, lease.ID)
+		return false, err
+	}
+	m.mu.Lock()
+	m.leases[name] = lease.ID
+	m.mu.Unlock()
+	return true, nil
+}
+
+func (m *EtcdManager) Renew(ctx context.Context, name string) (bool, error) {
+	m.mu.Lock()
+	id, ok := m.leases[name]
+	m.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	resp := &etcdKeepAliveResp{}
+	if err := m.post(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": fmt.Sprint(id)}, resp); err != nil {
+		return false, err
+	}
+	if resp.Result == nil || resp.Result.TTL <= 0 {
+		// 租约已过期，锁键随之删除
+		m.mu.Lock()
+		delete(m.leases, name)
+		m.mu.Unlock()
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *EtcdManager) Unlock(ctx context.Context, name string) error {
+	m.mu.Lock()
+	id, ok := m.leases[name]
+	delete(m.leases, name)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return m.revoke(ctx, id)
+}
+
+// 撤销租约时绑定的锁键一并删除
+func (m *EtcdManager) revoke(ctx context.Context, id int64) error {
+	return m.post(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": fmt.Sprint(id)}, nil)
+}
+
+// post 依次尝试各个etcd节点，直到请求成功。
+func (m *EtcdManager) post(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0, len(m.endpoints))
+	for _, endpoint := range m.endpoints {
+		if err = m.postEndpoint(ctx, strings.TrimSuffix(endpoint, "/")+path, body, resp); err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return errors.New("no etcd endpoints")
+	}
+	return errors.Join(errs...)
+}
+
+func (m *EtcdManager) postEndpoint(ctx context.Context, url string, body []byte, resp interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd %s status %d: %s", url, r.StatusCode, data)
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.Unmarshal(data, resp)
+}
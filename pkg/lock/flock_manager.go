@@ -0,0 +1,89 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"dingospeed/pkg/consts"
+)
+
+// FlockManager 基于flock的单机锁，锁文件为被保护文件同目录下的隐藏文件，进程退出时由内核释放，不适用于NFS。
+type FlockManager struct {
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+func NewFlockManager() *FlockManager {
+	return &FlockManager{files: make(map[string]*os.File)}
+}
+
+func (m *FlockManager) Backend() string {
+	return consts.LockBackendFlock
+}
+
+func (m *FlockManager) TTL() time.Duration {
+	return 0
+}
+
+func (m *FlockManager) TryLock(_ context.Context, name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; ok {
+		return true, nil
+	}
+	lockPath := filepath.Join(filepath.Dir(name), fmt.Sprintf(".%s.lock", filepath.Base(name)))
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return false, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return false, nil
+		}
+		return false, err
+	}
+	m.files[name] = f
+	return true, nil
+}
+
+func (m *FlockManager) Renew(_ context.Context, name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.files[name]
+	return ok, nil
+}
+
+func (m *FlockManager) Unlock(_ context.Context, name string) error {
+	m.mu.Lock()
+	f, ok := m.files[name]
+	delete(m.files, name)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return f.Close()
+}
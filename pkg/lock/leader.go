@@ -0,0 +1,101 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LeaderElector 基于锁管理器的leader选举，多个副本共享存储时保证磁盘清理等后台任务只由一个副本执行。
+type LeaderElector struct {
+	manager Manager
+	name    string
+	ttl     time.Duration
+	leader  atomic.Bool
+}
+
+var elector atomic.Pointer[LeaderElector]
+
+// NewLeaderElector 以path为租约文件进行选举。
+func NewLeaderElector(path string, ttl time.Duration) *LeaderElector {
+	return NewManagedLeaderElector(NewLeaseManager(ttl, nil), path, ttl)
+}
+
+func NewManagedLeaderElector(m Manager, name string, ttl time.Duration) *LeaderElector {
+	return &LeaderElector{manager: m, name: name, ttl: ttl}
+}
+
+// StartLeaderElection 启动全局leader选举，未启动时IsLeader始终返回true。
+func StartLeaderElection(ctx context.Context, m Manager, name string, ttl time.Duration) *LeaderElector {
+	e := NewManagedLeaderElector(m, name, ttl)
+	elector.Store(e)
+	go e.Run(ctx)
+	return e
+}
+
+// IsLeader 当前副本是否应执行后台任务，单副本部署时始终为true。
+func IsLeader() bool {
+	e := elector.Load()
+	return e == nil || e.IsLeader()
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run 定期竞选或续期租约，ctx结束时释放持有的租约。
+func (e *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		e.campaign()
+		select {
+		case <-ctx.Done():
+			if e.leader.Swap(false) {
+				if err := e.manager.Unlock(context.Background(), e.name); err != nil {
+					zap.S().Warnf("release leader lease err.%v", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElector) campaign() {
+	var (
+		ok  bool
+		err error
+	)
+	if e.leader.Load() {
+		ok, err = e.manager.Renew(context.Background(), e.name)
+	} else {
+		ok, err = e.manager.TryLock(context.Background(), e.name)
+	}
+	if err != nil {
+		zap.S().Warnf("leader lease err.%v", err)
+	}
+	if e.leader.Swap(ok) != ok {
+		if ok {
+			zap.S().Infof("%s became leader, background jobs run on this replica", LeaseOwner())
+		} else {
+			zap.S().Warnf("%s lost leadership", LeaseOwner())
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLeaderElector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "leader.lease")
+	e := NewLeaderElector(path, time.Minute)
+	e.campaign()
+	if !e.IsLeader() {
+		t.Fatal("expected to become leader")
+	}
+	// 模拟其他副本持有未过期的租约
+	if err := os.WriteFile(path, []byte("other-replica"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e.campaign()
+	if e.IsLeader() {
+		t.Fatal("expected to lose leadership")
+	}
+	old := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	e.campaign()
+	if !e.IsLeader() {
+		t.Fatal("expected takeover of expired lease")
+	}
+}
@@ -0,0 +1,166 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 当前进程的租约持有者标识，多个副本共享缓存卷时用于区分租约归属。
+var leaseOwner = func() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}()
+
+// LeaseLock 基于租约文件的跨进程锁，适用于NFS等共享卷：
+// 通过O_EXCL创建租约文件获取锁，持有者定期刷新文件修改时间续期，超过ttl未续期的租约可被其他副本接管。
+type LeaseLock struct {
+	path string
+	ttl  time.Duration
+}
+
+func NewLeaseLock(path string, ttl time.Duration) *LeaseLock {
+	return &LeaseLock{path: path, ttl: ttl}
+}
+
+func LeaseOwner() string {
+	return leaseOwner
+}
+
+// TryLock 尝试获取租约，已被其他副本持有且未过期时返回false。
+func (l *LeaseLock) TryLock() (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return false, err
+	}
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.WriteString(leaseOwner)
+			f.Close()
+			if err != nil {
+				os.Remove(l.path)
+				return false, err
+			}
+			return true, nil
+		}
+		if !os.IsExist(err) {
+			return false, err
+		}
+		owner, modTime, err := l.read()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return false, err
+		}
+		if owner == leaseOwner {
+			return true, l.touch()
+		}
+		if time.Since(modTime) < l.ttl {
+			return false, nil
+		}
+		// 租约已过期，先改名再删除，保证并发接管时只有一个副本能移走旧租约。
+		stalePath := fmt.Sprintf("%s.%s.%d.stale", l.path, leaseOwner, time.Now().UnixNano())
+		if err = os.Rename(l.path, stalePath); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return false, err
+		}
+		if _, staleModTime, err := readLease(stalePath); err == nil && time.Since(staleModTime) < l.ttl {
+			// 改名前租约已被其他副本接管，将其恢复。
+			_ = os.Link(stalePath, l.path)
+			os.Remove(stalePath)
+			return false, nil
+		}
+		os.Remove(stalePath)
+	}
+	return false, nil
+}
+
+// Renew 续期租约，租约已不属于当前进程时返回false。
+func (l *LeaseLock) Renew() (bool, error) {
+	owner, _, err := l.read()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if owner != leaseOwner {
+		return false, nil
+	}
+	return true, l.touch()
+}
+
+// KeepAlive 在ctx结束前定期续期租约，续期失败时调用onLost。
+func (l *LeaseLock) KeepAlive(ctx context.Context, onLost func()) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ok, err := l.Renew(); !ok || err != nil {
+				if onLost != nil {
+					onLost()
+				}
+				return
+			}
+		}
+	}
+}
+
+// Unlock 释放当前进程持有的租约。
+func (l *LeaseLock) Unlock() error {
+	owner, _, err := l.read()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if owner != leaseOwner {
+		return errors.New("lease is held by " + owner)
+	}
+	return os.Remove(l.path)
+}
+
+func (l *LeaseLock) read() (string, time.Time, error) {
+	return readLease(l.path)
+}
+
+func (l *LeaseLock) touch() error {
+	now := time.Now()
+	return os.Chtimes(l.path, now, now)
+}
+
+func readLease(path string) (string, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return string(content), info.ModTime(), nil
+}
@@ -0,0 +1,40 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLeaseLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "blob.lease")
+	lease := NewLeaseLock(path, time.Minute)
+	// 其他副本持有且未过期
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("other-1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := lease.TryLock(); err != nil || ok {
+		t.Fatalf("expected lease held by other replica, ok=%v err=%v", ok, err)
+	}
+	// 过期后可接管
+	expired := time.Now().Add(-2 * time.Minute)
+	if err := os.Chtimes(path, expired, expired); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := lease.TryLock(); err != nil || !ok {
+		t.Fatalf("expected takeover of expired lease, ok=%v err=%v", ok, err)
+	}
+	if ok, err := lease.Renew(); err != nil || !ok {
+		t.Fatalf("renew failed, ok=%v err=%v", ok, err)
+	}
+	if err := lease.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("lease file not removed")
+	}
+}
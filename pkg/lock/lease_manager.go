@@ -0,0 +1,61 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"time"
+
+	"dingospeed/pkg/consts"
+)
+
+// LeaseManager 基于租约文件的锁，适用于多个副本共享NFS等缓存卷。
+type LeaseManager struct {
+	ttl      time.Duration
+	pathFunc func(name string) string // 锁名到租约文件路径的映射，为nil时锁名即租约文件路径
+}
+
+func NewLeaseManager(ttl time.Duration, pathFunc func(name string) string) *LeaseManager {
+	return &LeaseManager{ttl: ttl, pathFunc: pathFunc}
+}
+
+func (m *LeaseManager) Backend() string {
+	return consts.LockBackendLease
+}
+
+func (m *LeaseManager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *LeaseManager) TryLock(_ context.Context, name string) (bool, error) {
+	return m.lease(name).TryLock()
+}
+
+func (m *LeaseManager) Renew(_ context.Context, name string) (bool, error) {
+	return m.lease(name).Renew()
+}
+
+func (m *LeaseManager) Unlock(_ context.Context, name string) error {
+	return m.lease(name).Unlock()
+}
+
+// 租约的状态全部保存在租约文件中，每次按需构造即可。
+func (m *LeaseManager) lease(name string) *LeaseLock {
+	path := name
+	if m.pathFunc != nil {
+		path = m.pathFunc(name)
+	}
+	return NewLeaseLock(path, m.ttl)
+}
@@ -0,0 +1,98 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Manager 命名锁管理器，屏蔽单机flock、共享卷租约文件、Redis和etcd的差异。
+// 锁的持有者为当前进程（LeaseOwner），同一进程重复获取视为成功，进程内的互斥由调用方保证。
+type Manager interface {
+	Backend() string
+	// TTL 锁的有效期，持有者需在到期前续期，0表示锁随进程释放，无需续期。
+	TTL() time.Duration
+	// TryLock 尝试获取锁，已被其他持有者占用时返回false。
+	TryLock(ctx context.Context, name string) (bool, error)
+	// Renew 续期锁，锁已不属于当前进程时返回false。
+	Renew(ctx context.Context, name string) (bool, error)
+	Unlock(ctx context.Context, name string) error
+}
+
+var defaultManager atomic.Pointer[Manager]
+
+// SetDefault 设置全局锁管理器，未设置时使用flock。
+func SetDefault(m Manager) {
+	defaultManager.Store(&m)
+}
+
+func Default() Manager {
+	if m := defaultManager.Load(); m != nil {
+		return *m
+	}
+	return localFlock
+}
+
+var localFlock = NewFlockManager()
+
+// Acquire 阻塞获取锁直至成功或ctx结束，持有期间自动续期，返回解锁函数。
+func Acquire(ctx context.Context, m Manager, name string) (func(), error) {
+	delay := 10 * time.Millisecond
+	for {
+		ok, err := m.TryLock(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay < 500*time.Millisecond {
+			delay *= 2
+		}
+	}
+	stop := make(chan struct{})
+	if ttl := m.TTL(); ttl > 0 {
+		go func() {
+			ticker := time.NewTicker(ttl / 3)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					if ok, err := m.Renew(context.Background(), name); !ok || err != nil {
+						zap.S().Warnf("renew lock %s err.%v", name, err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	return func() {
+		close(stop)
+		if err := m.Unlock(context.Background(), name); err != nil {
+			zap.S().Warnf("unlock %s err.%v", name, err)
+		}
+	}, nil
+}
@@ -0,0 +1,64 @@
+package lock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFlockManager(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "api", "meta.json")
+	m1, m2 := NewFlockManager(), NewFlockManager()
+	if ok, err := m1.TryLock(ctx, name); err != nil || !ok {
+		t.Fatalf("expected lock, ok=%v err=%v", ok, err)
+	}
+	// 同一管理器重复获取视为成功，不同打开文件之间互斥
+	if ok, _ := m1.TryLock(ctx, name); !ok {
+		t.Fatal("expected reentrant lock")
+	}
+	if ok, _ := m2.TryLock(ctx, name); ok {
+		t.Fatal("expected lock held by other manager")
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := Acquire(timeoutCtx, m2, name); err == nil {
+		t.Fatal("expected acquire timeout")
+	}
+	if err := m1.Unlock(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := Acquire(ctx, m2, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if ok, _ := m2.Renew(ctx, name); ok {
+		t.Fatal("expected lock released")
+	}
+}
+
+func TestLeaseManager(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	m := NewLeaseManager(time.Minute, func(name string) string {
+		return filepath.Join(dir, "locks", name+".lease")
+	})
+	if ok, err := m.TryLock(ctx, "leader"); err != nil || !ok {
+		t.Fatalf("expected lock, ok=%v err=%v", ok, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "locks", "leader.lease")); err != nil || string(content) != LeaseOwner() {
+		t.Fatalf("unexpected lease file %q err=%v", content, err)
+	}
+	if ok, err := m.Renew(ctx, "leader"); err != nil || !ok {
+		t.Fatalf("expected renew, ok=%v err=%v", ok, err)
+	}
+	if err := m.Unlock(ctx, "leader"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.Renew(ctx, "leader"); ok {
+		t.Fatal("expected lease released")
+	}
+}
@@ -0,0 +1,82 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/redis"
+)
+
+const (
+	redisTryLockScript = `local v = redis.call('GET', KEYS[1])
+if v == false or v == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`
+	redisRenewScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+	redisUnlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+// RedisManager 基于Redis的锁，键值为持有者标识并带有过期时间，适用于多节点集群部署。
+type RedisManager struct {
+	client  *redis.Client
+	ttl     time.Duration
+	keyFunc func(name string) string // 锁名到Redis键的映射
+}
+
+func NewRedisManager(client *redis.Client, ttl time.Duration, keyFunc func(name string) string) *RedisManager {
+	return &RedisManager{client: client, ttl: ttl, keyFunc: keyFunc}
+}
+
+func (m *RedisManager) Backend() string {
+	return consts.LockBackendRedis
+}
+
+func (m *RedisManager) TTL() time.Duration {
+	return m.ttl
+}
+
+func (m *RedisManager) TryLock(ctx context.Context, name string) (bool, error) {
+	return m.eval(ctx, redisTryLockScript, name)
+}
+
+func (m *RedisManager) Renew(ctx context.Context, name string) (bool, error) {
+	return m.eval(ctx, redisRenewScript, name)
+}
+
+func (m *RedisManager) Unlock(ctx context.Context, name string) error {
+	_, err := m.eval(ctx, redisUnlockScript, name)
+	return err
+}
+
+func (m *RedisManager) eval(ctx context.Context, script, name string) (bool, error) {
+	v, err := m.client.Do(ctx, "EVAL", script, "1", m.keyFunc(name), leaseOwner, strconv.FormatInt(m.ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	n, _ := v.(int64)
+	return n == 1, nil
+}
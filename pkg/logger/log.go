@@ -15,25 +15,183 @@
 package log
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"dingospeed/pkg/config"
+	"dingospeed/pkg/errreport"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// GlobalModule 设置全局日志级别时使用的模块名
+const GlobalModule = "global"
+
+var (
+	globalLevel  = zap.NewAtomicLevel()
+	moduleMu     sync.RWMutex
+	moduleLevels = map[string]zapcore.Level{} // 按模块（源码所在目录名，如dao、downloader）覆盖全局级别
+	accessLogger *zap.Logger
+)
+
 func InitLogger() {
 	logMode := zapcore.InfoLevel
 	if config.SysConfig.Server.Mode == "debug" {
 		logMode = zapcore.DebugLevel
 	}
-	core := zapcore.NewCore(getEncoder(), zapcore.NewMultiWriteSyncer(getWriteSyncer(), zapcore.AddSync(os.Stdout)), logMode)
-	logger := zap.New(core, zap.AddCaller())
+	if config.SysConfig.Log.Level != "" {
+		if err := logMode.Set(config.SysConfig.Log.Level); err != nil {
+			panic(fmt.Errorf("invalid log level %s: %v", config.SysConfig.Log.Level, err))
+		}
+	}
+	globalLevel.SetLevel(logMode)
+	for module, level := range config.SysConfig.Log.ModuleLevels {
+		if err := SetLevel(module, level); err != nil {
+			panic(err)
+		}
+	}
+	cores, err := getCores()
+	if err != nil {
+		panic(err)
+	}
+	logger := zap.New(&moduleCore{Core: zapcore.NewTee(cores...)}, zap.AddCaller())
 	zap.ReplaceGlobals(logger)
+	if config.SysConfig.Log.AccessLog.Enabled {
+		accessLogger = zap.New(zapcore.NewCore(getEncoder(), getFileSyncer(config.SysConfig.Log.AccessLog.File), zapcore.InfoLevel))
+	}
+}
+
+// AccessLogger 返回访问日志记录器，未启用访问日志时返回nil
+func AccessLogger() *zap.Logger {
+	return accessLogger
+}
+
+func getCores() ([]zapcore.Core, error) {
+	logConf := config.SysConfig.Log
+	var (
+		cores   []zapcore.Core
+		writers []zapcore.WriteSyncer
+	)
+	for _, output := range logConf.Outputs {
+		switch output {
+		case "file":
+			writers = append(writers, getWriteSyncer())
+		case "stdout":
+			writers = append(writers, zapcore.AddSync(os.Stdout))
+		case "stderr":
+			writers = append(writers, zapcore.AddSync(os.Stderr))
+		case "syslog":
+			core, err := newSyslogCore(getEncoder(), logConf.Syslog)
+			if err != nil {
+				return nil, fmt.Errorf("connect syslog err: %v", err)
+			}
+			cores = append(cores, core)
+		default:
+			return nil, fmt.Errorf("unknown log output %s", output)
+		}
+	}
+	if len(writers) > 0 {
+		cores = append(cores, zapcore.NewCore(getEncoder(), zapcore.NewMultiWriteSyncer(writers...), zapcore.DebugLevel))
+	}
+	if config.SysConfig.ErrorReport.Enabled {
+		cores = append(cores, errreport.NewRepeatCore())
+	}
+	if logConf.ErrorFile != "" {
+		cores = append(cores, zapcore.NewCore(getEncoder(), getFileSyncer(logConf.ErrorFile), zapcore.ErrorLevel))
+	}
+	return cores, nil
+}
+
+// SetLevel 运行时修改日志级别，module为空或global时修改全局级别，level为空时删除该模块的单独设置。
+func SetLevel(module, level string) error {
+	if module == "" || module == GlobalModule {
+		return globalLevel.UnmarshalText([]byte(level))
+	}
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	if level == "" {
+		delete(moduleLevels, module)
+		return nil
+	}
+	var l zapcore.Level
+	if err := l.Set(level); err != nil {
+		return fmt.Errorf("invalid log level %s: %v", level, err)
+	}
+	moduleLevels[module] = l
+	return nil
+}
+
+// Levels 返回全局及各模块当前的日志级别
+func Levels() map[string]string {
+	moduleMu.RLock()
+	defer moduleMu.RUnlock()
+	levels := make(map[string]string, len(moduleLevels)+1)
+	levels[GlobalModule] = globalLevel.Level().String()
+	for module, l := range moduleLevels {
+		levels[module] = l.String()
+	}
+	return levels
+}
+
+func minLevel() zapcore.Level {
+	l := globalLevel.Level()
+	moduleMu.RLock()
+	defer moduleMu.RUnlock()
+	for _, ml := range moduleLevels {
+		if ml < l {
+			l = ml
+		}
+	}
+	return l
+}
+
+func moduleLevel(caller zapcore.EntryCaller) zapcore.Level {
+	if caller.Defined {
+		module := filepath.Base(filepath.Dir(caller.File))
+		moduleMu.RLock()
+		l, ok := moduleLevels[module]
+		moduleMu.RUnlock()
+		if ok {
+			return l
+		}
+	}
+	return globalLevel.Level()
+}
+
+// moduleCore 按调用方所在模块过滤日志。调用位置在Check之后才填充，因此在Write时再按模块级别判断。
+type moduleCore struct {
+	zapcore.Core
+}
+
+func (c *moduleCore) Enabled(l zapcore.Level) bool {
+	return l >= minLevel()
+}
+
+func (c *moduleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleCore{Core: c.Core.With(fields)}
+}
+
+func (c *moduleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *moduleCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level < moduleLevel(ent.Caller) {
+		return nil
+	}
+	// 内部可能包含不同级别的多个输出（如error文件），交由各自的Check过滤
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
 }
 
 func getEncoder() zapcore.Encoder {
@@ -45,6 +203,9 @@ func getEncoder() zapcore.Encoder {
 	}
 	encoder.CallerKey = "caller"
 	encoder.EncodeCaller = zapcore.ShortCallerEncoder
+	if config.SysConfig.Log.Encoder == "console" {
+		return zapcore.NewConsoleEncoder(encoder)
+	}
 	return zapcore.NewJSONEncoder(encoder)
 }
 
@@ -52,9 +213,12 @@ func getWriteSyncer() zapcore.WriteSyncer {
 	stSeparator := string(filepath.Separator)
 	stRootDir, _ := os.Getwd()
 	stLogFilePath := stRootDir + stSeparator + "log" + stSeparator + time.Now().Format(time.DateOnly) + ".log"
+	return getFileSyncer(stLogFilePath)
+}
 
+func getFileSyncer(filename string) zapcore.WriteSyncer {
 	l := &lumberjack.Logger{
-		Filename:   stLogFilePath,
+		Filename:   filename,
 		MaxSize:    config.SysConfig.Log.MaxSize, // megabytes
 		MaxBackups: config.SysConfig.Log.MaxBackups,
 		MaxAge:     config.SysConfig.Log.MaxAge, // days
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestModuleLevel(t *testing.T) {
+	defer func() {
+		_ = SetLevel(GlobalModule, "info")
+		_ = SetLevel("logger", "")
+	}()
+	obs, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(&moduleCore{Core: obs}, zap.AddCaller())
+	if err := SetLevel(GlobalModule, "info"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("hidden")
+	if logs.Len() != 0 {
+		t.Fatalf("expected debug log filtered, got %d", logs.Len())
+	}
+	// 测试文件位于logger目录，模块名为logger
+	if err := SetLevel("logger", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("shown")
+	if logs.Len() != 1 {
+		t.Fatalf("expected module debug log, got %d", logs.Len())
+	}
+	if err := SetLevel("logger", "bad"); err == nil {
+		t.Fatal("expected invalid level error")
+	}
+	if Levels()["logger"] != "debug" || Levels()[GlobalModule] != "info" {
+		t.Fatalf("unexpected levels %v", Levels())
+	}
+}
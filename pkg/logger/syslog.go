@@ -0,0 +1,76 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package log
+
+import (
+	"log/syslog"
+
+	"dingospeed/pkg/config"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogCore 将日志写入syslog，并按日志级别映射syslog优先级。
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	writer *syslog.Writer
+}
+
+func newSyslogCore(enc zapcore.Encoder, conf config.SyslogConfig) (zapcore.Core, error) {
+	writer, err := syslog.Dial(conf.Network, conf.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, conf.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogCore{LevelEnabler: zapcore.DebugLevel, enc: enc, writer: writer}, nil
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, writer: c.writer}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	msg := buf.String()
+	switch {
+	case ent.Level >= zapcore.ErrorLevel:
+		return c.writer.Err(msg)
+	case ent.Level == zapcore.WarnLevel:
+		return c.writer.Warning(msg)
+	case ent.Level == zapcore.InfoLevel:
+		return c.writer.Info(msg)
+	default:
+		return c.writer.Debug(msg)
+	}
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"time"
+
+	log "dingospeed/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AccessLogMiddleware 每个请求结束后记录一行访问日志，未启用访问日志时直接放行。
+func AccessLogMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		logger := log.AccessLogger()
+		if logger == nil {
+			return next(c)
+		}
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+		req, resp := c.Request(), c.Response()
+		logger.Info("access",
+			zap.String("remoteIp", c.RealIP()),
+			zap.String("method", req.Method),
+			zap.String("uri", req.RequestURI),
+			zap.Int("status", resp.Status),
+			zap.Int64("bytes", resp.Size),
+			zap.Int64("latencyMs", time.Since(start).Milliseconds()),
+			zap.String("userAgent", req.UserAgent()),
+		)
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminAuthorized 校验管理接口的请求：配置adminToken时需携带Bearer令牌；
+// 未配置时只允许来自本机的连接（按连接地址判断，不信任X-Forwarded-For）。
+func AdminAuthorized(r *http.Request) bool {
+	token := config.SysConfig.Server.AdminToken
+	if token != "" {
+		expected := []byte("Bearer " + token)
+		return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) == 1
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// AdminAuthMiddleware /admin接口的鉴权，按匹配到的路由判断，其他路由直接放行。
+func AdminAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !strings.HasPrefix(c.Path(), "/admin/") {
+			return next(c)
+		}
+		if !AdminAuthorized(c.Request()) {
+			return c.String(http.StatusUnauthorized, "Unauthorized")
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestAdminAuthorized(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+
+	newReq := func(method, remote, auth string) *http.Request {
+		req := httptest.NewRequest(method, "/admin/downloads", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", "127.0.0.1")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return req
+	}
+	cases := []struct {
+		token, method, remote, auth string
+		want                        bool
+	}{
+		{"", http.MethodGet, "10.0.0.1:1234", "", false},
+		{"", http.MethodHead, "10.0.0.1:1234", "", false},
+		{"", http.MethodDelete, "10.0.0.1:1234", "", false},
+		{"", http.MethodGet, "127.0.0.1:1234", "", true},
+		{"", http.MethodDelete, "[::1]:1234", "", true},
+		{"secret", http.MethodGet, "127.0.0.1:1234", "", false},
+		{"secret", http.MethodDelete, "10.0.0.1:1234", "Bearer wrong", false},
+		{"secret", http.MethodDelete, "10.0.0.1:1234", "Bearer secret", true},
+	}
+	for _, tc := range cases {
+		config.SysConfig.Server.AdminToken = tc.token
+		if got := AdminAuthorized(newReq(tc.method, tc.remote, tc.auth)); got != tc.want {
+			t.Errorf("AdminAuthorized(token=%q, %s from %s, %q) = %v, want %v", tc.token, tc.method, tc.remote, tc.auth, got, tc.want)
+		}
+	}
+}
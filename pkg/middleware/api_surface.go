@@ -0,0 +1,35 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ApiSurfaceMiddleware 拒绝已按配置关闭的接口，按未找到处理，不暴露接口是否存在。
+func ApiSurfaceMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if feature := util.ApiFeature(req.Method, req.URL.Path); feature != "" && !config.SysConfig.ApiFeatureEnabled(feature) {
+			zap.S().Debugf("api feature %s is disabled, reject %s %s", feature, req.Method, req.URL.Path)
+			return util.ErrorPageNotFound(c)
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,62 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"strings"
+
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// BasePathMiddleware 配置了scheduler.pathPrefix时，在路由之前去掉请求路径中的前缀，路由按原有路径注册；
+// 反向代理已去掉前缀的请求原样处理。响应中以"/"开头的相对Location补上前缀，使客户端重定向后仍经过同一入口。
+func BasePathMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		prefix := util.BasePath()
+		if prefix == "" {
+			return next(c)
+		}
+		req := c.Request()
+		if p, ok := trimBasePath(req.URL.Path, prefix); ok {
+			req.URL.Path = p
+			if req.URL.RawPath != "" {
+				req.URL.RawPath, _ = trimBasePath(req.URL.RawPath, prefix)
+			}
+		}
+		resp := c.Response()
+		resp.Before(func() {
+			location := resp.Header().Get(consts.HUGGINGFACE_LOCATION)
+			if strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") {
+				if _, ok := trimBasePath(location, prefix); !ok {
+					resp.Header().Set(consts.HUGGINGFACE_LOCATION, prefix+location)
+				}
+			}
+		})
+		return next(c)
+	}
+}
+
+func trimBasePath(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "/", true
+	}
+	if strings.HasPrefix(path, prefix+"/") {
+		return path[len(prefix):], true
+	}
+	return path, false
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBasePathMiddleware(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Scheduler.PathPrefix = "/hf/"
+
+	e := echo.New()
+	e.Pre(BasePathMiddleware)
+	e.GET("/api/models/:org/:repo", func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Param("org")+"/"+c.Param("repo"))
+	})
+	e.GET("/api/models/:org/:repo/moved", func(c echo.Context) error {
+		return c.Redirect(http.StatusTemporaryRedirect, "/api/models/new/repo")
+	})
+
+	cases := []struct {
+		path     string
+		code     int
+		location string
+	}{
+		{"/hf/api/models/org/repo", http.StatusOK, ""},
+		{"/api/models/org/repo", http.StatusOK, ""}, // 反向代理已去掉前缀
+		{"/hf/api/models/org/repo/moved", http.StatusTemporaryRedirect, "/hf/api/models/new/repo"},
+		{"/hfx/api/models/org/repo", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%s: code = %d, want %d", tc.path, rec.Code, tc.code)
+		}
+		if got := rec.Header().Get("Location"); got != tc.location {
+			t.Errorf("%s: Location = %q, want %q", tc.path, got, tc.location)
+		}
+	}
+}
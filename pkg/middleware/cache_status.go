@@ -0,0 +1,47 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CacheStatusMiddleware 为元数据及文件响应添加X-Cache头，便于确认是否由缓存提供，
+// 请求带debug=1时附加缓存路径、缓存时长及使用的远端。
+func CacheStatusMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx, trace := util.WithCacheTrace(req.Context())
+		c.SetRequest(req.WithContext(ctx))
+		debug := c.QueryParam("debug") == "1"
+		resp := c.Response()
+		resp.Before(func() {
+			status := trace.Status()
+			if status == "" {
+				return
+			}
+			resp.Header().Set(consts.HeaderCache, status)
+			if debug {
+				for k, v := range trace.Diagnostics() {
+					resp.Header().Set(k, v)
+				}
+			}
+		})
+		return next(c)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCacheStatusMiddleware(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Repos = "/data/repos"
+
+	e := echo.New()
+	handler := CacheStatusMiddleware(func(c echo.Context) error {
+		trace := util.CacheTraceFrom(c.Request().Context())
+		switch c.QueryParam("case") {
+		case "hit":
+			trace.Hit(filepath.Join("/data/repos", "api/models/org/repo/meta_get.json"), time.Now().Add(-time.Minute))
+		case "revalidated":
+			trace.Revalidate("https://huggingface.co")
+			trace.Hit("/data/repos/files/models/org/repo/blobs/abc", time.Now())
+		case "miss":
+			trace.Miss("/data/repos/files/models/org/repo/blobs/abc", "https://huggingface.co")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	cases := []struct {
+		query    string
+		want     string
+		wantPath string
+	}{
+		{query: "case=none"},
+		{query: "case=hit&debug=1", want: util.CacheHit, wantPath: "api/models/org/repo/meta_get.json"},
+		{query: "case=revalidated", want: util.CacheRevalidated},
+		{query: "case=miss", want: util.CacheMiss},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/models/org/repo?"+tc.query, nil), rec)
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Header().Get(consts.HeaderCache); got != tc.want {
+			t.Errorf("%s: X-Cache = %q, want %q", tc.query, got, tc.want)
+		}
+		if got := rec.Header().Get(consts.HeaderCachePath); got != tc.wantPath {
+			t.Errorf("%s: cache path = %q, want %q", tc.query, got, tc.wantPath)
+		}
+	}
+}
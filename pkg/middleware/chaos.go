@@ -0,0 +1,56 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ChaosMiddleware 故障注入，仅用于测试环境：按配置对客户端请求增加延迟、直接返回错误或在写入部分响应体后中断连接。
+func ChaosMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if !util.ChaosMatch(req.URL.Path) {
+			return next(c)
+		}
+		conf := &config.SysConfig.Chaos
+		if err := util.ChaosDelay(req.Context(), conf.Latency, conf.LatencyJitter); err != nil {
+			return nil // 客户端已断开
+		}
+		if util.ChaosHit(conf.ErrorPercent) {
+			c.Response().Header().Set(consts.HeaderChaos, "error")
+			return util.ErrorHf(c, config.SysConfig.GetChaosErrorStatus(), "", "chaos: injected failure")
+		}
+		if !util.ChaosHit(conf.TruncatePercent) {
+			return next(c)
+		}
+		c.Response().Header().Set(consts.HeaderChaos, "truncate")
+		writer := util.NewChaosWriter(c.Response().Writer, conf.TruncateBytes)
+		c.Response().Writer = writer
+		err := next(c)
+		if writer.Truncated() {
+			zap.S().Debugf("chaos: truncated %s %s", req.Method, req.URL.RequestURI())
+			panic(http.ErrAbortHandler) // 中断连接，客户端收到的响应体不完整
+		}
+		return err
+	}
+}
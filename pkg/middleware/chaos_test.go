@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestChaosMiddleware(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	chaos := config.SysConfig.Chaos
+	defer func() { config.SysConfig.Chaos = chaos }()
+
+	body := strings.Repeat("x", 1000)
+	e := echo.New()
+	e.Use(RecoverMiddleware)
+	e.Use(ChaosMiddleware)
+	e.GET("/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, body)
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	config.SysConfig.Chaos = config.Chaos{Enabled: true, ErrorPercent: 100}
+	resp, err := http.Get(srv.URL + "/api/models/org/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(consts.HeaderChaos) != "error" {
+		t.Errorf("error: status %d, header %q", resp.StatusCode, resp.Header.Get(consts.HeaderChaos))
+	}
+
+	resp, err = http.Get(srv.URL + "/admin/downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("admin path injected: status %d", resp.StatusCode)
+	}
+
+	config.SysConfig.Chaos = config.Chaos{Enabled: true, TruncatePercent: 100, TruncateBytes: 100}
+	resp, err = http.Get(srv.URL + "/models/org/repo/resolve/main/a.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err == nil || len(data) != 100 {
+		t.Errorf("truncate: read %d bytes, err %v", len(data), err)
+	}
+}
@@ -0,0 +1,101 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// CORSMiddleware 跨域中间件（适配Echo框架），预检请求直接返回，不进入后续中间件。
+func CORSMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cors := config.SysConfig.Server.Cors
+			req, header := c.Request(), c.Response().Header()
+			origin := req.Header.Get(echo.HeaderOrigin)
+			preflight := req.Method == http.MethodOptions && req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+			if origin == "" {
+				if preflight {
+					return c.NoContent(http.StatusNoContent)
+				}
+				return next(c)
+			}
+			allowOrigin, ok := matchOrigin(cors.AllowOrigins, origin, cors.AllowCredentials)
+			if !ok {
+				if preflight {
+					return c.NoContent(http.StatusNoContent)
+				}
+				return next(c)
+			}
+			header.Add(echo.HeaderVary, echo.HeaderOrigin)
+			header.Set(echo.HeaderAccessControlAllowOrigin, allowOrigin)
+			if cors.AllowCredentials {
+				header.Set(echo.HeaderAccessControlAllowCredentials, "true")
+			}
+			if !preflight {
+				header.Set(echo.HeaderAccessControlExposeHeaders, strings.Join(cors.ExposeHeaders, ", "))
+				return next(c)
+			}
+
+			// 处理OPTIONS预检请求
+			header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+			header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+			header.Set(echo.HeaderAccessControlAllowMethods, strings.Join(cors.AllowMethods, ", "))
+			allowHeaders := strings.Join(cors.AllowHeaders, ", ")
+			if allowHeaders == "*" {
+				// 通配符不包含Authorization，回显请求声明的头部
+				if requestHeaders := req.Header.Get(echo.HeaderAccessControlRequestHeaders); requestHeaders != "" {
+					allowHeaders = requestHeaders
+				}
+			}
+			header.Set(echo.HeaderAccessControlAllowHeaders, allowHeaders)
+			if cors.MaxAge > 0 {
+				header.Set(echo.HeaderAccessControlMaxAge, strconv.Itoa(cors.MaxAge))
+			}
+			return c.NoContent(http.StatusNoContent)
+		}
+	}
+}
+
+// 返回应写入Access-Control-Allow-Origin的值，允许携带凭证时不能使用*，需回显来源。
+func matchOrigin(allowOrigins []string, origin string, allowCredentials bool) (string, bool) {
+	for _, allow := range allowOrigins {
+		switch {
+		case allow == "*":
+			if allowCredentials {
+				return origin, true
+			}
+			return "*", true
+		case strings.EqualFold(allow, origin):
+			return origin, true
+		case strings.Contains(allow, "://*."):
+			scheme, domain, _ := strings.Cut(allow, "://*")
+			if strings.HasPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://") && strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) {
+				return origin, true
+			}
+		case strings.HasPrefix(allow, "*."):
+			if strings.HasSuffix(strings.ToLower(origin), strings.ToLower(allow[1:])) {
+				return origin, true
+			}
+		}
+	}
+	return "", false
+}
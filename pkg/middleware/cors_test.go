@@ -0,0 +1,28 @@
+package middleware
+
+import "testing"
+
+func TestMatchOrigin(t *testing.T) {
+	cases := []struct {
+		allow       []string
+		origin      string
+		credentials bool
+		want        string
+		ok          bool
+	}{
+		{[]string{"*"}, "https://a.com", false, "*", true},
+		{[]string{"*"}, "https://a.com", true, "https://a.com", true},
+		{[]string{"https://a.com"}, "https://a.com", false, "https://a.com", true},
+		{[]string{"https://a.com"}, "https://b.com", false, "", false},
+		{[]string{"*.example.com"}, "https://ui.example.com", false, "https://ui.example.com", true},
+		{[]string{"https://*.example.com"}, "http://ui.example.com", false, "", false},
+		{[]string{"https://*.example.com"}, "https://ui.example.com", false, "https://ui.example.com", true},
+		{[]string{"*.example.com"}, "https://badexample.com", false, "", false},
+	}
+	for _, tc := range cases {
+		got, ok := matchOrigin(tc.allow, tc.origin, tc.credentials)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("matchOrigin(%v, %s, %v) = %s, %v; want %s, %v", tc.allow, tc.origin, tc.credentials, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DownloadsListenerMiddleware 开启独立下载端口时，下载端口只提供下载路由，其余请求按未找到处理；
+// exclusive时主端口的下载请求重定向到下载端口。
+func DownloadsListenerMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !config.SysConfig.EnableDownloadsListener() {
+			return next(c)
+		}
+		download := util.IsDownloadRoute(c.Path())
+		if util.IsDownloadsListener(c.Request()) {
+			if !download {
+				return util.ErrorPageNotFound(c)
+			}
+			return next(c)
+		}
+		if download && config.SysConfig.Server.Downloads.Exclusive {
+			return c.Redirect(http.StatusTemporaryRedirect, util.DownloadsLocation(c))
+		}
+		return next(c)
+	}
+}
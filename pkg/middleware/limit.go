@@ -0,0 +1,62 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// LimitMiddleware 按路由设置读写超时及请求体大小上限，声明的请求体超过上限时直接返回413，
+// 未声明长度的请求体在读取超过上限时报错。
+func LimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		limit := config.SysConfig.GetRouteLimit(req.Method, req.URL.Path)
+		if limit.MaxBodySize > 0 {
+			if req.ContentLength > limit.MaxBodySize {
+				return util.ErrorHf(c, http.StatusRequestEntityTooLarge, "",
+					fmt.Sprintf("request body too large, limit is %d bytes", limit.MaxBodySize))
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, limit.MaxBodySize)
+		}
+		if limit.ReadTimeout > 0 || limit.WriteTimeout > 0 {
+			setDeadline(c, limit)
+		}
+		return next(c)
+	}
+}
+
+func setDeadline(c echo.Context, limit config.RouteLimit) {
+	rc := http.NewResponseController(c.Response())
+	now := time.Now()
+	if limit.ReadTimeout > 0 {
+		if err := rc.SetReadDeadline(now.Add(time.Duration(limit.ReadTimeout) * time.Second)); err != nil {
+			zap.S().Debugf("set read deadline for %s failed: %v", c.Request().URL.Path, err)
+		}
+	}
+	if limit.WriteTimeout > 0 {
+		if err := rc.SetWriteDeadline(now.Add(time.Duration(limit.WriteTimeout) * time.Second)); err != nil {
+			zap.S().Debugf("set write deadline for %s failed: %v", c.Request().URL.Path, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestLimitMiddleware(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Limits = config.Limits{
+		MaxBodySize: 8,
+		Routes: []config.RouteLimit{
+			{Method: http.MethodPost, Path: "/api/*/*/*/paths-info/*", MaxBodySize: 4},
+		},
+	}
+	e := echo.New()
+	handler := LimitMiddleware(func(c echo.Context) error {
+		if _, err := io.ReadAll(c.Request().Body); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	cases := []struct {
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"/api/models/org/repo/paths-info/main", "1234", false, http.StatusOK},
+		{"/api/models/org/repo/paths-info/main", "12345", false, http.StatusRequestEntityTooLarge},
+		{"/api/models/org/repo/paths-info/main", "12345", true, http.StatusBadRequest},
+		{"/other", "12345678", false, http.StatusOK},
+		{"/other", "123456789", false, http.StatusRequestEntityTooLarge},
+		// 上传类路由使用内置的默认限制
+		{"/lfs-proxy/1", strings.Repeat("x", 100), false, http.StatusOK},
+		{"/api/models/org/repo/commit/main", strings.Repeat("x", 100), false, http.StatusOK},
+		{"/api/models/repo/commit/main", strings.Repeat("x", 100), false, http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		if err := handler(e.NewContext(req, rec)); err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s with %d bytes: got %d, want %d", tc.path, len(tc.body), rec.Code, tc.want)
+		}
+	}
+}
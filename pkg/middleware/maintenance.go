@@ -0,0 +1,46 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 维护模式下仍可切换维护状态的路由
+const maintenancePath = "/admin/maintenance"
+
+// MaintenanceMiddleware 维护模式下拒绝管理接口及缓存任务的修改类请求，查询类请求不受影响。
+func MaintenanceMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !config.SysConfig.InMaintenance() {
+			return next(c)
+		}
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+		path := c.Request().URL.Path
+		if path != maintenancePath && (strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/api/cacheJob/")) {
+			return util.ErrorHf(c, http.StatusServiceUnavailable, "", util.MaintenanceMessage())
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,70 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NetworkAclMiddleware 按客户端IP执行访问控制，拒绝的地址返回403，超过所属网段请求速率的返回429，
+// 所属网段记录在上下文中，下载时按网段带宽限速。
+func NetworkAclMiddleware() echo.MiddlewareFunc {
+	acl := newIPAcl(config.SysConfig.NetworkAcl)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if acl == nil {
+			return next
+		}
+		return func(c echo.Context) error {
+			ip := c.RealIP()
+			allowed, class := acl.Check(ip)
+			if !allowed {
+				return util.ErrorHf(c, http.StatusForbidden, "", fmt.Sprintf("access from %s is not allowed", ip))
+			}
+			if class == nil {
+				return next(c)
+			}
+			if !class.Allow(ip, time.Now()) {
+				c.Response().Header().Set("Retry-After", "1")
+				return util.ErrorHf(c, http.StatusTooManyRequests, "", fmt.Sprintf("too many requests from %s", ip))
+			}
+			c.Set(consts.KeyNetworkClass, class)
+			return next(c)
+		}
+	}
+}
+
+// newIPAcl 未启用时返回nil，配置已在加载时校验。
+func newIPAcl(conf config.NetworkAcl) *common.IPAcl {
+	if !conf.Enabled {
+		return nil
+	}
+	allow, _ := config.ParseIPNets(conf.Allow)
+	deny, _ := config.ParseIPNets(conf.Deny)
+	acl := common.NewIPAcl(allow, deny)
+	for _, class := range conf.Classes {
+		nets, _ := config.ParseIPNets(class.Cidrs)
+		acl.AddClass(class.Name, nets, class.Bandwidth, class.Rate, class.Burst)
+	}
+	return acl
+}
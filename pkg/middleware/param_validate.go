@@ -0,0 +1,47 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ParamValidateMiddleware 统一校验路由参数，防止org、repo、revision、filePath等参数构造出缓存目录之外的路径。
+func ParamValidateMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		values := c.ParamValues()
+		for i, name := range c.ParamNames() {
+			if i >= len(values) {
+				break
+			}
+			value, err := url.PathUnescape(values[i])
+			if err == nil {
+				err = util.ValidatePathParam(name, value)
+			}
+			if err != nil {
+				zap.S().Warnf("invalid param %s=%q from %s: %v", name, values[i], c.RealIP(), err)
+				return util.ErrorHf(c, http.StatusBadRequest, consts.HfErrorCodeBadRequest, err.Error())
+			}
+		}
+		return next(c)
+	}
+}
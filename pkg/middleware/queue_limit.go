@@ -1,16 +1,17 @@
 package middleware
 
 import (
-	"net"
-	"net/http"
+	"context"
 	"strings"
 
+	"dingospeed/pkg/common"
 	"dingospeed/pkg/config"
 	"dingospeed/pkg/consts"
 	"dingospeed/pkg/prom"
 	"dingospeed/pkg/util"
 
 	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
 )
 
 var (
@@ -21,18 +22,24 @@ var (
 func InitMiddlewareConfig() {
 	requestQueue = make(chan struct{}, config.SysConfig.TokenBucketLimit.HandlerCapacity)
 	fileDownloadQueue = make(chan struct{}, config.SysConfig.TokenBucketLimit.HandlerCapacity*2)
+	if config.SysConfig.EnablePriority() {
+		common.InitDownloadLimiter(config.SysConfig.TokenBucketLimit.HandlerCapacity * 2)
+	}
+	shadowSem = make(chan struct{}, config.SysConfig.GetShadowMaxConcurrent())
+	if config.SysConfig.Chaos.Enabled {
+		zap.S().Warnf("chaos fault injection is enabled, do not use in production")
+	}
 }
 
 func QueueLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		url := c.Request().URL.String()
 		method := c.Request().Method
-		remoteAddr := c.Request().RemoteAddr
-		source, _, err := net.SplitHostPort(remoteAddr)
-		if err != nil {
-			return err
-		}
+		source := c.RealIP()
 		c.Set(consts.PromSource, source)
+		if config.SysConfig.EnablePriority() && method == echo.GET && strings.Contains(url, "resolve") {
+			return priorityRequest(c, next, source)
+		}
 		if config.SysConfig.EnableMetric() {
 			metrics := strings.Contains(url, "metrics")
 			if metrics {
@@ -45,7 +52,7 @@ func QueueLimitMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 					defer func() {
 						<-fileDownloadQueue
 					}()
-					if err = next(c); err != nil {
+					if err := next(c); err != nil {
 						prom.PromSourceCounter(prom.RequestFailCnt, source)
 						return err
 					} else {
@@ -77,22 +84,48 @@ func nextRequest(c echo.Context, next echo.HandlerFunc) error {
 	}
 }
 
-// CORSMiddleware 跨域中间件（适配Echo框架）
-func CORSMiddleware() echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// 设置跨域头
-			c.Response().Header().Set("Access-Control-Allow-Origin", "*")
-			c.Response().Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS, HEAD")
-			c.Response().Header().Set("Access-Control-Allow-Headers", "*")
-			c.Response().Header().Set("Access-Control-Expose-Headers", "*")
+// 启用优先级后，文件下载请求按优先级排队等待名额，超时才拒绝。
+func priorityRequest(c echo.Context, next echo.HandlerFunc, source string) error {
+	enableMetric := config.SysConfig.EnableMetric()
+	if enableMetric {
+		prom.PromSourceCounter(prom.RequestTotalCnt, source)
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), config.SysConfig.GetPriorityWaitTimeout())
+	defer cancel()
+	if err := common.DownloadLimiter.Acquire(ctx, requestPriority(c)); err != nil {
+		if enableMetric {
+			prom.PromSourceCounter(prom.RequestTooManyCnt, source)
+		}
+		return util.ErrorTooManyRequest(c)
+	}
+	defer common.DownloadLimiter.Release()
+	err := next(c)
+	if enableMetric {
+		if err != nil {
+			prom.PromSourceCounter(prom.RequestFailCnt, source)
+		} else {
+			prom.PromSourceCounter(prom.RequestSuccessCnt, source)
+		}
+	}
+	return err
+}
 
-			// 处理OPTIONS预检请求
-			if c.Request().Method == http.MethodOptions {
-				return c.NoContent(http.StatusOK)
+// 优先级以token配置为上限，未配置的token及匿名请求为normal；请求头只能在上限之内指定，未指定时取上限
+func requestPriority(c echo.Context) int {
+	limit := consts.PriorityNormal
+	authorization := c.Request().Header.Get("Authorization")
+	if authorization != "" {
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if v, ok := config.SysConfig.TokenBucketLimit.Priority.Tokens[token]; ok {
+			if priority, ok := consts.PriorityMapping[strings.ToLower(v)]; ok {
+				limit = priority
 			}
-
-			return next(c)
 		}
 	}
+	if v := c.Request().Header.Get(config.SysConfig.GetPriorityHeader()); v != "" {
+		if priority, ok := consts.PriorityMapping[strings.ToLower(v)]; ok {
+			return min(priority, limit)
+		}
+	}
+	return limit
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestPriority(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.TokenBucketLimit.Priority.Tokens = map[string]string{"hf_high": "high", "hf_low": "low"}
+
+	e := echo.New()
+	cases := []struct {
+		token, header string
+		want          int
+	}{
+		{"", "", consts.PriorityNormal},
+		{"", "high", consts.PriorityNormal},
+		{"", "low", consts.PriorityLow},
+		{"hf_other", "high", consts.PriorityNormal},
+		{"hf_high", "", consts.PriorityHigh},
+		{"hf_high", "low", consts.PriorityLow},
+		{"hf_low", "", consts.PriorityLow},
+		{"hf_low", "high", consts.PriorityLow},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/org/repo/resolve/main/model.safetensors", nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		if tc.header != "" {
+			req.Header.Set(config.SysConfig.GetPriorityHeader(), tc.header)
+		}
+		if got := requestPriority(e.NewContext(req, httptest.NewRecorder())); got != tc.want {
+			t.Errorf("token %q header %q: got %d, want %d", tc.token, tc.header, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"dingospeed/pkg/errreport"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// RecoverMiddleware 捕获请求处理中的panic，记录堆栈并携带仓库信息上报，避免单个请求异常影响服务。
+func RecoverMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r) // 由net/http处理的主动中断
+			}
+			stack := debug.Stack()
+			uri := c.Request().RequestURI
+			zap.S().Errorf("panic recovered, %s %s: %v\n%s", c.Request().Method, uri, r, stack)
+			repoType, org := util.ParseUpstreamRoute(c.Request().URL.Path)
+			errreport.ReportPanic(r, stack, map[string]string{
+				"method":   c.Request().Method,
+				"uri":      uri,
+				"repoType": repoType,
+				"org":      org,
+				"repo":     c.Param("repo"),
+			})
+			if !c.Response().Committed {
+				err = util.ResponseError(c, fmt.Errorf("%v", r))
+			}
+		}()
+		return next(c)
+	}
+}
@@ -0,0 +1,45 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"strings"
+
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RepoAliasMiddleware 在路由之前将已改名仓库的旧名称替换为新名称，旧名称的请求按新名称读取缓存及请求远端，
+// 响应携带X-Dingospeed-Repo-Alias，客户端可据此更新配置。
+func RepoAliasMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		if strings.HasPrefix(req.URL.Path, "/admin/") {
+			return next(c)
+		}
+		p, alias, ok := util.RewriteRepoAliasPath(req.URL.Path)
+		if !ok {
+			return next(c)
+		}
+		req.URL.Path = p
+		if req.URL.RawPath != "" {
+			req.URL.RawPath, _, _ = util.RewriteRepoAliasPath(req.URL.RawPath)
+		}
+		c.Response().Header().Set(consts.HeaderRepoAlias, alias)
+		return next(c)
+	}
+}
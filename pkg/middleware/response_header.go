@@ -0,0 +1,36 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/util"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ResponseHeaderMiddleware 在写出响应头之前按配置的规则删除、设置或改写响应头，
+// 缓存命中与代理远端的响应均经过此处，不再原样透传远端的头部。
+func ResponseHeaderMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if rules := config.SysConfig.GetResponseHeaderRules(c.Request().URL.Path); len(rules) > 0 {
+			resp := c.Response()
+			resp.Before(func() {
+				util.ApplyHeaderRules(resp.Header(), rules)
+			})
+		}
+		return next(c)
+	}
+}
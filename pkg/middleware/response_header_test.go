@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestResponseHeaderMiddleware(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.ResponseHeaders = []config.HeaderRule{
+		{Action: "remove", Name: "Set-Cookie"},
+		{Action: "set", Name: "Cache-Control", Value: "public, max-age=60", Path: "/api/*/*/*/revision/*"},
+		{Action: "add", Name: "X-Cache-Policy", Value: "corp"},
+		{Action: "rewrite", Name: "Link", Pattern: "https://huggingface.co", Value: "https://hf.example.com"},
+	}
+	e := echo.New()
+	handler := ResponseHeaderMiddleware(func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("Set-Cookie", "session=1")
+		header.Set("Cache-Control", "private")
+		header.Add("Link", "<https://huggingface.co/api/models?p=1>; rel=\"next\"")
+		return c.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/models/org/repo/revision/main", nil), rec)
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if v := rec.Header().Get("Set-Cookie"); v != "" {
+		t.Errorf("Set-Cookie = %q, want removed", v)
+	}
+	if v := rec.Header().Get("Cache-Control"); v != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", v)
+	}
+	if v := rec.Header().Get("X-Cache-Policy"); v != "corp" {
+		t.Errorf("X-Cache-Policy = %q", v)
+	}
+	if v := rec.Header().Get("Link"); v != "<https://hf.example.com/api/models?p=1>; rel=\"next\"" {
+		t.Errorf("Link = %q", v)
+	}
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/models/org/repo/resolve/main/config.json", nil), rec)
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+	if v := rec.Header().Get("Cache-Control"); v != "private" {
+		t.Errorf("Cache-Control = %q, rule should not match other paths", v)
+	}
+}
@@ -0,0 +1,115 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/prom"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+var (
+	shadowSem    chan struct{}
+	shadowClient = &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+)
+
+// ShadowMiddleware 按比例将GET/HEAD请求复制到影子实例，在正常响应完成后异步比较状态码及响应头，
+// 影子请求的结果不影响客户端，并发超过上限时直接丢弃。
+func ShadowMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		conf := config.SysConfig
+		req := c.Request()
+		if !conf.Shadow.Enabled || (req.Method != http.MethodGet && req.Method != http.MethodHead) ||
+			req.Header.Get(consts.HeaderShadow) != "" || rand.Float64()*100 >= conf.Shadow.Percent {
+			return next(c)
+		}
+		shadowReq, err := newShadowRequest(req, conf.Shadow.Target)
+		if err != nil {
+			zap.S().Warnf("build shadow request err.%v", err)
+			return next(c)
+		}
+		err = next(c)
+		if err != nil {
+			c.Error(err)
+		}
+		resp := c.Response()
+		select {
+		case shadowSem <- struct{}{}:
+			go func(status int, header http.Header) {
+				defer func() { <-shadowSem }()
+				compareShadow(shadowReq, status, header, conf.GetShadowCompareHeaders())
+			}(resp.Status, resp.Header().Clone())
+		default:
+			prom.ShadowCnt.WithLabelValues("dropped").Inc()
+		}
+		return nil
+	}
+}
+
+func newShadowRequest(req *http.Request, target string) (*http.Request, error) {
+	shadowReq, err := http.NewRequest(req.Method, strings.TrimSuffix(target, "/")+req.URL.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+	shadowReq.Header = req.Header.Clone()
+	shadowReq.Header.Set(consts.HeaderShadow, "1")
+	shadowReq.Host = req.Host // 保持原始Host，使两边改写的链接地址一致
+	return shadowReq, nil
+}
+
+func compareShadow(shadowReq *http.Request, status int, header http.Header, names []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.SysConfig.GetShadowTimeout())
+	defer cancel()
+	resp, err := shadowClient.Do(shadowReq.WithContext(ctx))
+	if err != nil {
+		prom.ShadowCnt.WithLabelValues("error").Inc()
+		zap.S().Warnf("shadow %s %s err.%v", shadowReq.Method, shadowReq.URL.RequestURI(), err)
+		return
+	}
+	resp.Body.Close() // 只比较响应头，不读取响应体
+	if diffs := diffShadowResponse(status, header, resp.StatusCode, resp.Header, names); len(diffs) > 0 {
+		prom.ShadowCnt.WithLabelValues("mismatch").Inc()
+		zap.S().Warnf("shadow %s %s mismatch: %s", shadowReq.Method, shadowReq.URL.RequestURI(), strings.Join(diffs, "; "))
+		return
+	}
+	prom.ShadowCnt.WithLabelValues("match").Inc()
+}
+
+// diffShadowResponse 返回两个响应在状态码及指定响应头上的差异。
+func diffShadowResponse(status int, header http.Header, shadowStatus int, shadowHeader http.Header, names []string) []string {
+	var diffs []string
+	if status != shadowStatus {
+		diffs = append(diffs, fmt.Sprintf("status %d != %d", status, shadowStatus))
+	}
+	for _, name := range names {
+		if v, sv := header.Get(name), shadowHeader.Get(name); v != sv {
+			diffs = append(diffs, fmt.Sprintf("%s %q != %q", name, v, sv))
+		}
+	}
+	return diffs
+}
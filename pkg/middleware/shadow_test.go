@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestDiffShadowResponse(t *testing.T) {
+	header := http.Header{"Etag": {"\"a\""}, "Content-Length": {"10"}}
+	same := http.Header{"Etag": {"\"a\""}, "Content-Length": {"10"}, "Date": {"x"}}
+	if diffs := diffShadowResponse(200, header, 200, same, []string{"ETag", "Content-Length"}); len(diffs) != 0 {
+		t.Errorf("diffs = %v, want none", diffs)
+	}
+	other := http.Header{"Etag": {"\"b\""}, "Content-Length": {"10"}}
+	if diffs := diffShadowResponse(200, header, 404, other, []string{"ETag", "Content-Length"}); len(diffs) != 2 {
+		t.Errorf("diffs = %v, want status and ETag", diffs)
+	}
+}
+
+func TestShadowMiddleware(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Shadow = config.Shadow{Enabled: true, Target: target.URL, Percent: 100}
+	shadowSem = make(chan struct{}, 1)
+
+	e := echo.New()
+	handler := ShadowMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/models/org/repo/revision/main?expand=1", nil)
+	req.Header.Set("Authorization", "Bearer hf_x")
+	if err := handler(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-received:
+		if r.URL.RequestURI() != "/api/models/org/repo/revision/main?expand=1" {
+			t.Errorf("uri = %s", r.URL.RequestURI())
+		}
+		if r.Header.Get(consts.HeaderShadow) == "" || r.Header.Get("Authorization") != "Bearer hf_x" {
+			t.Errorf("headers = %v", r.Header)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shadow request not sent")
+	}
+
+	// 影子实例收到的请求不再转发
+	req = httptest.NewRequest(http.MethodGet, "/api/models/org/repo", nil)
+	req.Header.Set(consts.HeaderShadow, "1")
+	if err := handler(e.NewContext(req, httptest.NewRecorder())); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+		t.Error("shadowed request forwarded again")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
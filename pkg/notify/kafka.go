@@ -0,0 +1,53 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+)
+
+func init() {
+	Register("kafka", newKafka)
+}
+
+// kafka 经由Kafka REST Proxy（v2接口）写入topic，消息key为仓库，同一仓库的事件落在同一分区内保持顺序。
+type kafka struct {
+	sink   *config.NotifySink
+	client *http.Client
+}
+
+func newKafka(sink *config.NotifySink) (Notifier, error) {
+	if sink.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	return &kafka{sink: sink, client: &http.Client{}}, nil
+}
+
+func (k *kafka) Notify(ctx context.Context, event *RevisionEvent) error {
+	body, err := sonic.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{{"key": event.Datatype + "/" + event.OrgRepo, "value": event}},
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/topics/%s", k.sink.Url, k.sink.Topic)
+	return post(ctx, k.client, url, body, k.sink.Headers, map[string]string{"Content-Type": "application/vnd.kafka.json.v2+json"})
+}
@@ -0,0 +1,106 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+)
+
+func init() {
+	Register("nats", newNats)
+}
+
+// nats 按NATS文本协议发布到subject，通知频率很低，每次发送单独建立连接，以PING/PONG确认服务端已处理。
+type nats struct {
+	sink *config.NotifySink
+	addr string
+	user *url.Userinfo
+}
+
+func newNats(sink *config.NotifySink) (Notifier, error) {
+	if sink.Topic == "" {
+		return nil, fmt.Errorf("nats subject is required")
+	}
+	u, err := url.Parse(sink.Url)
+	if err != nil || u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid nats url %s", sink.Url)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &nats{sink: sink, addr: addr, user: u.User}, nil
+}
+
+func (n *nats) Notify(ctx context.Context, event *RevisionEvent) error {
+	payload, err := sonic.Marshal(event)
+	if err != nil {
+		return err
+	}
+	connect := map[string]interface{}{"verbose": false, "pedantic": false, "name": "dingospeed", "lang": "go"}
+	if n.user != nil {
+		if pass, ok := n.user.Password(); ok {
+			connect["user"], connect["pass"] = n.user.Username(), pass
+		} else {
+			connect["auth_token"] = n.user.Username()
+		}
+	}
+	connectJson, err := sonic.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+	// 服务端连接建立后首先发送INFO
+	if line, err := reader.ReadString('\n'); err != nil {
+		return err
+	} else if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("unexpected nats greeting: %s", strings.TrimSpace(line))
+	}
+	msg := fmt.Sprintf("CONNECT %s\r\nPUB %s %d\r\n%s\r\nPING\r\n", connectJson, n.sink.Topic, len(payload), payload)
+	if _, err = conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", line)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/events"
+
+	"go.uber.org/zap"
+)
+
+// 跟踪的仓库分支或标签指向新的commit时，按配置的目标依次通知下游系统，目标类型通过Register扩展。
+
+const (
+	queueSize   = 256
+	maxAttempts = 3
+)
+
+type RevisionEvent struct {
+	Datatype string    `json:"datatype"`
+	OrgRepo  string    `json:"orgRepo"`
+	Revision string    `json:"revision"`
+	OldSha   string    `json:"oldSha"`
+	NewSha   string    `json:"newSha"`
+	Instance string    `json:"instance"`
+	Time     time.Time `json:"time"`
+}
+
+// Notifier 通知目标，Notify在超时ctx内发送一次，失败时由调用方重试。
+type Notifier interface {
+	Notify(ctx context.Context, event *RevisionEvent) error
+}
+
+type Factory func(sink *config.NotifySink) (Notifier, error)
+
+type target struct {
+	sink     *config.NotifySink
+	notifier Notifier
+}
+
+var (
+	factories = make(map[string]Factory)
+	targets   []*target
+	queue     chan *RevisionEvent
+	notified  = common.NewSafeMap[string, string]() // 各仓库版本最近一次通知的commit，避免同一变化重复通知
+	instance  string
+)
+
+// Register 注册通知目标类型，需在Init之前调用。
+func Register(sinkType string, factory Factory) {
+	factories[sinkType] = factory
+}
+
+// Init 按配置创建通知目标并启动发送协程，未启用时RevisionMoved为空操作。
+func Init() error {
+	conf := config.SysConfig.RevisionNotify
+	if !conf.Enabled {
+		return nil
+	}
+	for _, pattern := range conf.Repos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid revisionNotify repo pattern %s", pattern)
+		}
+	}
+	targets = make([]*target, 0, len(conf.Sinks))
+	for i := range conf.Sinks {
+		sink := &conf.Sinks[i]
+		factory, ok := factories[sink.Type]
+		if !ok {
+			return fmt.Errorf("unknown revisionNotify sink type %s", sink.Type)
+		}
+		notifier, err := factory(sink)
+		if err != nil {
+			return fmt.Errorf("revisionNotify sink %s err.%v", sink.Url, err)
+		}
+		targets = append(targets, &target{sink: sink, notifier: notifier})
+	}
+	instance = config.SysConfig.Scheduler.Discovery.InstanceId
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+	queue = make(chan *RevisionEvent, queueSize)
+	go worker()
+	return nil
+}
+
+// Tracked 判断仓库的该分支或标签是否需要通知。
+func Tracked(repoType, orgRepo, revision string) bool {
+	if queue == nil || !slices.Contains(config.SysConfig.GetNotifyRevisions(), revision) {
+		return false
+	}
+	patterns := config.SysConfig.RevisionNotify.Repos
+	if len(patterns) == 0 {
+		return true
+	}
+	repo := repoType + "/" + orgRepo
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RevisionMoved 跟踪的版本由oldSha变为newSha时异步通知，oldSha为空（首次缓存）时不通知。
+func RevisionMoved(repoType, orgRepo, revision, oldSha, newSha string) {
+	if oldSha == "" || newSha == "" || oldSha == newSha || !Tracked(repoType, orgRepo, revision) {
+		return
+	}
+	key := fmt.Sprintf("%s/%s@%s", repoType, orgRepo, revision)
+	if last, ok := notified.Get(key); ok && last == newSha {
+		return
+	}
+	notified.Set(key, newSha)
+	event := &RevisionEvent{
+		Datatype: repoType,
+		OrgRepo:  orgRepo,
+		Revision: revision,
+		OldSha:   oldSha,
+		NewSha:   newSha,
+		Instance: instance,
+		Time:     time.Now(),
+	}
+	zap.S().Infof("%s moved from %s to %s", key, oldSha, newSha)
+	events.Publish(events.RevisionMove, event)
+	select {
+	case queue <- event:
+	default:
+		zap.S().Warnf("revision notify queue is full, drop %s", key)
+	}
+}
+
+func worker() {
+	for event := range queue {
+		for _, t := range targets {
+			send(t, event)
+		}
+	}
+}
+
+func send(t *target, event *RevisionEvent) {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), t.sink.GetTimeout())
+		err = t.notifier.Notify(ctx, event)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	zap.S().Warnf("notify %s %s/%s@%s to %s err.%v", t.sink.Type, event.Datatype, event.OrgRepo, event.Revision, t.sink.Url, err)
+}
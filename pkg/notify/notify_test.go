@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if r.Header.Get(headerSignature) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- string(body)
+	}))
+	defer srv.Close()
+
+	notifier, _ := newWebhook(&config.NotifySink{Type: "webhook", Url: srv.URL, Secret: "secret"})
+	event := &RevisionEvent{Datatype: "models", OrgRepo: "org/repo", Revision: "main", OldSha: "a", NewSha: "b"}
+	if err := notifier.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-received; !strings.Contains(body, `"newSha":"b"`) {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestNatsPublish(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	published := make(chan string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(line, "PUB ") {
+				payload, _ := reader.ReadString('\n')
+				published <- strings.TrimSpace(line) + " " + strings.TrimSpace(payload)
+			}
+			if strings.HasPrefix(line, "PING") {
+				_, _ = conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	notifier, err := newNats(&config.NotifySink{Type: "nats", Url: "nats://" + lis.Addr().String(), Topic: "models.revisions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = notifier.Notify(ctx, &RevisionEvent{OrgRepo: "org/repo", NewSha: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-published; !strings.HasPrefix(msg, "PUB models.revisions ") || !strings.Contains(msg, `"newSha":"b"`) {
+		t.Errorf("unexpected message %s", msg)
+	}
+}
+
+func TestTracked(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	conf := config.SysConfig.RevisionNotify
+	defer func() { config.SysConfig.RevisionNotify, queue = conf, nil }()
+
+	config.SysConfig.RevisionNotify = config.RevisionNotify{Enabled: true, Repos: []string{"models/org/*"}}
+	if Tracked("models", "org/repo", "main") {
+		t.Error("tracked before init")
+	}
+	queue = make(chan *RevisionEvent, 1)
+	if !Tracked("models", "org/repo", "main") || Tracked("models", "other/repo", "main") || Tracked("models", "org/repo", "dev") {
+		t.Error("unexpected tracked result")
+	}
+	RevisionMoved("models", "org/repo", "main", "a", "b")
+	RevisionMoved("models", "org/repo", "main", "a", "b")
+	RevisionMoved("models", "org/repo", "main", "", "c")
+	if len(queue) != 1 {
+		t.Errorf("queued %d events, want 1", len(queue))
+	}
+}
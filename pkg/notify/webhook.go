@@ -0,0 +1,84 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	headerEvent     = "X-Dingospeed-Event"
+	headerSignature = "X-Dingospeed-Signature"
+)
+
+func init() {
+	Register("webhook", newWebhook)
+}
+
+// webhook 以JSON格式POST事件，配置secret时以HMAC-SHA256对请求体签名，接收方据此校验来源。
+type webhook struct {
+	sink   *config.NotifySink
+	client *http.Client
+}
+
+func newWebhook(sink *config.NotifySink) (Notifier, error) {
+	return &webhook{sink: sink, client: &http.Client{}}, nil
+}
+
+func (w *webhook) Notify(ctx context.Context, event *RevisionEvent) error {
+	body, err := sonic.Marshal(event)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json", headerEvent: "revision.move"}
+	if w.sink.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.sink.Secret))
+		mac.Write(body)
+		headers[headerSignature] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return post(ctx, w.client, w.sink.Url, body, w.sink.Headers, headers)
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte, extra, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range extra {
+		req.Header.Set(k, v)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("response code %d", resp.StatusCode)
+	}
+	return nil
+}
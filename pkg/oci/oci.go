@@ -0,0 +1,219 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	MediaTypeEmptyJSON     = "application/vnd.oci.empty.v1+json"
+	MediaTypeLayer         = "application/vnd.oci.image.layer.v1.tar" // 与oras push单个文件时的默认类型一致
+	ArtifactTypeModel      = "application/vnd.dingospeed.repo.v1"
+
+	AnnotationTitle    = "org.opencontainers.image.title" // oras pull按该注解还原文件名
+	AnnotationRefName  = "org.opencontainers.image.ref.name"
+	AnnotationSource   = "org.opencontainers.image.source"
+	AnnotationRevision = "org.opencontainers.image.revision"
+	AnnotationCreated  = "org.opencontainers.image.created"
+
+	layoutVersion = "1.0.0"
+)
+
+// 空配置{}，OCI 1.1约定无配置的制品使用该描述符
+var emptyConfig = Descriptor{
+	MediaType: MediaTypeEmptyJSON,
+	Digest:    "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+	Size:      2,
+	Data:      []byte("{}"),
+}
+
+type Descriptor struct {
+	MediaType    string            `json:"mediaType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	Data         []byte            `json:"data,omitempty"`
+}
+
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// Layer 制品中的一个文件，Digest为空时打包过程中计算。
+type Layer struct {
+	Name   string
+	Size   int64
+	Digest string
+	Open   func() (io.ReadCloser, error)
+}
+
+func (l *Layer) descriptor() Descriptor {
+	return Descriptor{
+		MediaType:   MediaTypeLayer,
+		Digest:      l.Digest,
+		Size:        l.Size,
+		Annotations: map[string]string{AnnotationTitle: l.Name},
+	}
+}
+
+// BuildManifest 生成制品清单，所有层的Digest必须已知。
+func BuildManifest(layers []*Layer, annotations map[string]string) ([]byte, Descriptor, error) {
+	manifest := Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeImageManifest,
+		ArtifactType:  ArtifactTypeModel,
+		Config:        emptyConfig,
+		Layers:        make([]Descriptor, 0, len(layers)),
+		Annotations:   annotations,
+	}
+	manifest.Config.Data = nil
+	for _, layer := range layers {
+		if layer.Digest == "" {
+			return nil, Descriptor{}, fmt.Errorf("layer %s digest is empty", layer.Name)
+		}
+		manifest.Layers = append(manifest.Layers, layer.descriptor())
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, Descriptor{}, err
+	}
+	return content, Descriptor{
+		MediaType:    MediaTypeImageManifest,
+		ArtifactType: ArtifactTypeModel,
+		Digest:       Digest(content),
+		Size:         int64(len(content)),
+	}, nil
+}
+
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// WriteLayout 将制品写为OCI镜像布局目录，tag记录在index.json的ref.name注解中，可直接用oras cp --from-oci-layout推送。
+func WriteLayout(dir, tag string, layers []*Layer, annotations map[string]string) (string, error) {
+	blobsDir := filepath.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		return "", err
+	}
+	for _, layer := range layers {
+		if err := writeLayoutBlob(blobsDir, layer); err != nil {
+			return "", fmt.Errorf("write layer %s err: %v", layer.Name, err)
+		}
+	}
+	if err := writeBlobFile(blobsDir, emptyConfig.Digest, emptyConfig.Data); err != nil {
+		return "", err
+	}
+	manifest, desc, err := BuildManifest(layers, annotations)
+	if err != nil {
+		return "", err
+	}
+	if err = writeBlobFile(blobsDir, desc.Digest, manifest); err != nil {
+		return "", err
+	}
+	index := Index{SchemaVersion: 2, MediaType: MediaTypeImageIndex, Manifests: make([]Descriptor, 0, 1)}
+	indexPath := filepath.Join(dir, "index.json")
+	if b, err := os.ReadFile(indexPath); err == nil {
+		if err = json.Unmarshal(b, &index); err != nil {
+			return "", fmt.Errorf("parse %s err: %v", indexPath, err)
+		}
+	}
+	// 同名tag指向新清单
+	manifests := make([]Descriptor, 0, len(index.Manifests)+1)
+	for _, m := range index.Manifests {
+		if m.Annotations[AnnotationRefName] != tag {
+			manifests = append(manifests, m)
+		}
+	}
+	desc.Annotations = map[string]string{AnnotationRefName: tag}
+	index.Manifests = append(manifests, desc)
+	indexContent, err := json.Marshal(index)
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(fmt.Sprintf(`{"imageLayoutVersion":"%s"}`, layoutVersion)), 0644); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(indexPath, indexContent, 0644); err != nil {
+		return "", err
+	}
+	return desc.Digest, nil
+}
+
+func writeBlobFile(blobsDir, digest string, content []byte) error {
+	return os.WriteFile(filepath.Join(blobsDir, digestHex(digest)), content, 0644)
+}
+
+// 先写入临时文件并计算摘要，与预期不符时报错，已存在的同摘要文件直接复用。
+func writeLayoutBlob(blobsDir string, layer *Layer) error {
+	if layer.Digest != "" {
+		if info, err := os.Stat(filepath.Join(blobsDir, digestHex(layer.Digest))); err == nil && info.Size() == layer.Size {
+			return nil
+		}
+	}
+	r, err := layer.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tmp, err := os.CreateTemp(blobsDir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n != layer.Size {
+		return fmt.Errorf("size mismatch, expected %d, got %d", layer.Size, n)
+	}
+	digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if layer.Digest != "" && layer.Digest != digest {
+		return fmt.Errorf("digest mismatch, expected %s, got %s", layer.Digest, digest)
+	}
+	layer.Digest = digest
+	return os.Rename(tmp.Name(), filepath.Join(blobsDir, digestHex(digest)))
+}
+
+func digestHex(digest string) string {
+	return strings.TrimPrefix(digest, "sha256:")
+}
@@ -0,0 +1,65 @@
+package oci
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	cases := []struct {
+		ref, host, repository, tag string
+		ok                         bool
+	}{
+		{"registry.example.com/models/qwen:v1", "registry.example.com", "models/qwen", "v1", true},
+		{"localhost:5000/qwen", "localhost:5000", "qwen", "", true},
+		{"qwen:v1", "", "", "", false},
+		{"localhost:5000/Qwen:v1", "", "", "", false},
+	}
+	for _, tc := range cases {
+		host, repository, tag, err := ParseReference(tc.ref)
+		if (err == nil) != tc.ok || host != tc.host || repository != tc.repository || tag != tc.tag {
+			t.Errorf("ParseReference(%s) = %s, %s, %s, %v", tc.ref, host, repository, tag, err)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.example.com/token" || params["service"] != "registry.example.com" || params["scope"] != "repository:a:pull" {
+		t.Errorf("parseChallenge = %s, %v", scheme, params)
+	}
+}
+
+func TestWriteLayout(t *testing.T) {
+	dir := t.TempDir()
+	layers := []*Layer{{
+		Name: "config.json",
+		Size: 2,
+		Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("{}")), nil },
+	}}
+	digest, err := WriteLayout(dir, "v1", layers, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layers[0].Digest != emptyConfig.Digest {
+		t.Errorf("layer digest = %s, want %s", layers[0].Digest, emptyConfig.Digest)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index Index
+	if err = json.Unmarshal(b, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 || index.Manifests[0].Digest != digest || index.Manifests[0].Annotations[AnnotationRefName] != "v1" {
+		t.Errorf("unexpected index %s", b)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "blobs", "sha256", digestHex(digest))); err != nil {
+		t.Error(err)
+	}
+}
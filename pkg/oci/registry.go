@@ -0,0 +1,296 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Registry 遵循OCI distribution规范的镜像仓库客户端，支持Basic与Bearer token认证。
+type Registry struct {
+	Host       string
+	Repository string
+	Username   string
+	Password   string
+	PlainHTTP  bool
+	Client     *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// ParseReference 解析host[:port]/name[:tag]形式的制品地址，未指定tag时返回空。
+func ParseReference(reference string) (host, repository, tag string, err error) {
+	host, repository, ok := strings.Cut(reference, "/")
+	if !ok || host == "" || repository == "" {
+		return "", "", "", fmt.Errorf("invalid reference %q, expected host/name[:tag]", reference)
+	}
+	if i := strings.LastIndex(repository, ":"); i > 0 && !strings.Contains(repository[i:], "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	if repository != strings.ToLower(repository) {
+		return "", "", "", fmt.Errorf("repository name %q must be lowercase", repository)
+	}
+	return host, repository, tag, nil
+}
+
+// Push 上传所有层与清单并打上tag，已存在的层跳过上传，返回清单摘要。
+func (r *Registry) Push(ctx context.Context, tag string, layers []*Layer, annotations map[string]string, onLayer func(layer *Layer)) (string, error) {
+	for _, layer := range layers {
+		if err := r.pushLayer(ctx, layer); err != nil {
+			return "", fmt.Errorf("push layer %s err: %v", layer.Name, err)
+		}
+		if onLayer != nil {
+			onLayer(layer)
+		}
+	}
+	config := emptyConfig
+	if err := r.pushBlob(ctx, config.Digest, config.Size, func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(string(config.Data))), nil
+	}); err != nil {
+		return "", fmt.Errorf("push config err: %v", err)
+	}
+	manifest, desc, err := BuildManifest(layers, annotations)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.url("manifests/"+tag), strings.NewReader(string(manifest)))
+		if err == nil {
+			req.Header.Set("Content-Type", MediaTypeImageManifest)
+		}
+		return req, err
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", responseError("put manifest", resp)
+	}
+	return desc.Digest, nil
+}
+
+func (r *Registry) pushLayer(ctx context.Context, layer *Layer) error {
+	if layer.Digest == "" {
+		digest, err := computeDigest(layer)
+		if err != nil {
+			return err
+		}
+		layer.Digest = digest
+	}
+	return r.pushBlob(ctx, layer.Digest, layer.Size, layer.Open)
+}
+
+// 单次PUT上传整个blob，由仓库端校验摘要。
+func (r *Registry) pushBlob(ctx context.Context, digest string, size int64, open func() (io.ReadCloser, error)) error {
+	resp, err := r.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodHead, r.url("blobs/"+digest), nil)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	resp, err = r.do(ctx, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, r.url("blobs/uploads/"), nil)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return responseError("start upload", resp)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location %q: %v", resp.Header.Get("Location"), err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+	resp, err = r.do(ctx, func() (*http.Request, error) {
+		body, err := open()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, location.String(), body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return responseError("upload blob", resp)
+	}
+	return nil
+}
+
+func (r *Registry) url(path string) string {
+	scheme := "https"
+	if r.PlainHTTP {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, r.Host, r.Repository, path)
+}
+
+// 收到401时按WWW-Authenticate获取凭证后重试一次。
+func (r *Registry) do(ctx context.Context, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		r.authorize(req)
+		resp, err := r.client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err = r.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Registry) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return http.DefaultClient
+}
+
+func (r *Registry) authorize(req *http.Request) {
+	r.mu.Lock()
+	token := r.token
+	r.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	} else if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+}
+
+func (r *Registry) authenticate(ctx context.Context, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	if !strings.EqualFold(scheme, "bearer") {
+		if r.Username == "" {
+			return fmt.Errorf("registry %s requires authentication", r.Host)
+		}
+		return nil
+	}
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid auth realm %q", params["realm"])
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull,push", r.Repository))
+	tokenURL.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError("fetch token", resp)
+	}
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return err
+	}
+	token := tokenResp.Token
+	if token == "" {
+		token = tokenResp.AccessToken
+	}
+	if token == "" {
+		return fmt.Errorf("empty token from %s", tokenURL.Host)
+	}
+	r.mu.Lock()
+	r.token = "Bearer " + token
+	r.mu.Unlock()
+	return nil
+}
+
+// 解析Bearer realm="...",service="...",scope="..."形式的认证挑战。
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return scheme, params
+}
+
+func computeDigest(layer *Layer) (string, error) {
+	r, err := layer.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func responseError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s failed, status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -55,6 +55,46 @@ var (
 		Name: "request_response_byte",
 		Help: "Total number of request response byte",
 	}, []string{"source", "orgRepo"})
+
+	// 远端健康
+
+	UpstreamRequestCnt = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_request_cnt",
+		Help: "Total number of upstream request",
+	}, []string{"endpoint", "result"})
+
+	// 按分类统计的错误响应数
+
+	ErrorKindCnt = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "error_kind_cnt",
+		Help: "Total number of error response by kind",
+	}, []string{"kind"})
+
+	// 影子请求的比较结果：match、mismatch、error、dropped
+
+	ShadowCnt = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "shadow_request_cnt",
+		Help: "Total number of shadowed request by result",
+	}, []string{"result"})
+
+	// 恶意文件扫描结果：clean、infected、error
+
+	MalwareScanCnt = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "malware_scan_cnt",
+		Help: "Total number of scanned files by result",
+	}, []string{"result"})
+
+	// 窗口内文件请求完全由缓存响应的比例，用于判断节点的预热程度
+
+	CacheWarmRatio = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_warm_ratio",
+		Help: "Fraction of recent file requests served entirely from local cache",
+	})
+
+	UpstreamDown = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "upstream_down",
+		Help: "Whether the upstream is considered down",
+	}, []string{"endpoint"})
 )
 
 func PromSourceCounter(vec *prometheus.GaugeVec, source string) {
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 简单的内存RESP服务端，只支持测试用到的命令
+func startFakeServer(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	store := map[string]string{}
+	go func() {
+		for {
+			nc, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer nc.Close()
+				r := bufio.NewReader(nc)
+				for {
+					v, err := readReply(r)
+					if err != nil {
+						return
+					}
+					items := v.([]interface{})
+					args := make([]string, len(items))
+					for i, item := range items {
+						args[i] = item.(string)
+					}
+					var reply string
+					switch strings.ToUpper(args[0]) {
+					case "SET":
+						store[args[1]] = args[2]
+						reply = "+OK\r\n"
+					case "GET":
+						if v, ok := store[args[1]]; ok {
+							reply = "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+						} else {
+							reply = "$-1\r\n"
+						}
+					case "DEL":
+						delete(store, args[1])
+						reply = ":1\r\n"
+					default:
+						reply = "-ERR unknown command\r\n"
+					}
+					if _, err = nc.Write([]byte(reply)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+func TestClient(t *testing.T) {
+	c := NewClient(startFakeServer(t), "", 0, time.Second, 2)
+	defer c.Close()
+	ctx := context.Background()
+	if _, err := c.Get(ctx, "k"); err != ErrNil {
+		t.Fatalf("expected ErrNil, got %v", err)
+	}
+	if err := c.Set(ctx, "k", "v1", 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || v != "v1" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+	if err := c.Del(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(ctx, "BAD"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Fatalf("expected error reply, got %v", err)
+	}
+	// 错误回复后连接仍可复用
+	if _, err := c.Get(ctx, "k"); err != ErrNil {
+		t.Fatalf("expected ErrNil, got %v", err)
+	}
+}
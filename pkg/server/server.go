@@ -22,3 +22,8 @@ type Server interface {
 	Start(context.Context) error
 	Stop(context.Context) error
 }
+
+// Readier 由需要在监听就绪后才通知外部（如systemd）的服务实现，监听建立后关闭返回的channel。
+type Readier interface {
+	Ready() <-chan struct{}
+}
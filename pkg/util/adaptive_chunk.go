@@ -0,0 +1,86 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"time"
+
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+)
+
+// 每次写给客户端期望的耗时，按实测吞吐量换算出下一次写入的字节数
+const chunkTargetWriteTime = 50 * time.Millisecond
+
+// 客户端最近一次下载的实测吞吐量（B/s），用于预读大小，按客户端IP记录
+var clientThroughput = common.NewSafeMap[string, int64]()
+
+// chunkSizer 按连接实测的吞吐量调整每次写入的字节数，每次向目标值移动一半，避免单次抖动导致剧烈变化。
+type chunkSizer struct {
+	size     int
+	min, max int
+	bytes    int64
+	elapsed  time.Duration
+}
+
+func newChunkSizer() *chunkSizer {
+	if !config.SysConfig.Download.Adaptive.Enabled {
+		return &chunkSizer{size: streamBufferSize, min: streamBufferSize, max: streamBufferSize}
+	}
+	minSize, maxSize := config.SysConfig.GetAdaptiveChunkRange()
+	return &chunkSizer{size: min(max(streamBufferSize, minSize), maxSize), min: minSize, max: maxSize}
+}
+
+// observe 记录一次写入n字节的耗时并调整下一次写入的字节数，不足最小块的写入耗时误差较大，不参与调整。
+func (s *chunkSizer) observe(n int, elapsed time.Duration) {
+	s.bytes += int64(n)
+	s.elapsed += elapsed
+	if s.min == s.max || n < s.min {
+		return
+	}
+	target := s.max
+	if elapsed > 0 {
+		target = int(float64(n) / elapsed.Seconds() * chunkTargetWriteTime.Seconds())
+	}
+	s.size = min(max(s.size+(target-s.size)/2, s.min), s.max)
+}
+
+// throughput 返回连接的平均吞吐量，单位B/s，数据量过少时返回0。
+func (s *chunkSizer) throughput() int64 {
+	if s.elapsed <= 0 || s.bytes < int64(s.min) {
+		return 0
+	}
+	return int64(float64(s.bytes) / s.elapsed.Seconds())
+}
+
+func recordClientThroughput(clientIP string, bps int64) {
+	if bps > 0 && config.SysConfig.Download.Adaptive.Enabled {
+		clientThroughput.Set(clientIP, bps)
+	}
+}
+
+// ReadAheadSize 返回客户端的预读大小：开启自适应且已测得客户端吞吐量时，为该速度下readAheadSeconds秒读取的数据量，
+// 不小于一个块且不超过readAheadSize；否则为readAheadSize。
+func ReadAheadSize(clientIP string) int64 {
+	size := config.SysConfig.Download.ReadAheadSize
+	if size <= 0 || !config.SysConfig.Download.Adaptive.Enabled {
+		return size
+	}
+	bps, ok := clientThroughput.Get(clientIP)
+	if !ok {
+		return size
+	}
+	return min(max(bps*config.SysConfig.GetAdaptiveReadAheadSeconds(), config.SysConfig.Download.BlockSize), size)
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestChunkSizer(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	download := config.SysConfig.Download
+	defer func() { config.SysConfig.Download = download }()
+
+	config.SysConfig.Download.Adaptive = config.Adaptive{}
+	s := newChunkSizer()
+	s.observe(streamBufferSize, time.Microsecond)
+	if s.size != streamBufferSize {
+		t.Errorf("disabled: size %d", s.size)
+	}
+
+	config.SysConfig.Download.Adaptive = config.Adaptive{Enabled: true, MinChunkSize: 16 * 1024, MaxChunkSize: 1024 * 1024}
+	fast := newChunkSizer()
+	for i := 0; i < 20; i++ {
+		fast.observe(fast.size, time.Millisecond)
+	}
+	if fast.size != 1024*1024 {
+		t.Errorf("fast client: size %d, want max", fast.size)
+	}
+	slow := newChunkSizer()
+	for i := 0; i < 20; i++ {
+		slow.observe(slow.size, time.Second)
+	}
+	if slow.size != 16*1024 {
+		t.Errorf("slow client: size %d, want min", slow.size)
+	}
+	if bps := slow.throughput(); bps <= 0 || bps > 32*1024 {
+		t.Errorf("slow client throughput %d", bps)
+	}
+
+	config.SysConfig.Download.ReadAheadSize = 256 * 1024 * 1024
+	config.SysConfig.Download.BlockSize = 8 * 1024 * 1024
+	if got := ReadAheadSize("10.0.0.9"); got != 256*1024*1024 {
+		t.Errorf("unknown client read-ahead %d", got)
+	}
+	recordClientThroughput("10.0.0.9", 100*1024)
+	if got := ReadAheadSize("10.0.0.9"); got != 8*1024*1024 {
+		t.Errorf("slow client read-ahead %d, want block size", got)
+	}
+	recordClientThroughput("10.0.0.9", 10*1024*1024)
+	if got := ReadAheadSize("10.0.0.9"); got != 100*1024*1024 {
+		t.Errorf("read-ahead %d, want 10s of throughput", got)
+	}
+	clientThroughput.Delete("10.0.0.9")
+}
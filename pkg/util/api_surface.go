@@ -0,0 +1,65 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"dingospeed/pkg/consts"
+)
+
+// ApiFeature 返回请求所属的可关闭接口功能，不属于任何功能时返回空。
+// 未适配接口的原样转发无法从路径判断，由转发处单独校验。
+func ApiFeature(method, urlPath string) string {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	switch {
+	case len(segments) == 1 && slices.Contains(consts.DatasetsServerEndpoints, segments[0]):
+		return consts.ApiFeatureDatasetsServer
+	case segments[0] == "lfs-proxy" || strings.Contains(urlPath, "/info/lfs/objects/batch"):
+		return consts.ApiFeatureUpload
+	case segments[0] != "api" || len(segments) < 2:
+		return ""
+	}
+	if segments[1] == "repos" && len(segments) == 3 {
+		return consts.ApiFeatureUpload
+	}
+	if segments[1] == "quicksearch" {
+		return consts.ApiFeatureSearch
+	}
+	if _, ok := consts.RepoTypesMapping[segments[1]]; !ok {
+		return ""
+	}
+	if len(segments) == 2 {
+		if method == http.MethodGet {
+			return consts.ApiFeatureSearch
+		}
+		return ""
+	}
+	if len(segments) < 5 {
+		return ""
+	}
+	// 仓库接口为/api/{type}/{org}/{repo}/{action}，省略组织时为/api/{type}/{repo}/{action}/{revision}
+	for _, action := range segments[3:5] {
+		switch action {
+		case "commits":
+			return consts.ApiFeatureCommits
+		case "commit", "preupload", "settings", "branch":
+			return consts.ApiFeatureUpload
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+
+	"dingospeed/pkg/consts"
+)
+
+func TestApiFeature(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/models", consts.ApiFeatureSearch},
+		{http.MethodGet, "/api/quicksearch", consts.ApiFeatureSearch},
+		{http.MethodGet, "/api/models/org/repo/commits/main", consts.ApiFeatureCommits},
+		{http.MethodGet, "/api/models/gpt2/commits/main", consts.ApiFeatureCommits},
+		{http.MethodPost, "/api/models/org/repo/commit/main", consts.ApiFeatureUpload},
+		{http.MethodPost, "/api/models/org/repo/preupload/main", consts.ApiFeatureUpload},
+		{http.MethodPost, "/api/repos/create", consts.ApiFeatureUpload},
+		{http.MethodPost, "/org/repo.git/info/lfs/objects/batch", consts.ApiFeatureUpload},
+		{http.MethodPut, "/lfs-proxy/abc", consts.ApiFeatureUpload},
+		{http.MethodGet, "/rows", consts.ApiFeatureDatasetsServer},
+		{http.MethodGet, "/api/models/org/repo/revision/main", ""},
+		{http.MethodGet, "/api/models/org/commits", ""},
+		{http.MethodGet, "/api/models/org/repo/tree/main/commits", ""},
+		{http.MethodGet, "/org/repo/resolve/main/config.json", ""},
+		{http.MethodGet, "/api/whoami-v2", ""},
+		{http.MethodGet, "/api/models/gpt2", ""},
+	}
+	for _, c := range cases {
+		if got := ApiFeature(c.method, c.path); got != c.want {
+			t.Errorf("ApiFeature(%s %s) = %q, want %q", c.method, c.path, got, c.want)
+		}
+	}
+}
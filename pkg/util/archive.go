@@ -0,0 +1,119 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	myerr "dingospeed/pkg/error"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveChecksumFile 归档的最后一个文件，sha256sum格式记录其余文件的校验和
+const ArchiveChecksumFile = "SHA256SUMS"
+
+// 归档内所有文件使用固定的修改时间、属主及权限，相同的快照总是生成相同的归档。
+var archiveModTime = time.Unix(0, 0).UTC()
+
+type ArchiveFile struct {
+	Name   string
+	Size   int64
+	Sha256 string // 已知的内容校验和，为空时在写入时计算
+	Open   func() (io.ReadCloser, error)
+}
+
+// WriteArchive 将文件按路径排序后写入tar，文件位于prefix目录下，compress为true时使用zstd压缩。
+// 写入时校验内容的sha256，与已知值不一致时中止，避免打包损坏的缓存。
+func WriteArchive(w io.Writer, prefix string, files []*ArchiveFile, compress bool) error {
+	sorted := make([]*ArchiveFile, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	var encoder *zstd.Encoder
+	if compress {
+		var err error
+		if encoder, err = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1)); err != nil {
+			return err
+		}
+		w = encoder
+	}
+	tw := tar.NewWriter(w)
+	var sums strings.Builder
+	for _, f := range sorted {
+		sum, err := writeArchiveFile(tw, path.Join(prefix, f.Name), f)
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", f.Name, err)
+		}
+		fmt.Fprintf(&sums, "%s  %s\n", sum, f.Name)
+	}
+	if err := tw.WriteHeader(archiveHeader(path.Join(prefix, ArchiveChecksumFile), int64(sums.Len()))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, sums.String()); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if encoder != nil {
+		return encoder.Close()
+	}
+	return nil
+}
+
+func writeArchiveFile(tw *tar.Writer, name string, f *ArchiveFile) (string, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	if err = tw.WriteHeader(archiveHeader(name, f.Size)); err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, hash), reader)
+	if err != nil {
+		return "", err
+	}
+	if n != f.Size {
+		return "", myerr.NewKind(myerr.KindCacheCorrupt, fmt.Sprintf("size mismatch, expected %d, got %d", f.Size, n))
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if f.Sha256 != "" && f.Sha256 != sum {
+		return "", myerr.NewKind(myerr.KindCacheCorrupt, fmt.Sprintf("sha256 mismatch, expected %s, got %s", f.Sha256, sum))
+	}
+	return sum, nil
+}
+
+func archiveHeader(name string, size int64) *tar.Header {
+	return &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  archiveModTime,
+		Format:   tar.FormatPAX,
+	}
+}
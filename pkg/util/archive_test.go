@@ -0,0 +1,91 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func archiveFiles(contents map[string]string) []*ArchiveFile {
+	files := make([]*ArchiveFile, 0, len(contents))
+	for name, content := range contents {
+		files = append(files, &ArchiveFile{
+			Name: name,
+			Size: int64(len(content)),
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(content)), nil
+			},
+		})
+	}
+	return files
+}
+
+func TestWriteArchive(t *testing.T) {
+	contents := map[string]string{"b.txt": "bbb", "a/config.json": "{}", "README.md": "# readme"}
+	var first, second bytes.Buffer
+	if err := WriteArchive(&first, "repo-abc", archiveFiles(contents), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteArchive(&second, "repo-abc", archiveFiles(contents), false); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatal("archive of the same files should be identical")
+	}
+
+	tr := tar.NewReader(&first)
+	var names []string
+	var sums string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		data, _ := io.ReadAll(tr)
+		if hdr.Name == "repo-abc/"+ArchiveChecksumFile {
+			sums = string(data)
+		}
+	}
+	want := []string{"repo-abc/README.md", "repo-abc/a/config.json", "repo-abc/b.txt", "repo-abc/" + ArchiveChecksumFile}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+	sum := sha256.Sum256([]byte("bbb"))
+	if !strings.Contains(sums, hex.EncodeToString(sum[:])+"  b.txt\n") {
+		t.Errorf("checksums missing b.txt: %s", sums)
+	}
+}
+
+func TestWriteArchiveZstd(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, "repo", archiveFiles(map[string]string{"a": "aaa"}), true); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := zstd.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer decoder.Close()
+	hdr, err := tar.NewReader(decoder).Next()
+	if err != nil || hdr.Name != "repo/a" {
+		t.Fatalf("first entry = %v, %v", hdr, err)
+	}
+}
+
+func TestWriteArchiveChecksumMismatch(t *testing.T) {
+	files := archiveFiles(map[string]string{"a": "aaa"})
+	files[0].Sha256 = strings.Repeat("0", 64)
+	if err := WriteArchive(io.Discard, "repo", files, false); err == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+}
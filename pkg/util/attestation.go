@@ -0,0 +1,117 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"dingospeed/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// 缓存清单签名：清单序列化后的原始字节以ed25519签名，下游用公钥对payload原文校验，无需还原JSON格式。
+
+var attestationKey struct {
+	once sync.Once
+	key  ed25519.PrivateKey
+	err  error
+}
+
+func attestationKeyFile() string {
+	if keyFile := config.SysConfig.Attestation.KeyFile; keyFile != "" {
+		return keyFile
+	}
+	return filepath.Join(config.SysConfig.Repos(), "attestation", "ed25519.pem")
+}
+
+// AttestationKey 加载签名私钥，未配置且默认路径不存在时生成新的私钥。
+func AttestationKey() (ed25519.PrivateKey, error) {
+	attestationKey.once.Do(func() {
+		attestationKey.key, attestationKey.err = loadOrCreateAttestationKey(attestationKeyFile(), config.SysConfig.Attestation.KeyFile == "")
+	})
+	return attestationKey.key, attestationKey.err
+}
+
+func loadOrCreateAttestationKey(keyFile string, create bool) (ed25519.PrivateKey, error) {
+	b, err := os.ReadFile(keyFile)
+	if err == nil {
+		return parseAttestationKey(b)
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("read attestation key %s err.%v", keyFile, err)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	zap.S().Infof("attestation key is generated at %s, key id %s", keyFile, AttestationKeyId(key.Public().(ed25519.PublicKey)))
+	return key, nil
+}
+
+func parseAttestationKey(b []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("attestation key is not in PEM format")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("attestation key is not an ed25519 key")
+	}
+	return edKey, nil
+}
+
+// AttestationKeyId 公钥sha256的前8字节，用于区分轮换前后的密钥。
+func AttestationKeyId(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// AttestationPublicKeyPEM 以PKIX PEM格式导出公钥，供openssl等工具校验。
+func AttestationPublicKeyPEM(pub ed25519.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func VerifyAttestation(pub ed25519.PublicKey, payload, signature []byte) bool {
+	return len(pub) == ed25519.PublicKeySize && ed25519.Verify(pub, payload, signature)
+}
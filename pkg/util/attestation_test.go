@@ -0,0 +1,41 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttestationKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "attestation", "ed25519.pem")
+	if _, err := loadOrCreateAttestationKey(keyFile, false); err == nil {
+		t.Fatal("missing key file accepted without create")
+	}
+	key, err := loadOrCreateAttestationKey(keyFile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadOrCreateAttestationKey(keyFile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(loaded) {
+		t.Fatal("reloaded key differs from generated key")
+	}
+
+	pub := key.Public().(ed25519.PublicKey)
+	payload := []byte(`{"commit":"abc","files":[]}`)
+	signature := ed25519.Sign(key, payload)
+	if !VerifyAttestation(pub, payload, signature) {
+		t.Error("valid signature rejected")
+	}
+	if VerifyAttestation(pub, []byte(`{"commit":"abd","files":[]}`), signature) {
+		t.Error("tampered payload accepted")
+	}
+	if len(AttestationKeyId(pub)) != 16 {
+		t.Errorf("unexpected key id %s", AttestationKeyId(pub))
+	}
+	if _, err = AttestationPublicKeyPEM(pub); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,146 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// blob引用计数：resolve目录下的每个软链接是某个版本对blob的一次引用，多个版本的同名文件内容相同时共享同一个blob。
+// 索引按仓库在首次使用时扫描resolve目录建立，之后随软链接的创建和删除增减，磁盘清理及版本回收只删除引用数为0的blob。
+// 扫描与创建软链接并发时可能多计一次，只会使blob晚一些被删除。
+
+var (
+	blobRefsMu sync.Mutex
+	blobRefs   = make(map[string]map[string]int) // 仓库目录 -> blob路径 -> 引用数
+)
+
+// 仓库目录为blob所在blobs目录的上一级
+func blobRepoDir(blob string) string {
+	return filepath.Dir(filepath.Dir(blob))
+}
+
+func loadBlobRefs(repoDir string) map[string]int {
+	if refs, ok := blobRefs[repoDir]; ok {
+		return refs
+	}
+	refs := make(map[string]int)
+	for _, blob := range ResolveLinks(filepath.Join(repoDir, "resolve")) {
+		if blobRepoDir(blob) == repoDir {
+			refs[blob]++
+		}
+	}
+	blobRefs[repoDir] = refs
+	return refs
+}
+
+// ResolveLinks 返回dir下指向blobs目录的软链接及其指向的blob路径。
+func ResolveLinks(dir string) map[string]string {
+	links := make(map[string]string)
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		if blob, ok := linkBlob(path); ok {
+			links[path] = blob
+		}
+		return nil
+	})
+	return links
+}
+
+func linkBlob(link string) (string, bool) {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	target = filepath.Clean(target)
+	if filepath.Base(filepath.Dir(target)) != "blobs" {
+		return "", false
+	}
+	return target, true
+}
+
+// addBlobRef 新建指向blob的软链接后增加引用数，索引尚未建立时不做处理，建立时会扫描到该链接。
+func addBlobRef(blob string) {
+	if filepath.Base(filepath.Dir(blob)) != "blobs" {
+		return
+	}
+	blobRefsMu.Lock()
+	defer blobRefsMu.Unlock()
+	if refs, ok := blobRefs[blobRepoDir(blob)]; ok {
+		refs[filepath.Clean(blob)]++
+	}
+}
+
+// RemoveBlobRef 删除版本对blob的引用（软链接），返回其指向的blob及剩余的引用数。
+func RemoveBlobRef(link string) (string, int, error) {
+	blob, ok := linkBlob(link)
+	if err := os.Remove(link); err != nil {
+		return "", 0, err
+	}
+	if !ok {
+		return "", 0, nil
+	}
+	blobRefsMu.Lock()
+	defer blobRefsMu.Unlock()
+	repoDir := blobRepoDir(blob)
+	if _, loaded := blobRefs[repoDir]; !loaded {
+		// 链接已删除，建立索引时不会再计入
+		return blob, loadBlobRefs(repoDir)[blob], nil
+	}
+	refs := blobRefs[repoDir]
+	if refs[blob] > 1 {
+		refs[blob]--
+	} else {
+		delete(refs, blob)
+	}
+	return blob, refs[blob], nil
+}
+
+// BlobRefCount 返回blob被缓存版本引用的次数。
+func BlobRefCount(blob string) int {
+	blob = filepath.Clean(blob)
+	blobRefsMu.Lock()
+	defer blobRefsMu.Unlock()
+	return loadBlobRefs(blobRepoDir(blob))[blob]
+}
+
+// BlobRefCounts 返回仓库所有blob的引用数副本，用于预估删除版本后可释放的blob。
+func BlobRefCounts(repoType, orgRepo string) map[string]int {
+	blobRefsMu.Lock()
+	defer blobRefsMu.Unlock()
+	return maps.Clone(loadBlobRefs(RepoFilesDir(repoType, orgRepo)))
+}
+
+// ResetBlobRefs 仓库目录被整体移动或删除后清除其索引，下次使用时重新扫描。
+func ResetBlobRefs(repoType, orgRepo string) {
+	blobRefsMu.Lock()
+	defer blobRefsMu.Unlock()
+	delete(blobRefs, RepoFilesDir(repoType, orgRepo))
+}
+
+// IsBlobFile 判断缓存文件是否为blobs目录下的blob。
+func IsBlobFile(path string) bool {
+	return filepath.Base(filepath.Dir(path)) == "blobs" && !strings.HasSuffix(path, ".tmp")
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestBlobRefs(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	repos := config.SysConfig.Server.Repos
+	defer func() { config.SysConfig.Server.Repos = repos }()
+	config.SysConfig.Server.Repos = t.TempDir()
+
+	blob := BlobsFile("models", "org/repo", "etag1")
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blob, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := make([]string, 0)
+	for _, sha := range []string{"a", "b"} {
+		link := ResolveFile("models", "org/repo", sha, "dir/model.bin")
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := CreateSymlinkIfNotExists(blob, link); err != nil {
+			t.Fatal(err)
+		}
+		links = append(links, link)
+	}
+	defer ResetBlobRefs("models", "org/repo")
+	if n := BlobRefCount(blob); n != 2 {
+		t.Fatalf("ref count got %d, want 2", n)
+	}
+
+	// 索引建立后新增的链接计入引用数
+	link := ResolveFile("models", "org/repo", "c", "model.bin")
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateSymlinkIfNotExists(blob, link); err != nil {
+		t.Fatal(err)
+	}
+	links = append(links, link)
+	for i, link := range links {
+		got, remaining, err := RemoveBlobRef(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != blob || remaining != len(links)-i-1 {
+			t.Fatalf("RemoveBlobRef(%s) = %s, %d", link, got, remaining)
+		}
+	}
+}
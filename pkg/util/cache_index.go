@@ -0,0 +1,175 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"hash/maphash"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// 缓存文件索引：以布隆过滤器记录缓存目录下的所有文件，过滤器判定不存在的路径一定不存在，
+// 命中检查无需访问文件系统；判定可能存在时仍以文件系统为准。过滤器不支持删除，定期重建以淘汰已删除的文件。
+// 索引在启动时扫描缓存目录建立，建立完成前所有检查均回退到文件系统。
+
+var cacheIndex struct {
+	mu       sync.RWMutex
+	root     string
+	current  *bloomFilter
+	building *bloomFilter // 重建期间新写入的文件同时记录到新过滤器，避免切换后丢失
+	skipped  atomic.Int64 // 由过滤器直接判定为不存在的次数
+}
+
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+	seed maphash.Seed
+}
+
+// newBloomFilter 按预期元素数量及误判率计算位数组大小与哈希函数个数。
+func newBloomFilter(n int, p float64) *bloomFilter {
+	n = max(n, 1)
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max((m+63)/64*64, 64)
+	k := uint64(max(math.Round(float64(m)/float64(n)*math.Ln2), 1))
+	return &bloomFilter{bits: make([]uint64, m/64), m: m, k: k, seed: maphash.MakeSeed()}
+}
+
+// 双重哈希：第i个位置为h1+i*h2
+func (b *bloomFilter) hashes(key string) (uint64, uint64) {
+	h := maphash.String(b.seed, key)
+	return h, (h>>33 | h<<31) | 1
+}
+
+func (b *bloomFilter) add(key string) {
+	h1, h2 := b.hashes(key)
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *bloomFilter) mayContain(key string) bool {
+	h1, h2 := b.hashes(key)
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildCacheIndex 扫描缓存目录重建索引，扫描完成后替换当前索引。
+func BuildCacheIndex(root string) error {
+	conf := config.SysConfig.Cache.Index
+	filter := newBloomFilter(conf.ExpectedItems, conf.FalsePositiveRate)
+	cacheIndex.mu.Lock()
+	cacheIndex.root = filepath.Clean(root)
+	cacheIndex.building = filter
+	cacheIndex.mu.Unlock()
+
+	start := time.Now()
+	var count int
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			cacheIndex.mu.Lock()
+			filter.add(filepath.Clean(path))
+			cacheIndex.mu.Unlock()
+			count++
+		}
+		return nil
+	})
+
+	cacheIndex.mu.Lock()
+	defer cacheIndex.mu.Unlock()
+	cacheIndex.building = nil
+	if err != nil {
+		return err
+	}
+	cacheIndex.current = filter
+	if count > conf.ExpectedItems {
+		zap.S().Warnf("cache index holds %d files, more than expectedItems %d, false positive rate will increase", count, conf.ExpectedItems)
+	}
+	zap.S().Infof("cache index is built with %d files in %s", count, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// AddCacheIndex 记录新写入的缓存文件。
+func AddCacheIndex(path string) {
+	cacheIndex.mu.Lock()
+	defer cacheIndex.mu.Unlock()
+	if cacheIndex.current == nil && cacheIndex.building == nil {
+		return
+	}
+	path = filepath.Clean(path)
+	if cacheIndex.current != nil {
+		cacheIndex.current.add(path)
+	}
+	if cacheIndex.building != nil {
+		cacheIndex.building.add(path)
+	}
+}
+
+// AddCacheIndexDir 目录被整体移入缓存目录后，记录其中的所有文件。
+func AddCacheIndexDir(dir string) {
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			AddCacheIndex(path)
+		}
+		return nil
+	})
+}
+
+// CacheFileExists 判断缓存文件是否存在，索引判定不存在时不访问文件系统，仅用于文件，不可用于目录。
+func CacheFileExists(path string) bool {
+	cacheIndex.mu.RLock()
+	filter, root := cacheIndex.current, cacheIndex.root
+	if filter != nil {
+		clean := filepath.Clean(path)
+		if strings.HasPrefix(clean, root+string(filepath.Separator)) && !filter.mayContain(clean) {
+			cacheIndex.mu.RUnlock()
+			cacheIndex.skipped.Add(1)
+			return false
+		}
+	}
+	cacheIndex.mu.RUnlock()
+	return FileExists(path)
+}
+
+// CacheIndexSkipped 返回由索引直接判定为不存在、未访问文件系统的次数。
+func CacheIndexSkipped() int64 {
+	return cacheIndex.skipped.Load()
+}
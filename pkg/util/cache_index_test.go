@@ -0,0 +1,79 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestBloomFilter(t *testing.T) {
+	b := newBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		b.add(fmt.Sprintf("/repos/files/%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !b.mayContain(fmt.Sprintf("/repos/files/%d", i)) {
+			t.Fatalf("false negative for %d", i)
+		}
+	}
+	var fp int
+	for i := 1000; i < 11000; i++ {
+		if b.mayContain(fmt.Sprintf("/repos/files/%d", i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / 10000; rate > 0.03 {
+		t.Errorf("false positive rate %.4f is too high", rate)
+	}
+}
+
+func TestCacheFileExists(t *testing.T) {
+	old := config.SysConfig
+	defer func() {
+		config.SysConfig = old
+		cacheIndex.current, cacheIndex.root = nil, ""
+	}()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Cache.Index = config.CacheIndex{ExpectedItems: 100, FalsePositiveRate: 0.01}
+
+	root := t.TempDir()
+	existing := filepath.Join(root, "api", "a.json")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildCacheIndex(root); err != nil {
+		t.Fatal(err)
+	}
+	if !CacheFileExists(existing) {
+		t.Errorf("%s should exist", existing)
+	}
+	skipped := CacheIndexSkipped()
+	if CacheFileExists(filepath.Join(root, "api", "missing.json")) {
+		t.Errorf("missing file should not exist")
+	}
+	if CacheIndexSkipped() != skipped+1 {
+		t.Errorf("miss should be answered by the index")
+	}
+
+	// 经WriteDataToFile写入的文件立即记录到索引
+	written := filepath.Join(root, "api", "b.json")
+	if err := WriteDataToFile(written, map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if !CacheFileExists(written) {
+		t.Errorf("%s should exist after write", written)
+	}
+	outside := filepath.Join(t.TempDir(), "c.json")
+	if err := os.WriteFile(outside, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !CacheFileExists(outside) {
+		t.Errorf("files outside the cache root should fall back to the filesystem")
+	}
+}
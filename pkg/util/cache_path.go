@@ -0,0 +1,157 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"dingospeed/pkg/config"
+)
+
+// 缓存目录的路径统一在此拼接，使用filepath适配不同平台的分隔符，
+// 仓库名、文件名等来自请求的部分按"/"拆分后逐段转义，Windows下不允许出现的字符和设备名以%XX形式保存。
+
+var isWindows = runtime.GOOS == "windows"
+
+// Windows保留的设备名，带扩展名时同样不可用
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// CachePath 将以"/"分隔的各段拼接为本地路径，各段按平台规则转义。
+func CachePath(elem ...string) string {
+	segments := make([]string, 0, len(elem))
+	for _, e := range elem {
+		for _, segment := range strings.Split(e, "/") {
+			if segment != "" {
+				segments = append(segments, sanitizeSegment(segment, isWindows))
+			}
+		}
+	}
+	return filepath.Join(segments...)
+}
+
+// CacheName 将缓存目录中的相对路径还原为仓库内以"/"分隔的文件名，与CachePath互逆。
+func CacheName(rel string) string {
+	rel = filepath.ToSlash(rel)
+	if !isWindows {
+		return rel
+	}
+	segments := strings.Split(rel, "/")
+	for i, segment := range segments {
+		if name, err := url.PathUnescape(segment); err == nil {
+			segments[i] = name
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func sanitizeSegment(segment string, windows bool) string {
+	if !windows {
+		return segment
+	}
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		ch := segment[i]
+		if ch < 0x20 || strings.IndexByte(`<>:"\|?*%`, ch) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		} else {
+			b.WriteByte(ch)
+		}
+	}
+	escaped := b.String()
+	base := strings.ToUpper(strings.SplitN(escaped, ".", 2)[0])
+	if windowsReservedNames[base] {
+		escaped = fmt.Sprintf("%%%02X", escaped[0]) + escaped[1:]
+	}
+	// Windows会去掉结尾的点和空格
+	if last := escaped[len(escaped)-1]; last == '.' || last == ' ' {
+		escaped = escaped[:len(escaped)-1] + fmt.Sprintf("%%%02X", last)
+	}
+	return escaped
+}
+
+// RepoFilesDir {repos}/files/{repoType}/{orgRepo}
+func RepoFilesDir(repoType, orgRepo string) string {
+	return filepath.Join(config.SysConfig.Repos(), "files", CachePath(repoType, orgRepo))
+}
+
+// BlobsDir {repos}/files/{repoType}/{orgRepo}/blobs
+func BlobsDir(repoType, orgRepo string) string {
+	return filepath.Join(RepoFilesDir(repoType, orgRepo), "blobs")
+}
+
+func BlobsFile(repoType, orgRepo, etag string) string {
+	return filepath.Join(BlobsDir(repoType, orgRepo), CachePath(etag))
+}
+
+// ResolveDir {repos}/files/{repoType}/{orgRepo}/resolve/{commit}，其中的文件为指向blobs的软链接
+func ResolveDir(repoType, orgRepo, commit string) string {
+	return filepath.Join(RepoFilesDir(repoType, orgRepo), "resolve", CachePath(commit))
+}
+
+func ResolveFile(repoType, orgRepo, commit, fileName string) string {
+	return filepath.Join(ResolveDir(repoType, orgRepo, commit), CachePath(fileName))
+}
+
+// ApiRepoDir {apiRoot}/{repoType}/{orgRepo}
+func ApiRepoDir(apiRoot, repoType, orgRepo string) string {
+	return filepath.Join(apiRoot, CachePath(repoType, orgRepo))
+}
+
+func ApiRefsFile(apiRoot, repoType, orgRepo string) string {
+	return filepath.Join(ApiRepoDir(apiRoot, repoType, orgRepo), "refs", "refs_get.json")
+}
+
+func ApiMetaFile(apiRoot, repoType, orgRepo, commit, method string) string {
+	return filepath.Join(ApiRepoDir(apiRoot, repoType, orgRepo), "revision", CachePath(commit), fmt.Sprintf("meta_%s.json", method))
+}
+
+// ApiMetaQueryFile 带查询参数的仓库信息缓存文件，不同参数组合分别缓存，query为空时与ApiMetaFile相同。
+func ApiMetaQueryFile(apiRoot, repoType, orgRepo, commit, method, query string) string {
+	if query == "" {
+		return ApiMetaFile(apiRoot, repoType, orgRepo, commit, method)
+	}
+	sum := sha256.Sum256([]byte(query))
+	return ApiMetaFile(apiRoot, repoType, orgRepo, commit, fmt.Sprintf("%s_%s", method, hex.EncodeToString(sum[:8])))
+}
+
+// ApiDatasetsServerFile datasets-server接口的缓存文件，不同查询参数分别缓存。
+func ApiDatasetsServerFile(apiRoot, dataset, endpoint, query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return filepath.Join(ApiRepoDir(apiRoot, "datasets", dataset), "datasets-server", CachePath(endpoint), fmt.Sprintf("%s.json", hex.EncodeToString(sum[:8])))
+}
+
+// ApiForwardFile 原样转发接口的缓存文件，以请求路径及查询参数的哈希命名。
+func ApiForwardFile(apiRoot, urlPath, query string) string {
+	sum := sha256.Sum256([]byte(urlPath + "?" + query))
+	return filepath.Join(apiRoot, "forward", fmt.Sprintf("%s.json", hex.EncodeToString(sum[:8])))
+}
+
+func ApiPathsInfoDir(apiRoot, repoType, orgRepo, commit string) string {
+	return filepath.Join(ApiRepoDir(apiRoot, repoType, orgRepo), "paths-info", CachePath(commit))
+}
+
+func ApiPathsInfoFile(apiRoot, repoType, orgRepo, commit, fileName string) string {
+	return filepath.Join(ApiPathsInfoDir(apiRoot, repoType, orgRepo, commit), CachePath(fileName), "paths-info_post.json")
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestSanitizeSegment(t *testing.T) {
+	cases := []struct {
+		segment, linux, windows string
+	}{
+		{"model.safetensors", "model.safetensors", "model.safetensors"},
+		{"a:b?.txt", "a:b?.txt", "a%3Ab%3F.txt"},
+		{"100%", "100%", "100%25"},
+		{"con", "con", "%63on"},
+		{"NUL.json", "NUL.json", "%4EUL.json"},
+		{"console", "console", "console"},
+		{"name.", "name.", "name%2E"},
+	}
+	for _, c := range cases {
+		if got := sanitizeSegment(c.segment, false); got != c.linux {
+			t.Errorf("sanitizeSegment(%q, linux) = %q, want %q", c.segment, got, c.linux)
+		}
+		if got := sanitizeSegment(c.segment, true); got != c.windows {
+			t.Errorf("sanitizeSegment(%q, windows) = %q, want %q", c.segment, got, c.windows)
+		}
+	}
+}
+
+func TestCacheName(t *testing.T) {
+	old := isWindows
+	defer func() { isWindows = old }()
+	isWindows = true
+	for _, name := range []string{"sub/a:b?.txt", "aux/con.json", "dir./100%"} {
+		if got := CacheName(CachePath(name)); got != name {
+			t.Errorf("CacheName(CachePath(%q)) = %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+)
+
+// X-Cache的取值：HIT内容及版本均来自缓存，REVALIDATED内容来自缓存但版本已向远端确认，MISS内容来自远端。
+const (
+	CacheHit         = "HIT"
+	CacheMiss        = "MISS"
+	CacheRevalidated = "REVALIDATED"
+)
+
+type cacheTraceKey struct{}
+
+// CacheTrace 记录一次请求的缓存命中情况，用于X-Cache响应头及debug=1时的诊断信息，方法对nil安全。
+type CacheTrace struct {
+	mu          sync.Mutex
+	served      bool // 已记录内容的来源
+	hit         bool
+	revalidated bool
+	path        string
+	cachedAt    time.Time
+	upstream    string
+}
+
+func WithCacheTrace(ctx context.Context) (context.Context, *CacheTrace) {
+	t := &CacheTrace{}
+	return context.WithValue(ctx, cacheTraceKey{}, t), t
+}
+
+func CacheTraceFrom(ctx context.Context) *CacheTrace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(cacheTraceKey{}).(*CacheTrace)
+	return t
+}
+
+// Hit 内容来自缓存文件cachePath。
+func (t *CacheTrace) Hit(cachePath string, cachedAt time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.served, t.hit, t.path, t.cachedAt = true, true, cachePath, cachedAt
+}
+
+// Miss 内容需从远端upstream拉取并写入cachePath。
+func (t *CacheTrace) Miss(cachePath, upstream string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.served, t.hit, t.path, t.upstream = true, false, cachePath, upstream
+}
+
+// Revalidate 版本已向远端upstream确认。
+func (t *CacheTrace) Revalidate(upstream string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.revalidated = true
+	if t.upstream == "" {
+		t.upstream = upstream
+	}
+}
+
+// Status 返回X-Cache的取值，未记录内容来源时返回空字符串。
+func (t *CacheTrace) Status() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case !t.served:
+		return ""
+	case !t.hit:
+		return CacheMiss
+	case t.revalidated:
+		return CacheRevalidated
+	default:
+		return CacheHit
+	}
+}
+
+// Diagnostics 返回debug=1时附加的响应头：缓存相对于repos的路径、缓存时长（秒）及使用的远端。
+func (t *CacheTrace) Diagnostics() map[string]string {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	diag := make(map[string]string)
+	if t.path != "" {
+		p := t.path
+		if rel, err := filepath.Rel(config.SysConfig.Repos(), p); err == nil {
+			p = filepath.ToSlash(rel)
+		}
+		diag[consts.HeaderCachePath] = p
+	}
+	if !t.cachedAt.IsZero() {
+		diag[consts.HeaderCacheAge] = strconv.FormatInt(int64(time.Since(t.cachedAt).Seconds()), 10)
+	}
+	if t.upstream != "" {
+		diag[consts.HeaderUpstream] = t.upstream
+	}
+	return diag
+}
+
+// UpstreamFor 返回请求路径uri将发往的远端地址。
+func UpstreamFor(uri string) string {
+	domain, _ := upstreamDomain(uri)
+	return domain
+}
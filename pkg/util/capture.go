@@ -0,0 +1,325 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 抓包模式：管理员为指定仓库开启后，在有效期内将发往远端的请求及响应完整记录到repos/captures/{id}，
+// 认证信息脱敏，响应体按上限保存并记录完整内容的sha256，用于排查镜像与远端返回内容不一致的问题。
+
+const redacted = "******"
+
+var (
+	capturesMu sync.RWMutex
+	captures   = make(map[string]*CaptureSession)
+)
+
+// 需要脱敏的请求头及响应头
+var captureRedactHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+type CaptureSession struct {
+	Id          string    `json:"id"`
+	RepoType    string    `json:"repoType"`
+	OrgRepo     string    `json:"orgRepo"`
+	Dir         string    `json:"dir"`
+	StartTime   time.Time `json:"startTime"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	MaxBodySize int64     `json:"maxBodySize"` // 每个响应体保存的字节数上限，超过部分只计算sha256
+	Captured    int64     `json:"captured"`
+	seq         atomic.Int64
+	timer       *time.Timer
+}
+
+// CaptureRecord 一次远端请求及响应的记录，响应体保存在同名的.body文件中
+type CaptureRecord struct {
+	Time            time.Time   `json:"time"`
+	DurationMs      int64       `json:"durationMs"`
+	Method          string      `json:"method"`
+	Url             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders"`
+	Status          int         `json:"status,omitempty"`
+	FinalUrl        string      `json:"finalUrl,omitempty"` // 跟随重定向后的地址
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	BodySize        int64       `json:"bodySize"`
+	BodySha256      string      `json:"bodySha256,omitempty"` // 已读取内容的sha256，bodyComplete为false时不是完整响应体的摘要
+	BodyComplete    bool        `json:"bodyComplete"`         // 是否读取到了响应体末尾
+	BodyFile        string      `json:"bodyFile,omitempty"`
+	Truncated       bool        `json:"truncated"` // 响应体超过上限，只保存了前maxBodySize字节
+	Error           string      `json:"error,omitempty"`
+}
+
+// StartCapture 为仓库开启抓包，duration到期后自动停止，已记录的文件保留在磁盘上。
+func StartCapture(repoType, orgRepo string, duration time.Duration, maxBodySize int64) (*CaptureSession, error) {
+	id := time.Now().Format("20060102150405") + "-" + UUID()[:8]
+	dir := filepath.Join(config.SysConfig.Repos(), consts.CaptureDir, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &CaptureSession{
+		Id:          id,
+		RepoType:    repoType,
+		OrgRepo:     orgRepo,
+		Dir:         dir,
+		StartTime:   now,
+		ExpiresAt:   now.Add(duration),
+		MaxBodySize: maxBodySize,
+	}
+	s.timer = time.AfterFunc(duration, func() { StopCapture(id) })
+	capturesMu.Lock()
+	captures[id] = s
+	capturesMu.Unlock()
+	zap.S().Warnf("capture %s started for %s/%s, expires at %s, records in %s", id, repoType, orgRepo, s.ExpiresAt.Format(time.RFC3339), dir)
+	return s.info(), nil
+}
+
+// StopCapture 停止抓包，不存在时返回false。
+func StopCapture(id string) (*CaptureSession, bool) {
+	capturesMu.Lock()
+	s, ok := captures[id]
+	delete(captures, id)
+	capturesMu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	s.timer.Stop()
+	zap.S().Infof("capture %s stopped, %d requests captured", id, s.seq.Load())
+	return s.info(), true
+}
+
+func ListCaptures() []*CaptureSession {
+	capturesMu.RLock()
+	list := make([]*CaptureSession, 0, len(captures))
+	for _, s := range captures {
+		list = append(list, s.info())
+	}
+	capturesMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartTime.Before(list[j].StartTime)
+	})
+	return list
+}
+
+func (s *CaptureSession) info() *CaptureSession {
+	return &CaptureSession{
+		Id:          s.Id,
+		RepoType:    s.RepoType,
+		OrgRepo:     s.OrgRepo,
+		Dir:         s.Dir,
+		StartTime:   s.StartTime,
+		ExpiresAt:   s.ExpiresAt,
+		MaxBodySize: s.MaxBodySize,
+		Captured:    s.seq.Load(),
+	}
+}
+
+func matchCapture(targetURL string) *CaptureSession {
+	capturesMu.RLock()
+	defer capturesMu.RUnlock()
+	if len(captures) == 0 {
+		return nil
+	}
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil
+	}
+	repoType, orgRepo := parseCaptureRepo(u.Path)
+	if orgRepo == "" {
+		return nil
+	}
+	now := time.Now()
+	for _, s := range captures {
+		if s.RepoType == repoType && s.OrgRepo == orgRepo && now.Before(s.ExpiresAt) {
+			return s
+		}
+	}
+	return nil
+}
+
+// parseCaptureRepo 从远端请求路径中解析仓库类型及org/repo，
+// 支持/api/{type}/{org}/{repo}/...、/{type}/{org}/{repo}/...及省略models类型的/{org}/{repo}/resolve/...
+func parseCaptureRepo(uri string) (string, string) {
+	segments := strings.Split(strings.Trim(uri, "/"), "/")
+	if len(segments) > 0 && segments[0] == "api" {
+		segments = segments[1:]
+	}
+	if len(segments) >= 3 {
+		if _, ok := consts.RepoTypesMapping[segments[0]]; ok {
+			return segments[0], segments[1] + "/" + segments[2]
+		}
+		if segments[2] == "resolve" {
+			return "models", segments[0] + "/" + segments[1]
+		}
+	}
+	return "", ""
+}
+
+// captureUpstream 命中抓包时记录请求及响应，响应体在调用方读取的同时写入抓包文件，关闭时写出记录。
+func captureUpstream(req *http.Request, resp *http.Response, err error, start time.Time) {
+	s := matchCapture(req.URL.String())
+	if s == nil {
+		return
+	}
+	seq := s.seq.Add(1)
+	record := &CaptureRecord{
+		Time:           start,
+		Method:         req.Method,
+		Url:            redactURL(req.URL),
+		RequestHeaders: redactHeader(req.Header),
+	}
+	name := fmt.Sprintf("%06d", seq)
+	if err != nil || resp == nil {
+		record.DurationMs = time.Since(start).Milliseconds()
+		if err != nil {
+			record.Error = err.Error()
+		}
+		s.writeRecord(name, record)
+		return
+	}
+	record.Status = resp.StatusCode
+	if resp.Request != nil && resp.Request.URL.String() != req.URL.String() {
+		record.FinalUrl = redactURL(resp.Request.URL)
+	}
+	record.ResponseHeaders = redactHeader(resp.Header)
+	body := &captureBody{ReadCloser: resp.Body, session: s, name: name, record: record, start: start, hash: sha256.New()}
+	if s.MaxBodySize > 0 {
+		if f, err := os.Create(filepath.Join(s.Dir, name+".body")); err == nil {
+			body.file = f
+			record.BodyFile = name + ".body"
+		} else {
+			zap.S().Warnf("create capture body file err.%v", err)
+		}
+	}
+	resp.Body = body
+}
+
+func (s *CaptureSession) writeRecord(name string, record *CaptureRecord) {
+	b, err := sonic.ConfigStd.MarshalIndent(record, "", "  ")
+	if err != nil {
+		zap.S().Warnf("marshal capture record err.%v", err)
+		return
+	}
+	if err = os.WriteFile(filepath.Join(s.Dir, name+".json"), b, 0o644); err != nil {
+		zap.S().Warnf("write capture record err.%v", err)
+	}
+}
+
+type captureBody struct {
+	io.ReadCloser
+	session *CaptureSession
+	name    string
+	record  *CaptureRecord
+	start   time.Time
+	hash    hash.Hash
+	file    *os.File
+	saved   int64
+	once    sync.Once
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.hash.Write(p[:n])
+		b.record.BodySize += int64(n)
+		if b.file != nil && b.saved < b.session.MaxBodySize {
+			chunk := p[:min(int64(n), b.session.MaxBodySize-b.saved)]
+			if _, werr := b.file.Write(chunk); werr == nil {
+				b.saved += int64(len(chunk))
+			}
+		}
+	}
+	if err == io.EOF {
+		b.record.BodyComplete = true
+		b.finish(nil)
+	} else if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *captureBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *captureBody) finish(err error) {
+	b.once.Do(func() {
+		if b.file != nil {
+			b.file.Close()
+		}
+		b.record.DurationMs = time.Since(b.start).Milliseconds()
+		b.record.BodySha256 = hex.EncodeToString(b.hash.Sum(nil))
+		b.record.Truncated = b.record.BodySize > b.saved && b.file != nil
+		if err != nil {
+			b.record.Error = err.Error()
+		}
+		b.session.writeRecord(b.name, b.record)
+	})
+}
+
+func redactHeader(header http.Header) http.Header {
+	h := header.Clone()
+	for name := range h {
+		if captureRedactHeaders[http.CanonicalHeaderKey(name)] {
+			h[name] = []string{redacted}
+		}
+	}
+	return h
+}
+
+// redactURL 隐藏签名地址中的签名及凭证参数
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if len(q) == 0 {
+		return u.String()
+	}
+	for key := range q {
+		lower := strings.ToLower(key)
+		if strings.Contains(lower, "signature") || strings.Contains(lower, "credential") ||
+			strings.Contains(lower, "token") || strings.Contains(lower, "key-pair") || lower == "policy" {
+			q.Set(key, redacted)
+		}
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
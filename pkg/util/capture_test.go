@@ -0,0 +1,85 @@
+package util
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+)
+
+func TestParseCaptureRepo(t *testing.T) {
+	cases := []struct {
+		uri, repoType, orgRepo string
+	}{
+		{"/api/models/org/repo/revision/main", "models", "org/repo"},
+		{"/datasets/org/repo/resolve/main/a.json", "datasets", "org/repo"},
+		{"/org/repo/resolve/main/config.json", "models", "org/repo"},
+		{"/api/whoami-v2", "", ""},
+	}
+	for _, c := range cases {
+		if repoType, orgRepo := parseCaptureRepo(c.uri); repoType != c.repoType || orgRepo != c.orgRepo {
+			t.Errorf("parseCaptureRepo(%s) = %s %s, want %s %s", c.uri, repoType, orgRepo, c.repoType, c.orgRepo)
+		}
+	}
+}
+
+func TestCaptureUpstream(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Repos = t.TempDir()
+
+	s, err := StartCapture("models", "org/repo", time.Minute, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer StopCapture(s.Id)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://hf.co/org/repo/resolve/main/a.txt", nil)
+	req.Header.Set("Authorization", "Bearer hf_secret")
+	final, _ := url.Parse("https://cdn.example.com/a.txt?X-Amz-Signature=abc&x=1")
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {"\"e\""}},
+		Body:       io.NopCloser(strings.NewReader("hello world")),
+		Request:    &http.Request{URL: final},
+	}
+	captureUpstream(req, resp, nil, time.Now())
+	if _, err = io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	b, err := os.ReadFile(filepath.Join(s.Dir, "000001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "hf_secret") || strings.Contains(string(b), "abc") {
+		t.Errorf("record not redacted: %s", b)
+	}
+	record := new(CaptureRecord)
+	if err = sonic.Unmarshal(b, record); err != nil {
+		t.Fatal(err)
+	}
+	if record.BodySize != 11 || !record.BodyComplete || !record.Truncated || record.Status != http.StatusOK {
+		t.Errorf("record = %+v", record)
+	}
+	if body, _ := os.ReadFile(filepath.Join(s.Dir, record.BodyFile)); string(body) != "hell" {
+		t.Errorf("saved body = %q, want %q", body, "hell")
+	}
+
+	// 其他仓库的请求不记录
+	other, _ := http.NewRequest(http.MethodGet, "https://hf.co/api/models/org/other", nil)
+	captureUpstream(other, &http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil, time.Now())
+	if list := ListCaptures(); len(list) != 1 || list[0].Captured != 1 {
+		t.Errorf("captures = %+v", list)
+	}
+}
@@ -0,0 +1,66 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IsCdnCacheHost 判断地址的域名是否属于允许经由本镜像缓存的CDN。
+func IsCdnCacheHost(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	for _, pattern := range config.SysConfig.GetCdnCacheHosts() {
+		if ok, _ := path.Match(pattern, u.Hostname()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CdnCacheLocation 开启cdnCache且Location指向允许的CDN时，改写为本镜像按仓库、版本及文件路径访问的地址，
+// 签名地址不再下发给客户端，同一文件无论签名如何变化都命中同一份缓存。
+func CdnCacheLocation(c echo.Context, repoType, orgRepo, commit, fileName, location string) string {
+	if !config.SysConfig.CdnCache.Enabled || !IsCdnCacheHost(location) {
+		return location
+	}
+	return fmt.Sprintf("%s%s/cdn/%s/%s/%s/%s", RequestBaseURL(c), PathPrefix(c), repoType, orgRepo, commit, url.PathEscape(fileName))
+}
+
+// checkBlobRedirect 开启cdnCache时，文件下载只跟随指向远端或允许的CDN的重定向；仓库改名的重定向记录为别名。
+func checkBlobRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	detectRepoRename(req, via)
+	if !config.SysConfig.CdnCache.Enabled {
+		return nil
+	}
+	target := req.URL.String()
+	if isUpstreamURL(target) || IsCdnCacheHost(target) {
+		return nil
+	}
+	return fmt.Errorf("redirect to %s is not allowed by cdnCache.hosts", req.URL.Host)
+}
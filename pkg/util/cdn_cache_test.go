@@ -0,0 +1,44 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCdnCacheLocation(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	cdnCache := config.SysConfig.CdnCache
+	defer func() { config.SysConfig.CdnCache = cdnCache }()
+
+	req := httptest.NewRequest(http.MethodHead, "http://mirror.local:8090/models/org/repo/resolve/main/dir/a.bin", nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	signed := "https://cdn-lfs-us-1.hf.co/repos/ab/cd/0123?X-Amz-Signature=xyz"
+
+	config.SysConfig.CdnCache = config.CdnCache{}
+	if got := CdnCacheLocation(c, "models", "org/repo", "abc", "dir/a.bin", signed); got != signed {
+		t.Errorf("disabled: got %s", got)
+	}
+
+	config.SysConfig.CdnCache = config.CdnCache{Enabled: true}
+	want := "http://mirror.local:8090/cdn/models/org/repo/abc/dir%2Fa.bin"
+	if got := CdnCacheLocation(c, "models", "org/repo", "abc", "dir/a.bin", signed); got != want {
+		t.Errorf("enabled: got %s, want %s", got, want)
+	}
+	other := "https://example.com/file?sig=1"
+	if got := CdnCacheLocation(c, "models", "org/repo", "abc", "dir/a.bin", other); got != other {
+		t.Errorf("other host: got %s", got)
+	}
+
+	config.SysConfig.CdnCache = config.CdnCache{Enabled: true, Hosts: []string{"*.example.com"}}
+	if !IsCdnCacheHost("https://cdn.example.com/a") || IsCdnCacheHost(signed) {
+		t.Errorf("custom hosts not applied")
+	}
+}
@@ -0,0 +1,164 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+)
+
+// 截断响应时Content-Length未知所写入的字节数
+const defaultChaosTruncateBytes = 64 * 1024
+
+// ChaosMatch 判断请求路径是否需要注入故障，未配置路径前缀时管理接口之外的请求均注入。
+func ChaosMatch(path string) bool {
+	conf := &config.SysConfig.Chaos
+	if !conf.Enabled {
+		return false
+	}
+	if len(conf.Paths) == 0 {
+		return !strings.HasPrefix(path, "/admin/")
+	}
+	for _, prefix := range conf.Paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChaosHit 按百分比随机判断是否注入。
+func ChaosHit(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+// ChaosDelay 等待latency加上0-jitter的随机延迟，单位毫秒，ctx结束时提前返回。
+func ChaosDelay(ctx context.Context, latency, jitter int) error {
+	delay := time.Duration(latency) * time.Millisecond
+	if jitter > 0 {
+		delay += time.Duration(rand.Intn(jitter+1)) * time.Millisecond
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ChaosWriter 写入指定字节数后丢弃之后的响应体并返回错误，limit不大于0时为Content-Length的一半。
+type ChaosWriter struct {
+	http.ResponseWriter
+	limit     int64
+	written   int64
+	truncated bool
+}
+
+func NewChaosWriter(w http.ResponseWriter, limit int64) *ChaosWriter {
+	return &ChaosWriter{ResponseWriter: w, limit: limit}
+}
+
+func (w *ChaosWriter) WriteHeader(code int) {
+	if w.limit <= 0 {
+		w.limit = defaultChaosTruncateBytes
+		if size, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64); err == nil && size > 1 {
+			w.limit = size / 2
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ChaosWriter) Write(p []byte) (int, error) {
+	if w.limit <= 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	remaining := w.limit - w.written
+	if int64(len(p)) <= remaining {
+		n, err := w.ResponseWriter.Write(p)
+		w.written += int64(n)
+		return n, err
+	}
+	n, _ := w.ResponseWriter.Write(p[:remaining])
+	w.written += int64(n)
+	w.truncated = true
+	w.Flush() // 已写入的部分在中断连接前发送给客户端
+	return n, io.ErrShortWrite
+}
+
+// Truncated 返回响应体是否已被截断，截断后需中断连接，使客户端收到不完整的响应。
+func (w *ChaosWriter) Truncated() bool {
+	return w.truncated
+}
+
+func (w *ChaosWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *ChaosWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// chaosTransport 按配置对远端请求注入延迟及失败，未启用时直接转发。
+type chaosTransport struct {
+	next http.RoundTripper
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	conf := &config.SysConfig.Chaos
+	if !conf.Enabled {
+		return t.next.RoundTrip(req)
+	}
+	if err := ChaosDelay(req.Context(), conf.UpstreamLatency, 0); err != nil {
+		return nil, err
+	}
+	if !ChaosHit(conf.UpstreamErrorPercent) {
+		return t.next.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if conf.UpstreamErrorStatus <= 0 {
+		return nil, fmt.Errorf("chaos: injected upstream failure, %s %s", req.Method, req.URL.Host)
+	}
+	header := http.Header{}
+	header.Set(consts.HeaderChaos, "upstream")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", conf.UpstreamErrorStatus, http.StatusText(conf.UpstreamErrorStatus)),
+		StatusCode:    conf.UpstreamErrorStatus,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          http.NoBody,
+		ContentLength: 0,
+		Request:       req,
+	}, nil
+}
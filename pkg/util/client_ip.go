@@ -0,0 +1,65 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ClientIPExtractor 返回客户端IP提取函数，只有直连地址属于可信代理时才解析转发头。
+// X-Forwarded-For从右向左跳过可信代理，取第一个不可信地址；没有该头时使用X-Real-IP。
+func ClientIPExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	trusted := func(ip net.IP) bool {
+		for _, ipNet := range trustedProxies {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(req *http.Request) string {
+		direct, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			direct = req.RemoteAddr
+		}
+		ip := net.ParseIP(direct)
+		if ip == nil || !trusted(ip) {
+			return direct
+		}
+		if xff := req.Header.Values(echo.HeaderXForwardedFor); len(xff) > 0 {
+			ips := strings.Split(strings.Join(xff, ","), ",")
+			client := direct
+			for i := len(ips) - 1; i >= 0; i-- {
+				forwarded := net.ParseIP(strings.TrimSpace(ips[i]))
+				if forwarded == nil {
+					break
+				}
+				client = forwarded.String()
+				if !trusted(forwarded) {
+					break
+				}
+			}
+			return client
+		}
+		if realIP := net.ParseIP(strings.TrimSpace(req.Header.Get(echo.HeaderXRealIP))); realIP != nil {
+			return realIP.String()
+		}
+		return direct
+	}
+}
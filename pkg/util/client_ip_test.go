@@ -0,0 +1,41 @@
+package util
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestClientIPExtractor(t *testing.T) {
+	_, lb, _ := net.ParseCIDR("10.0.0.0/8")
+	extract := ClientIPExtractor([]*net.IPNet{lb})
+	cases := []struct {
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{"1.2.3.4:1000", "5.6.7.8", "", "1.2.3.4"},
+		{"10.0.0.1:1000", "", "", "10.0.0.1"},
+		{"10.0.0.1:1000", "5.6.7.8", "", "5.6.7.8"},
+		{"10.0.0.1:1000", "9.9.9.9, 5.6.7.8, 10.0.0.2", "", "5.6.7.8"},
+		{"10.0.0.1:1000", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"10.0.0.1:1000", "", "5.6.7.8", "5.6.7.8"},
+		{"10.0.0.1:1000", "bad, 5.6.7.8", "", "5.6.7.8"},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.xff != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, tc.xff)
+		}
+		if tc.realIP != "" {
+			req.Header.Set(echo.HeaderXRealIP, tc.realIP)
+		}
+		if got := extract(req); got != tc.want {
+			t.Errorf("remote %s xff %q realIP %q: got %s, want %s", tc.remote, tc.xff, tc.realIP, got, tc.want)
+		}
+	}
+}
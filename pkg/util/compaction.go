@@ -0,0 +1,127 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+)
+
+const compareBufferSize = 1 << 20
+
+// FileKey 返回文件所在设备及inode组成的标识，硬链接到同一文件的路径标识相同。
+func FileKey(info os.FileInfo) (string, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%d", uint64(stat.Dev), uint64(stat.Ino)), true
+}
+
+// SameContent 逐段比较两个文件的内容，每读取一段前调用wait，用于限制读取速度。
+func SameContent(a, b string, wait func(n int64) error) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+	bufA, bufB := make([]byte, compareBufferSize), make([]byte, compareBufferSize)
+	for {
+		if err = wait(2 * compareBufferSize); err != nil {
+			return false, err
+		}
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if na != nb || !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		endA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		endB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if endA || endB {
+			return endA && endB, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// RemoveEmptyDirs 自下而上删除root下（不含root）的空目录，修改时间晚于before的目录可能正在写入，不删除。
+// dryRun时只统计，不删除；子目录全部为空时其上级目录同样计为空目录。
+func RemoveEmptyDirs(root string, before time.Time, dryRun bool) (int, error) {
+	// 删除子目录会更新上级目录的修改时间，按遍历时的修改时间判断
+	dirs, modTimes := make([]string, 0), make(map[string]time.Time)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() && path != root {
+			if info, err := d.Info(); err == nil {
+				dirs = append(dirs, path)
+				modTimes[path] = info.ModTime()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// 路径较长的子目录排在前面，先于上级目录处理
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+	removed := make(map[string]struct{})
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		empty := true
+		for _, entry := range entries {
+			if _, ok := removed[filepath.Join(dir, entry.Name())]; !ok {
+				empty = false
+				break
+			}
+		}
+		if !empty || modTimes[dir].After(before) {
+			continue
+		}
+		if !dryRun {
+			if err = os.Remove(dir); err != nil {
+				continue
+			}
+		}
+		removed[dir] = struct{}{}
+	}
+	return len(removed), nil
+}
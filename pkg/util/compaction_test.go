@@ -0,0 +1,61 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSameContent(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	data := make([]byte, compareBufferSize+10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	os.WriteFile(a, data, 0644)
+	os.WriteFile(b, data, 0644)
+	os.WriteFile(c, data[:len(data)-1], 0644)
+	var waited int64
+	wait := func(n int64) error {
+		waited += n
+		return nil
+	}
+	if same, err := SameContent(a, b, wait); err != nil || !same {
+		t.Errorf("same files: %v %v", same, err)
+	}
+	if waited == 0 {
+		t.Error("wait not called")
+	}
+	if same, _ := SameContent(a, c, wait); same {
+		t.Error("files of different size reported same")
+	}
+}
+
+func TestRemoveEmptyDirs(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "empty", "nested"), 0755)
+	os.MkdirAll(filepath.Join(root, "used", "nested"), 0755)
+	os.WriteFile(filepath.Join(root, "used", "nested", "f"), []byte("x"), 0644)
+
+	if n, err := RemoveEmptyDirs(root, time.Now().Add(-time.Hour), false); err != nil || n != 0 {
+		t.Fatalf("recent dirs removed: %d %v", n, err)
+	}
+	n, err := RemoveEmptyDirs(root, time.Now().Add(time.Hour), true)
+	if err != nil || n != 2 {
+		t.Fatalf("dry run: %d %v", n, err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "empty", "nested")); err != nil {
+		t.Fatal("dry run removed dir")
+	}
+	if n, err = RemoveEmptyDirs(root, time.Now().Add(time.Hour), false); err != nil || n != 2 {
+		t.Fatalf("remove: %d %v", n, err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "empty")); !os.IsNotExist(err) {
+		t.Error("empty dir kept")
+	}
+	if _, err = os.Stat(filepath.Join(root, "used", "nested", "f")); err != nil {
+		t.Error("used dir removed")
+	}
+}
@@ -15,11 +15,13 @@
 package util
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"compress/zlib"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/andybalholm/brotli"
@@ -67,6 +69,63 @@ func DecompressData(rawData []byte, contentEncoding string) ([]byte, error) {
 	return finalData, nil
 }
 
+// DecompressReader 以流的方式解压，与DecompressData按相同的顺序处理各编码，解压后的内容不必全部放在内存中。
+func DecompressReader(r io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	dr := &decompressReader{Reader: r}
+	if contentEncoding == "" {
+		return dr, nil
+	}
+	for _, algo := range strings.Split(contentEncoding, ",") {
+		algo = strings.TrimSpace(strings.ToLower(algo))
+		switch algo {
+		case "gzip":
+			gzr, err := gzip.NewReader(dr.Reader)
+			if err != nil {
+				dr.Close()
+				return nil, fmt.Errorf("error decompressing gzip data: %w", err)
+			}
+			dr.Reader, dr.closers = gzr, append(dr.closers, gzr)
+		case "deflate":
+			zr, err := zlib.NewReader(dr.Reader)
+			if err != nil {
+				dr.Close()
+				return nil, fmt.Errorf("error decompressing deflate data: %w", err)
+			}
+			dr.Reader, dr.closers = zr, append(dr.closers, zr)
+		case "br":
+			dr.Reader = brotli.NewReader(dr.Reader)
+		case "zstd":
+			decoder, err := zstd.NewReader(dr.Reader)
+			if err != nil {
+				dr.Close()
+				return nil, fmt.Errorf("error decompressing Zstandard data: %w", err)
+			}
+			rc := decoder.IOReadCloser()
+			dr.Reader, dr.closers = rc, append(dr.closers, rc)
+		default:
+			dr.Close()
+			return nil, fmt.Errorf("unsupported compression algorithm: %s", algo)
+		}
+	}
+	return dr, nil
+}
+
+type decompressReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decompressReader) Close() error {
+	var err error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if cerr := d.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	d.closers = nil
+	return err
+}
+
 // decompressGzip 解压缩 gzip 数据
 func decompressGzip(data []byte) ([]byte, error) {
 	buf := bytes.NewBuffer(data)
@@ -109,3 +168,59 @@ func decompressZstd(data []byte) ([]byte, error) {
 	}
 	return decompressed, nil
 }
+
+var (
+	zstdMagic      = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// CompressZstd 使用zstd压缩数据，编码器可并发复用。
+func CompressZstd(data []byte) []byte {
+	return zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/4))
+}
+
+// IsZstd 根据帧头魔数判断数据是否为zstd压缩格式。
+func IsZstd(data []byte) bool {
+	return bytes.HasPrefix(data, zstdMagic)
+}
+
+// ReadCacheFile 读取缓存文件，zstd压缩存储的文件自动解压。
+func ReadCacheFile(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil || !IsZstd(data) {
+		return data, err
+	}
+	return zstdDecoder.DecodeAll(data, nil)
+}
+
+// OpenCacheFile 以流的方式读取缓存文件，zstd压缩存储的文件边读边解压。
+func OpenCacheFile(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(f)
+	if magic, _ := r.Peek(len(zstdMagic)); !IsZstd(magic) {
+		return &cacheFileReader{Reader: r, file: f}, nil
+	}
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &cacheFileReader{Reader: decoder, file: f, decoder: decoder}, nil
+}
+
+type cacheFileReader struct {
+	io.Reader
+	file    *os.File
+	decoder *zstd.Decoder
+}
+
+func (r *cacheFileReader) Close() error {
+	if r.decoder != nil {
+		r.decoder.Close()
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCacheFile(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(`{"version":1,"status_code":200,"content":"7b7d"}`)
+	plainPath := filepath.Join(dir, "plain.json")
+	zstdPath := filepath.Join(dir, "zstd.json")
+	if err := os.WriteFile(plainPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(zstdPath, CompressZstd(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{plainPath, zstdPath} {
+		data, err := ReadCacheFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(content) {
+			t.Errorf("%s: got %s", path, data)
+		}
+		r, err := OpenCacheFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(content) {
+			t.Errorf("%s: stream got %s", path, data)
+		}
+	}
+}
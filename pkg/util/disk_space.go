@@ -0,0 +1,93 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dingospeed/pkg/config"
+	myerr "dingospeed/pkg/error"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+// 磁盘空间不足时请求释放的字节数，由磁盘清理任务消费，未启用磁盘清理时丢弃。
+var diskSpaceRequests = make(chan int64, 1)
+
+// CheckDiskSpace 开始下载未缓存的文件前检查磁盘剩余空间，剩余空间减去保留值不足以容纳文件未缓存的部分时，
+// 触发磁盘清理并返回507，避免下载到中途因磁盘写满而失败。
+func CheckDiskSpace(blobsFile string, fileSize int64) error {
+	reserve := config.SysConfig.Download.DiskReserve
+	if reserve < 0 || fileSize <= 0 {
+		return nil
+	}
+	needed := fileSize - allocatedSize(blobsFile)
+	if needed <= 0 {
+		return nil
+	}
+	free, err := diskFree(filepath.Dir(blobsFile))
+	if err != nil {
+		zap.S().Warnf("get free space of %s err.%v", blobsFile, err)
+		return nil
+	}
+	if free-reserve >= needed {
+		return nil
+	}
+	RequestDiskSpace(needed + reserve - free)
+	zap.S().Warnf("insufficient storage for %s, need %d bytes, free %d bytes, reserve %d bytes", blobsFile, needed, free, reserve)
+	return myerr.NewKind(myerr.KindInsufficientStorage, fmt.Sprintf("insufficient storage: need %s, available %s",
+		ConvertBytesToHumanReadable(needed), ConvertBytesToHumanReadable(max(free-reserve, 0))))
+}
+
+// RequestDiskSpace 请求磁盘清理任务额外释放bytes字节，已有未处理的请求时忽略。
+func RequestDiskSpace(bytes int64) {
+	select {
+	case diskSpaceRequests <- bytes:
+	default:
+	}
+}
+
+func DiskSpaceRequests() <-chan int64 {
+	return diskSpaceRequests
+}
+
+// allocatedSize 返回文件实际占用的磁盘空间，稀疏文件只计算已写入的部分，文件不存在时返回0。
+func allocatedSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	size, err := getFilePhysicalSize(info, path)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+func diskFree(dir string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// DiskFree 返回dir所在文件系统的可用空间。
+func DiskFree(dir string) (int64, error) {
+	return diskFree(dir)
+}
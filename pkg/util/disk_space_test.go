@@ -0,0 +1,46 @@
+package util
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dingospeed/pkg/config"
+	myerr "dingospeed/pkg/error"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+
+	blobsFile := filepath.Join(t.TempDir(), "blob")
+	if err := os.WriteFile(blobsFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDiskSpace(blobsFile, 1024); err != nil {
+		t.Fatalf("CheckDiskSpace = %v, want nil", err)
+	}
+
+	config.SysConfig.Download.DiskReserve = 1 << 62
+	err := CheckDiskSpace(blobsFile, 1024)
+	var e myerr.Error
+	if !errors.As(err, &e) || e.StatusCode() != http.StatusInsufficientStorage {
+		t.Fatalf("CheckDiskSpace = %v, want 507", err)
+	}
+	select {
+	case needed := <-DiskSpaceRequests():
+		if needed <= 0 {
+			t.Errorf("requested %d bytes", needed)
+		}
+	default:
+		t.Error("disk clean not triggered")
+	}
+
+	config.SysConfig.Download.DiskReserve = -1
+	if err = CheckDiskSpace(blobsFile, 1<<62); err != nil {
+		t.Errorf("CheckDiskSpace with check disabled = %v", err)
+	}
+}
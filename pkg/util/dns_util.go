@@ -0,0 +1,108 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+)
+
+type dnsEntry struct {
+	addrs    []string
+	expireAt time.Time
+}
+
+var (
+	dnsCache   = common.NewSafeMap[string, *dnsEntry]()
+	nsSequence atomic.Uint64
+)
+
+func dnsResolver() *net.Resolver {
+	nameservers := config.SysConfig.Dns.Nameservers
+	if len(nameservers) == 0 {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			// 轮询使用配置的DNS服务器
+			ns := nameservers[nsSequence.Add(1)%uint64(len(nameservers))]
+			if _, _, err := net.SplitHostPort(ns); err != nil {
+				ns = net.JoinHostPort(ns, "53")
+			}
+			d := net.Dialer{Timeout: 5 * time.Second}
+			return d.DialContext(ctx, network, ns)
+		},
+	}
+}
+
+// LookupHost 解析域名，优先使用hosts配置，其次使用缓存，缓存过期且解析失败时使用过期的结果。
+func LookupHost(ctx context.Context, host string) ([]string, error) {
+	if ip, ok := config.SysConfig.Dns.Hosts[host]; ok {
+		return []string{ip}, nil
+	}
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	entry, cached := dnsCache.Get(host)
+	if cached && time.Now().Before(entry.expireAt) {
+		return entry.addrs, nil
+	}
+	addrs, err := dnsResolver().LookupHost(ctx, host)
+	if err != nil {
+		if cached {
+			return entry.addrs, nil
+		}
+		return nil, err
+	}
+	if ttl := config.SysConfig.GetDnsCacheTTL(); ttl > 0 {
+		dnsCache.Set(host, &dnsEntry{addrs: addrs, expireAt: time.Now().Add(ttl)})
+	}
+	return addrs, nil
+}
+
+func dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if !config.SysConfig.EnableDns() {
+		return dialer.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no address for host %s", host)
+		}
+		return nil, lastErr
+	}
+}
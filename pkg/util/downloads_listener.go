@@ -0,0 +1,80 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// downloadRoutes 由下载端口承载的路由，按注册的路由模板匹配
+var downloadRoutes = []string{"/resolve/", "/archive/"}
+
+var downloadRoutePrefixes = []string{"/cdn/", "/share/"}
+
+// IsDownloadRoute 判断路由模板是否属于文件下载（resolve、cdn、archive、share），/api下的接口均不属于下载。
+func IsDownloadRoute(routePath string) bool {
+	if strings.HasPrefix(routePath, "/api/") {
+		return false
+	}
+	for _, prefix := range downloadRoutePrefixes {
+		if strings.HasPrefix(routePath, prefix) {
+			return true
+		}
+	}
+	for _, route := range downloadRoutes {
+		if strings.Contains(routePath, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDownloadsListener 判断请求是否来自独立的下载监听端口。
+func IsDownloadsListener(req *http.Request) bool {
+	if !config.SysConfig.EnableDownloadsListener() {
+		return false
+	}
+	addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	_, port, err := net.SplitHostPort(addr.String())
+	return err == nil && port == strconv.Itoa(config.SysConfig.Server.Downloads.Port)
+}
+
+// DownloadsLocation 返回请求在下载端口上的地址，未配置endpoint时使用请求的主机名及下载端口。
+func DownloadsLocation(c echo.Context) string {
+	base := config.SysConfig.Server.Downloads.Endpoint
+	if base == "" {
+		host := c.Request().Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		base = fmt.Sprintf("%s://%s", c.Scheme(), net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(config.SysConfig.Server.Downloads.Port)))
+	}
+	location := base + PathPrefix(c) + c.Request().URL.EscapedPath()
+	if query := c.Request().URL.RawQuery; query != "" {
+		location += "?" + query
+	}
+	return location
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestIsDownloadRoute(t *testing.T) {
+	cases := map[string]bool{
+		"/:repoType/:org/:repo/resolve/:commit/:filePath": true,
+		"/:repo/resolve/:commit/:filePath":                true,
+		"/:orgOrRepoType/:repo/archive/:revision/*":       true,
+		"/cdn/:repoType/:org/:repo/:commit/:filePath":     true,
+		"/share/:repoType/:org/:repo/:archive":            true,
+		"/api/:repoType/:org/:repo/archive/:archive":      false,
+		"/api/:repoType/:org/:repo/revision/:commit":      false,
+		"/admin/events": false,
+		"/repos":        false,
+	}
+	for route, want := range cases {
+		if got := IsDownloadRoute(route); got != want {
+			t.Errorf("IsDownloadRoute(%s) = %v, want %v", route, got, want)
+		}
+	}
+}
+
+func TestDownloadsLocation(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	server := config.SysConfig.Server
+	defer func() { config.SysConfig.Server = server }()
+
+	req := httptest.NewRequest(http.MethodGet, "http://mirror.local:8090/org/repo/resolve/main/a%20b.bin?download=1", nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	config.SysConfig.Server.Downloads = config.Downloads{Port: 8091}
+	want := "http://mirror.local:8091/org/repo/resolve/main/a%20b.bin?download=1"
+	if got := DownloadsLocation(c); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	config.SysConfig.Server.Downloads.Endpoint = "https://dl.example.com"
+	want = "https://dl.example.com/org/repo/resolve/main/a%20b.bin?download=1"
+	if got := DownloadsLocation(c); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,191 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+)
+
+// ProxyObject 源站文件的信息，blob为缓存文件名，源站文件变化（ETag、大小或修改时间不同）时使用新的缓存文件。
+type ProxyObject struct {
+	Url          string `json:"url"`
+	Size         int64  `json:"size"`
+	Etag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+	Blob         string `json:"blob"`
+	CheckedAt    int64  `json:"checkedAt"`
+}
+
+// SameVersion 两次获取的源站文件信息是否为同一版本。
+func (o *ProxyObject) SameVersion(other *ProxyObject) bool {
+	return o.Size == other.Size && o.Etag == other.Etag && o.LastModified == other.LastModified
+}
+
+// BlobName 按缓存键及版本生成缓存文件名。
+func (o *ProxyObject) BlobName(key string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s|%s", key, o.Size, o.Etag, o.LastModified)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func IsProxyHost(host string) bool {
+	for _, pattern := range config.SysConfig.GenericProxy.AllowHosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProxyURL 校验代理的地址，只允许http、https及allowHosts中的主机。
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	if rawURL == "" {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, "url is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, fmt.Sprintf("invalid url: %s", rawURL))
+	}
+	if !IsProxyHost(u.Hostname()) {
+		return nil, myerr.NewAppendCode(http.StatusForbidden, fmt.Sprintf("host %s is not allowed", u.Hostname()))
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u, nil
+}
+
+// ProxyCacheKey 源站地址的缓存键，开启ignoreQuery时不含查询参数。
+func ProxyCacheKey(u *url.URL) string {
+	sum := sha256.Sum256([]byte(ProxyDisplayURL(u)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ProxyDisplayURL 记录在缓存中的地址，开启ignoreQuery时去掉查询参数，避免保存签名。
+func ProxyDisplayURL(u *url.URL) string {
+	if !config.SysConfig.GenericProxy.IgnoreQuery {
+		return u.String()
+	}
+	stripped := *u
+	stripped.RawQuery, stripped.ForceQuery = "", false
+	return stripped.String()
+}
+
+// 缓存位于{repos}/proxy/{host}下，meta目录保存源站文件信息，blobs目录保存按块缓存的文件。
+func proxyDir(u *url.URL) string {
+	return filepath.Join(config.SysConfig.Repos(), consts.RepoTypeProxy, strings.ReplaceAll(u.Host, ":", "_"))
+}
+
+func ProxyMetaFile(u *url.URL) string {
+	return filepath.Join(proxyDir(u), "meta", ProxyCacheKey(u)+".json")
+}
+
+func ProxyBlobFile(u *url.URL, blob string) string {
+	return filepath.Join(proxyDir(u), "blobs", blob)
+}
+
+// ProbeOrigin 以bytes=0-0的range请求获取源站文件的大小及版本，源站必须支持range请求。
+func ProbeOrigin(ctx context.Context, rawURL string) (*ProxyObject, error) {
+	client, err := NewHTTPClient(http.MethodGet, consts.RequestClassProxy)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadRequest, err.Error())
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	obj := &ProxyObject{
+		Etag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+	}
+	contentRange := resp.Header.Get("Content-Range")
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		obj.Size, err = parseContentRangeSize(contentRange)
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 空文件无法满足bytes=0-0
+		if obj.Size, err = parseContentRangeSize(contentRange); err == nil && obj.Size != 0 {
+			err = fmt.Errorf("unexpected content-range %q", contentRange)
+		}
+	case http.StatusOK:
+		return nil, myerr.NewAppendCode(http.StatusBadGateway, "origin does not support range requests")
+	default:
+		return nil, myerr.NewAppendCode(resp.StatusCode, fmt.Sprintf("origin responded %d", resp.StatusCode))
+	}
+	if err != nil {
+		return nil, myerr.NewAppendCode(http.StatusBadGateway, fmt.Sprintf("invalid origin response: %v", err))
+	}
+	return obj, nil
+}
+
+// parseContentRangeSize 返回bytes 0-0/1234或bytes */1234中的文件大小。
+func parseContentRangeSize(contentRange string) (int64, error) {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok || total == "*" {
+		return 0, fmt.Errorf("unknown size in content-range %q", contentRange)
+	}
+	return strconv.ParseInt(strings.TrimSpace(total), 10, 64)
+}
+
+// GetOriginStream 以流的方式请求源站，不附带远端的token及出站请求头，也不计入远端的请求预算。
+func GetOriginStream(ctx context.Context, rawURL string, headers map[string]string, f func(r *http.Response) error) error {
+	client, err := NewHTTPClient(http.MethodGet, consts.RequestClassProxy)
+	if err != nil {
+		return fmt.Errorf("construct http client err: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return f(resp)
+}
+
+// checkProxyRedirect 源站的重定向只允许指向allowHosts中的主机。
+func checkProxyRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" || !IsProxyHost(req.URL.Hostname()) {
+		return fmt.Errorf("redirect to %s is not allowed by genericProxy.allowHosts", req.URL.Host)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"dingospeed/pkg/config"
+	myerr "dingospeed/pkg/error"
+)
+
+func TestParseProxyURL(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.GenericProxy.AllowHosts = []string{"example.com", "*.example.org"}
+
+	for rawURL, code := range map[string]int{
+		"https://example.com/a.bin":     0,
+		"http://cdn.example.org/a.bin":  0,
+		"https://other.com/a.bin":       http.StatusForbidden,
+		"ftp://example.com/a.bin":       http.StatusBadRequest,
+		"https://user:pw@example.com/a": http.StatusBadRequest,
+		"":                              http.StatusBadRequest,
+	} {
+		_, err := ParseProxyURL(rawURL)
+		if code == 0 {
+			if err != nil {
+				t.Errorf("%q: unexpected err %v", rawURL, err)
+			}
+			continue
+		}
+		if err == nil || proxyErrCode(err) != code {
+			t.Errorf("%q: expected %d, got %v", rawURL, code, err)
+		}
+	}
+}
+
+func TestProxyCacheKeyIgnoreQuery(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+
+	a, _ := url.Parse("https://example.com/a.bin?sig=1")
+	b, _ := url.Parse("https://example.com/a.bin?sig=2")
+	if ProxyCacheKey(a) == ProxyCacheKey(b) {
+		t.Fatal("expected different keys when query is kept")
+	}
+	config.SysConfig.GenericProxy.IgnoreQuery = true
+	if ProxyCacheKey(a) != ProxyCacheKey(b) {
+		t.Fatal("expected same keys when query is ignored")
+	}
+	if ProxyDisplayURL(a) != "https://example.com/a.bin" {
+		t.Fatalf("unexpected display url %s", ProxyDisplayURL(a))
+	}
+}
+
+func TestProbeOrigin(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/norange" {
+			w.Write([]byte("hello"))
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Range", "bytes 0-0/1234")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("h"))
+	}))
+	defer server.Close()
+
+	obj, err := ProbeOrigin(context.Background(), server.URL+"/file.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Size != 1234 || obj.Etag != `"v1"` {
+		t.Fatalf("unexpected object %+v", obj)
+	}
+	if _, err = ProbeOrigin(context.Background(), fmt.Sprintf("%s/norange", server.URL)); proxyErrCode(err) != http.StatusBadGateway {
+		t.Fatalf("expected 502 for origin without range support, got %v", err)
+	}
+}
+
+func proxyErrCode(err error) int {
+	var e myerr.Error
+	if errors.As(err, &e) {
+		return e.StatusCode()
+	}
+	return 0
+}
@@ -0,0 +1,299 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	ggufMagic        = "GGUF"
+	ggufMaxStringLen = 1 << 20
+	ggufMaxArrayShow = 16 // 数组超过该长度时只返回类型和长度
+	ggufMaxTensors   = 1 << 20
+)
+
+const (
+	ggufTypeUint8 uint32 = iota
+	ggufTypeInt8
+	ggufTypeUint16
+	ggufTypeInt16
+	ggufTypeUint32
+	ggufTypeInt32
+	ggufTypeFloat32
+	ggufTypeBool
+	ggufTypeString
+	ggufTypeArray
+	ggufTypeUint64
+	ggufTypeInt64
+	ggufTypeFloat64
+)
+
+var ggmlTypeNames = map[uint32]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 6: "Q5_0", 7: "Q5_1", 8: "Q8_0", 9: "Q8_1",
+	10: "Q2_K", 11: "Q3_K", 12: "Q4_K", 13: "Q5_K", 14: "Q6_K", 15: "Q8_K",
+	16: "IQ2_XXS", 17: "IQ2_XS", 18: "IQ3_XXS", 19: "IQ1_S", 20: "IQ4_NL", 21: "IQ3_S", 22: "IQ2_S", 23: "IQ4_XS",
+	24: "I8", 25: "I16", 26: "I32", 27: "I64", 28: "F64", 29: "IQ1_M", 30: "BF16", 34: "TQ1_0", 35: "TQ2_0",
+}
+
+// general.file_type对应的量化类型
+var ggufFileTypeNames = map[uint64]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 7: "Q8_0", 8: "Q5_0", 9: "Q5_1", 10: "Q2_K",
+	11: "Q3_K_S", 12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S", 15: "Q4_K_M", 16: "Q5_K_S", 17: "Q5_K_M", 18: "Q6_K",
+	19: "IQ2_XXS", 20: "IQ2_XS", 21: "Q2_K_S", 22: "IQ3_XS", 23: "IQ3_XXS", 24: "IQ1_S", 25: "IQ4_NL", 26: "IQ3_S",
+	27: "IQ3_M", 28: "IQ2_S", 29: "IQ2_M", 30: "IQ4_XS", 31: "IQ1_M", 32: "BF16", 36: "TQ1_0", 37: "TQ2_0",
+}
+
+type GGUFInfo struct {
+	Version        uint32                 `json:"version"`
+	TensorCount    uint64                 `json:"tensorCount"`
+	Architecture   string                 `json:"architecture"`
+	ParameterCount uint64                 `json:"parameterCount"`
+	Quantization   string                 `json:"quantization"`
+	TensorTypes    map[string]uint64      `json:"tensorTypes"` // 各类型的张量数量
+	Metadata       map[string]interface{} `json:"metadata"`
+}
+
+type GGUFArraySummary struct {
+	Type   string `json:"type"`
+	Length uint64 `json:"length"`
+}
+
+type ggufReader struct {
+	r       io.Reader
+	version uint32
+}
+
+// ParseGGUF 解析GGUF文件头，只读取元数据和张量信息，不读取张量数据。
+func ParseGGUF(r io.Reader) (*GGUFInfo, error) {
+	gr := &ggufReader{r: r}
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != ggufMagic {
+		return nil, errors.New("not a gguf file")
+	}
+	info := &GGUFInfo{TensorTypes: make(map[string]uint64), Metadata: make(map[string]interface{})}
+	if err := binary.Read(r, binary.LittleEndian, &info.Version); err != nil {
+		return nil, err
+	}
+	gr.version = info.Version
+	tensorCount, err := gr.readCount()
+	if err != nil {
+		return nil, err
+	}
+	kvCount, err := gr.readCount()
+	if err != nil {
+		return nil, err
+	}
+	info.TensorCount = tensorCount
+	for i := uint64(0); i < kvCount; i++ {
+		key, err := gr.readString()
+		if err != nil {
+			return nil, err
+		}
+		var valueType uint32
+		if err = binary.Read(r, binary.LittleEndian, &valueType); err != nil {
+			return nil, err
+		}
+		value, err := gr.readValue(valueType, true)
+		if err != nil {
+			return nil, fmt.Errorf("read %s err: %v", key, err)
+		}
+		info.Metadata[key] = value
+	}
+	if arch, ok := info.Metadata["general.architecture"].(string); ok {
+		info.Architecture = arch
+	}
+	if fileType, ok := toUint64(info.Metadata["general.file_type"]); ok {
+		info.Quantization = ggufFileTypeNames[fileType]
+	}
+	if tensorCount > ggufMaxTensors {
+		return nil, fmt.Errorf("too many tensors: %d", tensorCount)
+	}
+	for i := uint64(0); i < tensorCount; i++ {
+		if _, err = gr.readString(); err != nil {
+			return nil, err
+		}
+		var nDims uint32
+		if err = binary.Read(r, binary.LittleEndian, &nDims); err != nil {
+			return nil, err
+		}
+		elements := uint64(1)
+		for d := uint32(0); d < nDims; d++ {
+			dim, err := gr.readCount()
+			if err != nil {
+				return nil, err
+			}
+			elements *= dim
+		}
+		var tensorType uint32
+		if err = binary.Read(r, binary.LittleEndian, &tensorType); err != nil {
+			return nil, err
+		}
+		var offset uint64
+		if err = binary.Read(r, binary.LittleEndian, &offset); err != nil {
+			return nil, err
+		}
+		info.ParameterCount += elements
+		info.TensorTypes[ggmlTypeName(tensorType)]++
+	}
+	return info, nil
+}
+
+// v1版本的数量和长度为uint32，v2及以上为uint64
+func (g *ggufReader) readCount() (uint64, error) {
+	if g.version == 1 {
+		var n uint32
+		err := binary.Read(g.r, binary.LittleEndian, &n)
+		return uint64(n), err
+	}
+	var n uint64
+	err := binary.Read(g.r, binary.LittleEndian, &n)
+	return n, err
+}
+
+func (g *ggufReader) readString() (string, error) {
+	n, err := g.readCount()
+	if err != nil {
+		return "", err
+	}
+	if n > ggufMaxStringLen {
+		return "", fmt.Errorf("string too long: %d", n)
+	}
+	b := make([]byte, n)
+	if _, err = io.ReadFull(g.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (g *ggufReader) readValue(valueType uint32, keep bool) (interface{}, error) {
+	switch valueType {
+	case ggufTypeUint8:
+		var v uint8
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeInt8:
+		var v int8
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeUint16:
+		var v uint16
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeInt16:
+		var v int16
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeUint32:
+		var v uint32
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeInt32:
+		var v int32
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeFloat32:
+		var v float32
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return SetValWhenFloatIsNaNOrInf(float64(v)), err
+	case ggufTypeBool:
+		var v uint8
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v != 0, err
+	case ggufTypeString:
+		return g.readString()
+	case ggufTypeUint64:
+		var v uint64
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeInt64:
+		var v int64
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeFloat64:
+		var v float64
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return SetValWhenFloatIsNaNOrInf(v), err
+	case ggufTypeArray:
+		var itemType uint32
+		if err := binary.Read(g.r, binary.LittleEndian, &itemType); err != nil {
+			return nil, err
+		}
+		n, err := g.readCount()
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt32 {
+			return nil, fmt.Errorf("array too long: %d", n)
+		}
+		// 数组较长时（如词表）只读取不保留
+		keep = keep && n <= ggufMaxArrayShow
+		items := make([]interface{}, 0)
+		for i := uint64(0); i < n; i++ {
+			item, err := g.readValue(itemType, keep)
+			if err != nil {
+				return nil, err
+			}
+			if keep {
+				items = append(items, item)
+			}
+		}
+		if !keep {
+			return &GGUFArraySummary{Type: ggufValueTypeName(itemType), Length: n}, nil
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown value type %d", valueType)
+}
+
+func ggmlTypeName(t uint32) string {
+	if name, ok := ggmlTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE_%d", t)
+}
+
+func ggufValueTypeName(t uint32) string {
+	names := []string{"uint8", "int8", "uint16", "int16", "uint32", "int32", "float32", "bool", "string", "array", "uint64", "int64", "float64"}
+	if int(t) < len(names) {
+		return names[t]
+	}
+	return fmt.Sprintf("type_%d", t)
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	case int32:
+		return uint64(n), true
+	case int64:
+		return uint64(n), true
+	}
+	return 0, false
+}
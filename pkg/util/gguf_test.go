@@ -0,0 +1,60 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func writeGGUFString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func TestParseGGUF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("GGUF")
+	binary.Write(buf, binary.LittleEndian, uint32(3))
+	binary.Write(buf, binary.LittleEndian, uint64(2)) // tensor count
+	binary.Write(buf, binary.LittleEndian, uint64(3)) // kv count
+	writeGGUFString(buf, "general.architecture")
+	binary.Write(buf, binary.LittleEndian, ggufTypeString)
+	writeGGUFString(buf, "llama")
+	writeGGUFString(buf, "general.file_type")
+	binary.Write(buf, binary.LittleEndian, ggufTypeUint32)
+	binary.Write(buf, binary.LittleEndian, uint32(15))
+	writeGGUFString(buf, "tokenizer.ggml.tokens")
+	binary.Write(buf, binary.LittleEndian, ggufTypeArray)
+	binary.Write(buf, binary.LittleEndian, ggufTypeString)
+	binary.Write(buf, binary.LittleEndian, uint64(20))
+	for i := 0; i < 20; i++ {
+		writeGGUFString(buf, "tok")
+	}
+	for _, tensor := range []struct {
+		name string
+		dims []uint64
+		typ  uint32
+	}{{"token_embd.weight", []uint64{4, 8}, 12}, {"output_norm.weight", []uint64{8}, 0}} {
+		writeGGUFString(buf, tensor.name)
+		binary.Write(buf, binary.LittleEndian, uint32(len(tensor.dims)))
+		for _, d := range tensor.dims {
+			binary.Write(buf, binary.LittleEndian, d)
+		}
+		binary.Write(buf, binary.LittleEndian, tensor.typ)
+		binary.Write(buf, binary.LittleEndian, uint64(0))
+	}
+
+	info, err := ParseGGUF(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Architecture != "llama" || info.Quantization != "Q4_K_M" || info.ParameterCount != 40 {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.TensorTypes["Q4_K"] != 1 || info.TensorTypes["F32"] != 1 {
+		t.Fatalf("unexpected tensor types: %v", info.TensorTypes)
+	}
+	if summary, ok := info.Metadata["tokenizer.ggml.tokens"].(*GGUFArraySummary); !ok || summary.Length != 20 {
+		t.Fatalf("unexpected tokens: %v", info.Metadata["tokenizer.ggml.tokens"])
+	}
+}
@@ -23,7 +23,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
 
 	"dingospeed/pkg/common"
@@ -39,13 +38,15 @@ import (
 
 var (
 	ProxyIsAvailable = true
-	simpleClient     *http.Client
-	proxyClient      *http.Client
-	simpleOnce       sync.Once
-	proxyOnce        sync.Once
+	clientMap        = common.NewSafeMap[string, *http.Client]()
 )
 
 func RetryRequest(f func() (*common.Response, error)) (*common.Response, error) {
+	return RetryRequestContext(context.Background(), f)
+}
+
+// RetryRequestContext 同RetryRequest，ctx取消后不再重试。
+func RetryRequestContext(ctx context.Context, f func() (*common.Response, error)) (*common.Response, error) {
 	var resp *common.Response
 	err := retry.Do(
 		func() error {
@@ -56,103 +57,142 @@ func RetryRequest(f func() (*common.Response, error)) (*common.Response, error)
 		retry.Delay(time.Duration(config.SysConfig.Retry.Delay)*time.Second),
 		retry.Attempts(config.SysConfig.Retry.Attempts),
 		retry.DelayType(retry.FixedDelay),
+		retry.Context(ctx),
 	)
 	return resp, err
 }
 
-func NewHTTPClient(method string) (*http.Client, error) {
+// RetryStream 按重试配置执行流式请求，retryIf返回false时不再重试（如已开始向客户端回传数据）。
+func RetryStream(f func() error, retryIf func(err error) bool) error {
+	return retry.Do(
+		f,
+		retry.Delay(time.Duration(config.SysConfig.Retry.Delay)*time.Second),
+		retry.Attempts(config.SysConfig.Retry.Attempts),
+		retry.DelayType(retry.FixedDelay),
+		retry.RetryIf(retryIf),
+		retry.LastErrorOnly(true),
+	)
+}
+
+func NewHTTPClient(method, class string) (*http.Client, error) {
+	return getClient(method, class, "", false)
+}
+
+func NewHTTPClientWithProxy(method, class string) (*http.Client, error) {
+	return getClient(method, class, "", true)
+}
+
+// 按请求类型、是否走代理及远端的TLS配置复用客户端，meta和pathsInfo限制整个请求的时长，blob只限制等待响应头的时长，避免大文件下载被中断。
+// endpoint为空时使用默认的TLS配置（server.upstreamTls）。
+func getClient(method, class, endpoint string, useProxy bool) (*http.Client, error) {
+	upstreamTls, tlsKey := config.SysConfig.GetUpstreamTLS(endpoint)
+	key := fmt.Sprintf("%s/%t/%t/%s", class, method == http.MethodHead, useProxy, tlsKey)
+	if client, ok := clientMap.Get(key); ok {
+		return client, nil
+	}
+	transport, err := newTransport(class, useProxy)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig, err = NewUpstreamTLSConfig(upstreamTls)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: &chaosTransport{next: transport}}
+	if class != consts.RequestClassBlob && class != consts.RequestClassUpload && class != consts.RequestClassProxy {
+		client.Timeout = config.SysConfig.GetReadTimeout(class)
+	}
 	if method == http.MethodHead {
-		return &http.Client{
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse // 阻止跟随重定向
-			},
-			Timeout: config.SysConfig.GetReqTimeOut()}, nil
-	}
-	simpleOnce.Do(
-		func() {
-			simpleClient = &http.Client{Timeout: config.SysConfig.GetReqTimeOut()}
-		})
-	return simpleClient, nil
-}
-
-func NewHTTPClientWithProxy(method string) (*http.Client, error) {
-	var transport *http.Transport
-	if config.SysConfig.GetHttpProxy() != "" {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // 阻止跟随重定向
+		}
+	} else if class == consts.RequestClassMeta {
+		client.CheckRedirect = checkMetaRedirect
+	} else if class == consts.RequestClassBlob {
+		client.CheckRedirect = checkBlobRedirect
+	} else if class == consts.RequestClassProxy {
+		client.CheckRedirect = checkProxyRedirect
+	}
+	clientMap.Set(key, client)
+	return client, nil
+}
+
+func newTransport(class string, useProxy bool) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialContext(&net.Dialer{
+		Timeout:   config.SysConfig.GetConnectTimeout(class),
+		KeepAlive: config.SysConfig.GetKeepAlive(),
+	})
+	transport.MaxIdleConns = config.SysConfig.GetMaxIdleConns()
+	transport.MaxIdleConnsPerHost = config.SysConfig.GetMaxIdleConnsPerHost()
+	transport.IdleConnTimeout = config.SysConfig.GetIdleConnTimeout()
+	transport.TLSHandshakeTimeout = config.SysConfig.GetTLSHandshakeTimeout()
+	if useProxy && config.SysConfig.GetHttpProxy() != "" {
 		proxyURL, err := url.Parse(config.SysConfig.GetHttpProxy())
 		if err != nil {
 			zap.S().Errorf("代理地址解析失败: %v", err)
 			return nil, err
 		}
-		transport = &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ForceAttemptHTTP2:     false,
-			ResponseHeaderTimeout: 10 * time.Second,
-			IdleConnTimeout:       90 * time.Second,
-		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		transport.ForceAttemptHTTP2 = false
+		transport.ResponseHeaderTimeout = 10 * time.Second
 	}
-	if method == http.MethodHead {
-		proxyHeadClient := &http.Client{
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse // 阻止跟随重定向
-			},
-			Timeout: config.SysConfig.GetReqTimeOut()}
-		if transport != nil {
-			proxyHeadClient.Transport = transport
-		}
-		return proxyHeadClient, nil
+	if class == consts.RequestClassBlob || class == consts.RequestClassUpload || class == consts.RequestClassProxy {
+		transport.ResponseHeaderTimeout = config.SysConfig.GetReadTimeout(class)
 	}
-	proxyOnce.Do(func() {
-		proxyClient = &http.Client{Timeout: config.SysConfig.GetReqTimeOut()}
-		if transport != nil {
-			proxyClient.Transport = transport
-		}
-	})
-	return proxyClient, nil
+	return transport, nil
 }
 
-func constructClient(method string) (string, *http.Client, error) {
-	var (
-		domain string
-		client *http.Client
-		err    error
-	)
+// constructClient 根据请求路径选择远端，命中路由规则时使用规则指定的远端。
+func constructClient(method, class, uri string) (string, *http.Client, error) {
+	if config.SysConfig.InMaintenance() {
+		return "", nil, MaintenanceError()
+	}
+	domain, useProxy := upstreamDomain(uri)
+	client, err := getClient(method, class, domain, useProxy)
+	return domain, client, err
+}
+
+// upstreamDomain 返回请求路径对应的远端地址及是否走代理。
+func upstreamDomain(uri string) (string, bool) {
+	if rule := config.SysConfig.MatchUpstream(ParseUpstreamRoute(uri)); rule != nil {
+		return rule.Endpoint, rule.UseProxy
+	}
 	// 代理不可用，且允许代理切换到备用，使用直联。
 	if !ProxyIsAvailable && config.SysConfig.DynamicProxy.Enabled {
-		domain = config.SysConfig.GetBpHFURLBase()
-		client, err = NewHTTPClient(method)
-	} else {
-		domain = config.SysConfig.GetHFURLBase()
-		client, err = NewHTTPClientWithProxy(method)
+		return config.SysConfig.GetBpHFURLBase(), false
 	}
-	return domain, client, err
+	return config.SysConfig.GetHFURLBase(), true
 }
 
 func Head(requestUri string, headers map[string]string) (*common.Response, error) {
-	domain, client, err := constructClient(http.MethodHead)
+	return HeadContext(context.Background(), requestUri, headers)
+}
+
+// HeadContext 发起HEAD请求，ctx取消（如客户端断开）时中止远端请求。
+func HeadContext(ctx context.Context, requestUri string, headers map[string]string) (*common.Response, error) {
+	domain, client, err := constructClient(http.MethodHead, consts.RequestClassMeta, requestUri)
 	if err != nil {
 		return nil, fmt.Errorf("construct http client err: %v", err)
 	}
 	requestURL := fmt.Sprintf("%s%s", domain, requestUri)
-	return doHead(client, requestURL, headers)
+	return doHead(ctx, client, requestURL, headers)
 }
 
-func doHead(client *http.Client, targetURL string, headers map[string]string) (*common.Response, error) {
-	req, err := http.NewRequest("HEAD", targetURL, nil)
+func doHead(ctx context.Context, client *http.Client, targetURL string, headers map[string]string) (*common.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", targetURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建HEAD请求失败: %v", err)
 	}
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
-	resp, err := client.Do(req)
+	ApplyOutboundHeaders(req.Header)
+	applyUpstreamToken(req)
+	resp, err := doUpstream(client, req, targetURL)
 	if err != nil {
 		zap.S().Warnf("URL请求失败: %s, 错误: %v", targetURL, err)
-		return nil, fmt.Errorf("执行HEAD请求失败: %v", err)
+		return nil, fmt.Errorf("执行HEAD请求失败: %w", err)
 	}
 	defer func() {
 		if r := recover(); r != nil {
@@ -171,26 +211,33 @@ func doHead(client *http.Client, targetURL string, headers map[string]string) (*
 }
 
 func Get(requestUri string, headers map[string]string) (*common.Response, error) {
-	domain, client, err := constructClient(http.MethodGet)
+	return GetContext(context.Background(), requestUri, headers)
+}
+
+// GetContext 发起GET请求，ctx取消（如客户端断开）时中止远端请求。
+func GetContext(ctx context.Context, requestUri string, headers map[string]string) (*common.Response, error) {
+	domain, client, err := constructClient(http.MethodGet, consts.RequestClassMeta, requestUri)
 	if err != nil {
 		return nil, fmt.Errorf("construct http client err: %v", err)
 	}
 	requestURL := fmt.Sprintf("%s%s", domain, requestUri)
-	return doGet(client, requestURL, headers)
+	return doGet(ctx, client, requestURL, headers)
 }
 
-func doGet(client *http.Client, targetURL string, headers map[string]string) (*common.Response, error) {
-	req, err := http.NewRequest("GET", targetURL, nil)
+func doGet(ctx context.Context, client *http.Client, targetURL string, headers map[string]string) (*common.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建GET请求失败: %v", err)
 	}
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
-	resp, err := client.Do(req)
+	ApplyOutboundHeaders(req.Header)
+	applyUpstreamToken(req)
+	resp, err := doUpstream(client, req, targetURL)
 	if err != nil {
 		zap.S().Warnf("URL请求失败: %s, 错误: %v", targetURL, err)
-		return nil, fmt.Errorf("执行GET请求失败: %v", err)
+		return nil, fmt.Errorf("执行GET请求失败: %w", err)
 	}
 
 	defer func() {
@@ -200,7 +247,7 @@ func doGet(client *http.Client, targetURL string, headers map[string]string) (*c
 		resp.Body.Close()
 	}()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := ReadAllBuffered(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("读取响应体失败: %v", err)
 	}
@@ -217,34 +264,70 @@ func doGet(client *http.Client, targetURL string, headers map[string]string) (*c
 	}, nil
 }
 
-func GetStream(domain, uri string, headers map[string]string, f func(r *http.Response) error) error {
+// GetEndpointContext 向huggingface之外的远端（如datasets-server）发起GET请求，是否走代理与默认远端一致。
+func GetEndpointContext(ctx context.Context, endpoint, requestUri string, headers map[string]string) (*common.Response, error) {
+	if config.SysConfig.InMaintenance() {
+		return nil, MaintenanceError()
+	}
+	useProxy := ProxyIsAvailable || !config.SysConfig.DynamicProxy.Enabled
+	client, err := getClient(http.MethodGet, consts.RequestClassMeta, endpoint, useProxy)
+	if err != nil {
+		return nil, fmt.Errorf("construct http client err: %v", err)
+	}
+	return doGet(ctx, client, strings.TrimSuffix(endpoint, "/")+requestUri, headers)
+}
+
+// GetStream 以流的方式请求远端文件，ctx取消时中止传输。
+func GetStream(ctx context.Context, domain, uri string, headers map[string]string, f func(r *http.Response) error) error {
 	var (
 		client *http.Client
 		err    error
 	)
 	if IsInnerDomain(domain) {
-		client, err = NewHTTPClient(http.MethodGet)
+		client, err = NewHTTPClient(http.MethodGet, consts.RequestClassBlob)
 		headers[consts.RequestSourceInner] = Itoa(1)
 	} else {
-		domain, client, err = constructClient(http.MethodGet)
+		domain, client, err = constructClient(http.MethodGet, consts.RequestClassBlob, uri)
 	}
 	if err != nil {
 		return fmt.Errorf("construct http client err: %v", err)
 	}
 	requestURL := fmt.Sprintf("%s%s", domain, uri)
-	return doGetStream(client, requestURL, headers, f)
+	return doGetStream(ctx, client, requestURL, headers, f)
+}
+
+// GetPeerStream 以流的方式请求其他镜像实例，不经过代理。
+func GetPeerStream(ctx context.Context, endpoint, uri string, headers map[string]string, f func(r *http.Response) error) error {
+	client, err := NewHTTPClient(http.MethodGet, consts.RequestClassBlob)
+	if err != nil {
+		return fmt.Errorf("construct http client err: %v", err)
+	}
+	return doGetStream(ctx, client, strings.TrimSuffix(endpoint, "/")+uri, headers, f)
 }
 
-func doGetStream(client *http.Client, targetURL string, headers map[string]string, f func(r *http.Response) error) error {
+// GetMetaStream 以流的方式请求远端元数据，响应体由f消费，不在内存中缓冲。
+// 客户端断开后仍继续写入缓存，因此不绑定请求的ctx。
+func GetMetaStream(requestUri string, headers map[string]string, f func(r *http.Response) error) error {
+	domain, client, err := constructClient(http.MethodGet, consts.RequestClassMeta, requestUri)
+	if err != nil {
+		return fmt.Errorf("construct http client err: %v", err)
+	}
+	requestURL := fmt.Sprintf("%s%s", domain, requestUri)
+	return doGetStream(context.Background(), client, requestURL, headers, f)
+}
+
+func doGetStream(ctx context.Context, client *http.Client, targetURL string, headers map[string]string, f func(r *http.Response) error) error {
 	escapedURL := strings.ReplaceAll(targetURL, "#", "%23")
-	req, err := http.NewRequest("GET", escapedURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", escapedURL, nil)
 	if err != nil {
 		return fmt.Errorf("创建GET请求失败: %v", err)
 	}
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
-	resp, err := client.Do(req)
+	ApplyOutboundHeaders(req.Header)
+	applyUpstreamToken(req)
+	resp, err := doUpstream(client, req, targetURL)
 	if err != nil {
 		return err
 	}
@@ -257,7 +340,7 @@ func doGetStream(client *http.Client, targetURL string, headers map[string]strin
 }
 
 func Post(requestUri string, contentType string, data []byte, headers map[string]string) (*common.Response, error) {
-	domain, client, err := constructClient(http.MethodPost)
+	domain, client, err := constructClient(http.MethodPost, consts.RequestClassPathsInfo, requestUri)
 	if err != nil {
 		return nil, fmt.Errorf("construct http client err: %v", err)
 	}
@@ -275,11 +358,13 @@ func doPost(client *http.Client, targetURL string, contentType string, data []by
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
+	ApplyOutboundHeaders(req.Header)
+	applyUpstreamToken(req)
 
-	resp, err := client.Do(req)
+	resp, err := doUpstream(client, req, targetURL)
 	if err != nil {
 		zap.S().Warnf("URL请求失败: %s, 错误: %v", targetURL, err)
-		return nil, fmt.Errorf("执行POST请求失败: %v", err)
+		return nil, fmt.Errorf("执行POST请求失败: %w", err)
 	}
 
 	defer func() {
@@ -289,7 +374,7 @@ func doPost(client *http.Client, targetURL string, contentType string, data []by
 		resp.Body.Close()
 	}()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := ReadAllBuffered(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("读取响应体失败: %v", err)
 	}
@@ -306,7 +391,13 @@ func doPost(client *http.Client, targetURL string, contentType string, data []by
 	}, nil
 }
 
-func ResponseStream(ctx context.Context, c echo.Context, fileName string, headers map[string]string, content <-chan []byte, fileErrCh chan error) error {
+// ResponseStream 从content中读取数据流式写回客户端，fileErrCh非空时先等待数据源就绪。
+func ResponseStream(ctx context.Context, c echo.Context, fileName string, headers map[string]string, content io.Reader, fileErrCh chan error) error {
+	return ResponseStreamStatus(ctx, c, fileName, http.StatusOK, headers, content, fileErrCh)
+}
+
+// ResponseStreamStatus 以code响应并流式发送content，如range续传时为206。
+func ResponseStreamStatus(ctx context.Context, c echo.Context, fileName string, code int, headers map[string]string, content io.Reader, fileErrCh chan error) error {
 	c.Response().Header().Set("Content-Type", "text/event-stream")
 	c.Response().Header().Set("Cache-Control", "no-cache")
 	c.Response().Header().Set("Connection", "keep-alive")
@@ -314,50 +405,136 @@ func ResponseStream(ctx context.Context, c echo.Context, fileName string, header
 		c.Response().Header().Set(k, v)
 	}
 	if fileErrCh != nil {
-		if err := <-fileErrCh; err != nil {
-			if e, ok := err.(myerr.Error); ok {
-				return c.String(e.StatusCode(), err.Error())
+		select {
+		case err := <-fileErrCh:
+			if err != nil {
+				if e, ok := err.(myerr.Error); ok {
+					return c.String(e.StatusCode(), err.Error())
+				}
+				return ErrorProxyError(c)
 			}
-			return ErrorProxyError(c)
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 	flusher, ok := c.Response().Writer.(http.Flusher)
 	if !ok {
 		return c.String(http.StatusInternalServerError, "Streaming unsupported!")
 	}
-	c.Response().WriteHeader(http.StatusOK)
+	c.Response().WriteHeader(code)
+	flusher.Flush()
+	// 每次只读取一个缓冲区的数据，写给客户端后再读下一段，客户端读取慢时上游写入方随之阻塞。
+	// 开启自适应时缓冲区大小随实测的写入速度调整。
+	sizer := newChunkSizer()
+	defer func() {
+		recordClientThroughput(c.RealIP(), sizer.throughput())
+	}()
+	buf := make([]byte, sizer.size)
 	for {
-		select {
-		case b, ok := <-content:
-			if !ok {
-				zap.S().Infof("ResponseStream complete, %s", fileName)
-				return nil
+		if len(buf) != sizer.size {
+			buf = make([]byte, sizer.size)
+		}
+		n, err := content.Read(buf)
+		if n > 0 {
+			if werr := WaitServe(c, int64(n)); werr != nil {
+				return werr
 			}
-			if len(b) > 0 {
-				if _, err := c.Response().Write(b); err != nil {
-					zap.S().Warnf("ResponseStream write err,file:%s,%v", fileName, err)
-					return ErrorProxyTimeout(c)
-				}
-				if config.SysConfig.EnableMetric() {
-					// 原子性地更新响应总数
-					source := Itoa(c.Get(consts.PromSource))
-					orgRepo := Itoa(c.Get(consts.PromOrgRepo))
-					prom.PromResponseByteCounter(prom.RequestResponseByte, source, orgRepo, int64(len(b)))
-				}
+			writeStart := time.Now()
+			if _, werr := c.Response().Write(buf[:n]); werr != nil {
+				zap.S().Warnf("ResponseStream write err,file:%s,%v", fileName, werr)
+				return werr
+			}
+			if config.SysConfig.EnableMetric() {
+				// 原子性地更新响应总数
+				source := Itoa(c.Get(consts.PromSource))
+				orgRepo := Itoa(c.Get(consts.PromOrgRepo))
+				prom.PromResponseByteCounter(prom.RequestResponseByte, source, orgRepo, int64(n))
 			}
 			flusher.Flush()
-		case <-ctx.Done():
-			return c.String(http.StatusInternalServerError, ctx.Err().Error())
+			sizer.observe(n, time.Since(writeStart))
+		}
+		if err == io.EOF {
+			zap.S().Infof("ResponseStream complete, %s", fileName)
+			return nil
+		}
+		if err != nil {
+			zap.S().Warnf("ResponseStream read err,file:%s,%v", fileName, err)
+			return err
+		}
+	}
+}
+
+const streamBufferSize = 32 * 1024
+
+// WaitServe 向客户端发送n字节前调用，启用带宽公平分配时按客户端的权重限速，节点间的内部请求不受限制，客户端断开时返回错误。
+// 客户端IP属于配置了带宽的网段时，同时受该网段带宽的限制。
+func WaitServe(c echo.Context, n int64) error {
+	if c.Request().Header.Get(consts.RequestSourceInner) == "1" {
+		return nil
+	}
+	ip := c.RealIP()
+	if class, ok := c.Get(consts.KeyNetworkClass).(*common.NetworkClass); ok {
+		if err := class.WaitN(c.Request().Context(), ip, n); err != nil {
+			return err
 		}
 	}
+	if common.ServeLimiter == nil {
+		return nil
+	}
+	token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+	key := "ip:" + ip
+	if token != "" {
+		key = "token:" + TokenHash(token)
+	}
+	return common.ServeLimiter.WaitN(c.Request().Context(), key, config.SysConfig.GetFairShareWeight(token, ip), n)
+}
+
+// ResponseStreamWriter 写入响应头后返回响应体的写入器，客户端断开后丢弃后续数据而不返回错误，不影响同时进行的缓存写入。
+func ResponseStreamWriter(c echo.Context, fileName string, headers map[string]string) io.Writer {
+	c.Response().Header().Set("Content-Type", "text/event-stream")
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Connection", "keep-alive")
+	for k, v := range headers {
+		c.Response().Header().Set(k, v)
+	}
+	c.Response().WriteHeader(http.StatusOK)
+	return &responseStreamWriter{c: c, fileName: fileName}
+}
+
+type responseStreamWriter struct {
+	c        echo.Context
+	fileName string
+	err      error
+}
+
+func (w *responseStreamWriter) Write(p []byte) (int, error) {
+	if w.err != nil || len(p) == 0 {
+		return len(p), nil
+	}
+	if w.err = WaitServe(w.c, int64(len(p))); w.err != nil {
+		return len(p), nil
+	}
+	if _, w.err = w.c.Response().Write(p); w.err != nil {
+		zap.S().Warnf("ResponseStream write err,file:%s,%v", w.fileName, w.err)
+		return len(p), nil
+	}
+	if config.SysConfig.EnableMetric() {
+		source := Itoa(w.c.Get(consts.PromSource))
+		orgRepo := Itoa(w.c.Get(consts.PromOrgRepo))
+		prom.PromResponseByteCounter(prom.RequestResponseByte, source, orgRepo, int64(len(p)))
+	}
+	w.c.Response().Flush()
+	return len(p), nil
 }
 
+// ForwardRequest 原样转发请求，转发前按forward配置改写请求头；开启重试时GET/HEAD请求在连接失败或远端返回5xx时按retry配置重试。
+// 配置了超时时间时，超时在返回的响应体关闭前始终生效。
 func ForwardRequest(originalReq echo.Context) (*http.Response, error) {
-	domain, client, err := constructClient(http.MethodGet)
+	reqUri := originalReq.Request().URL.Path
+	domain, client, err := constructClient(http.MethodGet, consts.RequestClassMeta, reqUri)
 	if err != nil {
 		return nil, fmt.Errorf("construct http client err: %v", err)
 	}
-	reqUri := originalReq.Request().URL.Path
 	targetURL, err := url.Parse(domain)
 	if err != nil {
 		return nil, fmt.Errorf("url.Parse err: %v", err)
@@ -369,8 +546,45 @@ func ForwardRequest(originalReq echo.Context) (*http.Response, error) {
 		Path:     forwardPath,
 		RawQuery: originalReq.Request().URL.RawQuery,
 	}
-	proxyReq, err := http.NewRequest(originalReq.Request().Method, forwardURL.String(), originalReq.Request().Body)
+	method := originalReq.Request().Method
+	attempts := 1
+	if config.SysConfig.Forward.Retry && (method == http.MethodGet || method == http.MethodHead) {
+		attempts = max(int(config.SysConfig.Retry.Attempts), 1)
+	}
+	headerRules := config.SysConfig.GetForwardRequestHeaderRules(reqUri)
+	ctx := originalReq.Request().Context()
+	for i := 1; ; i++ {
+		resp, err := forwardOnce(originalReq, client, forwardURL.String(), headerRules)
+		if i >= attempts || (err == nil && resp.StatusCode < http.StatusInternalServerError) {
+			return resp, err
+		}
+		if err == nil {
+			zap.S().Warnf("转发请求 %s 返回%d，第%d次重试", forwardURL, resp.StatusCode, i)
+			resp.Body.Close()
+		} else {
+			zap.S().Warnf("转发请求 %s 失败，第%d次重试: %v", forwardURL, i, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(config.SysConfig.Retry.Delay) * time.Second):
+		}
+	}
+}
+
+func forwardOnce(originalReq echo.Context, client *http.Client, targetURL string, headerRules []config.HeaderRule) (*http.Response, error) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout := config.SysConfig.Forward.Timeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(originalReq.Request().Context(), time.Duration(timeout)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(originalReq.Request().Context())
+	}
+	proxyReq, err := http.NewRequestWithContext(ctx, originalReq.Request().Method, targetURL, originalReq.Request().Body)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("创建转发请求失败: %v", err)
 	}
 	for key, values := range originalReq.Request().Header {
@@ -378,14 +592,195 @@ func ForwardRequest(originalReq echo.Context) (*http.Response, error) {
 			proxyReq.Header.Add(key, value)
 		}
 	}
+	ApplyHeaderRules(proxyReq.Header, headerRules)
+	ApplyOutboundHeaders(proxyReq.Header)
+	applyUpstreamToken(proxyReq)
+	start := time.Now()
 	resp, err := client.Do(proxyReq)
+	captureUpstream(proxyReq, resp, err, start)
 	if err != nil {
+		cancel()
 		zap.S().Warnf("转发请求失败: %s, 错误: %v", targetURL, err)
-		return nil, fmt.Errorf("执行转发请求失败: %v", err)
+		return nil, fmt.Errorf("执行转发请求失败: %w", err)
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// ReadLimited 读取全部内容，limit大于0且内容超过limit时返回错误。
+func ReadLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, myerr.NewAppendCode(http.StatusBadGateway, fmt.Sprintf("response body exceeds %d bytes", limit))
+	}
+	return content, nil
+}
+
+// cancelOnClose 关闭响应体时释放请求的context。
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// ForwardUpload 以流的方式转发上传请求，targetURL为相对路径时使用远端域名，不限制请求的总时长。
+func ForwardUpload(originalReq echo.Context, targetURL string, body io.Reader) (*http.Response, error) {
+	domain, client, err := constructClient(originalReq.Request().Method, consts.RequestClassUpload, targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("construct http client err: %v", err)
+	}
+	if strings.HasPrefix(targetURL, "/") {
+		targetURL = fmt.Sprintf("%s%s", domain, targetURL)
+	}
+	proxyReq, err := http.NewRequestWithContext(originalReq.Request().Context(), originalReq.Request().Method, targetURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建上传请求失败: %v", err)
+	}
+	proxyReq.ContentLength = originalReq.Request().ContentLength
+	for key, values := range originalReq.Request().Header {
+		if strings.EqualFold(key, "host") {
+			continue
+		}
+		for _, value := range values {
+			proxyReq.Header.Add(key, value)
+		}
+	}
+	ApplyOutboundHeaders(proxyReq.Header)
+	applyUpstreamToken(proxyReq)
+	resp, err := client.Do(proxyReq)
+	if err != nil {
+		zap.S().Warnf("上传请求失败: %s, 错误: %v", targetURL, err)
+		return nil, fmt.Errorf("执行上传请求失败: %w", err)
 	}
 	return resp, nil
 }
 
+// ApplyHeaderRules 按顺序对头部执行删除、设置、追加及改写规则。
+func ApplyHeaderRules(header http.Header, rules []config.HeaderRule) {
+	for _, rule := range rules {
+		switch rule.Action {
+		case "remove":
+			header.Del(rule.Name)
+		case "set":
+			header.Set(rule.Name, rule.Value)
+		case "add":
+			header.Add(rule.Name, rule.Value)
+		case "rewrite":
+			values := header[http.CanonicalHeaderKey(rule.Name)]
+			for i, v := range values {
+				values[i] = strings.ReplaceAll(v, rule.Pattern, rule.Value)
+			}
+		}
+	}
+}
+
+// ApplyOutboundHeaders 按outbound配置移除、附加请求头并改写User-Agent，集群内部节点间的请求不处理。
+func ApplyOutboundHeaders(header http.Header) {
+	if header.Get(consts.RequestSourceInner) != "" {
+		return
+	}
+	outbound := config.SysConfig.Outbound
+	for _, key := range outbound.StripHeaders {
+		header.Del(key)
+	}
+	for key, value := range outbound.Headers {
+		header.Set(key, value)
+	}
+	if outbound.UserAgent != "" {
+		header.Set("User-Agent", strings.ReplaceAll(outbound.UserAgent, "{version}", config.SystemInfo.Version))
+	}
+}
+
+// applyUpstreamToken 发往远端的请求中，客户端携带镜像签发的key时替换为映射的远端token；
+// 未携带token时，读取类请求附加默认token。发往其他地址（如其他镜像实例、CDN）的请求不处理。
+func applyUpstreamToken(req *http.Request) {
+	if req.Header.Get(consts.RequestSourceInner) != "" || !isUpstreamURL(req.URL.String()) {
+		return
+	}
+	if authorization := req.Header.Get("Authorization"); authorization != "" {
+		if token, _, ok := config.SysConfig.GetMappedToken(strings.TrimPrefix(authorization, "Bearer ")); ok {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return
+	}
+	token := config.SysConfig.Outbound.DefaultToken
+	// POST中只有paths-info是读取类请求
+	readOnly := req.Method == http.MethodGet || req.Method == http.MethodHead ||
+		(req.Method == http.MethodPost && strings.Contains(req.URL.Path, "/paths-info/"))
+	if token != "" && readOnly {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// upstreamEndpoints 返回默认远端、备用远端及路由规则中的远端地址。
+func upstreamEndpoints() []string {
+	endpoints := []string{config.SysConfig.GetHFURLBase(), config.SysConfig.GetBpHFURLBase()}
+	for _, rule := range config.SysConfig.Server.Upstreams {
+		endpoints = append(endpoints, rule.Endpoint)
+	}
+	return endpoints
+}
+
+// cutUpstreamEndpoint 地址属于远端时返回去掉远端地址后的部分。
+func cutUpstreamEndpoint(rawURL string) (string, bool) {
+	for _, endpoint := range upstreamEndpoints() {
+		// 未配置的远端（如备用远端）只有协议部分
+		if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+			continue
+		}
+		endpoint = strings.TrimSuffix(endpoint, "/")
+		if rest, ok := strings.CutPrefix(rawURL, endpoint); ok && (rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "?")) {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+func isUpstreamURL(rawURL string) bool {
+	_, ok := cutUpstreamEndpoint(rawURL)
+	return ok
+}
+
 func IsInnerDomain(url string) bool {
-	return !strings.Contains(url, consts.Huggingface) && !strings.Contains(url, consts.Hfmirror)
+	return !strings.Contains(url, consts.Huggingface) && !strings.Contains(url, consts.Hfmirror) && !config.SysConfig.IsUpstreamEndpoint(url)
+}
+
+// RequestBaseURL 返回客户端访问镜像的地址：开启forwardedHost时优先使用反向代理传递的地址，其次为publicDomain，最后为请求的Host。
+func RequestBaseURL(c echo.Context) string {
+	if config.SysConfig.Scheduler.ForwardedHost {
+		if host := firstHeaderValue(c.Request().Header.Get("X-Forwarded-Host")); host != "" {
+			return fmt.Sprintf("%s://%s", c.Scheme(), host)
+		}
+	}
+	if domain := config.SysConfig.Scheduler.PublicDomain; domain != "" {
+		return strings.TrimSuffix(domain, "/")
+	}
+	return fmt.Sprintf("%s://%s", c.Scheme(), c.Request().Host)
+}
+
+// DownloadBaseURL 返回文件下载链接的地址，该仓库类型配置了CDN域名时使用CDN域名。
+func DownloadBaseURL(c echo.Context, repoType string) string {
+	if domain := config.SysConfig.Scheduler.CdnDomains[repoType]; domain != "" {
+		return strings.TrimSuffix(domain, "/")
+	}
+	return RequestBaseURL(c)
+}
+
+// 经过多级代理时头部为逗号分隔的列表，第一个为客户端访问的地址。
+func firstHeaderValue(value string) string {
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
 }
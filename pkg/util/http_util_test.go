@@ -0,0 +1,30 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestResponseStreamStatus(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Download.RespChunkSize = 4
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	headers := map[string]string{"content-range": "bytes 6-10/11"}
+	if err := ResponseStreamStatus(context.Background(), c, "a.bin", http.StatusPartialContent, headers, strings.NewReader("world"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "world" || rec.Header().Get("Content-Range") != "bytes 6-10/11" {
+		t.Fatalf("unexpected response %d %q %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
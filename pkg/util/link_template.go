@@ -0,0 +1,98 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"net"
+	"strings"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 文件下载链接模板支持的占位符：
+//
+//	{base}      默认的下载地址，即cdnDomains、X-Forwarded-Host、publicDomain或请求的Host
+//	{scheme}    请求的协议，经反向代理时为X-Forwarded-Proto
+//	{host}      客户端访问的主机及端口，{hostname}、{port}为其中的主机名和端口
+//	{prefix}    路径前缀，来自scheduler.pathPrefix或反向代理传递的X-Forwarded-Prefix
+//	{node}      当前节点的instanceId，用于为每个节点配置独立的域名
+//	{repoType}、{orgRepo}、{revision}、{path}
+const defaultLinkTemplate = "{base}{prefix}/{repoType}/{orgRepo}/resolve/{revision}/{path}"
+
+// FileLinkFunc 按scheduler.linkTemplate生成文件下载链接，同一版本的文件共用占位符的取值，返回按文件路径生成链接的函数。
+// 先替换{path}以外的占位符，文件路径中的花括号不会被当作占位符。
+func FileLinkFunc(c echo.Context, repoType, orgRepo, revision string) func(filePath string) string {
+	template := config.SysConfig.Scheduler.LinkTemplate
+	if template == "" {
+		template = defaultLinkTemplate
+	}
+	host := requestHost(c)
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, ""
+	}
+	prefix := PathPrefix(c)
+	replacer := strings.NewReplacer(
+		"{base}", DownloadBaseURL(c, repoType),
+		"{scheme}", c.Scheme(),
+		"{host}", host,
+		"{hostname}", hostname,
+		"{port}", port,
+		"{prefix}", prefix,
+		"{node}", config.SysConfig.Scheduler.Discovery.InstanceId,
+		"{repoType}", repoType,
+		"{orgRepo}", orgRepo,
+		"{revision}", revision,
+	)
+	template = replacer.Replace(template)
+	return func(filePath string) string {
+		return strings.ReplaceAll(template, "{path}", filePath)
+	}
+}
+
+// requestHost 客户端访问的主机，开启forwardedHost时优先使用X-Forwarded-Host。
+func requestHost(c echo.Context) string {
+	if config.SysConfig.Scheduler.ForwardedHost {
+		if host := firstHeaderValue(c.Request().Header.Get("X-Forwarded-Host")); host != "" {
+			return host
+		}
+	}
+	return c.Request().Host
+}
+
+// PathPrefix 返回镜像在反向代理后的路径前缀，以"/"开头且不以"/"结尾，没有前缀时为空。
+func PathPrefix(c echo.Context) string {
+	if config.SysConfig.Scheduler.ForwardedHost {
+		if v := firstHeaderValue(c.Request().Header.Get("X-Forwarded-Prefix")); v != "" {
+			return normalizePrefix(v)
+		}
+	}
+	return BasePath()
+}
+
+// BasePath 返回配置的全局路径前缀，以"/"开头且不以"/"结尾，未配置时为空。
+func BasePath() string {
+	return normalizePrefix(config.SysConfig.Scheduler.PathPrefix)
+}
+
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
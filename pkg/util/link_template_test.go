@@ -0,0 +1,66 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestFileLinkFunc(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	scheduler := config.SysConfig.Scheduler
+	defer func() { config.SysConfig.Scheduler = scheduler }()
+
+	newContext := func(header map[string]string) echo.Context {
+		req := httptest.NewRequest(http.MethodGet, "http://mirror.local:8090/api/models/org/repo/files/main/", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		return echo.New().NewContext(req, httptest.NewRecorder())
+	}
+	cases := []struct {
+		name      string
+		scheduler config.Scheduler
+		header    map[string]string
+		want      string
+	}{
+		{
+			name: "default",
+			want: "http://mirror.local:8090/models/org/repo/resolve/abc/dir/a.bin",
+		},
+		{
+			name:      "path prefix",
+			scheduler: config.Scheduler{PublicDomain: "https://hf.example.com/", PathPrefix: "mirror/"},
+			want:      "https://hf.example.com/mirror/models/org/repo/resolve/abc/dir/a.bin",
+		},
+		{
+			name:      "forwarded prefix",
+			scheduler: config.Scheduler{ForwardedHost: true},
+			header:    map[string]string{"X-Forwarded-Host": "gw.example.com", "X-Forwarded-Proto": "https", "X-Forwarded-Prefix": "/hf"},
+			want:      "https://gw.example.com/hf/models/org/repo/resolve/abc/dir/a.bin",
+		},
+		{
+			name: "template",
+			scheduler: config.Scheduler{
+				LinkTemplate: "{scheme}://{node}.{hostname}:9000{prefix}/{repoType}/{orgRepo}/resolve/{revision}/{path}",
+				PathPrefix:   "/hf",
+				Discovery:    config.Discovery{InstanceId: "node1"},
+			},
+			want: "http://node1.mirror.local:9000/hf/models/org/repo/resolve/abc/dir/a.bin",
+		},
+	}
+	for _, tc := range cases {
+		config.SysConfig.Scheduler = tc.scheduler
+		link := FileLinkFunc(newContext(tc.header), "models", "org/repo", "abc")
+		if got := link("dir/a.bin"); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
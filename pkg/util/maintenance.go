@@ -0,0 +1,48 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"errors"
+
+	"dingospeed/pkg/config"
+	myerr "dingospeed/pkg/error"
+)
+
+const defaultMaintenanceMessage = "服务维护中，暂停从远端拉取，仅提供已缓存的内容"
+
+// MaintenanceMessage 维护模式下返回给客户端的提示，未设置原因时使用默认提示。
+func MaintenanceMessage() string {
+	if msg := config.SysConfig.GetMaintenance().Message; msg != "" {
+		return defaultMaintenanceMessage + ": " + msg
+	}
+	return defaultMaintenanceMessage
+}
+
+func MaintenanceError() error {
+	return myerr.NewKind(myerr.KindUnavailable, MaintenanceMessage())
+}
+
+// OfflineMissError 离线时缓存未命中的错误，维护模式下统一改为503，便于客户端区分。
+func OfflineMissError(err error) error {
+	if config.SysConfig.InMaintenance() {
+		return MaintenanceError()
+	}
+	var e myerr.Error
+	if errors.As(err, &e) {
+		return e.WithKind(myerr.KindOfflineMiss)
+	}
+	return err
+}
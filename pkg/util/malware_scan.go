@@ -0,0 +1,234 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+)
+
+// MalwareScanner 扫描文件内容，返回是否感染及命中的特征名称。
+type MalwareScanner interface {
+	Scan(ctx context.Context, name string, r io.Reader) (bool, string, error)
+}
+
+// NewMalwareScanner 按配置创建扫描器，clamd优先，均未配置时返回nil。
+func NewMalwareScanner() MalwareScanner {
+	conf := config.SysConfig.MalwareScan
+	if conf.Clamd != "" {
+		return &clamdScanner{addr: conf.Clamd}
+	}
+	if conf.Command != "" {
+		return &commandScanner{command: conf.Command}
+	}
+	return nil
+}
+
+// MalwareScanMatch 文件名是否需要扫描，按文件名匹配，不含目录。
+func MalwareScanMatch(fileName string) bool {
+	base := path.Base(fileName)
+	for _, pattern := range config.SysConfig.GetMalwareScanInclude() {
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// clamdScanner 通过clamd的INSTREAM命令扫描，地址为unix://或tcp://。
+type clamdScanner struct {
+	addr string
+}
+
+const clamdChunkSize = 64 << 10
+
+func (s *clamdScanner) Scan(ctx context.Context, name string, r io.Reader) (bool, string, error) {
+	network, address := "tcp", s.addr
+	if addr, ok := strings.CutPrefix(s.addr, "unix://"); ok {
+		network, address = "unix", addr
+	} else if addr, ok = strings.CutPrefix(s.addr, "tcp://"); ok {
+		address = addr
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return false, "", fmt.Errorf("connect clamd %s err.%v", s.addr, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return false, "", err
+	}
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err = conn.Write(size); err != nil {
+				return false, "", err
+			}
+			if _, err = conn.Write(buf[:n]); err != nil {
+				return false, "", err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return false, "", rerr
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err = conn.Write(size); err != nil {
+		return false, "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return false, "", fmt.Errorf("read clamd reply err.%v", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply 解析clamd的响应，如"stream: OK"、"stream: Eicar-Signature FOUND"。
+func parseClamdReply(reply string) (bool, string, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	result, _ := strings.CutPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return false, "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return true, strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return false, "", fmt.Errorf("clamd: %s", reply)
+	}
+}
+
+// commandScanner 执行外部扫描命令，退出码0为正常，1为感染，标准输出作为特征名称。
+type commandScanner struct {
+	command string
+}
+
+func (s *commandScanner) Scan(ctx context.Context, name string, r io.Reader) (bool, string, error) {
+	command := s.command
+	var stdin io.Reader = r
+	if strings.Contains(command, "{file}") {
+		// 部分扫描工具只接受文件路径，内容先写入临时文件，保留扩展名以便工具识别格式
+		tmp, err := os.CreateTemp("", "dingospeed-scan-*"+path.Ext(name))
+		if err != nil {
+			return false, "", err
+		}
+		defer os.Remove(tmp.Name())
+		_, err = io.Copy(tmp, r)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return false, "", err
+		}
+		command, stdin = strings.ReplaceAll(command, "{file}", tmp.Name()), nil
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), "DINGOSPEED_SCAN_NAME="+name)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return false, "", nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		signature := strings.TrimSpace(stdout.String())
+		if len(signature) > 256 {
+			signature = signature[:256]
+		}
+		return true, signature, nil
+	default:
+		return false, "", fmt.Errorf("scan command err.%v, %s", err, strings.TrimSpace(stderr.String()))
+	}
+}
+
+// ScanVerdict 文件的扫描结果，按blob的etag记录在{repos}/scan下，内容相同的文件只扫描一次。
+type ScanVerdict struct {
+	Etag      string `json:"etag"`
+	Datatype  string `json:"datatype"`
+	OrgRepo   string `json:"orgRepo"`
+	FileName  string `json:"fileName"`
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature,omitempty"`
+	ScannedAt int64  `json:"scannedAt"`
+}
+
+func scanVerdictPath(etag string) string {
+	return filepath.Join(config.SysConfig.Repos(), "scan", CachePath(etag)+".json")
+}
+
+func LoadScanVerdict(etag string) (*ScanVerdict, bool) {
+	b, err := os.ReadFile(scanVerdictPath(etag))
+	if err != nil {
+		return nil, false
+	}
+	verdict := &ScanVerdict{}
+	if err = sonic.Unmarshal(b, verdict); err != nil {
+		return nil, false
+	}
+	return verdict, true
+}
+
+func SaveScanVerdict(verdict *ScanVerdict) error {
+	if verdict.ScannedAt == 0 {
+		verdict.ScannedAt = time.Now().Unix()
+	}
+	verdictPath := scanVerdictPath(verdict.Etag)
+	if err := MakeDirs(verdictPath); err != nil {
+		return err
+	}
+	return WriteDataToFile(verdictPath, verdict)
+}
+
+// QuarantineBlob 将感染的blob移入{repos}/quarantine，保留原有目录结构以便人工核查。
+func QuarantineBlob(repoType, orgRepo, etag string) (string, error) {
+	blobsFile := BlobsFile(repoType, orgRepo, etag)
+	rel, err := filepath.Rel(config.SysConfig.Repos(), blobsFile)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(config.SysConfig.Repos(), "quarantine", rel)
+	if err = MakeDirs(dst); err != nil {
+		return "", err
+	}
+	if err = os.Rename(blobsFile, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestParseClamdReply(t *testing.T) {
+	if infected, _, err := parseClamdReply("stream: OK\x00"); err != nil || infected {
+		t.Errorf("clean reply: infected=%v err=%v", infected, err)
+	}
+	infected, signature, err := parseClamdReply("stream: Eicar-Signature FOUND\x00")
+	if err != nil || !infected || signature != "Eicar-Signature" {
+		t.Errorf("infected reply: infected=%v signature=%q err=%v", infected, signature, err)
+	}
+	if _, _, err = parseClamdReply("INSTREAM size limit exceeded. ERROR\x00"); err == nil {
+		t.Error("error reply accepted")
+	}
+}
+
+func TestCommandScanner(t *testing.T) {
+	s := &commandScanner{command: `if grep -q EVIL; then echo Pickle.Evil; exit 1; fi`}
+	if infected, _, err := s.Scan(context.Background(), "model.bin", strings.NewReader("weights")); err != nil || infected {
+		t.Errorf("clean file: infected=%v err=%v", infected, err)
+	}
+	infected, signature, err := s.Scan(context.Background(), "model.bin", strings.NewReader("EVIL payload"))
+	if err != nil || !infected || signature != "Pickle.Evil" {
+		t.Errorf("infected file: infected=%v signature=%q err=%v", infected, signature, err)
+	}
+	s = &commandScanner{command: `test "$(cat {file})" = weights`}
+	if infected, _, err = s.Scan(context.Background(), "model.pkl", strings.NewReader("weights")); err != nil || infected {
+		t.Errorf("file placeholder: infected=%v err=%v", infected, err)
+	}
+	s = &commandScanner{command: "exit 2"}
+	if _, _, err = s.Scan(context.Background(), "model.bin", strings.NewReader("")); err == nil {
+		t.Error("scanner failure not reported")
+	}
+}
+
+func TestMalwareScanMatch(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	if !MalwareScanMatch("sub/pytorch_model.bin") || !MalwareScanMatch("model.pt") {
+		t.Error("pickle files not matched")
+	}
+	if MalwareScanMatch("model.safetensors") {
+		t.Error("safetensors matched")
+	}
+}
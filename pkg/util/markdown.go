@@ -0,0 +1,198 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 仅支持模型卡片中常见的markdown语法，原始html会被转义。
+var (
+	mdHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdHr        = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	mdListItem  = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(.*)$`)
+	mdTableSep  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+&#34;[^)]*&#34;)?\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+&#34;[^)]*&#34;)?\)`)
+	mdBold      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalic    = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdAutoLink  = regexp.MustCompile(`&lt;(https?://[^&\s]+)&gt;`)
+	mdSafeURL   = regexp.MustCompile(`^(https?://|mailto:|/|#|\./|[\w.-]+(/|$))`)
+	mdFenceOpen = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+-]*)")
+)
+
+// SplitFrontMatter 拆分README开头的YAML元数据，解析失败时元数据为空。
+func SplitFrontMatter(src string) (map[string]interface{}, string) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	if !strings.HasPrefix(src, "---\n") {
+		return nil, src
+	}
+	end := strings.Index(src[4:], "\n---")
+	if end < 0 {
+		return nil, src
+	}
+	front := src[4 : 4+end]
+	body := src[4+end+4:]
+	if idx := strings.IndexByte(body, '\n'); idx >= 0 {
+		body = body[idx+1:]
+	} else {
+		body = ""
+	}
+	metadata := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(front), &metadata); err != nil {
+		return nil, body
+	}
+	return metadata, body
+}
+
+// RenderMarkdown 将markdown转换为html。
+func RenderMarkdown(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var sb strings.Builder
+	renderBlocks(&sb, lines)
+	return sb.String()
+}
+
+func renderBlocks(sb *strings.Builder, lines []string) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			sb.WriteString("<p>" + renderInline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case mdFenceOpen.MatchString(line):
+			flush()
+			m := mdFenceOpen.FindStringSubmatch(line)
+			fence := m[1]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			if m[2] != "" {
+				sb.WriteString(fmt.Sprintf("<pre><code class=\"language-%s\">", html.EscapeString(m[2])))
+			} else {
+				sb.WriteString("<pre><code>")
+			}
+			sb.WriteString(html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case mdHeading.MatchString(trimmed):
+			flush()
+			m := mdHeading.FindStringSubmatch(trimmed)
+			sb.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", len(m[1]), renderInline(m[2]), len(m[1])))
+		case mdHr.MatchString(line):
+			flush()
+			sb.WriteString("<hr>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			sb.WriteString("<blockquote>\n")
+			renderBlocks(sb, quote)
+			sb.WriteString("</blockquote>\n")
+		case mdListItem.MatchString(line):
+			flush()
+			tag := "ul"
+			if m := mdListItem.FindStringSubmatch(line); m[1] != "-" && m[1] != "*" && m[1] != "+" {
+				tag = "ol"
+			}
+			sb.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && mdListItem.MatchString(lines[i]); i++ {
+				sb.WriteString("<li>" + renderInline(mdListItem.FindStringSubmatch(lines[i])[2]) + "</li>\n")
+			}
+			i--
+			sb.WriteString("</" + tag + ">\n")
+		case strings.Contains(line, "|") && i+1 < len(lines) && mdTableSep.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			flush()
+			sb.WriteString("<table>\n<thead><tr>")
+			for _, cell := range splitTableRow(line) {
+				sb.WriteString("<th>" + renderInline(cell) + "</th>")
+			}
+			sb.WriteString("</tr></thead>\n<tbody>\n")
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|") && strings.TrimSpace(lines[i]) != ""; i++ {
+				sb.WriteString("<tr>")
+				for _, cell := range splitTableRow(lines[i]) {
+					sb.WriteString("<td>" + renderInline(cell) + "</td>")
+				}
+				sb.WriteString("</tr>\n")
+			}
+			i--
+			sb.WriteString("</tbody>\n</table>\n")
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func renderInline(text string) string {
+	// 按反引号切分，奇数段为行内代码，不做其他转换
+	parts := strings.Split(text, "`")
+	var sb strings.Builder
+	for i, part := range parts {
+		escaped := html.EscapeString(part)
+		if i%2 == 1 && i < len(parts)-1 {
+			sb.WriteString("<code>" + escaped + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			sb.WriteString("`")
+		}
+		escaped = mdImage.ReplaceAllStringFunc(escaped, func(s string) string {
+			m := mdImage.FindStringSubmatch(s)
+			return fmt.Sprintf("<img src=\"%s\" alt=\"%s\">", safeURL(m[2]), m[1])
+		})
+		escaped = mdLink.ReplaceAllStringFunc(escaped, func(s string) string {
+			m := mdLink.FindStringSubmatch(s)
+			return fmt.Sprintf("<a href=\"%s\">%s</a>", safeURL(m[2]), m[1])
+		})
+		escaped = mdAutoLink.ReplaceAllString(escaped, `<a href="$1">$1</a>`)
+		escaped = mdBold.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+		escaped = mdItalic.ReplaceAllString(escaped, "<em>$1</em>")
+		sb.WriteString(escaped)
+	}
+	return sb.String()
+}
+
+func safeURL(url string) string {
+	if mdSafeURL.MatchString(url) {
+		return url
+	}
+	return "#"
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	metadata, body := SplitFrontMatter("---\nlicense: mit\ntags:\n- text\n---\n# Title\n")
+	if metadata["license"] != "mit" {
+		t.Fatalf("unexpected metadata: %v", metadata)
+	}
+	if body != "# Title\n" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+	if metadata, body = SplitFrontMatter("# Title"); metadata != nil || body != "# Title" {
+		t.Fatalf("unexpected result: %v %q", metadata, body)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	src := "# Model\n\nSome **bold** and `code` with [link](https://hf.co).\n\n" +
+		"- a\n- b\n\n```python\nprint('<x>')\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n" +
+		"<script>alert(1)</script>\n\n[bad](javascript:alert(1))"
+	out := RenderMarkdown(src)
+	for _, want := range []string{
+		"<h1>Model</h1>",
+		"<strong>bold</strong>",
+		"<code>code</code>",
+		`<a href="https://hf.co">link</a>`,
+		"<ul>\n<li>a</li>\n<li>b</li>\n</ul>",
+		`<pre><code class="language-python">print(&#39;&lt;x&gt;&#39;)</code></pre>`,
+		"<th>a</th><th>b</th>",
+		"<td>1</td><td>2</td>",
+		"&lt;script&gt;",
+		`<a href="#">bad</a>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
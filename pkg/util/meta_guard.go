@@ -0,0 +1,222 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"dingospeed/pkg/common"
+	myerr "dingospeed/pkg/error"
+)
+
+// 远端返回的元数据在写入缓存前校验，避免将HTML错误页、认证门户页面或被截断的JSON缓存下来，
+// 之后解析元数据及客户端读取时才出错。
+
+const metaPeekSize = 512
+
+func metaSchemaError(format string, args ...interface{}) error {
+	return myerr.NewAppendCode(http.StatusBadGateway, "invalid upstream meta: "+fmt.Sprintf(format, args...))
+}
+
+// CheckMetaResponse 在读取响应体之前校验Content-Type及第一个非空白字符，不符合JSON对象时直接返回，
+// 不转发给客户端；body需为bufio.Reader以便预读。
+func CheckMetaResponse(headers map[string]string, body *bufio.Reader) error {
+	if err := checkJSONContentType(headers); err != nil {
+		return err
+	}
+	return peekJSON(body, '{')
+}
+
+func checkJSONContentType(headers map[string]string) error {
+	contentType := headers["content-type"]
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		return metaSchemaError("unexpected content type %s", contentType)
+	}
+	return nil
+}
+
+func peekJSON(body *bufio.Reader, open byte) error {
+	head, err := body.Peek(metaPeekSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return err
+	}
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) == 0 {
+		return metaSchemaError("empty body")
+	}
+	if head[0] != open {
+		return metaSchemaError("body starts with %q", head[:min(len(head), 16)])
+	}
+	return nil
+}
+
+// GuardMetaStream 返回透传body的Reader，同时校验内容为包含requiredKeys的JSON对象。
+// 校验失败时最后一次读取返回错误而非io.EOF，写入缓存的临时文件随之放弃；调用方读取结束后需Close。
+func GuardMetaStream(body io.Reader, requiredKeys ...string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	g := &metaGuard{body: body, pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := validateJSONObject(pr, requiredKeys)
+		// 校验提前结束时继续消费剩余数据，避免阻塞透传
+		_, _ = io.Copy(io.Discard, pr)
+		g.done <- err
+	}()
+	return g
+}
+
+type metaGuard struct {
+	body   io.Reader
+	pw     *io.PipeWriter
+	done   chan error
+	closed bool
+	err    error
+}
+
+func (g *metaGuard) Read(p []byte) (int, error) {
+	n, err := g.body.Read(p)
+	if n > 0 && !g.closed {
+		_, _ = g.pw.Write(p[:n])
+	}
+	if err == nil {
+		return n, nil
+	}
+	if errors.Is(err, io.EOF) {
+		if !g.closed {
+			g.closed = true
+			_ = g.pw.Close()
+			g.err = <-g.done
+		}
+		if g.err != nil {
+			return n, g.err
+		}
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func (g *metaGuard) Close() error {
+	if !g.closed {
+		g.closed = true
+		_ = g.pw.CloseWithError(io.ErrClosedPipe)
+		<-g.done
+	}
+	return nil
+}
+
+// CheckMetaBody 校验已完整读取的响应体为包含requiredKeys的JSON对象。
+func CheckMetaBody(headers map[string]string, body []byte, requiredKeys ...string) error {
+	if err := checkJSONContentType(headers); err != nil {
+		return err
+	}
+	return validateJSONObject(bytes.NewReader(body), requiredKeys)
+}
+
+// CheckPathsInfoBody 校验paths-info响应体为文件信息数组，每项须有路径及类型，文件须有oid。
+func CheckPathsInfoBody(headers map[string]string, body []byte) error {
+	if err := checkJSONContentType(headers); err != nil {
+		return err
+	}
+	if err := peekJSON(bufio.NewReader(bytes.NewReader(body)), '['); err != nil {
+		return err
+	}
+	pathsInfos := make([]*common.PathsInfo, 0)
+	if err := json.Unmarshal(body, &pathsInfos); err != nil {
+		return metaSchemaError("%v", err)
+	}
+	for _, pathsInfo := range pathsInfos {
+		if pathsInfo == nil || pathsInfo.Path == "" {
+			return metaSchemaError("paths-info entry without path")
+		}
+		switch pathsInfo.Type {
+		case "file":
+			if pathsInfo.Oid == "" || pathsInfo.Size < 0 {
+				return metaSchemaError("paths-info file %s without oid or size", pathsInfo.Path)
+			}
+		case "directory":
+		default:
+			return metaSchemaError("paths-info entry %s with type %q", pathsInfo.Path, pathsInfo.Type)
+		}
+	}
+	return nil
+}
+
+// validateJSONObject 逐个读取token校验JSON语法，只记录顶层对象的字段名，不在内存中保留内容。
+func validateJSONObject(r io.Reader, requiredKeys []string) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return metaSchemaError("%v", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return metaSchemaError("body is not a json object")
+	}
+	keys := make(map[string]struct{})
+	for dec.More() {
+		if tok, err = dec.Token(); err != nil {
+			return metaSchemaError("%v", err)
+		}
+		key, _ := tok.(string)
+		keys[key] = struct{}{}
+		if err = skipJSONValue(dec); err != nil {
+			return metaSchemaError("%v", err)
+		}
+	}
+	if _, err = dec.Token(); err != nil {
+		return metaSchemaError("%v", err)
+	}
+	if _, err = dec.Token(); !errors.Is(err, io.EOF) {
+		return metaSchemaError("unexpected data after json object")
+	}
+	for _, key := range requiredKeys {
+		if _, ok := keys[key]; !ok {
+			return metaSchemaError("missing field %s", key)
+		}
+	}
+	return nil
+}
+
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGuardMetaStream(t *testing.T) {
+	cases := []struct {
+		body string
+		ok   bool
+	}{
+		{`{"id":"org/repo","sha":"abc","siblings":[{"rfilename":"a.bin"}]}`, true},
+		{` {"id":"org/repo","sha":"abc"}` + "\n", true},
+		{`{"id":"org/repo","siblings":[]}`, false},
+		{`{"id":"org/repo","sha":"abc","siblings":[{"rfilename":`, false},
+		{`{"id":"org/repo","sha":"abc"}<html>`, false},
+		{`["id","sha"]`, false},
+	}
+	for _, tc := range cases {
+		guard := GuardMetaStream(strings.NewReader(tc.body), "id", "sha")
+		data, err := io.ReadAll(guard)
+		guard.Close()
+		if string(data) != tc.body {
+			t.Errorf("%s: body not passed through", tc.body)
+		}
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: err %v, want ok %v", tc.body, err, tc.ok)
+		}
+	}
+
+	// 未读完即关闭不阻塞
+	guard := GuardMetaStream(strings.NewReader(`{"id":"org/repo","sha":"abc"}`), "id")
+	buf := make([]byte, 4)
+	_, _ = guard.Read(buf)
+	guard.Close()
+}
+
+func TestCheckMetaResponse(t *testing.T) {
+	html := "<!DOCTYPE html><html><body>Sign in to continue</body></html>"
+	if err := CheckMetaResponse(map[string]string{"content-type": "text/html; charset=utf-8"}, bufio.NewReader(strings.NewReader(`{}`))); err == nil {
+		t.Error("html content type should be rejected")
+	}
+	if err := CheckMetaResponse(map[string]string{}, bufio.NewReader(strings.NewReader(html))); err == nil {
+		t.Error("html body should be rejected")
+	}
+	if err := CheckMetaResponse(map[string]string{"content-type": "application/json"}, bufio.NewReader(strings.NewReader("\n {\"id\":1}"))); err != nil {
+		t.Errorf("json body rejected: %v", err)
+	}
+}
+
+func TestCheckPathsInfoBody(t *testing.T) {
+	cases := map[string]bool{
+		`[{"type":"file","oid":"abc","size":3,"path":"a.bin"},{"type":"directory","oid":"def","size":0,"path":"dir"}]`: true,
+		`[]`: true,
+		`[{"type":"file","size":3,"path":"a.bin"}]`:   false,
+		`[{"type":"file","oid":"abc","size":3}]`:      false,
+		`{"error":"Repository not found"}`:            false,
+		`[{"type":"file","oid":"abc","size":3,"path"`: false,
+	}
+	for body, ok := range cases {
+		if err := CheckPathsInfoBody(map[string]string{}, []byte(body)); (err == nil) != ok {
+			t.Errorf("%s: err %v, want ok %v", body, err, ok)
+		}
+	}
+}
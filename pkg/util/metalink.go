@@ -0,0 +1,79 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+type MetalinkFile struct {
+	Name   string
+	Size   int64 // 小于0表示未知
+	Sha256 string
+	Url    string
+}
+
+type metalink struct {
+	XMLName xml.Name        `xml:"urn:ietf:params:xml:ns:metalink metalink"`
+	Files   []*metalinkFile `xml:"file"`
+}
+
+type metalinkFile struct {
+	Name string        `xml:"name,attr"`
+	Size int64         `xml:"size,omitempty"`
+	Hash *metalinkHash `xml:"hash,omitempty"`
+	Url  string        `xml:"url"`
+}
+
+type metalinkHash struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// BuildMetalink 生成RFC 5854格式的metalink文件。
+func BuildMetalink(files []*MetalinkFile) ([]byte, error) {
+	ml := &metalink{Files: make([]*metalinkFile, 0, len(files))}
+	for _, file := range files {
+		f := &metalinkFile{Name: file.Name, Url: file.Url}
+		if file.Size > 0 {
+			f.Size = file.Size
+		}
+		if file.Sha256 != "" {
+			f.Hash = &metalinkHash{Type: "sha-256", Value: file.Sha256}
+		}
+		ml.Files = append(ml.Files, f)
+	}
+	content, err := xml.MarshalIndent(ml, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+// BuildAria2Input 生成aria2c -i使用的输入文件，每个文件一行地址，缩进行为该文件的选项。
+func BuildAria2Input(files []*MetalinkFile) []byte {
+	var sb strings.Builder
+	for _, file := range files {
+		sb.WriteString(file.Url)
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("  out=%s\n", file.Name))
+		if file.Sha256 != "" {
+			sb.WriteString(fmt.Sprintf("  checksum=sha-256=%s\n", file.Sha256))
+		}
+	}
+	return []byte(sb.String())
+}
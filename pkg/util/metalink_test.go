@@ -0,0 +1,29 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMetalink(t *testing.T) {
+	files := []*MetalinkFile{
+		{Name: "config.json", Size: 10, Url: "http://mirror/models/a/b/resolve/sha/config.json"},
+		{Name: "model.safetensors", Size: 100, Sha256: "abc", Url: "http://mirror/models/a/b/resolve/sha/model.safetensors"},
+	}
+	content, err := BuildMetalink(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(content)
+	for _, want := range []string{`<metalink xmlns="urn:ietf:params:xml:ns:metalink">`, `<file name="config.json">`, `<hash type="sha-256">abc</hash>`, `<size>100</size>`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("metalink missing %s:\n%s", want, s)
+		}
+	}
+	aria2 := string(BuildAria2Input(files))
+	want := "http://mirror/models/a/b/resolve/sha/config.json\n  out=config.json\n" +
+		"http://mirror/models/a/b/resolve/sha/model.safetensors\n  out=model.safetensors\n  checksum=sha-256=abc\n"
+	if aria2 != want {
+		t.Errorf("got %q, want %q", aria2, want)
+	}
+}
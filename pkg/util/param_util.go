@@ -0,0 +1,101 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// 允许包含"/"的路径参数：文件路径，以及refs/pr/1形式的版本号
+var multiSegmentParams = map[string]bool{
+	"filePath":   true,
+	"commit":     true,
+	"revision":   true,
+	"collection": true, // collection的slug为org/name-id
+	"*":          true, // 通配路由匹配的剩余路径
+}
+
+// ValidatePathParam 校验拼接进本地缓存路径的参数，拒绝绝对路径、".."及控制字符。
+func ValidatePathParam(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("%s contains control characters", name)
+		}
+	}
+	if strings.HasPrefix(value, "/") || strings.HasPrefix(value, "\\") {
+		return fmt.Errorf("%s must not be an absolute path", name)
+	}
+	multiSegment := multiSegmentParams[name]
+	if !multiSegment && strings.Contains(value, "/") {
+		return fmt.Errorf("%s must not contain '/'", name)
+	}
+	for _, segment := range strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." || (!multiSegment && segment == ".") {
+			return fmt.Errorf("%s must not contain '%s'", name, segment)
+		}
+	}
+	return nil
+}
+
+// 影响仓库信息响应内容的查询参数，其余参数（如时间戳）不参与缓存区分
+var metaQueryParams = []string{"blobs", "expand", "expand[]", "securityStatus"}
+
+// MetaQuery 从仓库信息请求中提取影响响应内容的查询参数，按参数名及取值排序后编码，
+// 同一组参数的不同写法得到相同的结果，无相关参数时返回空字符串。
+func MetaQuery(params url.Values) string {
+	query := url.Values{}
+	for _, name := range metaQueryParams {
+		values := slices.Clone(params[name])
+		if len(values) == 0 {
+			continue
+		}
+		slices.Sort(values)
+		query[name] = slices.Compact(values)
+	}
+	return query.Encode()
+}
+
+// 影响datasets-server响应内容的查询参数
+var datasetsServerParams = []string{"dataset", "config", "split", "offset", "length", "where", "orderby", "query"}
+
+// DatasetsServerQuery 提取datasets-server请求中影响响应内容的查询参数并编码，与MetaQuery一样忽略无关参数。
+func DatasetsServerQuery(params url.Values) string {
+	query := url.Values{}
+	for _, name := range datasetsServerParams {
+		if value := params.Get(name); value != "" {
+			query.Set(name, value)
+		}
+	}
+	return query.Encode()
+}
+
+// NormalizeRevision 将refs%2Fpr%2F5形式的版本号还原为refs/pr/5，缓存键及本地目录统一使用还原后的形式。
+func NormalizeRevision(revision string) string {
+	if v, err := url.PathUnescape(revision); err == nil {
+		return v
+	}
+	return revision
+}
+
+// EscapeRevision 拼接远端地址时将版本号中的"/"转义，如refs/pr/5转为refs%2Fpr%2F5。
+func EscapeRevision(revision string) string {
+	return url.PathEscape(revision)
+}
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestValidatePathParam(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"filePath", "config.json", true},
+		{"filePath", "sub/dir/model.safetensors", true},
+		{"filePath", "", true},
+		{"filePath", "../etc/passwd", false},
+		{"filePath", "a/../../b", false},
+		{"filePath", "a\\..\\b", false},
+		{"filePath", "/etc/passwd", false},
+		{"filePath", "a\x00b", false},
+		{"filePath", "a\nb", false},
+		{"commit", "refs/pr/1", true},
+		{"commit", "main", true},
+		{"revision", "..", false},
+		{"org", "Qwen", true},
+		{"repo", "Qwen2.5-7B", true},
+		{"repo", "..", false},
+		{"repo", ".", false},
+		{"org", "a/b", false},
+	}
+	for _, tc := range cases {
+		err := ValidatePathParam(tc.name, tc.value)
+		if (err == nil) != tc.ok {
+			t.Errorf("ValidatePathParam(%s, %q) err = %v, want ok %v", tc.name, tc.value, err, tc.ok)
+		}
+	}
+}
+
+func TestMetaQuery(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"", ""},
+		{"foo=bar", ""},
+		{"blobs=true", "blobs=true"},
+		{"expand[]=sha&expand[]=downloads&blobs=true", "blobs=true&expand%5B%5D=downloads&expand%5B%5D=sha"},
+		{"expand[]=downloads&expand[]=sha&expand[]=sha&t=1", "expand%5B%5D=downloads&expand%5B%5D=sha"},
+	}
+	for _, tc := range cases {
+		params, err := url.ParseQuery(tc.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := MetaQuery(params); got != tc.want {
+			t.Errorf("MetaQuery(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestDatasetsServerQuery(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"dataset=squad", "dataset=squad"},
+		{"split=train&dataset=org/ds&config=default&offset=0&length=100&_=1", "config=default&dataset=org%2Fds&length=100&offset=0&split=train"},
+		{"dataset=squad&where=", "dataset=squad"},
+	}
+	for _, tc := range cases {
+		params, err := url.ParseQuery(tc.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := DatasetsServerQuery(params); got != tc.want {
+			t.Errorf("DatasetsServerQuery(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeRevision(t *testing.T) {
+	for _, revision := range []string{"refs%2Fpr%2F5", "refs/pr/5"} {
+		if got := NormalizeRevision(revision); got != "refs/pr/5" {
+			t.Errorf("NormalizeRevision(%q) = %q", revision, got)
+		}
+		if got := EscapeRevision(NormalizeRevision(revision)); got != "refs%2Fpr%2F5" {
+			t.Errorf("EscapeRevision(%q) = %q", revision, got)
+		}
+	}
+	if got := NormalizeRevision("main"); got != "main" {
+		t.Errorf("NormalizeRevision(main) = %q", got)
+	}
+}
@@ -0,0 +1,381 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pickle导入扫描：只解析操作码，不执行反序列化，收集GLOBAL/INST/STACK_GLOBAL引用的模块及名称。
+// PyTorch新格式的文件为zip，扫描其中的*.pkl；旧格式为多个连续的pickle后接张量数据。
+
+const (
+	PickleSafe      = "safe"
+	PickleDangerous = "dangerous"
+	PickleUnknown   = "unknown"
+)
+
+// 单个pickle流最多解析的字节数，权重数据不在pickle中，正常的pickle远小于该值
+const maxPickleSize = 256 << 20
+
+var pickleExtensions = []string{".bin", ".pt", ".pth", ".pkl", ".pickle", ".ckpt"}
+
+type PickleImport struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	Safety string `json:"safety"`
+}
+
+// IsPickleFile 按扩展名判断文件是否可能为pickle格式。
+func IsPickleFile(fileName string) bool {
+	ext := strings.ToLower(path.Ext(fileName))
+	for _, e := range pickleExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// 常见的权重文件引用的安全导入，按模块匹配全部名称时name为*
+var pickleSafeImports = map[string][]string{
+	"collections":            {"OrderedDict", "defaultdict"},
+	"torch":                  {"Size", "BFloat16Storage", "BoolStorage", "ByteStorage", "CharStorage", "ComplexDoubleStorage", "ComplexFloatStorage", "DoubleStorage", "FloatStorage", "HalfStorage", "IntStorage", "LongStorage", "ShortStorage", "QInt32Storage", "QInt8Storage", "QUInt8Storage", "QUInt4x2Storage", "QUInt2x4Storage", "bfloat16", "float16", "float32", "float64", "int8", "int16", "int32", "int64", "uint8", "bool", "device", "dtype", "float8_e4m3fn", "float8_e5m2"},
+	"torch._utils":           {"_rebuild_tensor", "_rebuild_tensor_v2", "_rebuild_tensor_v3", "_rebuild_parameter", "_rebuild_parameter_with_state", "_rebuild_qtensor", "_rebuild_sparse_tensor", "_rebuild_meta_tensor_no_storage", "_rebuild_device_tensor_from_numpy"},
+	"torch._tensor":          {"_rebuild_from_type_v2"},
+	"torch.nn.parameter":     {"Parameter"},
+	"torch.storage":          {"_load_from_bytes", "UntypedStorage", "TypedStorage"},
+	"numpy":                  {"dtype", "ndarray"},
+	"numpy.core.multiarray":  {"_reconstruct", "scalar"},
+	"numpy._core.multiarray": {"_reconstruct", "scalar"},
+	"_codecs":                {"encode"},
+	"builtins":               {"set", "frozenset", "slice", "range", "complex", "bytearray", "dict", "list", "tuple", "int", "float", "bool", "str", "object"},
+	"__builtin__":            {"set", "frozenset", "slice", "complex", "bytearray", "dict", "list", "tuple", "int", "float", "bool", "str", "object"},
+}
+
+// 可执行代码、访问文件系统或网络的导入，按模块匹配全部名称时name为*
+var pickleDangerousImports = map[string][]string{
+	"builtins":    {"eval", "exec", "execfile", "compile", "open", "getattr", "setattr", "delattr", "__import__", "globals", "locals", "breakpoint", "input", "apply"},
+	"__builtin__": {"eval", "exec", "execfile", "compile", "open", "file", "getattr", "setattr", "delattr", "__import__", "globals", "locals", "input", "apply"},
+	"os":          {"*"},
+	"posix":       {"*"},
+	"nt":          {"*"},
+	"subprocess":  {"*"},
+	"sys":         {"*"},
+	"socket":      {"*"},
+	"shutil":      {"*"},
+	"runpy":       {"*"},
+	"webbrowser":  {"*"},
+	"pty":         {"*"},
+	"ctypes":      {"*"},
+	"importlib":   {"*"},
+	"pickle":      {"*"},
+	"_pickle":     {"*"},
+	"marshal":     {"*"},
+	"code":        {"*"},
+	"codeop":      {"*"},
+	"requests":    {"*"},
+	"urllib":      {"*"},
+	"httplib":     {"*"},
+	"http.client": {"*"},
+	"aiohttp":     {"*"},
+	"asyncio":     {"*"},
+	"bdb":         {"*"},
+	"pdb":         {"*"},
+	"operator":    {"attrgetter", "methodcaller"},
+	"functools":   {"partial", "reduce"},
+}
+
+func matchPickleImport(rules map[string][]string, module, name string) bool {
+	modules := []string{module}
+	// 按顶层包匹配，如os.path命中os
+	if i := strings.Index(module, "."); i > 0 {
+		modules = append(modules, module[:i])
+	}
+	for _, m := range modules {
+		for _, n := range rules[m] {
+			if n == "*" || (n == name && m == module) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pickleImportSafety(module, name string) string {
+	if matchPickleImport(pickleDangerousImports, module, name) {
+		return PickleDangerous
+	}
+	if matchPickleImport(pickleSafeImports, module, name) {
+		return PickleSafe
+	}
+	return PickleUnknown
+}
+
+// ScanPickleImports 扫描pickle文件或PyTorch的zip文件，返回按模块、名称排序的导入列表。
+func ScanPickleImports(r io.ReaderAt, size int64) ([]*PickleImport, error) {
+	found := make(map[[2]string]bool)
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if bytes.Equal(magic, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !strings.HasSuffix(f.Name, ".pkl") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = scanPickleStream(bufio.NewReader(io.LimitReader(rc, maxPickleSize)), found)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f.Name, err)
+			}
+		}
+	} else if err := scanPickleStream(bufio.NewReader(io.NewSectionReader(r, 0, min(size, maxPickleSize))), found); err != nil {
+		return nil, err
+	}
+	imports := make([]*PickleImport, 0, len(found))
+	for k := range found {
+		imports = append(imports, &PickleImport{Module: k[0], Name: k[1], Safety: pickleImportSafety(k[0], k[1])})
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		if imports[i].Module != imports[j].Module {
+			return imports[i].Module < imports[j].Module
+		}
+		return imports[i].Name < imports[j].Name
+	})
+	return imports, nil
+}
+
+// scanPickleStream 解析连续的pickle，某个pickle结束后若后续不是新的pickle（PROTO开头）则停止。
+func scanPickleStream(r *bufio.Reader, found map[[2]string]bool) error {
+	for {
+		if err := scanPickle(r, found); err != nil {
+			return err
+		}
+		next, err := r.Peek(1)
+		if err != nil || next[0] != 0x80 {
+			return nil
+		}
+	}
+}
+
+// scanPickle 解析单个pickle直到STOP，字符串值及memo用于还原STACK_GLOBAL的参数。
+func scanPickle(r *bufio.Reader, found map[[2]string]bool) error {
+	var (
+		stack []string // 最近压栈的字符串，非字符串以空值占位
+		memo  = make(map[uint64]string)
+	)
+	push := func(v string) {
+		if len(stack) >= 2 {
+			stack = stack[1:]
+		}
+		stack = append(stack, v)
+	}
+	last := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		return stack[len(stack)-1]
+	}
+	readN := func(n uint64) ([]byte, error) {
+		if n > maxPickleSize {
+			return nil, fmt.Errorf("argument size %d is too large", n)
+		}
+		buf := make([]byte, n)
+		_, err := io.ReadFull(r, buf)
+		return buf, err
+	}
+	readUint := func(n int) (uint64, error) {
+		b, err := readN(uint64(n))
+		if err != nil {
+			return 0, err
+		}
+		b = append(b, make([]byte, 8-n)...)
+		return binary.LittleEndian.Uint64(b), nil
+	}
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimSuffix(line, "\n"), err
+	}
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("unexpected end of pickle")
+			}
+			return err
+		}
+		switch op {
+		case '.': // STOP
+			return nil
+		case 'c', 'i': // GLOBAL、INST
+			module, err := readLine()
+			if err != nil {
+				return err
+			}
+			name, err := readLine()
+			if err != nil {
+				return err
+			}
+			found[[2]string{module, name}] = true
+			push("")
+		case 0x93: // STACK_GLOBAL
+			module, name := "", ""
+			if len(stack) == 2 {
+				module, name = stack[0], stack[1]
+			}
+			if module == "" || name == "" {
+				module, name = "unknown", "unknown"
+			}
+			found[[2]string{module, name}] = true
+			push("")
+		case 'S', 'V': // STRING、UNICODE
+			line, err := readLine()
+			if err != nil {
+				return err
+			}
+			if op == 'S' {
+				if unquoted, err := strconv.Unquote(strings.ReplaceAll(line, "'", "\"")); err == nil {
+					line = unquoted
+				}
+			}
+			push(line)
+		case 'X', 'T', 'B': // BINUNICODE、BINSTRING、BINBYTES
+			n, err := readUint(4)
+			if err != nil {
+				return err
+			}
+			b, err := readN(n)
+			if err != nil {
+				return err
+			}
+			push(string(b))
+		case 0x8c, 'U', 'C': // SHORT_BINUNICODE、SHORT_BINSTRING、SHORT_BINBYTES
+			n, err := readUint(1)
+			if err != nil {
+				return err
+			}
+			b, err := readN(n)
+			if err != nil {
+				return err
+			}
+			push(string(b))
+		case 0x8d, 0x8e, 0x96: // BINUNICODE8、BINBYTES8、BYTEARRAY8
+			n, err := readUint(8)
+			if err != nil {
+				return err
+			}
+			b, err := readN(n)
+			if err != nil {
+				return err
+			}
+			push(string(b))
+		case 0x94: // MEMOIZE
+			memo[uint64(len(memo))] = last()
+		case 'p', 'g': // PUT、GET
+			line, err := readLine()
+			if err != nil {
+				return err
+			}
+			idx, _ := strconv.ParseUint(line, 10, 64)
+			if op == 'p' {
+				memo[idx] = last()
+			} else {
+				push(memo[idx])
+			}
+		case 'q', 'h', 'r', 'j': // BINPUT、BINGET、LONG_BINPUT、LONG_BINGET
+			size := 1
+			if op == 'r' || op == 'j' {
+				size = 4
+			}
+			idx, err := readUint(size)
+			if err != nil {
+				return err
+			}
+			if op == 'q' || op == 'r' {
+				memo[idx] = last()
+			} else {
+				push(memo[idx])
+			}
+		case 'F', 'I', 'L', 'P': // FLOAT、INT、LONG、PERSID
+			if _, err = readLine(); err != nil {
+				return err
+			}
+			push("")
+		case 'K', 0x80, 0x82: // BININT1、PROTO、EXT1
+			if _, err = readN(1); err != nil {
+				return err
+			}
+			if op != 0x80 {
+				push("")
+			}
+		case 'M', 0x83: // BININT2、EXT2
+			if _, err = readN(2); err != nil {
+				return err
+			}
+			push("")
+		case 'J', 0x84: // BININT、EXT4
+			if _, err = readN(4); err != nil {
+				return err
+			}
+			push("")
+		case 'G': // BINFLOAT
+			if _, err = readN(8); err != nil {
+				return err
+			}
+			push("")
+		case 0x95: // FRAME
+			if _, err = readN(8); err != nil {
+				return err
+			}
+		case 0x8a, 0x8b: // LONG1、LONG4
+			size := 1
+			if op == 0x8b {
+				size = 4
+			}
+			n, err := readUint(size)
+			if err != nil {
+				return err
+			}
+			if _, err = readN(n); err != nil {
+				return err
+			}
+			push("")
+		case '(', '0', '1', '2', 'N', 'Q', 'R', 'a', 'b', 'd', '}', 'e', 'l', ']', 'o', 's', 't', ')', 'u',
+			0x81, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8f, 0x90, 0x91, 0x92, 0x97, 0x98:
+			push("")
+		default:
+			return fmt.Errorf("unknown opcode 0x%02x", op)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package util
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestScanPickleImports(t *testing.T) {
+	// pickle.dumps(collections.OrderedDict(a=1), protocol=2)
+	safe := mustHex(t, "800263636f6c6c656374696f6e730a4f726465726564446963740a71002952710158010000006171024b01732e")
+	// pickle.dumps({'x': obj}, protocol=4)，obj.__reduce__返回(os.system, ('true',))
+	evil := mustHex(t, "80049526000000000000007d948c0178948c05706f736978948c0673797374656d9493948c04747275659485945294732e")
+	// pickle.dumps([collections.OrderedDict()], protocol=0)
+	proto0 := mustHex(t, "286c70300a63636f6c6c656374696f6e730a4f726465726564446963740a70310a28745270320a612e")
+
+	imports, err := ScanPickleImports(bytes.NewReader(safe), int64(len(safe)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imports) != 1 || imports[0].Module != "collections" || imports[0].Name != "OrderedDict" || imports[0].Safety != PickleSafe {
+		t.Errorf("unexpected imports %+v", imports)
+	}
+
+	imports, err = ScanPickleImports(bytes.NewReader(evil), int64(len(evil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imports) != 1 || imports[0].Module != "posix" || imports[0].Name != "system" || imports[0].Safety != PickleDangerous {
+		t.Errorf("unexpected imports %+v", imports)
+	}
+
+	imports, err = ScanPickleImports(bytes.NewReader(proto0), int64(len(proto0)))
+	if err != nil || len(imports) != 1 || imports[0].Name != "OrderedDict" {
+		t.Errorf("protocol 0: imports %+v, err %v", imports, err)
+	}
+
+	// 旧格式：多个连续的pickle后接张量数据
+	legacy := append(append(append([]byte{}, safe...), evil...), 0x00, 0x01, 0x02)
+	imports, err = ScanPickleImports(bytes.NewReader(legacy), int64(len(legacy)))
+	if err != nil || len(imports) != 2 {
+		t.Errorf("legacy: imports %+v, err %v", imports, err)
+	}
+
+	// 新格式：zip中的data.pkl
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("archive/data.pkl")
+	_, _ = w.Write(evil)
+	w, _ = zw.Create("archive/data/0")
+	_, _ = w.Write([]byte{1, 2, 3, 4})
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	imports, err = ScanPickleImports(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(imports) != 1 || imports[0].Safety != PickleDangerous {
+		t.Errorf("zip: imports %+v, err %v", imports, err)
+	}
+
+	if _, err = ScanPickleImports(bytes.NewReader([]byte{0x80, 0x02, 0xff}), 3); err == nil {
+		t.Error("invalid pickle accepted")
+	}
+}
@@ -0,0 +1,73 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// 可预览的文本文件类型，只使用此表，不按系统mime表或内容探测出html、svg等会被浏览器渲染的类型
+var previewTextTypes = map[string]string{
+	".json":          "application/json; charset=utf-8",
+	".jsonl":         "application/x-ndjson; charset=utf-8",
+	".md":            "text/markdown; charset=utf-8",
+	".txt":           "text/plain; charset=utf-8",
+	".yaml":          "text/yaml; charset=utf-8",
+	".yml":           "text/yaml; charset=utf-8",
+	".py":            "text/x-python; charset=utf-8",
+	".csv":           "text/csv; charset=utf-8",
+	".tsv":           "text/plain; charset=utf-8",
+	".toml":          "text/plain; charset=utf-8",
+	".cfg":           "text/plain; charset=utf-8",
+	".ini":           "text/plain; charset=utf-8",
+	".sh":            "text/plain; charset=utf-8",
+	".gitattributes": "text/plain; charset=utf-8",
+}
+
+// IsPreviewable 根据扩展名判断文件是否为可预览的文本文件。
+func IsPreviewable(fileName string) bool {
+	contentType := PreviewContentType(fileName, nil)
+	return strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json")
+}
+
+// PreviewContentType 按扩展名在previewTextTypes中确定内容类型，无法识别时内容为utf8文本的返回text/plain，其余为application/octet-stream。
+func PreviewContentType(fileName string, content []byte) string {
+	base := filepath.Base(fileName)
+	ext := strings.ToLower(filepath.Ext(base))
+	if strings.EqualFold(base, "README") || strings.EqualFold(base, "LICENSE") {
+		return "text/plain; charset=utf-8"
+	}
+	if contentType, ok := previewTextTypes[ext]; ok {
+		return contentType
+	}
+	if len(content) > 0 && utf8.Valid(TrimIncompleteRune(content)) && !bytes.ContainsRune(content, 0) {
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// TrimIncompleteRune 去掉截断后末尾不完整的utf8字符。
+func TrimIncompleteRune(content []byte) []byte {
+	for i := 0; i < utf8.UTFMax && i < len(content); i++ {
+		r, size := utf8.DecodeLastRune(content[:len(content)-i])
+		if r != utf8.RuneError || size > 1 {
+			return content[:len(content)-i]
+		}
+	}
+	return content
+}
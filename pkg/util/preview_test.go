@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestPreviewContentType(t *testing.T) {
+	cases := map[string]string{
+		"config.json":       "application/json; charset=utf-8",
+		"sub/README.md":     "text/markdown; charset=utf-8",
+		"tokenizer.model":   "application/octet-stream",
+		"model.safetensors": "application/octet-stream",
+		".gitattributes":    "text/plain; charset=utf-8",
+	}
+	for name, want := range cases {
+		if got := PreviewContentType(name, nil); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+	// 不在表中的类型不得返回会被浏览器渲染的类型
+	for name, content := range map[string]string{
+		"index.html": "<html><script>alert(1)</script></html>",
+		"logo.svg":   "<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>",
+		"page.xhtml": "<html></html>",
+	} {
+		if got := PreviewContentType(name, []byte(content)); got != "text/plain; charset=utf-8" {
+			t.Errorf("%s: got %s, want text/plain", name, got)
+		}
+	}
+	if got := PreviewContentType("weights.bin", []byte{0x00, 0x01, 0xff}); got != "application/octet-stream" {
+		t.Errorf("binary: got %s", got)
+	}
+	if !IsPreviewable("config.json") || IsPreviewable("model.safetensors") {
+		t.Errorf("unexpected previewable result")
+	}
+}
+
+func TestTrimIncompleteRune(t *testing.T) {
+	content := []byte("模型")
+	if got := string(TrimIncompleteRune(content[:5])); got != "模" {
+		t.Errorf("got %q", got)
+	}
+	if got := string(TrimIncompleteRune([]byte("abc"))); got != "abc" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+)
+
+// RedirectError 远端返回重定向且策略为rewrite时，将重定向返回给客户端。
+type RedirectError struct {
+	StatusCode int
+	Location   string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirect %d to %s", e.StatusCode, e.Location)
+}
+
+func NewRedirectError(statusCode int, location string) error {
+	return &RedirectError{StatusCode: statusCode, Location: RewriteLocation(location)}
+}
+
+func IsRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// RewriteLocation 将指向远端的重定向地址改写为相对路径，使客户端经由本镜像访问，指向其他地址（如CDN）时保持不变。
+func RewriteLocation(location string) string {
+	rest, ok := cutUpstreamEndpoint(location)
+	if !ok {
+		return location
+	}
+	if !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	return rest
+}
+
+// checkMetaRedirect 元数据请求按配置决定是否跟随重定向，策略为rewrite时返回重定向响应本身；仓库改名的重定向记录为别名。
+func checkMetaRedirect(req *http.Request, via []*http.Request) error {
+	detectRepoRename(req, via)
+	if config.SysConfig.GetRedirectPolicy(via[0].URL.Path) == consts.RedirectRewrite {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
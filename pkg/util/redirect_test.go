@@ -0,0 +1,86 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+)
+
+func TestRewriteLocation(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.HfScheme = "https"
+	config.SysConfig.Server.HfNetLoc = "huggingface.co"
+	config.SysConfig.Server.BpHfNetLoc = "hf-mirror.com"
+	config.SysConfig.Server.Upstreams = []config.UpstreamRule{{Endpoint: "http://hub.internal.example.com/hf/"}}
+
+	cases := map[string]string{
+		"https://huggingface.co/api/models/new/repo/revision/main": "/api/models/new/repo/revision/main",
+		"https://hf-mirror.com/api/models/new/repo?expand=sha":     "/api/models/new/repo?expand=sha",
+		"http://hub.internal.example.com/hf/api/datasets/org/ds":   "/api/datasets/org/ds",
+		"https://huggingface.co.evil.com/api/models/x":             "https://huggingface.co.evil.com/api/models/x",
+		"https://cdn-lfs.hf.co/repos/ab/cd/sha?X-Amz-Signature=1":  "https://cdn-lfs.hf.co/repos/ab/cd/sha?X-Amz-Signature=1",
+		"/api/models/new/repo/revision/main":                       "/api/models/new/repo/revision/main",
+	}
+	for location, want := range cases {
+		if got := RewriteLocation(location); got != want {
+			t.Errorf("RewriteLocation(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
+
+func TestGetRedirectPolicy(t *testing.T) {
+	conf := &config.Config{}
+	if p := conf.GetRedirectPolicy("/api/models/org/repo/revision/main"); p != consts.RedirectFollow {
+		t.Errorf("default policy = %q", p)
+	}
+	conf.Redirect.Rules = []config.RedirectRule{{Path: "/api/*/*/*/revision/*", Policy: consts.RedirectRewrite}}
+	if p := conf.GetRedirectPolicy("/api/models/org/repo/revision/main"); p != consts.RedirectRewrite {
+		t.Errorf("rule policy = %q", p)
+	}
+	if p := conf.GetRedirectPolicy("/api/models/org/repo"); p != consts.RedirectFollow {
+		t.Errorf("unmatched policy = %q", p)
+	}
+}
+
+func TestApplyUpstreamToken(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.HfScheme = "https"
+	config.SysConfig.Server.HfNetLoc = "huggingface.co"
+	config.SysConfig.Outbound.DefaultToken = "hf_default"
+	config.SysConfig.Outbound.TokenMappings = []config.TokenMapping{{Name: "team-a", Keys: []string{"sk-team-a"}, Token: "hf_team_a"}}
+
+	cases := []struct {
+		method string
+		url    string
+		auth   string
+		want   string
+	}{
+		{http.MethodGet, "https://huggingface.co/api/models/org/repo/revision/main", "", "Bearer hf_default"},
+		{http.MethodPost, "https://huggingface.co/api/models/org/repo/paths-info/abc", "", "Bearer hf_default"},
+		{http.MethodGet, "https://huggingface.co/api/models/org/repo", "Bearer hf_client", "Bearer hf_client"},
+		{http.MethodGet, "https://huggingface.co/api/models/org/repo", "Bearer sk-team-a", "Bearer hf_team_a"},
+		{http.MethodPost, "https://huggingface.co/api/models/org/repo/commit/main", "Bearer sk-team-a", "Bearer hf_team_a"},
+		{http.MethodGet, "http://peer.example.com/models/org/repo/resolve/main/a.bin", "Bearer sk-team-a", "Bearer sk-team-a"},
+		{http.MethodPost, "https://huggingface.co/api/models/org/repo/commit/main", "", ""},
+		{http.MethodPut, "https://huggingface.co/api/models/org/repo/upload", "", ""},
+		{http.MethodGet, "http://peer.example.com/models/org/repo/resolve/main/a.bin", "", ""},
+		{http.MethodGet, "https://cdn-lfs.hf.co/repos/ab/cd", "", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		applyUpstreamToken(req)
+		if got := req.Header.Get("Authorization"); got != tc.want {
+			t.Errorf("%s %s Authorization = %q, want %q", tc.method, tc.url, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 发布包：一组命名的仓库快照（仓库@commit），如prod-2025-06，包内的版本不参与版本回收，持久化到{repos}/bundles.json。
+var (
+	releaseBundles     = make(map[string]*ReleaseBundle)
+	releaseBundlesLock sync.RWMutex
+	releaseBundlesOnce sync.Once
+)
+
+type ReleaseBundle struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Repos       []*BundleRepo `json:"repos"`
+	CreatedAt   int64         `json:"createdAt"`
+}
+
+// BundleRepo revision为创建时指定的版本，commit为其解析后的提交，发布包始终指向该提交。
+type BundleRepo struct {
+	Datatype string `json:"datatype"`
+	OrgRepo  string `json:"orgRepo"`
+	Revision string `json:"revision"`
+	Commit   string `json:"commit"`
+}
+
+func releaseBundlesPath() string {
+	return filepath.Join(config.SysConfig.Repos(), "bundles.json")
+}
+
+func loadReleaseBundles() {
+	releaseBundlesOnce.Do(func() {
+		bundlesPath := releaseBundlesPath()
+		if !FileExists(bundlesPath) {
+			return
+		}
+		b, err := os.ReadFile(bundlesPath)
+		if err != nil {
+			zap.S().Errorf("read %s err.%v", bundlesPath, err)
+			return
+		}
+		bundles := make([]*ReleaseBundle, 0)
+		if err = sonic.Unmarshal(b, &bundles); err != nil {
+			zap.S().Errorf("unmarshal %s err.%v", bundlesPath, err)
+			return
+		}
+		releaseBundlesLock.Lock()
+		defer releaseBundlesLock.Unlock()
+		for _, bundle := range bundles {
+			releaseBundles[bundle.Name] = bundle
+		}
+	})
+}
+
+// 调用方需持有releaseBundlesLock
+func saveReleaseBundles() error {
+	bundlesPath := releaseBundlesPath()
+	if err := MakeDirs(bundlesPath); err != nil {
+		return err
+	}
+	return WriteDataToFile(bundlesPath, sortedReleaseBundles())
+}
+
+// 调用方需持有releaseBundlesLock
+func sortedReleaseBundles() []*ReleaseBundle {
+	bundles := make([]*ReleaseBundle, 0, len(releaseBundles))
+	for _, bundle := range releaseBundles {
+		bundles = append(bundles, bundle)
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		return bundles[i].Name < bundles[j].Name
+	})
+	return bundles
+}
+
+// AddReleaseBundle 保存发布包，同名的发布包已存在时返回false，发布包创建后不可修改。
+func AddReleaseBundle(bundle *ReleaseBundle) (bool, error) {
+	loadReleaseBundles()
+	releaseBundlesLock.Lock()
+	defer releaseBundlesLock.Unlock()
+	if _, ok := releaseBundles[bundle.Name]; ok {
+		return false, nil
+	}
+	releaseBundles[bundle.Name] = bundle
+	if err := saveReleaseBundles(); err != nil {
+		delete(releaseBundles, bundle.Name)
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteReleaseBundle 删除发布包，包内的版本不再因此被固定，发布包不存在时返回false。
+func DeleteReleaseBundle(name string) (bool, error) {
+	loadReleaseBundles()
+	releaseBundlesLock.Lock()
+	defer releaseBundlesLock.Unlock()
+	if _, ok := releaseBundles[name]; !ok {
+		return false, nil
+	}
+	delete(releaseBundles, name)
+	return true, saveReleaseBundles()
+}
+
+func GetReleaseBundle(name string) (*ReleaseBundle, bool) {
+	loadReleaseBundles()
+	releaseBundlesLock.RLock()
+	defer releaseBundlesLock.RUnlock()
+	bundle, ok := releaseBundles[name]
+	return bundle, ok
+}
+
+func ListReleaseBundles() []*ReleaseBundle {
+	loadReleaseBundles()
+	releaseBundlesLock.RLock()
+	defer releaseBundlesLock.RUnlock()
+	return sortedReleaseBundles()
+}
+
+// isBundlePinned 版本是否属于某个发布包，发布包按commit固定版本。
+func isBundlePinned(repoType, orgRepo string, revisions ...string) bool {
+	loadReleaseBundles()
+	releaseBundlesLock.RLock()
+	defer releaseBundlesLock.RUnlock()
+	for _, bundle := range releaseBundles {
+		for _, repo := range bundle.Repos {
+			if repo.Datatype != repoType || repo.OrgRepo != orgRepo {
+				continue
+			}
+			for _, revision := range revisions {
+				if revision == repo.Commit {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
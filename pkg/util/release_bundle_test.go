@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestReleaseBundles(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Repos = t.TempDir()
+	releaseBundlesOnce.Do(func() {})
+
+	bundle := &ReleaseBundle{Name: "prod-2025-06", Repos: []*BundleRepo{
+		{Datatype: "models", OrgRepo: "org/a", Revision: "v1", Commit: "abc"},
+	}}
+	if ok, err := AddReleaseBundle(bundle); err != nil || !ok {
+		t.Fatalf("add ok=%v err=%v", ok, err)
+	}
+	if ok, _ := AddReleaseBundle(&ReleaseBundle{Name: "prod-2025-06"}); ok {
+		t.Error("bundle with the same name is added")
+	}
+	if !FileExists(releaseBundlesPath()) {
+		t.Error("bundles are not persisted")
+	}
+	if !IsRevisionPinned("models", "org/a", "abc", "main") {
+		t.Error("bundle commit is not pinned")
+	}
+	if IsRevisionPinned("models", "org/a", "def", "v1") {
+		t.Error("moved revision of bundle is pinned")
+	}
+	if got := ListReleaseBundles(); len(got) != 1 || got[0].Name != bundle.Name {
+		t.Errorf("list bundles: %v", got)
+	}
+	if ok, err := DeleteReleaseBundle(bundle.Name); err != nil || !ok {
+		t.Fatalf("delete ok=%v err=%v", ok, err)
+	}
+	if _, ok := GetReleaseBundle(bundle.Name); ok {
+		t.Error("bundle exists after delete")
+	}
+	if IsRevisionPinned("models", "org/a", "abc") {
+		t.Error("commit is still pinned after bundle deleted")
+	}
+}
@@ -0,0 +1,265 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 仓库别名：远端仓库改名后以301/307/308重定向旧名称，记录旧名称到新名称的映射，之后旧名称的请求直接按新名称处理，
+// 持久化到{repos}/aliases.json。
+var (
+	repoAliases     = make(map[string]*RepoAlias)
+	repoAliasesLock sync.RWMutex
+	repoAliasesOnce sync.Once
+)
+
+// 仓库名称之后的路径段，用于区分单段的仓库名（如gpt2）与{org}/{repo}
+var repoRouteKeywords = map[string]struct{}{
+	"revision": {}, "resolve": {}, "tree": {}, "paths-info": {}, "refs": {}, "commits": {}, "archive": {}, "files": {},
+	"gguf": {}, "safetensors": {}, "preview": {}, "size": {}, "metalink": {}, "manifest": {}, "attestation": {},
+}
+
+// RepoAlias statusCode为检测到改名时远端的重定向状态码，0表示由管理员添加。
+type RepoAlias struct {
+	Datatype   string `json:"datatype"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+func repoAliasKey(repoType, orgRepo string) string {
+	return repoType + "/" + orgRepo
+}
+
+func repoAliasesPath() string {
+	return filepath.Join(config.SysConfig.Repos(), "aliases.json")
+}
+
+func loadRepoAliases() {
+	repoAliasesOnce.Do(func() {
+		aliasesPath := repoAliasesPath()
+		if !FileExists(aliasesPath) {
+			return
+		}
+		b, err := os.ReadFile(aliasesPath)
+		if err != nil {
+			zap.S().Errorf("read %s err.%v", aliasesPath, err)
+			return
+		}
+		aliases := make([]*RepoAlias, 0)
+		if err = sonic.Unmarshal(b, &aliases); err != nil {
+			zap.S().Errorf("unmarshal %s err.%v", aliasesPath, err)
+			return
+		}
+		repoAliasesLock.Lock()
+		defer repoAliasesLock.Unlock()
+		for _, alias := range aliases {
+			repoAliases[repoAliasKey(alias.Datatype, alias.From)] = alias
+		}
+	})
+}
+
+// 调用方需持有repoAliasesLock
+func listRepoAliases() []*RepoAlias {
+	aliases := make([]*RepoAlias, 0, len(repoAliases))
+	for _, alias := range repoAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return repoAliasKey(aliases[i].Datatype, aliases[i].From) < repoAliasKey(aliases[j].Datatype, aliases[j].From)
+	})
+	return aliases
+}
+
+// 调用方需持有repoAliasesLock
+func saveRepoAliases() error {
+	aliasesPath := repoAliasesPath()
+	if err := MakeDirs(aliasesPath); err != nil {
+		return err
+	}
+	return WriteDataToFile(aliasesPath, listRepoAliases())
+}
+
+func ListRepoAliases() []*RepoAlias {
+	loadRepoAliases()
+	repoAliasesLock.RLock()
+	defer repoAliasesLock.RUnlock()
+	return listRepoAliases()
+}
+
+// AddRepoAlias 记录from改名为to，已指向from的别名改为指向to，仓库改回原名时删除反向的别名，避免形成循环。
+func AddRepoAlias(repoType, from, to string, statusCode int) (*RepoAlias, error) {
+	if from == to {
+		return nil, fmt.Errorf("alias %s/%s points to itself", repoType, from)
+	}
+	loadRepoAliases()
+	repoAliasesLock.Lock()
+	defer repoAliasesLock.Unlock()
+	key := repoAliasKey(repoType, from)
+	if alias, ok := repoAliases[key]; ok && alias.To == to {
+		return alias, nil
+	}
+	delete(repoAliases, repoAliasKey(repoType, to))
+	for _, alias := range repoAliases {
+		if alias.Datatype == repoType && alias.To == from {
+			alias.To = to
+		}
+	}
+	alias := &RepoAlias{Datatype: repoType, From: from, To: to, StatusCode: statusCode, CreatedAt: time.Now().Unix()}
+	repoAliases[key] = alias
+	return alias, saveRepoAliases()
+}
+
+// RemoveRepoAlias 删除别名，别名不存在时返回false。
+func RemoveRepoAlias(repoType, from string) (bool, error) {
+	loadRepoAliases()
+	repoAliasesLock.Lock()
+	defer repoAliasesLock.Unlock()
+	key := repoAliasKey(repoType, from)
+	if _, ok := repoAliases[key]; !ok {
+		return false, nil
+	}
+	delete(repoAliases, key)
+	return true, saveRepoAliases()
+}
+
+// ResolveRepoAlias 返回仓库改名后的名称，没有别名时返回false。
+func ResolveRepoAlias(repoType, orgRepo string) (string, bool) {
+	loadRepoAliases()
+	repoAliasesLock.RLock()
+	defer repoAliasesLock.RUnlock()
+	if len(repoAliases) == 0 {
+		return "", false
+	}
+	alias, ok := repoAliases[repoAliasKey(repoType, orgRepo)]
+	if !ok {
+		return "", false
+	}
+	return alias.To, true
+}
+
+// splitRepoPath 从/api/{type}/{org}/{repo}/...、/{type}/{org}/{repo}/...或省略models类型的/{org}/{repo}/...中
+// 解析仓库，返回仓库名称之前及之后的路径段。
+func splitRepoPath(p string) (head []string, repoType, orgRepo string, tail []string, ok bool) {
+	p, _, _ = strings.Cut(p, "?")
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	i, api := 0, false
+	if len(segments) > 0 && segments[0] == "api" {
+		if len(segments) < 3 {
+			return nil, "", "", nil, false
+		}
+		if _, ok = consts.RepoTypesMapping[segments[1]]; !ok {
+			return nil, "", "", nil, false
+		}
+		i, api, repoType = 2, true, segments[1]
+	} else if _, ok = consts.RepoTypesMapping[segments[0]]; ok && len(segments) > 2 {
+		i, repoType = 1, segments[0]
+	} else {
+		repoType = "models"
+	}
+	rest := segments[i:]
+	isKeyword := func(n int) bool {
+		if n >= len(rest) {
+			return false
+		}
+		_, ok := repoRouteKeywords[rest[n]]
+		return ok
+	}
+	n := 0
+	switch {
+	case isKeyword(2) || (api && len(rest) == 2):
+		n = 2
+	case isKeyword(1) || (api && len(rest) == 1):
+		n = 1
+	default:
+		return nil, "", "", nil, false
+	}
+	for _, segment := range rest[:n] {
+		if segment == "" {
+			return nil, "", "", nil, false
+		}
+	}
+	return segments[:i], repoType, strings.Join(rest[:n], "/"), rest[n:], true
+}
+
+// RewriteRepoAliasPath 请求路径中的仓库有别名时替换为改名后的名称。
+func RewriteRepoAliasPath(p string) (string, string, bool) {
+	head, repoType, orgRepo, tail, ok := splitRepoPath(p)
+	if !ok {
+		return p, "", false
+	}
+	to, ok := ResolveRepoAlias(repoType, orgRepo)
+	if !ok {
+		return p, "", false
+	}
+	segments := make([]string, 0, len(head)+2+len(tail))
+	segments = append(append(append(segments, head...), strings.Split(to, "/")...), tail...)
+	rewritten := "/" + strings.Join(segments, "/")
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(rewritten, "/") {
+		rewritten += "/"
+	}
+	return rewritten, repoAliasKey(repoType, to), true
+}
+
+// detectRepoRename 远端将仓库永久或临时重定向到同类型的另一个仓库时，视为仓库改名并记录别名。
+func detectRepoRename(req *http.Request, via []*http.Request) {
+	if req.Response == nil || len(via) == 0 {
+		return
+	}
+	switch req.Response.StatusCode {
+	case http.StatusMovedPermanently, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return
+	}
+	oldPath, ok := cutUpstreamEndpoint(via[len(via)-1].URL.String())
+	if !ok {
+		return
+	}
+	newPath, ok := cutUpstreamEndpoint(req.URL.String())
+	if !ok {
+		return
+	}
+	_, oldType, oldRepo, oldTail, ok := splitRepoPath(oldPath)
+	if !ok {
+		return
+	}
+	_, newType, newRepo, newTail, ok := splitRepoPath(newPath)
+	if !ok || oldType != newType || oldRepo == newRepo || strings.Join(oldTail, "/") != strings.Join(newTail, "/") {
+		return
+	}
+	if to, ok := ResolveRepoAlias(oldType, oldRepo); ok && to == newRepo {
+		return
+	}
+	if _, err := AddRepoAlias(oldType, oldRepo, newRepo, req.Response.StatusCode); err != nil {
+		zap.S().Warnf("add repo alias %s/%s err.%v", oldType, oldRepo, err)
+		return
+	}
+	zap.S().Infof("repo %s/%s is renamed to %s, status %d", oldType, oldRepo, newRepo, req.Response.StatusCode)
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"net/http"
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestRepoAliasRewrite(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Repos = t.TempDir()
+	repoAliasesOnce.Do(func() {})
+	defer func() { repoAliases = make(map[string]*RepoAlias) }()
+
+	if _, err := AddRepoAlias("models", "gpt2", "openai-community/gpt2", http.StatusTemporaryRedirect); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddRepoAlias("datasets", "old/ds", "new/ds", http.StatusMovedPermanently); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"/api/models/gpt2/revision/main":          "/api/models/openai-community/gpt2/revision/main",
+		"/api/models/gpt2":                        "/api/models/openai-community/gpt2",
+		"/gpt2/resolve/main/config.json":          "/openai-community/gpt2/resolve/main/config.json",
+		"/datasets/old/ds/resolve/main/a/b.csv":   "/datasets/new/ds/resolve/main/a/b.csv",
+		"/api/datasets/old/ds/paths-info/main":    "/api/datasets/new/ds/paths-info/main",
+		"/api/datasets/old/ds/tree/main/dir/":     "/api/datasets/new/ds/tree/main/dir/",
+		"/api/models/gpt2/other/revision/main":    "",
+		"/api/datasets/gpt2/revision/main":        "",
+		"/old/ds/resolve/main/a.csv":              "",
+		"/api/models/org/gpt2/revision/main":      "",
+		"/models/openai-community/gpt2/resolve/x": "",
+	}
+	for p, want := range cases {
+		got, _, ok := RewriteRepoAliasPath(p)
+		if want == "" {
+			if ok {
+				t.Errorf("%s rewritten to %s", p, got)
+			}
+			continue
+		}
+		if !ok || got != want {
+			t.Errorf("%s: got %s, want %s", p, got, want)
+		}
+	}
+
+	// 改名链合并为指向最终名称，改回原名时删除反向别名
+	if _, err := AddRepoAlias("datasets", "new/ds", "newer/ds", http.StatusMovedPermanently); err != nil {
+		t.Fatal(err)
+	}
+	if to, _ := ResolveRepoAlias("datasets", "old/ds"); to != "newer/ds" {
+		t.Errorf("chained alias resolves to %s", to)
+	}
+	if _, err := AddRepoAlias("datasets", "newer/ds", "old/ds", http.StatusMovedPermanently); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ResolveRepoAlias("datasets", "old/ds"); ok {
+		t.Error("reverse alias is kept")
+	}
+	if ok, _ := RemoveRepoAlias("models", "gpt2"); !ok {
+		t.Error("remove alias")
+	}
+	if !FileExists(repoAliasesPath()) {
+		t.Error("aliases are not persisted")
+	}
+}
+
+func TestDetectRepoRename(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Repos = t.TempDir()
+	config.SysConfig.Server.HfScheme = "https"
+	config.SysConfig.Server.HfNetLoc = "huggingface.co"
+	repoAliasesOnce.Do(func() {})
+	defer func() { repoAliases = make(map[string]*RepoAlias) }()
+
+	redirect := func(from, to string, status int) {
+		prev, _ := http.NewRequest(http.MethodGet, from, nil)
+		req, _ := http.NewRequest(http.MethodGet, to, nil)
+		req.Response = &http.Response{StatusCode: status}
+		detectRepoRename(req, []*http.Request{prev})
+	}
+	redirect("https://huggingface.co/api/models/org/a/revision/main", "https://huggingface.co/api/models/org/b/revision/main", http.StatusTemporaryRedirect)
+	if to, ok := ResolveRepoAlias("models", "org/a"); !ok || to != "org/b" {
+		t.Errorf("rename is not detected: %s", to)
+	}
+	redirect("https://huggingface.co/org/c/resolve/main/f", "https://cdn-lfs.hf.co/repos/xx/yy", http.StatusFound)
+	redirect("https://huggingface.co/org/d/resolve/main/f", "https://huggingface.co/org/e/resolve/main/f", http.StatusFound)
+	redirect("https://huggingface.co/org/f/resolve/main/f", "https://huggingface.co/org/f/resolve/abc/f", http.StatusTemporaryRedirect)
+	if aliases := ListRepoAliases(); len(aliases) != 1 {
+		t.Errorf("unexpected aliases: %d", len(aliases))
+	}
+}
@@ -0,0 +1,236 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	myerr "dingospeed/pkg/error"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// RepoCard 仓库元数据中与访问策略相关的信息，取自模型卡片的license及gated标记。
+type RepoCard struct {
+	Licenses []string `json:"licenses"`
+	Gated    string   `json:"gated,omitempty"` // auto、manual，为空表示非gated仓库
+}
+
+// ParseRepoCard 从远端的仓库元数据中解析许可证及gated标记，许可证取cardData.license及license:前缀的标签。
+func ParseRepoCard(meta []byte) (*RepoCard, error) {
+	var raw struct {
+		Gated    interface{} `json:"gated"`
+		Tags     []string    `json:"tags"`
+		CardData struct {
+			License interface{} `json:"license"`
+		} `json:"cardData"`
+	}
+	if err := sonic.Unmarshal(meta, &raw); err != nil {
+		return nil, err
+	}
+	card := &RepoCard{Licenses: make([]string, 0)}
+	addLicense := func(license string) {
+		license = strings.ToLower(strings.TrimSpace(license))
+		if license == "" {
+			return
+		}
+		for _, l := range card.Licenses {
+			if l == license {
+				return
+			}
+		}
+		card.Licenses = append(card.Licenses, license)
+	}
+	switch v := raw.CardData.License.(type) {
+	case string:
+		addLicense(v)
+	case []interface{}:
+		for _, l := range v {
+			if s, ok := l.(string); ok {
+				addLicense(s)
+			}
+		}
+	}
+	for _, tag := range raw.Tags {
+		if license, ok := strings.CutPrefix(tag, "license:"); ok {
+			addLicense(license)
+		}
+	}
+	switch v := raw.Gated.(type) {
+	case string:
+		card.Gated = v
+	case bool:
+		if v {
+			card.Gated = "auto"
+		}
+	}
+	return card, nil
+}
+
+// CheckRepoPolicy 按配置的许可证及gated策略检查是否允许向客户端提供该仓库的文件，card为nil表示元数据不可用。
+func CheckRepoPolicy(repoType, orgRepo string, card *RepoCard) error {
+	policy := config.SysConfig.Policy
+	if !policy.Enabled {
+		return nil
+	}
+	if card == nil || len(card.Licenses) == 0 {
+		if policy.DenyUnknown {
+			return myerr.NewAppendCode(http.StatusForbidden, fmt.Sprintf("%s/%s has no declared license and is blocked by policy", repoType, orgRepo))
+		}
+	} else {
+		for _, license := range card.Licenses {
+			if !licenseAllowed(license, policy.AllowLicenses, policy.DenyLicenses) {
+				return myerr.NewAppendCode(http.StatusForbidden, fmt.Sprintf("license %s of %s/%s is blocked by policy", license, repoType, orgRepo))
+			}
+		}
+	}
+	if policy.GatedApproval && card != nil && card.Gated != "" && !RepoApproved(repoType, orgRepo) {
+		return myerr.NewErrorCode(http.StatusForbidden, consts.HfErrorCodeGatedRepo, fmt.Sprintf("%s/%s is a gated repo and requires admin approval", repoType, orgRepo)).WithKind(myerr.KindGated)
+	}
+	return nil
+}
+
+// licenseAllowed deny优先，allow不为空时只允许其中的许可证，两者均支持path.Match语法。
+func licenseAllowed(license string, allow, deny []string) bool {
+	if matchLicense(license, deny) {
+		return false
+	}
+	return len(allow) == 0 || matchLicense(license, allow)
+}
+
+func matchLicense(license string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), license); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 管理员审批通过的gated仓库，持久化到{repos}/policy/approvals.json。
+var (
+	repoApprovals     = make(map[string]*RepoApproval)
+	repoApprovalsLock sync.RWMutex
+	repoApprovalsOnce sync.Once
+)
+
+type RepoApproval struct {
+	Datatype   string `json:"datatype"`
+	OrgRepo    string `json:"orgRepo"`
+	Comment    string `json:"comment,omitempty"`
+	ApprovedAt int64  `json:"approvedAt"`
+}
+
+func repoApprovalKey(repoType, orgRepo string) string {
+	return repoType + "/" + orgRepo
+}
+
+func repoApprovalsPath() string {
+	return filepath.Join(config.SysConfig.Repos(), "policy", "approvals.json")
+}
+
+func loadRepoApprovals() {
+	repoApprovalsOnce.Do(func() {
+		approvalsPath := repoApprovalsPath()
+		if !FileExists(approvalsPath) {
+			return
+		}
+		b, err := os.ReadFile(approvalsPath)
+		if err != nil {
+			zap.S().Errorf("read %s err.%v", approvalsPath, err)
+			return
+		}
+		approvals := make([]*RepoApproval, 0)
+		if err = sonic.Unmarshal(b, &approvals); err != nil {
+			zap.S().Errorf("unmarshal %s err.%v", approvalsPath, err)
+			return
+		}
+		repoApprovalsLock.Lock()
+		defer repoApprovalsLock.Unlock()
+		for _, approval := range approvals {
+			repoApprovals[repoApprovalKey(approval.Datatype, approval.OrgRepo)] = approval
+		}
+	})
+}
+
+// 调用方需持有repoApprovalsLock
+func saveRepoApprovals() error {
+	approvalsPath := repoApprovalsPath()
+	if err := MakeDirs(approvalsPath); err != nil {
+		return err
+	}
+	return WriteDataToFile(approvalsPath, listRepoApprovals())
+}
+
+// 调用方需持有repoApprovalsLock
+func listRepoApprovals() []*RepoApproval {
+	approvals := make([]*RepoApproval, 0, len(repoApprovals))
+	for _, approval := range repoApprovals {
+		approvals = append(approvals, approval)
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		return repoApprovalKey(approvals[i].Datatype, approvals[i].OrgRepo) < repoApprovalKey(approvals[j].Datatype, approvals[j].OrgRepo)
+	})
+	return approvals
+}
+
+func RepoApproved(repoType, orgRepo string) bool {
+	loadRepoApprovals()
+	repoApprovalsLock.RLock()
+	defer repoApprovalsLock.RUnlock()
+	_, ok := repoApprovals[repoApprovalKey(repoType, orgRepo)]
+	return ok
+}
+
+func ListRepoApprovals() []*RepoApproval {
+	loadRepoApprovals()
+	repoApprovalsLock.RLock()
+	defer repoApprovalsLock.RUnlock()
+	return listRepoApprovals()
+}
+
+// ApproveRepo 审批通过gated仓库，重复审批时更新备注及时间。
+func ApproveRepo(repoType, orgRepo, comment string) (*RepoApproval, error) {
+	loadRepoApprovals()
+	repoApprovalsLock.Lock()
+	defer repoApprovalsLock.Unlock()
+	approval := &RepoApproval{Datatype: repoType, OrgRepo: orgRepo, Comment: comment, ApprovedAt: time.Now().Unix()}
+	repoApprovals[repoApprovalKey(repoType, orgRepo)] = approval
+	return approval, saveRepoApprovals()
+}
+
+// RevokeRepo 撤销审批，仓库未审批时返回false。
+func RevokeRepo(repoType, orgRepo string) (bool, error) {
+	loadRepoApprovals()
+	repoApprovalsLock.Lock()
+	defer repoApprovalsLock.Unlock()
+	key := repoApprovalKey(repoType, orgRepo)
+	if _, ok := repoApprovals[key]; !ok {
+		return false, nil
+	}
+	delete(repoApprovals, key)
+	return true, saveRepoApprovals()
+}
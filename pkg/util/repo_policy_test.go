@@ -0,0 +1,70 @@
+package util
+
+import (
+	"testing"
+
+	"dingospeed/pkg/config"
+	myerr "dingospeed/pkg/error"
+)
+
+func TestParseRepoCard(t *testing.T) {
+	card, err := ParseRepoCard([]byte(`{"gated":"manual","tags":["license:MIT","text-generation"],"cardData":{"license":["mit","other"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Licenses) != 2 || card.Licenses[0] != "mit" || card.Licenses[1] != "other" {
+		t.Errorf("unexpected licenses %v", card.Licenses)
+	}
+	if card.Gated != "manual" {
+		t.Errorf("unexpected gated %q", card.Gated)
+	}
+	card, err = ParseRepoCard([]byte(`{"gated":false,"cardData":{"license":"apache-2.0"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(card.Licenses) != 1 || card.Licenses[0] != "apache-2.0" || card.Gated != "" {
+		t.Errorf("unexpected card %+v", card)
+	}
+}
+
+func TestCheckRepoPolicy(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{Policy: config.Policy{
+		Enabled:       true,
+		DenyLicenses:  []string{"GPL-*"},
+		GatedApproval: true,
+	}}
+	repoApprovalsOnce.Do(func() {})
+
+	if err := CheckRepoPolicy("models", "org/a", &RepoCard{Licenses: []string{"apache-2.0"}}); err != nil {
+		t.Errorf("apache-2.0 blocked: %v", err)
+	}
+	if err := CheckRepoPolicy("models", "org/a", &RepoCard{Licenses: []string{"gpl-3.0"}}); err == nil {
+		t.Error("gpl-3.0 allowed")
+	}
+	if err := CheckRepoPolicy("models", "org/a", nil); err != nil {
+		t.Errorf("unknown license blocked without denyUnknown: %v", err)
+	}
+	config.SysConfig.Policy.AllowLicenses = []string{"mit"}
+	if err := CheckRepoPolicy("models", "org/a", &RepoCard{Licenses: []string{"apache-2.0"}}); err == nil {
+		t.Error("license outside allowLicenses allowed")
+	}
+
+	gated := &RepoCard{Licenses: []string{"mit"}, Gated: "auto"}
+	err := CheckRepoPolicy("models", "org/gated", gated)
+	if myerr.KindOf(err) != myerr.KindGated {
+		t.Errorf("gated repo without approval: %v", err)
+	}
+	repoApprovalsLock.Lock()
+	repoApprovals[repoApprovalKey("models", "org/gated")] = &RepoApproval{Datatype: "models", OrgRepo: "org/gated"}
+	repoApprovalsLock.Unlock()
+	defer func() {
+		repoApprovalsLock.Lock()
+		delete(repoApprovals, repoApprovalKey("models", "org/gated"))
+		repoApprovalsLock.Unlock()
+	}()
+	if err = CheckRepoPolicy("models", "org/gated", gated); err != nil {
+		t.Errorf("approved gated repo blocked: %v", err)
+	}
+}
@@ -15,9 +15,13 @@
 package util
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/gob"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -28,8 +32,12 @@ import (
 	"time"
 
 	"dingospeed/pkg/common"
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/lock"
 
 	"github.com/bytedance/sonic"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/zap"
 	"golang.org/x/sys/unix"
 )
@@ -57,6 +65,21 @@ func SplitOrgRepo(orgRepo string) (string, string) {
 	}
 }
 
+// GetApiRoot 返回元数据缓存根目录，启用privateCache后，携带token的请求按token哈希隔离，防止私有仓库的元数据被其他用户读取。
+func GetApiRoot(authorization string) string {
+	apiRoot := filepath.Join(config.SysConfig.Repos(), "api")
+	if !config.SysConfig.Cache.PrivateCache || authorization == "" {
+		return apiRoot
+	}
+	return filepath.Join(apiRoot, consts.PrivateCacheDir, TokenHash(authorization))
+}
+
+func TokenHash(authorization string) string {
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
 // MakeDirs 确保指定路径对应的目录存在
 func MakeDirs(path string) error {
 	fileInfo, err := os.Stat(path)
@@ -207,6 +230,7 @@ func ReName(src, dst string) {
 		fmt.Printf("移动文件失败: %v\n", err)
 		return
 	}
+	AddCacheIndex(dst)
 }
 
 func CreateSymlinkIfNotExists(src, dst string) error {
@@ -221,7 +245,12 @@ func CreateSymlinkIfNotExists(src, dst string) error {
 		if err != nil {
 			return fmt.Errorf("计算相对路径失败: %v", err)
 		}
-		return os.Symlink(relSrc, dst)
+		if err = os.Symlink(relSrc, dst); err != nil {
+			return err
+		}
+		addBlobRef(src)
+		AddCacheIndex(dst)
+		return nil
 	}
 	return err
 }
@@ -235,18 +264,72 @@ func WriteDataToFile(filename string, data interface{}) error {
 	if err != nil {
 		return fmt.Errorf("JSON 编码出错: %w", err)
 	}
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	return WriteStreamToFile(filename, func(w io.Writer) error {
+		_, err := w.Write(jsonData)
+		return err
+	})
+}
+
+// WriteStreamToFile 由write以流的方式写入文件内容，启用元数据压缩时边写边压缩，写入失败不会留下不完整的文件。
+func WriteStreamToFile(filename string, write func(w io.Writer) error) error {
+	// 先写临时文件再改名，多个副本共享缓存卷时并发写入也不会读到不完整的内容。
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("打开文件出错: %w", err)
 	}
-	defer file.Close()
-	_, err = file.Write(jsonData)
+	tmpName := file.Name()
+	if config.SysConfig != nil && config.SysConfig.Cache.CompressMeta {
+		var encoder *zstd.Encoder
+		if encoder, err = zstd.NewWriter(file, zstd.WithEncoderLevel(zstd.SpeedDefault)); err == nil {
+			if err = write(encoder); err == nil {
+				err = encoder.Close()
+			} else {
+				encoder.Close()
+			}
+		}
+	} else {
+		err = write(file)
+	}
+	if err == nil && (config.SysConfig == nil || config.SysConfig.GetSyncPolicy() != consts.SyncPolicyNever) {
+		err = file.Sync() // 确保改名前内容已落盘，异常退出后不会出现被截断的文件
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
 	if err != nil {
+		os.Remove(tmpName)
 		return fmt.Errorf("写入文件出错: %w", err)
 	}
+	AddCacheIndex(filename)
+	return nil
+}
+
+// LinkFile 将dst替换为src的硬链接，先链接到临时文件再改名，dst已存在时原子替换。
+// 缓存文件均以临时文件改名的方式写入，之后任一路径的更新不会影响另一路径。
+func LinkFile(src, dst string) error {
+	tmpName := fmt.Sprintf("%s.%d.tmp", dst, time.Now().UnixNano())
+	if err := os.Link(src, tmpName); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, dst); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	AddCacheIndex(dst)
 	return nil
 }
 
+// LockFile 通过全局锁管理器对path加进程间排他锁，单机时为同目录下隐藏文件的flock，返回解锁函数。
+func LockFile(path string) (func(), error) {
+	return lock.Acquire(context.Background(), lock.Default(), path)
+}
+
 // StoreMetadata 保存文件元数据
 func StoreMetadata(filePath string, metadata *common.FileMetadata) error {
 	// 写入文件
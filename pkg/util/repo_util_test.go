@@ -0,0 +1,45 @@
+package util
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "sha", "meta_get.json")
+	dst := filepath.Join(dir, "main", "meta_get.json")
+	for _, d := range []string{filepath.Dir(src), filepath.Dir(dst)} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(src, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LinkFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "v1" {
+		t.Fatalf("dst got %s", data)
+	}
+
+	// 重新写入dst时替换为新文件，不影响src
+	if err := WriteStreamToFile(dst, func(w io.Writer) error {
+		_, err := w.Write([]byte("v2"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(src); string(data) != "v1" {
+		t.Fatalf("src got %s", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dst)); len(entries) != 1 {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+}
@@ -19,7 +19,10 @@ import (
 	"fmt"
 	"net/http"
 
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
 	myerr "dingospeed/pkg/error"
+	"dingospeed/pkg/prom"
 
 	"github.com/labstack/echo/v4"
 )
@@ -66,21 +69,95 @@ func ErrorEntryNotFoundBranch(ctx echo.Context, branch, path string) error {
 }
 
 func ErrorEntryUnknown(ctx echo.Context, statusCode int, msg string) error {
+	return ErrorHf(ctx, statusCode, "", msg)
+}
+
+// ErrorHf 按照huggingface的格式返回错误，便于huggingface_hub识别具体的异常类型。
+func ErrorHf(ctx echo.Context, statusCode int, errorCode, msg string) error {
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	return errorHfKind(ctx, statusCode, errorCode, msg, myerr.InferKind(statusCode, errorCode))
+}
+
+// errorHfKind 同ErrorHf，错误分类写入X-Dingospeed-Error-Kind并计数；没有huggingface的错误编码时，X-Error-Code使用分类名。
+func errorHfKind(ctx echo.Context, statusCode int, errorCode, msg string, kind myerr.Kind) error {
+	if errorCode == "" {
+		errorCode = defaultErrorCode(statusCode)
+	}
+	if errorCode == "" {
+		errorCode = string(kind)
+	}
+	markErrorKind(ctx, kind)
 	content := map[string]string{
 		"error": msg,
 	}
-	if statusCode == 0 {
-		statusCode = http.StatusInternalServerError
+	headers := make(map[string]string)
+	if errorCode != "" {
+		headers[consts.HfHeaderErrorCode] = errorCode
+		headers[consts.HfHeaderErrorMessage] = msg
+	}
+	// 远端限流时告知客户端需要等待的时间
+	if statusCode == http.StatusTooManyRequests {
+		if seconds := UpstreamRetryAfter(ctx.Request().URL.Path); seconds > 0 {
+			headers["Retry-After"] = Itoa(seconds)
+		}
+	}
+	return Response(ctx, statusCode, headers, content)
+}
+
+// ResponseHfError 错误中携带了状态码和错误编码时按原样返回，否则视为代理异常。
+func ResponseHfError(ctx echo.Context, err error) error {
+	var redirect *RedirectError
+	if errors.As(err, &redirect) {
+		return ctx.Redirect(redirect.StatusCode, redirect.Location)
+	}
+	var e myerr.Error
+	if errors.As(err, &e) {
+		statusCode := e.StatusCode()
+		if statusCode == 0 {
+			statusCode = http.StatusInternalServerError
+		}
+		return errorHfKind(ctx, statusCode, e.ErrorCode(), e.Error(), e.Kind())
+	}
+	if kind := myerr.KindOf(err); kind == myerr.KindUpstreamTimeout {
+		return ErrorProxyTimeout(ctx)
+	}
+	return ErrorProxyError(ctx)
+}
+
+// markErrorKind 在响应头中标明错误分类，启用指标时按分类计数。
+func markErrorKind(ctx echo.Context, kind myerr.Kind) {
+	if kind == "" {
+		return
+	}
+	ctx.Response().Header().Set(consts.HeaderErrorKind, string(kind))
+	if config.SysConfig.EnableMetric() {
+		prom.ErrorKindCnt.WithLabelValues(string(kind)).Inc()
+	}
+}
+
+func defaultErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return consts.HfErrorCodeRepoNotFound
+	case http.StatusForbidden:
+		return consts.HfErrorCodeGatedRepo
+	case http.StatusNotFound:
+		return consts.HfErrorCodeEntryNotFound
 	}
-	return Response(ctx, statusCode, nil, content)
+	return ""
 }
 
 func ErrorEntryNotFound(ctx echo.Context) error {
+	content := map[string]string{
+		"error": "Entry not found",
+	}
 	headers := map[string]string{
 		"x-error-code":    "EntryNotFound",
 		"x-error-message": "Entry not found",
 	}
-	return Response(ctx, http.StatusNotFound, headers, nil)
+	return Response(ctx, http.StatusNotFound, headers, content)
 }
 
 func ErrorRevisionNotFound(ctx echo.Context, revision string) error {
@@ -95,19 +172,27 @@ func ErrorRevisionNotFound(ctx echo.Context, revision string) error {
 }
 
 func ErrorProxyTimeout(ctx echo.Context) error {
+	markErrorKind(ctx, myerr.KindUpstreamTimeout)
+	content := map[string]string{
+		"error": "Proxy Timeout",
+	}
 	headers := map[string]string{
 		"x-error-code":    "ProxyTimeout",
 		"x-error-message": "Proxy Timeout",
 	}
-	return Response(ctx, http.StatusGatewayTimeout, headers, nil)
+	return Response(ctx, http.StatusGatewayTimeout, headers, content)
 }
 
 func ErrorProxyError(ctx echo.Context) error {
+	markErrorKind(ctx, myerr.KindUpstreamError)
+	content := map[string]string{
+		"error": "Proxy error",
+	}
 	headers := map[string]string{
 		"x-error-code":    "Proxy error",
 		"x-error-message": "Proxy error",
 	}
-	return Response(ctx, http.StatusInternalServerError, headers, nil)
+	return Response(ctx, http.StatusInternalServerError, headers, content)
 }
 
 func ErrorMethodError(ctx echo.Context) error {
@@ -122,6 +207,7 @@ func ErrorMethodError(ctx echo.Context) error {
 }
 
 func ErrorTooManyRequest(ctx echo.Context) error {
+	markErrorKind(ctx, myerr.KindQuotaExceeded)
 	content := map[string]string{
 		"error": "Too many requests",
 	}
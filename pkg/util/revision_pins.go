@@ -0,0 +1,134 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// 管理员通过接口固定的版本，与revisionGc.pins配置的版本一样不参与版本回收，持久化到{repos}/pins.json。
+var (
+	revisionPins     = make(map[string]*RevisionPin)
+	revisionPinsLock sync.RWMutex
+	revisionPinsOnce sync.Once
+)
+
+type RevisionPin struct {
+	Datatype string `json:"datatype"`
+	OrgRepo  string `json:"orgRepo"`
+	Revision string `json:"revision"`
+	PinnedAt int64  `json:"pinnedAt"`
+}
+
+func revisionPinKey(repoType, orgRepo, revision string) string {
+	return fmt.Sprintf("%s/%s@%s", repoType, orgRepo, revision)
+}
+
+func revisionPinsPath() string {
+	return filepath.Join(config.SysConfig.Repos(), "pins.json")
+}
+
+func loadRevisionPins() {
+	revisionPinsOnce.Do(func() {
+		pinsPath := revisionPinsPath()
+		if !FileExists(pinsPath) {
+			return
+		}
+		b, err := os.ReadFile(pinsPath)
+		if err != nil {
+			zap.S().Errorf("read %s err.%v", pinsPath, err)
+			return
+		}
+		pins := make([]*RevisionPin, 0)
+		if err = sonic.Unmarshal(b, &pins); err != nil {
+			zap.S().Errorf("unmarshal %s err.%v", pinsPath, err)
+			return
+		}
+		revisionPinsLock.Lock()
+		defer revisionPinsLock.Unlock()
+		for _, pin := range pins {
+			revisionPins[revisionPinKey(pin.Datatype, pin.OrgRepo, pin.Revision)] = pin
+		}
+	})
+}
+
+// 调用方需持有revisionPinsLock
+func saveRevisionPins() error {
+	pins := make([]*RevisionPin, 0, len(revisionPins))
+	for _, pin := range revisionPins {
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return revisionPinKey(pins[i].Datatype, pins[i].OrgRepo, pins[i].Revision) < revisionPinKey(pins[j].Datatype, pins[j].OrgRepo, pins[j].Revision)
+	})
+	pinsPath := revisionPinsPath()
+	if err := MakeDirs(pinsPath); err != nil {
+		return err
+	}
+	return WriteDataToFile(pinsPath, pins)
+}
+
+// PinRevision 固定仓库的版本，revision为commit或分支、标签，已固定时返回原记录。
+func PinRevision(repoType, orgRepo, revision string) (*RevisionPin, error) {
+	loadRevisionPins()
+	revisionPinsLock.Lock()
+	defer revisionPinsLock.Unlock()
+	key := revisionPinKey(repoType, orgRepo, revision)
+	if pin, ok := revisionPins[key]; ok {
+		return pin, nil
+	}
+	pin := &RevisionPin{Datatype: repoType, OrgRepo: orgRepo, Revision: revision, PinnedAt: time.Now().Unix()}
+	revisionPins[key] = pin
+	return pin, saveRevisionPins()
+}
+
+// UnpinRevision 取消固定，版本未固定时返回false。
+func UnpinRevision(repoType, orgRepo, revision string) (bool, error) {
+	loadRevisionPins()
+	revisionPinsLock.Lock()
+	defer revisionPinsLock.Unlock()
+	key := revisionPinKey(repoType, orgRepo, revision)
+	if _, ok := revisionPins[key]; !ok {
+		return false, nil
+	}
+	delete(revisionPins, key)
+	return true, saveRevisionPins()
+}
+
+// IsRevisionPinned 版本是否由配置、管理员接口或发布包固定，revisions为同一版本的commit及指向它的分支、标签。
+func IsRevisionPinned(repoType, orgRepo string, revisions ...string) bool {
+	if config.SysConfig.IsRevisionPinned(repoType, orgRepo, revisions...) || isBundlePinned(repoType, orgRepo, revisions...) {
+		return true
+	}
+	loadRevisionPins()
+	revisionPinsLock.RLock()
+	defer revisionPinsLock.RUnlock()
+	for _, revision := range revisions {
+		if _, ok := revisionPins[revisionPinKey(repoType, orgRepo, revision)]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestRevisionPins(t *testing.T) {
+	old := config.SysConfig
+	defer func() { config.SysConfig = old }()
+	config.SysConfig = &config.Config{}
+	config.SysConfig.Server.Repos = t.TempDir()
+	config.SysConfig.RevisionGc.Pins = []string{"models/org/a@v1"}
+	revisionPinsOnce.Do(func() {})
+
+	if !IsRevisionPinned("models", "org/a", "abc", "v1") {
+		t.Error("revision pinned by config is not pinned")
+	}
+	if IsRevisionPinned("models", "org/b", "main") {
+		t.Error("unexpected pinned revision")
+	}
+	if _, err := PinRevision("models", "org/b", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if !IsRevisionPinned("models", "org/b", "abc", "main") {
+		t.Error("revision pinned by admin is not pinned")
+	}
+	if !FileExists(revisionPinsPath()) {
+		t.Error("pins are not persisted")
+	}
+	if ok, err := UnpinRevision("models", "org/b", "main"); err != nil || !ok {
+		t.Fatalf("unpin ok=%v err=%v", ok, err)
+	}
+	if ok, _ := UnpinRevision("models", "org/b", "main"); ok {
+		t.Error("unpin twice")
+	}
+	if IsRevisionPinned("models", "org/b", "main") {
+		t.Error("revision is still pinned after unpin")
+	}
+}
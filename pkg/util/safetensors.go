@@ -0,0 +1,92 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bytedance/sonic"
+)
+
+const maxSafetensorsHeaderSize = 100 * 1024 * 1024
+
+type SafetensorsInfo struct {
+	HeaderSize     uint64               `json:"headerSize"`
+	ParameterCount uint64               `json:"parameterCount"`
+	Dtypes         map[string]uint64    `json:"dtypes"` // 各数据类型的参数量
+	Metadata       map[string]string    `json:"metadata"`
+	Tensors        []*SafetensorsTensor `json:"tensors"`
+}
+
+type SafetensorsTensor struct {
+	Name        string   `json:"name"`
+	Dtype       string   `json:"dtype"`
+	Shape       []uint64 `json:"shape"`
+	DataOffsets []uint64 `json:"data_offsets"`
+}
+
+// ParseSafetensors 解析safetensors文件头：前8字节为头部长度，其后为描述各张量的json。
+func ParseSafetensors(r io.Reader) (*SafetensorsInfo, error) {
+	var headerSize uint64
+	if err := binary.Read(r, binary.LittleEndian, &headerSize); err != nil {
+		return nil, err
+	}
+	if headerSize == 0 || headerSize > maxSafetensorsHeaderSize {
+		return nil, fmt.Errorf("invalid safetensors header size: %d", headerSize)
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	var meta struct {
+		Metadata map[string]string `json:"__metadata__"`
+	}
+	if err := sonic.Unmarshal(header, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal safetensors header err: %v", err)
+	}
+	tensorMap := make(map[string]*SafetensorsTensor)
+	if err := sonic.Unmarshal(header, &tensorMap); err != nil {
+		return nil, fmt.Errorf("unmarshal safetensors header err: %v", err)
+	}
+	info := &SafetensorsInfo{
+		HeaderSize: headerSize,
+		Dtypes:     make(map[string]uint64),
+		Metadata:   meta.Metadata,
+		Tensors:    make([]*SafetensorsTensor, 0, len(tensorMap)),
+	}
+	for name, tensor := range tensorMap {
+		if name == "__metadata__" || tensor == nil {
+			continue
+		}
+		tensor.Name = name
+		elements := uint64(1)
+		for _, dim := range tensor.Shape {
+			elements *= dim
+		}
+		info.ParameterCount += elements
+		info.Dtypes[tensor.Dtype] += elements
+		info.Tensors = append(info.Tensors, tensor)
+	}
+	sort.Slice(info.Tensors, func(i, j int) bool {
+		if len(info.Tensors[i].DataOffsets) == 0 || len(info.Tensors[j].DataOffsets) == 0 {
+			return info.Tensors[i].Name < info.Tensors[j].Name
+		}
+		return info.Tensors[i].DataOffsets[0] < info.Tensors[j].DataOffsets[0]
+	})
+	return info, nil
+}
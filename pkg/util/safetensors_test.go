@@ -0,0 +1,24 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseSafetensors(t *testing.T) {
+	header := `{"__metadata__":{"format":"pt"},"b":{"dtype":"F16","shape":[2,3],"data_offsets":[16,28]},"a":{"dtype":"F32","shape":[4],"data_offsets":[0,16]}}`
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint64(len(header)))
+	buf.WriteString(header)
+	info, err := ParseSafetensors(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ParameterCount != 10 || info.Dtypes["F16"] != 6 || info.Metadata["format"] != "pt" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if len(info.Tensors) != 2 || info.Tensors[0].Name != "a" {
+		t.Fatalf("unexpected tensors: %+v", info.Tensors)
+	}
+}
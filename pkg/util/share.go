@@ -0,0 +1,65 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ShareScope 分享链接签名的内容，filePath为空表示整个快照的归档。
+func ShareScope(repoType, orgRepo, commit, filePath string) string {
+	return fmt.Sprintf("%s/%s@%s:%s", repoType, orgRepo, commit, filePath)
+}
+
+// SharePath 分享链接的路径，文件路径转义为一段；无组织仓库的文件链接与有组织仓库的归档链接段数相同，以resolve区分。
+func SharePath(repoType, org, repo, commit, filePath string) string {
+	orgRepo := GetOrgRepo(org, repo)
+	if filePath == "" {
+		return fmt.Sprintf("/share/%s/%s/%s.tar", repoType, orgRepo, commit)
+	}
+	if org == "" {
+		return fmt.Sprintf("/share/%s/%s/resolve/%s/%s", repoType, repo, commit, url.PathEscape(filePath))
+	}
+	return fmt.Sprintf("/share/%s/%s/%s/%s", repoType, orgRepo, commit, url.PathEscape(filePath))
+}
+
+// SignShare 对分享范围及过期时间（unix秒）签名。
+func SignShare(secret, scope string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(scope + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyShare 校验签名且链接未过期。
+func VerifyShare(secret, scope string, expires int64, sig string, now time.Time) bool {
+	if secret == "" || now.Unix() > expires {
+		return false
+	}
+	expected, err := hex.DecodeString(SignShare(secret, scope, expires))
+	if err != nil {
+		return false
+	}
+	actual, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, actual)
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyShare(t *testing.T) {
+	now := time.Unix(1000, 0)
+	scope := ShareScope("models", "org/repo", "abc", "model.safetensors")
+	expires := now.Add(time.Hour).Unix()
+	sig := SignShare("secret", scope, expires)
+
+	if !VerifyShare("secret", scope, expires, sig, now) {
+		t.Fatal("valid link rejected")
+	}
+	if VerifyShare("secret", scope, expires, sig, now.Add(2*time.Hour)) {
+		t.Error("expired link accepted")
+	}
+	if VerifyShare("secret", scope, expires+3600, sig, now) {
+		t.Error("link with extended expiry accepted")
+	}
+	if VerifyShare("secret", ShareScope("models", "org/repo", "abc", "other.bin"), expires, sig, now) {
+		t.Error("link for another file accepted")
+	}
+	if VerifyShare("secret", ShareScope("models", "org/repo", "abc", ""), expires, sig, now) {
+		t.Error("file link accepted as snapshot link")
+	}
+	if VerifyShare("other", scope, expires, sig, now) {
+		t.Error("link signed with another secret accepted")
+	}
+	if VerifyShare("", scope, expires, SignShare("", scope, expires), now) {
+		t.Error("link accepted without secret")
+	}
+	if VerifyShare("secret", scope, expires, "not-hex", now) {
+		t.Error("malformed signature accepted")
+	}
+}
@@ -0,0 +1,154 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"dingospeed/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// 所有SpillBuffer在内存中缓冲的字节数
+var bufferedBytes atomic.Int64
+
+// BufferedBytes 当前在内存中缓冲的远端响应体字节数。
+func BufferedBytes() int64 {
+	return bufferedBytes.Load()
+}
+
+// 预留n字节的内存预算，超过download.bufferBudget时返回false。
+func reserveBuffer(n int64) bool {
+	budget := config.SysConfig.GetBufferBudget()
+	for {
+		cur := bufferedBytes.Load()
+		if budget > 0 && cur+n > budget {
+			return false
+		}
+		if bufferedBytes.CompareAndSwap(cur, cur+n) {
+			return true
+		}
+	}
+}
+
+// SpillBuffer 缓冲远端响应体，优先使用内存，全局内存预算不足时将已缓冲的内容转存到临时文件，之后的写入均写入文件。
+// 非并发安全，使用完后需调用Close释放预算及临时文件。
+type SpillBuffer struct {
+	mem  []byte
+	file *os.File
+	size int64
+}
+
+func NewSpillBuffer() *SpillBuffer {
+	return &SpillBuffer{}
+}
+
+func (b *SpillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil {
+		if reserveBuffer(int64(len(p))) {
+			b.mem = append(b.mem, p...)
+			b.size += int64(len(p))
+			return len(p), nil
+		}
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := b.file.Write(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *SpillBuffer) spill() error {
+	dir := config.SysConfig.GetSpillDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, "spill-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(b.mem); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	zap.S().Infof("buffer budget exceeded, spill %d bytes to %s", len(b.mem), file.Name())
+	bufferedBytes.Add(-int64(len(b.mem)))
+	b.mem, b.file = nil, file
+	return nil
+}
+
+// Len 已缓冲的字节数。
+func (b *SpillBuffer) Len() int64 {
+	return b.size
+}
+
+// Spilled 内容是否已转存到临时文件。
+func (b *SpillBuffer) Spilled() bool {
+	return b.file != nil
+}
+
+// Reader 从头读取已缓冲的内容，读取期间不应再写入。
+func (b *SpillBuffer) Reader() (io.Reader, error) {
+	if b.file == nil {
+		return bytes.NewReader(b.mem), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return b.file, nil
+}
+
+func (b *SpillBuffer) Close() error {
+	if b.file == nil {
+		bufferedBytes.Add(-int64(len(b.mem)))
+		b.mem = nil
+		return nil
+	}
+	name := b.file.Name()
+	err := b.file.Close()
+	b.file = nil
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// ReadAllBuffered 经SpillBuffer读取完整的远端响应体：读取期间计入内存预算，预算不足时先转存到临时文件，
+// 读完后按实际大小一次读回，避免多个大响应同时在内存中扩容。返回的内容不再计入预算。
+func ReadAllBuffered(r io.Reader) ([]byte, error) {
+	b := NewSpillBuffer()
+	defer b.Close()
+	if _, err := io.Copy(b, r); err != nil {
+		return nil, err
+	}
+	if !b.Spilled() {
+		return b.mem, nil
+	}
+	reader, err := b.Reader()
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, b.Len())
+	if _, err = io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
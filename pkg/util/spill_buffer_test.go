@@ -0,0 +1,85 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	download := config.SysConfig.Download
+	defer func() { config.SysConfig.Download = download }()
+	config.SysConfig.Download.BufferBudget = 8
+	config.SysConfig.Download.SpillDir = t.TempDir()
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	content := bytes.Repeat([]byte("dingospeed"), 100)
+	w.Write(content)
+	w.Close()
+
+	b := NewSpillBuffer()
+	raw := gz.Bytes()
+	b.Write(raw[:4])
+	if b.Spilled() || BufferedBytes() != 4 {
+		t.Fatalf("expect buffered in memory, spilled=%v, buffered=%d", b.Spilled(), BufferedBytes())
+	}
+	if _, err := b.Write(raw[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Spilled() || BufferedBytes() != 0 || b.Len() != int64(len(raw)) {
+		t.Fatalf("expect spilled, spilled=%v, buffered=%d, len=%d", b.Spilled(), BufferedBytes(), b.Len())
+	}
+	r, err := b.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr, err := DecompressReader(r, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(dr)
+	dr.Close()
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("decompress got %d bytes, err %v", len(got), err)
+	}
+	if err = b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(config.SysConfig.Download.SpillDir); len(entries) != 0 {
+		t.Errorf("spill file not removed: %d entries", len(entries))
+	}
+}
+
+func TestReadAllBuffered(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	download := config.SysConfig.Download
+	defer func() { config.SysConfig.Download = download }()
+	config.SysConfig.Download.SpillDir = t.TempDir()
+
+	content := bytes.Repeat([]byte("dingospeed"), 100)
+	for _, budget := range []int64{1 << 20, 8} {
+		config.SysConfig.Download.BufferBudget = budget
+		got, err := ReadAllBuffered(bytes.NewReader(content))
+		if err != nil || !bytes.Equal(got, content) {
+			t.Fatalf("budget %d: got %d bytes, err %v", budget, len(got), err)
+		}
+		if BufferedBytes() != 0 {
+			t.Errorf("budget %d: %d bytes still reserved", budget, BufferedBytes())
+		}
+	}
+	if entries, _ := os.ReadDir(config.SysConfig.Download.SpillDir); len(entries) != 0 {
+		t.Errorf("spill file not removed: %d entries", len(entries))
+	}
+}
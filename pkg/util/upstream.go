@@ -0,0 +1,51 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"strings"
+
+	"dingospeed/pkg/consts"
+)
+
+// ParseUpstreamRoute 从远端请求路径中解析仓库类型与组织，用于匹配远端路由规则。
+// 支持/api/{type}/{org}/{repo}/...、/{type}/{org}/{repo}/resolve/...及省略models类型的/{org}/{repo}/resolve/...
+func ParseUpstreamRoute(uri string) (repoType, org string) {
+	uri, _, _ = strings.Cut(uri, "?")
+	segments := strings.Split(strings.Trim(uri, "/"), "/")
+	if len(segments) > 0 && segments[0] == "api" {
+		segments = segments[1:]
+		if len(segments) < 2 {
+			return "", ""
+		}
+		if _, ok := consts.RepoTypesMapping[segments[0]]; !ok {
+			return "", ""
+		}
+		return segments[0], segments[1]
+	}
+	if len(segments) < 2 {
+		return "", ""
+	}
+	if _, ok := consts.RepoTypesMapping[segments[0]]; ok {
+		if len(segments) > 2 && segments[2] != "resolve" {
+			return segments[0], segments[1]
+		}
+		return segments[0], ""
+	}
+	if segments[1] == "resolve" {
+		return "models", ""
+	}
+	return "models", strings.TrimSuffix(segments[0], ".git")
+}
@@ -0,0 +1,196 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/events"
+
+	"go.uber.org/zap"
+)
+
+// 远端请求预算：按小时或自然日统计发往远端的请求数及下载流量，达到上限后切换为只提供缓存内容，
+// 期间管理员的强制刷新请求排队，窗口重置后依次执行。计数保存在内存中，进程重启后重新计数。
+
+var budget = &upstreamBudget{refreshes: make(map[string]func())}
+
+type upstreamBudget struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int64
+	bytes       int64
+	refreshes   map[string]func() // 排队到下一个窗口的刷新任务，相同key只保留一个
+}
+
+type UpstreamBudgetStatus struct {
+	Enabled     bool      `json:"enabled"`
+	Window      string    `json:"window"`
+	WindowStart time.Time `json:"windowStart"`
+	ResetAt     time.Time `json:"resetAt"`
+	Requests    int64     `json:"requests"`
+	MaxRequests int64     `json:"maxRequests"`
+	Bytes       int64     `json:"bytes"`
+	MaxBytes    int64     `json:"maxBytes"`
+	Exhausted   bool      `json:"exhausted"`
+	Queued      int       `json:"queued"` // 排队等待窗口重置的刷新任务数
+}
+
+func budgetWindowStart(window string, now time.Time) time.Time {
+	if window == "hour" {
+		return now.Truncate(time.Hour)
+	}
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+func budgetWindowEnd(window string, start time.Time) time.Time {
+	if window == "hour" {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// roll 进入新窗口时清零计数，返回排队的刷新任务，调用方需持有锁。
+func (b *upstreamBudget) roll(now time.Time) []func() {
+	start := budgetWindowStart(config.SysConfig.GetUpstreamBudgetWindow(), now)
+	if !start.After(b.windowStart) {
+		return nil
+	}
+	b.windowStart, b.requests, b.bytes = start, 0, 0
+	tasks := make([]func(), 0, len(b.refreshes))
+	for _, fn := range b.refreshes {
+		tasks = append(tasks, fn)
+	}
+	clear(b.refreshes)
+	return tasks
+}
+
+func (b *upstreamBudget) exhausted() bool {
+	conf := config.SysConfig.UpstreamBudget
+	return (conf.MaxRequests > 0 && b.requests >= conf.MaxRequests) || (conf.MaxMB > 0 && b.bytes >= conf.MaxMB<<20)
+}
+
+// update 同步在线状态，调用方需持有锁。
+func (b *upstreamBudget) update() {
+	exhausted := b.exhausted()
+	if config.SysConfig.BudgetExhausted() == exhausted {
+		return
+	}
+	config.SysConfig.SetBudgetExhausted(exhausted)
+	events.Publish(events.UpstreamState, map[string]interface{}{"budgetExhausted": exhausted, "requests": b.requests, "bytes": b.bytes})
+	if exhausted {
+		zap.S().Warnf("upstream budget is exhausted (requests %d, bytes %d), serve cache only until %s",
+			b.requests, b.bytes, budgetWindowEnd(config.SysConfig.GetUpstreamBudgetWindow(), b.windowStart).Format(time.DateTime))
+	} else {
+		zap.S().Infof("upstream budget is reset, back to online")
+	}
+}
+
+// chargeUpstreamRequest 记录一次远端请求，预算已用尽时返回false及距窗口重置的时间。
+func chargeUpstreamRequest() (time.Duration, bool) {
+	if !config.SysConfig.UpstreamBudget.Enabled {
+		return 0, true
+	}
+	budget.mu.Lock()
+	tasks := budget.roll(time.Now())
+	exhausted := budget.exhausted()
+	if !exhausted {
+		budget.requests++
+	}
+	budget.update()
+	remaining := time.Until(budgetWindowEnd(config.SysConfig.GetUpstreamBudgetWindow(), budget.windowStart))
+	budget.mu.Unlock()
+	runBudgetRefreshes(tasks)
+	return remaining, !exhausted
+}
+
+func chargeUpstreamBytes(n int64) {
+	budget.mu.Lock()
+	budget.bytes += n
+	budget.update()
+	budget.mu.Unlock()
+}
+
+// budgetBody 统计远端响应体的下载流量
+type budgetBody struct {
+	io.ReadCloser
+}
+
+func (b *budgetBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		chargeUpstreamBytes(int64(n))
+	}
+	return n, err
+}
+
+// CheckUpstreamBudget 定期检查预算窗口，无远端请求时也能按时重置并执行排队的刷新任务。
+func CheckUpstreamBudget() {
+	budget.mu.Lock()
+	tasks := budget.roll(time.Now())
+	budget.update()
+	budget.mu.Unlock()
+	runBudgetRefreshes(tasks)
+}
+
+// QueueUpstreamRefresh 预算已用尽时将刷新任务排队到下一个窗口，返回false表示预算充足，调用方应立即执行。
+func QueueUpstreamRefresh(key string, fn func()) bool {
+	if !config.SysConfig.UpstreamBudget.Enabled || !config.SysConfig.BudgetExhausted() {
+		return false
+	}
+	budget.mu.Lock()
+	budget.refreshes[key] = fn
+	budget.mu.Unlock()
+	zap.S().Infof("upstream budget is exhausted, refresh %s is queued", key)
+	return true
+}
+
+func runBudgetRefreshes(tasks []func()) {
+	if len(tasks) == 0 {
+		return
+	}
+	zap.S().Infof("run %d queued refreshes of upstream budget", len(tasks))
+	go func() {
+		for _, fn := range tasks {
+			fn()
+		}
+	}()
+}
+
+func GetUpstreamBudgetStatus() *UpstreamBudgetStatus {
+	conf := config.SysConfig.UpstreamBudget
+	window := config.SysConfig.GetUpstreamBudgetWindow()
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	status := &UpstreamBudgetStatus{
+		Enabled:     conf.Enabled,
+		Window:      window,
+		WindowStart: budget.windowStart,
+		Requests:    budget.requests,
+		MaxRequests: conf.MaxRequests,
+		Bytes:       budget.bytes,
+		MaxBytes:    conf.MaxMB << 20,
+		Exhausted:   config.SysConfig.BudgetExhausted(),
+		Queued:      len(budget.refreshes),
+	}
+	if !budget.windowStart.IsZero() {
+		status.ResetAt = budgetWindowEnd(window, budget.windowStart)
+	}
+	return status
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestBudgetWindow(t *testing.T) {
+	now := time.Date(2025, 3, 31, 15, 42, 10, 0, time.UTC)
+	if got := budgetWindowStart("hour", now); !got.Equal(time.Date(2025, 3, 31, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("hour window start = %s", got)
+	}
+	start := budgetWindowStart("day", now)
+	if !start.Equal(time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day window start = %s", start)
+	}
+	if got := budgetWindowEnd("day", start); !got.Equal(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day window end = %s", got)
+	}
+}
+
+func TestUpstreamBudget(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	conf := config.SysConfig.UpstreamBudget
+	defer func() {
+		config.SysConfig.UpstreamBudget = conf
+		config.SysConfig.SetBudgetExhausted(false)
+		budget = &upstreamBudget{refreshes: make(map[string]func())}
+	}()
+	config.SysConfig.UpstreamBudget = config.UpstreamBudget{Enabled: true, Window: "hour", MaxRequests: 2, MaxMB: 1}
+	budget = &upstreamBudget{refreshes: make(map[string]func())}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := chargeUpstreamRequest(); !ok {
+			t.Fatalf("request %d should be within budget", i)
+		}
+	}
+	if remaining, ok := chargeUpstreamRequest(); ok || remaining <= 0 || remaining > time.Hour {
+		t.Fatalf("expected budget exhausted, ok=%v remaining=%s", ok, remaining)
+	}
+	if !config.SysConfig.BudgetExhausted() {
+		t.Fatal("expected cache-only mode")
+	}
+	if !QueueUpstreamRefresh("revalidate/models/org/repo", func() {}) {
+		t.Fatal("expected refresh queued")
+	}
+	status := GetUpstreamBudgetStatus()
+	if status.Requests != 2 || !status.Exhausted || status.Queued != 1 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// 进入新窗口后计数清零，排队的刷新被取出执行
+	budget.mu.Lock()
+	budget.windowStart = budget.windowStart.Add(-time.Hour)
+	budget.mu.Unlock()
+	CheckUpstreamBudget()
+	if config.SysConfig.BudgetExhausted() || GetUpstreamBudgetStatus().Queued != 0 {
+		t.Fatal("expected budget reset")
+	}
+	chargeUpstreamBytes(1 << 20)
+	if !config.SysConfig.BudgetExhausted() {
+		t.Fatal("expected bytes budget exhausted")
+	}
+}
@@ -0,0 +1,218 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/errreport"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/prom"
+
+	"go.uber.org/zap"
+)
+
+// 远端健康统计：按远端地址统计窗口内的请求数与失败数，失败率超过阈值时告警一次，恢复后再告警一次。
+// 默认远端被判定为不可用且启用autoOffline时切换为离线服务，离线期间定期探测，探测成功后切回在线。
+
+const healthBuckets = 10 // 窗口划分的桶数，按桶滚动淘汰过期的统计
+
+var (
+	upstreamHealthsMu sync.Mutex
+	upstreamHealths   = make(map[string]*upstreamHealth)
+)
+
+type healthBucket struct {
+	slot     int64
+	total    int
+	failures int
+}
+
+type upstreamHealth struct {
+	endpoint string
+	mu       sync.Mutex
+	buckets  [healthBuckets]healthBucket
+	down     bool
+	probing  bool
+}
+
+// RecordUpstream 记录一次远端请求的结果，网络错误及5xx计为失败，客户端取消的请求不计入。
+func RecordUpstream(targetURL string, statusCode int, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	endpoint := upstreamEndpoint(targetURL)
+	failed := err != nil || statusCode >= http.StatusInternalServerError
+	result := "success"
+	if failed {
+		result = "failure"
+	}
+	prom.UpstreamRequestCnt.WithLabelValues(endpoint, result).Inc()
+	conf := config.SysConfig.UpstreamAlert
+	if !conf.Enabled {
+		return
+	}
+	getUpstreamHealth(endpoint).record(time.Now(), failed, conf)
+}
+
+func getUpstreamHealth(endpoint string) *upstreamHealth {
+	upstreamHealthsMu.Lock()
+	defer upstreamHealthsMu.Unlock()
+	h, ok := upstreamHealths[endpoint]
+	if !ok {
+		h = &upstreamHealth{endpoint: endpoint}
+		upstreamHealths[endpoint] = h
+	}
+	return h
+}
+
+func recordUpstreamResp(targetURL string, resp *http.Response, err error) {
+	if resp != nil {
+		RecordUpstream(targetURL, resp.StatusCode, err)
+	} else {
+		RecordUpstream(targetURL, 0, err)
+	}
+}
+
+func upstreamEndpoint(targetURL string) string {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Host == "" {
+		return targetURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func (h *upstreamHealth) record(now time.Time, failed bool, conf config.UpstreamAlert) {
+	bucketSize := max(int64(conf.Window)/healthBuckets, 1)
+	slot := now.Unix() / bucketSize
+	h.mu.Lock()
+	b := &h.buckets[slot%healthBuckets]
+	if b.slot != slot {
+		*b = healthBucket{slot: slot}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+	total, failures := h.stats(slot)
+	var changed bool
+	rate := float64(failures) / float64(max(total, 1))
+	if total >= conf.MinRequests {
+		if !h.down && rate >= conf.ErrorRate {
+			h.down, changed = true, true
+		} else if h.down && rate < conf.ErrorRate {
+			h.down, changed = false, true
+		}
+	}
+	down := h.down
+	h.mu.Unlock()
+	if changed {
+		h.onChange(down, fmt.Sprintf("%d/%d requests failed in %ds", failures, total, conf.Window))
+	}
+}
+
+// stats 汇总窗口内未过期的桶
+func (h *upstreamHealth) stats(slot int64) (int, int) {
+	var total, failures int
+	for _, b := range h.buckets {
+		if slot-b.slot < healthBuckets {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+func (h *upstreamHealth) onChange(down bool, detail string) {
+	level, message, gauge := "info", fmt.Sprintf("upstream %s recovered, %s", h.endpoint, detail), 0.0
+	if down {
+		level, message, gauge = "error", fmt.Sprintf("upstream %s is down, %s", h.endpoint, detail), 1.0
+		zap.S().Errorf(message)
+	} else {
+		zap.S().Infof(message)
+	}
+	prom.UpstreamDown.WithLabelValues(h.endpoint).Set(gauge)
+	events.Publish(events.UpstreamState, map[string]interface{}{"endpoint": h.endpoint, "down": down, "detail": detail})
+	errreport.Report(&errreport.Event{Level: level, Message: message, Tags: map[string]string{"endpoint": h.endpoint}})
+
+	if !config.SysConfig.UpstreamAlert.AutoOffline || !isDefaultEndpoint(h.endpoint) {
+		return
+	}
+	config.SysConfig.SetUpstreamDown(down)
+	if down {
+		zap.S().Warnf("switch to offline serving until upstream %s recovers", h.endpoint)
+		h.mu.Lock()
+		probing := h.probing
+		h.probing = true
+		h.mu.Unlock()
+		if !probing {
+			go h.probe()
+		}
+	}
+}
+
+// probe 自动离线期间不再有远端请求，定期探测远端，成功后清空统计并切回在线。
+func (h *upstreamHealth) probe() {
+	defer func() {
+		h.mu.Lock()
+		h.probing = false
+		h.mu.Unlock()
+	}()
+	ticker := time.NewTicker(time.Duration(config.SysConfig.UpstreamAlert.ProbeInterval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.mu.Lock()
+		down := h.down
+		h.mu.Unlock()
+		if !down {
+			return
+		}
+		var client *http.Client
+		var err error
+		if h.endpoint == upstreamEndpoint(config.SysConfig.GetHFURLBase()) {
+			client, err = getClient(http.MethodHead, consts.RequestClassMeta, h.endpoint, true)
+		} else {
+			client, err = getClient(http.MethodHead, consts.RequestClassMeta, h.endpoint, false)
+		}
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		resp, err := doHead(ctx, client, h.endpoint+"/", nil)
+		cancel()
+		if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+			continue
+		}
+		h.mu.Lock()
+		h.buckets = [healthBuckets]healthBucket{}
+		h.down = false
+		h.mu.Unlock()
+		h.onChange(false, "probe succeeded")
+		return
+	}
+}
+
+func isDefaultEndpoint(endpoint string) bool {
+	return endpoint == upstreamEndpoint(config.SysConfig.GetHFURLBase()) ||
+		(config.SysConfig.DynamicProxy.Enabled && endpoint == upstreamEndpoint(config.SysConfig.GetBpHFURLBase()))
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestUpstreamHealthRecord(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	conf := config.UpstreamAlert{Enabled: true, Window: 10, MinRequests: 4, ErrorRate: 0.5}
+	h := &upstreamHealth{endpoint: "https://hf.example.com"}
+	now := time.Unix(1000, 0)
+
+	// 请求数不足时不判定
+	for i := 0; i < 3; i++ {
+		h.record(now, true, conf)
+	}
+	if h.down {
+		t.Fatal("should not be down before minRequests")
+	}
+	h.record(now, true, conf)
+	if !h.down {
+		t.Fatal("should be down after failures exceed threshold")
+	}
+
+	// 窗口滚动后旧的失败过期
+	later := now.Add(11 * time.Second)
+	for i := 0; i < 4; i++ {
+		h.record(later, false, conf)
+	}
+	if h.down {
+		t.Fatal("should recover after failures expire")
+	}
+}
+
+func TestUpstreamEndpoint(t *testing.T) {
+	if got := upstreamEndpoint("https://huggingface.co/api/models/a/b?x=1"); got != "https://huggingface.co" {
+		t.Errorf("upstreamEndpoint = %s", got)
+	}
+}
@@ -0,0 +1,143 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+	"dingospeed/pkg/consts"
+	"dingospeed/pkg/errreport"
+	"dingospeed/pkg/events"
+	"dingospeed/pkg/prom"
+
+	"go.uber.org/zap"
+)
+
+// 远端可用性探测：定期探测默认远端，连续失败达到阈值时切换为离线服务，连续成功达到阈值后切回在线。
+// 切换带有迟滞，单次探测失败或成功不会改变状态，避免网络抖动导致在线状态频繁切换。
+
+var prober = &upstreamProber{}
+
+type upstreamProber struct {
+	mu        sync.Mutex
+	failures  int // 连续失败次数
+	successes int // 连续成功次数
+	down      bool
+	lastProbe time.Time
+	lastError string
+	latency   time.Duration
+}
+
+type UpstreamProbeStatus struct {
+	Endpoint  string    `json:"endpoint"`
+	Down      bool      `json:"down"`
+	Failures  int       `json:"failures"`
+	Successes int       `json:"successes"`
+	LastProbe time.Time `json:"lastProbe"`
+	LastError string    `json:"lastError,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+}
+
+// observe 记录一次探测结果，返回状态是否发生切换。
+func (p *upstreamProber) observe(ok bool, conf config.UpstreamProbe) bool {
+	if ok {
+		p.failures = 0
+		p.successes++
+		if p.down && p.successes >= conf.RecoverThreshold {
+			p.down = false
+			return true
+		}
+		return false
+	}
+	p.successes = 0
+	p.failures++
+	if !p.down && p.failures >= conf.FailThreshold {
+		p.down = true
+		return true
+	}
+	return false
+}
+
+// ProbeUpstream 探测一次默认远端，网络错误及5xx计为失败。
+func ProbeUpstream() {
+	conf := config.SysConfig.UpstreamProbe
+	domain, useProxy := upstreamDomain("")
+	endpoint := upstreamEndpoint(domain)
+	start := time.Now()
+	err := probeEndpoint(endpoint, useProxy, time.Duration(conf.Timeout)*time.Second)
+
+	prober.mu.Lock()
+	prober.lastProbe = start
+	prober.latency = time.Since(start)
+	prober.lastError = ""
+	if err != nil {
+		prober.lastError = err.Error()
+	}
+	changed := prober.observe(err == nil, conf)
+	down, failures, successes := prober.down, prober.failures, prober.successes
+	prober.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	config.SysConfig.SetUpstreamDown(down)
+	events.Publish(events.UpstreamState, map[string]interface{}{"endpoint": endpoint, "down": down, "source": "probe"})
+	level, message, gauge := "info", fmt.Sprintf("upstream %s is reachable again after %d successful probes, switch to online serving", endpoint, successes), 0.0
+	if down {
+		level, message, gauge = "error", fmt.Sprintf("upstream %s is unreachable after %d failed probes, switch to offline serving: %v", endpoint, failures, err), 1.0
+		zap.S().Errorf(message)
+	} else {
+		zap.S().Infof(message)
+	}
+	prom.UpstreamDown.WithLabelValues(endpoint).Set(gauge)
+	errreport.Report(&errreport.Event{Level: level, Message: message, Tags: map[string]string{"endpoint": endpoint}})
+}
+
+func probeEndpoint(endpoint string, useProxy bool, timeout time.Duration) error {
+	client, err := getClient(http.MethodHead, consts.RequestClassMeta, endpoint, useProxy)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := doHead(ctx, client, endpoint+"/", nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func GetUpstreamProbeStatus() *UpstreamProbeStatus {
+	domain, _ := upstreamDomain("")
+	prober.mu.Lock()
+	defer prober.mu.Unlock()
+	return &UpstreamProbeStatus{
+		Endpoint:  upstreamEndpoint(domain),
+		Down:      prober.down,
+		Failures:  prober.failures,
+		Successes: prober.successes,
+		LastProbe: prober.lastProbe,
+		LastError: prober.lastError,
+		LatencyMs: prober.latency.Milliseconds(),
+	}
+}
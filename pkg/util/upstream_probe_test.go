@@ -0,0 +1,35 @@
+package util
+
+import (
+	"testing"
+
+	"dingospeed/pkg/config"
+)
+
+func TestUpstreamProberHysteresis(t *testing.T) {
+	conf := config.UpstreamProbe{FailThreshold: 3, RecoverThreshold: 2}
+	p := &upstreamProber{}
+	steps := []struct {
+		ok      bool
+		changed bool
+		down    bool
+	}{
+		{false, false, false},
+		{false, false, false},
+		{true, false, false}, // 成功一次后重新计数
+		{false, false, false},
+		{false, false, false},
+		{false, true, true},
+		{false, false, true},
+		{true, false, true},
+		{false, false, true}, // 失败一次后重新计数
+		{true, false, true},
+		{true, true, false},
+		{true, false, false},
+	}
+	for i, s := range steps {
+		if changed := p.observe(s.ok, conf); changed != s.changed || p.down != s.down {
+			t.Fatalf("step %d: changed=%v down=%v, want changed=%v down=%v", i, changed, p.down, s.changed, s.down)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package util
+
+import "testing"
+
+func TestParseUpstreamRoute(t *testing.T) {
+	cases := []struct {
+		uri      string
+		repoType string
+		org      string
+	}{
+		{"/api/models/Qwen/Qwen2.5-7B/revision/main", "models", "Qwen"},
+		{"/api/datasets/org/data/paths-info/abc", "datasets", "org"},
+		{"/api/whoami-v2", "", ""},
+		{"/Qwen/Qwen2.5-7B/resolve/main/config.json", "models", "Qwen"},
+		{"/gpt2/resolve/main/config.json", "models", ""},
+		{"/datasets/org/data/resolve/main/a.parquet?download=true", "datasets", "org"},
+		{"/datasets/squad/resolve/main/a.json", "datasets", ""},
+	}
+	for _, tc := range cases {
+		repoType, org := ParseUpstreamRoute(tc.uri)
+		if repoType != tc.repoType || org != tc.org {
+			t.Errorf("ParseUpstreamRoute(%s) = %s, %s; want %s, %s", tc.uri, repoType, org, tc.repoType, tc.org)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+//  Copyright (c) 2025 dingodb.com, Inc. All Rights Reserved
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http:www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package util
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dingospeed/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// 远端限流：远端返回429时按Retry-After记录该远端的暂停截止时间，暂停期间发往该远端的请求排队等待，
+// 剩余时间超过maxWait时不再请求远端，直接返回携带Retry-After的429响应。
+
+var (
+	upstreamPausesMu sync.Mutex
+	upstreamPauses   = make(map[string]time.Time)
+)
+
+// doUpstream 发送远端请求并记录请求结果，远端暂停期间或请求预算用尽时排队或返回模拟的429响应。
+func doUpstream(client *http.Client, req *http.Request, targetURL string) (*http.Response, error) {
+	if resp, err := throttleUpstream(req.Context(), targetURL); resp != nil || err != nil {
+		return resp, err
+	}
+	if remaining, ok := chargeUpstreamRequest(); !ok {
+		return throttledResponse(remaining), nil
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if resp != nil && config.SysConfig.UpstreamBudget.Enabled {
+		resp.Body = &budgetBody{ReadCloser: resp.Body}
+	}
+	captureUpstream(req, resp, err, start)
+	recordUpstreamResp(targetURL, resp, err)
+	recordThrottle(targetURL, resp, time.Now())
+	return resp, err
+}
+
+func throttleUpstream(ctx context.Context, targetURL string) (*http.Response, error) {
+	remaining := upstreamPauseRemaining(upstreamEndpoint(targetURL))
+	if remaining <= 0 {
+		return nil, nil
+	}
+	maxWait := config.SysConfig.UpstreamThrottle.MaxWait
+	if maxWait < 0 || remaining > time.Duration(maxWait)*time.Second {
+		return throttledResponse(remaining), nil
+	}
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func throttledResponse(remaining time.Duration) *http.Response {
+	header := make(http.Header)
+	header.Set("Retry-After", strconv.Itoa(retryAfterSeconds(remaining)))
+	return &http.Response{
+		Status:     "429 Too Many Requests",
+		StatusCode: http.StatusTooManyRequests,
+		Header:     header,
+		Body:       http.NoBody,
+	}
+}
+
+// recordThrottle 远端返回429时暂停该远端，重复限流时取较晚的截止时间。
+func recordThrottle(targetURL string, resp *http.Response, now time.Time) {
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	conf := config.SysConfig.UpstreamThrottle
+	pause := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if pause <= 0 {
+		pause = time.Duration(conf.DefaultPause) * time.Second
+	}
+	pause = min(pause, time.Duration(conf.MaxPause)*time.Second)
+	endpoint := upstreamEndpoint(targetURL)
+	until := now.Add(pause)
+	upstreamPausesMu.Lock()
+	if until.After(upstreamPauses[endpoint]) {
+		upstreamPauses[endpoint] = until
+	}
+	upstreamPausesMu.Unlock()
+	zap.S().Warnf("upstream %s is rate limited, pause for %s", endpoint, pause)
+}
+
+// parseRetryAfter 解析Retry-After，支持秒数和HTTP日期两种格式，无法解析时返回0。
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func upstreamPauseRemaining(endpoint string) time.Duration {
+	upstreamPausesMu.Lock()
+	defer upstreamPausesMu.Unlock()
+	until, ok := upstreamPauses[endpoint]
+	if !ok {
+		return 0
+	}
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		delete(upstreamPauses, endpoint)
+		return 0
+	}
+	return remaining
+}
+
+// UpstreamRetryAfter 返回请求路径对应远端的剩余暂停时间，单位秒，未暂停时返回0。
+func UpstreamRetryAfter(uri string) int {
+	domain, _ := upstreamDomain(uri)
+	if remaining := upstreamPauseRemaining(upstreamEndpoint(domain)); remaining > 0 {
+		return retryAfterSeconds(remaining)
+	}
+	return 0
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
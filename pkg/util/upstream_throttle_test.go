@@ -0,0 +1,67 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{" 5 ", 5 * time.Second},
+		{now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{"invalid", 0},
+	}
+	for _, tc := range cases {
+		if got := parseRetryAfter(tc.value, now); got != tc.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestThrottleUpstream(t *testing.T) {
+	if config.SysConfig == nil {
+		config.SysConfig = &config.Config{}
+		defer func() { config.SysConfig = nil }()
+	}
+	conf := config.SysConfig.UpstreamThrottle
+	defer func() { config.SysConfig.UpstreamThrottle = conf }()
+	config.SysConfig.UpstreamThrottle = config.UpstreamThrottle{MaxWait: 1, DefaultPause: 30, MaxPause: 60}
+
+	target := "https://throttle.example.com/api/models/org/repo"
+	defer func() {
+		upstreamPausesMu.Lock()
+		delete(upstreamPauses, upstreamEndpoint(target))
+		upstreamPausesMu.Unlock()
+	}()
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"3600"}}}
+	recordThrottle(target, resp, time.Now())
+
+	// 暂停时长不超过maxPause，剩余时间超过maxWait时直接返回429
+	remaining := upstreamPauseRemaining(upstreamEndpoint(target))
+	if remaining <= 0 || remaining > time.Minute {
+		t.Fatalf("unexpected remaining %s", remaining)
+	}
+	got, err := throttleUpstream(context.Background(), target)
+	if err != nil || got == nil || got.StatusCode != http.StatusTooManyRequests || got.Header.Get("Retry-After") != "60" {
+		t.Fatalf("unexpected throttled response %v, %v", got, err)
+	}
+
+	// 剩余时间在maxWait内时排队等待后放行
+	upstreamPausesMu.Lock()
+	upstreamPauses[upstreamEndpoint(target)] = time.Now().Add(50 * time.Millisecond)
+	upstreamPausesMu.Unlock()
+	got, err = throttleUpstream(context.Background(), target)
+	if err != nil || got != nil {
+		t.Fatalf("should pass after waiting, got %v, %v", got, err)
+	}
+}
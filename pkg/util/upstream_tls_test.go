@@ -0,0 +1,71 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dingospeed/pkg/config"
+)
+
+func writeTestCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestNewUpstreamTLSConfig(t *testing.T) {
+	if c, err := NewUpstreamTLSConfig(&config.UpstreamTLS{}); err != nil || c != nil {
+		t.Fatalf("empty config = %v, %v, want nil", c, err)
+	}
+
+	certFile, keyFile := writeTestCert(t, t.TempDir())
+	c, err := NewUpstreamTLSConfig(&config.UpstreamTLS{CaFile: certFile, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %x, want TLS1.2", c.MinVersion)
+	}
+	if c.RootCAs == nil || len(c.Certificates) != 1 {
+		t.Errorf("RootCAs = %v, Certificates = %d", c.RootCAs, len(c.Certificates))
+	}
+
+	if _, err = NewUpstreamTLSConfig(&config.UpstreamTLS{CaFile: keyFile}); err == nil {
+		t.Error("want error for ca file without certificate")
+	}
+}
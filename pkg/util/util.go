@@ -232,8 +232,8 @@ func ProcessPaths(paths []string) []string {
 	for _, p := range paths {
 		parts := strings.Split(p, string(filepath.Separator))
 		if len(parts) >= 2 {
-			org := parts[len(parts)-2]
-			repo := parts[len(parts)-1]
+			org := CacheName(parts[len(parts)-2])
+			repo := CacheName(parts[len(parts)-1])
 			repos = append(repos, org+"/"+repo)
 		}
 	}
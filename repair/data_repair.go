@@ -423,7 +423,7 @@ func WriteCacheRequest(apiPath string, statusCode int, headers map[string]string
 
 func ReadCacheRequest(apiPath string) (*common.CacheContent, error) {
 	cacheContent := common.CacheContent{}
-	bytes, err := util.ReadFileToBytes(apiPath)
+	bytes, err := util.ReadCacheFile(apiPath)
 	if err != nil {
 		return nil, myerr.Wrap("ReadFileToBytes err.", err)
 	}